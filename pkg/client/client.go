@@ -0,0 +1,611 @@
+// Package client is a Go client of the containerslist API, with typed methods
+// for the endpoints described in /api/v1/openapi.json.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the API of a containerslist node.
+type Client struct {
+	// BaseURL is the URL of the node, such as http://localhost:3000.
+	BaseURL string
+	// AdminToken is the bearer token of the admin endpoints.
+	AdminToken string
+	// Token is the bearer token of the other endpoints, such as an API key,
+	// when the node requires authentication. It is also sent to the admin
+	// endpoints when AdminToken is empty, for the API keys with the admin
+	// role.
+	Token string
+	// HTTPClient is used to send the requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// New returns a client of the node at the given URL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Error is returned when the API responds with an unexpected status. Code
+// is the stable code of the error, when the API answered with a problem.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.AdminToken != "" && strings.HasPrefix(path, "/api/v1/admin/") {
+		req.Header.Set("Authorization", "Bearer "+c.AdminToken)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+		var p struct {
+			Detail string `json:"detail"`
+			Code   string `json:"code"`
+		}
+		if resp.Header.Get("Content-Type") == "application/problem+json" && json.Unmarshal(b, &p) == nil {
+			apiErr.Code, apiErr.Message = p.Code, p.Detail
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, v interface{}) error {
+	resp, err := c.send(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// raw returns the body of a response which is not JSON.
+func (c *Client) raw(ctx context.Context, method, path string, query url.Values) ([]byte, error) {
+	resp, err := c.send(ctx, method, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Status returns the status of the node.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var v Status
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/status", nil, &v)
+}
+
+// Peers returns the members of the cluster.
+func (c *Client) Peers(ctx context.Context) ([]PeerInfo, error) {
+	var v []PeerInfo
+	return v, c.do(ctx, http.MethodGet, "/api/v1/peers", nil, &v)
+}
+
+// Members returns the members of the cluster as the serf members command,
+// filtered by the anchored regular expressions of their status, name and
+// tags, as key=regexp, when not empty.
+func (c *Client) Members(ctx context.Context, status, name string, tags []string) (*SerfMembers, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if name != "" {
+		q.Set("name", name)
+	}
+	for _, tag := range tags {
+		q.Add("tag", tag)
+	}
+	var v SerfMembers
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/members", q, &v)
+}
+
+// Containers returns the containers of each node in the given state: running,
+// exited or all; running when empty.
+func (c *Client) Containers(ctx context.Context, state string) ([]*NodeInventory, error) {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", state)
+	}
+	var v []*NodeInventory
+	return v, c.do(ctx, http.MethodGet, "/api/v1/containers", q, &v)
+}
+
+// Events streams the containers appearing and disappearing to fn, until the
+// context is done or the stream ends.
+func (c *Client) Events(ctx context.Context, fn func(ContainerEvent)) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/v1/events", url.Values{"format": {"native"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		data, ok := bytes.CutPrefix(sc.Bytes(), []byte("data: "))
+		if !ok {
+			continue
+		}
+		var e ContainerEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		fn(e)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return sc.Err()
+}
+
+// History returns the history of the running containers of the given nodes,
+// or all nodes, at the resolution of a tier: raw or downsampled.
+func (c *Client) History(ctx context.Context, tier string, nodes ...string) (*History, error) {
+	q := url.Values{"node": nodes}
+	if tier != "" {
+		q.Set("tier", tier)
+	}
+	var v History
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/history", q, &v)
+}
+
+// Changes returns the inventories changed since a sequence of an epoch, all
+// of them from sequence 0 or when the epoch is not the one of the server.
+// With a positive wait, the server holds the request until the sequence
+// advances past since or the wait elapses. The epoch and sequence of the
+// result are the ones of the next call.
+func (c *Client) Changes(ctx context.Context, epoch string, since uint64, wait time.Duration) (*Changes, error) {
+	q := url.Values{"since": {strconv.FormatUint(since, 10)}, "epoch": {epoch}}
+	if wait > 0 {
+		q.Set("wait", wait.String())
+	}
+	var v Changes
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/changes", q, &v)
+}
+
+// Conflicts returns the containers reported by several nodes.
+func (c *Client) Conflicts(ctx context.Context) ([]ContainerConflict, error) {
+	var v []ContainerConflict
+	return v, c.do(ctx, http.MethodGet, "/api/v1/conflicts", nil, &v)
+}
+
+// GPUs returns the GPUs attached to containers.
+func (c *Client) GPUs(ctx context.Context) ([]GPUUsage, error) {
+	var v []GPUUsage
+	return v, c.do(ctx, http.MethodGet, "/api/v1/gpus", nil, &v)
+}
+
+// Ports returns the host ports published by containers.
+func (c *Client) Ports(ctx context.Context) ([]NodePorts, error) {
+	var v []NodePorts
+	return v, c.do(ctx, http.MethodGet, "/api/v1/ports", nil, &v)
+}
+
+// Networks returns the networks of each node.
+func (c *Client) Networks(ctx context.Context) ([]*NodeResources[Network], error) {
+	var v []*NodeResources[Network]
+	return v, c.do(ctx, http.MethodGet, "/api/v1/networks", nil, &v)
+}
+
+// Volumes returns the volumes of each node.
+func (c *Client) Volumes(ctx context.Context) ([]*NodeResources[Volume], error) {
+	var v []*NodeResources[Volume]
+	return v, c.do(ctx, http.MethodGet, "/api/v1/volumes", nil, &v)
+}
+
+// Images returns the images of each node.
+func (c *Client) Images(ctx context.Context) (*ImagesReport, error) {
+	var v ImagesReport
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/images", nil, &v)
+}
+
+// Projects returns the Compose projects and Swarm stacks.
+func (c *Client) Projects(ctx context.Context) ([]Project, error) {
+	var v []Project
+	return v, c.do(ctx, http.MethodGet, "/api/v1/projects", nil, &v)
+}
+
+// Search searches the containers and nodes of the cluster.
+func (c *Client) Search(ctx context.Context, query string) (*SearchResults, error) {
+	var v SearchResults
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/search", url.Values{"q": {query}}, &v)
+}
+
+// TopContainers returns the containers of the cluster using the most memory,
+// or CPU when by is cpu; limit is 20 when 0.
+func (c *Client) TopContainers(ctx context.Context, by string, limit int) ([]NodeContainerUsage, error) {
+	q := url.Values{}
+	if by != "" {
+		q.Set("by", by)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var v []NodeContainerUsage
+	return v, c.do(ctx, http.MethodGet, "/api/v1/usage/top", q, &v)
+}
+
+// Packages returns the packages of the running images whose name contains
+// name and whose version starts with version, from the SBOMs of the images.
+func (c *Client) Packages(ctx context.Context, name, version string) ([]PackageMatch, error) {
+	q := url.Values{"name": {name}}
+	if version != "" {
+		q.Set("version", version)
+	}
+	var v []PackageMatch
+	return v, c.do(ctx, http.MethodGet, "/api/v1/packages", q, &v)
+}
+
+// PruneCandidates returns the containers and images recommended for pruning.
+func (c *Client) PruneCandidates(ctx context.Context) ([]*NodeResources[PruneCandidate], error) {
+	var v []*NodeResources[PruneCandidate]
+	return v, c.do(ctx, http.MethodGet, "/api/v1/prune/candidates", nil, &v)
+}
+
+// Prune prunes the stopped containers and unused images of a node, or of the
+// node receiving the request when empty. It requires the admin token.
+func (c *Client) Prune(ctx context.Context, node string) (*PruneResult, error) {
+	q := url.Values{}
+	if node != "" {
+		q.Set("node", node)
+	}
+	var v PruneResult
+	return &v, c.do(ctx, http.MethodPost, "/api/v1/admin/prune", q, &v)
+}
+
+// APIKeys returns the API keys of the node, without the keys themselves. It
+// requires the admin token.
+func (c *Client) APIKeys(ctx context.Context) ([]APIKey, error) {
+	var v []APIKey
+	return v, c.do(ctx, http.MethodGet, "/api/v1/admin/api-keys", nil, &v)
+}
+
+// CreateAPIKey creates an API key of the node with a role, viewer, editor or
+// admin, expiring after ttl, or never when 0. The key is only returned once.
+// It requires the admin token.
+func (c *Client) CreateAPIKey(ctx context.Context, name, role string, ttl time.Duration) (*CreatedAPIKey, error) {
+	q := url.Values{"role": {role}}
+	if ttl > 0 {
+		q.Set("ttl", ttl.String())
+	}
+	var v CreatedAPIKey
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/admin/api-keys/"+url.PathEscape(name), q, &v)
+}
+
+// RevokeAPIKey revokes an API key of the node. It requires the admin token.
+func (c *Client) RevokeAPIKey(ctx context.Context, name string) (*APIKey, error) {
+	var v APIKey
+	return &v, c.do(ctx, http.MethodDelete, "/api/v1/admin/api-keys/"+url.PathEscape(name), nil, &v)
+}
+
+// SelfAlerts returns the unhealthy conditions of the agent of the node, with
+// remediation hints.
+func (c *Client) SelfAlerts(ctx context.Context) ([]SelfAlert, error) {
+	var v []SelfAlert
+	return v, c.do(ctx, http.MethodGet, "/api/v1/selfalerts", nil, &v)
+}
+
+// Jobs returns the status of the scheduled jobs of the node.
+func (c *Client) Jobs(ctx context.Context) ([]JobStatus, error) {
+	var v []JobStatus
+	return v, c.do(ctx, http.MethodGet, "/api/v1/jobs", nil, &v)
+}
+
+// GrafanaDashboards returns the Grafana dashboards of the metrics of the
+// nodes.
+func (c *Client) GrafanaDashboards(ctx context.Context) ([]GrafanaDashboard, error) {
+	var v []GrafanaDashboard
+	return v, c.do(ctx, http.MethodGet, "/api/v1/grafana/dashboards", nil, &v)
+}
+
+// GrafanaDashboard returns the JSON model of a Grafana dashboard, wrapped in
+// the body of the dashboard import API of Grafana when forImport is set.
+func (c *Client) GrafanaDashboard(ctx context.Context, uid string, forImport bool) (map[string]interface{}, error) {
+	q := url.Values{}
+	if forImport {
+		q.Set("import", "true")
+	}
+	var v map[string]interface{}
+	return v, c.do(ctx, http.MethodGet, "/api/v1/grafana/dashboards/"+url.PathEscape(uid), q, &v)
+}
+
+// AnnotationSchemas returns the registered schemas of the container
+// annotations.
+func (c *Client) AnnotationSchemas(ctx context.Context) ([]AnnotationSchema, error) {
+	var v []AnnotationSchema
+	return v, c.do(ctx, http.MethodGet, "/api/v1/annotations", nil, &v)
+}
+
+// PeerStats returns the gossip statistics of a member of the cluster.
+func (c *Client) PeerStats(ctx context.Context, name string) (*PeerStats, error) {
+	var v PeerStats
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/peers/"+url.PathEscape(name)+"/stats", nil, &v)
+}
+
+// AnnotateContainer attaches an ephemeral annotation to a container of a
+// node, with an optional link, expiring after ttl, or the default of the
+// node when 0. An empty text clears the annotation.
+func (c *Client) AnnotateContainer(ctx context.Context, node, id, text, link string, ttl time.Duration) (*EphemeralAnnotation, error) {
+	q := url.Values{"text": {text}}
+	if link != "" {
+		q.Set("link", link)
+	}
+	if ttl > 0 {
+		q.Set("ttl", ttl.String())
+	}
+	var v EphemeralAnnotation
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/containers/"+url.PathEscape(node)+"/"+url.PathEscape(id)+"/annotations", q, &v)
+}
+
+// EphemeralAnnotations returns the active ephemeral annotations of the
+// containers.
+func (c *Client) EphemeralAnnotations(ctx context.Context) ([]EphemeralAnnotation, error) {
+	var v []EphemeralAnnotation
+	return v, c.do(ctx, http.MethodGet, "/api/v1/ephemeral-annotations", nil, &v)
+}
+
+// MaintenanceWindows returns the maintenance windows in progress and
+// upcoming.
+func (c *Client) MaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	var v []MaintenanceWindow
+	return v, c.do(ctx, http.MethodGet, "/api/v1/maintenance-windows", nil, &v)
+}
+
+// MaintenanceWindowOptions select the nodes of a maintenance window, by the
+// glob patterns of their names or by their labels as key=value, and its time
+// range: from Start, now when zero, to End or for Duration.
+type MaintenanceWindowOptions struct {
+	Nodes    []string
+	Labels   []string
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Reason   string
+}
+
+// ScheduleMaintenanceWindow schedules a maintenance window, replacing the
+// previous one with the same ID. It requires the admin token.
+func (c *Client) ScheduleMaintenanceWindow(ctx context.Context, id string, opts MaintenanceWindowOptions) (*MaintenanceWindow, error) {
+	q := url.Values{"node": opts.Nodes, "label": opts.Labels}
+	if !opts.Start.IsZero() {
+		q.Set("start", opts.Start.Format(time.RFC3339))
+	}
+	if !opts.End.IsZero() {
+		q.Set("end", opts.End.Format(time.RFC3339))
+	}
+	if opts.Duration > 0 {
+		q.Set("duration", opts.Duration.String())
+	}
+	if opts.Reason != "" {
+		q.Set("reason", opts.Reason)
+	}
+	var v MaintenanceWindow
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/maintenance-windows/"+url.PathEscape(id), q, &v)
+}
+
+// CancelMaintenanceWindow ends a maintenance window early. It requires the
+// admin token.
+func (c *Client) CancelMaintenanceWindow(ctx context.Context, id string) (*MaintenanceWindow, error) {
+	var v MaintenanceWindow
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/maintenance-windows/"+url.PathEscape(id), url.Values{"cancel": {"true"}}, &v)
+}
+
+// Views returns the views of the containers table saved on the node by the
+// user of the client, along with the shared views.
+func (c *Client) Views(ctx context.Context) ([]View, error) {
+	var v []View
+	return v, c.do(ctx, http.MethodGet, "/api/v1/views", nil, &v)
+}
+
+// SaveView saves a view of the containers table on the node, restored by the
+// query string of the index page, for the user of the client or shared.
+func (c *Client) SaveView(ctx context.Context, name, query string, shared bool) (*View, error) {
+	q := url.Values{"query": {query}}
+	if shared {
+		q.Set("shared", "true")
+	}
+	var v View
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/views/"+url.PathEscape(name), q, &v)
+}
+
+// DeleteView deletes a view saved on the node, for the user of the client or
+// shared.
+func (c *Client) DeleteView(ctx context.Context, name string, shared bool) (*View, error) {
+	q := url.Values{"delete": {"true"}}
+	if shared {
+		q.Set("shared", "true")
+	}
+	var v View
+	return &v, c.do(ctx, http.MethodPut, "/api/v1/views/"+url.PathEscape(name), q, &v)
+}
+
+// ContainersParquet returns the containers of each node in the given state
+// as a Parquet file, one row per container.
+func (c *Client) ContainersParquet(ctx context.Context, state string) ([]byte, error) {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", state)
+	}
+	return c.raw(ctx, http.MethodGet, "/api/v1/containers.parquet", q)
+}
+
+// Shards returns the shards of the nodes and the members aggregating them,
+// when the collection is sharded.
+func (c *Client) Shards(ctx context.Context) ([]ShardInfo, error) {
+	var v []ShardInfo
+	return v, c.do(ctx, http.MethodGet, "/api/v1/shards", nil, &v)
+}
+
+// HistoryParquet returns the samples of all the tiers of the history as a
+// Parquet file, one row per sample.
+func (c *Client) HistoryParquet(ctx context.Context) ([]byte, error) {
+	return c.raw(ctx, http.MethodGet, "/api/v1/history.parquet", nil)
+}
+
+// ContainersUsage returns the latest resource usage samples of the running
+// containers of each node.
+func (c *Client) ContainersUsage(ctx context.Context) ([]*NodeResources[ContainerUsage], error) {
+	var v []*NodeResources[ContainerUsage]
+	return v, c.do(ctx, http.MethodGet, "/api/v1/usage/containers", nil, &v)
+}
+
+// SBOMs returns the SBOMs of the running images.
+func (c *Client) SBOMs(ctx context.Context) ([]SBOMRef, error) {
+	var v []SBOMRef
+	return v, c.do(ctx, http.MethodGet, "/api/v1/sboms", nil, &v)
+}
+
+// Policies returns the compliance policies checked against the running
+// containers.
+func (c *Client) Policies(ctx context.Context) ([]Policy, error) {
+	var v []Policy
+	return v, c.do(ctx, http.MethodGet, "/api/v1/policies", nil, &v)
+}
+
+// Violations returns the policy violations of the running containers, per
+// node.
+func (c *Client) Violations(ctx context.Context) ([]NodeViolations, error) {
+	var v []NodeViolations
+	return v, c.do(ctx, http.MethodGet, "/api/v1/violations", nil, &v)
+}
+
+// RestartLoops returns the containers flagged as restart loops, grouped
+// across nodes by image, or by name when by is name.
+func (c *Client) RestartLoops(ctx context.Context, by string) ([]RestartLoopGroup, error) {
+	q := url.Values{}
+	if by != "" {
+		q.Set("by", by)
+	}
+	var v []RestartLoopGroup
+	return v, c.do(ctx, http.MethodGet, "/api/v1/reports/restart-loops", q, &v)
+}
+
+// AllowlistReport returns the running containers whose image is outside the
+// image allowlist, per node.
+func (c *Client) AllowlistReport(ctx context.Context) (*AllowlistReport, error) {
+	var v AllowlistReport
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/reports/allowlist", nil, &v)
+}
+
+// AllowlistReportCSV returns the running containers whose image is outside
+// the image allowlist as CSV, for compliance audits.
+func (c *Client) AllowlistReportCSV(ctx context.Context) ([]byte, error) {
+	return c.raw(ctx, http.MethodGet, "/api/v1/reports/allowlist", url.Values{"format": {"csv"}})
+}
+
+// Costs returns the estimated hourly cost of the running containers, grouped
+// by the cost label.
+func (c *Client) Costs(ctx context.Context) (*CostReport, error) {
+	var v CostReport
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/costs", nil, &v)
+}
+
+// Update returns whether a newer release than the one running on the node is
+// available.
+func (c *Client) Update(ctx context.Context) (*UpdateStatus, error) {
+	var v UpdateStatus
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/update", nil, &v)
+}
+
+// Blocklist adds and removes peer names or CIDRs of the blocklist, returning
+// the blocklist. It requires the admin token.
+func (c *Client) Blocklist(ctx context.Context, add, remove []string) ([]string, error) {
+	var v []string
+	return v, c.do(ctx, http.MethodPost, "/api/v1/admin/blocklist", url.Values{"add": add, "remove": remove}, &v)
+}
+
+// Cordon suspends and resumes the polling of discovery sources of the node,
+// returning their status. It requires the admin token.
+func (c *Client) Cordon(ctx context.Context, cordon, uncordon []string, reason string) ([]SourceStatus, error) {
+	q := url.Values{"cordon": cordon, "uncordon": uncordon}
+	if reason != "" {
+		q.Set("reason", reason)
+	}
+	var v []SourceStatus
+	return v, c.do(ctx, http.MethodPost, "/api/v1/admin/cordon", q, &v)
+}
+
+// Decommission permanently removes the node from the cluster, which then
+// exits. It requires the admin token.
+func (c *Client) Decommission(ctx context.Context) (*DecommissionResult, error) {
+	var v DecommissionResult
+	return &v, c.do(ctx, http.MethodPost, "/api/v1/admin/decommission", nil, &v)
+}
+
+// Goroutines returns the stacks of the goroutines of a node, or of the node
+// receiving the request when empty, each of them when full is set instead of
+// grouping the identical ones. It requires the admin token.
+func (c *Client) Goroutines(ctx context.Context, node string, full bool) (string, error) {
+	q := url.Values{}
+	if node != "" {
+		q.Set("node", node)
+	}
+	if full {
+		q.Set("full", "true")
+	}
+	b, err := c.raw(ctx, http.MethodGet, "/api/v1/admin/debug/goroutines", q)
+	return string(b), err
+}
+
+// HeapProfile returns the heap profile of a node in the pprof format, after
+// a garbage collection when gc is set. It requires the admin token.
+func (c *Client) HeapProfile(ctx context.Context, node string, gc bool) ([]byte, error) {
+	q := url.Values{}
+	if node != "" {
+		q.Set("node", node)
+	}
+	if gc {
+		q.Set("gc", "true")
+	}
+	return c.raw(ctx, http.MethodGet, "/api/v1/admin/debug/heap", q)
+}
+
+// GCStats returns the statistics of the garbage collector and of the heap of
+// a node. It requires the admin token.
+func (c *Client) GCStats(ctx context.Context, node string) (*GCStats, error) {
+	q := url.Values{}
+	if node != "" {
+		q.Set("node", node)
+	}
+	var v GCStats
+	return &v, c.do(ctx, http.MethodGet, "/api/v1/admin/debug/gc", q, &v)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/status" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"type": "urn:containerslist:problem:degraded", "status": 503, "code": "degraded", "detail": "no runtime answered"}`))
+			return
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name string
+		call func(*Client) error
+		want Error
+	}{
+		{
+			name: "problem",
+			call: func(c *Client) error { _, err := c.Status(context.Background()); return err },
+			want: Error{StatusCode: http.StatusServiceUnavailable, Code: "degraded", Message: "no runtime answered"},
+		},
+		{
+			name: "plain text",
+			call: func(c *Client) error { _, err := c.Peers(context.Background()); return err },
+			want: Error{StatusCode: http.StatusBadGateway, Message: "bad gateway"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var apiErr *Error
+			if err := tc.call(New(srv.URL + "/")); !errors.As(err, &apiErr) || *apiErr != tc.want {
+				t.Errorf("error = %v, want %+v", err, tc.want)
+			}
+		})
+	}
+}
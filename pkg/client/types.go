@@ -0,0 +1,648 @@
+package client
+
+import "time"
+
+// Signature is the Ed25519 signature of a gossiped node entry.
+type Signature struct {
+	Key []byte `json:"key"`
+	Sig []byte `json:"sig"`
+}
+
+// SourceStatus is the health of a source of the inventory of a node.
+type SourceStatus struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Cordoned            bool       `json:"cordoned,omitempty"`
+	CordonReason        string     `json:"cordonReason,omitempty"`
+	CordonedAt          *time.Time `json:"cordonedAt,omitempty"`
+}
+
+// PartitionStatus compares the size of the cluster seen by a node against its
+// expected size.
+type PartitionStatus struct {
+	Members  int  `json:"members"`
+	Expected int  `json:"expected"`
+	Minority bool `json:"minority"`
+}
+
+// Status is the status of a node.
+type Status struct {
+	Node      string          `json:"node"`
+	Cluster   string          `json:"cluster"`
+	Degraded  bool            `json:"degraded"`
+	Sources   []SourceStatus  `json:"sources"`
+	Partition PartitionStatus `json:"partition"`
+	Breakers  []BreakerStatus `json:"breakers"`
+	Version   string          `json:"version"`
+	Update    *UpdateStatus   `json:"update,omitempty"`
+}
+
+// UpdateStatus reports whether a newer release than the one running on a
+// node is available.
+type UpdateStatus struct {
+	Current    string    `json:"current"`
+	Latest     string    `json:"latest,omitempty"`
+	Available  bool      `json:"available"`
+	Notes      string    `json:"notes,omitempty"`
+	Checked    time.Time `json:"checked"`
+	Error      string    `json:"error,omitempty"`
+	AutoUpdate bool      `json:"autoUpdate"`
+	Installed  string    `json:"installed,omitempty"`
+}
+
+// BreakerStatus is the state of the circuit breaker of a dependency of a
+// node: "closed", "open" or "half-open".
+type BreakerStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	Rejected            int        `json:"rejected"`
+}
+
+// NodeFacts describes the operating system and container runtime of a node.
+type NodeFacts struct {
+	Node             string            `json:"node"`
+	Timestamp        time.Time         `json:"timestamp"`
+	Peer             string            `json:"peer"`
+	OS               string            `json:"os"`
+	Kernel           string            `json:"kernel"`
+	Architecture     string            `json:"architecture"`
+	Runtime          string            `json:"runtime"`
+	RuntimeVersion   string            `json:"runtimeVersion"`
+	CgroupVersion    string            `json:"cgroupVersion"`
+	CPUs             int               `json:"cpus,omitempty"`
+	InternalAddress  string            `json:"internalAddress,omitempty"`
+	InternalIdentity string            `json:"internalIdentity,omitempty"`
+	Zone             string            `json:"zone,omitempty"`
+	DaemonID         string            `json:"daemonID,omitempty"`
+	Fingerprint      string            `json:"fingerprint,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Passive          bool              `json:"passive,omitempty"`
+	InventoryProto   bool              `json:"inventoryProto,omitempty"`
+	Left             bool              `json:"left,omitempty"`
+	Outdated         bool              `json:"outdated"`
+	Signature        *Signature        `json:"signature,omitempty"`
+}
+
+// SerfMember is a member of the cluster in the format of the
+// serf members -format json command.
+type SerfMember struct {
+	Name     string            `json:"name"`
+	Addr     string            `json:"addr"`
+	Port     uint16            `json:"port"`
+	Tags     map[string]string `json:"tags"`
+	Status   string            `json:"status"`
+	Protocol map[string]uint8  `json:"protocol"`
+}
+
+// SerfMembers is the output of the serf members -format json command.
+type SerfMembers struct {
+	Members []SerfMember `json:"members"`
+}
+
+// PeerInfo is a member of the cluster.
+type PeerInfo struct {
+	Name             string     `json:"name"`
+	DisplayName      string     `json:"displayName"`
+	Address          string     `json:"address"`
+	Node             string     `json:"node,omitempty"`
+	Facts            *NodeFacts `json:"facts,omitempty"`
+	ClockSkewSeconds *float64   `json:"clockSkewSeconds,omitempty"`
+	Usage            *NodeUsage `json:"usage,omitempty"`
+	Passive          bool       `json:"passive,omitempty"`
+}
+
+// NodeUsage is the CPU, memory and disk usage of the host of a node.
+type NodeUsage struct {
+	Node             string     `json:"node"`
+	Timestamp        time.Time  `json:"timestamp"`
+	CPUPercent       float64    `json:"cpuPercent"`
+	MemoryPercent    float64    `json:"memoryPercent"`
+	MemoryTotalBytes uint64     `json:"memoryTotalBytes"`
+	DiskPercent      float64    `json:"diskPercent"`
+	DiskTotalBytes   uint64     `json:"diskTotalBytes"`
+	Signature        *Signature `json:"signature,omitempty"`
+}
+
+// Port is a port published by a container.
+type Port struct {
+	HostIP        string `json:"hostIP"`
+	HostPort      uint16 `json:"hostPort"`
+	ContainerPort uint16 `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// Device is a host device mapped into a container.
+type Device struct {
+	HostPath      string `json:"hostPath"`
+	ContainerPath string `json:"containerPath"`
+}
+
+// Pod is the Kubernetes pod a container belongs to.
+type Pod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+	Container string `json:"container"`
+	OwnerKind string `json:"ownerKind,omitempty"`
+	OwnerName string `json:"ownerName,omitempty"`
+}
+
+// Container is a container of a node.
+type Container struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Image      string            `json:"image"`
+	ImageID    string            `json:"imageID"`
+	State      string            `json:"state"`
+	Status     string            `json:"status"`
+	Created    time.Time         `json:"created"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Devices    []Device          `json:"devices,omitempty"`
+	GPUs       []string          `json:"gpus,omitempty"`
+	Ports      []Port            `json:"ports,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Pod        *Pod              `json:"pod,omitempty"`
+	// Annotations are typed values attached by enrichers: strings,
+	// float64 numbers or booleans. Unknown annotations should be ignored.
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	RestartCount int        `json:"restartCount,omitempty"`
+	OOMKilled    bool       `json:"oomKilled,omitempty"`
+	Flags        []string   `json:"flags,omitempty"`
+}
+
+// NodeInventory is the list of containers of a node.
+type NodeInventory struct {
+	Node            string         `json:"node"`
+	Timestamp       time.Time      `json:"timestamp"`
+	Containers      []Container    `json:"containers"`
+	Degraded        bool           `json:"degraded"`
+	Truncated       bool           `json:"truncated,omitempty"`
+	TotalContainers int            `json:"totalContainers,omitempty"`
+	ImageCounts     map[string]int `json:"imageCounts,omitempty"`
+	AgeSeconds      float64        `json:"ageSeconds"`
+	Stale           bool           `json:"stale"`
+	Zone            string         `json:"zone,omitempty"`
+	CrossZone       bool           `json:"crossZone,omitempty"`
+	Edge            bool           `json:"edge,omitempty"`
+	Offline         bool           `json:"offline,omitempty"`
+	Summary         bool           `json:"summary,omitempty"`
+	Decommissioned  bool           `json:"decommissioned,omitempty"`
+	Revision        uint64         `json:"revision,omitempty"`
+	Signature       *Signature     `json:"signature,omitempty"`
+}
+
+// ContainerEvent reports a container appearing in or disappearing from the
+// inventory of a node, or reported by several nodes.
+type ContainerEvent struct {
+	Type      string             `json:"type"`
+	Node      string             `json:"node"`
+	Time      time.Time          `json:"time"`
+	Container Container          `json:"container"`
+	Conflict  *ContainerConflict `json:"conflict,omitempty"`
+}
+
+// ContainerClaim is a node reporting a container.
+type ContainerClaim struct {
+	Node      string    `json:"node"`
+	Container Container `json:"container"`
+}
+
+// ContainerConflict is a container ID reported by several nodes.
+type ContainerConflict struct {
+	ID       string           `json:"id"`
+	Kept     ContainerClaim   `json:"kept"`
+	Dropped  []ContainerClaim `json:"dropped"`
+	Detected time.Time        `json:"detected"`
+}
+
+// Sample is the value of a series at a point in time.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series is the history of the running containers of a node, or of the
+// cluster when Node is empty.
+type Series struct {
+	Node    string   `json:"node,omitempty"`
+	Samples []Sample `json:"samples"`
+}
+
+// History is the history of the running containers.
+type History struct {
+	Tier       string   `json:"tier"`
+	Resolution string   `json:"resolution"`
+	Retention  string   `json:"retention"`
+	Cluster    Series   `json:"cluster"`
+	Nodes      []Series `json:"nodes"`
+}
+
+// GPUUsage is a GPU attached to a container.
+type GPUUsage struct {
+	Node      string   `json:"node"`
+	Container string   `json:"container"`
+	Name      string   `json:"name"`
+	Image     string   `json:"image"`
+	GPUs      []string `json:"gpus"`
+}
+
+// PortUsage is a host port and the containers publishing it.
+type PortUsage struct {
+	HostIP     string   `json:"hostIP"`
+	HostPort   uint16   `json:"hostPort"`
+	Protocol   string   `json:"protocol"`
+	Containers []string `json:"containers"`
+	Conflict   bool     `json:"conflict"`
+}
+
+// NodePorts is the usage of the host ports of a node.
+type NodePorts struct {
+	Node           string      `json:"node"`
+	Ports          []PortUsage `json:"ports"`
+	Conflicts      int         `json:"conflicts"`
+	Used           int         `json:"used"`
+	UsagePercent   float64     `json:"usagePercent"`
+	NearExhaustion bool        `json:"nearExhaustion"`
+}
+
+// Network is a network of a node.
+type Network struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Driver   string `json:"driver"`
+	Scope    string `json:"scope"`
+	Internal bool   `json:"internal"`
+}
+
+// Volume is a volume of a node.
+type Volume struct {
+	Name       string `json:"name"`
+	Driver     string `json:"driver"`
+	Mountpoint string `json:"mountpoint"`
+	Scope      string `json:"scope"`
+}
+
+// Image is an image of a node.
+type Image struct {
+	ID         string    `json:"id"`
+	Tags       []string  `json:"tags,omitempty"`
+	Size       int64     `json:"size"`
+	Created    time.Time `json:"created"`
+	Containers int       `json:"containers"`
+}
+
+// PruneCandidate is a container or image recommended for pruning.
+type PruneCandidate struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// NodeResources are the resources of a kind of a node.
+type NodeResources[T any] struct {
+	Node      string     `json:"node"`
+	Timestamp time.Time  `json:"timestamp"`
+	Items     []T        `json:"items"`
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+// ImagesReport lists the images of each node, with the unused ones.
+type ImagesReport struct {
+	Nodes       []*NodeResources[Image] `json:"nodes"`
+	Unused      int                     `json:"unused"`
+	UnusedBytes int64                   `json:"unusedBytes"`
+}
+
+// ServiceHealth is the health of a service of a project.
+type ServiceHealth struct {
+	Name     string   `json:"name"`
+	Expected int      `json:"expected"`
+	Running  int      `json:"running"`
+	Nodes    []string `json:"nodes"`
+}
+
+// Project is a Compose project or Swarm stack.
+type Project struct {
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`
+	Services []ServiceHealth `json:"services"`
+	Healthy  bool            `json:"healthy"`
+}
+
+// SearchResult is a container or node matching a search.
+type SearchResult struct {
+	Node      string     `json:"node"`
+	Container *Container `json:"container,omitempty"`
+	Field     string     `json:"field"`
+	Match     string     `json:"match"`
+	Score     int        `json:"score"`
+}
+
+// SearchGroup gathers search results of the same kind.
+type SearchGroup struct {
+	Name    string         `json:"name"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResults are the results of a search.
+type SearchResults struct {
+	Query  string        `json:"query"`
+	Groups []SearchGroup `json:"groups"`
+}
+
+// NodeContainerUsage is the latest resource usage sample of a running
+// container.
+type NodeContainerUsage struct {
+	Node             string  `json:"node"`
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryBytes      int64   `json:"memoryBytes"`
+	MemoryLimitBytes int64   `json:"memoryLimitBytes,omitempty"`
+}
+
+// PackageMatch is a package found in the SBOM of a running image, along with
+// the names of the containers running the image on each node.
+type PackageMatch struct {
+	Name    string              `json:"name"`
+	Version string              `json:"version,omitempty"`
+	Image   string              `json:"image"`
+	ImageID string              `json:"imageID"`
+	Nodes   map[string][]string `json:"nodes"`
+}
+
+// PruneResult is the result of pruning a node.
+type PruneResult struct {
+	Node              string   `json:"node"`
+	ContainersDeleted []string `json:"containersDeleted"`
+	ImagesDeleted     []string `json:"imagesDeleted"`
+	SpaceReclaimed    int64    `json:"spaceReclaimed"`
+}
+
+// APIKey is an API key of a node, granting the access of its role: viewer,
+// editor or admin.
+type APIKey struct {
+	Name    string     `json:"name"`
+	Role    string     `json:"role"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// CreatedAPIKey is an API key along with the key, only returned on creation.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Changes are the inventories of the nodes changed since a sequence of the
+// member answering, including the tombstones of the decommissioned nodes.
+// When Full is set, all the inventories are listed.
+type Changes struct {
+	Epoch    string           `json:"epoch"`
+	Sequence uint64           `json:"sequence"`
+	Revision uint64           `json:"revision"`
+	Full     bool             `json:"full"`
+	Nodes    []*NodeInventory `json:"nodes"`
+}
+
+// SelfAlert is an unhealthy condition of an agent, with a remediation hint.
+type SelfAlert struct {
+	Name        string `json:"name"`
+	Severity    string `json:"severity"`
+	Summary     string `json:"summary"`
+	Remediation string `json:"remediation"`
+	Node        string `json:"node"`
+}
+
+// JobStatus is the status of a scheduled job of a node.
+type JobStatus struct {
+	Name                string     `json:"name"`
+	Schedule            string     `json:"schedule"`
+	Running             bool       `json:"running"`
+	NextRun             *time.Time `json:"nextRun,omitempty"`
+	LastRun             *time.Time `json:"lastRun,omitempty"`
+	LastDurationSeconds float64    `json:"lastDurationSeconds"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	Runs                int        `json:"runs"`
+	Failures            int        `json:"failures"`
+	Skipped             int        `json:"skipped"`
+}
+
+// GrafanaDashboard is a Grafana dashboard of the metrics of the nodes, whose
+// JSON model is served at URL.
+type GrafanaDashboard struct {
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	URL         string   `json:"url"`
+}
+
+// AnnotationSchema is the registered schema of a container annotation.
+type AnnotationSchema struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// PeerStats are the gossip statistics of a member of the cluster.
+type PeerStats struct {
+	Peer             string     `json:"peer"`
+	DisplayName      string     `json:"displayName"`
+	Nodes            []string   `json:"nodes"`
+	UpdatesReceived  int        `json:"updatesReceived"`
+	MergeConflicts   int        `json:"mergeConflicts"`
+	RejectedPayloads int        `json:"rejectedPayloads"`
+	LastUpdate       *time.Time `json:"lastUpdate,omitempty"`
+	StateBytes       int        `json:"stateBytes"`
+}
+
+// EphemeralAnnotation is a note attached to a container until it expires.
+type EphemeralAnnotation struct {
+	Node        string    `json:"node"`
+	ContainerID string    `json:"containerID"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link,omitempty"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// MaintenanceWindow silences the self-alerts and the policy violations of
+// the nodes matching its node patterns or labels.
+type MaintenanceWindow struct {
+	ID        string            `json:"id"`
+	Nodes     []string          `json:"nodes,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Cancelled bool              `json:"cancelled,omitempty"`
+	Updated   time.Time         `json:"updated"`
+}
+
+// View is a saved view of the containers table, restored by its query
+// string. Owner is empty for the shared views.
+type View struct {
+	Name    string    `json:"name"`
+	Query   string    `json:"query"`
+	Owner   string    `json:"owner,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// ShardInfo is a shard of the nodes and the members aggregating it.
+type ShardInfo struct {
+	Shard  int      `json:"shard"`
+	Owners []string `json:"owners"`
+	Nodes  []string `json:"nodes"`
+}
+
+// ContainerUsage is a resource usage sample of a running container.
+type ContainerUsage struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryBytes      int64   `json:"memoryBytes"`
+	MemoryLimitBytes int64   `json:"memoryLimitBytes,omitempty"`
+}
+
+// SBOMRef is the SBOM of a running image.
+type SBOMRef struct {
+	Image     string    `json:"image"`
+	ImageID   string    `json:"imageID"`
+	Format    string    `json:"format,omitempty"`
+	Digest    string    `json:"digest,omitempty"`
+	Packages  int       `json:"packages"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Policy is a compliance policy checked against the running containers.
+type Policy struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Arg is the label of a required-label policy, the comma-separated
+	// registries of an approved-registries policy, or the Rego module file
+	// of a rego policy.
+	Arg string `json:"arg,omitempty"`
+}
+
+// Violation is a running container violating a policy.
+type Violation struct {
+	Policy      string `json:"policy"`
+	Container   string `json:"container"`
+	ContainerID string `json:"containerID"`
+	Image       string `json:"image"`
+	Message     string `json:"message"`
+}
+
+// NodeViolations are the policy violations of the containers of a node.
+type NodeViolations struct {
+	Node       string      `json:"node"`
+	Violations []Violation `json:"violations"`
+}
+
+// RestartLoopGroup are the containers of an image or a name looping on
+// Looping of the Running nodes running them.
+type RestartLoopGroup struct {
+	Key        string   `json:"key"`
+	Nodes      []string `json:"nodes"`
+	Looping    int      `json:"looping"`
+	Running    int      `json:"running"`
+	Containers int      `json:"containers"`
+}
+
+// DisallowedContainer is a running container whose image is outside the
+// image allowlist.
+type DisallowedContainer struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Image    string `json:"image"`
+	Registry string `json:"registry"`
+}
+
+// AllowlistNode are the running containers of a node outside the image
+// allowlist.
+type AllowlistNode struct {
+	Node       string                `json:"node"`
+	Running    int                   `json:"running"`
+	Outside    int                   `json:"outside"`
+	Containers []DisallowedContainer `json:"containers"`
+}
+
+// AllowlistReport are the running containers outside the image allowlist.
+type AllowlistReport struct {
+	Patterns []string        `json:"patterns"`
+	Nodes    []AllowlistNode `json:"nodes"`
+	Running  int             `json:"running"`
+	Outside  int             `json:"outside"`
+}
+
+// NodeCost is the estimated hourly cost of a node.
+type NodeCost struct {
+	Node    string  `json:"node"`
+	CPUs    int     `json:"cpus,omitempty"`
+	Hourly  float64 `json:"hourly"`
+	Running int     `json:"running"`
+}
+
+// CostGroup is the estimated hourly cost of the containers of a value of the
+// cost label.
+type CostGroup struct {
+	Group      string  `json:"group"`
+	Hourly     float64 `json:"hourly"`
+	Containers int     `json:"containers"`
+}
+
+// ContainerCost is the estimated hourly cost of a running container.
+type ContainerCost struct {
+	Node   string  `json:"node"`
+	Name   string  `json:"name"`
+	ID     string  `json:"id"`
+	Group  string  `json:"group"`
+	Hourly float64 `json:"hourly"`
+}
+
+// CostReport is the estimated hourly cost of the running containers,
+// grouped by the cost label.
+type CostReport struct {
+	Label      string          `json:"label"`
+	Hourly     float64         `json:"hourly"`
+	Nodes      []NodeCost      `json:"nodes"`
+	Groups     []CostGroup     `json:"groups"`
+	Containers []ContainerCost `json:"containers"`
+}
+
+// DecommissionResult is the result of decommissioning a node: the peers
+// which acknowledged the tombstones of its nodes, out of the required ones.
+type DecommissionResult struct {
+	Nodes    []string `json:"nodes"`
+	Acks     []string `json:"acks"`
+	Required int      `json:"required"`
+	TimedOut bool     `json:"timedOut"`
+}
+
+// GCStats are the statistics of the garbage collector and of the heap of a
+// node.
+type GCStats struct {
+	Node                string    `json:"node"`
+	Goroutines          int       `json:"goroutines"`
+	NumGC               int64     `json:"numGC"`
+	LastGC              time.Time `json:"lastGC"`
+	PauseTotalSeconds   float64   `json:"pauseTotalSeconds"`
+	RecentPausesSeconds []float64 `json:"recentPausesSeconds"`
+	HeapAllocBytes      uint64    `json:"heapAllocBytes"`
+	HeapInuseBytes      uint64    `json:"heapInuseBytes"`
+	HeapObjects         uint64    `json:"heapObjects"`
+	NextGCBytes         uint64    `json:"nextGCBytes"`
+	SysBytes            uint64    `json:"sysBytes"`
+}
@@ -0,0 +1,165 @@
+package containerslist
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// allowlistPattern matches the images of a registry whose repository matches
+// a glob pattern.
+type allowlistPattern struct {
+	Registry   string
+	Repository string
+}
+
+// parseAllowlistPattern parses a registry, such as ghcr.io, or a registry
+// and repository glob pattern, such as docker.io/library/* or ghcr.io/acme/*.
+// Patterns without registry match the Docker Hub.
+func parseAllowlistPattern(s string) (allowlistPattern, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return allowlistPattern{}, errors.New("empty image allowlist pattern")
+	}
+	host, repo, ok := strings.Cut(s, "/")
+	isHost := strings.ContainsAny(host, ".:") || host == "localhost"
+	switch {
+	case !ok && isHost:
+		return allowlistPattern{Registry: normalizeRegistry(host), Repository: "*"}, nil
+	case !isHost:
+		host, repo = defaultRegistry, s
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+	}
+	if _, err := path.Match(repo, ""); err != nil {
+		return allowlistPattern{}, fmt.Errorf("invalid image allowlist pattern %q: %w", s, err)
+	}
+	return allowlistPattern{Registry: normalizeRegistry(host), Repository: repo}, nil
+}
+
+// imageAllowlist are the registries and images which may run in the cluster.
+type imageAllowlist []allowlistPattern
+
+func parseImageAllowlist(s string) (imageAllowlist, error) {
+	var l imageAllowlist
+	if s == "" {
+		return l, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		pattern, err := parseAllowlistPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, pattern)
+	}
+	return l, nil
+}
+
+// Allowed reports whether an image matches one of the patterns. The patterns
+// of a whole registry match its repositories at any depth.
+func (l imageAllowlist) Allowed(image string) bool {
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return false
+	}
+	registry := normalizeRegistry(ref.Registry)
+	for _, p := range l {
+		if p.Registry != registry {
+			continue
+		}
+		if ok, _ := path.Match(p.Repository, ref.Repository); ok || p.Repository == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowlistReport lists the running containers whose image is outside the
+// image allowlist.
+type AllowlistReport struct {
+	Patterns []string        `json:"patterns"`
+	Nodes    []AllowlistNode `json:"nodes"`
+	Running  int             `json:"running"`
+	Outside  int             `json:"outside"`
+}
+
+// AllowlistNode counts the running containers of a node whose image is
+// outside the allowlist.
+type AllowlistNode struct {
+	Node       string                `json:"node"`
+	Running    int                   `json:"running"`
+	Outside    int                   `json:"outside"`
+	Containers []DisallowedContainer `json:"containers"`
+}
+
+// DisallowedContainer is a running container whose image is outside the
+// allowlist.
+type DisallowedContainer struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Image    string `json:"image"`
+	Registry string `json:"registry"`
+}
+
+// allowlistReport checks the running containers of the cluster against the
+// image allowlist.
+func (m *Manager) allowlistReport() AllowlistReport {
+	r := AllowlistReport{Patterns: []string{}, Nodes: []AllowlistNode{}}
+	for _, p := range m.imageAllowlist {
+		r.Patterns = append(r.Patterns, p.Registry+"/"+p.Repository)
+	}
+	if len(m.imageAllowlist) == 0 {
+		return r
+	}
+	for _, n := range m.inventory.Nodes() {
+		an := AllowlistNode{Node: n.Node, Containers: []DisallowedContainer{}}
+		for _, c := range n.Containers {
+			if c.State != StateRunning {
+				continue
+			}
+			an.Running++
+			if m.imageAllowlist.Allowed(c.Image) {
+				continue
+			}
+			an.Outside++
+			dc := DisallowedContainer{Name: c.Name, ID: c.ID, Image: c.Image}
+			if ref, err := parseImageRef(c.Image); err == nil {
+				dc.Registry = ref.Registry
+			}
+			an.Containers = append(an.Containers, dc)
+		}
+		r.Running += an.Running
+		r.Outside += an.Outside
+		r.Nodes = append(r.Nodes, an)
+	}
+	return r
+}
+
+func (m *Manager) apiAllowlistReport() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := m.allowlistReport()
+		switch format := r.URL.Query().Get("format"); format {
+		case "":
+			writeJSON(w, report)
+		case "csv":
+			// The CSV export has one line per container outside the
+			// allowlist, for compliance audits.
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="image-allowlist-report.csv"`)
+			cw := csv.NewWriter(w)
+			cw.Write([]string{"node", "container", "container_id", "image", "registry"})
+			for _, n := range report.Nodes {
+				for _, c := range n.Containers {
+					cw.Write([]string{n.Node, c.Name, c.ID, c.Image, c.Registry})
+				}
+			}
+			cw.Flush()
+		default:
+			writeProblem(w, r, problemf(ErrInvalidRequest, "invalid format %q: must be csv", format))
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImageAllowlist(t *testing.T) {
+	l, err := parseImageAllowlist("ghcr.io, docker.io/library/*, grafana/*, registry.example.com/shop/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []allowlistPattern{
+		{Registry: "ghcr.io", Repository: "*"},
+		{Registry: defaultRegistry, Repository: "library/*"},
+		{Registry: defaultRegistry, Repository: "grafana/*"},
+		{Registry: "registry.example.com", Repository: "shop/api"},
+	}
+	if len(l) != len(want) {
+		t.Fatalf("patterns = %+v, want %+v", l, want)
+	}
+	for i := range want {
+		if l[i] != want[i] {
+			t.Errorf("pattern %d = %+v, want %+v", i, l[i], want[i])
+		}
+	}
+
+	for image, allowed := range map[string]bool{
+		"ghcr.io/acme/app:1.0":                   true,
+		"nginx:1.25":                             true,
+		"docker.io/library/redis":                true,
+		"grafana/grafana:10.4.0":                 true,
+		"registry.example.com/shop/api:2.3.1":    true,
+		"registry.example.com/shop/worker:2.3.1": false,
+		"prom/prometheus:v2.51.0":                false,
+		"quay.io/prometheus/node-exporter":       false,
+		"@sha256:abc":                            false,
+	} {
+		if got := l.Allowed(image); got != allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", image, got, allowed)
+		}
+	}
+
+	for _, s := range []string{"ghcr.io,", "ghcr.io/[acme"} {
+		if _, err := parseImageAllowlist(s); err == nil {
+			t.Errorf("parseImageAllowlist(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestAPIAllowlistReport(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{
+			{ID: "a1", Name: "web", Image: "nginx:1.25", State: StateRunning},
+			{ID: "a2", Name: "exporter", Image: "quay.io/prometheus/node-exporter", State: StateRunning},
+			{ID: "a3", Name: "job", Image: "quay.io/acme/job", State: "exited"},
+		}},
+		&NodeInventory{Node: "b", Containers: []Container{
+			{ID: "b1", Name: "api", Image: "ghcr.io/acme/api:1.0", State: StateRunning},
+		}},
+	)
+
+	// Without allowlist, nothing is reported.
+	if r := m.allowlistReport(); len(r.Nodes) != 0 || r.Running != 0 {
+		t.Errorf("report = %+v, want none without allowlist", r)
+	}
+
+	var err error
+	if m.imageAllowlist, err = parseImageAllowlist("ghcr.io,nginx"); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	m.apiAllowlistReport().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/allowlist", nil))
+	var r AllowlistReport
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Running != 3 || r.Outside != 1 || len(r.Nodes) != 2 || r.Nodes[0].Outside != 1 || r.Nodes[1].Outside != 0 {
+		t.Errorf("report = %+v, want 1 of 3 running containers outside the allowlist, on a", r)
+	}
+	if c := r.Nodes[0].Containers; len(c) != 1 || c[0] != (DisallowedContainer{Name: "exporter", ID: "a2", Image: "quay.io/prometheus/node-exporter", Registry: "quay.io"}) {
+		t.Errorf("containers outside the allowlist = %+v, want the exporter", c)
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiAllowlistReport().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/allowlist?format=csv", nil))
+	if want := "node,container,container_id,image,registry\na,exporter,a2,quay.io/prometheus/node-exporter,quay.io\n"; rec.Body.String() != want {
+		t.Errorf("CSV = %q, want %q", rec.Body.String(), want)
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiAllowlistReport().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/allowlist?format=xml", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status of an invalid format = %d, want 400", rec.Code)
+	}
+}
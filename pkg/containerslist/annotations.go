@@ -0,0 +1,171 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of annotation values.
+const (
+	AnnotationString = "string"
+	AnnotationNumber = "number"
+	AnnotationBool   = "bool"
+)
+
+// annotationLabelPrefix prefixes the container labels turned into
+// annotations, such as containerslist.io/annotation.cost-center.
+const annotationLabelPrefix = "containerslist.io/annotation."
+
+// AnnotationValue is the typed value of an annotation, encoded in JSON as a
+// string, a number or a boolean.
+type AnnotationValue struct {
+	Type   string
+	String string
+	Number float64
+	Bool   bool
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v AnnotationValue) MarshalJSON() ([]byte, error) {
+	switch v.Type {
+	case AnnotationNumber:
+		return json.Marshal(v.Number)
+	case AnnotationBool:
+		return json.Marshal(v.Bool)
+	default:
+		return json.Marshal(v.String)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *AnnotationValue) UnmarshalJSON(b []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	switch x := raw.(type) {
+	case string:
+		*v = AnnotationValue{Type: AnnotationString, String: x}
+	case float64:
+		*v = AnnotationValue{Type: AnnotationNumber, Number: x}
+	case bool:
+		*v = AnnotationValue{Type: AnnotationBool, Bool: x}
+	default:
+		return fmt.Errorf("invalid annotation value %s: must be a string, a number or a boolean", b)
+	}
+	return nil
+}
+
+// parseAnnotationValue parses the textual value of an annotation of the given
+// type.
+func parseAnnotationValue(typ, s string) (AnnotationValue, error) {
+	switch typ {
+	case AnnotationString:
+		return AnnotationValue{Type: typ, String: s}, nil
+	case AnnotationNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		return AnnotationValue{Type: typ, Number: f}, err
+	case AnnotationBool:
+		b, err := strconv.ParseBool(s)
+		return AnnotationValue{Type: typ, Bool: b}, err
+	}
+	return AnnotationValue{}, fmt.Errorf("invalid annotation type %q", typ)
+}
+
+// AnnotationSchema describes an annotation which enrichers can attach to the
+// containers. Annotations without a registered schema, or whose value does
+// not have the registered type, are dropped from the local inventory;
+// consumers must ignore the annotations they do not know.
+type AnnotationSchema struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// annotationSchemas are the registered annotation schemas, by name.
+type annotationSchemas map[string]AnnotationSchema
+
+// String implements flag.Value.
+func (s annotationSchemas) String() string {
+	specs := make([]string, 0, len(s))
+	for _, a := range s {
+		specs = append(specs, a.Name+"="+a.Type)
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a name=type[:description] schema.
+func (s annotationSchemas) Set(spec string) error {
+	name, rest, ok := strings.Cut(spec, "=")
+	typ, description, _ := strings.Cut(rest, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid annotation schema %q: must be name=type[:description]", spec)
+	}
+	return s.Register(AnnotationSchema{Name: name, Type: typ, Description: description})
+}
+
+// Register registers the schema of an annotation.
+func (s annotationSchemas) Register(a AnnotationSchema) error {
+	switch a.Type {
+	case AnnotationString, AnnotationNumber, AnnotationBool:
+	default:
+		return fmt.Errorf("invalid type %q of annotation %s: must be one of %s, %s, %s", a.Type, a.Name, AnnotationString, AnnotationNumber, AnnotationBool)
+	}
+	if _, ok := s[a.Name]; ok {
+		return fmt.Errorf("annotation %s already registered", a.Name)
+	}
+	s[a.Name] = a
+	return nil
+}
+
+// List returns the schemas, sorted by name.
+func (s annotationSchemas) List() []AnnotationSchema {
+	list := make([]AnnotationSchema, 0, len(s))
+	for _, a := range s {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// annotate turns the annotation labels of a container into annotations, and
+// drops the annotations not matching their registered schema.
+func (s annotationSchemas) annotate(c *Container) {
+	for k, v := range c.Labels {
+		name, ok := strings.CutPrefix(k, annotationLabelPrefix)
+		if !ok {
+			continue
+		}
+		a, ok := s[name]
+		if !ok {
+			continue
+		}
+		if value, err := parseAnnotationValue(a.Type, v); err == nil {
+			if c.Annotations == nil {
+				c.Annotations = map[string]AnnotationValue{}
+			}
+			c.Annotations[name] = value
+		}
+	}
+	for name, v := range c.Annotations {
+		if a, ok := s[name]; !ok || a.Type != v.Type {
+			delete(c.Annotations, name)
+		}
+	}
+	if len(c.Annotations) == 0 {
+		c.Annotations = nil
+	}
+}
+
+func (m *Manager) apiAnnotations() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.cfg.AnnotationSchemas.List())
+	})
+}
@@ -0,0 +1,88 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnnotationValueJSON(t *testing.T) {
+	in := map[string]AnnotationValue{
+		"cost":     {Type: AnnotationNumber, Number: 1.5},
+		"critical": {Type: AnnotationBool, Bool: true},
+		"owner":    {Type: AnnotationString, String: "alice"},
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"cost":1.5,"critical":true,"owner":"alice"}`; string(b) != want {
+		t.Errorf("JSON = %s, want %s", b, want)
+	}
+	var out map[string]AnnotationValue
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("decoded = %+v, want %+v", out, in)
+	}
+	if err := json.Unmarshal([]byte(`{"tags": ["a"]}`), &out); err == nil {
+		t.Error("array value accepted, want an error")
+	}
+}
+
+func TestAnnotationSchemas(t *testing.T) {
+	s := annotationSchemas{}
+	for _, spec := range []string{"cost=number:Hourly cost", "critical=bool", "owner=string"} {
+		if err := s.Set(spec); err != nil {
+			t.Fatalf("Set(%q): %v", spec, err)
+		}
+	}
+	for _, tc := range []struct {
+		spec, wantErr string
+	}{
+		{spec: "cost=number", wantErr: "already registered"},
+		{spec: "tags=list", wantErr: "invalid type"},
+		{spec: "=string", wantErr: "must be name=type"},
+		{spec: "owner", wantErr: "must be name=type"},
+	} {
+		if err := s.Set(tc.spec); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("Set(%q) = %v, want an error containing %q", tc.spec, err, tc.wantErr)
+		}
+	}
+	if got := s.String(); got != "cost=number,critical=bool,owner=string" {
+		t.Errorf("String = %q", got)
+	}
+	if list := s.List(); len(list) != 3 || list[0] != (AnnotationSchema{Name: "cost", Type: AnnotationNumber, Description: "Hourly cost"}) {
+		t.Errorf("schemas = %+v, want cost first with its description", list)
+	}
+
+	c := Container{
+		Labels: map[string]string{
+			annotationLabelPrefix + "cost":     "0.25",
+			annotationLabelPrefix + "critical": "maybe",
+			annotationLabelPrefix + "unknown":  "x",
+			"app":                              "web",
+		},
+		Annotations: map[string]AnnotationValue{
+			"owner":    {Type: AnnotationString, String: "alice"},
+			"critical": {Type: AnnotationString, String: "yes"},
+			"other":    {Type: AnnotationBool, Bool: true},
+		},
+	}
+	s.annotate(&c)
+	want := map[string]AnnotationValue{
+		"cost":  {Type: AnnotationNumber, Number: 0.25},
+		"owner": {Type: AnnotationString, String: "alice"},
+	}
+	if !reflect.DeepEqual(c.Annotations, want) {
+		t.Errorf("annotations = %+v, want %+v", c.Annotations, want)
+	}
+
+	c = Container{Annotations: map[string]AnnotationValue{"other": {Type: AnnotationBool}}}
+	s.annotate(&c)
+	if c.Annotations != nil {
+		t.Errorf("annotations = %+v, want none", c.Annotations)
+	}
+}
@@ -0,0 +1,102 @@
+package containerslist
+
+import (
+	"fmt"
+	"time"
+)
+
+// Anomalies flagged on containers.
+const (
+	FlagRestartLoop = "restart_loop"
+	FlagOOMKilled   = "oom_killed"
+	FlagLongUptime  = "long_uptime"
+)
+
+// anomalyFlags are the flags which can be filtered on.
+var anomalyFlags = []string{FlagRestartLoop, FlagOOMKilled, FlagLongUptime}
+
+// restartObservation is the restart count of a container when it was
+// collected.
+type restartObservation struct {
+	time  time.Time
+	count int
+}
+
+// anomalyDetector flags the anomalies of the containers of a source. Restart
+// loops are detected from the restart counts observed within the restart
+// window.
+type anomalyDetector struct {
+	restartThreshold int
+	restartWindow    time.Duration
+	maxUptime        time.Duration
+
+	restarts map[string][]restartObservation
+}
+
+func newAnomalyDetector(restartThreshold int, restartWindow, maxUptime time.Duration) *anomalyDetector {
+	return &anomalyDetector{
+		restartThreshold: restartThreshold,
+		restartWindow:    restartWindow,
+		maxUptime:        maxUptime,
+		restarts:         map[string][]restartObservation{},
+	}
+}
+
+// flag sets the flags of the containers: restart_loop when a container
+// restarted at least -anomaly_restarts times within -anomaly_restart_window,
+// oom_killed when it was last killed for running out of memory, and
+// long_uptime when it has been running for longer than -anomaly_max_uptime,
+// and so has not picked up any image update since.
+func (d *anomalyDetector) flag(containers []Container, now time.Time) {
+	seen := make(map[string]struct{}, len(containers))
+	for i := range containers {
+		c := &containers[i]
+		seen[c.ID] = struct{}{}
+		c.Flags = nil
+
+		obs := append(d.restarts[c.ID], restartObservation{time: now, count: c.RestartCount})
+		for len(obs) > 1 && now.Sub(obs[0].time) > d.restartWindow {
+			obs = obs[1:]
+		}
+		d.restarts[c.ID] = obs
+		if d.restartThreshold > 0 && c.RestartCount-obs[0].count >= d.restartThreshold {
+			c.Flags = append(c.Flags, FlagRestartLoop)
+		}
+
+		if c.OOMKilled {
+			c.Flags = append(c.Flags, FlagOOMKilled)
+		}
+		if d.maxUptime > 0 && c.State == StateRunning && c.StartedAt != nil && now.Sub(*c.StartedAt) > d.maxUptime {
+			c.Flags = append(c.Flags, FlagLongUptime)
+		}
+	}
+	for id := range d.restarts {
+		if _, ok := seen[id]; !ok {
+			delete(d.restarts, id)
+		}
+	}
+}
+
+// hasFlag reports whether a container is flagged with an anomaly.
+func (c Container) hasFlag(flag string) bool {
+	for _, f := range c.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// flagFilter returns a predicate matching the containers flagged with the
+// given `flag` query value, or all of them when empty.
+func flagFilter(flag string) (func(Container) bool, error) {
+	if flag == "" {
+		return func(Container) bool { return true }, nil
+	}
+	for _, f := range anomalyFlags {
+		if f == flag {
+			return func(c Container) bool { return c.hasFlag(flag) }, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid flag %q: must be one of %s, %s, %s", flag, FlagRestartLoop, FlagOOMKilled, FlagLongUptime)
+}
@@ -0,0 +1,85 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	d := newAnomalyDetector(3, 10*time.Minute, 24*time.Hour)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := start.Add(-48 * time.Hour)
+
+	for _, tc := range []struct {
+		after    time.Duration
+		restarts int
+		want     []string
+	}{
+		{after: 0, restarts: 0},
+		{after: 2 * time.Minute, restarts: 2},
+		{after: 4 * time.Minute, restarts: 3, want: []string{FlagRestartLoop}},
+		// The restarts of the first minutes are out of the window.
+		{after: 15 * time.Minute, restarts: 4},
+	} {
+		containers := []Container{{ID: "a1", State: StateRunning, RestartCount: tc.restarts}}
+		d.flag(containers, start.Add(tc.after))
+		if got := containers[0].Flags; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("flags after %v with %d restarts = %q, want %q", tc.after, tc.restarts, got, tc.want)
+		}
+	}
+
+	containers := []Container{
+		{ID: "a2", State: "exited", OOMKilled: true, StartedAt: &old},
+		{ID: "a3", State: StateRunning, StartedAt: &old, Flags: []string{FlagOOMKilled}},
+		{ID: "a4", State: StateRunning, StartedAt: &start},
+	}
+	d.flag(containers, start)
+	for i, want := range [][]string{{FlagOOMKilled}, {FlagLongUptime}, nil} {
+		if got := containers[i].Flags; !reflect.DeepEqual(got, want) {
+			t.Errorf("flags of %s = %q, want %q", containers[i].ID, got, want)
+		}
+	}
+	// The restarts of removed containers are forgotten.
+	if _, ok := d.restarts["a1"]; ok || len(d.restarts) != 3 {
+		t.Errorf("restarts observed for %d containers, want 3 without a1", len(d.restarts))
+	}
+
+	// Without thresholds, only OOM kills are flagged.
+	containers = []Container{{ID: "a1", State: StateRunning, RestartCount: 100, StartedAt: &old}}
+	newAnomalyDetector(0, 10*time.Minute, 0).flag(containers, start)
+	if len(containers[0].Flags) != 0 {
+		t.Errorf("flags = %q, want none without thresholds", containers[0].Flags)
+	}
+}
+
+func TestFlagFilter(t *testing.T) {
+	containers := []Container{
+		{Name: "web", Flags: []string{FlagRestartLoop, FlagOOMKilled}},
+		{Name: "db", Flags: []string{FlagLongUptime}},
+		{Name: "api"},
+	}
+	for flag, want := range map[string][]string{
+		"":              {"web", "db", "api"},
+		FlagRestartLoop: {"web"},
+		FlagOOMKilled:   {"web"},
+		FlagLongUptime:  {"db"},
+	} {
+		match, err := flagFilter(flag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range containers {
+			if match(c) {
+				got = append(got, c.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("containers flagged %q = %q, want %q", flag, got, want)
+		}
+	}
+	if _, err := flagFilter("slow"); err == nil {
+		t.Error("flagFilter(slow) succeeded, want an error")
+	}
+}
@@ -0,0 +1,153 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(w, "Error: %v", err)
+	}
+}
+
+// containers returns the inventories of all nodes, only holding the
+// containers matching the `state` and `flag` query parameters. With the `scope=local`
+// query parameter, only the nodes collected locally are returned, and with
+// `scope=owned` only the nodes whose full inventory is kept locally. In
+// gateway mode, the local nodes of the peers are gathered from them, along
+// with the peers which did not answer. When the collection is sharded, the
+// nodes of the shards which are not aggregated locally are gathered from
+// their owners.
+func (m *Manager) containers(r *http.Request) ([]*NodeInventory, []string, error) {
+	state := r.URL.Query().Get("state")
+	f, ok := stateFilter(state)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid state %q: must be one of all, running, exited", state)
+	}
+	flagged, err := flagFilter(r.URL.Query().Get("flag"))
+	if err != nil {
+		return nil, nil, err
+	}
+	scope := r.URL.Query().Get("scope")
+	if scope != "" && scope != "local" && scope != "owned" {
+		return nil, nil, fmt.Errorf("invalid scope %q: must be one of local, owned", scope)
+	}
+
+	now := time.Now()
+	nodes := []*NodeInventory{}
+	for _, n := range m.inventory.Nodes() {
+		// In gateway mode, the owners of shards answer with their nodes
+		// to the gateways of other zones.
+		if (scope == "local" || m.cfg.Gateway && scope != "owned") && !m.isLocal(n.Node) || scope == "owned" && n.Summary {
+			continue
+		}
+		n = n.Filter(func(c Container) bool { return f(c) && flagged(c) })
+		n.AgeSeconds = now.Sub(n.Timestamp).Seconds()
+		n.Stale = now.Sub(n.Timestamp) > m.cfg.StaleAfter
+		nodes = append(nodes, n)
+	}
+	m.conflicts.drop(nodes)
+	if m.shards != nil && !m.cfg.Gateway && scope == "" {
+		nodes, failed := m.gatherShards(r.Context(), r.URL.Query(), nodes)
+		m.annotateZones(nodes, now)
+		annotateEdges(nodes)
+		return nodes, failed, nil
+	}
+	if !m.cfg.Gateway || scope != "" {
+		m.annotateZones(nodes, now)
+		annotateEdges(nodes)
+		return nodes, nil, nil
+	}
+	nodes, failed := m.gatherContainers(r.Context(), r.URL.Query(), nodes)
+	m.annotateZones(nodes, now)
+	annotateEdges(nodes)
+	return nodes, failed, nil
+}
+
+func (m *Manager) apiContainers() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mask, err := parseFieldMask(r, Container{})
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if mask != nil && format != "" {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "fields and format are mutually exclusive"))
+			return
+		}
+		nodes, failed, err := m.containers(r)
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		setPartial(w, failed)
+		switch format {
+		case "":
+			if mask == nil {
+				writeJSON(w, nodes)
+				return
+			}
+			res, err := mask.applyContainers(nodes)
+			if err != nil {
+				writeProblem(w, r, problemf(ErrInternal, "unable to select fields: %v", err))
+				return
+			}
+			writeJSON(w, res)
+		case "stable":
+			b, err := stableInventory(nodes)
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			writeJSON(w, b)
+		default:
+			writeProblem(w, r, problemf(ErrInvalidRequest, "invalid format %q: must be stable", format))
+		}
+	})
+}
+
+// GPUUsage lists the GPUs attached to a container.
+type GPUUsage struct {
+	Node      string   `json:"node"`
+	Container string   `json:"container"`
+	Name      string   `json:"name"`
+	Image     string   `json:"image"`
+	GPUs      []string `json:"gpus"`
+}
+
+// apiGPUs returns the cluster-wide list of running containers holding GPUs,
+// the ones holding the most GPUs first.
+func (m *Manager) apiGPUs() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mask, err := parseFieldMask(r, GPUUsage{})
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		usages := []GPUUsage{}
+		for _, n := range m.inventory.Nodes() {
+			for _, c := range n.Containers {
+				if c.State != StateRunning || len(c.GPUs) == 0 {
+					continue
+				}
+				usages = append(usages, GPUUsage{
+					Node:      n.Node,
+					Container: c.ID,
+					Name:      c.Name,
+					Image:     c.Image,
+					GPUs:      c.GPUs,
+				})
+			}
+		}
+		sort.SliceStable(usages, func(i, j int) bool {
+			return len(usages[i].GPUs) > len(usages[j].GPUs)
+		})
+		writeMaskedJSON(w, r, usages, mask)
+	})
+}
@@ -0,0 +1,32 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContainersFreshness(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "fresh", Timestamp: time.Now().Add(-10 * time.Second)},
+		&NodeInventory{Node: "stale", Timestamp: time.Now().Add(-2 * time.Minute)},
+	)
+	m.cfg.StaleAfter = time.Minute
+
+	nodes, _, err := m.containers(httptest.NewRequest(http.MethodGet, "/api/v1/containers", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("%d nodes, want 2", len(nodes))
+	}
+	for _, n := range nodes {
+		if want := n.Node == "stale"; n.Stale != want {
+			t.Errorf("%s stale = %v, want %v", n.Node, n.Stale, want)
+		}
+		if n.AgeSeconds < 10 {
+			t.Errorf("%s age = %.0fs, want at least 10s", n.Node, n.AgeSeconds)
+		}
+	}
+}
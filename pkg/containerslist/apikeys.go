@@ -0,0 +1,298 @@
+package containerslist
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Roles of the API keys, each granting the access of the previous ones.
+const (
+	// RoleViewer reads the API and the UI.
+	RoleViewer = "viewer"
+	// RoleEditor also writes the non-admin endpoints, such as the
+	// annotations and the maintenance windows.
+	RoleEditor = "editor"
+	// RoleAdmin also calls the admin API.
+	RoleAdmin = "admin"
+)
+
+var apiKeyRoles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// apiKeyName are the names of the API keys, such as ci-reports.
+var apiKeyName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// apiKeyPrefix prefixes the API keys, so that they are recognized by secret
+// scanners.
+const apiKeyPrefix = "clk_"
+
+// APIKey is an API key, granting the access of its role to automation
+// without sharing the admin token. The key itself is only returned on
+// creation: the node keeps its hash.
+type APIKey struct {
+	Name    string     `json:"name"`
+	Role    string     `json:"role"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Expired reports whether a key expired.
+func (k APIKey) Expired(now time.Time) bool {
+	return k.Expires != nil && !now.Before(*k.Expires)
+}
+
+// grants reports whether the role of a key grants the access of a role.
+func (k APIKey) grants(role string) bool {
+	return indexOf(apiKeyRoles, k.Role) >= indexOf(apiKeyRoles, role)
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// CreatedAPIKey is an API key along with the key, only known on creation.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// apiKeyRecord is an API key persisted in the API keys file.
+type apiKeyRecord struct {
+	APIKey
+	// Hash is the hex-encoded SHA-256 of the key.
+	Hash string `json:"hash"`
+}
+
+// apiKeyStore holds the API keys of the node, persisted in a file. The keys
+// are local to the node: they are not gossiped.
+type apiKeyStore struct {
+	file string
+
+	mtx  sync.Mutex
+	keys map[string]apiKeyRecord
+}
+
+// loadAPIKeys loads the API keys of a file, which is created on the first
+// key.
+func loadAPIKeys(file string) (*apiKeyStore, error) {
+	s := &apiKeyStore{file: file, keys: map[string]apiKeyRecord{}}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []apiKeyRecord
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", file, err)
+	}
+	for _, k := range records {
+		s.keys[k.Name] = k
+	}
+	return s, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// List returns the API keys, sorted by name.
+func (s *apiKeyStore) List() []APIKey {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.APIKey)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// Create creates a key, failing with ErrConflict when the name is taken.
+func (s *apiKeyStore) Create(k APIKey) (CreatedAPIKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return CreatedAPIKey{}, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(raw)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.keys[k.Name]; ok {
+		return CreatedAPIKey{}, problemf(ErrConflict, "API key %q already exists: revoke it first", k.Name)
+	}
+	s.keys[k.Name] = apiKeyRecord{APIKey: k, Hash: hashAPIKey(key)}
+	if err := s.save(); err != nil {
+		delete(s.keys, k.Name)
+		return CreatedAPIKey{}, err
+	}
+	return CreatedAPIKey{APIKey: k, Key: key}, nil
+}
+
+// Revoke deletes a key, returning it when it existed.
+func (s *apiKeyStore) Revoke(name string) (APIKey, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	k, ok := s.keys[name]
+	if !ok {
+		return APIKey{}, false, nil
+	}
+	delete(s.keys, name)
+	if err := s.save(); err != nil {
+		s.keys[name] = k
+		return APIKey{}, false, err
+	}
+	return k.APIKey, true, nil
+}
+
+// Lookup returns the unexpired key of a bearer token.
+func (s *apiKeyStore) Lookup(token string, now time.Time) (APIKey, bool) {
+	if !strings.HasPrefix(token, apiKeyPrefix) {
+		return APIKey{}, false
+	}
+	hash := hashAPIKey(token)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, k := range s.keys {
+		if k.Hash == hash && !k.Expired(now) {
+			return k.APIKey, true
+		}
+	}
+	return APIKey{}, false
+}
+
+func (s *apiKeyStore) save() error {
+	records := make([]apiKeyRecord, 0, len(s.keys))
+	for _, k := range s.keys {
+		records = append(records, k)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	b, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(s.file, b)
+}
+
+// bearerToken returns the bearer token of a request.
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// apiKey returns the API key of a token, when the API keys are enabled.
+func (m *Manager) apiKey(token string) (APIKey, bool) {
+	if m.apiKeys == nil {
+		return APIKey{}, false
+	}
+	return m.apiKeys.Lookup(token, time.Now())
+}
+
+// credential returns the API key of a token or else, over TLS, the client of
+// a request authenticated by its SVID, along with its kind, for the messages.
+func (m *Manager) credential(r *http.Request, token string, hasToken bool) (APIKey, string, bool) {
+	if k, ok := m.apiKey(token); hasToken && ok {
+		return k, "API key", true
+	}
+	if k, ok := m.spiffeClient(r); ok {
+		return k, "SPIFFE ID", true
+	}
+	return APIKey{}, "", false
+}
+
+// apiAPIKeys lists the API keys of the node, without the keys themselves.
+func (m *Manager) apiAPIKeys() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.apiKeys == nil {
+			writeProblem(w, r, problemf(ErrForbidden, "API keys are disabled: -api_keys_file is not set"))
+			return
+		}
+		writeJSON(w, m.apiKeys.List())
+	})
+}
+
+// apiKeyOf returns the name of the API key of the path
+// /api/v1/admin/api-keys/{name}, answering when the name is invalid or when
+// the API keys are disabled.
+func (m *Manager) apiKeyOf(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/admin/api-keys/")
+	if !apiKeyName.MatchString(name) {
+		writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+		return "", false
+	}
+	if m.apiKeys == nil {
+		writeProblem(w, r, problemf(ErrForbidden, "API keys are disabled: -api_keys_file is not set"))
+		return "", false
+	}
+	return name, true
+}
+
+// apiSetAPIKey creates the API key named in the path
+// /api/v1/admin/api-keys/{name}.
+func (m *Manager) apiSetAPIKey() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := m.apiKeyOf(w, r)
+		if !ok || !requireMethod(w, r, http.MethodPut) {
+			return
+		}
+		q := r.URL.Query()
+
+		k := APIKey{Name: name, Role: q.Get("role"), Created: time.Now().UTC()}
+		if !contains(apiKeyRoles, k.Role) {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "invalid role %q: must be one of %s", k.Role, strings.Join(apiKeyRoles, ", ")))
+			return
+		}
+		if s := q.Get("ttl"); s != "" {
+			ttl, err := time.ParseDuration(s)
+			if err != nil || ttl <= 0 {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid ttl %q: must be a positive duration", s))
+				return
+			}
+			expires := k.Created.Add(ttl)
+			k.Expires = &expires
+		}
+		created, err := m.apiKeys.Create(k)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		writeJSON(w, created)
+	})
+}
+
+// apiRevokeAPIKey revokes the API key named in the path
+// /api/v1/admin/api-keys/{name}.
+func (m *Manager) apiRevokeAPIKey() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := m.apiKeyOf(w, r)
+		if !ok || !requireMethod(w, r, http.MethodDelete) {
+			return
+		}
+		k, ok, err := m.apiKeys.Revoke(name)
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to revoke API key: %v", err))
+			return
+		}
+		if !ok {
+			writeProblem(w, r, problemf(ErrNotFound, "no API key %q", name))
+			return
+		}
+		writeJSON(w, k)
+	})
+}
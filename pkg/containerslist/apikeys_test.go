@@ -0,0 +1,115 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPIKeysManager(t *testing.T) (*Manager, string) {
+	keys, err := loadAPIKeys(filepath.Join(t.TempDir(), "api-keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := keys.Create(APIKey{Name: "admin", Role: RoleAdmin, Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Manager{cfg: DefaultConfig(), inventory: newInventory(), apiKeys: keys, idempotency: newIdempotencyStore(time.Hour)}, admin.Key
+}
+
+func TestAPIKeyEndpoints(t *testing.T) {
+	m, admin := newTestAPIKeysManager(t)
+	mux := http.NewServeMux()
+	var endpoints []apiEndpoint
+	for _, e := range m.apiEndpoints() {
+		if strings.HasPrefix(e.Path, "/api/v1/admin/api-keys") {
+			endpoints = append(endpoints, e)
+		}
+	}
+	m.handleEndpoints(mux, endpoints)
+
+	for _, tc := range []struct {
+		name, method, path string
+		want               int
+	}{
+		{name: "create", method: http.MethodPut, path: "/api/v1/admin/api-keys/ci-reports?role=viewer", want: http.StatusOK},
+		{name: "create existing", method: http.MethodPut, path: "/api/v1/admin/api-keys/ci-reports?role=viewer", want: http.StatusConflict},
+		{name: "invalid role", method: http.MethodPut, path: "/api/v1/admin/api-keys/ci?role=owner", want: http.StatusBadRequest},
+		{name: "invalid name", method: http.MethodPut, path: "/api/v1/admin/api-keys/-ci?role=viewer", want: http.StatusNotFound},
+		{name: "name too long", method: http.MethodPut, path: "/api/v1/admin/api-keys/" + strings.Repeat("a", 65) + "?role=viewer", want: http.StatusNotFound},
+		{name: "revoke", method: http.MethodDelete, path: "/api/v1/admin/api-keys/ci-reports", want: http.StatusOK},
+		{name: "revoke revoked", method: http.MethodDelete, path: "/api/v1/admin/api-keys/ci-reports", want: http.StatusNotFound},
+		{name: "other method", method: http.MethodPost, path: "/api/v1/admin/api-keys/ci-reports", want: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+admin)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if tc.want == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != "PUT, DELETE" {
+				t.Errorf("Allow = %q, want PUT, DELETE", rec.Header().Get("Allow"))
+			}
+		})
+	}
+	if keys := m.apiKeys.List(); len(keys) != 1 || keys[0].Name != "admin" {
+		t.Errorf("keys = %v, want the admin key only", keys)
+	}
+
+	paths := openAPI(endpoints)["paths"].(map[string]interface{})
+	item := paths["/api/v1/admin/api-keys/{name}"].(map[string]interface{})
+	if _, ok := item["put"]; !ok {
+		t.Error("creation missing from the OpenAPI document")
+	}
+	if _, ok := item["delete"]; !ok {
+		t.Error("revocation missing from the OpenAPI document")
+	}
+}
+
+func TestRequireAdminForwarded(t *testing.T) {
+	m, _ := newTestAPIKeysManager(t)
+	var caller string
+	h := m.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = m.adminCaller(r)
+	}))
+
+	for _, tc := range []struct {
+		name string
+		// internal serves the request over the internal API.
+		internal   bool
+		headers    map[string]string
+		want       int
+		wantCaller string
+	}{
+		{name: "forwarded by a peer", internal: true, headers: map[string]string{forwardedHeader: "node-1", forwardedCallerHeader: "API key ci"}, want: http.StatusOK, wantCaller: "API key ci"},
+		{name: "forwarded to the HTTP server", headers: map[string]string{forwardedHeader: "node-1", forwardedCallerHeader: "API key ci"}, want: http.StatusUnauthorized},
+		{name: "caller without forwarding peer", internal: true, headers: map[string]string{forwardedCallerHeader: "API key ci"}, want: http.StatusUnauthorized},
+		{name: "forwarded without caller", internal: true, headers: map[string]string{forwardedHeader: "node-1"}, want: http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			caller = ""
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			if tc.internal {
+				fromPeers(h).ServeHTTP(rec, req)
+			} else {
+				h.ServeHTTP(rec, req)
+			}
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if caller != tc.wantCaller {
+				t.Errorf("caller = %q, want %q", caller, tc.wantCaller)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	awsMetadataURL = "http://169.254.169.254/latest"
+	// awsASGTag is the tag set by EC2 Auto Scaling on the instances of a
+	// group.
+	awsASGTag = "aws:autoscaling:groupName"
+)
+
+// awsDiscoverer finds the running EC2 instances with a tag, or in an Auto
+// Scaling group, with the DescribeInstances API. Its credentials are the
+// ones of the instance profile, unless set in the environment.
+type awsDiscoverer struct {
+	endpoint string
+	region   string
+	filters  map[string]string
+	client   *http.Client
+
+	mtx   sync.Mutex
+	creds awsCredentials
+}
+
+// awsCredentials are temporary credentials of an instance profile.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+func newAWSDiscoverer(cfg *Config) (peerDiscoverer, error) {
+	d := &awsDiscoverer{
+		region:  cfg.DiscoveryAWSRegion,
+		filters: map[string]string{"instance-state-name": "running"},
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	if cfg.DiscoveryAWSTag != "" {
+		key, value, ok := strings.Cut(cfg.DiscoveryAWSTag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid -ha_discovery_aws_tag %q: must be key=value", cfg.DiscoveryAWSTag)
+		}
+		d.filters["tag:"+key] = value
+	}
+	if cfg.DiscoveryAWSASG != "" {
+		d.filters["tag:"+awsASGTag] = cfg.DiscoveryAWSASG
+	}
+	if len(d.filters) == 1 {
+		return nil, errors.New("the aws discovery requires -ha_discovery_aws_tag or -ha_discovery_aws_asg")
+	}
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		d.creds = awsCredentials{AccessKeyID: id, SecretAccessKey: secret, Token: os.Getenv("AWS_SESSION_TOKEN")}
+	}
+	return d, nil
+}
+
+// metadata returns a value of the instance metadata service, with a session
+// token as required by IMDSv2.
+func (d *awsDiscoverer) metadata(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, awsMetadataURL+"/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := d.get(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get metadata token: %w", err)
+	}
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, awsMetadataURL+"/meta-data/"+path, nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	return d.get(req)
+}
+
+func (d *awsDiscoverer) get(req *http.Request) ([]byte, error) {
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(b[:min(len(b), 1024)]))
+	}
+	return b, nil
+}
+
+// credentials returns the credentials of the environment, or else the ones
+// of the instance profile, cached until shortly before they expire.
+func (d *awsDiscoverer) credentials(ctx context.Context) (awsCredentials, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.creds.AccessKeyID != "" && (d.creds.Expiration.IsZero() || time.Until(d.creds.Expiration) > 5*time.Minute) {
+		return d.creds, nil
+	}
+	role, err := d.metadata(ctx, "iam/security-credentials/")
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("unable to get instance profile: %w", err)
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(string(role)), "\n")
+	b, err := d.metadata(ctx, "iam/security-credentials/"+name)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("unable to get credentials of instance profile %s: %w", name, err)
+	}
+	var creds awsCredentials
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return awsCredentials{}, err
+	}
+	d.creds = creds
+	return creds, nil
+}
+
+// endpointURL returns the endpoint of the EC2 API of the region, the one of
+// the instance by default.
+func (d *awsDiscoverer) endpointURL(ctx context.Context) (string, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.endpoint != "" {
+		return d.endpoint, nil
+	}
+	if d.region == "" {
+		b, err := d.metadata(ctx, "placement/region")
+		if err != nil {
+			return "", fmt.Errorf("unable to get region: %w", err)
+		}
+		d.region = strings.TrimSpace(string(b))
+	}
+	d.endpoint = "https://ec2." + d.region + ".amazonaws.com/"
+	return d.endpoint, nil
+}
+
+// describeInstancesResponse is the part of the response of the
+// DescribeInstances API holding the private addresses of the instances.
+type describeInstancesResponse struct {
+	Reservations []struct {
+		Instances []struct {
+			PrivateIPAddress string `xml:"privateIpAddress"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// Peers returns the private addresses of the matching instances.
+func (d *awsDiscoverer) Peers(ctx context.Context) ([]string, error) {
+	endpoint, err := d.endpointURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := d.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"Action": {"DescribeInstances"}, "Version": {"2016-11-15"}}
+	i := 1
+	for _, name := range sortedKeys(d.filters) {
+		q.Set("Filter."+strconv.Itoa(i)+".Name", name)
+		q.Set("Filter."+strconv.Itoa(i)+".Value.1", d.filters[name])
+		i++
+	}
+	var addrs []string
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		// The canonical query of the signature encodes the spaces as %20.
+		req.URL.RawQuery = strings.ReplaceAll(q.Encode(), "+", "%20")
+		signAWSv4(req, nil, time.Now().UTC(), d.region, "ec2", creds.AccessKeyID, creds.SecretAccessKey, creds.Token)
+		b, err := d.get(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe instances: %w", err)
+		}
+		var res describeInstancesResponse
+		if err := xml.Unmarshal(b, &res); err != nil {
+			return nil, err
+		}
+		for _, r := range res.Reservations {
+			for _, instance := range r.Instances {
+				if instance.PrivateIPAddress != "" {
+					addrs = append(addrs, instance.PrivateIPAddress)
+				}
+			}
+		}
+		if res.NextToken == "" {
+			return addrs, nil
+		}
+		q.Set("NextToken", res.NextToken)
+	}
+}
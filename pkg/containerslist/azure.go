@@ -0,0 +1,152 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	azureMetadataURL   = "http://169.254.169.254/metadata/"
+	azureManagementURL = "https://management.azure.com/"
+)
+
+// azureDiscoverer finds the instances of an Azure virtual machine scale set
+// with the network interfaces API of Azure Resource Manager. Its credentials
+// are the ones of the managed identity of the instance, and the scale set is
+// the one of the instance by default.
+type azureDiscoverer struct {
+	subscription  string
+	resourceGroup string
+	scaleSet      string
+	client        *http.Client
+
+	mtx     sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newAzureDiscoverer(cfg *Config) (peerDiscoverer, error) {
+	return &azureDiscoverer{
+		subscription:  cfg.DiscoveryAzureSubscription,
+		resourceGroup: cfg.DiscoveryAzureResourceGroup,
+		scaleSet:      cfg.DiscoveryAzureScaleSet,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// metadata decodes a JSON value of the instance metadata service.
+func (d *azureDiscoverer) metadata(ctx context.Context, path string, q url.Values, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureMetadataURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+	return doSinkRequest(d.client, req, res)
+}
+
+// accessToken returns the access token of the managed identity to Azure
+// Resource Manager, cached until it expires.
+func (d *azureDiscoverer) accessToken(ctx context.Context) (string, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.token != "" && time.Until(d.expires) > time.Minute {
+		return d.token, nil
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		// ExpiresOn is a Unix time, as a string.
+		ExpiresOn string `json:"expires_on"`
+	}
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {azureManagementURL}}
+	if err := d.metadata(ctx, "identity/oauth2/token", q, &res); err != nil {
+		return "", fmt.Errorf("unable to get access token: %w", err)
+	}
+	expires, err := strconv.ParseInt(res.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid expiry of access token %q: %w", res.ExpiresOn, err)
+	}
+	d.token, d.expires = res.AccessToken, time.Unix(expires, 0)
+	return d.token, nil
+}
+
+// locate fills the subscription, resource group and scale set not set from
+// the ones of the instance.
+func (d *azureDiscoverer) locate(ctx context.Context) error {
+	if d.subscription != "" && d.resourceGroup != "" && d.scaleSet != "" {
+		return nil
+	}
+	var compute struct {
+		SubscriptionID    string `json:"subscriptionId"`
+		ResourceGroupName string `json:"resourceGroupName"`
+		VMScaleSetName    string `json:"vmScaleSetName"`
+	}
+	if err := d.metadata(ctx, "instance/compute", url.Values{"api-version": {"2021-02-01"}}, &compute); err != nil {
+		return fmt.Errorf("unable to get instance metadata: %w", err)
+	}
+	if d.subscription == "" {
+		d.subscription = compute.SubscriptionID
+	}
+	if d.resourceGroup == "" {
+		d.resourceGroup = compute.ResourceGroupName
+	}
+	if d.scaleSet == "" {
+		d.scaleSet = compute.VMScaleSetName
+	}
+	if d.scaleSet == "" {
+		return errors.New("the instance is in no scale set: set -ha_discovery_azure_scale_set")
+	}
+	return nil
+}
+
+// Peers returns the primary private addresses of the instances of the
+// scale set.
+func (d *azureDiscoverer) Peers(ctx context.Context) ([]string, error) {
+	if err := d.locate(ctx); err != nil {
+		return nil, err
+	}
+	token, err := d.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	next := azureManagementURL + "subscriptions/" + url.PathEscape(d.subscription) + "/resourceGroups/" + url.PathEscape(d.resourceGroup) +
+		"/providers/Microsoft.Compute/virtualMachineScaleSets/" + url.PathEscape(d.scaleSet) + "/networkInterfaces?api-version=2018-10-01"
+	var addrs []string
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		var res struct {
+			Value []struct {
+				Properties struct {
+					IPConfigurations []struct {
+						Properties struct {
+							Primary          bool   `json:"primary"`
+							PrivateIPAddress string `json:"privateIPAddress"`
+						} `json:"properties"`
+					} `json:"ipConfigurations"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := doSinkRequest(d.client, req, &res); err != nil {
+			return nil, fmt.Errorf("unable to list network interfaces: %w", err)
+		}
+		for _, nic := range res.Value {
+			for _, ip := range nic.Properties.IPConfigurations {
+				if ip.Properties.Primary && ip.Properties.PrivateIPAddress != "" {
+					addrs = append(addrs, ip.Properties.PrivateIPAddress)
+				}
+			}
+		}
+		next = res.NextLink
+	}
+	return addrs, nil
+}
@@ -0,0 +1,84 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAzureDiscovererPeers(t *testing.T) {
+	var tokens int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Header.Get("X-Original-Host")
+		if host == "169.254.169.254" && r.Header.Get("Metadata") != "true" {
+			http.Error(w, "missing metadata header", http.StatusBadRequest)
+			return
+		}
+		switch host + r.URL.Path {
+		case "169.254.169.254/metadata/instance/compute":
+			w.Write([]byte(`{"subscriptionId":"sub","resourceGroupName":"rg","vmScaleSetName":"fleet"}`))
+		case "169.254.169.254/metadata/identity/oauth2/token":
+			tokens++
+			w.Write([]byte(`{"access_token":"t0k3n","expires_on":"` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `"}`))
+		case "management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/fleet/networkInterfaces":
+			if r.Header.Get("Authorization") != "Bearer t0k3n" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("page") == "" {
+				w.Write([]byte(`{"value":[{"properties":{"ipConfigurations":[
+					{"properties":{"primary":false,"privateIPAddress":"10.1.0.1"}},
+					{"properties":{"primary":true,"privateIPAddress":"10.0.0.1"}}
+				]}}],"nextLink":"https://management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/fleet/networkInterfaces?api-version=2018-10-01&page=2"}`))
+				return
+			}
+			w.Write([]byte(`{"value":[{"properties":{"ipConfigurations":[{"properties":{"primary":true,"privateIPAddress":"10.0.0.2"}}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	d, err := newAzureDiscoverer(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.(*azureDiscoverer).client = redirectedClient(t, srv)
+	for i := 0; i < 2; i++ {
+		addrs, err := d.Peers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(addrs, want) {
+			t.Errorf("peers = %q, want %q", addrs, want)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("%d tokens requested, want the first one cached", tokens)
+	}
+}
+
+func TestAzureDiscovererLocate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"subscriptionId":"sub","resourceGroupName":"rg"}`))
+	}))
+	defer srv.Close()
+
+	// The instance is in no scale set.
+	d := &azureDiscoverer{client: redirectedClient(t, srv)}
+	if err := d.locate(context.Background()); err == nil {
+		t.Error("instance outside of a scale set located")
+	}
+	// The scale set of the configuration is kept.
+	d = &azureDiscoverer{resourceGroup: "other", scaleSet: "fleet", client: redirectedClient(t, srv)}
+	if err := d.locate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d.subscription != "sub" || d.resourceGroup != "other" || d.scaleSet != "fleet" {
+		t.Errorf("location = %s/%s/%s, want sub/other/fleet", d.subscription, d.resourceGroup, d.scaleSet)
+	}
+}
@@ -0,0 +1,158 @@
+package containerslist
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// blocklist holds the peers whose gossiped updates are dropped, by name or by
+// CIDR. Gossiped entries are matched by their node ID, which is the name of
+// the peer unless a node ID file is used; CIDRs are matched against the
+// gossip address of the peer of that name.
+type blocklist struct {
+	// addrs returns the gossip address of the members, by name.
+	addrs func() map[string]string
+
+	mtx   sync.RWMutex
+	names map[string]bool
+	nets  map[string]*net.IPNet
+
+	dropped prometheus.Counter
+}
+
+func newBlocklist(reg prometheus.Registerer) *blocklist {
+	b := &blocklist{
+		names: map[string]bool{},
+		nets:  map[string]*net.IPNet{},
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "containerslist_gossip_blocked_updates_total",
+			Help: "Number of gossiped updates dropped because their node is blocklisted.",
+		}),
+	}
+	reg.MustRegister(b.dropped)
+	return b
+}
+
+// Add blocks a peer name or CIDR.
+func (b *blocklist) Add(entry string) error {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return fmt.Errorf("empty blocklist entry")
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if strings.Contains(entry, "/") {
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			b.nets[entry] = n
+			return nil
+		}
+	}
+	b.names[entry] = true
+	return nil
+}
+
+// Remove unblocks a peer name or CIDR.
+func (b *blocklist) Remove(entry string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	delete(b.names, entry)
+	delete(b.nets, entry)
+}
+
+// Entries returns the blocked names and CIDRs, sorted.
+func (b *blocklist) Entries() []string {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	entries := make([]string, 0, len(b.names)+len(b.nets))
+	for name := range b.names {
+		entries = append(entries, name)
+	}
+	for cidr := range b.nets {
+		entries = append(entries, cidr)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// BlockedAddr reports whether an address belongs to a blocked CIDR.
+func (b *blocklist) BlockedAddr(addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	for _, n := range b.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Drop reports whether the gossiped update of a node must be dropped, counting
+// the dropped ones. Sub-nodes are blocked along with their node. A nil
+// blocklist drops nothing.
+func (b *blocklist) Drop(node string) bool {
+	if b == nil {
+		return false
+	}
+	peer, _, _ := strings.Cut(node, "/")
+	b.mtx.RLock()
+	blocked := b.names[node] || b.names[peer]
+	checkAddr := len(b.nets) > 0
+	b.mtx.RUnlock()
+
+	if !blocked && checkAddr && b.addrs != nil {
+		if addr, ok := b.addrs()[peer]; ok {
+			blocked = b.BlockedAddr(addr)
+		}
+	}
+	if blocked {
+		b.dropped.Inc()
+	}
+	return blocked
+}
+
+// rejectBlocked rejects the requests coming from a blocked CIDR, such as the
+// state transfers of blocked peers joining the cluster.
+func (m *Manager) rejectBlocked(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.blocklist.BlockedAddr(r.RemoteAddr) {
+			writeProblem(w, r, problemf(ErrForbidden, "peer %s is blocklisted", r.RemoteAddr))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// apiBlocklist adds and removes entries of the blocklist, returning the
+// resulting one.
+func (m *Manager) apiBlocklist() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		q := r.URL.Query()
+		for _, e := range q["add"] {
+			if err := m.blocklist.Add(e); err != nil {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+				return
+			}
+		}
+		for _, e := range q["remove"] {
+			m.blocklist.Remove(e)
+		}
+		writeJSON(w, m.blocklist.Entries())
+	})
+}
@@ -0,0 +1,121 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBlocklist(t *testing.T) {
+	b := newBlocklist(prometheus.NewRegistry())
+	b.addrs = func() map[string]string {
+		return map[string]string{"flood": "10.0.0.7:9094", "good": "192.168.1.2:9094"}
+	}
+	for _, e := range []string{"bad", " 10.0.0.0/24 ", "not/a-cidr"} {
+		if err := b.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Add(" "); err == nil {
+		t.Error("empty entry accepted")
+	}
+	if got, want := b.Entries(), []string{"10.0.0.0/24", "bad", "not/a-cidr"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	for node, want := range map[string]bool{
+		"bad":         true,
+		"bad/sub":     true,
+		"flood":       true,
+		"good":        false,
+		"unknown":     false,
+		"not/a-cidr":  true,
+		"badly-named": false,
+	} {
+		if got := b.Drop(node); got != want {
+			t.Errorf("Drop(%q) = %v, want %v", node, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(b.dropped); got != 4 {
+		t.Errorf("dropped updates = %v, want 4", got)
+	}
+
+	b.Remove("10.0.0.0/24")
+	if b.Drop("flood") || b.BlockedAddr("10.0.0.7:9094") {
+		t.Error("flood still blocked once its CIDR is removed")
+	}
+	var nilBlocklist *blocklist
+	if nilBlocklist.Drop("bad") {
+		t.Error("nil blocklist dropped an update")
+	}
+}
+
+func TestBlocklistMerge(t *testing.T) {
+	i := newInventory()
+	i.blocklist = newBlocklist(prometheus.NewRegistry())
+	if err := i.blocklist.Add("bad"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal([]*NodeInventory{{Node: "bad", Timestamp: time.Now()}, {Node: "good", Timestamp: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.Get("bad"); ok {
+		t.Error("inventory of a blocked node merged")
+	}
+	if _, ok := i.Get("good"); !ok {
+		t.Error("inventory of good not merged")
+	}
+}
+
+func TestAPIBlocklist(t *testing.T) {
+	m := &Manager{blocklist: newBlocklist(prometheus.NewRegistry())}
+	api := m.apiBlocklist()
+	for _, tc := range []struct {
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{query: "?add=bad&add=10.0.0.0/8", wantStatus: http.StatusOK, want: []string{"10.0.0.0/8", "bad"}},
+		{query: "?remove=bad", wantStatus: http.StatusOK, want: []string{"10.0.0.0/8"}},
+		{query: "?add=", wantStatus: http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/blocklist"+tc.query, nil))
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tc.query, rec.Code, tc.wantStatus)
+			continue
+		}
+		if tc.want == nil {
+			continue
+		}
+		var got []string
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: entries = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+
+	// The blocked CIDRs cannot transfer their state.
+	h := m.rejectBlocked(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for addr, want := range map[string]int{"10.1.2.3:4000": http.StatusForbidden, "192.168.1.2:4000": http.StatusOK} {
+		r := httptest.NewRequest(http.MethodPost, "/internal/state", nil)
+		r.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != want {
+			t.Errorf("status from %s = %d, want %d", addr, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/cluster"
+)
+
+// gossipStates returns the gossiped states, by key.
+func (m *Manager) gossipStates() map[string]cluster.State {
+	return map[string]cluster.State{
+		"inventory": m.inventory,
+		"networks":  m.networks,
+		"volumes":   m.volumes,
+		"images":    m.images,
+		"prune":     m.pruneCandidates,
+		"facts":     m.facts,
+		"usage":     m.usage,
+
+		"decommission":   m.decommissionAcks,
+		"containerusage": m.containerUsage,
+	}
+}
+
+// internalTLSConfig returns the mutual TLS configuration of the internal API,
+// used both to serve it and to call the internal API of peers. Its
+// certificate is the SVID of the node, or else is issued by Vault, or else
+// read from files, and rotated without restarts.
+func (m *Manager) internalTLSConfig(ctx context.Context) (*tls.Config, error) {
+	var (
+		source  certSource
+		refresh time.Duration
+	)
+	switch {
+	case m.cfg.SPIFFESVIDDir != "":
+		source = spiffeCertSource(m.cfg.SPIFFESVIDDir)
+		refresh = certFileRefreshInterval
+	case m.cfg.InternalTLSVaultPKI != "":
+		commonName := m.cfg.InternalTLSVaultCommonName
+		if commonName == "" {
+			commonName, _ = os.Hostname()
+		}
+		var altNames []string
+		if m.cfg.InternalTLSVaultAltNames != "" {
+			altNames = strings.Split(m.cfg.InternalTLSVaultAltNames, ",")
+		}
+		source = vaultCertSource(m.cfg.InternalTLSVaultPKI, commonName, altNames, m.cfg.InternalTLSVaultTTL, m.cfg.InternalTLSCAFile)
+	case m.cfg.InternalTLSCertFile != "" && m.cfg.InternalTLSKeyFile != "" && m.cfg.InternalTLSCAFile != "":
+		source = fileCertSource(m.cfg.InternalTLSCertFile, m.cfg.InternalTLSKeyFile, m.cfg.InternalTLSCAFile)
+		refresh = certFileRefreshInterval
+	default:
+		return nil, errors.New("a certificate, key and client CA, a Vault PKI issue endpoint or a SPIFFE SVID are required for the internal API")
+	}
+	m.internalCerts = newRotatingCerts(source, refresh, m.logger, m.registry)
+	m.internalCerts.spiffe, m.internalCerts.policy = m.cfg.SPIFFESVIDDir != "", m.cfg.SPIFFETrust
+	if err := m.internalCerts.load(ctx); err != nil {
+		return nil, err
+	}
+	return m.internalCerts.config(), nil
+}
+
+// internalState serves the full merged state of the node, from which a
+// joining node can bootstrap instead of waiting for several push/pull cycles.
+func (m *Manager) internalState() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := map[string][]byte{}
+		for key, s := range m.gossipStates() {
+			b, err := s.MarshalBinary()
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			state[key] = b
+		}
+		writeJSON(w, state)
+	})
+}
+
+// bootstrap pulls the full state of a peer once, merging it into the local
+// states. Entries are verified as if they had been gossiped.
+func (m *Manager) bootstrap(ctx context.Context, url string, config *tls.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var state map[string][]byte
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return err
+	}
+	states := m.gossipStates()
+	for key, b := range state {
+		s, ok := states[key]
+		if !ok {
+			continue
+		}
+		if err := s.Merge(b); err != nil {
+			return fmt.Errorf("unable to merge %s state: %w", key, err)
+		}
+	}
+	level.Info(m.logger).Log("msg", "Bootstrapped state from peer", "url", url)
+	return nil
+}
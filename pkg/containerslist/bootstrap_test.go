@@ -0,0 +1,61 @@
+package containerslist
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// newTestStatesManager returns a manager holding all the gossiped states.
+func newTestStatesManager(t *testing.T) *Manager {
+	t.Helper()
+	m := newTestInventoryManager(t)
+	m.logger = log.NewNopLogger()
+	m.networks = newResourceState[Network]()
+	m.volumes = newResourceState[Volume]()
+	m.images = newResourceState[Image]()
+	m.pruneCandidates = newResourceState[PruneCandidate]()
+	m.facts = newNodeState[*NodeFacts]()
+	m.usage = newNodeState[*NodeUsage]()
+	m.containerUsage = newResourceState[ContainerUsage]()
+	m.decommissionAcks = newNodeState[*DecommissionAck]()
+	return m
+}
+
+func TestBootstrap(t *testing.T) {
+	now := time.Now().UTC()
+	peer := newTestStatesManager(t)
+	if _, err := peer.inventory.Set(&NodeInventory{Node: "a", Timestamp: now, Containers: []Container{{ID: "a1", Name: "web"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peer.volumes.Set(&NodeResources[Volume]{Node: "a", Timestamp: now, Items: []Volume{{Name: "data"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peer.facts.Set(&NodeFacts{Node: "a", Peer: "p1", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(peer.internalState())
+	defer srv.Close()
+
+	m := newTestStatesManager(t)
+	if err := m.bootstrap(context.Background(), srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := m.inventory.Get("a"); !ok || len(n.Containers) != 1 || n.Containers[0].Name != "web" {
+		t.Errorf("inventory = %+v, want the container of the peer", n)
+	}
+	if v, ok := m.volumes.Get("a"); !ok || len(v.Items) != 1 {
+		t.Errorf("volumes = %+v, want the volume of the peer", v)
+	}
+	if f, ok := m.facts.Get("a"); !ok || f.Peer != "p1" {
+		t.Errorf("facts = %+v, want the facts of the peer", f)
+	}
+
+	srv.Close()
+	if err := m.bootstrap(context.Background(), srv.URL, nil); err == nil {
+		t.Error("bootstrap from a stopped peer succeeded, want an error")
+	}
+}
@@ -0,0 +1,212 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// States of a circuit breaker.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// errCircuitOpen is returned instead of calling a dependency whose circuit
+// breaker is open.
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// callerError is returned by the calls which reached a healthy dependency
+// but failed because of the request, such as on a 404 Not Found. It is
+// returned by circuitBreaker.Do, unwrapped, without counting as a failure.
+type callerError struct {
+	error
+}
+
+var (
+	breakerStateDesc = prometheus.NewDesc(
+		"containerslist_circuit_breaker_state",
+		"State of the circuit breakers of the dependencies: 0 when closed, 1 when half-open and 2 when open.",
+		[]string{"breaker"}, nil,
+	)
+	breakerRejectedDesc = prometheus.NewDesc(
+		"containerslist_circuit_breaker_rejected_calls_total",
+		"Number of calls rejected by the circuit breakers of the dependencies.",
+		[]string{"breaker"}, nil,
+	)
+)
+
+// BreakerStatus is the state of the circuit breaker of a dependency.
+type BreakerStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	Rejected            int        `json:"rejected"`
+}
+
+// circuitBreaker stops calling a dependency after consecutive failures, so
+// that a dead daemon or peer fails fast instead of stalling its callers until
+// a timeout. Once open for a while, the breaker is half-open: a single probe
+// call is let through, closing the breaker on success.
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mtx    sync.Mutex
+	status BreakerStatus
+	// probing is set while the probe call of a half-open breaker runs.
+	probing bool
+}
+
+// Do calls f unless the breaker is open. Failures due to the cancellation of
+// the context are not counted, and the caller errors count as successes. A
+// nil breaker always calls f.
+func (b *circuitBreaker) Do(ctx context.Context, f func() error) error {
+	if b == nil {
+		return unwrapCallerError(f())
+	}
+	if !b.allow() {
+		return errCircuitOpen
+	}
+	err := f()
+	if err != nil && ctx.Err() != nil {
+		b.mtx.Lock()
+		b.probing = false
+		b.mtx.Unlock()
+		return unwrapCallerError(err)
+	}
+	if ce, ok := err.(callerError); ok {
+		b.record(nil)
+		return ce.error
+	}
+	b.record(err)
+	return err
+}
+
+// unwrapCallerError returns the error of a caller error, or err.
+func unwrapCallerError(err error) error {
+	if ce, ok := err.(callerError); ok {
+		return ce.error
+	}
+	return err
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.status.State == BreakerOpen && time.Since(*b.status.OpenedAt) >= b.openTimeout {
+		b.status.State = BreakerHalfOpen
+	}
+	switch {
+	case b.status.State == BreakerClosed:
+		return true
+	case b.status.State == BreakerHalfOpen && !b.probing:
+		b.probing = true
+		return true
+	default:
+		b.status.Rejected++
+		return false
+	}
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.status.State = BreakerClosed
+		b.status.ConsecutiveFailures = 0
+		b.status.OpenedAt = nil
+		return
+	}
+	b.status.ConsecutiveFailures++
+	if b.status.State == BreakerHalfOpen || b.status.ConsecutiveFailures >= b.threshold {
+		now := time.Now().UTC()
+		b.status.State = BreakerOpen
+		b.status.OpenedAt = &now
+	}
+}
+
+// breakers holds the circuit breakers of the dependencies, by name.
+type breakers struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mtx      sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newBreakers(threshold int, openTimeout time.Duration) *breakers {
+	return &breakers{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		breakers:    map[string]*circuitBreaker{},
+	}
+}
+
+// Get returns the circuit breaker of a dependency, creating it if needed. It
+// returns nil, disabling the breaker, when the threshold is not positive.
+func (bs *breakers) Get(name string) *circuitBreaker {
+	if bs.threshold <= 0 {
+		return nil
+	}
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+
+	b, ok := bs.breakers[name]
+	if !ok {
+		b = &circuitBreaker{
+			threshold:   bs.threshold,
+			openTimeout: bs.openTimeout,
+			status:      BreakerStatus{Name: name, State: BreakerClosed},
+		}
+		bs.breakers[name] = b
+	}
+	return b
+}
+
+// Statuses returns the state of all circuit breakers, sorted by name.
+func (bs *breakers) Statuses() []BreakerStatus {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(bs.breakers))
+	for _, b := range bs.breakers {
+		b.mtx.Lock()
+		statuses = append(statuses, b.status)
+		b.mtx.Unlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Describe implements prometheus.Collector.
+func (bs *breakers) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- breakerRejectedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (bs *breakers) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range bs.Statuses() {
+		state := 0.0
+		switch s.State {
+		case BreakerHalfOpen:
+			state = 1
+		case BreakerOpen:
+			state = 2
+		}
+		ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, state, s.Name)
+		ch <- prometheus.MustNewConstMetric(breakerRejectedDesc, prometheus.CounterValue, float64(s.Rejected), s.Name)
+	}
+}
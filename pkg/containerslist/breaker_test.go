@@ -0,0 +1,50 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDockerClientBreaker(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		status    int
+		wantState string
+	}{
+		{name: "ok", status: http.StatusOK, wantState: BreakerClosed},
+		{name: "not found", status: http.StatusNotFound, wantState: BreakerClosed},
+		{name: "conflict", status: http.StatusConflict, wantState: BreakerClosed},
+		{name: "server error", status: http.StatusInternalServerError, wantState: BreakerOpen},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantState: BreakerOpen},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte("{}"))
+			}))
+			defer srv.Close()
+
+			c, err := newDockerClient(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.breaker = &circuitBreaker{threshold: 2, openTimeout: time.Minute, status: BreakerStatus{State: BreakerClosed}}
+			for i := 0; i < 3; i++ {
+				var v struct{}
+				err := c.get(context.Background(), "/_ping", &v)
+				if _, ok := err.(callerError); ok {
+					t.Fatalf("call %d: caller error returned wrapped: %v", i, err)
+				}
+				if (err == nil) != (tc.status == http.StatusOK) {
+					t.Fatalf("call %d: unexpected error %v", i, err)
+				}
+			}
+			if got := c.breaker.status.State; got != tc.wantState {
+				t.Errorf("breaker state = %s, want %s", got, tc.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// certFileRefreshInterval is the interval between two reads of the
+	// certificate files, for them to be rotated by external tools.
+	certFileRefreshInterval = time.Minute
+	// certRetryInterval is the interval between two attempts to renew a
+	// certificate.
+	certRetryInterval = time.Minute
+)
+
+// certSource returns the current certificate of the node and the CAs it
+// trusts.
+type certSource func(ctx context.Context) (*tls.Certificate, *x509.CertPool, error)
+
+// loadCAPool returns the pool of the CAs of a PEM file, or an empty pool
+// when caFile is empty.
+func loadCAPool(caFile string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if caFile == "" {
+		return pool, nil
+	}
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read internal API CA: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificate found in internal API CA")
+	}
+	return pool, nil
+}
+
+// fileCertSource reads the certificate, key and CAs of files.
+func fileCertSource(certFile, keyFile, caFile string) certSource {
+	return func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load internal API certificate: %w", err)
+		}
+		pool, err := loadCAPool(caFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &cert, pool, nil
+	}
+}
+
+// rotatingCerts holds the certificate of the internal API, renewed from its
+// source before it expires, so that it is rotated without restarts.
+type rotatingCerts struct {
+	source certSource
+	// refresh bounds the interval between two renewals; the renewals
+	// otherwise happen after two thirds of the lifetime of the certificate.
+	refresh time.Duration
+	logger  log.Logger
+	expiry  prometheus.Gauge
+	// spiffe verifies the peers by their SPIFFE IDs, against the policy,
+	// instead of their DNS names.
+	spiffe bool
+	policy spiffePolicy
+
+	mtx   sync.RWMutex
+	cert  *tls.Certificate
+	roots *x509.CertPool
+}
+
+func newRotatingCerts(source certSource, refresh time.Duration, logger log.Logger, reg prometheus.Registerer) *rotatingCerts {
+	c := &rotatingCerts{
+		source:  source,
+		refresh: refresh,
+		logger:  logger,
+		expiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "containerslist_internal_tls_certificate_expiry_timestamp_seconds",
+			Help: "Expiry time of the certificate of the internal API.",
+		}),
+	}
+	reg.MustRegister(c.expiry)
+	return c
+}
+
+// load fetches the certificate from the source.
+func (c *rotatingCerts) load(ctx context.Context) error {
+	cert, roots, err := c.source(ctx)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("invalid internal API certificate: %w", err)
+		}
+	}
+	c.mtx.Lock()
+	c.cert, c.roots = cert, roots
+	c.mtx.Unlock()
+	c.expiry.Set(float64(cert.Leaf.NotAfter.Unix()))
+	return nil
+}
+
+func (c *rotatingCerts) current() (*tls.Certificate, *x509.CertPool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.cert, c.roots
+}
+
+// renewIn returns how long to wait before renewing the certificate: until
+// two thirds of its lifetime, at most refresh. A certificate past that point,
+// such as a file not replaced yet by the tool rotating it, is read again after
+// certRetryInterval, or refresh if shorter.
+func (c *rotatingCerts) renewIn(now time.Time) time.Duration {
+	wait := certRetryInterval
+	if cert, _ := c.current(); cert != nil {
+		lifetime := cert.Leaf.NotAfter.Sub(cert.Leaf.NotBefore)
+		if d := cert.Leaf.NotBefore.Add(lifetime * 2 / 3).Sub(now); d > 0 {
+			wait = d
+		}
+	}
+	if c.refresh > 0 && wait > c.refresh {
+		wait = c.refresh
+	}
+	return wait
+}
+
+// Run renews the certificate until ctx is done.
+func (c *rotatingCerts) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.renewIn(time.Now())):
+		}
+		if err := c.load(ctx); err != nil {
+			level.Error(c.logger).Log("msg", "Unable to renew the internal API certificate", "error", err)
+			continue
+		}
+		level.Debug(c.logger).Log("msg", "Internal API certificate renewed")
+	}
+}
+
+// certIdentity returns the identity of a certificate of the internal API: its
+// SPIFFE ID, or else its subject common name, or else its first DNS name.
+func certIdentity(cert *x509.Certificate) string {
+	if id, err := spiffeID(cert); err == nil {
+		return id.String()
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
+
+// verifyServer verifies the certificate chain of a server against the current
+// CAs and, with SPIFFE, its SPIFFE ID against the policy, or else its DNS
+// name.
+func (c *rotatingCerts) verifyServer(cs tls.ConnectionState) error {
+	cert, roots := c.current()
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no certificate presented by the internal API")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}
+	if c.spiffe {
+		opts.DNSName = ""
+	}
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return err
+	}
+	if c.spiffe {
+		return verifySPIFFEPeer(cs.PeerCertificates[0], cert.Leaf, c.policy)
+	}
+	return nil
+}
+
+// serverConfig returns the TLS configuration of a server with the current
+// certificate, verifying the client certificates against the current CAs
+// with clientAuth and, with SPIFFE, their SPIFFE IDs against the policy.
+func (c *rotatingCerts) serverConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := c.current()
+			return cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, roots := c.current()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    roots,
+				ClientAuth:   clientAuth,
+			}
+			if c.spiffe {
+				config.VerifyConnection = func(cs tls.ConnectionState) error {
+					if len(cs.PeerCertificates) == 0 {
+						return nil
+					}
+					return verifySPIFFEPeer(cs.PeerCertificates[0], cert.Leaf, c.policy)
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// config returns the mutual TLS configuration of the internal API, used both
+// to serve it and to call the internal API of peers, with the current
+// certificate and CAs.
+func (c *rotatingCerts) config() *tls.Config {
+	config := c.serverConfig(tls.RequireAndVerifyClientCert)
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		cert, _ := c.current()
+		return cert, nil
+	}
+	// The clients verify the servers against the current CAs too, instead
+	// of the static RootCAs.
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = c.verifyServer
+	return config
+}
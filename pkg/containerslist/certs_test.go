@@ -0,0 +1,66 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRotatingCertsRenewIn(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		// issued is the age of the certificate, of a 90 days lifetime, or
+		// zero for no certificate.
+		issued  time.Duration
+		refresh time.Duration
+		want    time.Duration
+	}{
+		{name: "no certificate", want: certRetryInterval},
+		{name: "fresh", issued: 24 * time.Hour, want: 59 * 24 * time.Hour},
+		{name: "fresh with refresh", issued: 24 * time.Hour, refresh: certFileRefreshInterval, want: certFileRefreshInterval},
+		{name: "past renewal point", issued: 70 * 24 * time.Hour, want: certRetryInterval},
+		{name: "past renewal point with refresh", issued: 70 * 24 * time.Hour, refresh: 10 * time.Second, want: 10 * time.Second},
+		{name: "expired", issued: 100 * 24 * time.Hour, refresh: certFileRefreshInterval, want: certRetryInterval},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newRotatingCerts(nil, tc.refresh, log.NewNopLogger(), prometheus.NewRegistry())
+			if tc.issued > 0 {
+				notBefore := now.Add(-tc.issued)
+				c.cert = &tls.Certificate{Leaf: &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * 24 * time.Hour)}}
+			}
+			if got := c.renewIn(now); got != tc.want {
+				t.Errorf("renewIn = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRotatingCertsRunPastRenewalPoint(t *testing.T) {
+	// The source keeps returning a certificate past its renewal point, as a
+	// file not rotated in time would.
+	notBefore := time.Now().Add(-80 * 24 * time.Hour)
+	cert := &tls.Certificate{Leaf: &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * 24 * time.Hour)}}
+	var loads atomic.Int32
+	source := func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		loads.Add(1)
+		return cert, x509.NewCertPool(), nil
+	}
+	c := newRotatingCerts(source, certFileRefreshInterval, log.NewNopLogger(), prometheus.NewRegistry())
+	if err := c.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c.Run(ctx)
+	if n := loads.Load(); n != 1 {
+		t.Errorf("certificate loaded %d times, want 1", n)
+	}
+}
@@ -0,0 +1,87 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Changes are the inventories of the nodes changed since a sequence of the
+// member answering.
+type Changes struct {
+	// Epoch identifies the incarnation of the member answering, to which the
+	// sequences are local.
+	Epoch string `json:"epoch"`
+	// Sequence is the sequence of the changes merged by the member, to pass
+	// as since, along with the epoch, to get the next changes.
+	Sequence uint64 `json:"sequence"`
+	// Revision is the revision of the cluster.
+	Revision uint64 `json:"revision"`
+	// Full is set when all the inventories are returned, rather than the
+	// changes: the nodes which are not listed are gone.
+	Full bool `json:"full"`
+	// Nodes are the changed inventories, including the tombstones of the
+	// decommissioned nodes, sorted by node name.
+	Nodes []*NodeInventory `json:"nodes"`
+}
+
+// changes returns the inventories changed after the sequence since of the
+// epoch. All of them are returned from sequence 0, or when the epoch is not
+// the one of the member, such as after it restarted or when another member
+// answers behind a load balancer.
+func (m *Manager) changes(epoch string, since uint64) Changes {
+	res := Changes{Revision: m.inventory.Revision(), Nodes: []*NodeInventory{}}
+	res.Epoch, res.Sequence = m.inventory.Sequence()
+	if since == 0 || epoch != res.Epoch {
+		res.Full = true
+		res.Nodes = append(append(res.Nodes, m.inventory.Nodes()...), m.inventory.Tombstones()...)
+		sort.Slice(res.Nodes, func(i, j int) bool { return res.Nodes[i].Node < res.Nodes[j].Node })
+		return res
+	}
+	res.Nodes = append(res.Nodes, m.inventory.ChangedSince(since)...)
+	return res
+}
+
+// apiChanges serves the inventories changed since the sequence of the since
+// and epoch query parameters, for external mirrors of the inventory to sync
+// incrementally. With wait, the request is held until the sequence advances
+// past since or the wait elapses.
+func (m *Manager) apiChanges() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var since uint64
+		if v := q.Get("since"); v != "" {
+			var err error
+			if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid since %q: must be a sequence", v))
+				return
+			}
+		}
+		epoch := q.Get("epoch")
+		if v := q.Get("wait"); v != "" {
+			wait, err := time.ParseDuration(v)
+			if err != nil || wait <= 0 || wait > maxLongPollWait {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid wait %q: must be a positive duration of at most %s", v, maxLongPollWait))
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			for ctx.Err() == nil {
+				// The version is read first, not to miss a change in between.
+				version := m.inventory.Version()
+				if current, seq := m.inventory.Sequence(); current != epoch || seq > since {
+					break
+				}
+				m.inventory.Wait(ctx, version)
+			}
+			cancel()
+			if r.Context().Err() != nil {
+				return
+			}
+		}
+		changes := m.changes(epoch, since)
+		w.Header().Set(clusterRevisionHeader, strconv.FormatUint(changes.Revision, 10))
+		writeJSON(w, changes)
+	})
+}
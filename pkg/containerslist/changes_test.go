@@ -0,0 +1,117 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManagerChanges(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig(), inventory: newInventory()}
+	epoch, _ := m.inventory.Sequence()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(node string, revision uint64, minutes int, containers ...string) *NodeInventory {
+		n := &NodeInventory{Node: node, Revision: revision, Timestamp: start.Add(time.Duration(minutes) * time.Minute)}
+		for _, id := range containers {
+			n.Containers = append(n.Containers, Container{ID: id, State: StateRunning})
+		}
+		return n
+	}
+
+	var sequence uint64
+	for _, step := range []struct {
+		name   string
+		merged []*NodeInventory
+		// epoch is the epoch of the request, the one of the member when
+		// empty.
+		epoch     string
+		full      bool
+		wantNodes []string
+		wantFull  bool
+	}{
+		{
+			name:      "first sync",
+			merged:    []*NodeInventory{entry("c", 1005, 0, "c1"), entry("a", 1000, 0, "a1")},
+			full:      true,
+			wantNodes: []string{"a", "c"},
+			wantFull:  true,
+		},
+		{
+			name:      "older revision merged late",
+			merged:    []*NodeInventory{entry("b", 1003, 0, "b1")},
+			wantNodes: []string{"b"},
+		},
+		{
+			name:      "no change",
+			wantNodes: []string{},
+		},
+		{
+			name:      "unchanged entry gossiped again",
+			merged:    []*NodeInventory{entry("c", 1005, 1, "c1")},
+			wantNodes: []string{},
+		},
+		{
+			name:      "peer without revisions",
+			merged:    []*NodeInventory{entry("d", 0, 0, "d1")},
+			wantNodes: []string{"d"},
+		},
+		{
+			name:      "peer without revisions changed",
+			merged:    []*NodeInventory{entry("d", 0, 1, "d1", "d2")},
+			wantNodes: []string{"d"},
+		},
+		{
+			name:      "stale entry",
+			merged:    []*NodeInventory{entry("a", 999, -1)},
+			wantNodes: []string{},
+		},
+		{
+			name:      "tombstone",
+			merged:    []*NodeInventory{{Node: "a", Revision: 1001, Timestamp: start.Add(time.Minute), Decommissioned: true}},
+			wantNodes: []string{"a"},
+		},
+		{
+			name:      "other epoch",
+			epoch:     "other",
+			wantNodes: []string{"a", "b", "c", "d"},
+			wantFull:  true,
+		},
+	} {
+		t.Run(step.name, func(t *testing.T) {
+			for _, n := range step.merged {
+				m.inventory.mtx.Lock()
+				m.inventory.merge(n)
+				m.inventory.mtx.Unlock()
+			}
+			reqEpoch := epoch
+			if step.epoch != "" {
+				reqEpoch = step.epoch
+			}
+			since := sequence
+			if step.full {
+				since = 0
+			}
+			changes := m.changes(reqEpoch, since)
+			var nodes []string
+			for _, n := range changes.Nodes {
+				nodes = append(nodes, n.Node)
+			}
+			if nodes == nil {
+				nodes = []string{}
+			}
+			if !reflect.DeepEqual(nodes, step.wantNodes) {
+				t.Errorf("changed nodes %v, want %v", nodes, step.wantNodes)
+			}
+			if changes.Full != step.wantFull {
+				t.Errorf("full = %v, want %v", changes.Full, step.wantFull)
+			}
+			if changes.Epoch != epoch {
+				t.Errorf("epoch %q, want %q", changes.Epoch, epoch)
+			}
+			if changes.Sequence < sequence {
+				t.Errorf("sequence went back from %d to %d", sequence, changes.Sequence)
+			}
+			sequence = changes.Sequence
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultChecksumInterval = time.Minute
+
+// InventoryChecksum is the checksum of the inventory of a node, as held by
+// the peer collecting it.
+type InventoryChecksum struct {
+	Node string `json:"node"`
+	// Timestamp is the timestamp of the version of the inventory.
+	Timestamp time.Time `json:"timestamp"`
+	Checksum  string    `json:"checksum"`
+}
+
+// inventoryChecksum returns the checksum of a version of the inventory of a
+// node: the SHA-256 of its protobuf encoding, which is the same on every
+// peer holding it.
+func inventoryChecksum(n *NodeInventory) string {
+	sum := sha256.Sum256(marshalInventoryProto([]*NodeInventory{n}))
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumVerifier cross-verifies the inventories held by the local node
+// against the checksums gossiped by the peers collecting them, catching the
+// silent corruptions of the merges. On a mismatch, the inventory of the node
+// is re-synchronized: the next copy of the same version matching the
+// checksum, pulled from any peer, replaces the corrupted one.
+type checksumVerifier struct {
+	verifications *prometheus.CounterVec
+}
+
+func newChecksumVerifier(reg prometheus.Registerer) *checksumVerifier {
+	v := &checksumVerifier{
+		verifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_inventory_checksum_verifications_total",
+			Help: "Total number of verifications of the inventories of the nodes against the checksums gossiped by the peers collecting them, by result: match or mismatch.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(v.verifications)
+	return v
+}
+
+// publishChecksums gossips the checksums of the inventories of the local
+// nodes.
+func (m *Manager) publishChecksums() error {
+	var items []InventoryChecksum
+	for _, n := range m.inventory.Nodes() {
+		if m.isLocal(n.Node) && !n.Summary {
+			items = append(items, InventoryChecksum{Node: n.Node, Timestamp: n.Timestamp, Checksum: inventoryChecksum(n)})
+		}
+	}
+	b, err := m.checksums.Set(&NodeResources[InventoryChecksum]{Node: m.nodeID, Timestamp: time.Now().UTC(), Items: items})
+	if err != nil {
+		return err
+	}
+	m.checksumsChannel.Broadcast(b)
+	return nil
+}
+
+// verifyChecksums compares the inventories of the nodes of the peers with
+// their checksums. The inventories of other versions, being behind or ahead
+// of the checksums, and the summaries are not compared.
+func (m *Manager) verifyChecksums() {
+	for _, peer := range m.checksums.Nodes() {
+		if peer.Node == m.nodeID {
+			continue
+		}
+		for _, c := range peer.Items {
+			n, ok := m.inventory.Get(c.Node)
+			if !ok || n.Summary || !n.Timestamp.Equal(c.Timestamp) {
+				continue
+			}
+			if inventoryChecksum(n) == c.Checksum {
+				m.checksumVerifier.verifications.WithLabelValues("match").Inc()
+				continue
+			}
+			m.checksumVerifier.verifications.WithLabelValues("mismatch").Inc()
+			level.Warn(m.logger).Log("msg", "Inventory not matching the checksum of its peer, re-synchronizing it", "node", c.Node, "peer", peer.Node)
+			m.inventory.Resync(c.Node, c.Timestamp, c.Checksum)
+		}
+	}
+}
+
+// runChecksums publishes and verifies the checksums at each interval until
+// ctx is done.
+func (m *Manager) runChecksums(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.ChecksumInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.publishChecksums(); err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to publish the inventory checksums", "error", err)
+		}
+		m.verifyChecksums()
+	}
+}
@@ -0,0 +1,104 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInventoryChecksum(t *testing.T) {
+	at := time.Now().UTC()
+	n := &NodeInventory{Node: "a", Timestamp: at, Containers: []Container{{ID: "a1", State: StateRunning}}}
+	c := inventoryChecksum(n)
+	if got := inventoryChecksum(&NodeInventory{Node: "a", Timestamp: at, Containers: []Container{{ID: "a1", State: StateRunning}}}); got != c {
+		t.Errorf("checksum of a copy = %s, want %s", got, c)
+	}
+	if got := inventoryChecksum(&NodeInventory{Node: "a", Timestamp: at, Containers: []Container{{ID: "a1", State: "exited"}}}); got == c {
+		t.Error("checksum of another inventory matching")
+	}
+}
+
+func TestPublishChecksums(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{{ID: "a1"}}},
+		&NodeInventory{Node: "b", Containers: []Container{{ID: "b1"}}},
+	)
+	ch := &testChannel{}
+	m.nodeID, m.sources, m.checksums, m.checksumsChannel = "peer-1", []*dockerSource{{node: "a"}}, newResourceState[InventoryChecksum](), ch
+	if err := m.publishChecksums(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.msgs) != 1 {
+		t.Fatalf("%d messages broadcast, want 1", len(ch.msgs))
+	}
+	var res []NodeResources[InventoryChecksum]
+	if err := json.Unmarshal(ch.msgs[0], &res); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := m.inventory.Get("a")
+	if len(res) != 1 || res[0].Node != "peer-1" || len(res[0].Items) != 1 || res[0].Items[0].Node != "a" || res[0].Items[0].Checksum != inventoryChecksum(a) {
+		t.Errorf("checksums = %+v, want the one of a", res)
+	}
+}
+
+// mergeInventories merges inventories as gossiped by a peer.
+func mergeInventories(t *testing.T, i *inventory, nodes ...*NodeInventory) {
+	t.Helper()
+	b, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	at := time.Now().UTC().Truncate(time.Second)
+	original := &NodeInventory{Node: "a", Timestamp: at, Revision: 1, Containers: []Container{{ID: "a1", State: StateRunning}}}
+	b := &NodeInventory{Node: "b", Timestamp: at, Revision: 1, Containers: []Container{{ID: "b1"}}}
+	m := newTestInventoryManager(t)
+	mergeInventories(t, m.inventory,
+		&NodeInventory{Node: "a", Timestamp: at, Revision: 1, Containers: []Container{{ID: "a1", State: "exited"}}},
+		b,
+		&NodeInventory{Node: "c", Timestamp: at.Add(time.Second), Revision: 1},
+	)
+	m.logger, m.nodeID, m.checksums = log.NewNopLogger(), "peer-1", newResourceState[InventoryChecksum]()
+	m.checksumVerifier = newChecksumVerifier(prometheus.NewRegistry())
+	for _, r := range []*NodeResources[InventoryChecksum]{
+		{Node: "peer-2", Timestamp: at, Items: []InventoryChecksum{
+			{Node: "a", Timestamp: at, Checksum: inventoryChecksum(original)},
+			{Node: "b", Timestamp: at, Checksum: inventoryChecksum(b)},
+			// Another version of c is not compared.
+			{Node: "c", Timestamp: at, Checksum: "stale"},
+		}},
+		// The checksums of the local node are not compared.
+		{Node: "peer-1", Timestamp: at, Items: []InventoryChecksum{{Node: "b", Timestamp: at, Checksum: "corrupted"}}},
+	} {
+		if _, err := m.checksums.Set(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.verifyChecksums()
+	if got := testutil.ToFloat64(m.checksumVerifier.verifications.WithLabelValues("match")); got != 1 {
+		t.Errorf("matches = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.checksumVerifier.verifications.WithLabelValues("mismatch")); got != 1 {
+		t.Errorf("mismatches = %v, want 1", got)
+	}
+
+	// Only a copy of the same version matching the checksum replaces the
+	// corrupted one.
+	mergeInventories(t, m.inventory, &NodeInventory{Node: "a", Timestamp: at, Revision: 1, Containers: []Container{{ID: "a2"}}})
+	if n, _ := m.inventory.Get("a"); n.Containers[0].ID != "a1" {
+		t.Errorf("containers of a = %+v, want the corrupted ones kept", n.Containers)
+	}
+	mergeInventories(t, m.inventory, original)
+	if n, _ := m.inventory.Get("a"); n.Containers[0].State != StateRunning {
+		t.Errorf("containers of a = %+v, want the re-synchronized ones", n.Containers)
+	}
+}
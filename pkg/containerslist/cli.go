@@ -0,0 +1,497 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultCLIServer is the server of the CLI commands when neither a flag nor
+// a profile sets one.
+const defaultCLIServer = "http://localhost:3000"
+
+// cliCommands are the subcommands of the binary, run instead of the server
+// when named by its first argument.
+var cliCommands = map[string]func(args []string) error{
+	"get":        runGet,
+	"top":        runTop,
+	"completion": runCompletion,
+	"members":    runMembers,
+}
+
+// cliConfig is the configuration of the CLI, read from
+// ~/.containerslist/config:
+//
+//	current-profile: prod
+//	profiles:
+//	  prod:
+//	    server: https://containerslist.example.com
+type cliConfig struct {
+	CurrentProfile string                `yaml:"current-profile"`
+	Profiles       map[string]cliProfile `yaml:"profiles"`
+}
+
+// cliProfile is a server to which the CLI sends its requests.
+type cliProfile struct {
+	Server string `yaml:"server"`
+	// AdminTokenFile is the file of the bearer token of the admin
+	// endpoints.
+	AdminTokenFile string `yaml:"admin-token-file"`
+}
+
+func defaultCLIConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".containerslist", "config")
+}
+
+// cliClientFlags are the flags of the CLI commands selecting the server.
+type cliClientFlags struct {
+	config  string
+	profile string
+	server  string
+}
+
+func (f *cliClientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", defaultCLIConfigPath(), "Configuration file of the profiles")
+	fs.StringVar(&f.profile, "profile", "", "Profile of the configuration file; its current profile when empty")
+	fs.StringVar(&f.server, "server", "", "Address of the server, overriding the one of the profile")
+}
+
+// client returns the client of the server of the flags, or else of the
+// selected profile, or else of the local node.
+func (f *cliClientFlags) client() (*client.Client, error) {
+	var cfg cliConfig
+	b, err := os.ReadFile(f.config)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && f.profile == "":
+	case err != nil:
+		return nil, err
+	default:
+		if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid configuration %s: %w", f.config, err)
+		}
+	}
+
+	name := f.profile
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	profile, ok := cfg.Profiles[name]
+	if name != "" && !ok {
+		return nil, fmt.Errorf("no profile %q in %s", name, f.config)
+	}
+	server := f.server
+	if server == "" {
+		server = profile.Server
+	}
+	if server == "" {
+		server = defaultCLIServer
+	}
+	c := client.New(server)
+	if profile.AdminTokenFile != "" {
+		b, err := os.ReadFile(profile.AdminTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		c.AdminToken = strings.TrimSpace(string(b))
+	}
+	return c, nil
+}
+
+// Output formats of the get command.
+const (
+	outputTable = ""
+	outputWide  = "wide"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// cliTable is a table printed with aligned columns, as by kubectl.
+type cliTable struct {
+	headers []string
+	rows    [][]string
+}
+
+func (t *cliTable) print(w io.Writer, headers bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	if headers {
+		fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cliResource is a resource listed by the get command.
+type cliResource struct {
+	// list returns the resource as a value encoded in JSON or YAML, and as a
+	// table, possibly wide.
+	list func(ctx context.Context, c *client.Client, opts getOptions) (interface{}, *cliTable, error)
+	// watch prints the rows of the changes of the resource until the
+	// context is done, or is nil when the resource cannot be watched.
+	watch func(ctx context.Context, c *client.Client, opts getOptions, w io.Writer) error
+}
+
+// cliResources are the resources of the get command, by name and alias.
+var cliResources = map[string]*cliResource{
+	"containers": containersResource,
+	"container":  containersResource,
+	"nodes":      nodesResource,
+	"node":       nodesResource,
+	"peers":      peersResource,
+	"peer":       peersResource,
+}
+
+type getOptions struct {
+	output  string
+	nodes   map[string]bool
+	state   string
+	names   []string
+	headers bool
+}
+
+// selected reports whether a node is selected by the --node flags.
+func (o getOptions) selected(node string) bool {
+	return len(o.nodes) == 0 || o.nodes[node]
+}
+
+// named reports whether a name is one of the arguments of the command.
+func (o getOptions) named(name string) bool {
+	return len(o.names) == 0 || contains(o.names, name)
+}
+
+// runGet lists a resource: containerslist get containers|nodes|peers
+// [name...] [--node node...] [-o wide|json|yaml] [--watch].
+func runGet(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s get containers|nodes|peers [name...] [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	var (
+		cf        cliClientFlags
+		nodes     stringSet
+		output    = fs.String("o", outputTable, "Output format: wide, json or yaml; a table when empty")
+		state     = fs.String("state", "", "State of the containers: running, exited or all; running when empty")
+		watch     = fs.Bool("watch", false, "After listing the containers, watch their changes")
+		noHeaders = fs.Bool("no-headers", false, "Do not print the headers of the table")
+	)
+	cf.register(fs)
+	fs.Var(&nodes, "node", "Only list the containers of this node; may be repeated")
+	fs.StringVar(output, "output", outputTable, "Same as -o")
+	fs.BoolVar(watch, "w", false, "Same as -watch")
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs.Usage()
+		return errors.New("missing resource")
+	}
+	res, ok := cliResources[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown resource %q: must be one of containers, nodes or peers", args[0])
+	}
+	// Flags and names may be interleaved, as in kubectl.
+	opts := getOptions{headers: true}
+	for rest := args[1:]; len(rest) > 0; {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if rest = fs.Args(); len(rest) > 0 {
+			opts.names = append(opts.names, rest[0])
+			rest = rest[1:]
+		}
+	}
+	switch *output {
+	case outputTable, outputWide, outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid output format %q: must be one of wide, json or yaml", *output)
+	}
+	opts.output, opts.nodes, opts.state, opts.headers = *output, nodes, *state, !*noHeaders
+	if *watch && res.watch == nil {
+		return fmt.Errorf("%s cannot be watched", args[0])
+	}
+
+	c, err := cf.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	v, table, err := res.list(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+	if err := printOutput(os.Stdout, opts, v, table); err != nil {
+		return err
+	}
+	if !*watch {
+		return nil
+	}
+	if err := res.watch(ctx, c, opts, os.Stdout); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func printOutput(w io.Writer, opts getOptions, v interface{}, table *cliTable) error {
+	switch opts.output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		b, err := marshalYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return table.print(w, opts.headers)
+}
+
+// marshalYAML encodes a value in YAML with the field names and the order of
+// its JSON encoding.
+func marshalYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// decodeOrdered decodes the next JSON value, with its objects as
+// yaml.MapSlice values to keep the order of their fields.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			values := []interface{}{}
+			for dec.More() {
+				v, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+			_, err := dec.Token()
+			return values, err
+		}
+		fields := yaml.MapSlice{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yaml.MapItem{Key: key, Value: v})
+		}
+		_, err := dec.Token()
+		return fields, err
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return i, nil
+		}
+		return tok.Float64()
+	}
+	return tok, nil
+}
+
+// stringSet is a repeatable flag of distinct strings.
+type stringSet map[string]bool
+
+func (s stringSet) String() string {
+	values := make([]string, 0, len(s))
+	for v := range s {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (s *stringSet) Set(v string) error {
+	if *s == nil {
+		*s = stringSet{}
+	}
+	(*s)[v] = true
+	return nil
+}
+
+var containersResource = &cliResource{
+	list: func(ctx context.Context, c *client.Client, opts getOptions) (interface{}, *cliTable, error) {
+		inventories, err := c.Containers(ctx, opts.state)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected := []*client.NodeInventory{}
+		table := &cliTable{headers: containerHeaders(opts)}
+		for _, n := range inventories {
+			if !opts.selected(n.Node) {
+				continue
+			}
+			var containers []client.Container
+			for _, ctr := range n.Containers {
+				if opts.named(ctr.Name) || opts.named(truncateID(ctr.ID)) {
+					containers = append(containers, ctr)
+					table.rows = append(table.rows, containerRow(opts, n, ctr))
+				}
+			}
+			if len(containers) > 0 || len(opts.names) == 0 {
+				sel := *n
+				sel.Containers = containers
+				selected = append(selected, &sel)
+			}
+		}
+		return selected, table, nil
+	},
+	watch: func(ctx context.Context, c *client.Client, opts getOptions, w io.Writer) error {
+		// The rows of the events are prefixed with their type: appeared or
+		// disappeared.
+		opts.headers = false
+		return c.Events(ctx, func(e client.ContainerEvent) {
+			if !opts.selected(e.Node) || !opts.named(e.Container.Name) && !opts.named(truncateID(e.Container.ID)) {
+				return
+			}
+			if opts.output == outputJSON || opts.output == outputYAML {
+				if opts.output == outputYAML {
+					fmt.Fprintln(w, "---")
+				}
+				printOutput(w, opts, e, nil)
+				return
+			}
+			n := &client.NodeInventory{Node: e.Node}
+			table := &cliTable{rows: [][]string{append([]string{e.Type}, containerRow(opts, n, e.Container)...)}}
+			table.print(w, false)
+		})
+	},
+}
+
+func containerHeaders(opts getOptions) []string {
+	headers := []string{"NODE", "NAME", "IMAGE", "STATE", "STATUS", "AGE"}
+	if opts.output == outputWide {
+		headers = append(headers, "ID", "RESTARTS", "PORTS", "ZONE")
+	}
+	return headers
+}
+
+func containerRow(opts getOptions, n *client.NodeInventory, c client.Container) []string {
+	row := []string{n.Node, c.Name, c.Image, c.State, c.Status, since(c.Created)}
+	if opts.output == outputWide {
+		ports := make([]string, 0, len(c.Ports))
+		for _, p := range c.Ports {
+			ports = append(ports, fmt.Sprintf("%d->%d/%s", p.HostPort, p.ContainerPort, p.Protocol))
+		}
+		row = append(row, truncateID(c.ID), strconv.Itoa(c.RestartCount), orNone(strings.Join(ports, ",")), orNone(n.Zone))
+	}
+	return row
+}
+
+var nodesResource = &cliResource{
+	list: func(ctx context.Context, c *client.Client, opts getOptions) (interface{}, *cliTable, error) {
+		inventories, err := c.Containers(ctx, opts.state)
+		if err != nil {
+			return nil, nil, err
+		}
+		table := &cliTable{headers: []string{"NODE", "CONTAINERS", "STATUS", "AGE"}}
+		if opts.output == outputWide {
+			table.headers = append(table.headers, "ZONE", "TRUNCATED")
+		}
+		selected := []*client.NodeInventory{}
+		for _, n := range inventories {
+			if !opts.selected(n.Node) || !opts.named(n.Node) {
+				continue
+			}
+			selected = append(selected, n)
+			status := "Ready"
+			switch {
+			case n.Offline:
+				status = "Offline"
+			case n.Stale:
+				status = "Stale"
+			case n.Degraded:
+				status = "Degraded"
+			}
+			count := len(n.Containers)
+			if n.TotalContainers > count {
+				count = n.TotalContainers
+			}
+			row := []string{n.Node, strconv.Itoa(count), status, since(n.Timestamp)}
+			if opts.output == outputWide {
+				row = append(row, orNone(n.Zone), strconv.FormatBool(n.Truncated))
+			}
+			table.rows = append(table.rows, row)
+		}
+		return selected, table, nil
+	},
+}
+
+var peersResource = &cliResource{
+	list: func(ctx context.Context, c *client.Client, opts getOptions) (interface{}, *cliTable, error) {
+		peers, err := c.Peers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		table := &cliTable{headers: []string{"NAME", "DISPLAY NAME", "ADDRESS", "NODE"}}
+		if opts.output == outputWide {
+			table.headers = append(table.headers, "CPU", "MEMORY", "DISK", "PASSIVE")
+		}
+		selected := []client.PeerInfo{}
+		for _, p := range peers {
+			if !opts.selected(p.Node) || !opts.named(p.Name) && !opts.named(p.DisplayName) {
+				continue
+			}
+			selected = append(selected, p)
+			row := []string{p.Name, orNone(p.DisplayName), p.Address, orNone(p.Node)}
+			if opts.output == outputWide {
+				cpu, mem, disk := "<none>", "<none>", "<none>"
+				if u := p.Usage; u != nil {
+					cpu = fmt.Sprintf("%.0f%%", u.CPUPercent)
+					mem = fmt.Sprintf("%.0f%%", u.MemoryPercent)
+					disk = fmt.Sprintf("%.0f%%", u.DiskPercent)
+				}
+				row = append(row, cpu, mem, disk, strconv.FormatBool(p.Passive))
+			}
+			table.rows = append(table.rows, row)
+		}
+		return selected, table, nil
+	},
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+func TestCLIClientFlags(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("admin\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte(`current-profile: prod
+profiles:
+  prod:
+    server: https://containerslist.example.com/
+    admin-token-file: `+tokenFile+`
+  staging:
+    server: https://staging.example.com
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		flags     cliClientFlags
+		wantURL   string
+		wantToken string
+	}{
+		{flags: cliClientFlags{config: config}, wantURL: "https://containerslist.example.com", wantToken: "admin"},
+		{flags: cliClientFlags{config: config, profile: "staging"}, wantURL: "https://staging.example.com"},
+		{flags: cliClientFlags{config: config, profile: "staging", server: "http://node-1:3000"}, wantURL: "http://node-1:3000"},
+		{flags: cliClientFlags{config: filepath.Join(dir, "missing")}, wantURL: defaultCLIServer},
+	} {
+		c, err := tc.flags.client()
+		if err != nil {
+			t.Errorf("client(%+v): %v", tc.flags, err)
+			continue
+		}
+		if c.BaseURL != tc.wantURL || c.AdminToken != tc.wantToken {
+			t.Errorf("client(%+v) = %s with token %q, want %s with token %q", tc.flags, c.BaseURL, c.AdminToken, tc.wantURL, tc.wantToken)
+		}
+	}
+
+	for _, flags := range []cliClientFlags{
+		{config: config, profile: "dev"},
+		{config: filepath.Join(dir, "missing"), profile: "prod"},
+		{config: tokenFile},
+	} {
+		if _, err := flags.client(); err == nil {
+			t.Errorf("client(%+v) succeeded, want an error", flags)
+		}
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	b, err := marshalYAML([]PeerInfo{{Name: "p1", Address: "10.0.0.1:9094", Node: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fields are in the order of the JSON encoding.
+	want := "- name: p1\n  displayName: \"\"\n  address: 10.0.0.1:9094\n  node: a\n"
+	if !strings.HasPrefix(string(b), want) {
+		t.Errorf("YAML = %q, want prefix %q", b, want)
+	}
+	if b, err = marshalYAML(map[string]interface{}{"count": 3, "ratio": 0.5}); err != nil || string(b) != "count: 3\nratio: 0.5\n" {
+		t.Errorf("YAML = %q, %v", b, err)
+	}
+}
+
+func TestGetContainers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/containers" || r.URL.Query().Get("state") != "all" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"node": "a", "zone": "eu-1", "containers": [
+				{"id": "0123456789abcdef", "name": "web", "image": "nginx", "state": "running", "restartCount": 2, "ports": [{"hostPort": 8080, "containerPort": 80, "protocol": "tcp"}]},
+				{"id": "fedcba9876543210", "name": "job", "image": "busybox", "state": "exited"}
+			]},
+			{"node": "b", "stale": true, "containers": [{"id": "b1", "name": "web", "image": "nginx", "state": "running"}]}
+		]`))
+	}))
+	defer srv.Close()
+	c := client.New(srv.URL)
+
+	list := func(res *cliResource, opts getOptions) string {
+		t.Helper()
+		v, table, err := res.list(context.Background(), c, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := printOutput(&b, opts, v, table); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	got := list(containersResource, getOptions{state: "all", headers: true, names: []string{"web"}})
+	want := "NODE   NAME   IMAGE   STATE     STATUS   AGE\n" +
+		"a      web    nginx   running            \n" +
+		"b      web    nginx   running            \n"
+	if got != want {
+		t.Errorf("containers:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = list(containersResource, getOptions{state: "all", output: outputWide, nodes: map[string]bool{"a": true}, names: []string{"0123456789ab"}})
+	if want := "a   web   nginx   running         0123456789ab   2   8080->80/tcp   eu-1\n"; got != want {
+		t.Errorf("wide containers = %q, want %q", got, want)
+	}
+
+	got = list(containersResource, getOptions{state: "all", output: outputJSON, names: []string{"job"}})
+	if !strings.Contains(got, `"name": "job"`) || strings.Contains(got, `"name": "web"`) || strings.Contains(got, `"node": "b"`) {
+		t.Errorf("JSON containers = %s, want the job of a", got)
+	}
+
+	got = list(nodesResource, getOptions{state: "all", headers: true, output: outputWide})
+	want = "NODE   CONTAINERS   STATUS   AGE   ZONE     TRUNCATED\n" +
+		"a      2            Ready          eu-1     false\n" +
+		"b      1            Stale          <none>   false\n"
+	if got != want {
+		t.Errorf("nodes:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, _, err := containersResource.list(context.Background(), c, getOptions{}); err == nil {
+		t.Error("list of running containers succeeded, want the error of the server")
+	}
+}
+
+func TestRunGetErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"-o", "json"},
+		{"volumes"},
+		{"containers", "-o", "xml"},
+		{"nodes", "--watch"},
+	} {
+		if err := runGet(args); err == nil {
+			t.Errorf("get %q succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clockSample is the time of a peer when its state was sent.
+type clockSample struct {
+	Peer string    `json:"peer"`
+	Time time.Time `json:"time"`
+}
+
+// peerSkew is the estimated clock skew of a peer.
+type peerSkew struct {
+	skew time.Duration
+	seen time.Time
+}
+
+// clockState estimates the clock skew of the peers: it is exchanged during
+// the push/pull of the cluster state, its receiver comparing the time of the
+// sender with its own. The estimate includes the network latency, small
+// compared to the skews breaking the last-write-wins merge of the inventory.
+type clockState struct {
+	self      string
+	threshold time.Duration
+	logger    log.Logger
+
+	// displayNames returns the display names of the peers, by name, for
+	// the labels of the metrics.
+	displayNames func() map[string]string
+
+	mtx   sync.Mutex
+	skews map[string]peerSkew
+}
+
+var peerClockSkewDesc = prometheus.NewDesc(
+	"containerslist_peer_clock_skew_seconds",
+	"Estimated clock skew of a peer, positive when its clock is ahead of the local one.",
+	[]string{"peer"}, nil,
+)
+
+func newClockState(self string, threshold time.Duration, logger log.Logger) *clockState {
+	return &clockState{
+		self:      self,
+		threshold: threshold,
+		logger:    logger,
+		skews:     map[string]peerSkew{},
+	}
+}
+
+// MarshalBinary implements cluster.State.
+func (c *clockState) MarshalBinary() ([]byte, error) {
+	return json.Marshal(clockSample{Peer: c.self, Time: time.Now()})
+}
+
+// Merge implements cluster.State.
+func (c *clockState) Merge(b []byte) error {
+	var s clockSample
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s.Peer == "" || s.Peer == c.self {
+		return nil
+	}
+	now := time.Now()
+	skew := s.Time.Sub(now)
+
+	c.mtx.Lock()
+	prev, known := c.skews[s.Peer]
+	c.skews[s.Peer] = peerSkew{skew: skew, seen: now}
+	c.mtx.Unlock()
+
+	if c.exceeds(skew) && (!known || !c.exceeds(prev.skew)) {
+		level.Warn(c.logger).Log("msg", "Clock skew with peer exceeds threshold", "peer", s.Peer, "skew", skew, "threshold", c.threshold)
+	}
+	return nil
+}
+
+func (c *clockState) exceeds(skew time.Duration) bool {
+	return math.Abs(float64(skew)) > float64(c.threshold)
+}
+
+// Skews returns the estimated clock skew of the peers seen recently.
+func (c *clockState) Skews() map[string]time.Duration {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	skews := map[string]time.Duration{}
+	for peer, s := range c.skews {
+		if time.Since(s.seen) > 10*time.Minute {
+			delete(c.skews, peer)
+			continue
+		}
+		skews[peer] = s.skew
+	}
+	return skews
+}
+
+// Describe implements prometheus.Collector.
+func (c *clockState) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerClockSkewDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *clockState) Collect(ch chan<- prometheus.Metric) {
+	names := map[string]string{}
+	if c.displayNames != nil {
+		names = c.displayNames()
+	}
+	for peer, skew := range c.Skews() {
+		if name, ok := names[peer]; ok {
+			peer = name
+		}
+		ch <- prometheus.MustNewConstMetric(peerClockSkewDesc, prometheus.GaugeValue, skew.Seconds(), peer)
+	}
+}
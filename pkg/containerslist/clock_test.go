@@ -0,0 +1,81 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClockState(t *testing.T) {
+	c := newClockState("self", time.Second, log.NewNopLogger())
+	c.displayNames = func() map[string]string { return map[string]string{"ahead": "node-ahead"} }
+	merge := func(peer string, offset time.Duration) {
+		t.Helper()
+		b, err := json.Marshal(clockSample{Peer: peer, Time: time.Now().Add(offset)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	merge("ahead", time.Hour)
+	merge("behind", -100*time.Millisecond)
+	merge("self", time.Hour)
+	merge("", time.Hour)
+	if err := c.Merge([]byte("not json")); err == nil {
+		t.Error("Merge of an invalid state succeeded, want an error")
+	}
+
+	// The state sent is the local time.
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s clockSample
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Peer != "self" || time.Since(s.Time) > time.Second {
+		t.Errorf("state = %+v, want the local time of self", s)
+	}
+
+	skews := c.Skews()
+	if len(skews) != 2 {
+		t.Fatalf("skews = %v, want ahead and behind", skews)
+	}
+	if skew := skews["ahead"]; !c.exceeds(skew) || skew < 59*time.Minute {
+		t.Errorf("skew of ahead = %v, want about 1h", skew)
+	}
+	if skew := skews["behind"]; c.exceeds(skew) || skew > 0 {
+		t.Errorf("skew of behind = %v, want about -100ms", skew)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	if n, err := testutil.GatherAndCount(reg, "containerslist_peer_clock_skew_seconds"); err != nil || n != 2 {
+		t.Errorf("%d skew metrics, %v, want 2", n, err)
+	}
+	metrics, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var peers []string
+	for _, metric := range metrics[0].Metric {
+		peers = append(peers, metric.Label[0].GetValue())
+	}
+	if got := strings.Join(peers, ","); got != "behind,node-ahead" {
+		t.Errorf("peer labels = %s, want behind and the display name of ahead", got)
+	}
+
+	// Peers not seen recently are forgotten.
+	c.skews["ahead"] = peerSkew{skew: time.Hour, seen: time.Now().Add(-time.Hour)}
+	if _, ok := c.Skews()["ahead"]; ok {
+		t.Error("skew of a peer not seen for 1h still reported")
+	}
+}
@@ -0,0 +1,122 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Formats of the container events.
+const (
+	eventFormatNative      = "native"
+	eventFormatCloudEvents = "cloudevents"
+)
+
+const (
+	defaultCloudEventsSource = "/containerslist"
+	// cloudEventsTypePrefix is prefixed to the type of the events, in the
+	// reverse-DNS notation recommended by the CloudEvents specification.
+	cloudEventsTypePrefix    = "io.containerslist.container."
+	cloudEventsContentType   = "application/cloudevents+json"
+	cloudEventsSpecVersion   = "1.0"
+	cloudEventsDataMediaType = "application/json"
+)
+
+// CloudEvent is a container event in the structured JSON format of
+// CloudEvents 1.0, for Knative, EventBridge and the other event routers.
+type CloudEvent struct {
+	SpecVersion string `json:"specversion"`
+	// ID is the same for the retries of an event, for the routers to
+	// deduplicate them.
+	ID string `json:"id"`
+	// Source identifies the node of the container, such as
+	// /containerslist/nodes/node-01.
+	Source string `json:"source"`
+	// Type is io.containerslist.container.appeared or
+	// io.containerslist.container.disappeared.
+	Type string `json:"type"`
+	// Subject is the ID of the container.
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	// Node is an extension attribute holding the name of the node, for the
+	// routers to filter on it.
+	Node string    `json:"node"`
+	Data Container `json:"data"`
+}
+
+// newCloudEvent returns the CloudEvent of a container event, its source
+// under a base URI reference.
+func newCloudEvent(e ContainerEvent, source string) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              e.Node + "/" + e.Container.ID + "/" + e.Type + "/" + e.Time.Format(time.RFC3339Nano),
+		Source:          strings.TrimSuffix(source, "/") + "/nodes/" + url.PathEscape(e.Node),
+		Type:            cloudEventsTypePrefix + e.Type,
+		Subject:         e.Container.ID,
+		Time:            e.Time,
+		DataContentType: cloudEventsDataMediaType,
+		Node:            e.Node,
+		Data:            e.Container,
+	}
+}
+
+// eventFormat returns the format of the events of a query: the format
+// parameter, or else the default of the node.
+func (m *Manager) eventFormat(q url.Values) (string, error) {
+	format := q.Get("format")
+	if format == "" {
+		format = m.cfg.EventsFormat
+	}
+	if format != eventFormatNative && format != eventFormatCloudEvents {
+		return "", fmt.Errorf("invalid event format %q: must be %s or %s", format, eventFormatNative, eventFormatCloudEvents)
+	}
+	return format, nil
+}
+
+// cloudEventsSink posts each event in the structured mode of the HTTP
+// binding of CloudEvents, such as to a Knative broker or an EventBridge API
+// destination.
+type cloudEventsSink struct {
+	url    string
+	source string
+	client *http.Client
+}
+
+func newCloudEventsSink(u *url.URL, cfg *Config) (eventSink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid CloudEvents sink %q: must be cloudevents+http[s]://host[:port]/path", u.Redacted())
+	}
+	target := *u
+	target.Scheme = strings.TrimPrefix(u.Scheme, "cloudevents+")
+	return &cloudEventsSink{
+		url:    target.String(),
+		source: cfg.CloudEventsSource,
+		client: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// Write posts the events one by one, the routers seldom accepting the
+// batched mode. The events of a retried batch keep their IDs.
+func (s *cloudEventsSink) Write(ctx context.Context, rows []SinkRow) error {
+	for _, r := range rows {
+		b, err := json.Marshal(newCloudEvent(r.event, s.source))
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", cloudEventsContentType)
+		if err := doSinkRequest(s.client, req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package containerslist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewCloudEvent(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ce := newCloudEvent(ContainerEvent{Type: EventAppeared, Node: "edge/a", Time: at, Container: Container{ID: "c1", Name: "web"}}, "https://fleet.example.com/")
+	want := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "edge/a/c1/appeared/2024-03-01T12:00:00Z",
+		Source:          "https://fleet.example.com/nodes/edge%2Fa",
+		Type:            "io.containerslist.container.appeared",
+		Subject:         "c1",
+		Time:            at,
+		DataContentType: "application/json",
+		Node:            "edge/a",
+		Data:            Container{ID: "c1", Name: "web"},
+	}
+	if !reflect.DeepEqual(ce, want) {
+		t.Errorf("event = %+v, want %+v", ce, want)
+	}
+
+	m := &Manager{cfg: DefaultConfig()}
+	for query, want := range map[string]string{"": eventFormatNative, "format=cloudevents": eventFormatCloudEvents, "format=xml": ""} {
+		q, _ := url.ParseQuery(query)
+		if got, err := m.eventFormat(q); got != want || (err != nil) != (want == "") {
+			t.Errorf("format of %q = %q, %v, want %q", query, got, err, want)
+		}
+	}
+	m.cfg.EventsFormat = eventFormatCloudEvents
+	if got, _ := m.eventFormat(nil); got != eventFormatCloudEvents {
+		t.Errorf("default format = %q, want the one of the node", got)
+	}
+}
+
+func TestCloudEventsStream(t *testing.T) {
+	m := newTestInventoryManager(t)
+	m.events = newEventBroker()
+	srv := httptest.NewServer(m.apiEvents())
+	defer srv.Close()
+	defer m.events.Close()
+
+	resp, err := http.Get(srv.URL + "?format=cloudevents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	m.events.Publish([]ContainerEvent{{Type: EventAppeared, Node: "a", Time: time.Now(), Container: Container{ID: "c1"}}})
+	sc := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 2 && sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if len(lines) != 2 || lines[0] != "event: io.containerslist.container.appeared" {
+		t.Fatalf("stream = %q, want the appeared CloudEvent", lines)
+	}
+	var ce CloudEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &ce); err != nil {
+		t.Fatal(err)
+	}
+	if ce.Source != defaultCloudEventsSource+"/nodes/a" || ce.Subject != "c1" {
+		t.Errorf("event = %+v, want the one of c1 on a", ce)
+	}
+}
+
+func TestCloudEventsSink(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		events []CloudEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/broker" || r.Header.Get("Content-Type") != cloudEventsContentType {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var ce CloudEvent
+		if err := json.NewDecoder(r.Body).Decode(&ce); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mtx.Lock()
+		events = append(events, ce)
+		mtx.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.CloudEventsSource = "urn:fleet"
+	s, err := newEventSink("cloudevents+"+srv.URL+"/broker", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []SinkRow{
+		newSinkRow(ContainerEvent{Type: EventAppeared, Node: "a", Time: time.Now(), Container: Container{ID: "c1"}}),
+		newSinkRow(ContainerEvent{Type: EventDisappeared, Node: "a", Time: time.Now(), Container: Container{ID: "c2"}}),
+	}
+	if err := s.Write(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(events) != 2 || events[0].Subject != "c1" || events[1].Type != "io.containerslist.container.disappeared" || events[0].Source != "urn:fleet/nodes/a" {
+		t.Errorf("events = %+v, want the ones of c1 and c2", events)
+	}
+
+	if _, err := newEventSink("cloudevents+https:///broker", cfg); err == nil {
+		t.Error("sink without a host accepted")
+	}
+}
@@ -0,0 +1,261 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// dockerSource is a Docker endpoint collected by the local agent. The main
+// endpoint is reported under the node name; additional endpoints appear as
+// virtual sub-nodes.
+type dockerSource struct {
+	// name is the name of the endpoint, empty for the main one.
+	name   string
+	node   string
+	client *dockerClient
+
+	exitedSince map[string]time.Time
+	details     map[string]containerDetails
+	anomalies   *anomalyDetector
+}
+
+func newDockerSource(name, node, host string, anomalies *anomalyDetector) (*dockerSource, error) {
+	client, err := newDockerClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerSource{
+		name:        name,
+		node:        node,
+		client:      client,
+		exitedSince: map[string]time.Time{},
+		details:     map[string]containerDetails{},
+		anomalies:   anomalies,
+	}, nil
+}
+
+// healthName is the name of the source in the health status.
+func (s *dockerSource) healthName() string {
+	if s.name == "" {
+		return SourceDocker
+	}
+	return SourceDocker + ":" + s.name
+}
+
+// parseDockerEndpoints parses a comma-separated list of name=host endpoints.
+func parseDockerEndpoints(s string) (map[string]string, error) {
+	endpoints := map[string]string{}
+	if s == "" {
+		return endpoints, nil
+	}
+	for _, e := range strings.Split(s, ",") {
+		name, host, ok := strings.Cut(strings.TrimSpace(e), "=")
+		if !ok || name == "" || host == "" {
+			return nil, fmt.Errorf("invalid docker endpoint %q: must be name=host", e)
+		}
+		if _, ok := endpoints[name]; ok {
+			return nil, fmt.Errorf("duplicate docker endpoint %q", name)
+		}
+		endpoints[name] = host
+	}
+	return endpoints, nil
+}
+
+// collect lists the containers of a source and broadcasts them to the
+// cluster.
+func (m *Manager) collect(ctx context.Context, s *dockerSource) error {
+	containers, err := s.client.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var degraded bool
+	if m.kubelet != nil && s.name == "" && !m.health.Cordoned(SourceKubelet) {
+		var owners map[string]podOwner
+		err := m.breakers.Get(SourceKubelet).Do(ctx, func() (err error) {
+			owners, err = m.kubelet.PodOwners(ctx)
+			return err
+		})
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to list pods from the kubelet", "error", err)
+			m.health.Failure(SourceKubelet, err)
+			degraded = true
+		} else {
+			m.health.Success(SourceKubelet)
+		}
+		attachPods(containers, owners)
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[string]struct{}, len(containers))
+	for i := range containers {
+		seen[containers[i].ID] = struct{}{}
+		m.attachDetails(ctx, s, &containers[i])
+	}
+	for id := range s.details {
+		if _, ok := seen[id]; !ok {
+			delete(s.details, id)
+		}
+	}
+	s.anomalies.flag(containers, now)
+	kept := retainExited(s.exitedSince, containers, now, m.cfg.ExitedRetention)
+
+	b, err := m.inventory.Set(&NodeInventory{
+		Node:       s.node,
+		Timestamp:  now,
+		Containers: kept,
+		Degraded:   degraded,
+		Edge:       m.cfg.EdgeMode,
+	})
+	if err != nil {
+		return err
+	}
+	m.channel.Broadcast(b)
+
+	images, err := m.collectImages(ctx, s, kept)
+	if err != nil {
+		level.Warn(m.logger).Log("msg", "Unable to list images", "source", s.healthName(), "error", err)
+	}
+	if err := m.recommendPrune(s, containers, images); err != nil {
+		level.Warn(m.logger).Log("msg", "Unable to compute prune candidates", "source", s.healthName(), "error", err)
+	}
+	return nil
+}
+
+// retainExited returns the containers which are not exited, along with the
+// exited and dead ones which are still within the retention window. The
+// window is counted from the first time a container was seen exited or dead,
+// as recorded in exitedSince. The paused, created and restarting containers
+// still exist and are always kept.
+func retainExited(exitedSince map[string]time.Time, containers []Container, now time.Time, retention time.Duration) []Container {
+	kept := make([]Container, 0, len(containers))
+	seen := make(map[string]struct{}, len(containers))
+	for _, c := range containers {
+		seen[c.ID] = struct{}{}
+		if c.State != StateExited && c.State != StateDead {
+			delete(exitedSince, c.ID)
+			kept = append(kept, c)
+			continue
+		}
+
+		since, ok := exitedSince[c.ID]
+		if !ok {
+			since = now
+			exitedSince[c.ID] = since
+		}
+		if now.Sub(since) <= retention {
+			kept = append(kept, c)
+		}
+	}
+	for id := range exitedSince {
+		if _, ok := seen[id]; !ok {
+			delete(exitedSince, id)
+		}
+	}
+	return kept
+}
+
+// markNodeDegraded broadcasts the last known inventory of a node, flagged as
+// degraded, when its runtime cannot be reached.
+func (m *Manager) markNodeDegraded(node string) error {
+	prev, ok := m.inventory.Get(node)
+	if !ok {
+		return nil
+	}
+
+	n := *prev
+	n.Timestamp = time.Now().UTC()
+	n.Degraded = true
+	b, err := m.inventory.Set(&n)
+	if err != nil {
+		return err
+	}
+	m.channel.Broadcast(b)
+	return nil
+}
+
+// collectResources lists the networks and volumes of a source and broadcasts
+// them to the cluster.
+func (m *Manager) collectResources(ctx context.Context, s *dockerSource) error {
+	now := time.Now().UTC()
+
+	networks, err := s.client.ListNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := m.networks.Set(&NodeResources[Network]{Node: s.node, Timestamp: now, Items: networks})
+	if err != nil {
+		return err
+	}
+	m.networksChannel.Broadcast(b)
+
+	volumes, err := s.client.ListVolumes(ctx)
+	if err != nil {
+		return err
+	}
+	b, err = m.volumes.Set(&NodeResources[Volume]{Node: s.node, Timestamp: now, Items: volumes})
+	if err != nil {
+		return err
+	}
+	m.volumesChannel.Broadcast(b)
+	return nil
+}
+
+// attachDetails fills in the details of a container, inspecting it only when
+// it is first seen or has changed state, or else every inspect refresh
+// interval to catch the restarts happening between two collections.
+func (m *Manager) attachDetails(ctx context.Context, s *dockerSource, c *Container) {
+	d, ok := s.details[c.ID]
+	if !ok || d.state != c.State || (m.cfg.InspectRefreshInterval > 0 && time.Since(d.inspectedAt) > m.cfg.InspectRefreshInterval) {
+		var err error
+		d, err = s.client.InspectContainer(ctx, c.ID)
+		if err != nil {
+			level.Debug(m.logger).Log("msg", "Unable to inspect container", "container", c.ID, "error", err)
+			return
+		}
+		s.details[c.ID] = d
+	}
+	c.Devices = d.devices
+	c.GPUs = d.gpus
+	c.FinishedAt = d.finishedAt
+	c.StartedAt = d.startedAt
+	c.RestartCount = d.restartCount
+	c.OOMKilled = d.oomKilled
+}
+
+// runCollector periodically collects the inventory of a source. When the
+// runtime cannot be reached, the last known inventory keeps being served
+// flagged as degraded, and collection is retried with an exponential backoff.
+func (m *Manager) runCollector(ctx context.Context, s *dockerSource) {
+	for {
+		delay := m.cfg.CollectInterval
+		if m.health.Cordoned(s.healthName()) {
+			level.Debug(m.logger).Log("msg", "Skipping cordoned source", "source", s.healthName())
+		} else if err := m.collect(ctx, s); err != nil {
+			failures := m.health.Failure(s.healthName(), err)
+			delay = retryBackoff(failures, m.cfg.CollectInterval)
+			level.Warn(m.logger).Log("msg", "Unable to list containers", "source", s.healthName(), "error", err, "failures", failures, "retry_in", delay)
+			if err := m.markNodeDegraded(s.node); err != nil {
+				level.Warn(m.logger).Log("msg", "Unable to flag inventory as degraded", "source", s.healthName(), "error", err)
+			}
+		} else {
+			m.health.Success(s.healthName())
+			if err := m.collectResources(ctx, s); err != nil {
+				level.Warn(m.logger).Log("msg", "Unable to list networks and volumes", "source", s.healthName(), "error", err)
+			}
+			if err := m.collectFacts(ctx, s); err != nil {
+				level.Warn(m.logger).Log("msg", "Unable to gather node facts", "source", s.healthName(), "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package containerslist
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestRetainExited(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-defaultExitedRetention - time.Minute)
+	recent := now.Add(-time.Minute)
+
+	for _, tc := range []struct {
+		name      string
+		state     string
+		since     time.Time
+		wantKept  bool
+		wantSince bool
+	}{
+		{name: "running", state: StateRunning, wantKept: true},
+		{name: "paused", state: "paused", since: expired, wantKept: true},
+		{name: "created", state: "created", since: expired, wantKept: true},
+		{name: "restarting", state: "restarting", since: expired, wantKept: true},
+		{name: "newly exited", state: StateExited, wantKept: true, wantSince: true},
+		{name: "recently exited", state: StateExited, since: recent, wantKept: true, wantSince: true},
+		{name: "exited beyond retention", state: StateExited, since: expired, wantSince: true},
+		{name: "dead beyond retention", state: StateDead, since: expired, wantSince: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exitedSince := map[string]time.Time{"gone": recent}
+			if !tc.since.IsZero() {
+				exitedSince["c"] = tc.since
+			}
+			kept := retainExited(exitedSince, []Container{{ID: "c", State: tc.state}}, now, defaultExitedRetention)
+			if got := len(kept) == 1; got != tc.wantKept {
+				t.Errorf("kept = %v, want %v", got, tc.wantKept)
+			}
+			if _, got := exitedSince["c"]; got != tc.wantSince {
+				t.Errorf("tracked = %v, want %v", got, tc.wantSince)
+			}
+			if _, ok := exitedSince["gone"]; ok {
+				t.Error("containers no longer listed must not be tracked")
+			}
+		})
+	}
+}
+
+func TestParseDockerEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		s       string
+		want    map[string]string
+		wantErr bool
+	}{
+		{s: "", want: map[string]string{}},
+		{s: "gpu=unix:///run/gpu/docker.sock, edge=tcp://10.0.0.5:2375", want: map[string]string{"gpu": "unix:///run/gpu/docker.sock", "edge": "tcp://10.0.0.5:2375"}},
+		{s: "gpu", wantErr: true},
+		{s: "=tcp://10.0.0.5:2375", wantErr: true},
+		{s: "gpu=", wantErr: true},
+		{s: "gpu=tcp://a:2375,gpu=tcp://b:2375", wantErr: true},
+	} {
+		got, err := parseDockerEndpoints(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseDockerEndpoints(%q) error = %v, want error %v", tc.s, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseDockerEndpoints(%q) = %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestCollectSubNode(t *testing.T) {
+	client := newTestDockerClient(t, map[string]string{
+		"/containers/json":    `[{"Id": "c1", "Names": ["/web"], "Image": "nginx:1.25", "ImageID": "sha256:web", "State": "running", "Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}, {"PrivatePort": 443, "Type": "tcp"}]}]`,
+		"/containers/c1/json": `{"State": {"Status": "running", "StartedAt": "2024-01-01T12:00:00Z"}, "RestartCount": 2}`,
+		"/images/json":        `[{"Id": "sha256:web", "RepoTags": ["nginx:1.25"]}]`,
+	})
+	s := &dockerSource{name: "gpu", node: "n1/gpu", client: client, exitedSince: map[string]time.Time{}, details: map[string]containerDetails{}, anomalies: newAnomalyDetector(0, time.Hour, 0)}
+	if s.healthName() != "docker:gpu" {
+		t.Errorf("health name = %q, want docker:gpu", s.healthName())
+	}
+
+	m := newTestInventoryManager(t)
+	m.logger = log.NewNopLogger()
+	m.sources = []*dockerSource{{node: "n1"}, s}
+	m.channel, m.imagesChannel, m.pruneChannel = &testChannel{}, &testChannel{}, &testChannel{}
+	m.images, m.pruneCandidates = newResourceState[Image](), newResourceState[PruneCandidate]()
+	if err := m.collect(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	n, ok := m.inventory.Get("n1/gpu")
+	if !ok || len(n.Containers) != 1 {
+		t.Fatalf("inventory of n1/gpu = %+v, want one container", n)
+	}
+	c := n.Containers[0]
+	if c.Name != "web" || c.RestartCount != 2 || c.StartedAt == nil || !reflect.DeepEqual(c.Ports, []Port{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}) {
+		t.Errorf("container = %+v", c)
+	}
+	if img, ok := m.images.Get("n1/gpu"); !ok || len(img.Items) != 1 || img.Items[0].Containers != 1 {
+		t.Errorf("images = %+v, want nginx used once", img)
+	}
+	if !m.isLocal("n1/gpu") || m.isLocal("n2") {
+		t.Error("the sub-node is not local")
+	}
+}
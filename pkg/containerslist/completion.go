@@ -0,0 +1,103 @@
+package containerslist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completionScripts are the completion scripts of the CLI commands, by
+// shell. The names of the nodes are completed from the output of the get
+// command, with the server of the current profile.
+var completionScripts = map[string]string{
+	"bash": `_containerslist() {
+	local cur prev
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[COMP_CWORD-1]}"
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "{{commands}}" -- "$cur"))
+		return
+	fi
+	case "$prev" in
+	-o|--o|-output|--output)
+		COMPREPLY=($(compgen -W "wide json yaml" -- "$cur")); return ;;
+	-state|--state)
+		COMPREPLY=($(compgen -W "running exited all" -- "$cur")); return ;;
+	-sort|--sort)
+		COMPREPLY=($(compgen -W "{{sortKeys}}" -- "$cur")); return ;;
+	-node|--node)
+		COMPREPLY=($(compgen -W "$({{binary}} get nodes -no-headers 2>/dev/null | cut -d' ' -f1)" -- "$cur")); return ;;
+	-config|--config)
+		COMPREPLY=($(compgen -f -- "$cur")); return ;;
+	esac
+	case "${COMP_WORDS[1]}" in
+	get)
+		if [ "$COMP_CWORD" -eq 2 ]; then
+			COMPREPLY=($(compgen -W "{{resources}}" -- "$cur"))
+		else
+			COMPREPLY=($(compgen -W "{{getFlags}}" -- "$cur"))
+		fi ;;
+	top)
+		COMPREPLY=($(compgen -W "{{topFlags}}" -- "$cur")) ;;
+	completion)
+		COMPREPLY=($(compgen -W "{{shells}}" -- "$cur")) ;;
+	esac
+}
+complete -F _containerslist {{binary}}
+`,
+	"zsh": `#compdef {{binary}}
+autoload -U bashcompinit && bashcompinit
+{{bash}}`,
+	"fish": `complete -c {{binary}} -f
+complete -c {{binary}} -n '__fish_use_subcommand' -a '{{commands}}'
+complete -c {{binary}} -n '__fish_seen_subcommand_from get; and test (count (commandline -opc)) -eq 2' -a '{{resources}}'
+complete -c {{binary}} -n '__fish_seen_subcommand_from completion' -a '{{shells}}'
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o o -o output -x -a 'wide json yaml'
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o state -x -a 'running exited all'
+complete -c {{binary}} -n '__fish_seen_subcommand_from top' -o sort -x -a '{{sortKeys}}'
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o node -x -a '({{binary}} get nodes -no-headers 2>/dev/null | string split -f1 " ")'
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o profile -x
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o server -x
+complete -c {{binary}} -n '__fish_seen_subcommand_from get top' -o config -r -F
+complete -c {{binary}} -n '__fish_seen_subcommand_from get' -o watch -o w -o no-headers
+`,
+}
+
+// runCompletion prints the completion script of a shell:
+// containerslist completion bash|zsh|fish.
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s completion %s", filepath.Base(os.Args[0]), strings.Join(sortedKeys(completionScripts), "|"))
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q: must be one of %s", args[0], strings.Join(sortedKeys(completionScripts), ", "))
+	}
+	binary := filepath.Base(os.Args[0])
+	r := strings.NewReplacer(
+		"{{bash}}", completionScripts["bash"],
+		"{{binary}}", binary,
+		// Listing cliCommands here would be an initialization cycle.
+		"{{commands}}", "completion get members top",
+		"{{resources}}", strings.Join(sortedKeys(cliResources), " "),
+		"{{shells}}", strings.Join(sortedKeys(completionScripts), " "),
+		"{{sortKeys}}", strings.Join(topSortKeys, " "),
+		"{{getFlags}}", "-o -output -node -state -watch -w -no-headers -server -profile -config",
+		"{{topFlags}}", "-node -state -sort -refresh -server -profile -config",
+	)
+	// The bash script is embedded in the zsh one before its placeholders
+	// are replaced.
+	fmt.Print(r.Replace(r.Replace(script)))
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
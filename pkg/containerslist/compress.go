@@ -0,0 +1,231 @@
+package containerslist
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultCompressionMinSize is the size under which responses are not worth
+// compressing.
+const defaultCompressionMinSize = 1024
+
+// compressibleTypes are the media types of the responses compressed: the
+// pages, the JSON of the API and the event streams.
+var compressibleTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/csv",
+	"text/event-stream",
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// compressionMetrics observe how much the responses are compressed.
+type compressionMetrics struct {
+	ratio *prometheus.HistogramVec
+	bytes *prometheus.CounterVec
+}
+
+func newCompressionMetrics(reg prometheus.Registerer) *compressionMetrics {
+	c := &compressionMetrics{
+		ratio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "containerslist_http_compression_ratio",
+			Help:    "Ratio of the uncompressed to the compressed size of the compressed HTTP responses, by encoding.",
+			Buckets: []float64{1, 1.5, 2, 3, 5, 8, 13, 21, 34},
+		}, []string{"encoding"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_http_compression_bytes_total",
+			Help: "Size of the compressed HTTP responses, by encoding, before and after compression.",
+		}, []string{"encoding", "stage"}),
+	}
+	reg.MustRegister(c.ratio, c.bytes)
+	return c
+}
+
+// compress compresses the responses of the compressible types with gzip for
+// the clients accepting it, once they reach minSize bytes. The event streams
+// are compressed right away, each flush flushing the compressor, so that
+// their events and keep-alives are not delayed.
+func (c *compressionMetrics) compress(minSize int) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsEncoding(r, "gzip") {
+				h.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK, metrics: c}
+			defer cw.close()
+			h.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of a request
+// accepts a content coding.
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.TrimSpace(name)
+			if name != coding && name != "*" {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			if f, err := strconv.ParseFloat(q, 64); err == nil && f > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the beginning of a response until it knows whether
+// to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	metrics *compressionMetrics
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+	in, out countingWriter
+}
+
+// countingWriter counts the bytes written to a writer.
+type countingWriter struct {
+	io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += n
+	return n, err
+}
+
+// WriteHeader implements http.ResponseWriter, delaying the header until the
+// response is known to be compressed or not.
+func (w *compressWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.status = status
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		return w.write(p)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(append(w.buf, p...)))
+	}
+	if !w.compressible() {
+		w.decide(false)
+		return w.write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize || w.streaming() {
+		w.decide(true)
+	}
+	return len(p), nil
+}
+
+func (w *compressWriter) write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.in.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// compressible reports whether the response can be compressed, from its
+// status, type and encoding.
+func (w *compressWriter) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	return contains(compressibleTypes, mediaType)
+}
+
+// streaming reports whether the response is an event stream.
+func (w *compressWriter) streaming() bool {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// decide writes the header, compressing the response or not, then the
+// buffered beginning of the response.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.out = countingWriter{Writer: w.ResponseWriter}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(&w.out)
+		w.in = countingWriter{Writer: w.gz}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.write(w.buf)
+		w.buf = nil
+	}
+}
+
+// Flush implements http.Flusher, for the event streams.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.compressible() && w.streaming())
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for
+// http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close writes the rest of the response, observing its compression.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(io.Discard)
+	gzipWriters.Put(w.gz)
+	w.metrics.bytes.WithLabelValues("gzip", "uncompressed").Add(float64(w.in.n))
+	w.metrics.bytes.WithLabelValues("gzip", "compressed").Add(float64(w.out.n))
+	if w.out.n > 0 {
+		w.metrics.ratio.WithLabelValues("gzip").Observe(float64(w.in.n) / float64(w.out.n))
+	}
+}
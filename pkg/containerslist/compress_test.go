@@ -0,0 +1,101 @@
+package containerslist
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                        false,
+		"gzip":                    true,
+		"deflate, gzip;q=0.5":     true,
+		"br, gzip;q=0":            false,
+		"*":                       true,
+		"identity, br;q=1, *;q=0": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsEncoding(r, "gzip"); got != want {
+			t.Errorf("acceptsEncoding(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	c := newCompressionMetrics(prometheus.NewRegistry())
+	large := strings.Repeat(`{"node":"a"},`, 200)
+	serve := func(contentType, body, encoding string, status int) *httptest.ResponseRecorder {
+		h := c.compress(defaultCompressionMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/containers", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serve("application/json", large, "gzip", http.StatusTeapot)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Code != http.StatusTeapot || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("status %d with headers %v, want a gzipped 418", rec.Code, rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(zr); err != nil || string(b) != large {
+		t.Errorf("uncompressed body = %d bytes, %v, want the response", len(b), err)
+	}
+	if got := testutil.ToFloat64(c.bytes.WithLabelValues("gzip", "uncompressed")); got != float64(len(large)) {
+		t.Errorf("uncompressed bytes = %v, want %d", got, len(large))
+	}
+	if got := testutil.CollectAndCount(c.ratio); got != 1 {
+		t.Errorf("%d ratio series, want 1", got)
+	}
+
+	for _, tc := range []struct {
+		name, contentType, body, encoding string
+	}{
+		{name: "small", contentType: "application/json", body: `{}`, encoding: "gzip"},
+		{name: "not accepted", contentType: "application/json", body: large, encoding: "br"},
+		{name: "image", contentType: "image/png", body: large, encoding: "gzip"},
+	} {
+		rec := serve(tc.contentType, tc.body, tc.encoding, http.StatusOK)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != tc.body {
+			t.Errorf("%s: response compressed with %q", tc.name, rec.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	c := newCompressionMetrics(prometheus.NewRegistry())
+	h := c.compress(defaultCompressionMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n")
+		w.(http.Flusher).Flush()
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Header().Get("Content-Encoding") != "gzip" || !rec.Flushed {
+		t.Errorf("headers %v, flushed: %v, want a gzipped stream flushed", rec.Header(), rec.Flushed)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != ": keep-alive\n\n" {
+		t.Errorf("stream = %q, want the keep-alive", b)
+	}
+}
@@ -0,0 +1,492 @@
+package containerslist
+
+import (
+	"flag"
+	"time"
+
+	"github.com/prometheus/alertmanager/cluster"
+)
+
+// Config is the configuration of a manager, documented by the usages of its
+// flags. Main sets it from the command line; embedders start from
+// DefaultConfig, and set the fields of the flags taking a list, such as
+// Policies, with their Set method.
+type Config struct {
+	HTTPAddress string
+
+	GossipInterval   time.Duration
+	PushPullInterval time.Duration
+	ListenAddress    string
+	AdvertiseAddress string
+	ClusterLabel     string
+	Peers            string
+	ExpectedSize     int
+	Gateway          bool
+	GatewayTimeout   time.Duration
+	Shards           int
+	ShardReplicas    int
+	Passive          bool
+	BootstrapURL     string
+	BootstrapPeers   bool
+
+	PeersFilePath     string
+	PeersFileInterval time.Duration
+
+	DiscoveryProvider  string
+	DiscoveryInterval  time.Duration
+	DiscoveryPort      string
+	DiscoveryAWSRegion string
+	DiscoveryAWSTag    string
+	DiscoveryAWSASG    string
+
+	DiscoveryGCEProject         string
+	DiscoveryGCELabel           string
+	DiscoveryAzureSubscription  string
+	DiscoveryAzureResourceGroup string
+	DiscoveryAzureScaleSet      string
+	DiscoveryDNSName            string
+
+	ClockSkewThreshold time.Duration
+	ChecksumInterval   time.Duration
+
+	BreakerThreshold   int
+	BreakerOpenTimeout time.Duration
+
+	SchedulerJitter time.Duration
+	SnapshotFile    string
+
+	ShutdownDrainTimeout      time.Duration
+	ShutdownCollectorsTimeout time.Duration
+	ShutdownLeaveTimeout      time.Duration
+
+	BroadcastRateLimit     int
+	StateTransferRateLimit int
+
+	SampleUsage   bool
+	ProcPath      string
+	UsageDiskPath string
+
+	InspectRefreshInterval time.Duration
+	AnomalyRestarts        int
+	AnomalyRestartWindow   time.Duration
+	AnomalyMaxUptime       time.Duration
+
+	ContainerUsageInterval time.Duration
+
+	CostPerNodeHour float64
+	CostPerCPUHour  float64
+	CostLabel       string
+
+	ImageAllowlistPatterns string
+
+	FetchSBOMs bool
+
+	EdgeMode       bool
+	EdgeBufferSize int
+
+	RedisKeyPrefix    string
+	RedisSyncInterval time.Duration
+	RedisTimeout      time.Duration
+
+	ExportURL                 string
+	ExportEndpoint            string
+	ExportRegion              string
+	ExportPrefix              string
+	ExportFormat              string
+	ExportSecretAccessKeyFile string
+
+	EventSinkURL          string
+	SinkBatchSize         int
+	SinkFlushInterval     time.Duration
+	SinkMaxRetries        int
+	EventsFormat          string
+	CloudEventsSource     string
+	SinkBigQueryTokenFile string
+
+	BlocklistEntries string
+
+	DecommissionTimeout time.Duration
+
+	InternalListenAddress    string
+	InternalAdvertiseAddress string
+	InternalTLSCertFile      string
+	InternalTLSKeyFile       string
+	InternalTLSCAFile        string
+
+	InternalTLSVaultPKI        string
+	InternalTLSVaultCommonName string
+	InternalTLSVaultAltNames   string
+	InternalTLSVaultTTL        time.Duration
+
+	SPIFFESVIDDir  string
+	SPIFFETrust    spiffePolicy
+	HTTPTLSSPIFFE  bool
+	HTTPSPIFFERole string
+
+	MetricsNodeLabels         string
+	MetricsNodeLabelMaxValues int
+
+	Zone                string
+	CrossZoneStaleAfter time.Duration
+
+	NodeIDFile      string
+	FingerprintFile string
+
+	GossipRecordFile  string
+	GossipReplayFile  string
+	GossipReplaySpeed float64
+
+	GossipTrustedKeys string
+	GossipKeysRefresh time.Duration
+
+	DockerHost      string
+	DockerEndpoints string
+	CollectInterval time.Duration
+	ExitedRetention time.Duration
+	StaleAfter      time.Duration
+
+	FakeSpec string
+
+	MaxContainersPerNode int
+	MemoryBudget         int64
+
+	PruneStoppedAfter time.Duration
+	APIKeysFile       string
+	IdempotencyTTL    time.Duration
+
+	SSHTargets        string
+	SSHRuntime        string
+	SSHKeyFile        string
+	SSHKnownHostsFile string
+
+	Kubernetes                bool
+	KubeletURL                string
+	KubeletTokenFile          string
+	KubeletInsecureSkipVerify bool
+
+	MinRuntimeVersion string
+
+	PortsRange               string
+	PortsExhaustionThreshold float64
+
+	HistoryResolution time.Duration
+	HistoryRetention  time.Duration
+
+	HistoryDownsampledResolution time.Duration
+	HistoryDownsampledRetention  time.Duration
+
+	EphemeralAnnotationMaxTTL time.Duration
+
+	MaintenanceWindowMaxDuration time.Duration
+
+	HTTPMiddlewares    string
+	HTTPRateLimit      float64
+	HTTPRateLimitBurst int
+	CompressionMinSize int
+
+	HTTPMaxBodySize       int64
+	HTTPMaxHeaderSize     int
+	HTTPMaxConnsPerIP     int
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPIdleTimeout       time.Duration
+
+	UIPageSize  int
+	UIViewsFile string
+
+	StatsdAddress       string
+	StatsdFormat        string
+	StatsdPrefix        string
+	StatsdTags          string
+	StatsdMetrics       string
+	StatsdFlushInterval time.Duration
+
+	GoGC               int
+	GoMemLimit         int64
+	GoMemLimitRatio    float64
+	GoMemLimitInterval time.Duration
+
+	UpdateFeedURL       string
+	UpdateCheckInterval time.Duration
+	UpdateAuto          bool
+	UpdateTrustedKeys   string
+
+	UIColumns         uiColumns
+	Schedules         jobSchedules
+	AnnotationSchemas annotationSchemas
+
+	Policies policies
+
+	PeerDisplayName peerNameTemplate
+	PeerAliases     peerAliases
+	PeerSites       peerSites
+
+	NodeCosts nodeCosts
+
+	NodeLabels nodeTags
+
+	MiddlewareOptOuts middlewareOptOuts
+
+	// Secrets, which may be given as references resolved by ResolveSecrets.
+	AdminToken        Secret
+	HTTPAuthToken     Secret
+	GossipSigningKey  Secret
+	RedisURL          Secret
+	ExportAccessKeyID Secret
+
+	GossipEncryptionKeys Secret
+}
+
+// DefaultConfig returns the configuration of the default values of the
+// flags.
+func DefaultConfig() *Config {
+	c := &Config{}
+	c.RegisterFlags(flag.NewFlagSet("containerslist", flag.ContinueOnError))
+	return c
+}
+
+// RegisterFlags registers the flags of the configuration on a flag set,
+// setting the fields to their defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.HTTPAddress, "http", defaultHttpListenAddress, "HTTP listen address")
+
+	fs.DurationVar(&c.GossipInterval, "ha_gossip_interval", defaultGossipInterval, "HA gossip interval")
+	fs.DurationVar(&c.PushPullInterval, "ha_push_pull_interval", cluster.DefaultPushPullInterval, "HA push/pull interval")
+	fs.StringVar(&c.ListenAddress, "ha_listen_address", defaultClusterAddress, "HA listen address")
+	fs.StringVar(&c.AdvertiseAddress, "ha_advertise_address", "", "HA advertise address")
+	fs.StringVar(&c.ClusterLabel, "ha_label", "", "HA label")
+	fs.StringVar(&c.Peers, "ha_peers", "", "HA peers")
+	fs.IntVar(&c.ExpectedSize, "ha_expected_size", 0, "Expected number of members of the cluster, used to detect partitions; by default, the largest size seen since startup")
+	fs.BoolVar(&c.Gateway, "ha_gateway", false, "Gateway mode: list queries are fanned out to the internal API of the peers instead of answered from the gossiped inventory")
+	fs.DurationVar(&c.GatewayTimeout, "ha_gateway_timeout", defaultGatewayTimeout, "Timeout of the queries to each peer in gateway mode")
+	fs.IntVar(&c.Shards, "ha_shards", 0, "Number of shards of the nodes for very large fleets: each shard is assigned to -ha_shard_replicas members on a consistent hash ring, which keep the full inventories of its nodes while the other members only keep their counts of containers per image, and the queries fan out to the owners of the shards over the internal API; not sharded when 0")
+	fs.IntVar(&c.ShardReplicas, "ha_shard_replicas", 2, "Number of members aggregating each shard")
+	fs.BoolVar(&c.Passive, "ha_passive", false, "Passive member mode, for dashboards and API gateways in a DMZ: the node receives the gossiped state to serve the API and the UI, but collects no node, aggregates no shard and never writes the shared history")
+	fs.StringVar(&c.BootstrapURL, "ha_bootstrap_url", "", "URL of the internal state endpoint of a peer, from which the full state is pulled once on startup")
+	fs.BoolVar(&c.BootstrapPeers, "ha_bootstrap_from_peers", false, "Pull the full state once on startup from the internal API of a peer, as known once joined, preferring the peers of the local -zone, instead of -ha_bootstrap_url")
+
+	fs.StringVar(&c.PeersFilePath, "ha_peers_file", "", "File listing peers joined along with -ha_peers, one host or host:port per line with # comments, such as /etc/containerslist/peers.txt; it is watched for changes, the new peers being joined and the removed ones forgotten by the mesh once they stop")
+	fs.DurationVar(&c.PeersFileInterval, "ha_peers_file_interval", defaultPeersFileInterval, "Interval at which -ha_peers_file is polled for changes, when it cannot be watched")
+
+	fs.StringVar(&c.DiscoveryProvider, "ha_discovery", "", "Provider with which the peers are discovered, in addition to -ha_peers: aws, gce, azure or dns; the peers found at startup are joined with -ha_peers, and the ones found by the periodic refreshes afterwards are joined too; not discovered when empty")
+	fs.DurationVar(&c.DiscoveryInterval, "ha_discovery_interval", defaultDiscoveryInterval, "Interval between two refreshes of the peers found by -ha_discovery")
+	fs.StringVar(&c.DiscoveryPort, "ha_discovery_port", "", "Gossip port of the peers found by -ha_discovery, and of the ones of -ha_peers_file without a port; the port of -ha_listen_address by default")
+	fs.StringVar(&c.DiscoveryAWSRegion, "ha_discovery_aws_region", "", "Region of the EC2 instances discovered by the aws discovery; the region of the instance by default")
+	fs.StringVar(&c.DiscoveryAWSTag, "ha_discovery_aws_tag", "", "Tag of the EC2 instances discovered by the aws discovery, as key=value")
+	fs.StringVar(&c.DiscoveryAWSASG, "ha_discovery_aws_asg", "", "Auto Scaling group of the EC2 instances discovered by the aws discovery")
+
+	fs.StringVar(&c.DiscoveryGCEProject, "ha_discovery_gce_project", "", "Project of the GCE instances discovered by the gce discovery; the project of the instance by default")
+	fs.StringVar(&c.DiscoveryGCELabel, "ha_discovery_gce_label", "", "Label of the GCE instances discovered by the gce discovery, in all the zones of the project, as key=value, such as the label of the template of an instance group")
+	fs.StringVar(&c.DiscoveryAzureSubscription, "ha_discovery_azure_subscription", "", "Subscription of the scale set discovered by the azure discovery; the subscription of the instance by default")
+	fs.StringVar(&c.DiscoveryAzureResourceGroup, "ha_discovery_azure_resource_group", "", "Resource group of the scale set discovered by the azure discovery; the resource group of the instance by default")
+	fs.StringVar(&c.DiscoveryAzureScaleSet, "ha_discovery_azure_scale_set", "", "Virtual machine scale set whose instances are discovered by the azure discovery; the scale set of the instance by default")
+	fs.StringVar(&c.DiscoveryDNSName, "ha_discovery_dns_name", "", "Name resolved at each refresh of the dns discovery: its SRV records when it starts with an underscore, such as _gossip._tcp.containerslist.example.org, or else its A and AAAA records")
+
+	fs.DurationVar(&c.ClockSkewThreshold, "ha_clock_skew_threshold", defaultClockSkewThreshold, "Clock skew with a peer above which a warning is raised")
+	fs.DurationVar(&c.ChecksumInterval, "ha_checksum_interval", defaultChecksumInterval, "Interval at which the checksums of the inventories of the local nodes are gossiped, and the inventories of the other nodes verified against the checksums of their peers, re-synchronizing the ones not matching; not verified when 0")
+
+	fs.IntVar(&c.BreakerThreshold, "breaker_failure_threshold", defaultBreakerThreshold, "Consecutive failures of a Docker daemon, SSH host, kubelet or peer after which it is no longer called for a while; circuit breakers are disabled when 0")
+	fs.DurationVar(&c.BreakerOpenTimeout, "breaker_open_timeout", defaultBreakerOpenTimeout, "How long an open circuit breaker rejects calls before letting a probe call through")
+
+	fs.DurationVar(&c.SchedulerJitter, "scheduler_jitter", defaultSchedulerJitter, "Maximum random delay added to each run of the scheduled jobs")
+	fs.StringVar(&c.SnapshotFile, "snapshot_file", "", "File to which the snapshot job writes the cluster inventory; the job is disabled when empty")
+
+	fs.DurationVar(&c.ShutdownDrainTimeout, "shutdown_drain_timeout", defaultShutdownDrainTimeout, "How long in-flight HTTP requests are waited for on shutdown")
+	fs.DurationVar(&c.ShutdownCollectorsTimeout, "shutdown_collectors_timeout", defaultShutdownCollectorsTimeout, "How long the running collections are waited for on shutdown")
+	fs.DurationVar(&c.ShutdownLeaveTimeout, "shutdown_leave_timeout", defaultShutdownLeaveTimeout, "How long the leave of the cluster is waited for on shutdown")
+
+	fs.IntVar(&c.BroadcastRateLimit, "ha_broadcast_rate_limit", 0, "Maximum rate in bytes per second of the state updates broadcast to the cluster, for bandwidth-constrained links; unlimited when 0")
+	fs.IntVar(&c.StateTransferRateLimit, "internal_state_rate_limit", 0, "Maximum rate in bytes per second of the full-state transfers served to each peer by the internal API; unlimited when 0")
+
+	fs.BoolVar(&c.SampleUsage, "node_usage", false, "Sample the CPU, memory and disk usage of the host from /proc, and gossip it to show it next to the node")
+	fs.StringVar(&c.ProcPath, "proc_path", "/proc", "Path of the proc filesystem of the host, such as /host/proc when running in a container")
+	fs.StringVar(&c.UsageDiskPath, "node_usage_disk_path", "/", "Path whose filesystem disk usage is sampled")
+
+	fs.DurationVar(&c.InspectRefreshInterval, "inspect_refresh_interval", time.Minute, "Interval after which the containers are inspected again even if their state did not change, to catch their restarts; only on state changes when 0")
+	fs.IntVar(&c.AnomalyRestarts, "anomaly_restarts", 3, "Number of restarts within -anomaly_restart_window after which a container is flagged as restart_loop; disabled when 0")
+	fs.DurationVar(&c.AnomalyRestartWindow, "anomaly_restart_window", 10*time.Minute, "Window over which the restarts of a container are counted")
+	fs.DurationVar(&c.AnomalyMaxUptime, "anomaly_max_uptime", 0, "Uptime after which a running container is flagged as long_uptime, not having picked up image updates since; disabled when 0")
+
+	fs.DurationVar(&c.ContainerUsageInterval, "container_usage_interval", 0, "Interval between samples of the CPU and memory usage of the running containers, from the Docker stats API; not sampled when 0")
+
+	fs.Float64Var(&c.CostPerNodeHour, "cost_per_node_hour", 0, "Hourly cost of a node, attributed evenly to its running containers in the costs API")
+	fs.Float64Var(&c.CostPerCPUHour, "cost_per_cpu_hour", 0, "Hourly cost of a CPU; the cost of the nodes whose number of CPUs is known is the cost of their CPUs, rather than -cost_per_node_hour")
+	fs.StringVar(&c.CostLabel, "cost_label", "team", "Container label by which the costs are grouped")
+
+	fs.StringVar(&c.ImageAllowlistPatterns, "image_allowlist", "", "Comma-separated registries, such as ghcr.io, or image patterns, such as docker.io/library/* or ghcr.io/acme/*, allowed to run in the cluster; the containers of other images are reported")
+
+	fs.BoolVar(&c.FetchSBOMs, "sbom", false, "Fetch the SBOMs attached to the running images in their registries, as BuildKit attestations or OCI referrers, to search their packages")
+
+	fs.BoolVar(&c.EdgeMode, "edge", false, "Run as an edge node, frequently disconnected from the cluster: the state updates are buffered while the node has no peer and forwarded on reconnect, and the other nodes report its inventory as last synced rather than stale")
+	fs.IntVar(&c.EdgeBufferSize, "edge_buffer_size", defaultEdgeBufferSize, "Maximum number of state updates buffered by an edge node while disconnected; the oldest ones are dropped beyond")
+
+	fs.StringVar(&c.RedisKeyPrefix, "redis_key_prefix", "containerslist:", "Prefix of the Redis keys of the shared state, for several clusters to share a Redis server")
+	fs.DurationVar(&c.RedisSyncInterval, "redis_sync_interval", 15*time.Second, "Interval between two syncs of the shared state with Redis")
+	fs.DurationVar(&c.RedisTimeout, "redis_timeout", 5*time.Second, "Timeout of the connections and commands to Redis")
+
+	fs.StringVar(&c.ExportURL, "export_url", "", "Bucket to which the export job uploads snapshots of the cluster inventory, as s3://bucket or gs://bucket; the job is disabled when empty")
+	fs.StringVar(&c.ExportEndpoint, "export_endpoint", "", "Endpoint of the S3-compatible object storage of the export bucket; by default, the AWS endpoint of -export_region for s3:// buckets, and https://storage.googleapis.com for gs:// buckets")
+	fs.StringVar(&c.ExportRegion, "export_region", "us-east-1", "Region of the export bucket, used to sign the requests; auto for GCS")
+	fs.StringVar(&c.ExportPrefix, "export_prefix", `containerslist/{{ .Time.Format "2006/01/02" }}/`, "Template of the prefix of the exported objects, executed against the .Time of the export and the exporting .Node; date-based prefixes let lifecycle rules expire old snapshots")
+	fs.StringVar(&c.ExportFormat, "export_format", exportJSON, "Format of the exported snapshots: json, or parquet with one row per container")
+	fs.StringVar(&c.ExportSecretAccessKeyFile, "export_secret_access_key_file", "", "File holding the secret access key of the export bucket; AWS_SECRET_ACCESS_KEY by default")
+
+	fs.StringVar(&c.EventSinkURL, "event_sink", "", "Analytics database to which the container events of the local nodes are streamed, as clickhouse://[user:password@]host:port/[database.]table (clickhouses:// for HTTPS), bigquery://project/dataset.table, or cloudevents+http[s]://host/path to post them as CloudEvents, such as to a Knative broker; not streamed when empty")
+	fs.IntVar(&c.SinkBatchSize, "event_sink_batch_size", 500, "Maximum number of events written to the event sink at once")
+	fs.DurationVar(&c.SinkFlushInterval, "event_sink_flush_interval", 5*time.Second, "Maximum delay before the pending events are written to the event sink")
+	fs.IntVar(&c.SinkMaxRetries, "event_sink_max_retries", 5, "Number of retries of a failed write to the event sink, with an exponential backoff, before its events are dropped")
+	fs.StringVar(&c.EventsFormat, "events_format", eventFormatNative, "Default format of the stream of container events, overridden by its format parameter: native, or cloudevents for the structured JSON format of CloudEvents 1.0")
+	fs.StringVar(&c.CloudEventsSource, "cloudevents_source", defaultCloudEventsSource, "URI reference under which the source of the CloudEvents is the node of their container, as <source>/nodes/<node>")
+	fs.StringVar(&c.SinkBigQueryTokenFile, "event_sink_bigquery_token_file", "", "File holding the OAuth2 access token of the BigQuery sink, read on each write; by default, the token of the service account of the instance, from the metadata server")
+
+	fs.StringVar(&c.BlocklistEntries, "ha_blocklist", "", "Comma-separated peer names or CIDRs whose gossiped updates are dropped and whose state transfers are rejected")
+
+	fs.DurationVar(&c.DecommissionTimeout, "ha_decommission_timeout", defaultDecommissionTimeout, "How long a decommissioned node waits for a quorum of peers to acknowledge its tombstones before exiting")
+
+	fs.StringVar(&c.InternalListenAddress, "internal_listen_address", "", "Listen address of the internal API, served over mutual TLS; the internal API is disabled when empty")
+	fs.StringVar(&c.InternalAdvertiseAddress, "internal_advertise_address", "", "Address of the internal API advertised to peers, which forward to it the requests about the local nodes; by default, the internal listen port on the HA advertised host")
+	fs.StringVar(&c.InternalTLSCertFile, "internal_tls_cert_file", "", "Certificate of the internal API, also used as client certificate towards peers")
+	fs.StringVar(&c.InternalTLSKeyFile, "internal_tls_key_file", "", "Private key of the internal API certificate")
+	fs.StringVar(&c.InternalTLSCAFile, "internal_tls_ca_file", "", "CA used to verify the certificates of peers on the internal API")
+
+	fs.StringVar(&c.InternalTLSVaultPKI, "internal_tls_vault_pki", "", "Issue endpoint of a Vault PKI secrets engine from which the certificate of the internal API is issued and renewed, such as pki/issue/containerslist, instead of the certificate files; the issuing CA is trusted along with -internal_tls_ca_file")
+	fs.StringVar(&c.InternalTLSVaultCommonName, "internal_tls_vault_common_name", "", "Common name of the certificates issued by Vault; the hostname by default")
+	fs.StringVar(&c.InternalTLSVaultAltNames, "internal_tls_vault_alt_names", "", "Comma-separated subject alternative names of the certificates issued by Vault")
+	fs.DurationVar(&c.InternalTLSVaultTTL, "internal_tls_vault_ttl", defaultInternalTLSVaultTTL, "Lifetime of the certificates issued by Vault, renewed after two thirds of it")
+
+	fs.StringVar(&c.SPIFFESVIDDir, "spiffe_svid_dir", "", "Directory of the X.509 SVID of the node, written and rotated by the SPIFFE helper or the SPIRE agent as svid.pem, svid_key.pem and svid_bundle.pem; when set, it is the certificate of the internal API, instead of the certificate files or Vault, and peers are verified by their SPIFFE IDs")
+	fs.Var(&c.SPIFFETrust, "spiffe_trust", "Comma-separated trust domains, such as example.org, or SPIFFE IDs, such as spiffe://example.org/containerslist, ending with /* to include the IDs under them, of the peers and clients allowed by their SVID; the trust domain of the node by default")
+	fs.BoolVar(&c.HTTPTLSSPIFFE, "http_tls_spiffe", false, "Serve the HTTP server over TLS with the SVID of -spiffe_svid_dir; the clients presenting an SVID must be allowed by -spiffe_trust and are authenticated with -http_spiffe_role")
+	fs.StringVar(&c.HTTPSPIFFERole, "http_spiffe_role", RoleViewer, "Role granted to the clients of the HTTP server authenticated by their SVID: viewer, editor or admin")
+
+	fs.StringVar(&c.MetricsNodeLabels, "metrics_node_labels", "", "Comma-separated node labels, such as zone,role,tenant, added to the labels of the per-node metrics; the values of each label beyond -metrics_node_label_max_values are reported as other")
+	fs.IntVar(&c.MetricsNodeLabelMaxValues, "metrics_node_label_max_values", defaultMetricsNodeLabelMaxValues, "Maximum number of values of each label of -metrics_node_labels, guarding against the cardinality of the series")
+
+	fs.StringVar(&c.Zone, "zone", "", "Zone of the node, such as its cloud availability zone; the nodes of other zones are flagged in the containers API, and the peers of the zone are preferred to query the shards, in gateway mode and to bootstrap the state")
+	fs.DurationVar(&c.CrossZoneStaleAfter, "cross_zone_stale_after", 0, "Age after which the inventory of a node in another zone is reported as stale; -stale_after when 0")
+
+	fs.StringVar(&c.NodeIDFile, "node_id_file", "", "File persisting the node ID across restarts; by default, the node is identified by its random cluster name")
+	fs.StringVar(&c.FingerprintFile, "fingerprint_file", defaultFingerprintFile, "File holding a stable identifier of the host, such as its machine ID, from which the fingerprint of the node is derived to recognize it across hostname changes; none when empty")
+
+	fs.StringVar(&c.GossipRecordFile, "gossip_record_file", "", "File to which the incoming gossiped updates are appended, for debugging")
+	fs.StringVar(&c.GossipReplayFile, "gossip_replay_file", "", "File of recorded gossiped updates merged on startup, instead of collecting the local sources, to reproduce the state of a node offline")
+	fs.Float64Var(&c.GossipReplaySpeed, "gossip_replay_speed", 0, "Speed factor at which the recorded updates are replayed, relative to their recorded pace; all at once when 0")
+
+	fs.StringVar(&c.GossipTrustedKeys, "gossip_trusted_keys", "", "File of PEM-encoded Ed25519 public keys; when set, gossiped state not signed by one of them is rejected")
+	fs.DurationVar(&c.GossipKeysRefresh, "gossip_keys_refresh_interval", time.Minute, "Interval between two reads of the gossip signing key and encryption keys references and of the trusted keys, for them to be rotated without restarts; never when 0")
+
+	fs.StringVar(&c.DockerHost, "docker_host", defaultDockerHost, "Docker daemon address")
+	fs.StringVar(&c.DockerEndpoints, "docker_endpoints", "", "Additional Docker daemons, as a comma-separated list of name=address, each reported as a sub-node")
+	fs.DurationVar(&c.CollectInterval, "collect_interval", defaultCollectInterval, "Interval between two container listings")
+	fs.DurationVar(&c.ExitedRetention, "exited_retention", defaultExitedRetention, "How long exited containers are kept in the inventory")
+	fs.DurationVar(&c.StaleAfter, "stale_after", defaultStaleAfter, "Age after which the inventory of a node is reported as stale")
+
+	fs.StringVar(&c.FakeSpec, "discovery.fake", "", "Generate synthetic nodes and containers with churn instead of listing the local sources, as nodes:N,containers:M, such as nodes:5,containers:200")
+
+	fs.IntVar(&c.MaxContainersPerNode, "inventory.max-containers-per-node", 0, "Maximum number of containers of a node kept in the inventory, the other ones being counted per image; unlimited when 0")
+	fs.Int64Var(&c.MemoryBudget, "inventory.memory-budget", 0, "Memory budget in bytes of the inventory of a node, estimated from its gossiped encoding, above which containers are counted per image; unlimited when 0")
+
+	fs.DurationVar(&c.PruneStoppedAfter, "prune_stopped_after", defaultPruneStoppedAfter, "Age after which stopped containers are recommended for pruning")
+	fs.StringVar(&c.APIKeysFile, "api_keys_file", "", "File persisting the API keys of the node, managed through the admin API and requiring the auth middleware; API keys are disabled when empty")
+	fs.DurationVar(&c.IdempotencyTTL, "idempotency_ttl", defaultIdempotencyTTL, "How long the responses of admin requests bearing an Idempotency-Key header are kept to answer their retries")
+
+	fs.StringVar(&c.SSHTargets, "ssh_targets", "", "Remote hosts whose containers are listed over SSH, as a comma-separated list of name=user@host[:port]")
+	fs.StringVar(&c.SSHRuntime, "ssh_runtime", RuntimeDocker, "Runtime of the SSH targets: docker or crictl")
+	fs.StringVar(&c.SSHKeyFile, "ssh_key_file", "", "Private key used to connect to the SSH targets")
+	fs.StringVar(&c.SSHKnownHostsFile, "ssh_known_hosts_file", "", "Known hosts file used to verify the SSH targets")
+
+	fs.BoolVar(&c.Kubernetes, "kubernetes", false, "Correlate containers with the Kubernetes pods of the node")
+	fs.StringVar(&c.KubeletURL, "kubelet_url", defaultKubeletURL, "Kubelet API URL")
+	fs.StringVar(&c.KubeletTokenFile, "kubelet_token_file", defaultKubeletTokenFile, "File holding the bearer token used to authenticate against the kubelet")
+	fs.BoolVar(&c.KubeletInsecureSkipVerify, "kubelet_insecure_skip_verify", false, "Skip verification of the kubelet serving certificate")
+
+	fs.StringVar(&c.MinRuntimeVersion, "min_runtime_version", "", "Minimum container runtime version; by default, runtimes older than the newest one in the cluster are reported as outdated")
+
+	fs.StringVar(&c.PortsRange, "ports_range", defaultPortsRange, "Range of host ports available for publishing, used for exhaustion detection")
+	fs.Float64Var(&c.PortsExhaustionThreshold, "ports_exhaustion_threshold", defaultPortsExhaustionThreshold, "Ratio of the ports range in use above which a node is reported as near exhaustion")
+
+	fs.DurationVar(&c.HistoryResolution, "history_resolution", defaultHistoryResolution, "Interval between two samples of the running containers history")
+	fs.DurationVar(&c.HistoryRetention, "history_retention", defaultHistoryRetention, "How long raw samples of the running containers history are kept")
+
+	fs.DurationVar(&c.HistoryDownsampledResolution, "history_downsampled_resolution", defaultHistoryDownsampledResolution, "Resolution of the downsampled running containers history")
+	fs.DurationVar(&c.HistoryDownsampledRetention, "history_downsampled_retention", defaultHistoryDownsampledRetention, "How long downsampled samples of the running containers history are kept")
+
+	fs.DurationVar(&c.EphemeralAnnotationMaxTTL, "ephemeral_annotation_max_ttl", defaultEphemeralAnnotationMaxTTL, "Maximum TTL of the ephemeral annotations attached to containers through the API")
+
+	fs.DurationVar(&c.MaintenanceWindowMaxDuration, "maintenance_window_max_duration", defaultMaintenanceWindowMaxDuration, "Maximum duration of the maintenance windows scheduled through the API")
+
+	fs.StringVar(&c.HTTPMiddlewares, "http_middlewares", defaultHTTPMiddlewares, "Middlewares of the HTTP server, as a comma-separated list ordered from the outermost, among request_id, limits, logging, metrics, auth, rate_limit, compression and recovery")
+	fs.Float64Var(&c.HTTPRateLimit, "http_rate_limit", defaultHTTPRateLimit, "Requests per second allowed to each client IP by the rate_limit middleware")
+	fs.IntVar(&c.HTTPRateLimitBurst, "http_rate_limit_burst", defaultHTTPRateLimitBurst, "Requests allowed in a burst to each client IP by the rate_limit middleware")
+	fs.IntVar(&c.CompressionMinSize, "http_compression_min_size", defaultCompressionMinSize, "Size in bytes from which the responses are compressed by the compression middleware")
+
+	fs.Int64Var(&c.HTTPMaxBodySize, "http_max_body_size", defaultHTTPMaxBodySize, "Maximum size in bytes of the request bodies, larger ones being rejected with a 413 by the limits middleware")
+	fs.IntVar(&c.HTTPMaxHeaderSize, "http_max_header_size", defaultHTTPMaxHeaderSize, "Maximum size in bytes of the request line and headers, larger ones being rejected with a 431 by the limits middleware")
+	fs.IntVar(&c.HTTPMaxConnsPerIP, "http_max_conns_per_ip", 0, "Maximum number of open connections of each client IP to the HTTP servers, the requests of the extra connections being rejected with a 429 by the limits middleware; unlimited when 0")
+	fs.DurationVar(&c.HTTPReadHeaderTimeout, "http_read_header_timeout", defaultHTTPReadHeaderTimeout, "Time allowed to the clients of the HTTP servers to send the headers of a request, before their connection is closed")
+	fs.DurationVar(&c.HTTPReadTimeout, "http_read_timeout", defaultHTTPReadTimeout, "Time allowed to the clients of the HTTP servers to send a whole request, body included, before their connection is closed")
+	fs.DurationVar(&c.HTTPIdleTimeout, "http_idle_timeout", defaultHTTPIdleTimeout, "Time after which the idle keep-alive connections to the HTTP servers are closed")
+
+	fs.IntVar(&c.UIPageSize, "ui_page_size", defaultUIPageSize, "Number of rows of the pages of the containers table of the UI, overridden by the per_page parameter")
+	fs.StringVar(&c.UIViewsFile, "ui_views_file", "", "File persisting the views of the containers table saved on the node, by user or shared; the views are only saved in the browsers when empty")
+
+	fs.StringVar(&c.StatsdAddress, "statsd_address", "", "UDP address of a statsd or DogStatsD server, such as 127.0.0.1:8125, to which key metrics are mirrored; disabled when empty")
+	fs.StringVar(&c.StatsdFormat, "statsd_format", statsdFormatDogStatsD, "Format of the lines sent to -statsd_address: dogstatsd, with the labels as tags, or statsd, with the labels as suffixes of the names")
+	fs.StringVar(&c.StatsdPrefix, "statsd_prefix", defaultStatsdPrefix, "Prefix of the names of the metrics sent to -statsd_address")
+	fs.StringVar(&c.StatsdTags, "statsd_tags", "", "Comma-separated tags, such as env:prod, added to the metrics sent to -statsd_address; dogstatsd format only")
+	fs.StringVar(&c.StatsdMetrics, "statsd_metrics", defaultStatsdMetrics, "Comma-separated names of the Prometheus metrics mirrored to -statsd_address")
+	fs.DurationVar(&c.StatsdFlushInterval, "statsd_flush_interval", defaultStatsdFlushInterval, "Interval at which the metrics are sent to -statsd_address")
+
+	fs.IntVar(&c.GoGC, "gogc", 0, "Garbage collection target percentage, as GOGC; the GOGC environment variable or 100 when 0, and garbage collection disabled when negative")
+	fs.Int64Var(&c.GoMemLimit, "gomemlimit", 0, "Soft memory limit in bytes of the Go runtime, as GOMEMLIMIT; when 0 and GOMEMLIMIT is not set, the soft memory limit is adapted to the memory limit of the cgroup of the node with -gomemlimit_ratio")
+	fs.Float64Var(&c.GoMemLimitRatio, "gomemlimit_ratio", defaultGoMemLimitRatio, "Ratio of the memory limit of the cgroup of the node kept as its soft memory limit, leaving room for the memory not managed by the Go runtime; not adapted when 0")
+	fs.DurationVar(&c.GoMemLimitInterval, "gomemlimit_interval", defaultGoMemLimitInterval, "Interval at which the memory limit of the cgroup is re-read to adapt the soft memory limit; only read on startup when 0")
+
+	fs.StringVar(&c.UpdateFeedURL, "update_feed_url", "", "URL of the release feed, a JSON document describing the latest release, checked for a newer release than the running one; not checked when empty")
+	fs.DurationVar(&c.UpdateCheckInterval, "update_check_interval", defaultUpdateCheckInterval, "Interval at which -update_feed_url is checked")
+	fs.BoolVar(&c.UpdateAuto, "update_auto", false, "Install the newer releases of -update_feed_url signed by one of -update_trusted_keys, replacing the running binary and restarting on it")
+	fs.StringVar(&c.UpdateTrustedKeys, "update_trusted_keys", "", "File of PEM-encoded Ed25519 public keys, one of which must have signed the binaries installed by -update_auto")
+
+	fs.Var(&c.UIColumns, "ui_column", "Extra column of the containers table, as Header=template executed against each container, such as 'Owner={{ labelGet .Labels \"owner\" }}'; may be repeated")
+	c.Schedules = jobSchedules{}
+	fs.Var(c.Schedules, "schedule", "Schedule of a job, as job=schedule with a cron expression, an @hourly, @daily, @weekly or @monthly shortcut, '@every <duration>' or 'off', such as 'snapshot=*/5 * * * *'; jobs are gc, report, snapshot, sbom, export and handoff; may be repeated")
+	c.AnnotationSchemas = annotationSchemas{}
+	fs.Var(c.AnnotationSchemas, "annotation_schema", "Schema of a container annotation, as name=type[:description] with type one of string, number or bool; the "+annotationLabelPrefix+"<name> labels of the containers are turned into annotations; may be repeated")
+
+	c.Policies = policies{}
+	fs.Var(c.Policies, "policy", "Compliance policy checked against the running containers, as name=kind[:arg] with kind one of no-latest, required-label:<label>, approved-registries:<registry>[,<registry>...] or rego:<file> of a Rego module whose deny rule is the set of the violations of its input container; may be repeated")
+
+	fs.Var(&c.PeerDisplayName, "peer_display_name", "Template of the display names of the peers in the UI, the API and the metrics labels, executed against their .Name, .Address, .Host, .Node and .Site, with a stripDomain function, such as '{{ stripDomain .Node }}'; their name by default")
+	c.PeerAliases = peerAliases{}
+	fs.Var(c.PeerAliases, "peer_alias", "Display name of a peer, as name=alias with name the name of the peer, of its node or the host of its address, overriding -peer_display_name; may be repeated")
+	fs.Var(&c.PeerSites, "peer_site", "Site of the peers whose address is in a range, as CIDR=site, available as .Site to -peer_display_name; may be repeated")
+
+	c.NodeCosts = nodeCosts{}
+	fs.Var(c.NodeCosts, "cost_node", "Hourly cost of a node, as node=cost, overriding -cost_per_node_hour and -cost_per_cpu_hour; may be repeated")
+
+	c.NodeLabels = nodeTags{}
+	fs.Var(c.NodeLabels, "node_label", "Label of the local nodes, as key=value such as role=web or tenant=acme, gossiped with their facts and available to the maintenance windows, the members and -metrics_node_labels; may be repeated")
+
+	c.MiddlewareOptOuts = defaultMiddlewareOptOuts()
+	fs.Var(c.MiddlewareOptOuts, "http_middleware_opt_out", "Middlewares skipped by a route of the HTTP server, as pattern=middleware[,middleware...], such as /metrics=logging,auth; /metrics and /api/v1/status skip logging, auth and rate_limit by default; may be repeated")
+
+	secretVar(fs, &c.AdminToken, "admin_token", "Bearer token required by the admin API; the admin API is disabled when empty, unless -api_keys_file is set")
+	secretVar(fs, &c.HTTPAuthToken, "http_auth_token", "Bearer token, or basic authentication password, required by the auth middleware; the admin token is accepted too")
+	secretVar(fs, &c.GossipSigningKey, "gossip_signing_key", "PEM-encoded Ed25519 private key used to sign the gossiped state of the node, such as file://<path> to read it from a file")
+	secretVar(fs, &c.RedisURL, "redis_url", "Redis server through which several aggregators share the merged inventory and the history, as redis://[[user]:password@]host[:port][/db]; not shared when empty")
+	secretVar(fs, &c.ExportAccessKeyID, "export_access_key_id", "Access key ID of the export bucket, an HMAC key for GCS; AWS_ACCESS_KEY_ID by default")
+
+	secretVar(fs, &c.GossipEncryptionKeys, "gossip_encryption_keys", "Comma-separated base64-encoded AES keys of 16, 24 or 32 bytes encrypting the gossip, the first one encrypting and all of them decrypting; not encrypted when empty")
+}
+
+// secrets returns the secrets of the configuration, by flag name.
+func (c *Config) secrets() map[string]*Secret {
+	return map[string]*Secret{
+		"admin_token":            &c.AdminToken,
+		"http_auth_token":        &c.HTTPAuthToken,
+		"gossip_signing_key":     &c.GossipSigningKey,
+		"redis_url":              &c.RedisURL,
+		"export_access_key_id":   &c.ExportAccessKeyID,
+		"gossip_encryption_keys": &c.GossipEncryptionKeys,
+	}
+}
@@ -0,0 +1,42 @@
+package containerslist
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestConfigRegisterFlags(t *testing.T) {
+	if f := flag.CommandLine.Lookup("http"); f != nil {
+		t.Fatalf("the package registered the flag %s on the command line", f.Name)
+	}
+
+	c := &Config{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.RegisterFlags(fs)
+	if c.HTTPAddress != defaultHttpListenAddress || c.GossipInterval != defaultGossipInterval {
+		t.Errorf("defaults = %q, %v, want %q, %v", c.HTTPAddress, c.GossipInterval, defaultHttpListenAddress, defaultGossipInterval)
+	}
+	if err := fs.Parse([]string{"-http", ":8080", "-ha_gossip_interval", "1s", "-policy", "latest=no-latest", "-admin_token", "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.HTTPAddress != ":8080" || c.GossipInterval != time.Second {
+		t.Errorf("parsed = %q, %v, want :8080, 1s", c.HTTPAddress, c.GossipInterval)
+	}
+	if _, ok := c.Policies["latest"]; !ok {
+		t.Errorf("policies = %v, want latest", c.Policies)
+	}
+
+	// The flags of the secrets are the keys of the secrets of the
+	// configuration, for them to be resolved and reloaded.
+	for name := range c.secrets() {
+		if fs.Lookup(name) == nil {
+			t.Errorf("no flag %s for the secret", name)
+		}
+	}
+
+	// The defaults do not share the values of the flags taking a list.
+	if d := DefaultConfig(); len(d.Policies) != 0 {
+		t.Errorf("default policies = %v, want none", d.Policies)
+	}
+}
@@ -0,0 +1,217 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ContainerClaim is a node reporting a container.
+type ContainerClaim struct {
+	Node      string    `json:"node"`
+	Container Container `json:"container"`
+}
+
+// ContainerConflict is a container ID reported by several nodes, such as
+// after a migration or the cloning of a host. The freshest claim is kept in
+// the listings, the other ones being dropped until the conflict is resolved.
+type ContainerConflict struct {
+	ID       string           `json:"id"`
+	Kept     ContainerClaim   `json:"kept"`
+	Dropped  []ContainerClaim `json:"dropped"`
+	Detected time.Time        `json:"detected"`
+}
+
+// freshness returns the last time a container changed: when it last
+// started or stopped, or else when it was created.
+func freshness(c Container) time.Time {
+	t := c.Created
+	if c.StartedAt != nil && c.StartedAt.After(t) {
+		t = *c.StartedAt
+	}
+	if c.FinishedAt != nil && c.FinishedAt.After(t) {
+		t = *c.FinishedAt
+	}
+	return t
+}
+
+// conflictTracker detects the containers reported by several nodes.
+type conflictTracker struct {
+	mtx       sync.RWMutex
+	conflicts map[string]ContainerConflict
+
+	detected prometheus.Counter
+	active   prometheus.GaugeFunc
+}
+
+func newConflictTracker(reg prometheus.Registerer) *conflictTracker {
+	t := &conflictTracker{
+		conflicts: map[string]ContainerConflict{},
+		detected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "containerslist_container_conflicts_detected_total",
+			Help: "Total number of containers detected as reported by several nodes.",
+		}),
+	}
+	t.active = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "containerslist_container_conflicts",
+		Help: "Number of containers currently reported by several nodes.",
+	}, func() float64 {
+		t.mtx.RLock()
+		defer t.mtx.RUnlock()
+		return float64(len(t.conflicts))
+	})
+	reg.MustRegister(t.detected, t.active)
+	return t
+}
+
+// detectConflicts returns the conflicts of the inventories, the claims of each one
+// sorted from the freshest, ties being broken by the most recent inventory
+// and then by node.
+func detectConflicts(nodes []*NodeInventory, now time.Time) map[string]ContainerConflict {
+	type claim struct {
+		ContainerClaim
+		timestamp time.Time
+	}
+	claims := map[string][]claim{}
+	for _, n := range nodes {
+		if n.Summary {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, c := range n.Containers {
+			if seen[c.ID] {
+				continue
+			}
+			seen[c.ID] = true
+			claims[c.ID] = append(claims[c.ID], claim{ContainerClaim{Node: n.Node, Container: c}, n.Timestamp})
+		}
+	}
+	conflicts := map[string]ContainerConflict{}
+	for id, cs := range claims {
+		if len(cs) < 2 {
+			continue
+		}
+		sort.Slice(cs, func(i, j int) bool {
+			fi, fj := freshness(cs[i].Container), freshness(cs[j].Container)
+			switch {
+			case !fi.Equal(fj):
+				return fi.After(fj)
+			case !cs[i].timestamp.Equal(cs[j].timestamp):
+				return cs[i].timestamp.After(cs[j].timestamp)
+			default:
+				return cs[i].Node < cs[j].Node
+			}
+		})
+		conflict := ContainerConflict{ID: id, Kept: cs[0].ContainerClaim, Detected: now}
+		for _, c := range cs[1:] {
+			conflict.Dropped = append(conflict.Dropped, c.ContainerClaim)
+		}
+		conflicts[id] = conflict
+	}
+	return conflicts
+}
+
+// conflictNodes identifies the claims of a conflict, to tell the new
+// conflicts from the known ones.
+func conflictNodes(c ContainerConflict) string {
+	nodes := []string{c.Kept.Node}
+	for _, d := range c.Dropped {
+		nodes = append(nodes, d.Node)
+	}
+	return strings.Join(nodes, ",")
+}
+
+// update replaces the conflicts, returning the new ones: the conflicts of
+// the containers which were not conflicting, or whose claims changed.
+func (t *conflictTracker) update(conflicts map[string]ContainerConflict) []ContainerConflict {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	var added []ContainerConflict
+	for id, c := range conflicts {
+		if prev, ok := t.conflicts[id]; ok && conflictNodes(prev) == conflictNodes(c) {
+			conflicts[id] = prev
+			continue
+		}
+		added = append(added, c)
+	}
+	t.conflicts = conflicts
+	t.detected.Add(float64(len(added)))
+	sort.Slice(added, func(i, j int) bool { return added[i].ID < added[j].ID })
+	return added
+}
+
+// List returns the current conflicts, sorted by container ID.
+func (t *conflictTracker) List() []ContainerConflict {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	res := make([]ContainerConflict, 0, len(t.conflicts))
+	for _, id := range sortedKeys(t.conflicts) {
+		res = append(res, t.conflicts[id])
+	}
+	return res
+}
+
+// drop removes the dropped claims of the conflicts from inventories
+// filtered for a listing.
+func (t *conflictTracker) drop(nodes []*NodeInventory) {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	if len(t.conflicts) == 0 {
+		return
+	}
+	dropped := map[string]map[string]bool{}
+	for _, c := range t.conflicts {
+		for _, d := range c.Dropped {
+			if dropped[d.Node] == nil {
+				dropped[d.Node] = map[string]bool{}
+			}
+			dropped[d.Node][c.ID] = true
+		}
+	}
+	for _, n := range nodes {
+		ids := dropped[n.Node]
+		if ids == nil {
+			continue
+		}
+		kept := n.Containers[:0:0]
+		for _, c := range n.Containers {
+			if !ids[c.ID] {
+				kept = append(kept, c)
+			}
+		}
+		n.Containers = kept
+	}
+}
+
+// runConflicts detects the conflicts on each change of the inventory until
+// ctx is done, publishing a conflict event for each new one.
+func (m *Manager) runConflicts(ctx context.Context) {
+	var version uint64
+	for ctx.Err() == nil {
+		version = m.inventory.Wait(ctx, version)
+		now := time.Now().UTC()
+		for _, c := range m.conflicts.update(detectConflicts(m.inventory.Nodes(), now)) {
+			nodes := []string{}
+			for _, d := range c.Dropped {
+				nodes = append(nodes, d.Node)
+			}
+			level.Warn(m.logger).Log("msg", "Container reported by several nodes, keeping the freshest claim", "container", c.ID, "kept", c.Kept.Node, "dropped", strings.Join(nodes, ","))
+			conflict := c
+			m.events.Publish([]ContainerEvent{{Type: EventConflict, Node: c.Kept.Node, Time: now, Container: c.Kept.Container, Conflict: &conflict}})
+		}
+	}
+}
+
+// apiConflicts lists the containers reported by several nodes, for the
+// operators to review them.
+func (m *Manager) apiConflicts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.conflicts.List())
+	})
+}
@@ -0,0 +1,148 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	started := at.Add(time.Hour)
+	nodes := []*NodeInventory{
+		{Node: "a", Timestamp: at, Containers: []Container{{ID: "c1", Created: at}, {ID: "c2", Created: at}, {ID: "c3", Created: at}}},
+		{Node: "b", Timestamp: at.Add(time.Minute), Containers: []Container{{ID: "c1", Created: at, StartedAt: &started}, {ID: "c2", Created: at}, {ID: "c4"}}},
+		{Node: "c", Timestamp: at, Containers: []Container{{ID: "c2", Created: at}, {ID: "c2", Created: at}}},
+		// The summaries are not compared.
+		{Node: "d", Timestamp: at, Summary: true, Containers: []Container{{ID: "c3"}}},
+	}
+	conflicts := detectConflicts(nodes, at)
+	for _, tc := range []struct {
+		id, nodes string
+	}{
+		// The container started last wins.
+		{"c1", "b,a"},
+		// Then the freshest inventory, then the node.
+		{"c2", "b,a,c"},
+	} {
+		c, ok := conflicts[tc.id]
+		if !ok {
+			t.Errorf("%s not conflicting", tc.id)
+			continue
+		}
+		if got := conflictNodes(c); got != tc.nodes {
+			t.Errorf("claims of %s = %s, want %s", tc.id, got, tc.nodes)
+		}
+	}
+	if len(conflicts) != 2 {
+		t.Errorf("%d conflicts, want 2", len(conflicts))
+	}
+}
+
+func TestConflictTracker(t *testing.T) {
+	at := time.Now()
+	nodes := []*NodeInventory{
+		{Node: "a", Timestamp: at, Containers: []Container{{ID: "c1"}, {ID: "c2"}}},
+		{Node: "b", Timestamp: at.Add(time.Second), Containers: []Container{{ID: "c1"}}},
+	}
+	tr := newConflictTracker(prometheus.NewRegistry())
+	if added := tr.update(detectConflicts(nodes, at)); len(added) != 1 || added[0].ID != "c1" || added[0].Kept.Node != "b" {
+		t.Errorf("new conflicts = %+v, want c1 kept on b", added)
+	}
+	// The known conflicts are not new, and keep their detection time.
+	if added := tr.update(detectConflicts(nodes, at.Add(time.Minute))); len(added) != 0 {
+		t.Errorf("new conflicts = %+v, want none", added)
+	}
+	if list := tr.List(); len(list) != 1 || !list[0].Detected.Equal(at) {
+		t.Errorf("conflicts = %+v, want c1 detected first", list)
+	}
+
+	listed := []*NodeInventory{
+		{Node: "a", Containers: []Container{{ID: "c1"}, {ID: "c2"}}},
+		{Node: "b", Containers: []Container{{ID: "c1"}}},
+	}
+	tr.drop(listed)
+	if len(listed[0].Containers) != 1 || listed[0].Containers[0].ID != "c2" || len(listed[1].Containers) != 1 {
+		t.Errorf("listed containers = %+v, %+v, want c1 dropped from a", listed[0].Containers, listed[1].Containers)
+	}
+	// The inventories are filtered copies, but their containers are not.
+	if len(nodes[0].Containers) != 2 {
+		t.Errorf("containers of a = %+v, want them untouched", nodes[0].Containers)
+	}
+
+	// A claim moving to another node is a new conflict.
+	nodes = append(nodes, &NodeInventory{Node: "c", Timestamp: at, Containers: []Container{{ID: "c1"}}})
+	if added := tr.update(detectConflicts(nodes, at)); len(added) != 1 {
+		t.Errorf("new conflicts = %+v, want c1 with the claim of c", added)
+	}
+	if got := testutil.ToFloat64(tr.detected); got != 2 {
+		t.Errorf("detected conflicts = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(tr.active); got != 1 {
+		t.Errorf("active conflicts = %v, want 1", got)
+	}
+	tr.update(detectConflicts(nodes[:1], at))
+	if got := testutil.ToFloat64(tr.active); got != 0 {
+		t.Errorf("active conflicts = %v, want the resolved one gone", got)
+	}
+}
+
+func TestRunConflicts(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{{ID: "c1", Name: "web", State: StateRunning}}},
+	)
+	m.logger, m.events = log.NewNopLogger(), newEventBroker()
+	defer m.events.Close()
+	events, unsubscribe := m.events.Subscribe()
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.runConflicts(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if _, err := m.inventory.Set(&NodeInventory{Node: "b", Timestamp: time.Now(), Containers: []Container{{ID: "c1", Name: "web", State: StateRunning}}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventConflict || e.Node != "b" || e.Conflict == nil || conflictNodes(*e.Conflict) != "b,a" {
+			t.Errorf("event = %+v, want the conflict of c1 kept on b", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no conflict event")
+	}
+
+	srv := httptest.NewServer(m.apiConflicts())
+	defer srv.Close()
+	conflicts, err := client.New(srv.URL).Conflicts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].ID != "c1" || conflicts[0].Kept.Node != "b" || len(conflicts[0].Dropped) != 1 || conflicts[0].Dropped[0].Node != "a" {
+		t.Errorf("conflicts = %+v, want c1 kept on b", conflicts)
+	}
+
+	nodes, _, err := m.containers(httptest.NewRequest(http.MethodGet, "/api/v1/containers", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if n.Node == "a" && len(n.Containers) != 0 || n.Node == "b" && len(n.Containers) != 1 {
+			t.Errorf("containers of %s = %+v, want c1 listed on b only", n.Node, n.Containers)
+		}
+	}
+}
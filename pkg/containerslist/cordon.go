@@ -0,0 +1,54 @@
+package containerslist
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/go-kit/log/level"
+)
+
+// sourceNames returns the health names of the discovery sources of the
+// local node, which can be cordoned.
+func (m *Manager) sourceNames() []string {
+	var names []string
+	for _, s := range m.sources {
+		names = append(names, s.healthName())
+	}
+	for _, s := range m.sshSources {
+		names = append(names, s.healthName())
+	}
+	if m.kubelet != nil {
+		names = append(names, SourceKubelet)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apiCordon cordons or uncordons discovery sources of the receiving node,
+// such as a flaky remote Docker endpoint, without restarting it: their
+// polling is suspended until they are uncordoned. It answers with the status
+// of the sources. The cordons are not persisted across restarts.
+func (m *Manager) apiCordon() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		q := r.URL.Query()
+		names := m.sourceNames()
+		for _, name := range append(q["cordon"], q["uncordon"]...) {
+			if !contains(names, name) {
+				writeProblem(w, r, problemf(ErrNotFound, "no source %q on node %s", name, m.nodeID))
+				return
+			}
+		}
+		for _, name := range q["cordon"] {
+			m.health.Cordon(name, q.Get("reason"))
+			level.Info(m.requestLogger(r.Context())).Log("msg", "Source cordoned", "source", name, "reason", q.Get("reason"))
+		}
+		for _, name := range q["uncordon"] {
+			m.health.Uncordon(name)
+			level.Info(m.requestLogger(r.Context())).Log("msg", "Source uncordoned", "source", name)
+		}
+		writeJSON(w, m.health.Statuses())
+	})
+}
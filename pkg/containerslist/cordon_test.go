@@ -0,0 +1,76 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestSourceCordon(t *testing.T) {
+	h := newSourceHealth()
+	h.Failure(SourceDocker, errors.New("connection refused"))
+	if h.Cordoned(SourceDocker) || h.Cordoned("unknown") {
+		t.Error("source cordoned by default")
+	}
+
+	h.Cordon(SourceDocker, "flaky daemon")
+	if !h.Cordoned(SourceDocker) {
+		t.Error("docker not cordoned")
+	}
+	if s := h.Statuses()[0]; !s.Cordoned || s.CordonReason != "flaky daemon" || s.CordonedAt == nil || s.ConsecutiveFailures != 1 {
+		t.Errorf("docker = %+v, want cordoned with its failures kept", s)
+	}
+
+	h.Uncordon(SourceDocker)
+	if s := h.Statuses()[0]; h.Cordoned(SourceDocker) || s.Cordoned || s.CordonReason != "" || s.CordonedAt != nil {
+		t.Errorf("docker = %+v, want uncordoned", s)
+	}
+}
+
+func TestAPICordon(t *testing.T) {
+	m := &Manager{
+		nodeID:     "n1",
+		logger:     log.NewNopLogger(),
+		health:     newSourceHealth(),
+		sources:    []*dockerSource{{node: "n1"}, {name: "gpu", node: "n1/gpu"}},
+		sshSources: []*sshSource{{name: "edge"}},
+	}
+	if got, want := m.sourceNames(), []string{"docker", "docker:gpu", "ssh:edge"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sources = %q, want %q", got, want)
+	}
+
+	post := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.apiCordon().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/cordon?"+query, nil))
+		return rec
+	}
+	rec := post("cordon=docker:gpu&cordon=ssh:edge&reason=maintenance")
+	var statuses []SourceStatus
+	if err := json.NewDecoder(rec.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || !statuses[0].Cordoned || statuses[0].CordonReason != "maintenance" || !statuses[1].Cordoned {
+		t.Errorf("statuses = %+v, want docker:gpu and ssh:edge cordoned", statuses)
+	}
+
+	post("uncordon=ssh:edge")
+	if !m.health.Cordoned("docker:gpu") || m.health.Cordoned("ssh:edge") {
+		t.Error("ssh:edge not uncordoned alone")
+	}
+
+	// Nothing is cordoned when a source is unknown.
+	if rec := post("cordon=docker&cordon=kubelet"); rec.Code != http.StatusNotFound || m.health.Cordoned(SourceDocker) {
+		t.Errorf("status with an unknown source = %d, want 404 without any cordon", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiCordon().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/cordon?cordon=docker", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status of a GET = %d, want 405", rec.Code)
+	}
+}
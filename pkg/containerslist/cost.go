@@ -0,0 +1,150 @@
+package containerslist
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nodeCosts are the hourly costs of specific nodes, overriding the costs
+// per node or per CPU.
+type nodeCosts map[string]float64
+
+// String implements flag.Value.
+func (c nodeCosts) String() string {
+	specs := make([]string, 0, len(c))
+	for node, cost := range c {
+		specs = append(specs, node+"="+strconv.FormatFloat(cost, 'f', -1, 64))
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a node=cost override.
+func (c nodeCosts) Set(spec string) error {
+	node, s, ok := strings.Cut(spec, "=")
+	if !ok || node == "" {
+		return fmt.Errorf("invalid node cost %q: must be node=cost", spec)
+	}
+	cost, err := strconv.ParseFloat(s, 64)
+	if err != nil || cost < 0 {
+		return fmt.Errorf("invalid cost %q of node %s", s, node)
+	}
+	c[node] = cost
+	return nil
+}
+
+// costEnabled reports whether a cost is configured.
+func (m *Manager) costEnabled() bool {
+	return m.cfg.CostPerNodeHour > 0 || m.cfg.CostPerCPUHour > 0 || len(m.cfg.NodeCosts) > 0
+}
+
+// nodeHourlyCost returns the hourly cost of a node: its configured cost, or
+// else the cost of its CPUs when known, or else the cost per node.
+func (m *Manager) nodeHourlyCost(node string) (cost float64, cpus int) {
+	if f, ok := m.facts.Get(node); ok {
+		cpus = f.CPUs
+	}
+	if cost, ok := m.cfg.NodeCosts[node]; ok {
+		return cost, cpus
+	}
+	if m.cfg.CostPerCPUHour > 0 && cpus > 0 {
+		return m.cfg.CostPerCPUHour * float64(cpus), cpus
+	}
+	return m.cfg.CostPerNodeHour, cpus
+}
+
+// CostReport is the estimated hourly cost of the cluster, attributed to the
+// running containers and grouped by the value of the cost label.
+type CostReport struct {
+	Label      string          `json:"label"`
+	Hourly     float64         `json:"hourly"`
+	Nodes      []NodeCost      `json:"nodes"`
+	Groups     []CostGroup     `json:"groups"`
+	Containers []ContainerCost `json:"containers"`
+}
+
+// NodeCost is the hourly cost of a node, shared evenly by its running
+// containers.
+type NodeCost struct {
+	Node    string  `json:"node"`
+	CPUs    int     `json:"cpus,omitempty"`
+	Hourly  float64 `json:"hourly"`
+	Running int     `json:"running"`
+}
+
+// CostGroup is the cost attributed to the containers with the same value of
+// the cost label; Group is empty for the containers without the label.
+type CostGroup struct {
+	Group      string  `json:"group"`
+	Hourly     float64 `json:"hourly"`
+	Containers int     `json:"containers"`
+}
+
+// ContainerCost is the cost attributed to a running container.
+type ContainerCost struct {
+	Node   string  `json:"node"`
+	Name   string  `json:"name"`
+	ID     string  `json:"id"`
+	Group  string  `json:"group"`
+	Hourly float64 `json:"hourly"`
+}
+
+// costReport estimates the cost of the running containers. The cost of a
+// node without running containers is not attributed to any group.
+func (m *Manager) costReport() CostReport {
+	r := CostReport{Label: m.cfg.CostLabel, Nodes: []NodeCost{}, Groups: []CostGroup{}, Containers: []ContainerCost{}}
+	groups := map[string]*CostGroup{}
+	for _, n := range m.inventory.Nodes() {
+		nc := NodeCost{Node: n.Node}
+		nc.Hourly, nc.CPUs = m.nodeHourlyCost(n.Node)
+		for _, c := range n.Containers {
+			if c.State == StateRunning {
+				nc.Running++
+			}
+		}
+		r.Hourly += nc.Hourly
+		r.Nodes = append(r.Nodes, nc)
+		if nc.Running == 0 {
+			continue
+		}
+
+		share := nc.Hourly / float64(nc.Running)
+		for _, c := range n.Containers {
+			if c.State != StateRunning {
+				continue
+			}
+			group := c.Labels[m.cfg.CostLabel]
+			r.Containers = append(r.Containers, ContainerCost{Node: n.Node, Name: c.Name, ID: c.ID, Group: group, Hourly: share})
+			g, ok := groups[group]
+			if !ok {
+				g = &CostGroup{Group: group}
+				groups[group] = g
+			}
+			g.Hourly += share
+			g.Containers++
+		}
+	}
+	for _, g := range groups {
+		r.Groups = append(r.Groups, *g)
+	}
+	sort.Slice(r.Groups, func(i, j int) bool {
+		if r.Groups[i].Hourly != r.Groups[j].Hourly {
+			return r.Groups[i].Hourly > r.Groups[j].Hourly
+		}
+		return r.Groups[i].Group < r.Groups[j].Group
+	})
+	return r
+}
+
+func (m *Manager) apiCosts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.costEnabled() {
+			writeProblem(w, r, problemf(ErrNotFound, "no cost configured"))
+			return
+		}
+		writeJSON(w, m.costReport())
+	})
+}
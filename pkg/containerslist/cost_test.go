@@ -0,0 +1,87 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNodeCosts(t *testing.T) {
+	c := nodeCosts{}
+	for _, spec := range []string{"gpu-1=3.5", "edge=0"} {
+		if err := c.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := c.String(); got != "edge=0,gpu-1=3.5" {
+		t.Errorf("String = %q", got)
+	}
+	for _, spec := range []string{"gpu-1", "=1", "gpu-1=-1", "gpu-1=cheap"} {
+		if err := c.Set(spec); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCostReport(t *testing.T) {
+	team := func(name string) map[string]string { return map[string]string{"team": name} }
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{
+			{ID: "a1", Name: "web", State: StateRunning, Labels: team("shop")},
+			{ID: "a2", Name: "api", State: StateRunning, Labels: team("shop")},
+			{ID: "a3", Name: "old", State: "exited", Labels: team("shop")},
+			{ID: "a4", Name: "debug", State: StateRunning},
+			{ID: "a5", Name: "grafana", State: StateRunning, Labels: team("monitoring")},
+		}},
+		&NodeInventory{Node: "b", Containers: []Container{
+			{ID: "b1", Name: "prometheus", State: StateRunning, Labels: team("monitoring")},
+		}},
+		&NodeInventory{Node: "c"},
+		&NodeInventory{Node: "gpu-1", Containers: []Container{
+			{ID: "g1", Name: "train", State: StateRunning, Labels: team("ml")},
+		}},
+	)
+	m.facts = newNodeState[*NodeFacts]()
+	if _, err := m.facts.Set(&NodeFacts{Node: "a", CPUs: 8, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiCosts().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/costs", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status without cost = %d, want 404", rec.Code)
+	}
+
+	m.cfg.CostLabel = "team"
+	m.cfg.CostPerNodeHour = 1
+	m.cfg.CostPerCPUHour = 0.5
+	m.cfg.NodeCosts = nodeCosts{"gpu-1": 3}
+	r := m.costReport()
+	if r.Hourly != 9 {
+		t.Errorf("hourly cost = %v, want 9", r.Hourly)
+	}
+	wantNodes := []NodeCost{
+		{Node: "a", CPUs: 8, Hourly: 4, Running: 4},
+		{Node: "b", Hourly: 1, Running: 1},
+		{Node: "c", Hourly: 1},
+		{Node: "gpu-1", Hourly: 3, Running: 1},
+	}
+	if !reflect.DeepEqual(r.Nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", r.Nodes, wantNodes)
+	}
+	// The cost of the idle node c is not attributed.
+	wantGroups := []CostGroup{
+		{Group: "ml", Hourly: 3, Containers: 1},
+		{Group: "monitoring", Hourly: 2, Containers: 2},
+		{Group: "shop", Hourly: 2, Containers: 2},
+		{Group: "", Hourly: 1, Containers: 1},
+	}
+	if !reflect.DeepEqual(r.Groups, wantGroups) {
+		t.Errorf("groups = %+v, want %+v", r.Groups, wantGroups)
+	}
+	if len(r.Containers) != 6 || r.Containers[0] != (ContainerCost{Node: "a", Name: "web", ID: "a1", Group: "shop", Hourly: 1}) {
+		t.Errorf("containers = %+v, want 6, web first", r.Containers)
+	}
+}
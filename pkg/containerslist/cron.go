@@ -0,0 +1,170 @@
+package containerslist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule computes the next run of a periodic task.
+type schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// everySchedule runs at a fixed interval.
+type everySchedule time.Duration
+
+// Next implements schedule.
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// cronSchedule is a cron expression: each field holds the bitmask of the
+// minutes, hours, days of month, months and days of week it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set when the corresponding field starts with
+	// "*", such as "*" or "*/2": as in Vixie cron, a day then has to match
+	// both day fields, and either of them otherwise.
+	domStar, dowStar bool
+	// wildcard is set when the minute or hour field starts with "*". The
+	// other jobs run at a fixed time of the day, once even when the clocks
+	// are set back and the time repeats.
+	wildcard bool
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseSchedule parses a cron expression of five fields (minute, hour, day of
+// month, month, day of week), one of the @hourly, @daily, @weekly and
+// @monthly shortcuts, or an "@every <duration>" interval.
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: invalid interval", spec)
+		}
+		return everySchedule(interval), nil
+	}
+	if s, ok := cronShortcuts[spec]; ok {
+		spec = s
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: must have 5 fields", spec)
+	}
+	var s cronSchedule
+	var err error
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	masks := [5]*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range fields {
+		if *masks[i], err = parseCronField(f, bounds[i][0], bounds[i][1]); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// Sunday is both 0 and 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	s.wildcard = strings.HasPrefix(fields[0], "*") || strings.HasPrefix(fields[1], "*")
+	return &s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps,
+// such as "*/15" or "1-5,10".
+func parseCronField(f string, min, max int) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid range %q", item)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// wallClock returns the date and time of the day of t, in UTC, so that the
+// times repeated when the clocks are set back compare equal.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// advance returns next, the start of the following month, day or hour of t,
+// unless it was skipped when the clocks were set forward and normalized to a
+// time before t, then returns the following hour of next instead.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return next.Add(time.Hour)
+}
+
+// Next implements schedule, returning the first matching minute after t. The
+// times skipped when the clocks are set forward never match.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	start := wallClock(t)
+	// Matching times recur at least every few years; give up after that.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<t.Month()) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+		case !s.dayMatches(t):
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+		case s.hour&(1<<t.Hour()) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()))
+		case s.minute&(1<<t.Minute()) == 0 || !s.wildcard && wallClock(t).Before(start):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,81 @@
+package containerslist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "*/15 * * * *"},
+		{spec: "0 0 1-5,10 * 1-5"},
+		{spec: "@daily"},
+		{spec: "@every 90s"},
+		{spec: "0 0 * * 7"},
+		{spec: "@every -1s", wantErr: true},
+		{spec: "@yearly", wantErr: true},
+		{spec: "0 0 * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "0 0 0 * *", wantErr: true},
+		{spec: "0 0 5-1 * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "a * * * *", wantErr: true},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := parseSchedule(tc.spec)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("parseSchedule error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	date := func(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, loc)
+	}
+	// In 2024, the clocks of New York were set forward on March 10 at 2:00
+	// and back on November 3 at 2:00.
+	fallBackEDT := date(newYork, 2024, time.November, 3, 1, 30)
+	fallBackEST := fallBackEDT.Add(time.Hour)
+
+	for _, tc := range []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{name: "step", spec: "*/15 * * * *", from: date(time.UTC, 2024, time.January, 1, 10, 7), want: date(time.UTC, 2024, time.January, 1, 10, 15)},
+		{name: "strictly after", spec: "*/15 * * * *", from: date(time.UTC, 2024, time.January, 1, 10, 15), want: date(time.UTC, 2024, time.January, 1, 10, 30)},
+		{name: "next month", spec: "0 0 1 * *", from: date(time.UTC, 2024, time.January, 1, 0, 0), want: date(time.UTC, 2024, time.February, 1, 0, 0)},
+		{name: "sunday as 7", spec: "0 0 * * 7", from: date(time.UTC, 2024, time.January, 1, 0, 0), want: date(time.UTC, 2024, time.January, 7, 0, 0)},
+		// 2024-01-01 is a Monday, 2024-05-15 a Wednesday.
+		{name: "day of month or week", spec: "0 0 15 * 1", from: date(time.UTC, 2024, time.January, 2, 0, 0), want: date(time.UTC, 2024, time.January, 8, 0, 0)},
+		{name: "day of month and week with star step", spec: "0 0 */2 * 1", from: date(time.UTC, 2024, time.January, 2, 0, 0), want: date(time.UTC, 2024, time.January, 15, 0, 0)},
+		{name: "day of week and month with star step", spec: "0 0 15 * */3", from: date(time.UTC, 2024, time.January, 2, 0, 0), want: date(time.UTC, 2024, time.May, 15, 0, 0)},
+		{name: "leap day", spec: "0 0 29 2 *", from: date(time.UTC, 2024, time.March, 1, 0, 0), want: date(time.UTC, 2028, time.February, 29, 0, 0)},
+		{name: "february 30", spec: "0 0 30 2 *", from: date(time.UTC, 2024, time.January, 1, 0, 0)},
+		{name: "time skipped by DST", spec: "30 2 * * *", from: date(newYork, 2024, time.March, 9, 3, 0), want: date(newYork, 2024, time.March, 11, 2, 30)},
+		{name: "across DST start", spec: "0 3 * * *", from: date(newYork, 2024, time.March, 10, 1, 0), want: date(newYork, 2024, time.March, 10, 3, 0)},
+		{name: "time repeated by DST", spec: "30 1 * * *", from: date(newYork, 2024, time.November, 3, 0, 0), want: fallBackEDT},
+		{name: "time repeated by DST runs once", spec: "30 1 * * *", from: fallBackEDT, want: date(newYork, 2024, time.November, 4, 1, 30)},
+		{name: "wildcard hour repeated by DST", spec: "30 * * * *", from: fallBackEDT, want: fallBackEST},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := parseSchedule(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tc.from); !got.Equal(tc.want) {
+				t.Errorf("Next(%v) = %v, want %v", tc.from, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package containerslist
+
+import (
+	"bytes"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// GCStats are the statistics of the garbage collector and of the heap of a
+// node.
+type GCStats struct {
+	Node       string    `json:"node"`
+	Goroutines int       `json:"goroutines"`
+	NumGC      int64     `json:"numGC"`
+	LastGC     time.Time `json:"lastGC"`
+	// PauseTotalSeconds is the total of the stop-the-world pauses, and
+	// RecentPausesSeconds the most recent ones, the latest first.
+	PauseTotalSeconds   float64   `json:"pauseTotalSeconds"`
+	RecentPausesSeconds []float64 `json:"recentPausesSeconds"`
+	HeapAllocBytes      uint64    `json:"heapAllocBytes"`
+	HeapInuseBytes      uint64    `json:"heapInuseBytes"`
+	HeapObjects         uint64    `json:"heapObjects"`
+	NextGCBytes         uint64    `json:"nextGCBytes"`
+	SysBytes            uint64    `json:"sysBytes"`
+}
+
+// readGCStats returns the statistics of the garbage collector, with at most
+// the 16 most recent pauses.
+func readGCStats(node string) GCStats {
+	var gc debug.GCStats
+	gc.Pause = make([]time.Duration, 16)
+	debug.ReadGCStats(&gc)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s := GCStats{
+		Node:                node,
+		Goroutines:          runtime.NumGoroutine(),
+		NumGC:               gc.NumGC,
+		LastGC:              gc.LastGC,
+		PauseTotalSeconds:   gc.PauseTotal.Seconds(),
+		RecentPausesSeconds: []float64{},
+		HeapAllocBytes:      mem.HeapAlloc,
+		HeapInuseBytes:      mem.HeapInuse,
+		HeapObjects:         mem.HeapObjects,
+		NextGCBytes:         mem.NextGC,
+		SysBytes:            mem.Sys,
+	}
+	for _, p := range gc.Pause {
+		s.RecentPausesSeconds = append(s.RecentPausesSeconds, p.Seconds())
+	}
+	return s
+}
+
+// debugNode handles the debugging requests about the node of the node query
+// parameter, the receiving node by default, forwarding the ones about the
+// other nodes to them. It returns the node, and false when the request was
+// forwarded.
+func (m *Manager) debugNode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	node := r.URL.Query().Get("node")
+	if node == "" || node == m.nodeID {
+		return m.nodeID, true
+	}
+	if _, ok := m.source(node); ok {
+		return node, true
+	}
+	m.forward(w, r, node)
+	return "", false
+}
+
+// apiGoroutines dumps the stacks of the goroutines of a node, as with
+// SIGQUIT.
+func (m *Manager) apiGoroutines() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node, ok := m.debugNode(w, r)
+		if !ok {
+			return
+		}
+		// The stacks are grouped by identical goroutines, unless asked for
+		// the full dump.
+		format := 1
+		if r.URL.Query().Get("full") == "true" {
+			format = 2
+		}
+		level.Info(m.requestLogger(r.Context())).Log("msg", "Dumping goroutines", "node", node)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		pprof.Lookup("goroutine").WriteTo(w, format)
+	})
+}
+
+// apiHeapProfile writes the heap profile of a node in the pprof format, for
+// go tool pprof to read it.
+func (m *Manager) apiHeapProfile() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node, ok := m.debugNode(w, r)
+		if !ok {
+			return
+		}
+		if r.URL.Query().Get("gc") == "true" {
+			runtime.GC()
+		}
+		var buf bytes.Buffer
+		if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to write heap profile: %v", err))
+			return
+		}
+		level.Info(m.requestLogger(r.Context())).Log("msg", "Writing heap profile", "node", node, "size", buf.Len())
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+node+`.heap.pb.gz"`)
+		w.Write(buf.Bytes())
+	})
+}
+
+// apiGCStats returns the statistics of the garbage collector of a node.
+func (m *Manager) apiGCStats() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node, ok := m.debugNode(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, readGCStats(node))
+	})
+}
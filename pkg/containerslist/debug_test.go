@@ -0,0 +1,72 @@
+package containerslist
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAPIDebug(t *testing.T) {
+	m := &Manager{
+		nodeID:   "n1",
+		logger:   log.NewNopLogger(),
+		registry: prometheus.NewRegistry(),
+		sources:  []*dockerSource{{node: "n2"}},
+		facts:    newNodeState[*NodeFacts](),
+	}
+	m.router = newRouter(m)
+	get := func(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for query, want := range map[string]string{"": "n1", "?node=n1": "n1", "?node=n2": "n2"} {
+		rec := get(m.apiGCStats(), "/api/v1/admin/debug/gc"+query, nil)
+		var s GCStats
+		if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Node != want || s.Goroutines == 0 || s.HeapAllocBytes == 0 || s.SysBytes == 0 || len(s.RecentPausesSeconds) > 16 {
+			t.Errorf("%q: stats = %+v, want the ones of %s", query, s, want)
+		}
+	}
+
+	if body := get(m.apiGoroutines(), "/api/v1/admin/debug/goroutines", nil).Body.String(); !strings.HasPrefix(body, "goroutine profile: total ") {
+		t.Errorf("goroutines = %.40q, want the grouped stacks", body)
+	}
+	if body := get(m.apiGoroutines(), "/api/v1/admin/debug/goroutines?full=true", nil).Body.String(); !strings.HasPrefix(body, "goroutine ") || strings.HasPrefix(body, "goroutine profile:") {
+		t.Errorf("goroutines = %.40q, want the full dump", body)
+	}
+
+	rec := get(m.apiHeapProfile(), "/api/v1/admin/debug/heap?node=n2&gc=true", nil)
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="n2.heap.pb.gz"` {
+		t.Errorf("disposition = %q, want the profile of n2", got)
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte{0x1f, 0x8b}) {
+		t.Error("heap profile not gzipped")
+	}
+
+	// The requests about the unknown nodes cannot be forwarded, and the
+	// forwarded ones are not forwarded again.
+	if rec := get(m.apiGCStats(), "/api/v1/admin/debug/gc?node=n3", nil); rec.Code != http.StatusMisdirectedRequest {
+		t.Errorf("status of n3 = %d, want %d", rec.Code, http.StatusMisdirectedRequest)
+	}
+	if rec := get(m.apiHeapProfile(), "/api/v1/admin/debug/heap?node=n3", http.Header{forwardedHeader: {"n4"}}); rec.Code != http.StatusMisdirectedRequest || rec.Body.Len() == 0 || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("status of a forwarded request = %d, want %d", rec.Code, http.StatusMisdirectedRequest)
+	}
+	if got := testutil.ToFloat64(m.router.loops); got != 1 {
+		t.Errorf("forwarding loops = %v, want 1", got)
+	}
+}
@@ -0,0 +1,146 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// DecommissionAck acknowledges the tombstones of decommissioned nodes seen
+// by a peer.
+type DecommissionAck struct {
+	// Node is the name of the acknowledging peer.
+	Node      string    `json:"node"`
+	Timestamp time.Time `json:"timestamp"`
+	// Nodes are the decommissioned nodes whose tombstone was received.
+	Nodes []string `json:"nodes"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+func (a *DecommissionAck) node() string              { return a.Node }
+func (a *DecommissionAck) timestamp() time.Time      { return a.Timestamp }
+func (a *DecommissionAck) signature() *Signature     { return a.Signature }
+func (a *DecommissionAck) setSignature(s *Signature) { a.Signature = s }
+
+// DecommissionResult is the result of decommissioning the local node.
+type DecommissionResult struct {
+	Nodes    []string `json:"nodes"`
+	Acks     []string `json:"acks"`
+	Required int      `json:"required"`
+	TimedOut bool     `json:"timedOut"`
+}
+
+// collectors tracks the running collectors, so that they can be stopped for
+// good when decommissioning the node.
+type collectors struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ackTombstones acknowledges the tombstones known to the local peer.
+func (m *Manager) ackTombstones() {
+	var nodes []string
+	for _, n := range m.inventory.Tombstones() {
+		nodes = append(nodes, n.Node)
+	}
+	b, err := m.decommissionAcks.Set(&DecommissionAck{
+		Node:      m.peer.Name(),
+		Timestamp: time.Now().UTC(),
+		Nodes:     nodes,
+	})
+	if err != nil {
+		level.Warn(m.logger).Log("msg", "Unable to acknowledge tombstones", "error", err)
+		return
+	}
+	m.decommissionChannel.Broadcast(b)
+}
+
+// acks returns the other peers which acknowledged the tombstones of all the
+// given nodes.
+func (m *Manager) acks(nodes []string) []string {
+	var peers []string
+	for _, a := range m.decommissionAcks.Nodes() {
+		if a.Node == m.peer.Name() {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, n := range a.Nodes {
+			seen[n] = true
+		}
+		all := true
+		for _, n := range nodes {
+			all = all && seen[n]
+		}
+		if all {
+			peers = append(peers, a.Node)
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// apiDecommission permanently removes the local node from the cluster: the
+// collectors are stopped, a tombstone replacing the inventory of each local
+// node is broadcast, and once a quorum of the other peers acknowledged them
+// or the timeout expired, the node leaves the cluster and exits.
+func (m *Manager) apiDecommission() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		if !m.decommissioned.CompareAndSwap(false, true) {
+			writeProblem(w, r, problemf(ErrConflict, "node already decommissioned"))
+			return
+		}
+		level.Info(m.requestLogger(r.Context())).Log("msg", "Decommissioning node")
+		m.collectors.cancel()
+		m.collectors.wg.Wait()
+
+		var res DecommissionResult
+		for _, s := range m.sources {
+			res.Nodes = append(res.Nodes, s.node)
+		}
+		for _, s := range m.sshSources {
+			res.Nodes = append(res.Nodes, s.name)
+		}
+		now := time.Now().UTC()
+		for _, node := range res.Nodes {
+			b, err := m.inventory.Set(&NodeInventory{Node: node, Timestamp: now, Decommissioned: true})
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			m.channel.Broadcast(b)
+		}
+
+		res.Required = (m.peer.ClusterSize()-1)/2 + 1
+		if m.peer.ClusterSize() <= 1 {
+			res.Required = 0
+		}
+		timeout := time.After(m.cfg.DecommissionTimeout)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+	wait:
+		for {
+			res.Acks = m.acks(res.Nodes)
+			if len(res.Acks) >= res.Required {
+				break
+			}
+			select {
+			case <-timeout:
+				res.TimedOut = true
+				break wait
+			case <-ticker.C:
+			}
+		}
+		level.Info(m.requestLogger(r.Context())).Log("msg", "Node decommissioned", "acks", len(res.Acks), "required", res.Required, "timed_out", res.TimedOut)
+		writeJSON(w, res)
+		m.stop()
+	})
+}
@@ -0,0 +1,111 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestPeer returns a peer alone in its cluster, which the caller must
+// leave.
+func newTestPeer(t *testing.T) *cluster.Peer {
+	t.Helper()
+	peer, err := cluster.Create(log.NewNopLogger(), prometheus.NewRegistry(), "127.0.0.1:0", "", nil, false, time.Minute, cluster.DefaultGossipInterval, time.Second, time.Second, time.Second, nil, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peer
+}
+
+func newTestDecommissionManager(t *testing.T, nodes ...*NodeInventory) *Manager {
+	t.Helper()
+	m := newTestInventoryManager(t, nodes...)
+	m.logger = log.NewNopLogger()
+	peer := newTestPeer(t)
+	t.Cleanup(func() { peer.Leave(time.Second) })
+	m.peer = peer
+	m.channel = &testChannel{}
+	m.decommissionAcks = newNodeState[*DecommissionAck]()
+	m.decommissionChannel = &testChannel{}
+	m.quit = make(chan struct{})
+	m.collectors.ctx, m.collectors.cancel = context.WithCancel(context.Background())
+	return m
+}
+
+func TestDecommissionAcks(t *testing.T) {
+	m := newTestDecommissionManager(t, &NodeInventory{Node: "a"}, &NodeInventory{Node: "b", Decommissioned: true})
+	m.ackTombstones()
+	if ch := m.decommissionChannel.(*testChannel); len(ch.msgs) != 1 {
+		t.Errorf("%d broadcasts, want 1", len(ch.msgs))
+	}
+	for _, a := range []*DecommissionAck{
+		{Node: "p1", Timestamp: time.Now(), Nodes: []string{"b", "c"}},
+		{Node: "p2", Timestamp: time.Now(), Nodes: []string{"b"}},
+		{Node: "p3", Timestamp: time.Now()},
+	} {
+		if _, err := m.decommissionAcks.Set(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The acknowledgement of the local peer is left out.
+	if got, want := m.acks([]string{"b"}), []string{"p1", "p2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("acks of b = %v, want %v", got, want)
+	}
+	if got, want := m.acks([]string{"b", "c"}), []string{"p1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("acks of b and c = %v, want %v", got, want)
+	}
+	if a, ok := m.decommissionAcks.Get(m.peer.Name()); !ok || !reflect.DeepEqual(a.Nodes, []string{"b"}) {
+		t.Errorf("local ack = %+v, want b", a)
+	}
+}
+
+func TestAPIDecommission(t *testing.T) {
+	m := newTestDecommissionManager(t, &NodeInventory{Node: "a", Containers: []Container{{ID: "a1"}}})
+	m.sources = []*dockerSource{{node: "a"}}
+
+	rec := httptest.NewRecorder()
+	m.apiDecommission().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/decommission", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var res DecommissionResult
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	// A single member needs no acknowledgement.
+	if !reflect.DeepEqual(res.Nodes, []string{"a"}) || res.Required != 0 || res.TimedOut {
+		t.Errorf("result = %+v, want a decommissioned without acknowledgement", res)
+	}
+	if m.collectors.ctx.Err() == nil {
+		t.Error("collectors not stopped")
+	}
+	if _, ok := m.inventory.Get("a"); ok {
+		t.Error("inventory of a still listed")
+	}
+	if tombstones := m.inventory.Tombstones(); len(tombstones) != 1 || len(tombstones[0].Containers) != 0 {
+		t.Errorf("tombstones = %+v, want the one of a", tombstones)
+	}
+	if len(m.channel.(*testChannel).msgs) != 1 {
+		t.Error("tombstone not broadcast")
+	}
+	select {
+	case <-m.quit:
+	default:
+		t.Error("node not stopped")
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiDecommission().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/decommission", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status of a second decommission = %d, want 409", rec.Code)
+	}
+}
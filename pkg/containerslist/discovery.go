@@ -0,0 +1,222 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hashicorp/memberlist"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultDiscoveryInterval = time.Minute
+	// discoveryTimeout bounds the discovery of the peers at startup, which
+	// delays the creation of the gossip mesh.
+	discoveryTimeout = 30 * time.Second
+)
+
+// peerDiscoverer finds the addresses of the peers, such as in the API of a
+// cloud provider.
+type peerDiscoverer interface {
+	// Peers returns the addresses of the peers, as host or host:port, the
+	// node itself included.
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// peerDiscoverers are the constructors of the discovery providers, by name.
+var peerDiscoverers = map[string]func(*Config) (peerDiscoverer, error){
+	"aws":   newAWSDiscoverer,
+	"azure": newAzureDiscoverer,
+	"dns":   newDNSDiscoverer,
+	"gce":   newGCEDiscoverer,
+}
+
+// dnsDiscoverer finds the peers in the SRV records of a name starting with
+// an underscore, such as _gossip._tcp.containerslist.example.org, or else in
+// its A and AAAA records. Unlike the names of -ha_peers, resolved once when
+// joining, the name is resolved at each refresh.
+type dnsDiscoverer struct {
+	name string
+}
+
+func newDNSDiscoverer(cfg *Config) (peerDiscoverer, error) {
+	if cfg.DiscoveryDNSName == "" {
+		return nil, errors.New("the dns discovery requires -ha_discovery_dns_name")
+	}
+	return dnsDiscoverer{name: cfg.DiscoveryDNSName}, nil
+}
+
+func (d dnsDiscoverer) Peers(ctx context.Context) ([]string, error) {
+	if !strings.HasPrefix(d.name, "_") {
+		return net.DefaultResolver.LookupHost(ctx, d.name)
+	}
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(records))
+	for _, r := range records {
+		addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+	}
+	return addrs, nil
+}
+
+// peerDiscovery seeds the peers of the gossip mesh with the ones found by a
+// discovery provider, and joins the peers found afterwards, such as the
+// instances added to an autoscaling group. The peers which disappear are
+// left to the failure detection of the mesh.
+type peerDiscovery struct {
+	provider   string
+	discoverer peerDiscoverer
+	// port is the gossip port of the discovered hosts without one.
+	port     string
+	interval time.Duration
+	logger   log.Logger
+
+	refreshes  *prometheus.CounterVec
+	discovered prometheus.Gauge
+}
+
+func newPeerDiscovery(cfg *Config, port string, logger log.Logger, reg prometheus.Registerer) (*peerDiscovery, error) {
+	provider, interval := cfg.DiscoveryProvider, cfg.DiscoveryInterval
+	newDiscoverer, ok := peerDiscoverers[provider]
+	if !ok {
+		return nil, fmt.Errorf("invalid discovery provider %q: must be one of %s", provider, strings.Join(sortedKeys(peerDiscoverers), ", "))
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid discovery interval %s: must be positive", interval)
+	}
+	discoverer, err := newDiscoverer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &peerDiscovery{
+		provider:   provider,
+		discoverer: discoverer,
+		port:       port,
+		interval:   interval,
+		logger:     log.With(logger, "component", "discovery", "provider", provider),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "containerslist_discovery_refreshes_total",
+			Help:        "Total number of refreshes of the peers found by the discovery provider, by result: success or failure.",
+			ConstLabels: prometheus.Labels{"provider": provider},
+		}, []string{"result"}),
+		discovered: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "containerslist_discovery_peers",
+			Help:        "Number of peers found by the last successful refresh of the discovery provider, the node included.",
+			ConstLabels: prometheus.Labels{"provider": provider},
+		}),
+	}
+	reg.MustRegister(d.refreshes, d.discovered)
+	return d, nil
+}
+
+// discover returns the sorted addresses of the discovered peers, as
+// host:port.
+func (d *peerDiscovery) discover(ctx context.Context) ([]string, error) {
+	found, err := d.discoverer.Peers(ctx)
+	if err != nil {
+		d.refreshes.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	d.refreshes.WithLabelValues("success").Inc()
+	seen := map[string]bool{}
+	var addrs []string
+	for _, addr := range found {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, d.port)
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	d.discovered.Set(float64(len(addrs)))
+	return addrs, nil
+}
+
+// Seed returns the peers found at startup, none when the provider fails,
+// for the node to start alone rather than not at all.
+func (d *peerDiscovery) Seed(ctx context.Context) []string {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	addrs, err := d.discover(ctx)
+	if err != nil {
+		level.Error(d.logger).Log("msg", "Unable to discover peers", "error", err)
+		return nil
+	}
+	level.Info(d.logger).Log("msg", "Discovered peers", "peers", len(addrs))
+	return addrs
+}
+
+// Run refreshes the discovered peers at each interval until ctx is done,
+// joining the ones which are not members of the mesh.
+func (d *peerDiscovery) Run(ctx context.Context, peer *cluster.Peer) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		addrs, err := d.discover(ctx)
+		if err != nil {
+			level.Warn(d.logger).Log("msg", "Unable to refresh the discovered peers", "error", err)
+			continue
+		}
+		joinNewPeers(peer, addrs, d.logger)
+	}
+}
+
+// joinNewPeers joins the addresses which are not the ones of a member of the
+// mesh.
+func joinNewPeers(peer *cluster.Peer, addrs []string, logger log.Logger) {
+	members := map[string]bool{peer.Self().Address(): true}
+	for _, p := range peer.Peers() {
+		members[p.Address()] = true
+	}
+	var joining []string
+	for _, addr := range addrs {
+		if !members[addr] {
+			joining = append(joining, addr)
+		}
+	}
+	if len(joining) == 0 {
+		return
+	}
+	mlist, err := memberlistOf(peer)
+	if err != nil {
+		level.Error(logger).Log("msg", "Unable to join new peers", "peers", len(joining), "error", err)
+		return
+	}
+	n, err := mlist.Join(joining)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Unable to join some new peers", "joined", n, "peers", len(joining), "error", err)
+		return
+	}
+	level.Info(logger).Log("msg", "Joined new peers", "joined", n)
+}
+
+// memberlistOf returns the memberlist of a peer, which the cluster package
+// of Alertmanager does not expose, only joining the peers given at its
+// creation.
+func memberlistOf(peer *cluster.Peer) (*memberlist.Memberlist, error) {
+	f := reflect.ValueOf(peer).Elem().FieldByName("mlist")
+	if !f.IsValid() || f.Type() != reflect.TypeOf((*memberlist.Memberlist)(nil)) || f.IsNil() {
+		return nil, errors.New("memberlist of the cluster peer not found")
+	}
+	return (*memberlist.Memberlist)(unsafe.Pointer(f.Pointer())), nil
+}
@@ -0,0 +1,143 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// staticDiscoverer returns fixed peers, or an error.
+type staticDiscoverer struct {
+	addrs []string
+	err   error
+}
+
+func (d staticDiscoverer) Peers(context.Context) ([]string, error) {
+	return d.addrs, d.err
+}
+
+func TestNewAWSDiscoverer(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	for _, tc := range []struct {
+		tag, asg string
+		want     map[string]string
+	}{
+		{tag: "role=containerslist", want: map[string]string{"instance-state-name": "running", "tag:role": "containerslist"}},
+		{asg: "fleet", want: map[string]string{"instance-state-name": "running", "tag:aws:autoscaling:groupName": "fleet"}},
+		{},
+		{tag: "role"},
+		{tag: "=containerslist"},
+	} {
+		cfg := DefaultConfig()
+		cfg.DiscoveryAWSTag, cfg.DiscoveryAWSASG = tc.tag, tc.asg
+		d, err := newAWSDiscoverer(cfg)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("discoverer with tag %q and group %q accepted, want an error", tc.tag, tc.asg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.(*awsDiscoverer).filters; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("filters = %v, want %v", got, tc.want)
+		}
+	}
+}
+
+func TestAWSDiscovererPeers(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("Action") != "DescribeInstances" || q.Get("Filter.2.Name") != "tag:role" || q.Get("Filter.2.Value.1") != "containerslist" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if q.Get("NextToken") == "" {
+			w.Write([]byte(`<DescribeInstancesResponse><reservationSet><item><instancesSet>
+				<item><privateIpAddress>10.0.0.1</privateIpAddress></item>
+				<item></item>
+			</instancesSet></item></reservationSet><nextToken>page-2</nextToken></DescribeInstancesResponse>`))
+			return
+		}
+		w.Write([]byte(`<DescribeInstancesResponse><reservationSet><item><instancesSet>
+			<item><privateIpAddress>10.0.0.2</privateIpAddress></item>
+		</instancesSet></item></reservationSet></DescribeInstancesResponse>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.DiscoveryAWSRegion, cfg.DiscoveryAWSTag = "eu-west-3", "role=containerslist"
+	d, err := newAWSDiscoverer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.(*awsDiscoverer).endpoint = srv.URL + "/"
+	addrs, err := d.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("peers = %q, want %q", addrs, want)
+	}
+
+	// The discoverer of another tag is rejected by the server.
+	d.(*awsDiscoverer).filters["tag:role"] = "other"
+	if _, err := d.Peers(context.Background()); err == nil {
+		t.Error("peers found with a rejected request")
+	}
+}
+
+func TestPeerDiscovery(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DiscoveryProvider = "dns"
+	if _, err := newPeerDiscovery(cfg, "9094", log.NewNopLogger(), prometheus.NewRegistry()); err == nil {
+		t.Error("dns discovery without a name accepted")
+	}
+	cfg.DiscoveryProvider = "consul"
+	if _, err := newPeerDiscovery(cfg, "9094", log.NewNopLogger(), prometheus.NewRegistry()); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	cfg.DiscoveryProvider, cfg.DiscoveryDNSName, cfg.DiscoveryInterval = "dns", "containerslist.example.org", 0
+	if _, err := newPeerDiscovery(cfg, "9094", log.NewNopLogger(), prometheus.NewRegistry()); err == nil {
+		t.Error("discovery without an interval accepted")
+	}
+
+	cfg.DiscoveryInterval = defaultDiscoveryInterval
+	d, err := newPeerDiscovery(cfg, "9094", log.NewNopLogger(), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.discoverer = staticDiscoverer{addrs: []string{"10.0.0.2", "10.0.0.1:7946", "10.0.0.2:9094", "fd00::1"}}
+	want := []string{"10.0.0.1:7946", "10.0.0.2:9094", "[fd00::1]:9094"}
+	if got := d.Seed(context.Background()); !reflect.DeepEqual(got, want) {
+		t.Errorf("peers = %q, want %q", got, want)
+	}
+	if got := testutil.ToFloat64(d.discovered); got != 3 {
+		t.Errorf("discovered peers = %v, want 3", got)
+	}
+
+	// The node starts alone when the provider fails.
+	d.discoverer = staticDiscoverer{err: errors.New("throttled")}
+	if got := d.Seed(context.Background()); got != nil {
+		t.Errorf("peers = %q, want none", got)
+	}
+	if got := testutil.ToFloat64(d.refreshes.WithLabelValues("failure")); got != 1 {
+		t.Errorf("failed refreshes = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(d.discovered); got != 3 {
+		t.Errorf("discovered peers = %v, want the ones of the last success", got)
+	}
+}
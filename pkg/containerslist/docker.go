@@ -0,0 +1,361 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerClient is a minimal client for the Docker Engine API.
+type dockerClient struct {
+	client  *http.Client
+	baseURL string
+	breaker *circuitBreaker
+}
+
+func newDockerClient(host string) (*dockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	c := &dockerClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	switch u.Scheme {
+	case "unix":
+		c.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", u.Path)
+			},
+		}
+		c.baseURL = "http://docker"
+	case "tcp", "http":
+		c.baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+	return c, nil
+}
+
+func (c *dockerClient) get(ctx context.Context, path string, v interface{}) error {
+	return c.do(ctx, http.MethodGet, path, v)
+}
+
+func (c *dockerClient) do(ctx context.Context, method, path string, v interface{}) error {
+	return c.breaker.Do(ctx, func() error {
+		return c.call(ctx, method, path, v)
+	})
+}
+
+func (c *dockerClient) call(ctx context.Context, method, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("docker API %s: unexpected status %s", path, resp.Status)
+		// The daemon answered: only its server errors count as failures.
+		if resp.StatusCode < http.StatusInternalServerError {
+			return callerError{err}
+		}
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// dockerContainer is the subset of the Docker container summary we use.
+type dockerContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+	Ports   []struct {
+		IP          string `json:"IP"`
+		PrivatePort uint16 `json:"PrivatePort"`
+		PublicPort  uint16 `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// ListContainers returns all containers known to the daemon, whatever their
+// state.
+func (c *dockerClient) ListContainers(ctx context.Context) ([]Container, error) {
+	var dcs []dockerContainer
+	if err := c.get(ctx, "/containers/json?all=true", &dcs); err != nil {
+		return nil, err
+	}
+
+	containers := make([]Container, 0, len(dcs))
+	for _, dc := range dcs {
+		var name string
+		if len(dc.Names) > 0 {
+			name = strings.TrimPrefix(dc.Names[0], "/")
+		}
+		var ports []Port
+		for _, p := range dc.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			ports = append(ports, Port{
+				HostIP:        p.IP,
+				HostPort:      p.PublicPort,
+				ContainerPort: p.PrivatePort,
+				Protocol:      p.Type,
+			})
+		}
+		containers = append(containers, Container{
+			ID:      dc.ID,
+			Name:    name,
+			Image:   dc.Image,
+			ImageID: dc.ImageID,
+			State:   dc.State,
+			Status:  dc.Status,
+			Created: time.Unix(dc.Created, 0).UTC(),
+			Labels:  dc.Labels,
+			Ports:   ports,
+		})
+	}
+	return containers, nil
+}
+
+// dockerContainerJSON is the subset of the Docker container inspect response
+// we use.
+type dockerContainerJSON struct {
+	State struct {
+		Status     string    `json:"Status"`
+		OOMKilled  bool      `json:"OOMKilled"`
+		StartedAt  time.Time `json:"StartedAt"`
+		FinishedAt time.Time `json:"FinishedAt"`
+	} `json:"State"`
+	Config struct {
+		Env []string `json:"Env"`
+	} `json:"Config"`
+	HostConfig struct {
+		Runtime string `json:"Runtime"`
+		Devices []struct {
+			PathOnHost      string `json:"PathOnHost"`
+			PathInContainer string `json:"PathInContainer"`
+		} `json:"Devices"`
+		DeviceRequests []struct {
+			Driver       string     `json:"Driver"`
+			Count        int        `json:"Count"`
+			DeviceIDs    []string   `json:"DeviceIDs"`
+			Capabilities [][]string `json:"Capabilities"`
+		} `json:"DeviceRequests"`
+	} `json:"HostConfig"`
+
+	RestartCount int `json:"RestartCount"`
+}
+
+// InspectContainer returns the details of a container which are not part of
+// the container list: its attached devices and GPUs, when it started and
+// finished, and its restarts.
+func (c *dockerClient) InspectContainer(ctx context.Context, id string) (containerDetails, error) {
+	var dc dockerContainerJSON
+	if err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/json", &dc); err != nil {
+		return containerDetails{}, err
+	}
+
+	var (
+		devices []Device
+		gpus    []string
+	)
+	for _, d := range dc.HostConfig.Devices {
+		devices = append(devices, Device{HostPath: d.PathOnHost, ContainerPath: d.PathInContainer})
+		if idx := strings.TrimPrefix(d.PathOnHost, "/dev/nvidia"); idx != d.PathOnHost && isDigits(idx) {
+			gpus = append(gpus, idx)
+		}
+	}
+
+	for _, r := range dc.HostConfig.DeviceRequests {
+		if r.Driver != "nvidia" && !hasCapability(r.Capabilities, "gpu") {
+			continue
+		}
+		switch {
+		case len(r.DeviceIDs) > 0:
+			gpus = append(gpus, r.DeviceIDs...)
+		case r.Count < 0:
+			gpus = append(gpus, "all")
+		default:
+			for i := 0; i < r.Count; i++ {
+				gpus = append(gpus, "any")
+			}
+		}
+	}
+
+	// The legacy nvidia runtime selects GPUs through an environment variable.
+	if dc.HostConfig.Runtime == "nvidia" && len(gpus) == 0 {
+		for _, env := range dc.Config.Env {
+			if v, ok := strings.CutPrefix(env, "NVIDIA_VISIBLE_DEVICES="); ok && v != "" && v != "none" && v != "void" {
+				gpus = append(gpus, strings.Split(v, ",")...)
+			}
+		}
+	}
+	details := containerDetails{
+		state:        dc.State.Status,
+		devices:      devices,
+		gpus:         gpus,
+		restartCount: dc.RestartCount,
+		oomKilled:    dc.State.OOMKilled,
+		inspectedAt:  time.Now(),
+	}
+	if !dc.State.StartedAt.IsZero() {
+		startedAt := dc.State.StartedAt.UTC()
+		details.startedAt = &startedAt
+	}
+	if dc.State.Status != StateRunning && !dc.State.FinishedAt.IsZero() {
+		finishedAt := dc.State.FinishedAt.UTC()
+		details.finishedAt = &finishedAt
+	}
+	return details, nil
+}
+
+func hasCapability(capabilities [][]string, capability string) bool {
+	for _, caps := range capabilities {
+		for _, c := range caps {
+			if c == capability {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ListNetworks returns the networks defined on the daemon.
+func (c *dockerClient) ListNetworks(ctx context.Context) ([]Network, error) {
+	var dns []struct {
+		ID       string `json:"Id"`
+		Name     string `json:"Name"`
+		Driver   string `json:"Driver"`
+		Scope    string `json:"Scope"`
+		Internal bool   `json:"Internal"`
+	}
+	if err := c.get(ctx, "/networks", &dns); err != nil {
+		return nil, err
+	}
+
+	networks := make([]Network, 0, len(dns))
+	for _, dn := range dns {
+		networks = append(networks, Network(dn))
+	}
+	return networks, nil
+}
+
+// ListVolumes returns the volumes defined on the daemon.
+func (c *dockerClient) ListVolumes(ctx context.Context) ([]Volume, error) {
+	var resp struct {
+		Volumes []struct {
+			Name       string `json:"Name"`
+			Driver     string `json:"Driver"`
+			Mountpoint string `json:"Mountpoint"`
+			Scope      string `json:"Scope"`
+		} `json:"Volumes"`
+	}
+	if err := c.get(ctx, "/volumes", &resp); err != nil {
+		return nil, err
+	}
+
+	volumes := make([]Volume, 0, len(resp.Volumes))
+	for _, dv := range resp.Volumes {
+		volumes = append(volumes, Volume(dv))
+	}
+	return volumes, nil
+}
+
+// ListImages returns the images present on the daemon.
+func (c *dockerClient) ListImages(ctx context.Context) ([]Image, error) {
+	var dis []struct {
+		ID       string   `json:"Id"`
+		RepoTags []string `json:"RepoTags"`
+		Size     int64    `json:"Size"`
+		Created  int64    `json:"Created"`
+	}
+	if err := c.get(ctx, "/images/json", &dis); err != nil {
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(dis))
+	for _, di := range dis {
+		var tags []string
+		for _, t := range di.RepoTags {
+			if t != "<none>:<none>" {
+				tags = append(tags, t)
+			}
+		}
+		images = append(images, Image{
+			ID:      di.ID,
+			Tags:    tags,
+			Size:    di.Size,
+			Created: time.Unix(di.Created, 0).UTC(),
+		})
+	}
+	return images, nil
+}
+
+// PruneContainers removes the stopped containers which finished before the
+// given age, returning their IDs and the reclaimed space.
+func (c *dockerClient) PruneContainers(ctx context.Context, until time.Duration) ([]string, int64, error) {
+	filters := url.QueryEscape(fmt.Sprintf(`{"until":[%q]}`, until.String()))
+	var resp struct {
+		ContainersDeleted []string `json:"ContainersDeleted"`
+		SpaceReclaimed    int64    `json:"SpaceReclaimed"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/containers/prune?filters="+filters, &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.ContainersDeleted, resp.SpaceReclaimed, nil
+}
+
+// PruneImages removes the dangling images, returning their IDs and the
+// reclaimed space.
+func (c *dockerClient) PruneImages(ctx context.Context) ([]string, int64, error) {
+	filters := url.QueryEscape(`{"dangling":["true"]}`)
+	var resp struct {
+		ImagesDeleted []struct {
+			Deleted string `json:"Deleted"`
+		} `json:"ImagesDeleted"`
+		SpaceReclaimed int64 `json:"SpaceReclaimed"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/images/prune?filters="+filters, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	var deleted []string
+	for _, d := range resp.ImagesDeleted {
+		if d.Deleted != "" {
+			deleted = append(deleted, d.Deleted)
+		}
+	}
+	return deleted, resp.SpaceReclaimed, nil
+}
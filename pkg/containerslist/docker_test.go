@@ -0,0 +1,136 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestDockerClient returns a client of a fake Docker daemon answering the
+// requests of the paths of responses with their JSON, and 404 to the others.
+func newTestDockerClient(t *testing.T, responses map[string]string) *dockerClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	c, err := newDockerClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestInspectContainerGPUs(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		hostConfig  string
+		env         []string
+		wantDevices []Device
+		wantGPUs    []string
+	}{
+		{
+			name:        "devices",
+			hostConfig:  `{"Devices": [{"PathOnHost": "/dev/fuse", "PathInContainer": "/dev/fuse"}, {"PathOnHost": "/dev/nvidia1", "PathInContainer": "/dev/nvidia0"}, {"PathOnHost": "/dev/nvidiactl", "PathInContainer": "/dev/nvidiactl"}]}`,
+			wantDevices: []Device{{HostPath: "/dev/fuse", ContainerPath: "/dev/fuse"}, {HostPath: "/dev/nvidia1", ContainerPath: "/dev/nvidia0"}, {HostPath: "/dev/nvidiactl", ContainerPath: "/dev/nvidiactl"}},
+			wantGPUs:    []string{"1"},
+		},
+		{
+			name:       "device IDs",
+			hostConfig: `{"DeviceRequests": [{"Driver": "nvidia", "DeviceIDs": ["0", "GPU-8a2f"]}]}`,
+			wantGPUs:   []string{"0", "GPU-8a2f"},
+		},
+		{
+			name:       "all GPUs",
+			hostConfig: `{"DeviceRequests": [{"Count": -1, "Capabilities": [["gpu"]]}]}`,
+			wantGPUs:   []string{"all"},
+		},
+		{
+			name:       "count",
+			hostConfig: `{"DeviceRequests": [{"Driver": "nvidia", "Count": 2}]}`,
+			wantGPUs:   []string{"any", "any"},
+		},
+		{
+			name:       "other driver",
+			hostConfig: `{"DeviceRequests": [{"Driver": "intel", "Count": 1, "Capabilities": [["compute"]]}]}`,
+		},
+		{
+			name:       "legacy runtime",
+			hostConfig: `{"Runtime": "nvidia"}`,
+			env:        []string{"PATH=/usr/bin", "NVIDIA_VISIBLE_DEVICES=0,1"},
+			wantGPUs:   []string{"0", "1"},
+		},
+		{
+			name:       "legacy runtime without GPUs",
+			hostConfig: `{"Runtime": "nvidia"}`,
+			env:        []string{"NVIDIA_VISIBLE_DEVICES=none"},
+		},
+		{
+			name:       "other runtime",
+			hostConfig: `{"Runtime": "runc"}`,
+			env:        []string{"NVIDIA_VISIBLE_DEVICES=0"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := json.Marshal(tc.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := newTestDockerClient(t, map[string]string{
+				"/containers/c1/json": `{"State": {"Status": "running"}, "Config": {"Env": ` + string(env) + `}, "HostConfig": ` + tc.hostConfig + `}`,
+			})
+			d, err := c.InspectContainer(context.Background(), "c1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(d.devices, tc.wantDevices) {
+				t.Errorf("devices = %v, want %v", d.devices, tc.wantDevices)
+			}
+			if !reflect.DeepEqual(d.gpus, tc.wantGPUs) {
+				t.Errorf("gpus = %q, want %q", d.gpus, tc.wantGPUs)
+			}
+		})
+	}
+}
+
+func TestAPIGPUs(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig(), inventory: newInventory()}
+	for _, n := range []*NodeInventory{
+		{Node: "a", Timestamp: time.Now(), Containers: []Container{
+			{ID: "a1", Name: "train", Image: "pytorch", State: StateRunning, GPUs: []string{"0"}},
+			{ID: "a2", Name: "done", Image: "pytorch", State: "exited", GPUs: []string{"1"}},
+			{ID: "a3", Name: "web", Image: "nginx", State: StateRunning},
+		}},
+		{Node: "b", Timestamp: time.Now(), Containers: []Container{
+			{ID: "b1", Name: "infer", Image: "triton", State: StateRunning, GPUs: []string{"0", "1"}},
+		}},
+	} {
+		if _, err := m.inventory.Set(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiGPUs().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/gpus", nil))
+	var got []GPUUsage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []GPUUsage{
+		{Node: "b", Container: "b1", Name: "infer", Image: "triton", GPUs: []string{"0", "1"}},
+		{Node: "a", Container: "a1", Name: "train", Image: "pytorch", GPUs: []string{"0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GPUs = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package containerslist
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bufferedUpdate is a state update broadcast while the node was disconnected.
+type bufferedUpdate struct {
+	time    time.Time
+	channel cluster.ClusterChannel
+	msg     []byte
+}
+
+// edgeBuffer stores the state updates of a frequently disconnected node
+// while it has no peer, and forwards them in order once it reconnects, so
+// that the peers see every change instead of only the final state.
+type edgeBuffer struct {
+	connected func() bool
+	size      int
+	logger    log.Logger
+
+	mtx     sync.Mutex
+	updates []bufferedUpdate
+
+	buffered prometheus.GaugeFunc
+	dropped  prometheus.Counter
+}
+
+func newEdgeBuffer(connected func() bool, size int, logger log.Logger, reg prometheus.Registerer) *edgeBuffer {
+	b := &edgeBuffer{
+		connected: connected,
+		size:      size,
+		logger:    logger,
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "containerslist_edge_dropped_updates_total",
+			Help: "Number of state updates dropped because the edge buffer was full.",
+		}),
+	}
+	b.buffered = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "containerslist_edge_buffered_updates",
+		Help: "Number of state updates buffered while the node is disconnected.",
+	}, func() float64 {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		return float64(len(b.updates))
+	})
+	reg.MustRegister(b.buffered, b.dropped)
+	return b
+}
+
+// edgeChannel buffers the broadcasts of a channel while the node is
+// disconnected.
+type edgeChannel struct {
+	cluster.ClusterChannel
+	buffer *edgeBuffer
+}
+
+// Broadcast implements cluster.ClusterChannel.
+func (c edgeChannel) Broadcast(b []byte) {
+	c.buffer.broadcast(c.ClusterChannel, b)
+}
+
+// channel wraps a channel with the buffer, when the node runs in edge mode.
+func (b *edgeBuffer) channel(c cluster.ClusterChannel) cluster.ClusterChannel {
+	if b == nil {
+		return c
+	}
+	return edgeChannel{ClusterChannel: c, buffer: b}
+}
+
+func (b *edgeBuffer) broadcast(c cluster.ClusterChannel, msg []byte) {
+	if !b.connected() {
+		b.mtx.Lock()
+		if len(b.updates) >= b.size {
+			b.updates = b.updates[1:]
+			b.dropped.Inc()
+		}
+		b.updates = append(b.updates, bufferedUpdate{time: time.Now(), channel: c, msg: msg})
+		b.mtx.Unlock()
+		return
+	}
+	// Flush the pending updates first, so that they are not superseded by
+	// the new one.
+	b.flush()
+	c.Broadcast(msg)
+}
+
+// flush forwards the buffered updates.
+func (b *edgeBuffer) flush() {
+	b.mtx.Lock()
+	updates := b.updates
+	b.updates = nil
+	b.mtx.Unlock()
+
+	if len(updates) == 0 {
+		return
+	}
+	for _, u := range updates {
+		u.channel.Broadcast(u.msg)
+	}
+	level.Info(b.logger).Log("msg", "Reconnected, flushed buffered updates", "updates", len(updates), "disconnected_for", time.Since(updates[0].time).Round(time.Second))
+}
+
+// Run periodically flushes the buffered updates once the node is connected
+// again, even when nothing new is broadcast.
+func (b *edgeBuffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if b.connected() {
+			b.flush()
+		}
+	}
+}
+
+// connected reports whether the node sees at least one peer.
+func (m *Manager) connected() bool {
+	return m.peer.ClusterSize() > 1
+}
+
+// annotateEdges reports the edge nodes whose inventory is stale as offline
+// instead: they are expected to be disconnected at times, and their
+// inventory is the one of their last sync.
+func annotateEdges(nodes []*NodeInventory) {
+	for _, n := range nodes {
+		if n.Edge && n.Stale {
+			n.Stale = false
+			n.Offline = true
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEdgeBuffer(t *testing.T) {
+	var b *edgeBuffer
+	inventory, facts := &testChannel{}, &testChannel{}
+	if c := b.channel(inventory); c != inventory {
+		t.Error("channel wrapped without edge mode")
+	}
+
+	connected := false
+	b = newEdgeBuffer(func() bool { return connected }, 2, log.NewNopLogger(), prometheus.NewRegistry())
+	ci, cf := b.channel(inventory), b.channel(facts)
+	ci.Broadcast([]byte("i1"))
+	cf.Broadcast([]byte("f1"))
+	ci.Broadcast([]byte("i2"))
+	if len(inventory.msgs)+len(facts.msgs) != 0 {
+		t.Error("updates broadcast while disconnected")
+	}
+	if got := testutil.ToFloat64(b.buffered); got != 2 {
+		t.Errorf("buffered updates = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(b.dropped); got != 1 {
+		t.Errorf("dropped updates = %v, want the oldest one", got)
+	}
+
+	// The buffered updates are flushed before the new one.
+	connected = true
+	ci.Broadcast([]byte("i3"))
+	var got []string
+	for _, m := range inventory.msgs {
+		got = append(got, string(m))
+	}
+	if want := []string{"i2", "i3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inventory broadcasts = %q, want %q", got, want)
+	}
+	if len(facts.msgs) != 1 || string(facts.msgs[0]) != "f1" {
+		t.Errorf("facts broadcasts = %q, want f1", facts.msgs)
+	}
+	if got := testutil.ToFloat64(b.buffered); got != 0 {
+		t.Errorf("buffered updates = %v, want none once flushed", got)
+	}
+}
+
+func TestAnnotateEdges(t *testing.T) {
+	nodes := []*NodeInventory{
+		{Node: "edge", Edge: true, Stale: true},
+		{Node: "fresh-edge", Edge: true},
+		{Node: "stale", Stale: true},
+	}
+	annotateEdges(nodes)
+	want := map[string][2]bool{"edge": {false, true}, "fresh-edge": {false, false}, "stale": {true, false}}
+	for _, n := range nodes {
+		if got := [2]bool{n.Stale, n.Offline}; got != want[n.Node] {
+			t.Errorf("%s: stale and offline = %v, want %v", n.Node, got, want[n.Node])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package containerslist
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultEphemeralAnnotationTTL is the lifetime of the ephemeral
+	// annotations set without a TTL.
+	defaultEphemeralAnnotationTTL    = 24 * time.Hour
+	defaultEphemeralAnnotationMaxTTL = 7 * 24 * time.Hour
+)
+
+// EphemeralAnnotation is a note attached by an operator to a container until
+// it expires, such as "under investigation" with a link to the ticket. An
+// annotation with an empty text clears the previous ones of the container.
+type EphemeralAnnotation struct {
+	Node        string    `json:"node"`
+	ContainerID string    `json:"containerID"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link,omitempty"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// ephemeralAnnotations is the latest active annotation of each container, by
+// node and container ID.
+type ephemeralAnnotations map[string]EphemeralAnnotation
+
+// For returns the annotation of a container, for the templates.
+func (a ephemeralAnnotations) For(node, id string) *EphemeralAnnotation {
+	if e, ok := a[node+"/"+id]; ok {
+		return &e
+	}
+	return nil
+}
+
+// activeEphemeralAnnotations returns the latest annotation of each container
+// which has not expired, leaving out the cleared ones. Each member gossips
+// the annotations set through its API, so the annotations of a container may
+// come from several members: the most recent one wins.
+func (m *Manager) activeEphemeralAnnotations(now time.Time) ephemeralAnnotations {
+	latest := map[string]EphemeralAnnotation{}
+	for _, n := range m.ephemeralAnnotations.Nodes() {
+		for _, a := range n.Items {
+			key := a.Node + "/" + a.ContainerID
+			if prev, ok := latest[key]; !ok || a.Created.After(prev.Created) {
+				latest[key] = a
+			}
+		}
+	}
+	res := ephemeralAnnotations{}
+	for key, a := range latest {
+		if a.Text != "" && a.Expires.After(now) {
+			res[key] = a
+		}
+	}
+	return res
+}
+
+// setEphemeralAnnotation adds an annotation to the entry of the local node,
+// dropping the expired ones and the previous ones of the same container, and
+// broadcasts it.
+func (m *Manager) setEphemeralAnnotation(a EphemeralAnnotation) error {
+	m.ephemeralAnnotationsMtx.Lock()
+	defer m.ephemeralAnnotationsMtx.Unlock()
+
+	items := []EphemeralAnnotation{a}
+	if n, ok := m.ephemeralAnnotations.Get(m.nodeID); ok {
+		for _, prev := range n.Items {
+			if prev.Expires.After(a.Created) && (prev.Node != a.Node || prev.ContainerID != a.ContainerID) {
+				items = append(items, prev)
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Created.Before(items[j].Created) })
+	b, err := m.ephemeralAnnotations.Set(&NodeResources[EphemeralAnnotation]{Node: m.nodeID, Timestamp: a.Created, Items: items})
+	if err != nil {
+		return err
+	}
+	m.ephemeralAnnotationsChannel.Broadcast(b)
+	return nil
+}
+
+// apiSetEphemeralAnnotation attaches an annotation to the container of the
+// path /api/v1/containers/{node}/{id}/annotations, with the text, link and
+// ttl query parameters. Node names may contain slashes.
+func (m *Manager) apiSetEphemeralAnnotation() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/containers/"), "/annotations")
+		i := strings.LastIndex(rest, "/")
+		if !ok || i <= 0 || i == len(rest)-1 {
+			writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+			return
+		}
+		if !requireMethod(w, r, http.MethodPut) {
+			return
+		}
+		node, id := rest[:i], rest[i+1:]
+		q := r.URL.Query()
+		ttl := defaultEphemeralAnnotationTTL
+		if v := q.Get("ttl"); v != "" {
+			var err error
+			if ttl, err = time.ParseDuration(v); err != nil || ttl <= 0 {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid ttl %q: must be a positive duration", v))
+				return
+			}
+		}
+		if ttl > m.cfg.EphemeralAnnotationMaxTTL {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "ttl %s exceeds the maximum of %s", ttl, m.cfg.EphemeralAnnotationMaxTTL))
+			return
+		}
+		n, ok := m.inventory.Get(node)
+		if !ok {
+			writeProblem(w, r, problemf(ErrNotFound, "no node %q", node))
+			return
+		}
+		// The containers of the summarized nodes are not known locally.
+		found := n.Summary
+		for _, c := range n.Containers {
+			if c.ID == id || truncateID(c.ID) == id {
+				id, found = c.ID, true
+				break
+			}
+		}
+		if !found {
+			writeProblem(w, r, problemf(ErrNotFound, "no container %q on node %s", id, node))
+			return
+		}
+
+		now := time.Now().UTC()
+		a := EphemeralAnnotation{
+			Node:        node,
+			ContainerID: id,
+			Text:        q.Get("text"),
+			Link:        q.Get("link"),
+			Created:     now,
+			Expires:     now.Add(ttl),
+		}
+		if err := m.setEphemeralAnnotation(a); err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to set annotation: %v", err))
+			return
+		}
+		writeJSON(w, a)
+	})
+}
+
+// apiEphemeralAnnotations lists the active ephemeral annotations, sorted by
+// node and container.
+func (m *Manager) apiEphemeralAnnotations() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active := m.activeEphemeralAnnotations(time.Now())
+		res := make([]EphemeralAnnotation, 0, len(active))
+		for _, a := range active {
+			res = append(res, a)
+		}
+		sort.Slice(res, func(i, j int) bool {
+			if res[i].Node != res[j].Node {
+				return res[i].Node < res[j].Node
+			}
+			return res[i].ContainerID < res[j].ContainerID
+		})
+		writeJSON(w, res)
+	})
+}
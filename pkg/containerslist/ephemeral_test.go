@@ -0,0 +1,119 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestEphemeralManager(t *testing.T, nodes ...*NodeInventory) (*Manager, *testChannel) {
+	t.Helper()
+	m := newTestInventoryManager(t, nodes...)
+	m.nodeID = "n1"
+	m.ephemeralAnnotations = newResourceState[EphemeralAnnotation]()
+	ch := &testChannel{}
+	m.ephemeralAnnotationsChannel = ch
+	return m, ch
+}
+
+func TestAPISetEphemeralAnnotation(t *testing.T) {
+	m, ch := newTestEphemeralManager(t,
+		&NodeInventory{Node: "edge/a", Containers: []Container{{ID: "0123456789abcdef", Name: "web"}, {ID: "a2", Name: "db"}}},
+		&NodeInventory{Node: "b", Summary: true},
+	)
+	put := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.apiSetEphemeralAnnotation().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, nil))
+		return rec
+	}
+
+	rec := put("/api/v1/containers/edge/a/0123456789ab/annotations?text=under+investigation&link=https://tickets.example.com/42&ttl=1h")
+	var a EphemeralAnnotation
+	if err := json.NewDecoder(rec.Body).Decode(&a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Node != "edge/a" || a.ContainerID != "0123456789abcdef" || a.Text != "under investigation" || a.Link != "https://tickets.example.com/42" || a.Expires.Sub(a.Created) != time.Hour {
+		t.Errorf("annotation = %+v, want the one of web on edge/a for 1h", a)
+	}
+	if len(ch.msgs) != 1 {
+		t.Errorf("%d broadcasts, want 1", len(ch.msgs))
+	}
+
+	// The containers of the summarized nodes are not checked.
+	if rec := put("/api/v1/containers/b/b1/annotations?text=flaky"); rec.Code != http.StatusOK {
+		t.Errorf("status on a summarized node = %d, want 200", rec.Code)
+	}
+	if a := m.activeEphemeralAnnotations(time.Now()).For("b", "b1"); a == nil || a.Expires.Sub(a.Created) != defaultEphemeralAnnotationTTL {
+		t.Errorf("annotation = %+v, want the default TTL", a)
+	}
+
+	for path, code := range map[string]int{
+		"/api/v1/containers/edge/a/a3/annotations":                   http.StatusNotFound,
+		"/api/v1/containers/c/c1/annotations":                        http.StatusNotFound,
+		"/api/v1/containers/a2/annotations":                          http.StatusNotFound,
+		"/api/v1/containers/edge/a/a2/labels":                        http.StatusNotFound,
+		"/api/v1/containers/edge/a/a2/annotations?ttl=-1h":           http.StatusBadRequest,
+		"/api/v1/containers/edge/a/a2/annotations?ttl=soon":          http.StatusBadRequest,
+		"/api/v1/containers/edge/a/a2/annotations?ttl=1000h":         http.StatusBadRequest,
+		"/api/v1/containers/edge/a/a2/annotations?ttl=1h":            http.StatusOK,
+		"/api/v1/containers/edge/a/a2/annotations?text=cleared&ttl=": http.StatusOK,
+	} {
+		if rec := put(path); rec.Code != code {
+			t.Errorf("status of %s = %d, want %d", path, rec.Code, code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiSetEphemeralAnnotation().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers/edge/a/a2/annotations", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status of a GET = %d, want 405", rec.Code)
+	}
+}
+
+func TestActiveEphemeralAnnotations(t *testing.T) {
+	m, _ := newTestEphemeralManager(t)
+	now := time.Now().UTC()
+	annotation := func(id, text string, created time.Time) EphemeralAnnotation {
+		return EphemeralAnnotation{Node: "a", ContainerID: id, Text: text, Created: created, Expires: created.Add(time.Hour)}
+	}
+
+	// The local entry keeps the latest annotation of each container.
+	for _, a := range []EphemeralAnnotation{
+		annotation("a1", "first", now.Add(-2*time.Hour)),
+		annotation("a2", "investigating", now.Add(-time.Minute)),
+		annotation("a3", "slow", now.Add(-time.Second)),
+		annotation("a2", "fixed", now),
+	} {
+		if err := m.setEphemeralAnnotation(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, _ := m.ephemeralAnnotations.Get("n1")
+	if len(n.Items) != 2 || n.Items[0].ContainerID != "a3" || n.Items[1].Text != "fixed" {
+		t.Errorf("local annotations = %+v, want the ones of a3 and a2 without the expired one", n.Items)
+	}
+
+	// Another member cleared the annotation of a3 more recently.
+	if _, err := m.ephemeralAnnotations.Set(&NodeResources[EphemeralAnnotation]{Node: "n2", Timestamp: now, Items: []EphemeralAnnotation{
+		annotation("a3", "", now),
+		annotation("a4", "old", now.Add(-2*time.Hour)),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	active := m.activeEphemeralAnnotations(now)
+	if len(active) != 1 || active.For("a", "a2") == nil || active.For("a", "a2").Text != "fixed" || active.For("a", "a3") != nil {
+		t.Errorf("active annotations = %+v, want the one of a2", active)
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiEphemeralAnnotations().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/annotations", nil))
+	var list []EphemeralAnnotation
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ContainerID != "a2" {
+		t.Errorf("annotations = %+v, want the one of a2", list)
+	}
+}
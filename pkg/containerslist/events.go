@@ -0,0 +1,161 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Types of container events.
+const (
+	EventAppeared    = "appeared"
+	EventDisappeared = "disappeared"
+	EventConflict    = "conflict"
+)
+
+// ContainerEvent reports a container appearing in or disappearing from the
+// inventory of a node, or reported by several nodes.
+type ContainerEvent struct {
+	Type      string    `json:"type"`
+	Node      string    `json:"node"`
+	Time      time.Time `json:"time"`
+	Container Container `json:"container"`
+	// Conflict is set on the conflict events, with the claims of the nodes
+	// reporting the container.
+	Conflict *ContainerConflict `json:"conflict,omitempty"`
+}
+
+// eventBroker fans out container events to the subscribed streams. Events are
+// dropped for subscribers which do not keep up.
+type eventBroker struct {
+	mtx  sync.Mutex
+	subs map[chan ContainerEvent]struct{}
+	// published counts the events by type.
+	published *prometheus.CounterVec
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subs: map[chan ContainerEvent]struct{}{},
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_container_events_total",
+			Help: "Total number of container events of all the nodes of the cluster, by type: appeared, disappeared or conflict.",
+		}, []string{"type"}),
+		closed: make(chan struct{}),
+	}
+}
+
+// Close ends the streams of the subscribers, when shutting down.
+func (b *eventBroker) Close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+// Subscribe returns a channel of events, and a function to unsubscribe.
+func (b *eventBroker) Subscribe() (<-chan ContainerEvent, func()) {
+	ch := make(chan ContainerEvent, 64)
+	b.mtx.Lock()
+	b.subs[ch] = struct{}{}
+	b.mtx.Unlock()
+	return ch, func() {
+		b.mtx.Lock()
+		delete(b.subs, ch)
+		b.mtx.Unlock()
+	}
+}
+
+// Publish sends events to all subscribers.
+func (b *eventBroker) Publish(events []ContainerEvent) {
+	if b == nil || len(events) == 0 {
+		return
+	}
+	for _, e := range events {
+		b.published.WithLabelValues(e.Type).Inc()
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for ch := range b.subs {
+		for _, e := range events {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// diffContainers returns the events turning the previous inventory of a node
+// into the next one.
+func diffContainers(prev, next *NodeInventory) []ContainerEvent {
+	before := make(map[string]Container, len(prev.Containers))
+	for _, c := range prev.Containers {
+		before[c.ID] = c
+	}
+	var events []ContainerEvent
+	for _, c := range next.Containers {
+		if _, ok := before[c.ID]; ok {
+			delete(before, c.ID)
+			continue
+		}
+		events = append(events, ContainerEvent{Type: EventAppeared, Node: next.Node, Time: next.Timestamp, Container: c})
+	}
+	for _, c := range before {
+		events = append(events, ContainerEvent{Type: EventDisappeared, Node: next.Node, Time: next.Timestamp, Container: c})
+	}
+	return events
+}
+
+// apiEvents streams the container events of the cluster as server-sent events.
+func (m *Manager) apiEvents() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeProblem(w, r, problemf(ErrInternal, "streaming unsupported"))
+			return
+		}
+		format, err := m.eventFormat(r.URL.Query())
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		events, unsubscribe := m.events.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepalive := time.NewTicker(30 * time.Second)
+		defer keepalive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-m.events.closed:
+				return
+			case <-keepalive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+			case e := <-events:
+				var v interface{} = e
+				name := e.Type
+				if format == eventFormatCloudEvents {
+					ce := newCloudEvent(e, m.cfg.CloudEventsSource)
+					v, name = ce, ce.Type
+				}
+				b, err := json.Marshal(v)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+			}
+			flusher.Flush()
+		}
+	})
+}
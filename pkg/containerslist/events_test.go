@@ -0,0 +1,95 @@
+package containerslist
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffContainers(t *testing.T) {
+	prev := &NodeInventory{Node: "a", Containers: []Container{{ID: "c1"}, {ID: "c2"}}}
+	next := &NodeInventory{Node: "a", Timestamp: time.Now(), Containers: []Container{{ID: "c2", State: "exited"}, {ID: "c3"}}}
+	var got []string
+	for _, e := range diffContainers(prev, next) {
+		if e.Node != "a" || !e.Time.Equal(next.Timestamp) {
+			t.Errorf("event = %+v, want one of node a at the time of the inventory", e)
+		}
+		got = append(got, e.Type+" "+e.Container.ID)
+	}
+	sort.Strings(got)
+	if want := []string{"appeared c3", "disappeared c1"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestInventoryEvents(t *testing.T) {
+	m := newTestInventoryManager(t)
+	m.events = newEventBroker()
+	m.inventory.events = m.events
+	events, unsubscribe := m.events.Subscribe()
+	defer unsubscribe()
+
+	now := time.Now()
+	for i, n := range []*NodeInventory{
+		// The containers of a new node do not appear.
+		{Node: "a", Timestamp: now, Containers: []Container{{ID: "c1"}}},
+		{Node: "a", Timestamp: now.Add(time.Second), Containers: []Container{{ID: "c1"}, {ID: "c2"}}},
+		// An older inventory is ignored.
+		{Node: "a", Timestamp: now, Containers: []Container{}},
+	} {
+		if _, err := m.inventory.Set(n); err != nil {
+			t.Fatalf("inventory %d: %v", i, err)
+		}
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventAppeared || e.Container.ID != "c2" {
+			t.Errorf("event = %+v, want c2 appeared", e)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestAPIEvents(t *testing.T) {
+	m := newTestInventoryManager(t)
+	m.events = newEventBroker()
+	srv := httptest.NewServer(m.apiEvents())
+	defer srv.Close()
+	defer m.events.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// The stream is subscribed once its headers are sent.
+	m.events.Publish([]ContainerEvent{{Type: EventDisappeared, Node: "a", Container: Container{ID: "c1"}}})
+	sc := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 2 && sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if len(lines) != 2 || lines[0] != "event: disappeared" || !strings.HasPrefix(lines[1], "data: {") || !strings.Contains(lines[1], `"id":"c1"`) {
+		t.Errorf("stream = %q, want the disappeared event", lines)
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiEvents().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events?format=xml", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status of an invalid format = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
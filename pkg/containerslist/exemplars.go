@@ -0,0 +1,71 @@
+package containerslist
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// traceparentHeader is the W3C Trace Context header of the incoming
+// requests: version-traceid-parentid-flags.
+const traceparentHeader = "traceparent"
+
+// latencyHistogramOpts sets the options of the native histograms on the
+// options of a latency histogram. The classic buckets are kept for the
+// scrapers which do not negotiate the protobuf format.
+func latencyHistogramOpts(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+	opts.NativeHistogramBucketFactor = 1.1
+	opts.NativeHistogramMaxBucketNumber = 160
+	opts.NativeHistogramMinResetDuration = time.Hour
+	return opts
+}
+
+// traceID returns the trace ID of the traceparent header of a request, or an
+// empty string when it has none or an invalid one.
+func traceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get(traceparentHeader), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return ""
+		}
+	}
+	return parts[1]
+}
+
+// observeRequest observes the duration of a request, with an exemplar
+// holding its trace ID, or else its request ID, for the dashboards to link
+// the slow requests to their traces or logs.
+func observeRequest(o prometheus.Observer, r *http.Request, d time.Duration) {
+	labels := prometheus.Labels{}
+	if id := traceID(r); id != "" {
+		labels["trace_id"] = id
+	} else if id := requestID(r.Context()); id != "" {
+		labels["request_id"] = id
+	}
+	if e, ok := o.(prometheus.ExemplarObserver); ok && len(labels) > 0 {
+		e.ObserveWithExemplar(d.Seconds(), labels)
+		return
+	}
+	o.Observe(d.Seconds())
+}
+
+// instrumentHandler observes the duration of the requests served by h, by
+// pattern of the handler of mux serving them.
+func instrumentHandler(mux *http.ServeMux, h http.Handler, duration *prometheus.HistogramVec) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		observeRequest(duration.WithLabelValues(pattern, r.Method, strconv.Itoa(rec.status)), r, time.Since(start))
+	})
+}
@@ -0,0 +1,139 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTraceID(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for header, want := range map[string]string{
+		valid:                                 "4bf92f3577b34da6a3ce929d0e0e4736",
+		"":                                    "",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736": "",
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01":                 "",
+		strings.Replace(valid, "00", "ff", 1):                     "",
+		strings.ToUpper(valid):                                    "",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01": "",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(traceparentHeader, header)
+		if got := traceID(r); got != want {
+			t.Errorf("traceID(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+// histogram returns the histogram gathered from a registry.
+func histogram(t *testing.T, reg *prometheus.Registry, name string) *dto.Histogram {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name && len(f.GetMetric()) == 1 {
+			return f.GetMetric()[0].GetHistogram()
+		}
+	}
+	t.Fatalf("no single %s histogram", name)
+	return nil
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	duration := prometheus.NewHistogramVec(latencyHistogramOpts(prometheus.HistogramOpts{
+		Name: "test_request_duration_seconds",
+		Help: "Duration of the test requests.",
+	}), []string{"handler", "method", "code"})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(duration)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/nodes/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("instrumented response writer is not a flusher")
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	h := instrumentHandler(mux, mux, duration)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/nodes/a", nil)
+	r.Header.Set(traceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{}
+	for _, l := range families[0].GetMetric()[0].GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	if labels["handler"] != "/api/v1/nodes/" || labels["method"] != http.MethodGet || labels["code"] != "418" {
+		t.Errorf("labels = %v, want the pattern, the method and the status", labels)
+	}
+
+	hist := histogram(t, reg, "test_request_duration_seconds")
+	if hist.GetSampleCount() != 1 || hist.Schema == nil || len(hist.GetBucket()) != len(prometheus.DefBuckets) {
+		t.Errorf("histogram = %v, want a native histogram with the default classic buckets", hist)
+	}
+	var exemplar *dto.Exemplar
+	for _, b := range hist.GetBucket() {
+		if b.Exemplar != nil {
+			exemplar = b.Exemplar
+		}
+	}
+	if exemplar == nil || len(exemplar.GetLabel()) != 1 || exemplar.GetLabel()[0].GetName() != "trace_id" || exemplar.GetLabel()[0].GetValue() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("exemplar = %v, want the trace ID", exemplar)
+	}
+}
+
+func TestObserveRequestID(t *testing.T) {
+	h := prometheus.NewHistogram(latencyHistogramOpts(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test."}))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(h)
+
+	// Without a trace, the exemplar holds the request ID.
+	withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observeRequest(h, r, time.Millisecond)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var labels []*dto.LabelPair
+	for _, b := range histogram(t, reg, "test_seconds").GetBucket() {
+		if b.Exemplar != nil {
+			labels = b.Exemplar.GetLabel()
+		}
+	}
+	if len(labels) != 1 || labels[0].GetName() != "request_id" || labels[0].GetValue() == "" {
+		t.Errorf("exemplar labels = %v, want the request ID", labels)
+	}
+}
+
+func TestGossipPropagationDelay(t *testing.T) {
+	i := newInventory()
+	i.delay = prometheus.NewHistogram(latencyHistogramOpts(prometheus.HistogramOpts{Name: "test_delay_seconds", Help: "Test."}))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(i.delay)
+
+	b, err := json.Marshal([]*NodeInventory{{Node: "a", Timestamp: time.Now().Add(-2 * time.Second)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second merge is a conflict, which is not observed.
+	for n := 0; n < 2; n++ {
+		if err := i.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hist := histogram(t, reg, "test_delay_seconds")
+	if hist.GetSampleCount() != 1 || hist.GetSampleSum() < 2 || hist.GetSampleSum() > 10 {
+		t.Errorf("delays = %d observations summing to %vs, want one of about 2s", hist.GetSampleCount(), hist.GetSampleSum())
+	}
+}
@@ -0,0 +1,239 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Export formats of the inventory snapshots.
+const (
+	exportJSON    = "json"
+	exportParquet = "parquet"
+)
+
+// exportNameData is the data against which the prefix template of the
+// exported objects is executed.
+type exportNameData struct {
+	// Time is the UTC time of the export.
+	Time time.Time
+	// Node is the node exporting the snapshot.
+	Node string
+}
+
+// objectStore uploads objects to an S3-compatible object storage, signing
+// its requests with AWS Signature Version 4. Google Cloud Storage is
+// supported through its XML API, with HMAC keys.
+type objectStore struct {
+	endpoint *url.URL
+	bucket   string
+	region   string
+
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+
+	client *http.Client
+}
+
+// newObjectStore parses an s3://bucket or gs://bucket URL. The endpoint
+// defaults to the one of the region for S3, and to storage.googleapis.com
+// for GCS.
+func newObjectStore(rawURL, endpoint, region, accessKeyID, secretAccessKeyFile string) (*objectStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || (u.Scheme != "s3" && u.Scheme != "gs") {
+		return nil, fmt.Errorf("invalid export URL %q: must be s3://bucket or gs://bucket", rawURL)
+	}
+	if endpoint == "" {
+		if u.Scheme == "gs" {
+			endpoint = "https://storage.googleapis.com"
+		} else {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+	}
+	s := &objectStore{
+		bucket:       u.Host,
+		region:       region,
+		accessKeyID:  accessKeyID,
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 5 * time.Minute},
+	}
+	if s.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid export endpoint %q: %w", endpoint, err)
+	}
+	if s.accessKeyID == "" {
+		s.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if secretAccessKeyFile != "" {
+		b, err := os.ReadFile(secretAccessKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.secretAccessKey = strings.TrimSpace(string(b))
+	} else {
+		s.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if s.accessKeyID == "" || s.secretAccessKey == "" {
+		return nil, fmt.Errorf("no credentials for the export bucket %s", s.bucket)
+	}
+	return s, nil
+}
+
+// Put uploads an object, addressing the bucket in the path of the endpoint.
+func (s *objectStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unable to upload %s: %s: %s", key, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 of a request to its headers.
+func (s *objectStore) sign(req *http.Request, body []byte, now time.Time) {
+	signAWSv4(req, body, now, s.region, "s3", s.accessKeyID, s.secretAccessKey, s.sessionToken)
+}
+
+// signAWSv4 adds the AWS Signature Version 4 of a request to a service to
+// its headers. The query of the request must be in canonical form.
+func signAWSv4(req *http.Request, body []byte, now time.Time, region, service, accessKeyID, secretAccessKey, sessionToken string) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath escapes a path as in the canonical requests of S3: every
+// byte but the unreserved characters and the slashes is percent-encoded.
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// exporter periodically exports snapshots of the cluster inventory to an
+// object storage. The objects are named after the time of the export, under
+// a prefix template which can partition them by date, for lifecycle rules
+// to expire them.
+type exporter struct {
+	store  *objectStore
+	prefix *template.Template
+	format string
+}
+
+func newExporter(store *objectStore, prefix, format string) (*exporter, error) {
+	t, err := template.New("prefix").Parse(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export prefix: %w", err)
+	}
+	if format != exportJSON && format != exportParquet {
+		return nil, fmt.Errorf("invalid export format %q: must be one of %s, %s", format, exportJSON, exportParquet)
+	}
+	return &exporter{store: store, prefix: t, format: format}, nil
+}
+
+// key returns the key of the object exported at the given time.
+func (e *exporter) key(now time.Time, node string) (string, error) {
+	var b strings.Builder
+	if err := e.prefix.Execute(&b, exportNameData{Time: now, Node: node}); err != nil {
+		return "", err
+	}
+	return b.String() + "inventory-" + now.Format("20060102T150405Z") + "." + e.format, nil
+}
+
+// exportJob uploads a snapshot of the cluster inventory, in the stable
+// format of the containers API or one row per container in Parquet.
+func (m *Manager) exportJob(ctx context.Context) error {
+	now := time.Now().UTC()
+	key, err := m.exporter.key(now, m.nodeID)
+	if err != nil {
+		return err
+	}
+	if m.exporter.format == exportParquet {
+		return m.exporter.store.Put(ctx, key, parquetContentType, containersParquet(m.inventory.Nodes()))
+	}
+	b, err := stableInventory(m.inventory.Nodes())
+	if err != nil {
+		return err
+	}
+	return m.exporter.store.Put(ctx, key, "application/json", b)
+}
@@ -0,0 +1,324 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nodesTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - nodes</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <table>
+      <tr><th>Node</th><th>Zone</th><th>OS</th><th>Kernel</th><th>Architecture</th><th>Runtime</th><th>cgroups</th><th>Containers</th><th>Usage</th></tr>
+    {{ range . }}
+      <tr><td>{{ .Node }}{{ with .Maintenance }} <mark title="{{ .Reason }}">{{ t "in maintenance" }}</mark>{{ end }}</td><td>{{ .Zone }}</td><td>{{ .OS }}</td><td>{{ .Kernel }}</td><td>{{ .Architecture }}</td><td>{{ .Runtime }} {{ .RuntimeVersion }}{{ if .Outdated }} <strong>(outdated)</strong>{{ end }}</td><td>v{{ .CgroupVersion }}</td><td>{{ .Containers }}</td><td>{{ with .Usage }}CPU {{ printf "%.0f" .CPUPercent }}%, memory {{ printf "%.0f" .MemoryPercent }}%, disk {{ printf "%.0f" .DiskPercent }}%{{ end }}</td></tr>
+    {{ end }}
+    </table>
+  </body>
+</html>
+`
+
+// NodeFacts describes the operating system and container runtime of a node.
+type NodeFacts struct {
+	Node           string    `json:"node"`
+	Timestamp      time.Time `json:"timestamp"`
+	Peer           string    `json:"peer"`
+	OS             string    `json:"os"`
+	Kernel         string    `json:"kernel"`
+	Architecture   string    `json:"architecture"`
+	Runtime        string    `json:"runtime"`
+	RuntimeVersion string    `json:"runtimeVersion"`
+	CgroupVersion  string    `json:"cgroupVersion"`
+	CPUs           int       `json:"cpus,omitempty"`
+	// InternalAddress is the address of the internal API of the peer, to
+	// which the requests about the node are forwarded.
+	InternalAddress string `json:"internalAddress,omitempty"`
+	// InternalIdentity is the identity of the certificate of the internal
+	// API of the peer, verified before forwarding credentials to it.
+	InternalIdentity string `json:"internalIdentity,omitempty"`
+	// Zone is the zone of the node, such as its cloud availability zone.
+	Zone string `json:"zone,omitempty"`
+	// DaemonID is the ID of the Docker daemon of the node, persisted by
+	// Docker, by which the renamed nodes are recognized.
+	DaemonID string `json:"daemonID,omitempty"`
+	// Fingerprint is derived from the machine ID of the host of the agent,
+	// suffixed with the name of the endpoint for the sub-nodes. It is
+	// preferred to the daemon ID, which is shared by cloned hosts.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Labels are set by the operator on the node, such as role=web.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Passive is set on the facts advertised by the passive members, which
+	// only describe their peer.
+	Passive bool `json:"passive,omitempty"`
+	// InventoryProto is set by the peers decoding the protobuf encoding of
+	// the gossiped inventory, which is only used once all the members do.
+	InventoryProto bool `json:"inventoryProto,omitempty"`
+	// Left is set on the facts of the nodes of a peer which left the cluster
+	// gracefully, on shutdown or once decommissioned, and is no longer
+	// expected as a member.
+	Left bool `json:"left,omitempty"`
+	// Outdated is computed when serving the facts, comparing the runtime
+	// version against the other nodes running the same runtime.
+	Outdated bool `json:"outdated"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+func (f *NodeFacts) node() string              { return f.Node }
+func (f *NodeFacts) timestamp() time.Time      { return f.Timestamp }
+func (f *NodeFacts) signature() *Signature     { return f.Signature }
+func (f *NodeFacts) setSignature(s *Signature) { f.Signature = s }
+
+// PeerInfo is a member of the cluster, with its node ID and facts when known.
+type PeerInfo struct {
+	Name string `json:"name"`
+	// DisplayName is its alias, or the name given by the display name
+	// template.
+	DisplayName string     `json:"displayName"`
+	Address     string     `json:"address"`
+	Node        string     `json:"node,omitempty"`
+	Facts       *NodeFacts `json:"facts,omitempty"`
+	// ClockSkewSeconds is the estimated clock skew of the peer, positive
+	// when its clock is ahead of the local one.
+	ClockSkewSeconds *float64 `json:"clockSkewSeconds,omitempty"`
+	// Usage is the usage of the host of the node, when sampled.
+	Usage *NodeUsage `json:"usage,omitempty"`
+	// Passive is set on the passive members, which collect no node.
+	Passive bool `json:"passive,omitempty"`
+}
+
+// Info returns the facts of the Docker daemon and the host it runs on.
+func (c *dockerClient) Info(ctx context.Context) (NodeFacts, error) {
+	var info struct {
+		OperatingSystem string `json:"OperatingSystem"`
+		KernelVersion   string `json:"KernelVersion"`
+		Architecture    string `json:"Architecture"`
+		ServerVersion   string `json:"ServerVersion"`
+		CgroupVersion   string `json:"CgroupVersion"`
+		NCPU            int    `json:"NCPU"`
+		ID              string `json:"ID"`
+	}
+	if err := c.get(ctx, "/info", &info); err != nil {
+		return NodeFacts{}, err
+	}
+	return NodeFacts{
+		OS:             info.OperatingSystem,
+		Kernel:         info.KernelVersion,
+		Architecture:   info.Architecture,
+		Runtime:        "docker",
+		RuntimeVersion: info.ServerVersion,
+		CgroupVersion:  info.CgroupVersion,
+		CPUs:           info.NCPU,
+		DaemonID:       info.ID,
+	}, nil
+}
+
+// collectFacts gathers the facts of a source and broadcasts them to the
+// cluster.
+func (m *Manager) collectFacts(ctx context.Context, s *dockerSource) error {
+	facts, err := s.client.Info(ctx)
+	if err != nil {
+		return err
+	}
+	facts.Node = s.node
+	facts.Peer = m.peer.Name()
+	if m.fingerprint != "" {
+		facts.Fingerprint = m.fingerprint
+		if s.name != "" {
+			facts.Fingerprint += "/" + s.name
+		}
+	}
+	facts.InternalAddress = m.internalAddress
+	facts.InternalIdentity = m.internalIdentity()
+	facts.InventoryProto = true
+	facts.Zone = m.cfg.Zone
+	if len(m.cfg.NodeLabels) > 0 {
+		facts.Labels = m.cfg.NodeLabels
+	}
+	facts.Timestamp = time.Now().UTC()
+
+	b, err := m.facts.Set(&facts)
+	if err != nil {
+		return err
+	}
+	m.factsChannel.Broadcast(b)
+	return nil
+}
+
+// legacyPeers reports whether some members of the cluster do not advertise
+// decoding the protobuf inventory: the peers running older versions during a
+// rolling upgrade, or the ones whose facts were not received yet.
+func (m *Manager) legacyPeers() bool {
+	if m.peer == nil {
+		return false
+	}
+	proto := map[string]bool{}
+	for _, f := range m.facts.Nodes() {
+		if f.InventoryProto {
+			proto[f.Peer] = true
+		}
+	}
+	for _, p := range m.peer.Peers() {
+		if !proto[p.Name()] {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions compares two dotted version strings numerically, ignoring
+// any pre-release or build suffix.
+func compareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+~"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// nodeFacts returns the facts of all nodes, flagging the runtimes older than
+// the minimum configured version or than the newest version of the same
+// runtime in the cluster. The passive members, which have no node, and the
+// renamed nodes are left out.
+func (m *Manager) nodeFacts() []*NodeFacts {
+	var nodes []*NodeFacts
+	for _, f := range m.facts.Nodes() {
+		if !f.Passive && !m.handedOff(f.Node) {
+			nodes = append(nodes, f)
+		}
+	}
+
+	newest := map[string]string{}
+	for _, f := range nodes {
+		if compareVersions(f.RuntimeVersion, newest[f.Runtime]) > 0 {
+			newest[f.Runtime] = f.RuntimeVersion
+		}
+	}
+
+	res := make([]*NodeFacts, 0, len(nodes))
+	for _, f := range nodes {
+		f := *f
+		if m.cfg.MinRuntimeVersion != "" {
+			f.Outdated = compareVersions(f.RuntimeVersion, m.cfg.MinRuntimeVersion) < 0
+		} else {
+			f.Outdated = compareVersions(f.RuntimeVersion, newest[f.Runtime]) < 0
+		}
+		res = append(res, &f)
+	}
+	return res
+}
+
+// peerFacts returns the facts of the node of each peer. Sub-nodes share the
+// peer of their node: only the facts of the node itself are kept.
+func (m *Manager) peerFacts() map[string]*NodeFacts {
+	facts := map[string]*NodeFacts{}
+	for _, f := range m.nodeFacts() {
+		if _, ok := facts[f.Peer]; !ok || len(f.Node) < len(facts[f.Peer].Node) {
+			facts[f.Peer] = f
+		}
+	}
+	return facts
+}
+
+func (m *Manager) apiPeers() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mask, err := parseFieldMask(r, PeerInfo{})
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		facts := m.peerFacts()
+		skews := m.clock.Skews()
+		passive := m.passivePeers()
+		names := m.peerDisplayNames()
+		peers := []PeerInfo{}
+		for _, p := range m.peer.Peers() {
+			info := PeerInfo{
+				Name:        p.Name(),
+				DisplayName: names[p.Name()],
+				Address:     p.Address(),
+				Passive:     passive[p.Name()],
+			}
+			if f, ok := facts[p.Name()]; ok {
+				info.Node = f.Node
+				info.Facts = f
+				if u, ok := m.usage.Get(f.Node); ok {
+					info.Usage = u
+				}
+			}
+			if skew, ok := skews[p.Name()]; ok {
+				seconds := skew.Seconds()
+				info.ClockSkewSeconds = &seconds
+			}
+			peers = append(peers, info)
+		}
+		writeMaskedJSON(w, r, peers, mask)
+	})
+}
+
+// nodeRow is a row of the nodes page.
+type nodeRow struct {
+	*NodeFacts
+	Containers int
+	Usage      *NodeUsage
+	// Maintenance is the maintenance window in progress of the node.
+	Maintenance *MaintenanceWindow
+}
+
+func (m *Manager) nodesPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rows []nodeRow
+		maintenance := m.activeMaintenance(time.Now())
+		for _, f := range m.nodeFacts() {
+			row := nodeRow{NodeFacts: f, Maintenance: maintenance.Window(f.Node)}
+			if n, ok := m.inventory.Get(f.Node); ok {
+				row.Containers = len(n.Containers)
+			}
+			if u, ok := m.usage.Get(f.Node); ok {
+				row.Usage = u
+			}
+			rows = append(rows, row)
+		}
+		if err := localize(t, r).ExecuteTemplate(w, "nodes", rows); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
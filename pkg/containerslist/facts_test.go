@@ -0,0 +1,77 @@
+package containerslist
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{a: "24.0.7", b: "24.0.7", want: 0},
+		{a: "24.0.7", b: "24.0.10", want: -1},
+		{a: "25.0.0", b: "24.0.10", want: 1},
+		{a: "v1.7", b: "1.7.0", want: 0},
+		{a: "20.10.24-ce", b: "20.10.24", want: 0},
+		{a: "1.6.0+build", b: "1.6.1~rc1", want: -1},
+		{a: "24.0.7", b: "", want: 1},
+		{a: "unknown", b: "1.0", want: -1},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestDockerInfo(t *testing.T) {
+	c := newTestDockerClient(t, map[string]string{
+		"/info": `{"OperatingSystem": "Ubuntu 22.04.3 LTS", "KernelVersion": "6.5.0", "Architecture": "x86_64", "ServerVersion": "24.0.7", "CgroupVersion": "2", "NCPU": 8, "ID": "daemon-1"}`,
+	})
+	facts, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NodeFacts{OS: "Ubuntu 22.04.3 LTS", Kernel: "6.5.0", Architecture: "x86_64", Runtime: "docker", RuntimeVersion: "24.0.7", CgroupVersion: "2", CPUs: 8, DaemonID: "daemon-1"}
+	if !reflect.DeepEqual(facts, want) {
+		t.Errorf("facts = %+v, want %+v", facts, want)
+	}
+}
+
+func TestNodeFactsOutdated(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		minVersion string
+		want       map[string]bool
+	}{
+		{name: "newest in cluster", want: map[string]bool{"a": false, "b": true, "c": false, "d": false}},
+		{name: "minimum version", minVersion: "24.0.0", want: map[string]bool{"a": false, "b": true, "c": true, "d": false}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Manager{cfg: DefaultConfig(), facts: newNodeState[*NodeFacts]()}
+			m.cfg.MinRuntimeVersion = tc.minVersion
+			for _, f := range []*NodeFacts{
+				{Node: "a", Peer: "p1", Runtime: "docker", RuntimeVersion: "24.0.7"},
+				{Node: "b", Peer: "p2", Runtime: "docker", RuntimeVersion: "20.10.24"},
+				{Node: "c", Peer: "p3", Runtime: "containerd", RuntimeVersion: "1.7.2"},
+				{Node: "d", Peer: "p4", Runtime: "docker", RuntimeVersion: "24.0.7"},
+				{Node: "p5", Peer: "p5", Passive: true},
+			} {
+				f.Timestamp = time.Now()
+				if _, err := m.facts.Set(f); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := map[string]bool{}
+			for _, f := range m.nodeFacts() {
+				got[f.Node] = f.Outdated
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("outdated = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakeImages are the images of the synthetic containers, with the port they
+// expose, if any.
+var fakeImages = []struct {
+	image string
+	port  uint16
+}{
+	{"nginx:1.25", 80},
+	{"redis:7.2", 6379},
+	{"postgres:16", 5432},
+	{"grafana/grafana:10.4.0", 3000},
+	{"prom/prometheus:v2.51.0", 9090},
+	{"busybox:1.36", 0},
+	{"alpine:3.19", 0},
+	{"registry.example.com/shop/api:2.3.1", 8080},
+	{"registry.example.com/shop/worker:2.3.1", 0},
+}
+
+var fakeZones = []string{"zone-a", "zone-b", "zone-c"}
+
+var fakeProjects = []string{"shop", "monitoring", "billing", "search", "batch"}
+
+// fakeDiscovery generates synthetic nodes and containers with churn, for
+// demos, UI work and load tests without real runtimes.
+type fakeDiscovery struct {
+	nodes      int
+	containers int
+
+	rnd         *rand.Rand
+	inventories map[string][]Container
+	exitedSince map[string]map[string]time.Time
+}
+
+// parseFakeDiscovery parses a nodes:N,containers:M specification, M being the
+// total number of containers of the cluster.
+func parseFakeDiscovery(spec string) (*fakeDiscovery, error) {
+	f := &fakeDiscovery{nodes: 3, containers: 50}
+	for _, kv := range strings.Split(spec, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), ":")
+		n, err := strconv.Atoi(v)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid fake discovery %q: must be nodes:N,containers:M", spec)
+		}
+		switch k {
+		case "nodes":
+			f.nodes = n
+		case "containers":
+			f.containers = n
+		default:
+			return nil, fmt.Errorf("invalid fake discovery %q: unknown key %q", spec, k)
+		}
+	}
+	if f.nodes == 0 {
+		return nil, fmt.Errorf("invalid fake discovery %q: at least one node is required", spec)
+	}
+	f.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	f.inventories = map[string][]Container{}
+	f.exitedSince = map[string]map[string]time.Time{}
+	return f, nil
+}
+
+func (f *fakeDiscovery) nodeName(i int) string {
+	return fmt.Sprintf("fake-%02d", i+1)
+}
+
+func (f *fakeDiscovery) newContainer(now time.Time) Container {
+	img := fakeImages[f.rnd.Intn(len(fakeImages))]
+	project := fakeProjects[f.rnd.Intn(len(fakeProjects))]
+	name := strings.SplitN(img.image[strings.LastIndex(img.image, "/")+1:], ":", 2)[0]
+
+	raw := make([]byte, 32)
+	f.rnd.Read(raw)
+	id := hex.EncodeToString(raw)
+	digest := sha256.Sum256([]byte(img.image))
+
+	c := Container{
+		ID:      id,
+		Name:    fmt.Sprintf("%s-%s-%d", project, name, f.rnd.Intn(10)+1),
+		Image:   img.image,
+		ImageID: "sha256:" + hex.EncodeToString(digest[:]),
+		State:   StateRunning,
+		Created: now.Add(-time.Duration(f.rnd.Int63n(int64(72 * time.Hour)))).Truncate(time.Second),
+		Labels: map[string]string{
+			"com.docker.compose.project": project,
+			"com.docker.compose.service": name,
+		},
+	}
+	c.Status = "Up " + time.Since(c.Created).Truncate(time.Minute).String()
+	if img.port != 0 && f.rnd.Intn(2) == 0 {
+		c.Ports = []Port{{HostIP: "0.0.0.0", HostPort: uint16(32768 + f.rnd.Intn(28000)), ContainerPort: img.port, Protocol: "tcp"}}
+	}
+	return c
+}
+
+// tick advances the synthetic inventory of a node: about 5% of the running
+// containers exit and are replaced by new ones.
+func (f *fakeDiscovery) tick(node string, size int, now time.Time, retention time.Duration) []Container {
+	containers, ok := f.inventories[node]
+	if !ok {
+		for i := 0; i < size; i++ {
+			containers = append(containers, f.newContainer(now))
+		}
+	}
+	for i := range containers {
+		c := &containers[i]
+		if c.State != StateRunning || f.rnd.Intn(20) != 0 {
+			continue
+		}
+		finished := now
+		c.State = StateExited
+		c.Status = fmt.Sprintf("Exited (%d) 1 second ago", f.rnd.Intn(2)*137)
+		c.FinishedAt = &finished
+		c.Ports = nil
+		containers = append(containers, f.newContainer(now))
+	}
+
+	if f.exitedSince[node] == nil {
+		f.exitedSince[node] = map[string]time.Time{}
+	}
+	containers = retainExited(f.exitedSince[node], containers, now, retention)
+	f.inventories[node] = containers
+	return containers
+}
+
+// runFakeDiscovery periodically broadcasts the synthetic nodes in place of
+// the local sources.
+func (m *Manager) runFakeDiscovery(ctx context.Context, f *fakeDiscovery) {
+	for {
+		now := time.Now().UTC()
+		for i := 0; i < f.nodes; i++ {
+			node := f.nodeName(i)
+			size := f.containers / f.nodes
+			if i < f.containers%f.nodes {
+				size++
+			}
+			containers := append([]Container{}, f.tick(node, size, now, m.cfg.ExitedRetention)...)
+			if b, err := m.inventory.Set(&NodeInventory{Node: node, Timestamp: now, Containers: containers}); err == nil {
+				m.channel.Broadcast(b)
+			}
+			facts := &NodeFacts{
+				Node:           node,
+				Timestamp:      now,
+				Peer:           m.peer.Name(),
+				OS:             "Fake Linux",
+				Kernel:         "6.1.0-fake",
+				Architecture:   "x86_64",
+				Runtime:        "docker",
+				RuntimeVersion: "25.0.3",
+				CgroupVersion:  "2",
+				Zone:           fakeZones[i%len(fakeZones)],
+				InventoryProto: true,
+			}
+			if b, err := m.facts.Set(facts); err == nil {
+				m.factsChannel.Broadcast(b)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.cfg.CollectInterval):
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package containerslist
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFakeDiscovery(t *testing.T) {
+	f, err := parseFakeDiscovery("nodes:5, containers:200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.nodes != 5 || f.containers != 200 {
+		t.Errorf("fake discovery = %d nodes, %d containers, want 5 and 200", f.nodes, f.containers)
+	}
+	if f, err := parseFakeDiscovery("containers:10"); err != nil || f.nodes != 3 {
+		t.Errorf("fake discovery = %+v, %v, want 3 nodes by default", f, err)
+	}
+	for spec, wantErr := range map[string]string{
+		"nodes:0":      "at least one node",
+		"nodes:-1":     "must be nodes:N,containers:M",
+		"nodes=5":      "must be nodes:N,containers:M",
+		"pods:5":       "unknown key",
+		"containers:x": "must be nodes:N,containers:M",
+	} {
+		if _, err := parseFakeDiscovery(spec); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parseFakeDiscovery(%q) = %v, want an error containing %q", spec, err, wantErr)
+		}
+	}
+}
+
+func TestFakeDiscoveryTick(t *testing.T) {
+	f, err := parseFakeDiscovery("nodes:1,containers:100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	var containers []Container
+	for i := 0; i < 20; i++ {
+		containers = f.tick("fake-01", 100, now.Add(time.Duration(i)*time.Second), time.Hour)
+		if got := countRunning(containers); got != 100 {
+			t.Fatalf("tick %d: %d running containers, want 100", i, got)
+		}
+	}
+	// With a 5% churn, some containers exited in 20 ticks.
+	if len(containers) == 100 {
+		t.Error("no container exited")
+	}
+	for _, c := range containers {
+		if c.State == StateExited && (c.FinishedAt == nil || c.Ports != nil) {
+			t.Errorf("exited container %+v, want finished without ports", c)
+		}
+		if c.ID == "" || c.Image == "" || c.Labels[labelComposeProject] == "" {
+			t.Errorf("container %+v, want an ID, an image and a project", c)
+		}
+	}
+
+	// The exited containers are dropped after the retention.
+	containers = f.tick("fake-01", 100, now.Add(2*time.Hour), time.Hour)
+	for _, c := range containers {
+		if c.State == StateExited && c.FinishedAt.Before(now.Add(time.Hour)) {
+			t.Errorf("container %s exited at %v still listed", c.ID, c.FinishedAt)
+		}
+	}
+}
+
+func TestRunFakeDiscovery(t *testing.T) {
+	m := newTestDecommissionManager(t)
+	m.facts = newNodeState[*NodeFacts]()
+	m.factsChannel = &testChannel{}
+	f, err := parseFakeDiscovery("nodes:3,containers:200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.runFakeDiscovery(ctx, f)
+
+	var sizes []int
+	for _, n := range m.inventory.Nodes() {
+		sizes = append(sizes, countRunning(n.Containers))
+	}
+	if len(sizes) != 3 || sizes[0] != 67 || sizes[1] != 67 || sizes[2] != 66 {
+		t.Errorf("running containers per node = %v, want 67, 67 and 66", sizes)
+	}
+	if facts := m.facts.Nodes(); len(facts) != 3 || facts[0].Zone != "zone-a" || facts[1].Zone != "zone-b" {
+		t.Errorf("facts = %+v, want the zones spread", facts)
+	}
+	if len(m.channel.(*testChannel).msgs) != 3 || len(m.factsChannel.(*testChannel).msgs) != 3 {
+		t.Error("fake nodes not broadcast")
+	}
+}
+
+// countRunning returns the number of running containers.
+func countRunning(containers []Container) int {
+	n := 0
+	for _, c := range containers {
+		if c.State == StateRunning {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,128 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// fieldMask is the sparse fieldset requested with the fields query
+// parameter of the list endpoints, such as ?fields=name,image,state, so that
+// frequent pollers do not pay for the heavy fields of the items.
+type fieldMask map[string]bool
+
+// jsonFields returns the names of the JSON fields of a struct type.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// fieldsParam documents the fields query parameter of a list endpoint whose
+// items, described by name, are of the type of item.
+func fieldsParam(name string, item interface{}) apiParam {
+	return apiParam{
+		Name:        "fields",
+		Description: "Only return these fields of the " + name + ", comma-separated; all of them by default",
+		Enum:        jsonFields(reflect.TypeOf(item)),
+		Multiple:    true,
+	}
+}
+
+// parseFieldMask parses the fields query parameter, validating the names
+// against the JSON fields of item. It returns nil when all the fields are
+// requested.
+func parseFieldMask(r *http.Request, item interface{}) (fieldMask, error) {
+	values := r.URL.Query()["fields"]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	known := jsonFields(reflect.TypeOf(item))
+	mask := fieldMask{}
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !contains(known, name) {
+				return nil, fmt.Errorf("invalid field %q: must be one of %s", name, strings.Join(known, ", "))
+			}
+			mask[name] = true
+		}
+	}
+	return mask, nil
+}
+
+// apply returns the JSON objects of a list of items, only holding the fields
+// of the mask.
+func (f fieldMask) apply(items interface{}) ([]map[string]json.RawMessage, error) {
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	objects := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		for name := range o {
+			if !f[name] {
+				delete(o, name)
+			}
+		}
+	}
+	return objects, nil
+}
+
+// applyContainers returns the JSON objects of the inventories of nodes,
+// whose containers only hold the fields of the mask.
+func (f fieldMask) applyContainers(nodes []*NodeInventory) ([]map[string]json.RawMessage, error) {
+	res := make([]map[string]json.RawMessage, 0, len(nodes))
+	for _, n := range nodes {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return nil, err
+		}
+		var o map[string]json.RawMessage
+		if err := json.Unmarshal(b, &o); err != nil {
+			return nil, err
+		}
+		containers, err := f.apply(n.Containers)
+		if err != nil {
+			return nil, err
+		}
+		if o["containers"], err = json.Marshal(containers); err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, nil
+}
+
+// writeMaskedJSON writes a list of items as JSON, only holding the fields of
+// the mask when it is not nil.
+func writeMaskedJSON(w http.ResponseWriter, r *http.Request, items interface{}, mask fieldMask) {
+	if mask == nil {
+		writeJSON(w, items)
+		return
+	}
+	objects, err := mask.apply(items)
+	if err != nil {
+		writeProblem(w, r, problemf(ErrInternal, "unable to select fields: %v", err))
+		return
+	}
+	writeJSON(w, objects)
+}
@@ -0,0 +1,70 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseFieldMask(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  fieldMask
+		err   bool
+	}{
+		{query: ""},
+		{query: "fields=name,image&fields=+state,", want: fieldMask{"name": true, "image": true, "state": true}},
+		{query: "fields=name,env", err: true},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/containers?"+tc.query, nil)
+		got, err := parseFieldMask(r, Container{})
+		if (err != nil) != tc.err {
+			t.Errorf("%s: error = %v, want an error: %v", tc.query, err, tc.err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: mask = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestAPIContainersFields(t *testing.T) {
+	m := newTestInventoryManager(t, &NodeInventory{Node: "a", Containers: []Container{
+		{ID: "a1", Name: "web", Image: "nginx", State: "running", Labels: map[string]string{"team": "shop"}},
+	}})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.apiContainers().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers?"+query, nil))
+		return rec
+	}
+
+	var nodes []struct {
+		Node       string                       `json:"node"`
+		Containers []map[string]json.RawMessage `json:"containers"`
+	}
+	if err := json.NewDecoder(get("fields=name,state").Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Node != "a" || len(nodes[0].Containers) != 1 {
+		t.Fatalf("nodes = %+v, want a with its container", nodes)
+	}
+	var fields []string
+	for name := range nodes[0].Containers[0] {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	if want := []string{"name", "state"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %q, want %q", fields, want)
+	}
+
+	for query, code := range map[string]int{
+		"fields=env":                http.StatusBadRequest,
+		"fields=name&format=stable": http.StatusBadRequest,
+	} {
+		if rec := get(query); rec.Code != code {
+			t.Errorf("status of %s = %d, want %d", query, rec.Code, code)
+		}
+	}
+}
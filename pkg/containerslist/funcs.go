@@ -0,0 +1,167 @@
+package containerslist
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// templateFuncs are the functions available to the UI templates and to the
+// custom columns.
+var templateFuncs = template.FuncMap{
+	"megabytes":     func(b int64) float64 { return float64(b) / 1e6 },
+	"gigabytes":     func(b int64) float64 { return float64(b) / 1e9 },
+	"humanizeBytes": humanizeBytes,
+	"since":         since,
+	"until":         until,
+	"truncateID":    truncateID,
+	"labelGet":      labelGet,
+	// t and lang are bound to the language of each request by localize.
+	"t":    func(msg string, args ...interface{}) string { return translate(language.English, msg, args...) },
+	"lang": func() string { return language.English.String() },
+}
+
+// humanizeBytes formats a size in bytes with a decimal unit, such as "1.2 GB".
+func humanizeBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "kMGTPE"[exp])
+}
+
+// since formats the time elapsed since t, rounded to its largest unit, such
+// as "3d" or "5m".
+func since(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return shortDuration(time.Since(t))
+}
+
+// until formats the time remaining until t, as since.
+func until(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return shortDuration(time.Until(t))
+}
+
+// shortDuration formats a duration in its largest unit: days, hours,
+// minutes or seconds.
+func shortDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// truncateID shortens a container or image ID to 12 characters, dropping its
+// digest algorithm.
+func truncateID(id string) string {
+	if _, hex, ok := strings.Cut(id, ":"); ok {
+		id = hex
+	}
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// labelGet returns the value of a label, or an empty string.
+func labelGet(labels map[string]string, key string) string {
+	return labels[key]
+}
+
+// uiColumn is an extra column of the containers table, configured by the
+// operator as a template executed against each container.
+type uiColumn struct {
+	Header string
+	tpl    *texttemplate.Template
+}
+
+// uiColumns are the extra columns of the containers table.
+type uiColumns []uiColumn
+
+// String implements flag.Value.
+func (cs *uiColumns) String() string {
+	headers := make([]string, 0, len(*cs))
+	for _, c := range *cs {
+		headers = append(headers, c.Header)
+	}
+	return strings.Join(headers, ",")
+}
+
+// Set implements flag.Value, parsing a Header=template column.
+func (cs *uiColumns) Set(spec string) error {
+	header, text, ok := strings.Cut(spec, "=")
+	if !ok || header == "" {
+		return fmt.Errorf("invalid column %q: must be Header=template", spec)
+	}
+	tpl, err := texttemplate.New(header).Funcs(texttemplate.FuncMap(templateFuncs)).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid column %q: %w", header, err)
+	}
+	*cs = append(*cs, uiColumn{Header: header, tpl: tpl})
+	return nil
+}
+
+// Render returns the values of the columns for a container.
+func (cs uiColumns) Render(c Container) []string {
+	values := make([]string, 0, len(cs))
+	for _, col := range cs {
+		var b bytes.Buffer
+		if err := col.tpl.Execute(&b, c); err != nil {
+			values = append(values, "error: "+err.Error())
+			continue
+		}
+		values = append(values, b.String())
+	}
+	return values
+}
+
+// Select returns the columns of the columns parameter of a query, as
+// comma-separated or repeated headers, or all the columns without it.
+func (cs uiColumns) Select(q url.Values) uiColumns {
+	if !q.Has("columns") {
+		return cs
+	}
+	var headers []string
+	for _, v := range q["columns"] {
+		headers = append(headers, strings.Split(v, ",")...)
+	}
+	selected := uiColumns{}
+	for _, c := range cs {
+		if contains(headers, c.Header) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// Has reports whether there is a column with a header.
+func (cs uiColumns) Has(header string) bool {
+	for _, c := range cs {
+		if c.Header == header {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package containerslist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	for _, tc := range []struct {
+		b    int64
+		want string
+	}{
+		{b: 0, want: "0 B"},
+		{b: 999, want: "999 B"},
+		{b: 1000, want: "1.0 kB"},
+		{b: 1234567, want: "1.2 MB"},
+		{b: 5e12, want: "5.0 TB"},
+	} {
+		if got := humanizeBytes(tc.b); got != tc.want {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", tc.b, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 30 * time.Second, want: "30s"},
+		{d: 5*time.Minute + 10*time.Second, want: "5m"},
+		{d: 2*time.Hour + 59*time.Minute, want: "2h"},
+		{d: 73 * time.Hour, want: "3d"},
+	} {
+		if got := shortDuration(tc.d); got != tc.want {
+			t.Errorf("shortDuration(%v) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+	if got := since(time.Now().Add(-90 * time.Minute)); got != "1h" {
+		t.Errorf("since = %q, want 1h", got)
+	}
+	if got := since(time.Time{}); got != "" {
+		t.Errorf("since of the zero time = %q, want none", got)
+	}
+
+	for id, want := range map[string]string{
+		"sha256:0123456789abcdef0123": "0123456789ab",
+		"0123456789abcdef":            "0123456789ab",
+		"abc":                         "abc",
+	} {
+		if got := truncateID(id); got != want {
+			t.Errorf("truncateID(%q) = %q, want %q", id, got, want)
+		}
+	}
+	if got := labelGet(nil, "app"); got != "" {
+		t.Errorf("labelGet of no labels = %q, want none", got)
+	}
+}
+
+func TestUIColumns(t *testing.T) {
+	var cs uiColumns
+	for _, spec := range []string{
+		`Team={{ labelGet .Labels "team" }}`,
+		`ID={{ truncateID .ID }}`,
+		`Missing={{ .Pod.Name }}`,
+	} {
+		if err := cs.Set(spec); err != nil {
+			t.Fatalf("Set(%q): %v", spec, err)
+		}
+	}
+	if got := cs.String(); got != "Team,ID,Missing" {
+		t.Errorf("String = %q, want Team,ID,Missing", got)
+	}
+
+	got := cs.Render(Container{ID: "sha256:0123456789abcdef", Labels: map[string]string{"team": "shop"}})
+	if len(got) != 3 || got[0] != "shop" || got[1] != "0123456789ab" || !strings.HasPrefix(got[2], "error: ") {
+		t.Errorf("Render = %q, want shop, the short ID and an error", got)
+	}
+
+	for _, tc := range []struct {
+		spec, wantErr string
+	}{
+		{spec: "{{ .ID }}", wantErr: "must be Header=template"},
+		{spec: "=.ID", wantErr: "must be Header=template"},
+		{spec: "Bad={{ unknown .ID }}", wantErr: `invalid column "Bad"`},
+	} {
+		if err := cs.Set(tc.spec); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("Set(%q) = %v, want an error containing %q", tc.spec, err, tc.wantErr)
+		}
+	}
+	if len(cs) != 3 {
+		t.Errorf("%d columns after invalid ones, want 3", len(cs))
+	}
+
+	var headers []string
+	for _, c := range cs.Select(map[string][]string{"columns": {"ID,Team"}}) {
+		headers = append(headers, c.Header)
+	}
+	if want := []string{"Team", "ID"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("selected columns = %q, want %q", headers, want)
+	}
+}
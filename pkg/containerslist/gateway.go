@@ -0,0 +1,207 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log/level"
+)
+
+// Headers of the responses gathered from peers in gateway mode.
+const (
+	partialHeader     = "X-Containerslist-Partial"
+	failedPeersHeader = "X-Containerslist-Failed-Peers"
+)
+
+// isLocal reports whether a node is collected by the local node.
+func (m *Manager) isLocal(node string) bool {
+	if _, ok := m.source(node); ok {
+		return true
+	}
+	for _, s := range m.sshSources {
+		if s.name == node {
+			return true
+		}
+	}
+	return false
+}
+
+// gatewayTargets returns the internal API addresses of the other peers, with
+// the scope of their query: local, for the nodes of the peer. With shards and
+// zones, the nodes of a peer of another zone are rather queried from owners
+// of their shards in the local zone, with the owned scope, when they all have
+// one, sparing the cross-zone traffic. The peers covered by each of these
+// owners are returned, to query them if the owner does not answer.
+func (m *Manager) gatewayTargets() (targets map[string]string, covered map[string][]string) {
+	targets, covered = map[string]string{}, map[string][]string{}
+	// The addresses of the peers of the local zone, by name, and the nodes
+	// of the peers of other zones, by address.
+	sameZone := map[string]string{}
+	crossZone := map[string][]string{}
+	for _, f := range m.facts.Nodes() {
+		if f.InternalAddress == "" || f.Passive || f.InternalAddress == m.internalAddress {
+			continue
+		}
+		if m.shards == nil || m.cfg.Zone == "" || f.Zone == "" || f.Zone == m.cfg.Zone {
+			targets[f.InternalAddress] = "local"
+			if f.Peer != "" && f.Zone == m.cfg.Zone {
+				sameZone[f.Peer] = f.InternalAddress
+			}
+			continue
+		}
+		crossZone[f.InternalAddress] = append(crossZone[f.InternalAddress], f.Node)
+	}
+
+	for addr, nodes := range crossZone {
+		var owners []string
+		for _, node := range nodes {
+			owner := ""
+			for _, o := range m.shards.Owners(m.shards.Shard(node)) {
+				if a, ok := sameZone[o]; ok {
+					owner = a
+					break
+				}
+			}
+			if owner == "" {
+				owners = nil
+				break
+			}
+			if !contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+		if len(owners) == 0 {
+			targets[addr] = "local"
+			continue
+		}
+		for _, owner := range owners {
+			targets[owner] = "owned"
+			covered[owner] = append(covered[owner], addr)
+		}
+	}
+	for addr, scope := range targets {
+		if scope == "local" {
+			delete(covered, addr)
+		}
+	}
+	return targets, covered
+}
+
+// gatherContainers queries the nodes of every peer in parallel, each with a
+// timeout, returning the inventories of the peers which answered and the
+// addresses of those which did not.
+func (m *Manager) gatherContainers(ctx context.Context, query url.Values, local []*NodeInventory) ([]*NodeInventory, []string) {
+	targets, covered := m.gatewayTargets()
+	nodes, failed := m.queryPeers(ctx, query, targets)
+	fallback := map[string]string{}
+	for _, addr := range failed {
+		for _, peer := range covered[addr] {
+			if _, ok := targets[peer]; !ok {
+				fallback[peer] = "local"
+			}
+		}
+	}
+	if len(fallback) > 0 {
+		fallbackNodes, fallbackFailed := m.queryPeers(ctx, query, fallback)
+		nodes = append(nodes, fallbackNodes...)
+		failed = append(failed, fallbackFailed...)
+	}
+
+	// The owners of shards also answer with the nodes of other peers: the
+	// latest inventory of each node is kept.
+	latest := map[string]*NodeInventory{}
+	for _, n := range append(local, nodes...) {
+		if prev, ok := latest[n.Node]; !ok || n.Timestamp.After(prev.Timestamp) {
+			latest[n.Node] = n
+		}
+	}
+	nodes = make([]*NodeInventory, 0, len(latest))
+	for _, n := range latest {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+	sort.Strings(failed)
+	return nodes, failed
+}
+
+// queryPeers queries the internal API of peers in parallel, each with its
+// scope, returning the inventories they answered and the addresses of those
+// which did not.
+func (m *Manager) queryPeers(ctx context.Context, query url.Values, targets map[string]string) ([]*NodeInventory, []string) {
+	var (
+		mtx    sync.Mutex
+		wg     sync.WaitGroup
+		nodes  []*NodeInventory
+		failed []string
+	)
+	client := &http.Client{Transport: m.router.transport, Timeout: m.cfg.GatewayTimeout}
+	for addr, scope := range targets {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("scope", scope)
+		q.Del("format")
+
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			var peerNodes []*NodeInventory
+			err := m.breakers.Get("peer:"+addr).Do(ctx, func() (err error) {
+				peerNodes, err = m.queryPeer(ctx, client, addr, q)
+				return err
+			})
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				m.gatewayFailures.Inc()
+				level.Warn(m.requestLogger(ctx)).Log("msg", "Unable to query peer", "peer", addr, "error", err)
+				failed = append(failed, addr)
+				return
+			}
+			nodes = append(nodes, peerNodes...)
+		}(addr)
+	}
+	wg.Wait()
+	return nodes, failed
+}
+
+func (m *Manager) queryPeer(ctx context.Context, client *http.Client, addr string, q url.Values) ([]*NodeInventory, error) {
+	u := url.URL{Scheme: "https", Host: addr, Path: "/api/v1/containers", RawQuery: q.Encode()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(forwardedHeader, m.nodeID)
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var nodes []*NodeInventory
+	return nodes, json.NewDecoder(resp.Body).Decode(&nodes)
+}
+
+// setPartial flags a response gathered from peers as partial, listing the
+// peers which did not answer.
+func setPartial(w http.ResponseWriter, failed []string) {
+	if len(failed) > 0 {
+		w.Header().Set(partialHeader, "true")
+		w.Header().Set(failedPeersHeader, strings.Join(failed, ","))
+	}
+}
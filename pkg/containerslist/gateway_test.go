@@ -0,0 +1,68 @@
+package containerslist
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGatherContainers(t *testing.T) {
+	a := &testZonePeer{name: "a", nodes: []string{"node-a"}}
+	b := &testZonePeer{name: "b", nodes: []string{"node-b"}}
+	a.start(t, map[string][]string{"local": {"node-a", "node-shared"}})
+	b.start(t, map[string][]string{"local": {"node-b"}})
+	m := newTestZoneManager(t, "", 1, nil, a, b)
+	// Passive nodes and the local node are not queried.
+	for _, f := range []*NodeFacts{
+		{Node: "passive", InternalAddress: "127.0.0.1:2", Passive: true, Timestamp: time.Now()},
+		{Node: "self", InternalAddress: m.internalAddress, Timestamp: time.Now()},
+	} {
+		if _, err := m.facts.Set(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	local := []*NodeInventory{{Node: "node-shared", Timestamp: time.Now().Add(-time.Minute)}}
+	nodes, failed := m.gatherContainers(context.Background(), url.Values{"format": {"stable"}, "state": {"running"}}, local)
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Node)
+	}
+	if want := []string{"node-a", "node-b", "node-shared"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+	if nodes[2].Timestamp.Before(time.Now().Add(-time.Second)) {
+		t.Error("node-shared is the local inventory, want the latest one of a")
+	}
+	if len(failed) != 0 {
+		t.Errorf("failed = %v, want none", failed)
+	}
+	if !reflect.DeepEqual(a.scopes, []string{"local"}) || !reflect.DeepEqual(b.scopes, []string{"local"}) {
+		t.Errorf("scopes = %v and %v, want local", a.scopes, b.scopes)
+	}
+
+	b.srv.Close()
+	nodes, failed = m.gatherContainers(context.Background(), url.Values{}, nil)
+	if len(nodes) != 2 || !reflect.DeepEqual(failed, []string{b.addr()}) {
+		t.Errorf("nodes = %d, failed = %v, want the nodes of a and b failed", len(nodes), failed)
+	}
+	if got := testutil.ToFloat64(m.gatewayFailures); got != 1 {
+		t.Errorf("gateway failures = %v, want 1", got)
+	}
+
+	w := httptest.NewRecorder()
+	setPartial(w, failed)
+	if w.Header().Get(partialHeader) != "true" || w.Header().Get(failedPeersHeader) != b.addr() {
+		t.Errorf("headers = %v, want a partial response", w.Header())
+	}
+	w = httptest.NewRecorder()
+	setPartial(w, nil)
+	if len(w.Header()) != 0 {
+		t.Errorf("headers = %v, want none for a complete response", w.Header())
+	}
+}
@@ -0,0 +1,138 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gceMetadataURL = "http://metadata.google.internal/computeMetadata/v1/"
+
+// gceMetadata returns a value of the metadata server of the instance.
+func gceMetadata(ctx context.Context, client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gceMetadataURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return b, nil
+}
+
+// gceToken is the OAuth2 access token of the service account of the
+// instance, from the metadata server, cached until it expires.
+type gceToken struct {
+	mtx     sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (t *gceToken) get(ctx context.Context, client *http.Client) (string, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.token != "" && time.Until(t.expires) > time.Minute {
+		return t.token, nil
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	b, err := gceMetadata(ctx, client, "instance/service-accounts/default/token")
+	if err == nil {
+		err = json.Unmarshal(b, &res)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get access token: %w", err)
+	}
+	t.token, t.expires = res.AccessToken, time.Now().Add(time.Duration(res.ExpiresIn)*time.Second)
+	return t.token, nil
+}
+
+// gceDiscoverer finds the running GCE instances with a label, in all the
+// zones of a project, such as the instances of a managed instance group
+// created from a labelled template. Its credentials are the ones of the
+// service account of the instance.
+type gceDiscoverer struct {
+	project string
+	filter  string
+	client  *http.Client
+	token   gceToken
+}
+
+func newGCEDiscoverer(cfg *Config) (peerDiscoverer, error) {
+	key, value, ok := strings.Cut(cfg.DiscoveryGCELabel, "=")
+	if !ok || key == "" {
+		return nil, fmt.Errorf("invalid -ha_discovery_gce_label %q: must be key=value", cfg.DiscoveryGCELabel)
+	}
+	return &gceDiscoverer{
+		project: cfg.DiscoveryGCEProject,
+		filter:  fmt.Sprintf("(labels.%s = %q) AND (status = RUNNING)", key, value),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Peers returns the internal addresses of the matching instances.
+func (d *gceDiscoverer) Peers(ctx context.Context) ([]string, error) {
+	if d.project == "" {
+		b, err := gceMetadata(ctx, d.client, "project/project-id")
+		if err != nil {
+			return nil, fmt.Errorf("unable to get project: %w", err)
+		}
+		d.project = strings.TrimSpace(string(b))
+	}
+	token, err := d.token.get(ctx, d.client)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"filter": {d.filter}}
+	var addrs []string
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://compute.googleapis.com/compute/v1/projects/"+url.PathEscape(d.project)+"/aggregated/instances?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		var res struct {
+			Items map[string]struct {
+				Instances []struct {
+					NetworkInterfaces []struct {
+						NetworkIP string `json:"networkIP"`
+					} `json:"networkInterfaces"`
+				} `json:"instances"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := doSinkRequest(d.client, req, &res); err != nil {
+			return nil, fmt.Errorf("unable to list instances: %w", err)
+		}
+		for _, zone := range res.Items {
+			for _, instance := range zone.Instances {
+				if len(instance.NetworkInterfaces) > 0 && instance.NetworkInterfaces[0].NetworkIP != "" {
+					addrs = append(addrs, instance.NetworkInterfaces[0].NetworkIP)
+				}
+			}
+		}
+		if res.NextPageToken == "" {
+			return addrs, nil
+		}
+		q.Set("pageToken", res.NextPageToken)
+	}
+}
@@ -0,0 +1,110 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// redirectTransport sends all the requests to a test server, keeping their
+// original host in the X-Original-Host header.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Original-Host", req.URL.Host)
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// redirectedClient returns a client of which all the requests go to srv.
+func redirectedClient(t *testing.T, srv *httptest.Server) *http.Client {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: redirectTransport{target: u}}
+}
+
+func TestNewGCEDiscoverer(t *testing.T) {
+	for _, label := range []string{"", "role", "=containerslist"} {
+		cfg := DefaultConfig()
+		cfg.DiscoveryGCELabel = label
+		if _, err := newGCEDiscoverer(cfg); err == nil {
+			t.Errorf("label %q accepted, want an error", label)
+		}
+	}
+	cfg := DefaultConfig()
+	cfg.DiscoveryGCELabel = "role=containerslist"
+	d, err := newGCEDiscoverer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.(*gceDiscoverer).filter, `(labels.role = "containerslist") AND (status = RUNNING)`; got != want {
+		t.Errorf("filter = %q, want %q", got, want)
+	}
+}
+
+func TestGCEDiscovererPeers(t *testing.T) {
+	var tokens int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Original-Host") + r.URL.Path {
+		case "metadata.google.internal/computeMetadata/v1/project/project-id":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				http.Error(w, "missing flavor", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("fleet\n"))
+		case "metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token":
+			tokens++
+			w.Write([]byte(`{"access_token":"t0k3n","expires_in":3600}`))
+		case "compute.googleapis.com/compute/v1/projects/fleet/aggregated/instances":
+			if r.Header.Get("Authorization") != "Bearer t0k3n" || r.URL.Query().Get("filter") != `(labels.role = "containerslist") AND (status = RUNNING)` {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"items":{
+					"zones/europe-west1-b":{"instances":[{"networkInterfaces":[{"networkIP":"10.0.0.1"},{"networkIP":"10.1.0.1"}]},{"networkInterfaces":[]}]},
+					"zones/europe-west1-c":{}
+				},"nextPageToken":"page-2"}`))
+				return
+			}
+			w.Write([]byte(`{"items":{"zones/europe-west1-c":{"instances":[{"networkInterfaces":[{"networkIP":"10.0.0.2"}]}]}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.DiscoveryGCELabel = "role=containerslist"
+	d, err := newGCEDiscoverer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.(*gceDiscoverer).client = redirectedClient(t, srv)
+	for i := 0; i < 2; i++ {
+		addrs, err := d.Peers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(addrs)
+		if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(addrs, want) {
+			t.Errorf("peers = %q, want %q", addrs, want)
+		}
+	}
+	if d.(*gceDiscoverer).project != "fleet" {
+		t.Errorf("project = %q, want the one of the instance", d.(*gceDiscoverer).project)
+	}
+	if tokens != 1 {
+		t.Errorf("%d tokens requested, want the first one cached", tokens)
+	}
+}
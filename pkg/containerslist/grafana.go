@@ -0,0 +1,90 @@
+package containerslist
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// grafanaFS holds the Grafana dashboards of the metrics of the nodes. Their
+// queries select a Prometheus data source and a job with variables.
+//
+//go:embed grafana/*.json
+var grafanaFS embed.FS
+
+// GrafanaDashboard describes a dashboard served by the API.
+type GrafanaDashboard struct {
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	// URL is the path of its JSON model.
+	URL string `json:"url"`
+}
+
+// grafanaDashboards returns the JSON models of the dashboards, by UID.
+func grafanaDashboards() (map[string]json.RawMessage, error) {
+	files, err := grafanaFS.ReadDir("grafana")
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]json.RawMessage{}
+	for _, f := range files {
+		b, err := grafanaFS.ReadFile(path.Join("grafana", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var d struct {
+			UID string `json:"uid"`
+		}
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, err
+		}
+		res[d.UID] = b
+	}
+	return res, nil
+}
+
+// apiGrafanaDashboards lists the dashboards, at /api/v1/grafana/dashboards,
+// and serves the JSON model of each one at
+// /api/v1/grafana/dashboards/{uid}, as expected by the file provisioning of
+// Grafana. With ?import=true, the model is wrapped in the body of the
+// dashboard import API of Grafana, overwriting the previous version.
+func apiGrafanaDashboards() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dashboards, err := grafanaDashboards()
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to load dashboards: %v", err))
+			return
+		}
+		uid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/grafana/dashboards"), "/")
+		if uid == "" {
+			list := []GrafanaDashboard{}
+			for uid, b := range dashboards {
+				d := GrafanaDashboard{URL: "/api/v1/grafana/dashboards/" + uid}
+				if err := json.Unmarshal(b, &d); err != nil {
+					writeProblem(w, r, problemf(ErrInternal, "invalid dashboard %s: %v", uid, err))
+					return
+				}
+				list = append(list, d)
+			}
+			sort.Slice(list, func(i, j int) bool { return list[i].UID < list[j].UID })
+			writeJSON(w, list)
+			return
+		}
+		b, ok := dashboards[uid]
+		if !ok {
+			writeProblem(w, r, problemf(ErrNotFound, "no dashboard %q", uid))
+			return
+		}
+		if r.URL.Query().Get("import") == "true" {
+			writeJSON(w, map[string]interface{}{"dashboard": b, "overwrite": true})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
@@ -0,0 +1,85 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAPIGrafanaDashboards(t *testing.T) {
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		apiGrafanaDashboards().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	var list []GrafanaDashboard
+	if err := json.NewDecoder(get("/api/v1/grafana/dashboards").Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	var uids []string
+	for _, d := range list {
+		uids = append(uids, d.UID)
+		if d.Title == "" || d.URL != "/api/v1/grafana/dashboards/"+d.UID || !reflect.DeepEqual(d.Tags, []string{"containerslist"}) {
+			t.Errorf("dashboard = %+v", d)
+		}
+	}
+	if want := []string{"containerslist-cluster-health", "containerslist-http-performance", "containerslist-inventory-churn"}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("dashboards = %q, want %q", uids, want)
+	}
+
+	// Each model selects the data source and the job with variables.
+	for _, uid := range uids {
+		var model struct {
+			UID        string `json:"uid"`
+			Templating struct {
+				List []struct {
+					Name string `json:"name"`
+				} `json:"list"`
+			} `json:"templating"`
+		}
+		rec := get("/api/v1/grafana/dashboards/" + uid)
+		if err := json.NewDecoder(rec.Body).Decode(&model); err != nil {
+			t.Fatal(err)
+		}
+		var vars []string
+		for _, v := range model.Templating.List {
+			vars = append(vars, v.Name)
+		}
+		if model.UID != uid || !reflect.DeepEqual(vars, []string{"datasource", "job"}) {
+			t.Errorf("model of %s has UID %s and variables %q", uid, model.UID, vars)
+		}
+	}
+
+	var body struct {
+		Dashboard struct {
+			UID string `json:"uid"`
+		} `json:"dashboard"`
+		Overwrite bool `json:"overwrite"`
+	}
+	if err := json.NewDecoder(get("/api/v1/grafana/dashboards/containerslist-cluster-health?import=true").Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Dashboard.UID != "containerslist-cluster-health" || !body.Overwrite {
+		t.Errorf("import body = %+v, want the overwriting import of the dashboard", body)
+	}
+
+	if rec := get("/api/v1/grafana/dashboards/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("status of a missing dashboard = %d, want 404", rec.Code)
+	}
+}
+
+func TestContainerEventsCounter(t *testing.T) {
+	b := newEventBroker()
+	b.Publish([]ContainerEvent{{Type: EventAppeared}, {Type: EventAppeared}, {Type: EventDisappeared}})
+	if got := testutil.ToFloat64(b.published.WithLabelValues(EventAppeared)); got != 2 {
+		t.Errorf("appeared events = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(b.published.WithLabelValues(EventDisappeared)); got != 1 {
+		t.Errorf("disappeared events = %v, want 1", got)
+	}
+}
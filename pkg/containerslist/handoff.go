@@ -0,0 +1,88 @@
+package containerslist
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// nodeIdentity returns the persisted identity of a node, by which a node
+// renamed or readdressed is recognized: its fingerprint, or else the ID of
+// its Docker daemon, both of which survive the changes of hostname and of
+// node ID.
+func nodeIdentity(f *NodeFacts) string {
+	if f.Fingerprint != "" {
+		return f.Fingerprint
+	}
+	return f.DaemonID
+}
+
+// handoffJob detects the departed nodes whose identity was taken over by a
+// current member under a new name, and hands their entries off to it: the
+// history of the departed node is merged into the one of the new node and
+// its facts are hidden, and the new node replaces its inventory with a
+// tombstone, so that its containers are not listed twice. Nodes sharing an
+// identity while both are members, such as cloned hosts, are left alone.
+func (m *Manager) handoffJob(context.Context) error {
+	members := map[string]bool{}
+	for _, p := range m.peer.Peers() {
+		members[p.Name()] = true
+	}
+	byIdentity := map[string][]*NodeFacts{}
+	for _, f := range m.facts.Nodes() {
+		if id := nodeIdentity(f); id != "" && !f.Passive {
+			byIdentity[id] = append(byIdentity[id], f)
+		}
+	}
+	for _, facts := range byIdentity {
+		if len(facts) < 2 {
+			continue
+		}
+		sort.Slice(facts, func(i, j int) bool { return facts[i].Timestamp.After(facts[j].Timestamp) })
+		to := facts[0]
+		if !members[to.Peer] {
+			continue
+		}
+		for _, from := range facts[1:] {
+			if members[from.Peer] || !m.handoff(from.Node, to.Node) {
+				continue
+			}
+			level.Info(m.logger).Log("msg", "Node renamed, handing its entries off", "from", from.Node, "to", to.Node)
+			m.history.Merge(from.Node, to.Node)
+			if !m.isLocal(to.Node) {
+				continue
+			}
+			if _, ok := m.inventory.Get(from.Node); !ok {
+				continue
+			}
+			b, err := m.inventory.Set(&NodeInventory{Node: from.Node, Timestamp: time.Now().UTC(), Decommissioned: true})
+			if err != nil {
+				return err
+			}
+			m.channel.Broadcast(b)
+		}
+	}
+	return nil
+}
+
+// handoff records that a node was renamed, reporting whether it was not
+// known yet.
+func (m *Manager) handoff(from, to string) bool {
+	m.handoffsMtx.Lock()
+	defer m.handoffsMtx.Unlock()
+	if _, ok := m.handoffs[from]; ok {
+		return false
+	}
+	m.handoffs[from] = to
+	return true
+}
+
+// handedOff reports whether a node was renamed.
+func (m *Manager) handedOff(node string) bool {
+	m.handoffsMtx.Lock()
+	defer m.handoffsMtx.Unlock()
+	_, ok := m.handoffs[node]
+	return ok
+}
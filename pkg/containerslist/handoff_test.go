@@ -0,0 +1,71 @@
+package containerslist
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNodeIdentity(t *testing.T) {
+	for _, tc := range []struct {
+		facts NodeFacts
+		want  string
+	}{
+		{facts: NodeFacts{Fingerprint: "f1", DaemonID: "d1"}, want: "f1"},
+		{facts: NodeFacts{DaemonID: "d1"}, want: "d1"},
+		{facts: NodeFacts{}},
+	} {
+		if got := nodeIdentity(&tc.facts); got != tc.want {
+			t.Errorf("identity of %+v = %q, want %q", tc.facts, got, tc.want)
+		}
+	}
+}
+
+func TestHandoffJob(t *testing.T) {
+	m := newTestDecommissionManager(t, &NodeInventory{Node: "old", Containers: []Container{{ID: "a1"}}}, &NodeInventory{Node: "new"})
+	m.handoffs = map[string]string{}
+	m.sources = []*dockerSource{{node: "new"}}
+	m.history = newHistory(newHistoryTier(tierRaw, time.Minute, time.Hour))
+	start := time.Now().Add(-10 * time.Minute)
+	m.history.Record(start, map[string]int{"old": 2})
+	m.history.Record(start.Add(time.Minute), map[string]int{"new": 3})
+
+	m.facts = newNodeState[*NodeFacts]()
+	self := m.peer.Name()
+	for _, f := range []*NodeFacts{
+		{Node: "old", Peer: "gone", DaemonID: "X", Timestamp: start},
+		{Node: "new", Peer: self, DaemonID: "X", Timestamp: start.Add(time.Minute)},
+		// Cloned hosts are both members.
+		{Node: "c1", Peer: self, DaemonID: "Y", Timestamp: start},
+		{Node: "c2", Peer: self, DaemonID: "Y", Timestamp: start.Add(time.Minute)},
+	} {
+		if _, err := m.facts.Set(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := 0; n < 2; n++ {
+		if err := m.handoffJob(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !m.handedOff("old") || m.handedOff("c1") || m.handedOff("c2") {
+		t.Errorf("handoffs = %v, want old only", m.handoffs)
+	}
+	for _, f := range m.nodeFacts() {
+		if f.Node == "old" {
+			t.Error("facts of old still listed")
+		}
+	}
+	if tombstones := m.inventory.Tombstones(); len(tombstones) != 1 || tombstones[0].Node != "old" || len(tombstones[0].Containers) != 0 {
+		t.Errorf("tombstones = %+v, want the one of old", tombstones)
+	}
+	if ch := m.channel.(*testChannel); len(ch.msgs) != 1 {
+		t.Errorf("%d broadcasts, want 1", len(ch.msgs))
+	}
+
+	h, _ := m.history.Get(tierRaw, []string{"old", "new"})
+	if len(h.Nodes) != 1 || h.Nodes[0].Node != "new" || len(h.Nodes[0].Samples) != 2 || h.Nodes[0].Samples[0].Value != 2 {
+		t.Errorf("history = %+v, want the samples of old merged into new", h.Nodes)
+	}
+}
@@ -0,0 +1,173 @@
+package containerslist
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sources of the local inventory.
+const (
+	SourceDocker  = "docker"
+	SourceKubelet = "kubelet"
+)
+
+// SourceStatus is the health of a source of the local inventory.
+type SourceStatus struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	// Cordoned is set while the polling of the source is suspended by an
+	// operator; the last inventory of its node is kept, and turns stale.
+	Cordoned     bool       `json:"cordoned,omitempty"`
+	CordonReason string     `json:"cordonReason,omitempty"`
+	CordonedAt   *time.Time `json:"cordonedAt,omitempty"`
+}
+
+// sourceHealth tracks the health of the inventory sources.
+type sourceHealth struct {
+	mtx     sync.RWMutex
+	sources map[string]*SourceStatus
+}
+
+func newSourceHealth() *sourceHealth {
+	return &sourceHealth{
+		sources: map[string]*SourceStatus{},
+	}
+}
+
+func (h *sourceHealth) get(name string) *SourceStatus {
+	s, ok := h.sources[name]
+	if !ok {
+		s = &SourceStatus{Name: name}
+		h.sources[name] = s
+	}
+	return s
+}
+
+// Success records a successful call to a source.
+func (h *sourceHealth) Success(name string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	now := time.Now().UTC()
+	s := h.get(name)
+	s.Healthy = true
+	s.LastError = ""
+	s.LastSuccess = &now
+	s.ConsecutiveFailures = 0
+}
+
+// Failure records a failed call to a source, returning the number of
+// consecutive failures.
+func (h *sourceHealth) Failure(name string, err error) int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	s := h.get(name)
+	s.Healthy = false
+	s.LastError = err.Error()
+	s.ConsecutiveFailures++
+	return s.ConsecutiveFailures
+}
+
+// Cordon suspends the polling of a source.
+func (h *sourceHealth) Cordon(name, reason string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	now := time.Now().UTC()
+	s := h.get(name)
+	s.Cordoned = true
+	s.CordonReason = reason
+	s.CordonedAt = &now
+}
+
+// Uncordon resumes the polling of a source.
+func (h *sourceHealth) Uncordon(name string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	s := h.get(name)
+	s.Cordoned = false
+	s.CordonReason = ""
+	s.CordonedAt = nil
+}
+
+// Cordoned reports whether the polling of a source is suspended.
+func (h *sourceHealth) Cordoned(name string) bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	s, ok := h.sources[name]
+	return ok && s.Cordoned
+}
+
+// Statuses returns the status of all sources, sorted by name.
+func (h *sourceHealth) Statuses() []SourceStatus {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	statuses := make([]SourceStatus, 0, len(h.sources))
+	for _, s := range h.sources {
+		statuses = append(statuses, *s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// retryBackoff returns the delay before retrying a source after the given
+// number of consecutive failures: it doubles from one second on, up to the
+// collect interval.
+func retryBackoff(failures int, collectInterval time.Duration) time.Duration {
+	d := time.Second
+	for i := 1; i < failures && d < collectInterval; i++ {
+		d *= 2
+	}
+	if d > collectInterval {
+		d = collectInterval
+	}
+	return d
+}
+
+// Status is the status of the local node.
+type Status struct {
+	Node      string          `json:"node"`
+	Cluster   string          `json:"cluster"`
+	Degraded  bool            `json:"degraded"`
+	Sources   []SourceStatus  `json:"sources"`
+	Partition PartitionStatus `json:"partition"`
+	Breakers  []BreakerStatus `json:"breakers"`
+	Version   string          `json:"version"`
+	// Update is set when the release feed is checked.
+	Update *UpdateStatus `json:"update,omitempty"`
+}
+
+func (m *Manager) apiStatus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := Status{
+			Node:      m.nodeID,
+			Cluster:   m.peer.Status(),
+			Sources:   m.health.Statuses(),
+			Partition: m.partition.Status(),
+			Breakers:  m.breakers.Statuses(),
+			Version:   buildVersion(),
+		}
+		if m.updates != nil {
+			update := m.updates.Status()
+			status.Update = &update
+		}
+		for _, s := range status.Sources {
+			// The cordoned sources are known to be unreliable.
+			if !s.Healthy && !s.Cordoned {
+				status.Degraded = true
+			}
+		}
+		writeJSON(w, status)
+	})
+}
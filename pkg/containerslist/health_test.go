@@ -0,0 +1,81 @@
+package containerslist
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSourceHealth(t *testing.T) {
+	h := newSourceHealth()
+	h.Success(SourceKubelet)
+	for i := 1; i <= 3; i++ {
+		if got := h.Failure(SourceDocker, errors.New("connection refused")); got != i {
+			t.Errorf("failure %d: consecutive failures = %d", i, got)
+		}
+	}
+
+	statuses := h.Statuses()
+	if len(statuses) != 2 || statuses[0].Name != SourceDocker || statuses[1].Name != SourceKubelet {
+		t.Fatalf("statuses = %+v, want docker and kubelet", statuses)
+	}
+	if s := statuses[0]; s.Healthy || s.LastError != "connection refused" || s.ConsecutiveFailures != 3 || s.LastSuccess != nil {
+		t.Errorf("docker = %+v, want unhealthy after 3 failures", s)
+	}
+	if s := statuses[1]; !s.Healthy || s.LastSuccess == nil {
+		t.Errorf("kubelet = %+v, want healthy", s)
+	}
+
+	// A success resets the failures.
+	h.Success(SourceDocker)
+	if s := h.Statuses()[0]; !s.Healthy || s.LastError != "" || s.ConsecutiveFailures != 0 {
+		t.Errorf("docker = %+v, want healthy", s)
+	}
+	if got := h.Failure(SourceDocker, errors.New("timeout")); got != 1 {
+		t.Errorf("consecutive failures = %d, want 1", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 4, want: 8 * time.Second},
+		{failures: 5, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	} {
+		if got := retryBackoff(tc.failures, 10*time.Second); got != tc.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+	if got := retryBackoff(1, 500*time.Millisecond); got != 500*time.Millisecond {
+		t.Errorf("retryBackoff with a short interval = %v, want 500ms", got)
+	}
+}
+
+func TestMarkNodeDegraded(t *testing.T) {
+	ch := &testChannel{}
+	m := newTestInventoryManager(t, &NodeInventory{Node: "a", Timestamp: time.Now().Add(-time.Minute), Containers: []Container{{ID: "a1"}}})
+	m.channel = ch
+
+	if err := m.markNodeDegraded("unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.msgs) != 0 {
+		t.Errorf("%d broadcasts for an unknown node, want none", len(ch.msgs))
+	}
+
+	if err := m.markNodeDegraded("a"); err != nil {
+		t.Fatal(err)
+	}
+	n, ok := m.inventory.Get("a")
+	if !ok || !n.Degraded || len(n.Containers) != 1 || time.Since(n.Timestamp) > time.Second {
+		t.Errorf("inventory = %+v, want the last known containers, degraded and refreshed", n)
+	}
+	if len(ch.msgs) != 1 {
+		t.Errorf("%d broadcasts, want 1", len(ch.msgs))
+	}
+}
@@ -0,0 +1,390 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sample is the value of a series at a point in time.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// ring is a bounded buffer of samples, overwriting the oldest ones once full.
+// It grows as samples are added, up to its size.
+type ring struct {
+	size    int
+	samples []Sample
+	next    int
+}
+
+func newRing(size int) *ring {
+	return &ring{size: size}
+}
+
+// Add appends a sample, evicting the oldest one when the buffer is full.
+func (r *ring) Add(s Sample) {
+	if r.size <= 0 {
+		return
+	}
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, s)
+		return
+	}
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % r.size
+}
+
+// Len returns the number of samples in the buffer.
+func (r *ring) Len() int {
+	return len(r.samples)
+}
+
+// Samples returns the samples, oldest first.
+func (r *ring) Samples() []Sample {
+	return append(append([]Sample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// Series is the history of the running containers of a node, or of the
+// cluster when Node is empty.
+type Series struct {
+	Node    string   `json:"node,omitempty"`
+	Samples []Sample `json:"samples"`
+}
+
+// History is the history of the running containers of the cluster and of
+// each node, at the resolution of a tier.
+type History struct {
+	Tier       string   `json:"tier"`
+	Resolution string   `json:"resolution"`
+	Retention  string   `json:"retention"`
+	Cluster    Series   `json:"cluster"`
+	Nodes      []Series `json:"nodes"`
+}
+
+// Tiers of the history.
+const (
+	tierRaw         = "raw"
+	tierDownsampled = "downsampled"
+)
+
+// historyTier keeps the series at a given resolution for a given retention.
+type historyTier struct {
+	name       string
+	resolution time.Duration
+	retention  time.Duration
+	cluster    *ring
+	nodes      map[string]*ring
+}
+
+func newHistoryTier(name string, resolution, retention time.Duration) *historyTier {
+	return &historyTier{
+		name:       name,
+		resolution: resolution,
+		retention:  retention,
+		cluster:    newRing(int(retention / resolution)),
+		nodes:      map[string]*ring{},
+	}
+}
+
+func (t *historyTier) add(node string, s Sample) {
+	r, ok := t.nodes[node]
+	if !ok {
+		r = newRing(int(t.retention / t.resolution))
+		t.nodes[node] = r
+	}
+	r.Add(s)
+}
+
+// samples returns the number of samples held by the tier.
+func (t *historyTier) samples() int {
+	n := t.cluster.Len()
+	for _, r := range t.nodes {
+		n += r.Len()
+	}
+	return n
+}
+
+// history records the number of running containers at a fixed resolution,
+// and downsamples it for a longer retention. The first tier holds the raw
+// samples.
+type history struct {
+	mtx   sync.RWMutex
+	tiers []*historyTier
+}
+
+func newHistory(tiers ...*historyTier) *history {
+	return &history{tiers: tiers}
+}
+
+// Record adds a raw sample of the running containers of each node.
+func (h *history) Record(now time.Time, running map[string]int) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	raw := h.tiers[0]
+	total := 0
+	for node, n := range running {
+		raw.add(node, Sample{Time: now, Value: float64(n)})
+		total += n
+	}
+	raw.cluster.Add(Sample{Time: now, Value: float64(total)})
+}
+
+// Merge moves the series of a node into the ones of another node, such as
+// when the node was renamed, keeping the most recent samples.
+func (h *history) Merge(from, to string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for _, t := range h.tiers {
+		src, ok := t.nodes[from]
+		if !ok {
+			continue
+		}
+		delete(t.nodes, from)
+		samples := src.Samples()
+		if dst, ok := t.nodes[to]; ok {
+			samples = append(samples, dst.Samples()...)
+		}
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+		r := newRing(int(t.retention / t.resolution))
+		for _, s := range samples {
+			r.Add(s)
+		}
+		t.nodes[to] = r
+	}
+}
+
+// Compact downsamples the raw samples of the last period of a tier into a
+// single sample, averaging them.
+func (h *history) Compact(now time.Time, tier string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	t, ok := h.tier(tier)
+	if !ok {
+		return
+	}
+	raw := h.tiers[0]
+	since := now.Add(-t.resolution)
+	average := func(r *ring) (Sample, bool) {
+		var sum float64
+		var n int
+		for _, s := range r.Samples() {
+			if s.Time.After(since) {
+				sum += s.Value
+				n++
+			}
+		}
+		if n == 0 {
+			return Sample{}, false
+		}
+		return Sample{Time: now, Value: sum / float64(n)}, true
+	}
+	if s, ok := average(raw.cluster); ok {
+		t.cluster.Add(s)
+	}
+	for node, r := range raw.nodes {
+		if s, ok := average(r); ok {
+			t.add(node, s)
+		}
+	}
+}
+
+func (h *history) tier(name string) (*historyTier, bool) {
+	for _, t := range h.tiers {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Get returns the cluster-wide series and the series of the given nodes at
+// the resolution of a tier.
+func (h *history) Get(tier string, nodes []string) (History, bool) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	t, ok := h.tier(tier)
+	if !ok {
+		return History{}, false
+	}
+	res := History{
+		Tier:       t.name,
+		Resolution: t.resolution.String(),
+		Retention:  t.retention.String(),
+		Cluster:    Series{Samples: t.cluster.Samples()},
+		Nodes:      []Series{},
+	}
+	for _, node := range nodes {
+		if r, ok := t.nodes[node]; ok {
+			res.Nodes = append(res.Nodes, Series{Node: node, Samples: r.Samples()})
+		}
+	}
+	return res, true
+}
+
+// historySnapshot is the content of the tiers of a history, by tier name.
+type historySnapshot map[string]tierSnapshot
+
+type tierSnapshot struct {
+	Cluster []Sample            `json:"cluster"`
+	Nodes   map[string][]Sample `json:"nodes"`
+}
+
+// Snapshot returns the samples of all tiers.
+func (h *history) Snapshot() historySnapshot {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	res := historySnapshot{}
+	for _, t := range h.tiers {
+		ts := tierSnapshot{Cluster: t.cluster.Samples(), Nodes: map[string][]Sample{}}
+		for node, r := range t.nodes {
+			ts.Nodes[node] = r.Samples()
+		}
+		res[t.name] = ts
+	}
+	return res
+}
+
+// Restore replaces the samples of the tiers with the ones of a snapshot,
+// keeping the most recent ones when the snapshot holds more than their
+// retention.
+func (h *history) Restore(s historySnapshot) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for _, t := range h.tiers {
+		ts, ok := s[t.name]
+		if !ok {
+			continue
+		}
+		t.cluster = newRing(t.cluster.size)
+		for _, sample := range ts.Cluster {
+			t.cluster.Add(sample)
+		}
+		t.nodes = map[string]*ring{}
+		for node, samples := range ts.Nodes {
+			for _, sample := range samples {
+				t.add(node, sample)
+			}
+		}
+	}
+}
+
+var (
+	historySamplesDesc = prometheus.NewDesc(
+		"containerslist_history_samples",
+		"Number of samples held by a tier of the running containers history.",
+		[]string{"tier"}, nil,
+	)
+	historyBytesDesc = prometheus.NewDesc(
+		"containerslist_history_bytes",
+		"Estimated memory used by the samples of a tier of the running containers history.",
+		[]string{"tier"}, nil,
+	)
+)
+
+// Describe implements prometheus.Collector.
+func (h *history) Describe(ch chan<- *prometheus.Desc) {
+	ch <- historySamplesDesc
+	ch <- historyBytesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (h *history) Collect(ch chan<- prometheus.Metric) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	for _, t := range h.tiers {
+		n := t.samples()
+		ch <- prometheus.MustNewConstMetric(historySamplesDesc, prometheus.GaugeValue, float64(n), t.name)
+		ch <- prometheus.MustNewConstMetric(historyBytesDesc, prometheus.GaugeValue, float64(n)*float64(unsafe.Sizeof(Sample{})), t.name)
+	}
+}
+
+// recordHistory samples the running containers of each node.
+func (m *Manager) recordHistory(now time.Time) {
+	running := map[string]int{}
+	for _, n := range m.inventory.Nodes() {
+		running[n.Node] = 0
+		for _, c := range n.Containers {
+			if c.State == StateRunning {
+				running[n.Node]++
+			}
+		}
+	}
+	m.history.Record(now.UTC(), running)
+}
+
+// runHistory periodically records the running containers, and compacts them
+// in the background into the downsampled tier.
+func (m *Manager) runHistory(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.HistoryResolution)
+	defer ticker.Stop()
+	compactor := time.NewTicker(m.cfg.HistoryDownsampledResolution)
+	defer compactor.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.recordHistory(now)
+		case now := <-compactor.C:
+			m.history.Compact(now.UTC(), tierDownsampled)
+		}
+	}
+}
+
+// sparkline returns the points of an SVG polyline plotting the samples in a
+// box of the given size.
+func sparkline(samples []Sample, width, height float64) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	maxValue := 1.0
+	for _, s := range samples {
+		maxValue = max(maxValue, s.Value)
+	}
+	step := 0.0
+	if len(samples) > 1 {
+		step = width / float64(len(samples)-1)
+	}
+	points := make([]string, 0, len(samples))
+	for i, s := range samples {
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, height-s.Value/maxValue*height))
+	}
+	return strings.Join(points, " ")
+}
+
+func (m *Manager) apiHistory() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tier := r.URL.Query().Get("tier")
+		if tier == "" {
+			tier = tierRaw
+		}
+		nodes := r.URL.Query()["node"]
+		if len(nodes) == 0 {
+			for _, n := range m.inventory.Nodes() {
+				nodes = append(nodes, n.Node)
+			}
+		}
+		res, ok := m.history.Get(tier, nodes)
+		if !ok {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "invalid tier %q: must be one of %s, %s", tier, tierRaw, tierDownsampled))
+			return
+		}
+		writeJSON(w, res)
+	})
+}
@@ -0,0 +1,161 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	for i := 1; i <= 5; i++ {
+		r.Add(Sample{Value: float64(i)})
+	}
+	var got []float64
+	for _, s := range r.Samples() {
+		got = append(got, s.Value)
+	}
+	if want := []float64{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("samples = %v, want %v", got, want)
+	}
+	if r.Len() != 3 {
+		t.Errorf("Len = %d, want 3", r.Len())
+	}
+
+	empty := newRing(0)
+	empty.Add(Sample{Value: 1})
+	if empty.Len() != 0 {
+		t.Errorf("Len of an empty ring = %d, want 0", empty.Len())
+	}
+}
+
+func TestAPIHistory(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{{ID: "a1", State: StateRunning}, {ID: "a2", State: StateRunning}, {ID: "a3", State: "exited"}}},
+		&NodeInventory{Node: "b", Containers: []Container{{ID: "b1", State: StateRunning}}},
+	)
+	m.history = newHistory(newHistoryTier(tierRaw, time.Minute, 3*time.Minute), newHistoryTier(tierDownsampled, time.Hour, 24*time.Hour))
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		m.recordHistory(start.Add(time.Duration(i) * time.Minute))
+	}
+
+	for _, tc := range []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNodes  []string
+	}{
+		{name: "all nodes", wantStatus: http.StatusOK, wantNodes: []string{"a", "b"}},
+		{name: "one node", query: "?node=b&node=unknown", wantStatus: http.StatusOK, wantNodes: []string{"b"}},
+		{name: "invalid tier", query: "?tier=hourly", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.apiHistory().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/history"+tc.query, nil))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var h History
+			if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil {
+				t.Fatal(err)
+			}
+			if h.Tier != tierRaw || h.Resolution != "1m0s" {
+				t.Errorf("tier = %s at %s, want raw at 1m0s", h.Tier, h.Resolution)
+			}
+			// The retention of 3 minutes holds the last 3 samples.
+			if len(h.Cluster.Samples) != 3 || !h.Cluster.Samples[0].Time.Equal(start.Add(time.Minute)) || h.Cluster.Samples[0].Value != 3 {
+				t.Errorf("cluster samples = %+v, want 3 samples of 3 containers from 12:01", h.Cluster.Samples)
+			}
+			var nodes []string
+			for _, s := range h.Nodes {
+				nodes = append(nodes, s.Node)
+			}
+			if !reflect.DeepEqual(nodes, tc.wantNodes) {
+				t.Errorf("nodes = %v, want %v", nodes, tc.wantNodes)
+			}
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		samples []Sample
+		want    string
+	}{
+		{name: "empty"},
+		{name: "single", samples: []Sample{{Value: 0}}, want: "0.0,20.0"},
+		{name: "scaled", samples: []Sample{{Value: 0}, {Value: 5}, {Value: 10}}, want: "0.0,20.0 50.0,10.0 100.0,0.0"},
+	} {
+		if got := sparkline(tc.samples, 100, 20); got != tc.want {
+			t.Errorf("%s: sparkline = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHistoryCompact(t *testing.T) {
+	h := newHistory(newHistoryTier(tierRaw, time.Minute, time.Hour), newHistoryTier(tierDownsampled, 10*time.Minute, 30*time.Minute))
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		now := start.Add(time.Duration(i+1) * time.Minute)
+		running := map[string]int{"a": i}
+		if i >= 20 {
+			running["b"] = 1
+		}
+		h.Record(now, running)
+		if (i+1)%10 == 0 {
+			h.Compact(now, tierDownsampled)
+		}
+	}
+	h.Compact(start, "hourly")
+
+	res, ok := h.Get(tierDownsampled, []string{"a", "b"})
+	if !ok {
+		t.Fatal("no downsampled tier")
+	}
+	values := func(s Series) []float64 {
+		var v []float64
+		for _, sample := range s.Samples {
+			v = append(v, sample.Value)
+		}
+		return v
+	}
+	// The retention of 30 minutes at 10 minutes holds the last 3 averages.
+	if got, want := values(res.Cluster), []float64{14.5, 25.5, 35.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cluster = %v, want %v", got, want)
+	}
+	if len(res.Nodes) != 2 {
+		t.Fatalf("nodes = %+v, want a and b", res.Nodes)
+	}
+	if got, want := values(res.Nodes[0]), []float64{14.5, 24.5, 34.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a = %v, want %v", got, want)
+	}
+	if got, want := values(res.Nodes[1]), []float64{1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b = %v, want %v", got, want)
+	}
+	if !res.Cluster.Samples[2].Time.Equal(start.Add(40 * time.Minute)) {
+		t.Errorf("time of the last average = %v, want the compaction time", res.Cluster.Samples[2].Time)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(h)
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP containerslist_history_samples Number of samples held by a tier of the running containers history.
+# TYPE containerslist_history_samples gauge
+containerslist_history_samples{tier="downsampled"} 8
+containerslist_history_samples{tier="raw"} 100
+`), "containerslist_history_samples"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,154 @@
+package containerslist
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultHTTPMaxBodySize       = 1 << 20
+	defaultHTTPMaxHeaderSize     = 32 << 10
+	defaultHTTPReadHeaderTimeout = 10 * time.Second
+	defaultHTTPReadTimeout       = time.Minute
+	defaultHTTPIdleTimeout       = 2 * time.Minute
+)
+
+// Reasons of the rejected requests, in the metrics.
+const (
+	rejectBodyTooLarge      = "body_too_large"
+	rejectHeadersTooLarge   = "headers_too_large"
+	rejectTooManyConnection = "too_many_connections"
+)
+
+// requestLimits bounds the size of the requests and the number of
+// connections of each client IP, hardening the nodes exposed on shared
+// networks.
+type requestLimits struct {
+	maxBodySize   int64
+	maxHeaderSize int
+	maxConnsPerIP int
+	// The timeouts of the servers hardened by the limits.
+	readHeaderTimeout, readTimeout, idleTimeout time.Duration
+	rejected                                    *prometheus.CounterVec
+	openConns                                   prometheus.Gauge
+
+	mtx            sync.Mutex
+	connsPerClient map[string]int
+}
+
+type connOverLimitKey struct{}
+
+func newRequestLimits(maxBodySize int64, maxHeaderSize, maxConnsPerIP int, reg prometheus.Registerer) *requestLimits {
+	l := &requestLimits{
+		maxBodySize:    maxBodySize,
+		maxHeaderSize:  maxHeaderSize,
+		maxConnsPerIP:  maxConnsPerIP,
+		connsPerClient: map[string]int{},
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_http_rejected_requests_total",
+			Help: "Number of HTTP requests rejected by the limits, by reason.",
+		}, []string{"reason"}),
+		openConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "containerslist_http_open_connections",
+			Help: "Number of open connections to the HTTP servers.",
+		}),
+	}
+	for _, reason := range []string{rejectBodyTooLarge, rejectHeadersTooLarge, rejectTooManyConnection} {
+		l.rejected.WithLabelValues(reason)
+	}
+	reg.MustRegister(l.rejected, l.openConns)
+	return l
+}
+
+// harden sets the timeouts of a server, protecting it from the slow clients,
+// and counts its connections by client IP. The request headers are bounded
+// by the server to twice -http_max_header_size, so that the limits
+// middleware answers the requests exceeding it with a problem.
+func (l *requestLimits) harden(s *http.Server) {
+	s.ReadHeaderTimeout = l.readHeaderTimeout
+	s.ReadTimeout = l.readTimeout
+	s.IdleTimeout = l.idleTimeout
+	s.MaxHeaderBytes = 2 * l.maxHeaderSize
+	s.ConnContext = l.connContext
+	s.ConnState = l.connState
+}
+
+// clientIP returns the IP of a host:port address.
+func clientIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// connContext counts a new connection, flagging it in its context when its
+// client has too many connections.
+func (l *requestLimits) connContext(ctx context.Context, c net.Conn) context.Context {
+	client := clientIP(c.RemoteAddr().String())
+	l.mtx.Lock()
+	l.connsPerClient[client]++
+	over := l.maxConnsPerIP > 0 && l.connsPerClient[client] > l.maxConnsPerIP
+	l.mtx.Unlock()
+	l.openConns.Inc()
+	return context.WithValue(ctx, connOverLimitKey{}, over)
+}
+
+// connState forgets the closed connections.
+func (l *requestLimits) connState(c net.Conn, state http.ConnState) {
+	if state != http.StateClosed && state != http.StateHijacked {
+		return
+	}
+	client := clientIP(c.RemoteAddr().String())
+	l.mtx.Lock()
+	if l.connsPerClient[client]--; l.connsPerClient[client] <= 0 {
+		delete(l.connsPerClient, client)
+	}
+	l.mtx.Unlock()
+	l.openConns.Dec()
+}
+
+// headerSize returns the size of the request line and headers of a request.
+func headerSize(r *http.Request) int {
+	n := len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+	for name, values := range r.Header {
+		for _, v := range values {
+			n += len(name) + len(v) + 4
+		}
+	}
+	return n
+}
+
+// limit answers with a problem the requests of the connections beyond the
+// limit of their client, closing them, and the requests whose headers or
+// body are too large. The bodies without a length are cut at the limit.
+func (l *requestLimits) limit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if over, _ := r.Context().Value(connOverLimitKey{}).(bool); over {
+			l.rejected.WithLabelValues(rejectTooManyConnection).Inc()
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "1")
+			writeProblem(w, r, problemf(ErrTooManyConns, "too many connections from %s: at most %d are allowed", clientIP(r.RemoteAddr), l.maxConnsPerIP))
+			return
+		}
+		if size := headerSize(r); size > l.maxHeaderSize {
+			l.rejected.WithLabelValues(rejectHeadersTooLarge).Inc()
+			w.Header().Set("Connection", "close")
+			writeProblem(w, r, problemf(ErrHeadersTooLarge, "request headers of %d bytes: at most %d are allowed", size, l.maxHeaderSize))
+			return
+		}
+		if r.ContentLength > l.maxBodySize {
+			l.rejected.WithLabelValues(rejectBodyTooLarge).Inc()
+			w.Header().Set("Connection", "close")
+			writeProblem(w, r, problemf(ErrTooLarge, "request body of %d bytes: at most %d are allowed", r.ContentLength, l.maxBodySize))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, l.maxBodySize)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,90 @@
+package containerslist
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRequestLimits(t *testing.T) {
+	l := newRequestLimits(16, 512, 1, prometheus.NewRegistry())
+	srv := httptest.NewUnstartedServer(l.limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	})))
+	l.harden(srv.Config)
+	srv.Start()
+	defer srv.Close()
+
+	// send sends a request on a new connection, left open.
+	send := func(method, body string, header http.Header) *http.Response {
+		t.Helper()
+		c, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		r, err := http.NewRequest(method, srv.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		if r.Header.Get("Transfer-Encoding") != "" {
+			r.ContentLength, r.TransferEncoding = -1, []string{"chunked"}
+		}
+		if err := r.Write(c); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(c), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := send(http.MethodGet, "", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := testutil.ToFloat64(l.openConns); got != 1 {
+		t.Errorf("open connections = %v, want 1", got)
+	}
+	// The first connection is still open.
+	if resp := send(http.MethodGet, "", nil); resp.StatusCode != http.StatusTooManyRequests || !resp.Close {
+		t.Errorf("status of a second connection = %d, want 429 closing it", resp.StatusCode)
+	}
+
+	l.mtx.Lock()
+	l.maxConnsPerIP = 0
+	l.mtx.Unlock()
+	for _, tc := range []struct {
+		name   string
+		body   string
+		header http.Header
+		want   int
+	}{
+		{name: "headers", header: http.Header{"X-Padding": {strings.Repeat("x", 600)}}, want: http.StatusRequestHeaderFieldsTooLarge},
+		{name: "body", body: strings.Repeat("x", 17), want: http.StatusRequestEntityTooLarge},
+		{name: "chunked body", body: strings.Repeat("x", 17), header: http.Header{"Transfer-Encoding": {"chunked"}}, want: http.StatusRequestEntityTooLarge},
+		{name: "small body", body: strings.Repeat("x", 16), want: http.StatusOK},
+	} {
+		if resp := send(http.MethodPost, tc.body, tc.header); resp.StatusCode != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+	for reason, want := range map[string]float64{rejectTooManyConnection: 1, rejectHeadersTooLarge: 1, rejectBodyTooLarge: 1} {
+		if got := testutil.ToFloat64(l.rejected.WithLabelValues(reason)); got != want {
+			t.Errorf("rejected for %s = %v, want %v", reason, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// catalogs are the translations of the UI messages, by language. Messages
+// are keyed by their English text, which is used when no translation exists.
+var catalogs = map[language.Tag]map[string]string{
+	language.English: {},
+	language.French: {
+		"containers":  "conteneurs",
+		"ports":       "ports",
+		"networks":    "réseaux",
+		"volumes":     "volumes",
+		"images":      "images",
+		"projects":    "projets",
+		"nodes":       "nœuds",
+		"search":      "recherche",
+		"violations":  "violations",
+		"maintenance": "maintenance",
+		"top":         "top",
+
+		"Node name: %s":                     "Nom du nœud : %s",
+		"Status: %s":                        "Statut : %s",
+		"Peers:":                            "Pairs :",
+		"Nodes:":                            "Nœuds :",
+		"updated %.0fs ago":                 "mis à jour il y a %.0fs",
+		"stale":                             "obsolète",
+		"No violation":                      "Aucune violation",
+		"Estimated cost: %.2f per hour":     "Coût estimé : %.2f par heure",
+		"restart_loop":                      "redémarrages en boucle",
+		"oom_killed":                        "tué par manque de mémoire",
+		"long_uptime":                       "démarré depuis longtemps",
+		"no %s label":                       "sans label %s",
+		"last synced %s ago":                "dernière synchronisation il y a %s",
+		"degraded":                          "dégradé",
+		"truncated, %d containers in total": "tronqué, %d conteneurs au total",
+		"Running containers:":               "Conteneurs en cours d'exécution :",
+		"Containers:":                       "Conteneurs :",
+		"Node":                              "Nœud",
+		"Name":                              "Nom",
+		"Image":                             "Image",
+		"State":                             "État",
+		"Status":                            "Statut",
+
+		"Notify when containers matching": "Notifier quand des conteneurs correspondant à",
+		"name, image or node":             "nom, image ou nœud",
+		"appear or disappear:":            "apparaissent ou disparaissent :",
+		"Enable notifications":            "Activer les notifications",
+		"Disable notifications":           "Désactiver les notifications",
+
+		"Nodes":             "Nœuds",
+		"Updates received":  "Mises à jour reçues",
+		"Merge conflicts":   "Conflits de fusion",
+		"Rejected payloads": "Données rejetées",
+		"Last update":       "Dernière mise à jour",
+		"%s ago":            "il y a %s",
+		"State size":        "Taille de l'état",
+
+		"expires in %s": "expire dans %s",
+		"link":          "lien",
+
+		"Reason":                         "Raison",
+		"Window":                         "Fenêtre",
+		"Silenced nodes":                 "Nœuds silencieux",
+		"ends in %s":                     "se termine dans %s",
+		"starts in %s":                   "commence dans %s",
+		"for %s":                         "pour %s",
+		"Cancel":                         "Annuler",
+		"No maintenance window":          "Aucune fenêtre de maintenance",
+		"node patterns, comma-separated": "motifs de nœuds, séparés par des virgules",
+		"labels, such as zone=a":         "labels, comme zone=a",
+		"duration, such as 2h":           "durée, comme 2h",
+		"reason":                         "raison",
+		"Schedule":                       "Planifier",
+		"in maintenance":                 "en maintenance",
+
+		"error":                "erreur",
+		"Something went wrong": "Une erreur est survenue",
+		"The page could not be displayed. Retry later, or report the request ID %s.": "La page n'a pas pu être affichée. Réessayez plus tard, ou signalez l'identifiant de requête %s.",
+
+		"Containers %d to %d of %d": "Conteneurs %d à %d sur %d",
+		"page %d of %d":             "page %d sur %d",
+		"first":                     "première",
+		"previous":                  "précédente",
+		"next":                      "suivante",
+		"last":                      "dernière",
+
+		"Views:":            "Vues :",
+		"shared":            "partagée",
+		"name of the view":  "nom de la vue",
+		"in this browser":   "dans ce navigateur",
+		"for me":            "pour moi",
+		"Save view":         "Enregistrer la vue",
+		"Delete view":       "Supprimer la vue",
+		"Copy link":         "Copier le lien",
+		"Filter":            "Filtrer",
+		"by %s":             "par %s",
+		"by %s, descending": "par %s, décroissant",
+		"running":           "en cours",
+		"exited":            "arrêtés",
+		"all":               "tous",
+		"node":              "nœud",
+		"name":              "nom",
+		"image":             "image",
+		"state":             "état",
+		"created":           "création",
+
+		"Auto-refresh:": "Actualisation automatique :",
+		"off":           "désactivée",
+
+		"Self-alerts:": "Alertes internes :",
+		"Partial results: the following peers did not answer:": "Résultats partiels : les pairs suivants n'ont pas répondu :",
+
+		"Update available: %s, running %s.": "Mise à jour disponible : %s, version actuelle %s.",
+		"Release notes":                     "Notes de version",
+
+		"Warning: this node only sees %d of %d expected members and is likely in a minority partition; its view of the cluster may be incomplete.": "Attention : ce nœud ne voit que %d des %d membres attendus et est probablement dans une partition minoritaire ; sa vue du cluster peut être incomplète.",
+	},
+}
+
+var languageMatcher = language.NewMatcher([]language.Tag{language.English, language.French})
+
+// negotiateLanguage returns the language of the UI for a request, from the
+// lang query parameter or else the Accept-Language header.
+func negotiateLanguage(r *http.Request) language.Tag {
+	tag, _ := language.MatchStrings(languageMatcher, r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+	base, _ := tag.Base()
+	for t := range catalogs {
+		if b, _ := t.Base(); b == base {
+			return t
+		}
+	}
+	return language.English
+}
+
+// translate returns the translation of a message, formatted with the given
+// arguments.
+func translate(lang language.Tag, msg string, args ...interface{}) string {
+	if t, ok := catalogs[lang][msg]; ok {
+		msg = t
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// localize returns the UI templates translated to the language of a request.
+func localize(t *template.Template, r *http.Request) *template.Template {
+	lang := negotiateLanguage(r)
+	c, err := t.Clone()
+	if err != nil {
+		return t
+	}
+	return c.Funcs(template.FuncMap{
+		"t":    func(msg string, args ...interface{}) string { return translate(lang, msg, args...) },
+		"lang": func() string { return lang.String() },
+	})
+}
@@ -0,0 +1,51 @@
+package containerslist
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		name, query, acceptLanguage string
+		want                        language.Tag
+	}{
+		{name: "default", want: language.English},
+		{name: "accept language", acceptLanguage: "fr-CA,fr;q=0.9,en;q=0.8", want: language.French},
+		{name: "unsupported", acceptLanguage: "de-DE", want: language.English},
+		{name: "query overrides header", query: "?lang=en", acceptLanguage: "fr", want: language.English},
+		{name: "query", query: "?lang=fr", acceptLanguage: "en-US", want: language.French},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/"+tc.query, nil)
+			if tc.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			if got := negotiateLanguage(r); got != tc.want {
+				t.Errorf("language = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tpl := template.Must(template.New("page").Funcs(templateFuncs).Parse(`<html lang="{{ lang }}">{{ t "Node name: %s" "a" }} {{ t "untranslated" }}`))
+	for _, tc := range []struct {
+		query, want string
+	}{
+		{query: "", want: `<html lang="en">Node name: a untranslated`},
+		{query: "?lang=fr", want: `<html lang="fr">Nom du nœud : a untranslated`},
+	} {
+		var b bytes.Buffer
+		if err := localize(tpl, httptest.NewRequest("GET", "/"+tc.query, nil)).Execute(&b, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tc.want {
+			t.Errorf("page %q = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
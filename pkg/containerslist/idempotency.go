@@ -0,0 +1,151 @@
+package containerslist
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotentResponse is the recorded response of a request bearing an
+// Idempotency-Key header.
+type idempotentResponse struct {
+	// request identifies the request the key was first used with.
+	request string
+	done    chan struct{}
+	expires time.Time
+
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.
+func (r *idempotentResponse) Header() http.Header {
+	return r.header
+}
+
+// Write implements http.ResponseWriter.
+func (r *idempotentResponse) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *idempotentResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *idempotentResponse) replay(w http.ResponseWriter, replayed bool) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
+}
+
+// idempotencyStore keeps the responses of mutating requests for a while, so
+// that retried requests bearing the same Idempotency-Key are not executed
+// twice. The keys are scoped to the callers, so that a caller can neither
+// replay nor block the requests of another one.
+type idempotencyStore struct {
+	mtx       sync.Mutex
+	ttl       time.Duration
+	responses map[string]*idempotentResponse
+	// caller returns the identity of the caller of a request, when not nil.
+	caller func(r *http.Request) string
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+	}
+}
+
+// Purge drops the expired responses.
+func (s *idempotencyStore) Purge(now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for k, resp := range s.responses {
+		if !resp.expires.IsZero() && now.After(resp.expires) {
+			delete(s.responses, k)
+		}
+	}
+}
+
+// Wrap executes a request at most once per Idempotency-Key, replaying the
+// recorded response to its retries. Requests without the header are executed
+// as usual.
+func (s *idempotencyStore) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		request := r.Method + " " + r.URL.RequestURI()
+		if s.caller != nil {
+			key = s.caller(r) + "\x00" + key
+		}
+
+		s.Purge(time.Now())
+		s.mtx.Lock()
+		resp, ok := s.responses[key]
+		if !ok {
+			resp = &idempotentResponse{
+				request: request,
+				done:    make(chan struct{}),
+				header:  http.Header{},
+			}
+			s.responses[key] = resp
+		}
+		s.mtx.Unlock()
+
+		if ok {
+			if resp.request != request {
+				writeProblem(w, r, problemf(ErrKeyReused, "Idempotency-Key already used for another request"))
+				return
+			}
+			select {
+			case <-resp.done:
+				resp.replay(w, true)
+			default:
+				writeProblem(w, r, problemf(ErrConflict, "a request with the same Idempotency-Key is in progress"))
+			}
+			return
+		}
+
+		s.serve(h, key, resp, r)
+		resp.replay(w, false)
+	})
+}
+
+// serve executes a request, recording its response under key. Server errors
+// are not recorded, so that the request can be retried, nor are the responses
+// of the handlers which panicked.
+func (s *idempotencyStore) serve(h http.Handler, key string, resp *idempotentResponse, r *http.Request) {
+	recorded := false
+	defer func() {
+		s.mtx.Lock()
+		resp.expires = time.Now().Add(s.ttl)
+		if !recorded {
+			delete(s.responses, key)
+		}
+		s.mtx.Unlock()
+		close(resp.done)
+	}()
+
+	h.ServeHTTP(resp, r)
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+	recorded = resp.status < http.StatusInternalServerError
+}
@@ -0,0 +1,119 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStoreWrap(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// status is answered by the handler, which panics when it is zero.
+		status       int
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{name: "success", status: http.StatusOK, wantCalls: 1, wantReplayed: true},
+		{name: "client error", status: http.StatusBadRequest, wantCalls: 1, wantReplayed: true},
+		{name: "server error", status: http.StatusInternalServerError, wantCalls: 2},
+		{name: "panic", wantCalls: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newIdempotencyStore(time.Hour)
+			calls := 0
+			h := s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if tc.status == 0 && calls == 1 {
+					panic("handler failure")
+				}
+				w.WriteHeader(tc.status)
+			}))
+			send := func() *httptest.ResponseRecorder {
+				defer func() {
+					if p := recover(); p != nil && tc.status != 0 {
+						t.Fatalf("unexpected panic: %v", p)
+					}
+				}()
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune", nil)
+				req.Header.Set("Idempotency-Key", "key")
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				return rec
+			}
+
+			send()
+			rec := send()
+			if calls != tc.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, tc.wantCalls)
+			}
+			if got := rec.Header().Get("Idempotent-Replayed") == "true"; got != tc.wantReplayed {
+				t.Errorf("replayed = %v, want %v", got, tc.wantReplayed)
+			}
+			if rec.Code == http.StatusConflict {
+				t.Error("retry rejected as in progress")
+			}
+		})
+	}
+}
+
+func TestIdempotencyStoreWrapCallers(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	s.caller = func(r *http.Request) string { return r.Header.Get("X-Caller") }
+	var mtx sync.Mutex
+	calls := map[string]int{}
+	release := make(chan struct{})
+	h := s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		calls[r.Header.Get("X-Caller")]++
+		mtx.Unlock()
+		if r.Header.Get("X-Caller") == "slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(caller string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune", nil)
+		req.Header.Set("Idempotency-Key", "key")
+		req.Header.Set("X-Caller", caller)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A request in progress does not block the same key of another caller.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		send("slow")
+	}()
+	for {
+		s.mtx.Lock()
+		n := len(s.responses)
+		s.mtx.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if rec := send("a"); rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("first request of a: status %d, replayed %q", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	close(release)
+	<-done
+
+	// The responses are replayed to their own caller only.
+	if rec := send("b"); rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("response of another caller replayed")
+	}
+	if rec := send("a"); rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("response not replayed to its caller")
+	}
+	for caller, want := range map[string]int{"slow": 1, "a": 1, "b": 1} {
+		if calls[caller] != want {
+			t.Errorf("handler called %d times for %s, want %d", calls[caller], caller, want)
+		}
+	}
+}
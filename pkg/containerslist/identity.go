@@ -0,0 +1,75 @@
+package containerslist
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultFingerprintFile is the machine ID of systemd hosts.
+const defaultFingerprintFile = "/etc/machine-id"
+
+// fingerprintKey is the application key with which the machine ID is hashed:
+// the machine ID itself is confidential and must not be gossiped.
+const fingerprintKey = "containerslist"
+
+// loadNodeID returns the node ID persisted in a file, generating and
+// persisting a new one on first start. The memberlist name of a node is
+// random on each start, so the gossiped state is keyed by this ID instead:
+// a restarted node takes its entries over rather than appearing as a new one.
+func loadNodeID(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err == nil {
+		id := strings.TrimSpace(string(b))
+		if id == "" {
+			return "", fmt.Errorf("empty node ID in %s", file)
+		}
+		return id, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("unable to read node ID: %w", err)
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("unable to persist node ID: %w", err)
+	}
+	if err := os.WriteFile(file, []byte(id+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("unable to persist node ID: %w", err)
+	}
+	return id, nil
+}
+
+// loadFingerprint returns the fingerprint of the host, derived from the
+// stable identifier held in a file, such as its machine ID. A missing file
+// yields no fingerprint.
+func loadFingerprint(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read fingerprint: %w", err)
+	}
+	id := strings.TrimSpace(string(b))
+	if id == "" {
+		return "", fmt.Errorf("empty fingerprint in %s", file)
+	}
+	mac := hmac.New(sha256.New, []byte(id))
+	mac.Write([]byte(fingerprintKey))
+	return hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
@@ -0,0 +1,74 @@
+package containerslist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadNodeID(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", "node-id")
+	id, err := loadNodeID(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 32 {
+		t.Errorf("generated ID = %q, want 32 hex digits", id)
+	}
+
+	// The ID is kept across restarts.
+	again, err := loadNodeID(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != id {
+		t.Errorf("ID after restart = %q, want %q", again, id)
+	}
+
+	if err := os.WriteFile(file, []byte("web-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := loadNodeID(file); err != nil || id != "web-1" {
+		t.Errorf("loadNodeID = %q, %v, want the ID set by the operator", id, err)
+	}
+
+	if err := os.WriteFile(file, []byte("\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadNodeID(file); err == nil {
+		t.Error("loadNodeID of an empty file succeeded, want an error")
+	}
+}
+
+func TestLoadFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"", filepath.Join(dir, "missing")} {
+		if fp, err := loadFingerprint(file); err != nil || fp != "" {
+			t.Errorf("loadFingerprint(%q) = %q, %v, want no fingerprint", file, fp, err)
+		}
+	}
+
+	file := filepath.Join(dir, "machine-id")
+	if err := os.WriteFile(file, []byte("\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFingerprint(file); err == nil {
+		t.Error("loadFingerprint of an empty file succeeded, want an error")
+	}
+
+	const machineID = "4c4c4544003510528032b3c04f583132"
+	if err := os.WriteFile(file, []byte(machineID+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := loadFingerprint(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fp) != 32 || strings.Contains(fp, machineID) {
+		t.Errorf("fingerprint = %q, want 32 hex digits hiding the machine ID", fp)
+	}
+	if again, _ := loadFingerprint(file); again != fp {
+		t.Errorf("fingerprint = %q, then %q, want a stable one", fp, again)
+	}
+}
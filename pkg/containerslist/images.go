@@ -0,0 +1,98 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+const imagesTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - images</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <p>{{ .Unused }} unused images consuming {{ printf "%.2f" (gigabytes .UnusedBytes) }} GB</p>
+    <table>
+      <tr><th>Node</th><th>Image</th><th>Tags</th><th>Size (MB)</th><th>Created</th><th>Running containers</th></tr>
+    {{ range .Nodes }}{{ $node := .Node }}{{ range .Items }}
+      <tr><td>{{ $node }}</td><td><code>{{ truncateID .ID }}</code></td><td>{{ range .Tags }}{{ . }} {{ end }}</td><td>{{ printf "%.1f" (megabytes .Size) }}</td><td>{{ .Created.Format "2006-01-02" }}</td><td>{{ .Containers }}</td></tr>
+    {{ end }}{{ end }}
+    </table>
+  </body>
+</html>
+`
+
+// Image is a container image present on a node.
+type Image struct {
+	ID      string    `json:"id"`
+	Tags    []string  `json:"tags,omitempty"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+	// Containers is the number of running containers using the image.
+	Containers int `json:"containers"`
+}
+
+// ImagesReport is the cluster-wide image inventory.
+type ImagesReport struct {
+	Nodes       []*NodeResources[Image] `json:"nodes"`
+	Unused      int                     `json:"unused"`
+	UnusedBytes int64                   `json:"unusedBytes"`
+}
+
+// collectImages lists the images of a source and broadcasts them to the
+// cluster, along with the number of running containers using each of them.
+func (m *Manager) collectImages(ctx context.Context, s *dockerSource, containers []Container) ([]Image, error) {
+	images, err := s.client.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]int{}
+	for _, c := range containers {
+		if c.State == StateRunning {
+			used[c.ImageID]++
+		}
+	}
+	for i := range images {
+		images[i].Containers = used[images[i].ID]
+	}
+
+	b, err := m.images.Set(&NodeResources[Image]{Node: s.node, Timestamp: time.Now().UTC(), Items: images})
+	if err != nil {
+		return nil, err
+	}
+	m.imagesChannel.Broadcast(b)
+	return images, nil
+}
+
+func (m *Manager) imagesReport() ImagesReport {
+	r := ImagesReport{Nodes: m.images.Nodes()}
+	for _, n := range r.Nodes {
+		for _, i := range n.Items {
+			if i.Containers == 0 {
+				r.Unused++
+				r.UnusedBytes += i.Size
+			}
+		}
+	}
+	return r
+}
+
+func (m *Manager) apiImages() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.imagesReport())
+	})
+}
+
+func (m *Manager) imagesPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := localize(t, r).ExecuteTemplate(w, "images", m.imagesReport()); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package containerslist
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testChannel is a cluster channel recording the broadcast messages.
+type testChannel struct {
+	msgs [][]byte
+}
+
+// Broadcast implements cluster.ClusterChannel.
+func (c *testChannel) Broadcast(b []byte) {
+	c.msgs = append(c.msgs, b)
+}
+
+func TestCollectImages(t *testing.T) {
+	s := &dockerSource{node: "a", client: newTestDockerClient(t, map[string]string{
+		"/images/json": `[
+			{"Id": "sha256:web", "RepoTags": ["nginx:1.25", "nginx:latest"], "Size": 100, "Created": 1700000000},
+			{"Id": "sha256:old", "RepoTags": ["<none>:<none>"], "Size": 50, "Created": 1600000000},
+			{"Id": "sha256:db", "RepoTags": ["postgres:16"], "Size": 200, "Created": 1700000000}
+		]`,
+	})}
+	ch := &testChannel{}
+	m := &Manager{cfg: DefaultConfig(), images: newResourceState[Image](), imagesChannel: ch}
+	images, err := m.collectImages(context.Background(), s, []Container{
+		{ID: "c1", ImageID: "sha256:web", State: StateRunning},
+		{ID: "c2", ImageID: "sha256:web", State: StateRunning},
+		{ID: "c3", ImageID: "sha256:db", State: "exited"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Image{
+		{ID: "sha256:web", Tags: []string{"nginx:1.25", "nginx:latest"}, Size: 100, Created: time.Unix(1700000000, 0).UTC(), Containers: 2},
+		{ID: "sha256:old", Size: 50, Created: time.Unix(1600000000, 0).UTC()},
+		{ID: "sha256:db", Tags: []string{"postgres:16"}, Size: 200, Created: time.Unix(1700000000, 0).UTC()},
+	}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %+v, want %+v", images, want)
+	}
+	if len(ch.msgs) != 1 {
+		t.Errorf("%d broadcasts, want 1", len(ch.msgs))
+	}
+
+	r := m.imagesReport()
+	if r.Unused != 2 || r.UnusedBytes != 250 {
+		t.Errorf("unused = %d, %d bytes, want 2, 250 bytes", r.Unused, r.UnusedBytes)
+	}
+}
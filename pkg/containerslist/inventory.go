@@ -0,0 +1,488 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Container states as reported by the runtime.
+const (
+	StateRunning = "running"
+	StateExited  = "exited"
+	StateDead    = "dead"
+)
+
+// Container describes a single container running (or having run) on a node.
+type Container struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	ImageID string            `json:"imageID"`
+	State   string            `json:"state"`
+	Status  string            `json:"status"`
+	Created time.Time         `json:"created"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Devices []Device          `json:"devices,omitempty"`
+	GPUs    []string          `json:"gpus,omitempty"`
+	Ports   []Port            `json:"ports,omitempty"`
+	// FinishedAt is when a container which is not running last stopped.
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Pod is set for containers started by the kubelet.
+	Pod *Pod `json:"pod,omitempty"`
+	// Annotations are typed values attached by enrichers, each matching a
+	// registered AnnotationSchema.
+	Annotations map[string]AnnotationValue `json:"annotations,omitempty"`
+	// StartedAt is when the container last started, and RestartCount the
+	// number of times the runtime restarted it.
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	RestartCount int        `json:"restartCount,omitempty"`
+	// OOMKilled is set when the container was last killed for running out of
+	// memory.
+	OOMKilled bool `json:"oomKilled,omitempty"`
+	// Flags are the anomalies detected on the container, such as
+	// restart_loop.
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Port is a container port published on the host.
+type Port struct {
+	HostIP        string `json:"hostIP"`
+	HostPort      uint16 `json:"hostPort"`
+	ContainerPort uint16 `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// Device is a host device mapped into a container.
+type Device struct {
+	HostPath      string `json:"hostPath"`
+	ContainerPath string `json:"containerPath"`
+}
+
+// containerDetails holds the details of a container obtained by inspecting
+// it. They mostly change when the container changes state.
+type containerDetails struct {
+	state      string
+	devices    []Device
+	gpus       []string
+	finishedAt *time.Time
+
+	startedAt    *time.Time
+	restartCount int
+	oomKilled    bool
+	inspectedAt  time.Time
+}
+
+// stateFilter returns a predicate matching containers for the given `state`
+// query value: "running" (the default), "exited" or "all".
+func stateFilter(state string) (func(Container) bool, bool) {
+	switch state {
+	case "", StateRunning:
+		return func(c Container) bool { return c.State == StateRunning }, true
+	case StateExited:
+		return func(c Container) bool { return c.State == StateExited || c.State == StateDead }, true
+	case "all":
+		return func(Container) bool { return true }, true
+	default:
+		return nil, false
+	}
+}
+
+// NodeInventory is the list of containers of a single node, as gossiped to
+// the other peers.
+type NodeInventory struct {
+	Node       string      `json:"node"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Containers []Container `json:"containers"`
+	// Degraded is set when the runtime could not be reached and the
+	// containers are the last known ones.
+	Degraded bool `json:"degraded"`
+	// Truncated is set when the node has more containers than the limits of
+	// its agent: Containers is then a sample of its TotalContainers, the other
+	// ones being counted per image in ImageCounts.
+	Truncated       bool           `json:"truncated,omitempty"`
+	TotalContainers int            `json:"totalContainers,omitempty"`
+	ImageCounts     map[string]int `json:"imageCounts,omitempty"`
+	// Decommissioned is set on the tombstone replacing the inventory of a
+	// node permanently removed from the cluster.
+	Decommissioned bool `json:"decommissioned,omitempty"`
+	// Edge is set by the nodes running in edge mode, which are frequently
+	// disconnected from the cluster.
+	Edge bool `json:"edge,omitempty"`
+	// Revision advances on each change of the inventory of the node. It is
+	// a hybrid logical clock: the time in milliseconds, unless the node
+	// already saw a later revision, so that it keeps increasing across
+	// restarts and orders the changes of the cluster.
+	Revision uint64 `json:"revision,omitempty"`
+	// Summary is set on the inventories of the nodes of the shards which
+	// are not aggregated locally, of which only the counts of containers per
+	// image are kept. It is never gossiped.
+	Summary bool `json:"summary,omitempty"`
+
+	// AgeSeconds and Stale are computed when serving the inventory.
+	AgeSeconds float64 `json:"ageSeconds"`
+	Stale      bool    `json:"stale"`
+	// Zone and CrossZone are computed when serving the inventory, when the
+	// local node has a zone.
+	Zone      string `json:"zone,omitempty"`
+	CrossZone bool   `json:"crossZone,omitempty"`
+	// Offline is computed when serving the inventory: it replaces Stale for
+	// the edge nodes, whose inventory is the one of their last sync.
+	Offline bool `json:"offline,omitempty"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+func (n *NodeInventory) node() string              { return n.Node }
+func (n *NodeInventory) signature() *Signature     { return n.Signature }
+func (n *NodeInventory) setSignature(s *Signature) { n.Signature = s }
+
+// Filter returns a copy of the inventory only holding the containers matching f.
+func (n *NodeInventory) Filter(f func(Container) bool) *NodeInventory {
+	res := *n
+	res.Containers = make([]Container, 0, len(n.Containers))
+	for _, c := range n.Containers {
+		if f(c) {
+			res.Containers = append(res.Containers, c)
+		}
+	}
+	return &res
+}
+
+// inventory is the cluster-wide container inventory. It implements
+// cluster.State: each node owns its own entry and the most recent version of
+// an entry wins on merge.
+type inventory struct {
+	mtx       sync.RWMutex
+	nodes     map[string]*NodeInventory
+	signer    *gossipSigner
+	blocklist *blocklist
+	events    *eventBroker
+	// annotations are the schemas of the annotations of the containers.
+	annotations annotationSchemas
+	// maxContainers and memoryBudget bound the inventories of the nodes,
+	// unbounded when 0.
+	maxContainers int
+	memoryBudget  int64
+	// tombstoned is called when the tombstone of a node is received.
+	tombstoned func()
+	// owns reports whether the full inventory of a node is kept, when the
+	// collection is sharded; the other ones are summarized.
+	owns func(node string) bool
+	// legacyPeers reports whether some members may not decode the protobuf
+	// encoding, the inventory then being gossiped as JSON.
+	legacyPeers func() bool
+	// stats counts the entries received for each node.
+	stats *gossipStats
+	// delay observes the time elapsed between the collection of the merged
+	// entries and their merge, when not nil.
+	delay prometheus.Histogram
+	// version advances on each change of the inventory, closing changed.
+	version uint64
+	changed chan struct{}
+	// revision is the revision of the cluster: the latest revision of the
+	// merged entries.
+	revision uint64
+	// sequence advances on each merged change, in the order of the merges,
+	// unlike the revisions of the entries which can be received out of
+	// order. sequences are the sequences of the changes of the nodes. The
+	// sequences are local to the incarnation of the member identified by
+	// epoch.
+	sequence  uint64
+	sequences map[string]uint64
+	epoch     string
+	// resyncs are the checksums of the versions of the inventories to
+	// re-synchronize, by node.
+	resyncs map[string]InventoryChecksum
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		nodes:     map[string]*NodeInventory{},
+		stats:     newGossipStats(),
+		changed:   make(chan struct{}),
+		resyncs:   map[string]InventoryChecksum{},
+		sequences: map[string]uint64{},
+		epoch:     newRequestID(),
+	}
+}
+
+// marshal encodes node inventories with the protobuf schema of
+// proto/inventory.proto, or as JSON while some members may not decode it.
+func (i *inventory) marshal(nodes []*NodeInventory) ([]byte, error) {
+	if i.legacyPeers != nil && i.legacyPeers() {
+		return json.Marshal(nodes)
+	}
+	return marshalInventoryProto(nodes), nil
+}
+
+// MarshalBinary implements cluster.State.
+func (i *inventory) MarshalBinary() ([]byte, error) {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	nodes := make([]*NodeInventory, 0, len(i.nodes))
+	for _, n := range i.nodes {
+		if !n.Summary {
+			nodes = append(nodes, n)
+		}
+	}
+	return i.marshal(nodes)
+}
+
+// Merge implements cluster.State. The JSON encoding of peers running older
+// versions is still accepted.
+func (i *inventory) Merge(b []byte) error {
+	var nodes []*NodeInventory
+	var err error
+	if len(b) > 0 && b[0] == '[' {
+		err = json.Unmarshal(b, &nodes)
+	} else {
+		nodes, err = unmarshalInventoryProto(b)
+	}
+	if err != nil {
+		return err
+	}
+
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	tombstoned := false
+	now := time.Now()
+	for _, n := range nodes {
+		switch {
+		case i.blocklist.Drop(n.Node) || !i.signer.Verify(n):
+			i.stats.observe(n.Node, gossipRejected, now)
+		case !i.merge(n):
+			i.stats.observe(n.Node, gossipConflict, now)
+		default:
+			i.stats.observe(n.Node, gossipMerged, now)
+			if i.delay != nil && !n.Timestamp.IsZero() {
+				i.delay.Observe(math.Max(now.Sub(n.Timestamp).Seconds(), 0))
+			}
+			tombstoned = tombstoned || n.Decommissioned
+		}
+	}
+	if tombstoned && i.tombstoned != nil {
+		go i.tombstoned()
+	}
+	return nil
+}
+
+func (i *inventory) merge(n *NodeInventory) bool {
+	if i.owns != nil && !n.Decommissioned && !i.owns(n.Node) {
+		n = n.summary()
+	}
+	prev, ok := i.nodes[n.Node]
+	if ok && i.resynced(prev, n) {
+		i.changedNode(n.Node)
+		i.nodes[n.Node] = n
+		return true
+	}
+	// The full inventory replaces the summary of the same version, once the
+	// node owns its shard.
+	if ok && (n.Timestamp.Before(prev.Timestamp) || n.Timestamp.Equal(prev.Timestamp) && (n.Summary || !prev.Summary)) {
+		return false
+	}
+	// The containers of a node seen for the first time are not reported as
+	// appearing, not to flood subscribers when joining the cluster. Neither
+	// are the ones of the nodes of the shards which are not aggregated
+	// locally.
+	if ok && !prev.Summary && !n.Summary {
+		i.events.Publish(diffContainers(prev, n))
+	}
+	if !ok || n.Revision != prev.Revision || inventoryChanged(prev, n) {
+		i.changedNode(n.Node)
+	}
+	if n.Revision > i.revision {
+		i.revision = n.Revision
+	}
+	i.nodes[n.Node] = n
+	return true
+}
+
+// changedNode records a change of the inventory of a node, advancing the
+// version and the sequence.
+func (i *inventory) changedNode(node string) {
+	i.version++
+	i.sequence++
+	i.sequences[node] = i.sequence
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// Resync marks the inventory of a node as not matching the checksum of its
+// version, for the next copy of the version matching it to replace it.
+func (i *inventory) Resync(node string, timestamp time.Time, checksum string) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	i.resyncs[node] = InventoryChecksum{Node: node, Timestamp: timestamp, Checksum: checksum}
+}
+
+// resynced reports whether a copy of the inventory of a node replaces the
+// one held, as the version to re-synchronize matching its checksum. The
+// re-synchronizations of older versions are given up.
+func (i *inventory) resynced(prev, n *NodeInventory) bool {
+	c, ok := i.resyncs[n.Node]
+	if !ok {
+		return false
+	}
+	if !c.Timestamp.Equal(prev.Timestamp) {
+		delete(i.resyncs, n.Node)
+		return false
+	}
+	if n.Summary || !n.Timestamp.Equal(c.Timestamp) || inventoryChecksum(n) != c.Checksum {
+		return false
+	}
+	delete(i.resyncs, n.Node)
+	return true
+}
+
+// inventoryChanged reports whether the inventory of a node changed between
+// two versions: when a container appeared, disappeared or changed state, or
+// when the node was degraded, truncated, summarized or decommissioned. The
+// statuses, such as "Up 5 minutes", are left out as they change constantly.
+func inventoryChanged(prev, n *NodeInventory) bool {
+	if prev.Degraded != n.Degraded || prev.Truncated != n.Truncated || prev.Summary != n.Summary || prev.Decommissioned != n.Decommissioned || len(prev.Containers) != len(n.Containers) {
+		return true
+	}
+	states := make(map[string]string, len(prev.Containers))
+	for _, c := range prev.Containers {
+		states[c.ID] = c.State
+	}
+	for _, c := range n.Containers {
+		if state, ok := states[c.ID]; !ok || state != c.State {
+			return true
+		}
+	}
+	return false
+}
+
+// Version returns the version of the inventory, which advances on each
+// change of the inventory of a node.
+func (i *inventory) Version() uint64 {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.version
+}
+
+// Revision returns the revision of the cluster, which only increases.
+func (i *inventory) Revision() uint64 {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.revision
+}
+
+// Sequence returns the sequence of the changes of the inventory, and the
+// epoch of the member to which it is local.
+func (i *inventory) Sequence() (string, uint64) {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.epoch, i.sequence
+}
+
+// ChangedSince returns the inventories, including the tombstones, changed
+// after the sequence since, sorted by node name.
+func (i *inventory) ChangedSince(since uint64) []*NodeInventory {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	var nodes []*NodeInventory
+	for node, seq := range i.sequences {
+		if seq > since {
+			nodes = append(nodes, i.nodes[node])
+		}
+	}
+	sort.Slice(nodes, func(a, b int) bool {
+		return nodes[a].Node < nodes[b].Node
+	})
+	return nodes
+}
+
+// Wait blocks until the version of the inventory differs from version, or
+// ctx is done, and returns the current version.
+func (i *inventory) Wait(ctx context.Context, version uint64) uint64 {
+	for {
+		i.mtx.RLock()
+		current, changed := i.version, i.changed
+		i.mtx.RUnlock()
+		if current != version {
+			return current
+		}
+		select {
+		case <-ctx.Done():
+			return current
+		case <-changed:
+		}
+	}
+}
+
+// Set replaces the inventory of a node, returning the serialized update to
+// broadcast.
+func (i *inventory) Set(n *NodeInventory) ([]byte, error) {
+	for j := range n.Containers {
+		i.annotations.annotate(&n.Containers[j])
+	}
+	n.limit(i.maxContainers, i.memoryBudget)
+	n.normalize()
+	i.mtx.RLock()
+	if prev, ok := i.nodes[n.Node]; ok && !inventoryChanged(prev, n) {
+		n.Revision = prev.Revision
+	} else {
+		n.Revision = max(i.revision+1, uint64(time.Now().UnixMilli()))
+	}
+	i.mtx.RUnlock()
+	if err := i.signer.Sign(n); err != nil {
+		return nil, err
+	}
+
+	i.mtx.Lock()
+	i.merge(n)
+	i.mtx.Unlock()
+
+	return i.marshal([]*NodeInventory{n})
+}
+
+// Get returns the inventory of a node.
+func (i *inventory) Get(node string) (*NodeInventory, bool) {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	n, ok := i.nodes[node]
+	if ok && n.Decommissioned {
+		return nil, false
+	}
+	return n, ok
+}
+
+// Nodes returns the inventories of all nodes, sorted by node name. The
+// decommissioned nodes are left out.
+func (i *inventory) Nodes() []*NodeInventory {
+	return i.list(false)
+}
+
+// Tombstones returns the tombstones of the decommissioned nodes, sorted by
+// node name.
+func (i *inventory) Tombstones() []*NodeInventory {
+	return i.list(true)
+}
+
+func (i *inventory) list(decommissioned bool) []*NodeInventory {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	nodes := make([]*NodeInventory, 0, len(i.nodes))
+	for _, n := range i.nodes {
+		if n.Decommissioned == decommissioned {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(a, b int) bool {
+		return nodes[a].Node < nodes[b].Node
+	})
+	return nodes
+}
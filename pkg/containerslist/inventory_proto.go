@@ -0,0 +1,459 @@
+package containerslist
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The gossiped inventory is encoded with the protobuf schema of
+// proto/inventory.proto, JSON being only used at the API edge.
+
+var errInvalidProto = errors.New("invalid protobuf inventory")
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func encodeTimestamp(t time.Time) []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(t.Unix()))
+	b = appendVarint(b, 2, uint64(t.Nanosecond()))
+	return b
+}
+
+func encodeContainer(c *Container) []byte {
+	var b []byte
+	b = appendString(b, 1, c.ID)
+	b = appendString(b, 2, c.Name)
+	b = appendString(b, 3, c.Image)
+	b = appendString(b, 4, c.ImageID)
+	b = appendString(b, 5, c.State)
+	b = appendString(b, 6, c.Status)
+	b = appendMessage(b, 7, encodeTimestamp(c.Created))
+
+	keys := make([]string, 0, len(c.Labels))
+	for k := range c.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var e []byte
+		e = appendString(e, 1, k)
+		e = appendString(e, 2, c.Labels[k])
+		b = appendMessage(b, 8, e)
+	}
+	for _, d := range c.Devices {
+		var e []byte
+		e = appendString(e, 1, d.HostPath)
+		e = appendString(e, 2, d.ContainerPath)
+		b = appendMessage(b, 9, e)
+	}
+	for _, g := range c.GPUs {
+		b = protowire.AppendTag(b, 10, protowire.BytesType)
+		b = protowire.AppendString(b, g)
+	}
+	for _, p := range c.Ports {
+		var e []byte
+		e = appendString(e, 1, p.HostIP)
+		e = appendVarint(e, 2, uint64(p.HostPort))
+		e = appendVarint(e, 3, uint64(p.ContainerPort))
+		e = appendString(e, 4, p.Protocol)
+		b = appendMessage(b, 11, e)
+	}
+	if c.FinishedAt != nil {
+		b = appendMessage(b, 12, encodeTimestamp(*c.FinishedAt))
+	}
+	if p := c.Pod; p != nil {
+		var e []byte
+		e = appendString(e, 1, p.Name)
+		e = appendString(e, 2, p.Namespace)
+		e = appendString(e, 3, p.UID)
+		e = appendString(e, 4, p.Container)
+		e = appendString(e, 5, p.OwnerKind)
+		e = appendString(e, 6, p.OwnerName)
+		b = appendMessage(b, 13, e)
+	}
+	names := make([]string, 0, len(c.Annotations))
+	for name := range c.Annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := c.Annotations[name]
+		var value []byte
+		switch v.Type {
+		case AnnotationNumber:
+			value = protowire.AppendTag(value, 2, protowire.Fixed64Type)
+			value = protowire.AppendFixed64(value, math.Float64bits(v.Number))
+		case AnnotationBool:
+			value = protowire.AppendTag(value, 3, protowire.VarintType)
+			value = protowire.AppendVarint(value, protowire.EncodeBool(v.Bool))
+		default:
+			value = protowire.AppendTag(value, 1, protowire.BytesType)
+			value = protowire.AppendString(value, v.String)
+		}
+		var e []byte
+		e = appendString(e, 1, name)
+		e = appendMessage(e, 2, value)
+		b = appendMessage(b, 14, e)
+	}
+	if c.StartedAt != nil {
+		b = appendMessage(b, 15, encodeTimestamp(*c.StartedAt))
+	}
+	b = appendVarint(b, 16, uint64(c.RestartCount))
+	if c.OOMKilled {
+		b = appendVarint(b, 17, 1)
+	}
+	for _, f := range c.Flags {
+		b = appendString(b, 18, f)
+	}
+	return b
+}
+
+func encodeNodeInventory(n *NodeInventory) []byte {
+	var b []byte
+	b = appendString(b, 1, n.Node)
+	b = appendMessage(b, 2, encodeTimestamp(n.Timestamp))
+	for i := range n.Containers {
+		b = appendMessage(b, 3, encodeContainer(&n.Containers[i]))
+	}
+	if n.Degraded {
+		b = appendVarint(b, 4, 1)
+	}
+	if n.Truncated {
+		b = appendVarint(b, 6, 1)
+	}
+	b = appendVarint(b, 7, uint64(n.TotalContainers))
+	images := make([]string, 0, len(n.ImageCounts))
+	for image := range n.ImageCounts {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		var e []byte
+		e = appendString(e, 1, image)
+		e = appendVarint(e, 2, uint64(n.ImageCounts[image]))
+		b = appendMessage(b, 8, e)
+	}
+	if n.Decommissioned {
+		b = appendVarint(b, 9, 1)
+	}
+	if n.Edge {
+		b = appendVarint(b, 10, 1)
+	}
+	if n.Revision != 0 {
+		b = appendVarint(b, 11, n.Revision)
+	}
+	if s := n.Signature; s != nil {
+		var e []byte
+		e = appendBytes(e, 1, s.Key)
+		e = appendBytes(e, 2, s.Sig)
+		b = appendMessage(b, 5, e)
+	}
+	return b
+}
+
+// marshalInventoryProto encodes node inventories as an Inventory message.
+func marshalInventoryProto(nodes []*NodeInventory) []byte {
+	var b []byte
+	for _, n := range nodes {
+		b = appendMessage(b, 1, encodeNodeInventory(n))
+	}
+	return b
+}
+
+// decodeFields calls fn with each field of a message, passing the value of
+// varint fields as v and the content of length-delimited fields as data.
+func decodeFields(b []byte, fn func(num protowire.Number, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidProto
+		}
+		b = b[n:]
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errInvalidProto
+		}
+		b = b[n:]
+		if err := fn(num, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var sec, nsec int64
+	err := decodeFields(b, func(num protowire.Number, v uint64, _ []byte) error {
+		switch num {
+		case 1:
+			sec = int64(v)
+		case 2:
+			nsec = int64(v)
+		}
+		return nil
+	})
+	return time.Unix(sec, nsec).UTC(), err
+}
+
+func decodeStrings(b []byte, fields ...*string) error {
+	return decodeFields(b, func(num protowire.Number, _ uint64, data []byte) error {
+		if int(num) >= 1 && int(num) <= len(fields) {
+			*fields[num-1] = string(data)
+		}
+		return nil
+	})
+}
+
+func decodeContainer(b []byte) (Container, error) {
+	var c Container
+	err := decodeFields(b, func(num protowire.Number, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			c.ID = string(data)
+		case 2:
+			c.Name = string(data)
+		case 3:
+			c.Image = string(data)
+		case 4:
+			c.ImageID = string(data)
+		case 5:
+			c.State = string(data)
+		case 6:
+			c.Status = string(data)
+		case 7:
+			t, err := decodeTimestamp(data)
+			c.Created = t
+			return err
+		case 8:
+			var k, val string
+			if err := decodeStrings(data, &k, &val); err != nil {
+				return err
+			}
+			if c.Labels == nil {
+				c.Labels = map[string]string{}
+			}
+			c.Labels[k] = val
+		case 9:
+			var d Device
+			if err := decodeStrings(data, &d.HostPath, &d.ContainerPath); err != nil {
+				return err
+			}
+			c.Devices = append(c.Devices, d)
+		case 10:
+			c.GPUs = append(c.GPUs, string(data))
+		case 11:
+			var p Port
+			err := decodeFields(data, func(num protowire.Number, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					p.HostIP = string(data)
+				case 2:
+					p.HostPort = uint16(v)
+				case 3:
+					p.ContainerPort = uint16(v)
+				case 4:
+					p.Protocol = string(data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			c.Ports = append(c.Ports, p)
+		case 12:
+			t, err := decodeTimestamp(data)
+			c.FinishedAt = &t
+			return err
+		case 13:
+			var p Pod
+			if err := decodeStrings(data, &p.Name, &p.Namespace, &p.UID, &p.Container, &p.OwnerKind, &p.OwnerName); err != nil {
+				return err
+			}
+			c.Pod = &p
+		case 14:
+			var name string
+			var value AnnotationValue
+			err := decodeFields(data, func(num protowire.Number, _ uint64, data []byte) error {
+				switch num {
+				case 1:
+					name = string(data)
+				case 2:
+					return decodeFields(data, func(num protowire.Number, v uint64, data []byte) error {
+						switch num {
+						case 1:
+							value = AnnotationValue{Type: AnnotationString, String: string(data)}
+						case 2:
+							value = AnnotationValue{Type: AnnotationNumber, Number: math.Float64frombits(v)}
+						case 3:
+							value = AnnotationValue{Type: AnnotationBool, Bool: v != 0}
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			if c.Annotations == nil {
+				c.Annotations = map[string]AnnotationValue{}
+			}
+			c.Annotations[name] = value
+			return err
+		case 15:
+			t, err := decodeTimestamp(data)
+			c.StartedAt = &t
+			return err
+		case 16:
+			c.RestartCount = int(v)
+		case 17:
+			c.OOMKilled = v != 0
+		case 18:
+			c.Flags = append(c.Flags, string(data))
+		}
+		return nil
+	})
+	return c, err
+}
+
+func decodeNodeInventory(b []byte) (*NodeInventory, error) {
+	n := &NodeInventory{Containers: []Container{}}
+	err := decodeFields(b, func(num protowire.Number, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			n.Node = string(data)
+		case 2:
+			t, err := decodeTimestamp(data)
+			n.Timestamp = t
+			return err
+		case 3:
+			c, err := decodeContainer(data)
+			if err != nil {
+				return err
+			}
+			n.Containers = append(n.Containers, c)
+		case 4:
+			n.Degraded = v != 0
+		case 5:
+			s := &Signature{}
+			err := decodeFields(data, func(num protowire.Number, _ uint64, data []byte) error {
+				switch num {
+				case 1:
+					s.Key = append([]byte(nil), data...)
+				case 2:
+					s.Sig = append([]byte(nil), data...)
+				}
+				return nil
+			})
+			n.Signature = s
+			return err
+		case 6:
+			n.Truncated = v != 0
+		case 7:
+			n.TotalContainers = int(v)
+		case 8:
+			var image string
+			var count int
+			err := decodeFields(data, func(num protowire.Number, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					image = string(data)
+				case 2:
+					count = int(v)
+				}
+				return nil
+			})
+			if n.ImageCounts == nil {
+				n.ImageCounts = map[string]int{}
+			}
+			n.ImageCounts[image] = count
+			return err
+		case 9:
+			n.Decommissioned = v != 0
+		case 10:
+			n.Edge = v != 0
+		case 11:
+			n.Revision = v
+		}
+		return nil
+	})
+	return n, err
+}
+
+// unmarshalInventoryProto decodes an Inventory message.
+func unmarshalInventoryProto(b []byte) ([]*NodeInventory, error) {
+	var nodes []*NodeInventory
+	err := decodeFields(b, func(num protowire.Number, _ uint64, data []byte) error {
+		if num != 1 {
+			return nil
+		}
+		n, err := decodeNodeInventory(data)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+		return nil
+	})
+	return nodes, err
+}
+
+// normalize makes the inventory of a node survive a protobuf round trip
+// unchanged, so that its signature, computed on its JSON encoding, can be
+// verified by the peers.
+func (n *NodeInventory) normalize() {
+	n.Timestamp = n.Timestamp.UTC()
+	if n.Containers == nil {
+		n.Containers = []Container{}
+	}
+	for i := range n.Containers {
+		c := &n.Containers[i]
+		c.Created = c.Created.UTC()
+		if c.StartedAt != nil {
+			t := c.StartedAt.UTC()
+			c.StartedAt = &t
+		}
+		if c.FinishedAt != nil {
+			t := c.FinishedAt.UTC()
+			c.FinishedAt = &t
+		}
+	}
+}
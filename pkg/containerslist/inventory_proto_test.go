@@ -0,0 +1,263 @@
+package containerslist
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testFullInventory returns the inventory of a node setting all the gossiped
+// fields, with times in another location than UTC.
+func testFullInventory() *NodeInventory {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	started := created.Add(time.Minute)
+	finished := started.Add(time.Hour)
+	c := Container{
+		ID:          "0123456789ab",
+		Name:        "web",
+		Image:       "nginx:1.25",
+		ImageID:     "sha256:abc",
+		State:       StateExited,
+		Status:      "Exited (1) 2 minutes ago",
+		Created:     created,
+		Labels:      map[string]string{"app": "web", "team": "", "": "empty key"},
+		Devices:     []Device{{HostPath: "/dev/nvidia0", ContainerPath: "/dev/nvidia0"}, {HostPath: "/dev/fuse"}},
+		GPUs:        []string{"GPU-1", "GPU-2"},
+		Ports:       []Port{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}, {ContainerPort: 53, Protocol: "udp"}},
+		FinishedAt:  &finished,
+		Pod:         &Pod{Name: "web-0", Namespace: "default", UID: "uid", Container: "nginx", OwnerKind: "StatefulSet", OwnerName: "web"},
+		Annotations: map[string]AnnotationValue{"cost": {Type: AnnotationNumber, Number: -1.5}, "critical": {Type: AnnotationBool, Bool: true}, "owner": {Type: AnnotationString, String: "alice"}, "empty": {Type: AnnotationString}},
+		StartedAt:   &started,
+		// The counts survive any integer, even when meaningless.
+		RestartCount: 1 << 40,
+		OOMKilled:    true,
+		Flags:        []string{"restart_loop", "oom"},
+	}
+	return &NodeInventory{
+		Node:            "node-1",
+		Timestamp:       created.Add(2 * time.Hour),
+		Containers:      []Container{c, {ID: "minimal", Created: time.Unix(0, 0)}},
+		Degraded:        true,
+		Truncated:       true,
+		TotalContainers: 1000,
+		ImageCounts:     map[string]int{"nginx:1.25": 998, "redis": 2},
+		Decommissioned:  true,
+		Edge:            true,
+		Revision:        1709281800000,
+	}
+}
+
+// nonGossipedFields are the fields of NodeInventory computed when serving it.
+var nonGossipedFields = map[string]bool{"Summary": true, "AgeSeconds": true, "Stale": true, "Zone": true, "CrossZone": true, "Offline": true}
+
+// TestInventoryProtoFields checks that testFullInventory sets all the fields,
+// so that a field added without its protobuf encoding fails the round trip.
+func TestInventoryProtoFields(t *testing.T) {
+	n := testFullInventory()
+	v := reflect.ValueOf(*n)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.Name != "Signature" && !nonGossipedFields[f.Name] && v.Field(i).IsZero() {
+			t.Errorf("NodeInventory.%s is not set", f.Name)
+		}
+	}
+	v = reflect.ValueOf(n.Containers[0])
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsZero() {
+			t.Errorf("Container.%s is not set", v.Type().Field(i).Name)
+		}
+	}
+}
+
+func testSigner(t *testing.T) *gossipSigner {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newGossipSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// signatureValid reports whether the signature of an entry matches its JSON
+// encoding, as verified by the peers.
+func signatureValid(t *testing.T, n *NodeInventory) bool {
+	t.Helper()
+	sig := n.Signature
+	if sig == nil {
+		t.Fatal("entry not signed")
+	}
+	unsigned := *n
+	unsigned.Signature = nil
+	payload, err := json.Marshal(&unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ed25519.Verify(sig.Key, payload, sig.Sig)
+}
+
+func TestInventoryProtoRoundTrip(t *testing.T) {
+	signer := testSigner(t)
+	for _, tc := range []struct {
+		name string
+		node *NodeInventory
+	}{
+		{name: "full", node: testFullInventory()},
+		{name: "empty", node: &NodeInventory{Node: "node-2", Timestamp: time.Now()}},
+		{name: "tombstone", node: &NodeInventory{Node: "node-3", Timestamp: time.Now(), Decommissioned: true, Revision: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := tc.node
+			n.normalize()
+			if err := signer.Sign(n); err != nil {
+				t.Fatal(err)
+			}
+			nodes, err := unmarshalInventoryProto(marshalInventoryProto([]*NodeInventory{n, n}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(nodes) != 2 {
+				t.Fatalf("decoded %d inventories, want 2", len(nodes))
+			}
+			got := nodes[0]
+			want, _ := json.Marshal(n)
+			if b, _ := json.Marshal(got); string(b) != string(want) {
+				t.Errorf("decoded inventory:\n%s\nwant:\n%s", b, want)
+			}
+			if !signatureValid(t, got) {
+				t.Error("signature invalid once decoded")
+			}
+		})
+	}
+}
+
+func TestInventoryNormalize(t *testing.T) {
+	n := testFullInventory()
+	n.normalize()
+	c := n.Containers[0]
+	for name, ts := range map[string]time.Time{
+		"timestamp":  n.Timestamp,
+		"created":    c.Created,
+		"startedAt":  *c.StartedAt,
+		"finishedAt": *c.FinishedAt,
+	} {
+		if ts.Location() != time.UTC {
+			t.Errorf("%s in %v, want UTC", name, ts.Location())
+		}
+	}
+	empty := &NodeInventory{}
+	if empty.normalize(); empty.Containers == nil {
+		t.Error("nil containers not normalized")
+	}
+}
+
+func FuzzUnmarshalInventoryProto(f *testing.F) {
+	f.Add(marshalInventoryProto([]*NodeInventory{testFullInventory()}))
+	f.Add(marshalInventoryProto([]*NodeInventory{{Node: "node", Timestamp: time.Unix(1, 2)}}))
+	f.Add([]byte{})
+	f.Add([]byte{0x0a, 0xff})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		nodes, err := unmarshalInventoryProto(b)
+		if err != nil {
+			return
+		}
+		// Whatever was decoded is encoded again as decoded.
+		again, err := unmarshalInventoryProto(marshalInventoryProto(nodes))
+		if err != nil {
+			t.Fatalf("decoding the encoded inventories: %v", err)
+		}
+		want, _ := json.Marshal(nodes)
+		if got, _ := json.Marshal(again); string(got) != string(want) {
+			t.Errorf("inventories changed by a round trip:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestInventoryMarshalLegacyPeers(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		i := newInventory()
+		i.legacyPeers = func() bool { return legacy }
+		b, err := i.Set(&NodeInventory{Node: "node-1", Timestamp: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isJSON := len(b) > 0 && b[0] == '['; isJSON != legacy {
+			t.Errorf("legacy peers %v: update encoded as JSON = %v", legacy, isJSON)
+		}
+		state, err := i.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isJSON := len(state) > 0 && state[0] == '['; isJSON != legacy {
+			t.Errorf("legacy peers %v: state encoded as JSON = %v", legacy, isJSON)
+		}
+
+		// Both encodings are merged.
+		other := newInventory()
+		if err := other.Merge(state); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := other.Get("node-1"); !ok {
+			t.Errorf("legacy peers %v: node not merged", legacy)
+		}
+	}
+}
+
+func TestLegacyPeers(t *testing.T) {
+	newPeer := func() *cluster.Peer {
+		peer, err := cluster.Create(log.NewNopLogger(), prometheus.NewRegistry(), "127.0.0.1:0", "", nil, false, time.Minute, cluster.DefaultGossipInterval, time.Second, time.Second, time.Second, nil, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { peer.Leave(time.Second) })
+		return peer
+	}
+	m := &Manager{cfg: DefaultConfig(), facts: newNodeState[*NodeFacts](), peer: newPeer()}
+	if !m.legacyPeers() {
+		t.Error("members without facts not considered legacy")
+	}
+	if _, err := m.facts.Set(&NodeFacts{Node: "node-1", Peer: m.peer.Name(), InventoryProto: true}); err != nil {
+		t.Fatal(err)
+	}
+	if m.legacyPeers() {
+		t.Error("members all advertising protobuf considered legacy")
+	}
+
+	other := newPeer()
+	mlist, err := memberlistOf(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mlist.Join([]string{m.peer.Self().Address()}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(m.peer.Peers()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !m.legacyPeers() {
+		t.Error("member not advertising protobuf not considered legacy")
+	}
+	if _, err := m.facts.Set(&NodeFacts{Node: "node-2", Peer: other.Name(), InventoryProto: true}); err != nil {
+		t.Fatal(err)
+	}
+	if m.legacyPeers() {
+		t.Error("members all advertising protobuf considered legacy")
+	}
+}
@@ -0,0 +1,114 @@
+package containerslist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// Scheduled jobs.
+const (
+	jobGC       = "gc"
+	jobReport   = "report"
+	jobSnapshot = "snapshot"
+	jobSBOM     = "sbom"
+	jobExport   = "export"
+	jobHandoff  = "handoff"
+)
+
+// defaultSchedules are the schedules of the jobs which are not overridden
+// with the -schedule flag.
+var defaultSchedules = map[string]string{
+	jobGC:       "*/10 * * * *",
+	jobReport:   "@hourly",
+	jobSnapshot: "*/15 * * * *",
+	jobSBOM:     "*/5 * * * *",
+	jobExport:   "@hourly",
+	jobHandoff:  "* * * * *",
+}
+
+// setupJobs schedules the periodic jobs. The snapshot job is only scheduled
+// when a snapshot file is configured, the SBOM job when SBOMs are fetched,
+// and the export job when an export bucket is configured.
+func (m *Manager) setupJobs() error {
+	jobs := map[string]func(context.Context) error{
+		jobGC:      m.gcJob,
+		jobReport:  m.reportJob,
+		jobHandoff: m.handoffJob,
+	}
+	if m.cfg.SnapshotFile != "" {
+		jobs[jobSnapshot] = m.snapshotJob
+	}
+	if m.cfg.FetchSBOMs {
+		jobs[jobSBOM] = m.sbomJob
+	}
+	if m.exporter != nil {
+		jobs[jobExport] = m.exportJob
+	}
+	for name, run := range jobs {
+		spec, ok := m.cfg.Schedules[name]
+		if !ok {
+			spec = defaultSchedules[name]
+		}
+		if err := m.scheduler.Add(name, spec, run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gcJob drops the expired state kept by the agent.
+func (m *Manager) gcJob(context.Context) error {
+	m.idempotency.Purge(time.Now())
+	return nil
+}
+
+// reportJob logs a summary of the cluster inventory.
+func (m *Manager) reportJob(context.Context) error {
+	nodes := m.inventory.Nodes()
+	var containers, running, stale int
+	for _, n := range nodes {
+		containers += len(n.Containers)
+		for _, c := range n.Containers {
+			if c.State == StateRunning {
+				running++
+			}
+		}
+		if time.Since(n.Timestamp) > m.cfg.StaleAfter {
+			stale++
+		}
+	}
+	level.Info(m.logger).Log("msg", "Cluster report", "members", m.peer.ClusterSize(), "nodes", len(nodes), "stale_nodes", stale, "containers", containers, "running", running)
+	return nil
+}
+
+// snapshotJob writes the cluster inventory to the snapshot file, in the
+// stable format of the containers API.
+func (m *Manager) snapshotJob(context.Context) error {
+	b, err := stableInventory(m.inventory.Nodes())
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(m.cfg.SnapshotFile, b)
+}
+
+// writeFileAtomically writes a file through a temporary file, only readable
+// by its owner, renamed over it, so that readers never see a partial file.
+func writeFileAtomically(file string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
@@ -0,0 +1,179 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hashicorp/memberlist"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// parseGossipKeys parses a comma-separated list of base64-encoded AES keys of
+// 16, 24 or 32 bytes, the first one being the primary key.
+func parseGossipKeys(v string) ([][]byte, error) {
+	var keys [][]byte
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gossip encryption key: %w", err)
+		}
+		if err := memberlist.ValidateKey(key); err != nil {
+			return nil, fmt.Errorf("invalid gossip encryption key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no gossip encryption key")
+	}
+	return keys, nil
+}
+
+// gossipKeyring holds the keys encrypting the gossip of the node. The primary
+// key encrypts the messages, which are decrypted with any of the keys. The
+// keys are read again by Run, so that they are rotated without restarts:
+// the new key is first added to the keys of every node, then made primary,
+// and finally the previous key is removed.
+type gossipKeyring struct {
+	// ref is the reference of the keys, such as vault://<path>, resolved
+	// again by Run. The keys are not reloaded when they were set as is.
+	ref     string
+	keyring *memberlist.Keyring
+
+	reloads *prometheus.CounterVec
+}
+
+func newGossipKeyring(v, ref string, reg prometheus.Registerer) (*gossipKeyring, error) {
+	keys, err := parseGossipKeys(v)
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := memberlist.NewKeyring(keys[1:], keys[0])
+	if err != nil {
+		return nil, err
+	}
+	k := &gossipKeyring{
+		ref:     ref,
+		keyring: keyring,
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_gossip_keyring_reloads_total",
+			Help: "Number of reloads of the gossip encryption keys, by result: unchanged, rotated or failure.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(k.reloads)
+	return k, nil
+}
+
+// install makes the memberlist of a peer encrypt its gossip with the keyring.
+// The cluster package of Alertmanager does not take a keyring, so it is set
+// on the configuration of the memberlist once created, before joining the
+// peers: until then, the messages of encrypting peers are dropped.
+func (k *gossipKeyring) install(peer *cluster.Peer) error {
+	mlist, err := memberlistOf(peer)
+	if err != nil {
+		return err
+	}
+	f := reflect.ValueOf(mlist).Elem().FieldByName("config")
+	if !f.IsValid() || f.Type() != reflect.TypeOf((*memberlist.Config)(nil)) || f.IsNil() {
+		return errors.New("configuration of the memberlist not found")
+	}
+	config := (*memberlist.Config)(unsafe.Pointer(f.Pointer()))
+	config.Keyring = k.keyring
+	return nil
+}
+
+// update installs the keys, the first one being the primary key, and removes
+// the others. It reports whether the keys changed.
+func (k *gossipKeyring) update(keys [][]byte) (bool, error) {
+	changed := !bytes.Equal(k.keyring.GetPrimaryKey(), keys[0])
+	for _, key := range keys {
+		if !containsKey(k.keyring.GetKeys(), key) {
+			if err := k.keyring.AddKey(key); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+	if err := k.keyring.UseKey(keys[0]); err != nil {
+		return false, err
+	}
+	var removed [][]byte
+	for _, key := range k.keyring.GetKeys() {
+		if !containsKey(keys, key) {
+			removed = append(removed, key)
+		}
+	}
+	for _, key := range removed {
+		if err := k.keyring.RemoveKey(key); err != nil {
+			return false, err
+		}
+	}
+	return changed || len(removed) > 0, nil
+}
+
+func containsKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload resolves the reference of the keys again and updates the keyring.
+func (k *gossipKeyring) reload(ctx context.Context) (bool, error) {
+	v, err := resolveSecret(ctx, k.ref)
+	if err != nil {
+		return false, fmt.Errorf("unable to resolve gossip encryption keys: %w", err)
+	}
+	keys, err := parseGossipKeys(v)
+	if err != nil {
+		return false, err
+	}
+	return k.update(keys)
+}
+
+// Run reloads the keys every interval until ctx is done. It returns at once
+// when the keys were not given as a reference.
+func (k *gossipKeyring) Run(ctx context.Context, interval time.Duration, logger log.Logger) {
+	if !isSecretRef(k.ref) {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloadCtx, cancel := context.WithTimeout(ctx, secretResolveTimeout)
+		changed, err := k.reload(reloadCtx)
+		cancel()
+		switch {
+		case err != nil:
+			k.reloads.WithLabelValues("failure").Inc()
+			level.Error(logger).Log("msg", "Unable to reload the gossip encryption keys", "error", err)
+		case changed:
+			k.reloads.WithLabelValues("rotated").Inc()
+			level.Info(logger).Log("msg", "Gossip encryption keys rotated", "keys", len(k.keyring.GetKeys()))
+		default:
+			k.reloads.WithLabelValues("unchanged").Inc()
+		}
+	}
+}
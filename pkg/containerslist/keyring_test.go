@@ -0,0 +1,145 @@
+package containerslist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testGossipKey returns a base64-encoded AES-128 key made of the byte b.
+func testGossipKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 16))
+}
+
+func TestParseGossipKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		v       string
+		want    int
+		wantErr bool
+	}{
+		{name: "one key", v: testGossipKey(1), want: 1},
+		{name: "several keys", v: testGossipKey(1) + ", " + testGossipKey(2), want: 2},
+		{name: "AES-256", v: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)), want: 1},
+		{name: "empty", v: " , ", wantErr: true},
+		{name: "not base64", v: "not a key!", wantErr: true},
+		{name: "bad length", v: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := parseGossipKeys(tc.v)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != tc.want {
+				t.Errorf("got %d keys, want %d", len(keys), tc.want)
+			}
+		})
+	}
+}
+
+func TestGossipKeyringUpdate(t *testing.T) {
+	k, err := newGossipKeyring(testGossipKey(1), "", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		keys        []byte
+		wantPrimary byte
+		wantChanged bool
+	}{
+		// A rotation from key 1 to key 2.
+		{keys: []byte{1}, wantPrimary: 1},
+		{keys: []byte{1, 2}, wantPrimary: 1, wantChanged: true},
+		{keys: []byte{2, 1}, wantPrimary: 2, wantChanged: true},
+		{keys: []byte{2}, wantPrimary: 2, wantChanged: true},
+		{keys: []byte{2}, wantPrimary: 2},
+		// A replacement of all the keys at once.
+		{keys: []byte{3, 4}, wantPrimary: 3, wantChanged: true},
+	} {
+		t.Run(fmt.Sprint(step.keys), func(t *testing.T) {
+			var keys [][]byte
+			for _, b := range step.keys {
+				keys = append(keys, bytes.Repeat([]byte{b}, 16))
+			}
+			changed, err := k.update(keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != step.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, step.wantChanged)
+			}
+			if got := k.keyring.GetPrimaryKey()[0]; got != step.wantPrimary {
+				t.Errorf("primary key %d, want %d", got, step.wantPrimary)
+			}
+			if got := len(k.keyring.GetKeys()); got != len(keys) {
+				t.Errorf("got %d keys, want %d", got, len(keys))
+			}
+		})
+	}
+}
+
+func TestGossipKeyringInstall(t *testing.T) {
+	// newPeer returns a peer encrypting its gossip with keys, if any.
+	newPeer := func(keys string) *cluster.Peer {
+		peer, err := cluster.Create(log.NewNopLogger(), prometheus.NewRegistry(), "127.0.0.1:0", "", nil, false, time.Minute, cluster.DefaultGossipInterval, time.Second, time.Second, time.Second, nil, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { peer.Leave(time.Second) })
+		if keys != "" {
+			k, err := newGossipKeyring(keys, "", prometheus.NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := k.install(peer); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return peer
+	}
+	seed := newPeer(testGossipKey(1) + "," + testGossipKey(2))
+	seedList, err := memberlistOf(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := seedList.LocalNode().Address()
+
+	for _, tc := range []struct {
+		name     string
+		keys     string
+		wantJoin bool
+	}{
+		{name: "same primary key", keys: testGossipKey(1), wantJoin: true},
+		{name: "rotated primary key", keys: testGossipKey(2) + "," + testGossipKey(1), wantJoin: true},
+		{name: "secondary key only", keys: testGossipKey(2)},
+		{name: "unknown key", keys: testGossipKey(3)},
+		{name: "not encrypted"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mlist, err := memberlistOf(newPeer(tc.keys))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = mlist.Join([]string{addr})
+			if tc.wantJoin && err != nil {
+				t.Fatalf("unable to join: %v", err)
+			}
+			if !tc.wantJoin && (err == nil || !strings.Contains(err.Error(), "1 error")) {
+				t.Fatalf("joined the encrypted peer: %v", err)
+			}
+		})
+	}
+}
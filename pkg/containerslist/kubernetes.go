@@ -0,0 +1,154 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Labels set on containers by the kubelet, whatever the CRI runtime.
+const (
+	labelPodName       = "io.kubernetes.pod.name"
+	labelPodNamespace  = "io.kubernetes.pod.namespace"
+	labelPodUID        = "io.kubernetes.pod.uid"
+	labelContainerName = "io.kubernetes.container.name"
+
+	// labelPodTemplateHash is the suffix added by Deployments to the name of
+	// their ReplicaSets.
+	labelPodTemplateHash = "pod-template-hash"
+
+	defaultKubeletURL       = "https://127.0.0.1:10250"
+	defaultKubeletTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Pod describes the Kubernetes pod a container belongs to.
+type Pod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+	Container string `json:"container"`
+	OwnerKind string `json:"ownerKind,omitempty"`
+	OwnerName string `json:"ownerName,omitempty"`
+}
+
+// Workload returns the name of the workload owning the pod, or the pod
+// itself for bare pods.
+func (p *Pod) Workload() string {
+	if p.OwnerKind == "" {
+		return "Pod/" + p.Name
+	}
+	return p.OwnerKind + "/" + p.OwnerName
+}
+
+// podOwner is the workload owning a pod.
+type podOwner struct {
+	kind string
+	name string
+}
+
+// kubeletClient fetches the pods running on the local node from the kubelet
+// API.
+type kubeletClient struct {
+	client    *http.Client
+	url       string
+	tokenFile string
+}
+
+func newKubeletClient(url, tokenFile string, insecureSkipVerify bool) *kubeletClient {
+	return &kubeletClient{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+			},
+		},
+		url:       strings.TrimSuffix(url, "/"),
+		tokenFile: tokenFile,
+	}
+}
+
+// PodOwners returns the owner workload of the local pods, by pod UID.
+func (k *kubeletClient) PodOwners(ctx context.Context) (map[string]podOwner, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url+"/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+	if k.tokenFile != "" {
+		token, err := os.ReadFile(k.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read kubelet token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubelet API: unexpected status %s", resp.Status)
+	}
+
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				UID             string            `json:"uid"`
+				Labels          map[string]string `json:"labels"`
+				OwnerReferences []struct {
+					Kind       string `json:"kind"`
+					Name       string `json:"name"`
+					Controller bool   `json:"controller"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]podOwner, len(pods.Items))
+	for _, p := range pods.Items {
+		for _, o := range p.Metadata.OwnerReferences {
+			if !o.Controller {
+				continue
+			}
+			owner := podOwner{kind: o.Kind, name: o.Name}
+			// Resolve the Deployment of a ReplicaSet without querying the API
+			// server, from the pod template hash suffix.
+			if hash := p.Metadata.Labels[labelPodTemplateHash]; o.Kind == "ReplicaSet" && hash != "" {
+				if name, ok := strings.CutSuffix(o.Name, "-"+hash); ok {
+					owner = podOwner{kind: "Deployment", name: name}
+				}
+			}
+			owners[p.Metadata.UID] = owner
+		}
+	}
+	return owners, nil
+}
+
+// attachPods fills in the pod of the containers started by the kubelet.
+func attachPods(containers []Container, owners map[string]podOwner) {
+	for i := range containers {
+		c := &containers[i]
+		uid := c.Labels[labelPodUID]
+		if uid == "" {
+			continue
+		}
+		c.Pod = &Pod{
+			Name:      c.Labels[labelPodName],
+			Namespace: c.Labels[labelPodNamespace],
+			UID:       uid,
+			Container: c.Labels[labelContainerName],
+		}
+		if o, ok := owners[uid]; ok {
+			c.Pod.OwnerKind = o.kind
+			c.Pod.OwnerName = o.name
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPodOwners(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pods" || r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"uid": "u1", "labels": {"pod-template-hash": "5d4f8c"}, "ownerReferences": [{"kind": "ReplicaSet", "name": "web-5d4f8c", "controller": true}]}},
+			{"metadata": {"uid": "u2", "ownerReferences": [{"kind": "ReplicaSet", "name": "batch-rs", "controller": true}]}},
+			{"metadata": {"uid": "u3", "ownerReferences": [{"kind": "StatefulSet", "name": "db", "controller": true}, {"kind": "Node", "name": "n1"}]}},
+			{"metadata": {"uid": "u4"}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	owners, err := newKubeletClient(srv.URL+"/", token, false).PodOwners(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]podOwner{
+		"u1": {kind: "Deployment", name: "web"},
+		"u2": {kind: "ReplicaSet", name: "batch-rs"},
+		"u3": {kind: "StatefulSet", name: "db"},
+	}
+	if !reflect.DeepEqual(owners, want) {
+		t.Errorf("owners = %+v, want %+v", owners, want)
+	}
+
+	if _, err := newKubeletClient(srv.URL, "", false).PodOwners(context.Background()); err == nil {
+		t.Error("PodOwners without token succeeded, want an error")
+	}
+}
+
+func TestAttachPods(t *testing.T) {
+	containers := []Container{
+		{ID: "c1", Labels: map[string]string{labelPodUID: "u1", labelPodName: "web-5d4f8c-x2x9z", labelPodNamespace: "shop", labelContainerName: "nginx"}},
+		{ID: "c2", Labels: map[string]string{labelPodUID: "u4", labelPodName: "debug", labelPodNamespace: "default", labelContainerName: "sh"}},
+		{ID: "c3"},
+	}
+	attachPods(containers, map[string]podOwner{"u1": {kind: "Deployment", name: "web"}})
+
+	want := []*Pod{
+		{Name: "web-5d4f8c-x2x9z", Namespace: "shop", UID: "u1", Container: "nginx", OwnerKind: "Deployment", OwnerName: "web"},
+		{Name: "debug", Namespace: "default", UID: "u4", Container: "sh"},
+		nil,
+	}
+	for i, c := range containers {
+		if !reflect.DeepEqual(c.Pod, want[i]) {
+			t.Errorf("pod of %s = %+v, want %+v", c.ID, c.Pod, want[i])
+		}
+	}
+	if got := containers[0].Pod.Workload(); got != "Deployment/web" {
+		t.Errorf("workload = %q, want Deployment/web", got)
+	}
+	if got := containers[1].Pod.Workload(); got != "Pod/debug" {
+		t.Errorf("workload = %q, want Pod/debug", got)
+	}
+}
@@ -0,0 +1,82 @@
+package containerslist
+
+import (
+	"sort"
+)
+
+// limit caps the containers of a node to a number of containers and to a
+// memory budget, estimated from their gossiped encoding. Running containers
+// and then the most recent ones are kept; the dropped ones are summarized as
+// counts per image.
+func (n *NodeInventory) limit(maxContainers int, budget int64) {
+	sizes := make([]int64, len(n.Containers))
+	var total int64
+	if budget > 0 {
+		for i := range n.Containers {
+			sizes[i] = int64(len(encodeContainer(&n.Containers[i]))) + 4
+			total += sizes[i]
+		}
+	}
+	keep := len(n.Containers)
+	if maxContainers > 0 && keep > maxContainers {
+		keep = maxContainers
+	}
+	if budget <= 0 || total <= budget {
+		if keep == len(n.Containers) {
+			return
+		}
+	}
+
+	containers := append([]Container{}, n.Containers...)
+	order := make([]int, len(containers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := containers[order[a]], containers[order[b]]
+		if (ca.State == StateRunning) != (cb.State == StateRunning) {
+			return ca.State == StateRunning
+		}
+		return ca.Created.After(cb.Created)
+	})
+
+	var used int64
+	kept := make([]Container, 0, keep)
+	counts := map[string]int{}
+	for _, i := range order {
+		c := containers[i]
+		if len(kept) < keep && (budget <= 0 || used+sizes[i] <= budget) {
+			kept = append(kept, c)
+			used += sizes[i]
+			continue
+		}
+		counts[c.Image]++
+	}
+
+	n.TotalContainers = len(containers)
+	n.Containers = kept
+	n.Truncated = true
+	n.ImageCounts = counts
+}
+
+// summary returns a copy of the inventory of a node only counting its
+// containers per image, as kept for the nodes of the shards which are not
+// aggregated locally.
+func (n *NodeInventory) summary() *NodeInventory {
+	res := *n
+	res.Containers = nil
+	res.Summary = true
+	res.Truncated = true
+	res.TotalContainers = len(n.Containers)
+	res.ImageCounts = map[string]int{}
+	if n.Truncated {
+		res.TotalContainers = n.TotalContainers
+		for image, count := range n.ImageCounts {
+			res.ImageCounts[image] = count
+		}
+	}
+	for _, c := range n.Containers {
+		res.ImageCounts[c.Image]++
+	}
+	return &res
+}
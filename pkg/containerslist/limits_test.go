@@ -0,0 +1,63 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInventoryLimit(t *testing.T) {
+	now := time.Now()
+	containers := func() []Container {
+		return []Container{
+			{ID: "old", Image: "busybox", State: "exited", Created: now.Add(-3 * time.Hour)},
+			{ID: "web", Image: "nginx", State: StateRunning, Created: now.Add(-2 * time.Hour)},
+			{ID: "recent", Image: "busybox", State: "exited", Created: now.Add(-time.Hour)},
+			{ID: "api", Image: "api", State: StateRunning, Created: now.Add(-4 * time.Hour)},
+		}
+	}
+	size := int64(len(encodeContainer(&containers()[1]))) + 4
+
+	for _, tc := range []struct {
+		name          string
+		maxContainers int
+		budget        int64
+		wantIDs       []string
+		wantCounts    map[string]int
+	}{
+		{name: "unlimited", wantIDs: []string{"old", "web", "recent", "api"}},
+		{name: "under the limits", maxContainers: 4, budget: 100 * size, wantIDs: []string{"old", "web", "recent", "api"}},
+		{name: "max containers", maxContainers: 3, wantIDs: []string{"web", "api", "recent"}, wantCounts: map[string]int{"busybox": 1}},
+		{name: "running first", maxContainers: 1, wantIDs: []string{"web"}, wantCounts: map[string]int{"busybox": 2, "api": 1}},
+		{name: "memory budget", budget: 2*size + 1, wantIDs: []string{"web", "api"}, wantCounts: map[string]int{"busybox": 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &NodeInventory{Node: "a", Containers: containers()}
+			n.limit(tc.maxContainers, tc.budget)
+			var ids []string
+			for _, c := range n.Containers {
+				ids = append(ids, c.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIDs) {
+				t.Errorf("containers = %v, want %v", ids, tc.wantIDs)
+			}
+			if truncated := tc.wantCounts != nil; n.Truncated != truncated {
+				t.Errorf("truncated = %v, want %v", n.Truncated, truncated)
+			}
+			if tc.wantCounts != nil {
+				if !reflect.DeepEqual(n.ImageCounts, tc.wantCounts) || n.TotalContainers != 4 {
+					t.Errorf("image counts = %v of %d containers, want %v of 4", n.ImageCounts, n.TotalContainers, tc.wantCounts)
+				}
+			}
+		})
+	}
+
+	i := newInventory()
+	i.maxContainers = 2
+	if _, err := i.Set(&NodeInventory{Node: "a", Timestamp: now, Containers: containers()}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := i.Get("a"); len(n.Containers) != 2 || !n.Truncated {
+		t.Errorf("inventory = %+v, want 2 containers, truncated", n)
+	}
+}
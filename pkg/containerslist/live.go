@@ -0,0 +1,90 @@
+package containerslist
+
+// liveTpl refreshes the containers table of the index page in place, at the
+// interval chosen by the user and kept in the local storage, highlighting
+// with a fading animation the cells changed since the previous refresh and
+// the rows which appeared. The cells marked data-volatile, such as the
+// statuses counting the uptime, are not compared.
+const liveTpl = `<style>
+      @keyframes changed { from { background-color: #ffe066; } to { background-color: transparent; } }
+      @keyframes appeared { from { background-color: #b2f2bb; } to { background-color: transparent; } }
+      #containers td.changed { animation: changed 4s ease-out; }
+      #containers tr.appeared td { animation: appeared 4s ease-out; }
+      @media (prefers-reduced-motion: reduce) {
+        #containers td.changed, #containers tr.appeared td { animation: none; outline: 1px dashed #f08c00; }
+      }
+    </style>
+    <p>
+      <label>{{ t "Auto-refresh:" }}
+        <select id="live-interval">
+          <option value="0">{{ t "off" }}</option>
+          <option value="5">5s</option>
+          <option value="15">15s</option>
+          <option value="60">1m</option>
+        </select>
+      </label>
+    </p>
+    <script>
+      (function() {
+        var interval = document.getElementById("live-interval");
+        var timer = null;
+        function rows(table) {
+          var byKey = {};
+          table.querySelectorAll("tr[data-key]").forEach(function(tr) { byKey[tr.dataset.key] = tr; });
+          return byKey;
+        }
+        function highlight(prev, next) {
+          var before = rows(prev);
+          next.querySelectorAll("tr[data-key]").forEach(function(tr) {
+            var old = before[tr.dataset.key];
+            if (!old) {
+              tr.classList.add("appeared");
+              return;
+            }
+            Array.prototype.forEach.call(tr.cells, function(td, i) {
+              var was = old.cells[i];
+              if (!td.hasAttribute("data-volatile") && (!was || was.textContent != td.textContent)) {
+                td.classList.add("changed");
+              }
+            });
+          });
+        }
+        function refresh() {
+          var active = document.activeElement;
+          if (document.hidden || (active && active.form)) {
+            return;
+          }
+          fetch(location.href, { headers: { "Accept": "text/html" } }).then(function(res) {
+            return res.ok ? res.text() : Promise.reject(res.status);
+          }).then(function(html) {
+            var doc = new DOMParser().parseFromString(html, "text/html");
+            var prev = document.getElementById("containers"), next = doc.getElementById("containers");
+            if (!prev || !next) {
+              return;
+            }
+            highlight(prev, next);
+            prev.replaceWith(document.adoptNode(next));
+            var pagers = doc.querySelectorAll(".pager");
+            document.querySelectorAll(".pager").forEach(function(p, i) {
+              if (pagers[i]) {
+                p.replaceWith(document.adoptNode(pagers[i]));
+              }
+            });
+          }).catch(function() {});
+        }
+        function schedule() {
+          clearInterval(timer);
+          timer = null;
+          var seconds = parseInt(interval.value, 10);
+          if (seconds > 0) {
+            timer = setInterval(refresh, seconds * 1000);
+            localStorage.setItem("liveInterval", interval.value);
+          } else {
+            localStorage.removeItem("liveInterval");
+          }
+        }
+        interval.value = localStorage.getItem("liveInterval") || "0";
+        interval.onchange = schedule;
+        schedule();
+      })();
+    </script>`
@@ -0,0 +1,40 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestIndexLiveRefresh(t *testing.T) {
+	m := newTestDecommissionManager(t, &NodeInventory{Node: "a", Containers: []Container{{ID: "a1", Name: "web", State: StateRunning, Status: "Up 5 minutes"}}})
+	m.facts = newNodeState[*NodeFacts]()
+	m.maintenance = newResourceState[MaintenanceWindow]()
+	m.ephemeralAnnotations = newResourceState[EphemeralAnnotation]()
+	m.clock = newClockState(m.peer.Name(), time.Second, log.NewNopLogger())
+	m.partition = newPartitionDetector(func() []string { return []string{m.peer.Name()} }, func() map[string]bool { return nil }, 1, prometheus.NewRegistry())
+	m.history = newHistory(newHistoryTier(tierRaw, time.Minute, time.Hour))
+	tpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.http(tpl).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	page := rec.Body.String()
+	if strings.Contains(page, "Error:") || !strings.HasSuffix(strings.TrimSpace(page), "</html>") {
+		t.Fatalf("page not rendered: %s", page)
+	}
+	// The rows are matched by key across refreshes, and the statuses are
+	// not compared.
+	for _, want := range []string{`<table id="containers">`, `<tr data-key="a/a1">`, `<td data-volatile>Up 5 minutes`, `id="live-interval"`, `<p class="pager">`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+}
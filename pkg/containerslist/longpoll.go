@@ -0,0 +1,57 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxLongPollWait is the longest a long polling request waits for the
+// inventory to change.
+const maxLongPollWait = 5 * time.Minute
+
+// inventoryVersionHeader is the header of the responses of the endpoints
+// derived from the inventory, holding its version.
+const inventoryVersionHeader = "X-Inventory-Version"
+
+// longPollParams document the long polling query parameters.
+var longPollParams = []apiParam{
+	{Name: "since", Description: "Version of the inventory, from the " + inventoryVersionHeader + " header of a previous response; with wait, the response is delayed until the version differs"},
+	{Name: "wait", Description: "Longest time to wait for the version of the inventory to differ from since, such as 30s; at most " + maxLongPollWait.String()},
+}
+
+// longPolled serves the requests of an endpoint derived from the inventory
+// with h, setting the version of the inventory in the response. With the
+// wait and since query parameters, the request is held until the version
+// advances past since or the wait elapses, letting simple clients get near
+// real-time updates without streaming. Versions are local to each member.
+func (m *Manager) longPolled(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if v := q.Get("wait"); v != "" {
+			wait, err := time.ParseDuration(v)
+			if err != nil || wait <= 0 || wait > maxLongPollWait {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid wait %q: must be a positive duration of at most %s", v, maxLongPollWait))
+				return
+			}
+			since, err := strconv.ParseUint(q.Get("since"), 10, 64)
+			if err != nil {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "invalid since %q: must be a version of the inventory", q.Get("since")))
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			m.inventory.Wait(ctx, since)
+			cancel()
+			if r.Context().Err() != nil {
+				return
+			}
+			// The revision set by withRevision predates the wait.
+			w.Header().Set(clusterRevisionHeader, strconv.FormatUint(m.inventory.Revision(), 10))
+		}
+		// The version is read before serving the request, so that a change
+		// in between is not missed by the next request.
+		w.Header().Set(inventoryVersionHeader, strconv.FormatUint(m.inventory.Version(), 10))
+		h.ServeHTTP(w, r)
+	})
+}
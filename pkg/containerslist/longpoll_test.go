@@ -0,0 +1,79 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInventoryWait(t *testing.T) {
+	i := newInventory()
+	v := i.Version()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if got := i.Wait(ctx, v); got != v {
+		t.Errorf("version after the timeout = %d, want %d", got, v)
+	}
+
+	done := make(chan uint64)
+	go func() { done <- i.Wait(context.Background(), v) }()
+	if _, err := i.Set(&NodeInventory{Node: "a", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-done:
+		if got != v+1 {
+			t.Errorf("version after a change = %d, want %d", got, v+1)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait not woken up by a change")
+	}
+
+	// The statuses alone do not change the version.
+	if _, err := i.Set(&NodeInventory{Node: "b", Timestamp: time.Now(), Containers: []Container{{ID: "b1", State: "running", Status: "Up 1 minute"}}}); err != nil {
+		t.Fatal(err)
+	}
+	v = i.Version()
+	if _, err := i.Set(&NodeInventory{Node: "b", Timestamp: time.Now().Add(time.Second), Containers: []Container{{ID: "b1", State: "running", Status: "Up 2 minutes"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := i.Version(); got != v {
+		t.Errorf("version after a status change = %d, want %d", got, v)
+	}
+}
+
+func TestLongPolled(t *testing.T) {
+	m := newTestInventoryManager(t, &NodeInventory{Node: "a"})
+	h := m.longPolled(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers?"+query, nil))
+		return rec
+	}
+
+	rec := get("")
+	version := rec.Header().Get(inventoryVersionHeader)
+	if version != strconv.FormatUint(m.inventory.Version(), 10) {
+		t.Errorf("version = %q, want %d", version, m.inventory.Version())
+	}
+
+	// An outdated version is answered immediately.
+	start := time.Now()
+	if rec := get("wait=1m&since=0"); rec.Code != http.StatusOK || time.Since(start) > 5*time.Second {
+		t.Errorf("status of an outdated version = %d after %s, want 200 at once", rec.Code, time.Since(start))
+	}
+	// The current one is held until the wait elapses.
+	start = time.Now()
+	if rec := get("wait=50ms&since=" + version); rec.Header().Get(inventoryVersionHeader) != version || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("version %q after %s, want %s after the wait", rec.Header().Get(inventoryVersionHeader), time.Since(start), version)
+	}
+
+	for _, query := range []string{"wait=soon&since=1", "wait=-1s&since=1", "wait=1h&since=1", "wait=1s", "wait=1s&since=latest"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("status of %s = %d, want 400", query, rec.Code)
+		}
+	}
+}
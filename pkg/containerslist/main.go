@@ -0,0 +1,980 @@
+// Package containerslist lists the containers of a cluster of Docker hosts,
+// gossiping their inventories between the nodes. It is run by the
+// containerslist command, and can be embedded with NewManager, serving the
+// manager with web.NewHandler.
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/olivierlemasle/containerslist/pkg/web"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+	gocollectors "github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	defaultHttpListenAddress = "0.0.0.0:3000"
+
+	defaultClusterAddress     = "0.0.0.0:9094"
+	defaultPeerTimeout        = 15 * time.Second
+	defaultGossipInterval     = 200 * time.Millisecond
+	defaultConfigPollInterval = time.Minute
+
+	defaultCollectInterval = 10 * time.Second
+	defaultExitedRetention = time.Hour
+
+	defaultStaleAfter = time.Minute
+
+	defaultEdgeBufferSize = 1000
+
+	defaultPruneStoppedAfter = 7 * 24 * time.Hour
+
+	defaultPortsRange               = "32768-60999"
+	defaultPortsExhaustionThreshold = 0.8
+
+	defaultIdempotencyTTL = 10 * time.Minute
+	defaultGatewayTimeout = 2 * time.Second
+
+	defaultClockSkewThreshold = 5 * time.Second
+
+	defaultDecommissionTimeout = 30 * time.Second
+
+	defaultSchedulerJitter = 10 * time.Second
+
+	defaultBreakerThreshold   = 5
+	defaultBreakerOpenTimeout = 30 * time.Second
+
+	defaultShutdownDrainTimeout      = 15 * time.Second
+	defaultShutdownCollectorsTimeout = 5 * time.Second
+	defaultShutdownLeaveTimeout      = 10 * time.Second
+
+	defaultHistoryResolution            = time.Minute
+	defaultHistoryRetention             = 24 * time.Hour
+	defaultHistoryDownsampledResolution = 5 * time.Minute
+	defaultHistoryDownsampledRetention  = 30 * 24 * time.Hour
+
+	tpl = `<!DOCTYPE html>
+<html lang="{{ lang }}">
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    {{ if .Partition.Minority }}
+    <p><strong>{{ t "Warning: this node only sees %d of %d expected members and is likely in a minority partition; its view of the cluster may be incomplete." .Partition.Members .Partition.Expected }}</strong></p>
+    {{ end }}
+    {{ with .Update }}{{ if .Available }}
+    <p><strong>{{ t "Update available: %s, running %s." .Latest .Current }}</strong>{{ with .Notes }} <a href="{{ . }}">{{ t "Release notes" }}</a>{{ end }}</p>
+    {{ end }}{{ end }}
+    {{ with .Alerts }}
+    <p><strong>{{ t "Self-alerts:" }}</strong></p>
+    <ul>
+    {{ range . }}
+      <li><strong>{{ .Severity }}</strong> {{ .Name }}: {{ .Summary }} <em>{{ .Remediation }}</em></li>
+    {{ end }}
+    </ul>
+    {{ end }}
+    {{ with .Failed }}
+    <p><strong>{{ t "Partial results: the following peers did not answer:" }}</strong> {{ range . }}<code>{{ . }}</code> {{ end }}</p>
+    {{ end }}
+    <p>{{ t "Node name: %s" .Name }}</p>
+    <p>{{ t "Status: %s" .Status }}</p>
+    <p>{{ t "Peers:" }}</p>
+    <ul>
+    {{ range .Peers }}
+      <li><details><summary><span title="{{ .Name }}">{{ index $.PeerNames .Name }}</span>: <code>{{ .Address }}</code></summary>
+      {{ with index $.PeerStats .Name }}
+        <dl>
+          <dt>{{ t "Nodes" }}</dt><dd>{{ range .Nodes }}{{ . }} {{ end }}</dd>
+          <dt>{{ t "Updates received" }}</dt><dd>{{ .UpdatesReceived }}</dd>
+          <dt>{{ t "Merge conflicts" }}</dt><dd>{{ .MergeConflicts }}</dd>
+          <dt>{{ t "Rejected payloads" }}</dt><dd>{{ .RejectedPayloads }}</dd>
+          <dt>{{ t "Last update" }}</dt><dd>{{ with .LastUpdate }}{{ t "%s ago" (since .UTC) }}{{ end }}</dd>
+          <dt>{{ t "State size" }}</dt><dd>{{ .StateBytes }} B</dd>
+        </dl>
+      {{ end }}
+      </details></li>
+    {{ end }}
+    </ul>
+    <p>{{ t "Nodes:" }}</p>
+    <ul>
+    {{ range .Nodes }}
+      <li>{{ .Node }}: {{ t "updated %.0fs ago" .AgeSeconds }}{{ if .CrossZone }} ({{ .Zone }}){{ end }}{{ if .Stale }} - <strong>{{ t "stale" }}</strong>{{ end }}{{ if .Offline }} - {{ t "last synced %s ago" (since .Timestamp) }}{{ end }}{{ if .Degraded }} - <strong>{{ t "degraded" }}</strong>{{ end }}{{ if .Truncated }} - <strong>{{ t "truncated, %d containers in total" .TotalContainers }}</strong>{{ end }}</li>
+    {{ end }}
+    </ul>
+    {{ with .Cost }}
+    <p>{{ t "Estimated cost: %.2f per hour" .Hourly }}</p>
+    <ul>
+    {{ range $i, $g := .Groups }}{{ if lt $i 5 }}
+      <li>{{ with $g.Group }}{{ . }}{{ else }}<em>{{ t "no %s label" $.Cost.Label }}</em>{{ end }}: {{ printf "%.2f" $g.Hourly }} ({{ $g.Containers }})</li>
+    {{ end }}{{ end }}
+    </ul>
+    {{ end }}
+    {{ with .History }}
+    <p>{{ t "Running containers:" }}</p>
+    <svg width="300" height="40"><polyline fill="none" stroke="black" points="{{ . }}"/></svg>
+    {{ end }}
+    <p>{{ t "Containers:" }} <a href="/?flag=restart_loop">{{ t "restart_loop" }}</a> | <a href="/?flag=oom_killed">{{ t "oom_killed" }}</a> | <a href="/?flag=long_uptime">{{ t "long_uptime" }}</a></p>
+    <form>
+      <input name="q" value="{{ .Query.Get "q" }}" placeholder="{{ t "name, image or node" }}">
+      <select name="state">
+      {{ range .States }}
+        <option value="{{ . }}"{{ if eq ($.Query.Get "state") . }} selected{{ end }}>{{ t . }}</option>
+      {{ end }}
+      </select>
+      <select name="sort">
+      {{ range .SortKeys }}
+        <option value="{{ . }}"{{ if eq ($.Query.Get "sort") . }} selected{{ end }}>{{ t "by %s" (t .) }}</option>
+        <option value="-{{ . }}"{{ if eq ($.Query.Get "sort") (print "-" .) }} selected{{ end }}>{{ t "by %s, descending" (t .) }}</option>
+      {{ end }}
+      </select>
+      {{ if .AllColumns }}
+      <input type="hidden" name="columns" value="">
+      {{ range .AllColumns }}
+      <label><input type="checkbox" name="columns" value="{{ .Header }}"{{ if $.Columns.Has .Header }} checked{{ end }}> {{ .Header }}</label>
+      {{ end }}
+      {{ end }}
+      {{ with .Query.Get "flag" }}<input type="hidden" name="flag" value="{{ . }}">{{ end }}
+      {{ with .Query.Get "per_page" }}<input type="hidden" name="per_page" value="{{ . }}">{{ end }}
+      <button type="submit">{{ t "Filter" }}</button>
+    </form>
+    {{ template "views" . }}
+    {{ template "live" }}
+    {{ template "pager" .Page }}
+    <table id="containers">
+      <thead><tr><th>{{ t "Node" }}</th><th>{{ t "Name" }}</th><th>{{ t "Image" }}</th><th>{{ t "State" }}</th><th>{{ t "Status" }}</th>{{ range $.Columns }}<th>{{ .Header }}</th>{{ end }}</tr></thead>
+    {{ range .Page.Chunks }}
+      <tbody style="content-visibility: auto; contain-intrinsic-size: auto calc({{ len . }} * 1.5em)">
+    {{ range . }}{{ $node := .Node }}
+      <tr data-key="{{ $node }}/{{ .ID }}"><td>{{ $node }}</td><td>{{ with .Pod }}{{ .Namespace }}/{{ .Name }} ({{ .Workload }}){{ else }}{{ .Name }}{{ end }}{{ with $.Annotations.For $node .ID }} <mark title="{{ t "expires in %s" (until .Expires) }}">{{ .Text }}</mark>{{ with .Link }} <a href="{{ . }}">{{ t "link" }}</a>{{ end }}{{ end }}</td><td><code>{{ .Image }}</code></td><td>{{ .State }}</td><td data-volatile>{{ .Status }}{{ range .Flags }} <strong>{{ t . }}</strong>{{ end }}</td>{{ range $.Columns.Render .Container }}<td>{{ . }}</td>{{ end }}</tr>
+    {{ end }}
+      </tbody>
+    {{ end }}
+    </table>
+    {{ template "pager" .Page }}
+    <p>
+      {{ t "Notify when containers matching" }} <input id="notify-filter" placeholder="{{ t "name, image or node" }}"> {{ t "appear or disappear:" }}
+      <button id="notify-toggle">{{ t "Enable notifications" }}</button>
+    </p>
+    <script>
+      (function() {
+        var filter = document.getElementById("notify-filter");
+        var toggle = document.getElementById("notify-toggle");
+        var source = null;
+        filter.value = localStorage.getItem("notifyFilter") || "";
+        filter.onchange = function() { localStorage.setItem("notifyFilter", filter.value); };
+        function start() {
+          source = new EventSource("/api/v1/events?format=native");
+          ["appeared", "disappeared"].forEach(function(type) {
+            source.addEventListener(type, function(e) {
+              var ev = JSON.parse(e.data), c = ev.container, f = filter.value.toLowerCase();
+              if (f && [c.name, c.image, ev.node].join(" ").toLowerCase().indexOf(f) < 0) {
+                return;
+              }
+              new Notification("Container " + c.name + " " + type, { body: c.image + " on " + ev.node });
+            });
+          });
+          toggle.textContent = {{ t "Disable notifications" }};
+          localStorage.setItem("notify", "on");
+        }
+        function stop() {
+          source.close();
+          source = null;
+          toggle.textContent = {{ t "Enable notifications" }};
+          localStorage.removeItem("notify");
+        }
+        toggle.onclick = function() {
+          if (source) {
+            stop();
+          } else if ("Notification" in window) {
+            Notification.requestPermission().then(function(p) { if (p === "granted") start(); });
+          }
+        };
+        if (localStorage.getItem("notify") && "Notification" in window && Notification.permission === "granted") {
+          start();
+        }
+      })();
+    </script>
+  </body>
+</html>
+`
+)
+
+// Main runs the containerslist command of the given version: the command of
+// its first argument, such as get or top, or else the node itself, configured
+// by the command-line flags.
+func Main(v string) {
+	version = v
+	if len(os.Args) > 1 {
+		if run, ok := cliCommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				if !errors.Is(err, flag.ErrHelp) {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	cfg := &Config{}
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	w := log.NewSyncWriter(os.Stderr)
+	logger := log.NewLogfmtLogger(w)
+
+	ctx := handleSignals(context.Background(), logger)
+	if err := cfg.ResolveSecrets(ctx); err != nil {
+		panic(err)
+	}
+	go cfg.reloadSecrets(ctx, logger)
+
+	m, err := NewManager(logger, cfg)
+	if err != nil {
+		panic(err)
+	}
+	handler, err := web.NewHandler(m, web.Options{Metrics: true, Resolve: true})
+	if err != nil {
+		panic(err)
+	}
+	internalHandler, err := newInternalHandler(m)
+	if err != nil {
+		panic(err)
+	}
+	go m.Run(ctx)
+
+	s := &http.Server{
+		Addr:    m.cfg.HTTPAddress,
+		Handler: handler,
+	}
+	m.limits.harden(s)
+	if m.cfg.HTTPTLSSPIFFE {
+		// Browsers and clients without an SVID still authenticate with
+		// tokens.
+		s.TLSConfig = m.internalCerts.serverConfig(tls.VerifyClientCertIfGiven)
+	}
+	servers := []*http.Server{s}
+	if m.cfg.InternalListenAddress != "" {
+		is := &http.Server{
+			Addr:      m.cfg.InternalListenAddress,
+			Handler:   internalHandler,
+			TLSConfig: m.internalTLS,
+		}
+		m.limits.harden(is)
+		go listenAndServe(is, true, logger)
+		servers = append(servers, is)
+	}
+
+	go listenAndServe(s, m.cfg.HTTPTLSSPIFFE, logger)
+
+	select {
+	case <-ctx.Done():
+	case <-m.quit:
+	}
+	level.Debug(m.logger).Log("Stopping")
+	m.shutdown(servers...)
+	if m.restartBinary != "" {
+		level.Info(m.logger).Log("msg", "Restarting on the installed update", "path", m.restartBinary)
+		if err := reexec(m.restartBinary); err != nil {
+			level.Error(m.logger).Log("msg", "Unable to restart on the installed update", "error", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// Manager collects the containers of the local nodes, shares them with the
+// cluster and answers the UI and the API.
+type Manager struct {
+	cfg          *Config
+	logger       log.Logger
+	registry     *prometheus.Registry
+	peer         *cluster.Peer
+	settleCancel context.CancelFunc
+	joinErr      error
+	clock        *clockState
+	membership   membershipTracker
+	internalTLS  *tls.Config
+	// internalCerts rotate the certificate of internalTLS.
+	internalCerts *rotatingCerts
+	router        *router
+	// signer signs the gossiped entries of the local node and verifies those
+	// of its peers.
+	signer *gossipSigner
+	// keyring encrypts the gossip, when -gossip_encryption_keys is set.
+	keyring *gossipKeyring
+	// internalAddress is the address of the internal API advertised to peers.
+	internalAddress string
+	// nodeID identifies the node in the gossiped state.
+	nodeID string
+	// fingerprint identifies the host of the node across hostname and node
+	// ID changes.
+	fingerprint string
+	partition   *partitionDetector
+
+	sources    []*dockerSource
+	sshSources []*sshSource
+	kubelet    *kubeletClient
+	health     *sourceHealth
+	inventory  *inventory
+	channel    cluster.ClusterChannel
+	events     *eventBroker
+	history    *history
+
+	networks        *resourceState[Network]
+	networksChannel cluster.ClusterChannel
+	volumes         *resourceState[Volume]
+	volumesChannel  cluster.ClusterChannel
+	images          *resourceState[Image]
+	imagesChannel   cluster.ClusterChannel
+	pruneCandidates *resourceState[PruneCandidate]
+	pruneChannel    cluster.ClusterChannel
+	facts           *nodeState[*NodeFacts]
+	factsChannel    cluster.ClusterChannel
+	usage           *nodeState[*NodeUsage]
+	usageChannel    cluster.ClusterChannel
+
+	containerUsage        *resourceState[ContainerUsage]
+	containerUsageChannel cluster.ClusterChannel
+
+	decommissionAcks    *nodeState[*DecommissionAck]
+	decommissionChannel cluster.ClusterChannel
+
+	ephemeralAnnotations        *resourceState[EphemeralAnnotation]
+	ephemeralAnnotationsChannel cluster.ClusterChannel
+	// ephemeralAnnotationsMtx serializes the updates of the ephemeral
+	// annotations of the local node.
+	ephemeralAnnotationsMtx sync.Mutex
+
+	maintenance        *resourceState[MaintenanceWindow]
+	maintenanceChannel cluster.ClusterChannel
+
+	// checksums are the checksums of the inventories of the nodes, gossiped
+	// by the peers collecting them.
+	checksums        *resourceState[InventoryChecksum]
+	checksumsChannel cluster.ClusterChannel
+	checksumVerifier *checksumVerifier
+	// conflicts are the containers reported by several nodes.
+	conflicts *conflictTracker
+	// maintenanceMtx serializes the updates of the maintenance windows of
+	// the local node.
+	maintenanceMtx sync.Mutex
+
+	// handoffs maps the renamed nodes to their new name.
+	handoffs    map[string]string
+	handoffsMtx sync.Mutex
+
+	collectors collectors
+	// quit is closed once the node is decommissioned, or an update is
+	// installed.
+	quit           chan struct{}
+	quitOnce       sync.Once
+	decommissioned atomic.Bool
+
+	idempotency *idempotencyStore
+	// apiKeys are the API keys of the node, nil when disabled.
+	apiKeys *apiKeyStore
+	// savedViews are the views of the UI saved on the node, nil when
+	// disabled.
+	savedViews *viewStore
+	// discovery discovers the peers, nil when disabled.
+	discovery *peerDiscovery
+	// peersFile is the watched file of peers, nil when disabled.
+	peersFile *peersFile
+	// statsd mirrors key metrics to statsd, nil when disabled.
+	statsd          *statsdEmitter
+	gatewayFailures prometheus.Counter
+	// httpDuration observes the duration of the requests of the HTTP
+	// servers.
+	httpDuration *prometheus.HistogramVec
+	compression  *compressionMetrics
+	limits       *requestLimits
+	// panics counts the panics of the HTTP handlers.
+	panics *prometheus.CounterVec
+
+	scheduler *scheduler
+	breakers  *breakers
+	sboms     *sbomStore
+	blocklist *blocklist
+	fake      *fakeDiscovery
+	recorder  *gossipRecorder
+	throttle  *throttle
+	edge      *edgeBuffer
+	shards    *shardRing
+	redis     *redisState
+	exporter  *exporter
+	sink      *sinkStreamer
+	// updates checks the release feed, and restartBinary is the binary of
+	// the installed release, on which the node restarts once shut down.
+	updates       *updateChecker
+	restartBinary string
+	// memoryLimiter adapts the soft memory limit to the cgroup, when
+	// neither -gomemlimit nor GOMEMLIMIT are set.
+	memoryLimiter *memoryLimiter
+
+	imageAllowlist imageAllowlist
+	// policies are the compliance policies, prepared for evaluation.
+	policies []*compiledPolicy
+}
+
+// NewManager returns a manager of a configuration, which it keeps. The
+// secret references of the configuration, such as vault://<path>, must have
+// been resolved by Config.ResolveSecrets.
+func NewManager(logger log.Logger, cfg *Config) (*Manager, error) {
+	m := &Manager{
+		cfg:       cfg,
+		logger:    logger,
+		registry:  prometheus.NewRegistry(),
+		inventory: newInventory(),
+		health:    newSourceHealth(),
+		events:    newEventBroker(),
+		networks:  newResourceState[Network](),
+		volumes:   newResourceState[Volume](),
+		images:    newResourceState[Image](),
+
+		pruneCandidates: newResourceState[PruneCandidate](),
+		facts:           newNodeState[*NodeFacts](),
+		usage:           newNodeState[*NodeUsage](),
+		containerUsage:  newResourceState[ContainerUsage](),
+		idempotency:     newIdempotencyStore(cfg.IdempotencyTTL),
+		history: newHistory(
+			newHistoryTier(tierRaw, cfg.HistoryResolution, cfg.HistoryRetention),
+			newHistoryTier(tierDownsampled, cfg.HistoryDownsampledResolution, cfg.HistoryDownsampledRetention),
+		),
+
+		decommissionAcks:     newNodeState[*DecommissionAck](),
+		ephemeralAnnotations: newResourceState[EphemeralAnnotation](),
+		maintenance:          newResourceState[MaintenanceWindow](),
+		checksums:            newResourceState[InventoryChecksum](),
+		handoffs:             map[string]string{},
+		quit:                 make(chan struct{}),
+	}
+	m.collectors.ctx, m.collectors.cancel = context.WithCancel(context.Background())
+	m.breakers = newBreakers(m.cfg.BreakerThreshold, m.cfg.BreakerOpenTimeout)
+	m.sboms = newSBOMStore(newRegistryClient(m.breakers))
+
+	primary, err := newDockerSource("", "", m.cfg.DockerHost, newAnomalyDetector(cfg.AnomalyRestarts, cfg.AnomalyRestartWindow, cfg.AnomalyMaxUptime))
+	if err != nil {
+		return nil, err
+	}
+	m.sources = append(m.sources, primary)
+	endpoints, err := parseDockerEndpoints(m.cfg.DockerEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	for name, host := range endpoints {
+		s, err := newDockerSource(name, "", host, newAnomalyDetector(cfg.AnomalyRestarts, cfg.AnomalyRestartWindow, cfg.AnomalyMaxUptime))
+		if err != nil {
+			return nil, err
+		}
+		m.sources = append(m.sources, s)
+	}
+
+	m.sshSources, err = newSSHSources(m.cfg.SSHTargets, m.cfg.SSHRuntime, m.cfg.SSHKeyFile, m.cfg.SSHKnownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.cfg.Kubernetes {
+		m.kubelet = newKubeletClient(m.cfg.KubeletURL, m.cfg.KubeletTokenFile, m.cfg.KubeletInsecureSkipVerify)
+	}
+
+	signer, err := newGossipSigner([]byte(m.cfg.GossipSigningKey.Value()), m.cfg.GossipTrustedKeys, m.registry)
+	if err != nil {
+		return nil, err
+	}
+	signer.keyRef = m.cfg.GossipSigningKey.ref
+	m.signer = signer
+	m.inventory.signer = signer
+	m.inventory.legacyPeers = m.legacyPeers
+	m.inventory.annotations = cfg.AnnotationSchemas
+	m.inventory.maxContainers, m.inventory.memoryBudget = cfg.MaxContainersPerNode, cfg.MemoryBudget
+	m.idempotency.caller = m.adminCaller
+	m.inventory.events = m.events
+	m.networks.signer = signer
+	m.volumes.signer = signer
+	m.images.signer = signer
+	m.pruneCandidates.signer = signer
+	m.facts.signer = signer
+	m.usage.signer = signer
+	m.containerUsage.signer = signer
+	m.decommissionAcks.signer = signer
+	m.ephemeralAnnotations.signer = signer
+	m.maintenance.signer = signer
+	m.checksums.signer = signer
+
+	m.blocklist = newBlocklist(m.registry)
+	if m.cfg.BlocklistEntries != "" {
+		for _, e := range strings.Split(m.cfg.BlocklistEntries, ",") {
+			if err := m.blocklist.Add(e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.inventory.blocklist = m.blocklist
+	m.networks.blocklist = m.blocklist
+	m.volumes.blocklist = m.blocklist
+	m.images.blocklist = m.blocklist
+	m.pruneCandidates.blocklist = m.blocklist
+	m.facts.blocklist = m.blocklist
+	m.usage.blocklist = m.blocklist
+	m.containerUsage.blocklist = m.blocklist
+	m.decommissionAcks.blocklist = m.blocklist
+	m.ephemeralAnnotations.blocklist = m.blocklist
+	m.maintenance.blocklist = m.blocklist
+	m.checksums.blocklist = m.blocklist
+	m.inventory.tombstoned = m.ackTombstones
+
+	if m.cfg.HTTPTLSSPIFFE && m.cfg.SPIFFESVIDDir == "" {
+		return nil, errors.New("-http_tls_spiffe requires -spiffe_svid_dir")
+	}
+	if !contains(apiKeyRoles, m.cfg.HTTPSPIFFERole) {
+		return nil, fmt.Errorf("invalid -http_spiffe_role %q: must be one of %s", m.cfg.HTTPSPIFFERole, strings.Join(apiKeyRoles, ", "))
+	}
+	if m.cfg.InternalListenAddress != "" || m.cfg.BootstrapURL != "" || m.cfg.BootstrapPeers || m.cfg.Gateway || m.cfg.HTTPTLSSPIFFE {
+		if m.internalTLS, err = m.internalTLSConfig(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	m.throttle = newThrottle(m.cfg.BroadcastRateLimit, m.cfg.StateTransferRateLimit, m.registry)
+	if m.cfg.EdgeMode {
+		m.edge = newEdgeBuffer(m.connected, m.cfg.EdgeBufferSize, m.logger, m.registry)
+	}
+	if m.cfg.RedisURL.Value() != "" {
+		client, err := newRedisClient(m.cfg.RedisURL.Value(), m.cfg.RedisTimeout)
+		if err != nil {
+			return nil, err
+		}
+		m.redis = newRedisState(client, m.cfg.RedisKeyPrefix, m.cfg.RedisSyncInterval, m.registry)
+	}
+	if m.cfg.ExportURL != "" {
+		store, err := newObjectStore(m.cfg.ExportURL, m.cfg.ExportEndpoint, m.cfg.ExportRegion, m.cfg.ExportAccessKeyID.Value(), m.cfg.ExportSecretAccessKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if m.exporter, err = newExporter(store, m.cfg.ExportPrefix, m.cfg.ExportFormat); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := m.eventFormat(nil); err != nil {
+		return nil, err
+	}
+	if m.cfg.EventSinkURL != "" {
+		sink, err := newEventSink(m.cfg.EventSinkURL, m.cfg)
+		if err != nil {
+			return nil, err
+		}
+		m.sink = newSinkStreamer(sink, m.cfg.SinkBatchSize, m.cfg.SinkFlushInterval, m.cfg.SinkMaxRetries, m.logger, m.registry)
+	}
+	if m.cfg.UpdateFeedURL != "" {
+		if m.updates, err = newUpdateChecker(m.cfg.UpdateFeedURL, m.cfg.UpdateCheckInterval, m.cfg.UpdateAuto, m.cfg.UpdateTrustedKeys, m.logger, m.registry, m.restartOn); err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.StatsdAddress != "" {
+		if m.statsd, err = newStatsdEmitter(m.cfg.StatsdAddress, m.cfg.StatsdFormat, m.cfg.StatsdPrefix, m.cfg.StatsdTags, m.cfg.StatsdMetrics, m.cfg.StatsdFlushInterval, m.registry, m.logger); err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.UIViewsFile != "" {
+		if m.savedViews, err = loadViews(m.cfg.UIViewsFile); err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.APIKeysFile != "" {
+		// The auth middleware enforces the viewer and editor roles: without
+		// it, the keys would only restrict the admin API.
+		if !contains(parseMiddlewares(m.cfg.HTTPMiddlewares), middlewareAuth) {
+			return nil, fmt.Errorf("-api_keys_file requires the %s middleware in -http_middlewares", middlewareAuth)
+		}
+		if m.apiKeys, err = loadAPIKeys(m.cfg.APIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.GossipRecordFile != "" {
+		if m.recorder, err = newGossipRecorder(m.cfg.GossipRecordFile); err != nil {
+			return nil, err
+		}
+	}
+	var peers []string
+	if m.cfg.Peers != "" {
+		for _, peer := range strings.Split(m.cfg.Peers, ",") {
+			peer = strings.TrimSpace(peer)
+			peers = append(peers, peer)
+		}
+	}
+
+	gossipPort := m.cfg.DiscoveryPort
+	if gossipPort == "" {
+		_, gossipPort, _ = net.SplitHostPort(m.cfg.ListenAddress)
+	}
+	if m.cfg.DiscoveryProvider != "" {
+		if m.discovery, err = newPeerDiscovery(m.cfg, gossipPort, m.logger, m.registry); err != nil {
+			return nil, err
+		}
+		peers = append(peers, m.discovery.Seed(context.Background())...)
+	}
+	if m.cfg.PeersFilePath != "" {
+		m.peersFile = newPeersFile(m.cfg.PeersFilePath, gossipPort, m.cfg.PeersFileInterval, m.logger)
+		filePeers, _, err := m.peersFile.read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the peers file: %w", err)
+		}
+		peers = append(peers, filePeers...)
+	}
+	if err := m.setupClustering(peers); err != nil {
+		return nil, err
+	}
+	m.router = newRouter(m)
+	m.blocklist.addrs = func() map[string]string {
+		addrs := map[string]string{}
+		for _, p := range m.peer.Peers() {
+			addrs[p.Name()] = p.Address()
+		}
+		return addrs
+	}
+	m.gatewayFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "containerslist_gateway_peer_failures_total",
+		Help: "Number of peer queries which failed or timed out in gateway mode.",
+	})
+	m.registry.MustRegister(m.gatewayFailures)
+	m.httpDuration = prometheus.NewHistogramVec(latencyHistogramOpts(prometheus.HistogramOpts{
+		Name: "containerslist_http_request_duration_seconds",
+		Help: "Duration of the HTTP requests, by pattern of the handler serving them, method and status code.",
+	}), []string{"handler", "method", "code"})
+	m.inventory.delay = prometheus.NewHistogram(latencyHistogramOpts(prometheus.HistogramOpts{
+		Name: "containerslist_gossip_propagation_delay_seconds",
+		Help: "Time elapsed between the collection of the inventory of a node and the merge of the gossiped inventory.",
+	}))
+	m.registry.MustRegister(m.httpDuration, m.inventory.delay, m.events.published)
+	m.compression = newCompressionMetrics(m.registry)
+	m.limits = newRequestLimits(m.cfg.HTTPMaxBodySize, m.cfg.HTTPMaxHeaderSize, m.cfg.HTTPMaxConnsPerIP, m.registry)
+	m.limits.readHeaderTimeout, m.limits.readTimeout, m.limits.idleTimeout = m.cfg.HTTPReadHeaderTimeout, m.cfg.HTTPReadTimeout, m.cfg.HTTPIdleTimeout
+	m.panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "containerslist_http_panics_total",
+		Help: "Number of panics of the HTTP handlers, by pattern of the handler.",
+	}, []string{"handler"})
+	m.registry.MustRegister(m.panics)
+	m.registry.MustRegister(
+		gocollectors.NewGoCollector(gocollectors.WithGoCollectorRuntimeMetrics(gocollectors.MetricsGC, gocollectors.MetricsMemory, gocollectors.MetricsScheduler)),
+		gocollectors.NewProcessCollector(gocollectors.ProcessCollectorOpts{}),
+	)
+	if m.memoryLimiter, err = newMemoryLimiter(m.cfg, m.logger, m.registry); err != nil {
+		return nil, err
+	}
+	m.internalAddress = m.internalAdvertiseAddress()
+	m.partition = newPartitionDetector(m.memberNames, m.leftPeers, m.cfg.ExpectedSize, m.registry)
+
+	m.nodeID = m.peer.Name()
+	if m.cfg.NodeIDFile != "" {
+		if m.nodeID, err = loadNodeID(m.cfg.NodeIDFile); err != nil {
+			return nil, err
+		}
+	}
+	if m.fingerprint, err = loadFingerprint(m.cfg.FingerprintFile); err != nil {
+		return nil, err
+	}
+	for _, s := range m.sources {
+		s.node = m.nodeID
+		if s.name != "" {
+			s.node += "/" + s.name
+		}
+	}
+	for _, s := range m.sources {
+		s.client.breaker = m.breakers.Get(s.healthName())
+	}
+	if m.cfg.Shards > 0 {
+		m.shards = newShardRing(m.cfg.Shards, m.cfg.ShardReplicas, m.clusterMembers)
+		m.inventory.owns = m.ownsNode
+	}
+	nodeMetricLabels, err := newNodeMetricLabels(m.cfg.MetricsNodeLabels, m.cfg.MetricsNodeLabelMaxValues, m.facts.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	m.checksumVerifier = newChecksumVerifier(m.registry)
+	m.conflicts = newConflictTracker(m.registry)
+	m.registry.MustRegister(newInventoryCollector(m.inventory, nodeMetricLabels), m.history, m.breakers)
+
+	if m.cfg.FakeSpec != "" {
+		if m.fake, err = parseFakeDiscovery(m.cfg.FakeSpec); err != nil {
+			return nil, err
+		}
+	}
+	if m.imageAllowlist, err = parseImageAllowlist(m.cfg.ImageAllowlistPatterns); err != nil {
+		return nil, err
+	}
+	if m.policies, err = m.cfg.Policies.compile(context.Background()); err != nil {
+		return nil, err
+	}
+
+	m.scheduler = newScheduler(m.cfg.SchedulerJitter, m.logger, m.registry)
+	if err := m.setupJobs(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// setupClustering joins the cluster through the initial peers.
+func (m *Manager) setupClustering(peers []string) error {
+	reg := m.registry
+
+	peer, err := cluster.Create(m.logger, reg, m.cfg.ListenAddress, m.cfg.AdvertiseAddress, peers, true, m.cfg.PushPullInterval, m.cfg.GossipInterval, cluster.DefaultTCPTimeout, cluster.DefaultProbeTimeout, cluster.DefaultProbeInterval, nil, true, m.cfg.ClusterLabel)
+	if err != nil {
+		return fmt.Errorf("unable to initialize gossip mesh: %w", err)
+	}
+	if m.cfg.GossipEncryptionKeys.Value() != "" {
+		if m.keyring, err = newGossipKeyring(m.cfg.GossipEncryptionKeys.Value(), m.cfg.GossipEncryptionKeys.ref, reg); err != nil {
+			return err
+		}
+		if err := m.keyring.install(peer); err != nil {
+			return fmt.Errorf("unable to encrypt the gossip: %w", err)
+		}
+	}
+
+	err = peer.Join(cluster.DefaultReconnectInterval, cluster.DefaultReconnectTimeout)
+	if err != nil {
+		level.Error(m.logger).Log("Unable to join gossip mesh while initializing cluster for high availability mode", "error", err)
+		m.joinErr = err
+	}
+
+	// Attempt to verify the number of peers for 30s every 2s.
+	var ctx context.Context
+	const settleTimeout = cluster.DefaultGossipInterval * 10
+	ctx, m.settleCancel = context.WithTimeout(context.Background(), 30*time.Second)
+	go peer.Settle(ctx, settleTimeout)
+	m.peer = peer
+	m.channel = m.edge.channel(m.throttle.channel(peer.AddState("inventory", m.recorded("inventory", m.inventory), reg)))
+	m.networksChannel = m.edge.channel(m.throttle.channel(peer.AddState("networks", m.recorded("networks", m.networks), reg)))
+	m.volumesChannel = m.edge.channel(m.throttle.channel(peer.AddState("volumes", m.recorded("volumes", m.volumes), reg)))
+	m.imagesChannel = m.edge.channel(m.throttle.channel(peer.AddState("images", m.recorded("images", m.images), reg)))
+	m.pruneChannel = m.edge.channel(m.throttle.channel(peer.AddState("prune", m.recorded("prune", m.pruneCandidates), reg)))
+	m.factsChannel = m.edge.channel(m.throttle.channel(peer.AddState("facts", m.recorded("facts", m.facts), reg)))
+	m.usageChannel = m.edge.channel(m.throttle.channel(peer.AddState("usage", m.recorded("usage", m.usage), reg)))
+	m.containerUsageChannel = m.edge.channel(m.throttle.channel(peer.AddState("containerusage", m.recorded("containerusage", m.containerUsage), reg)))
+	m.decommissionChannel = m.edge.channel(m.throttle.channel(peer.AddState("decommission", m.recorded("decommission", m.decommissionAcks), reg)))
+	m.ephemeralAnnotationsChannel = m.edge.channel(m.throttle.channel(peer.AddState("ephemeralannotations", m.recorded("ephemeralannotations", m.ephemeralAnnotations), reg)))
+	m.maintenanceChannel = m.edge.channel(m.throttle.channel(peer.AddState("maintenance", m.recorded("maintenance", m.maintenance), reg)))
+	m.checksumsChannel = m.edge.channel(m.throttle.channel(peer.AddState("checksums", m.recorded("checksums", m.checksums), reg)))
+
+	// The clock state is only exchanged on push/pull, never broadcast, as
+	// relayed broadcasts would skew the estimates.
+	m.clock = newClockState(peer.Name(), m.cfg.ClockSkewThreshold, m.logger)
+	m.clock.displayNames = m.peerDisplayNames
+	peer.AddState("clock", m.clock, reg)
+	reg.MustRegister(m.clock)
+	return nil
+}
+
+// Run collects the containers and runs the jobs of the manager until ctx is
+// done.
+func (m *Manager) Run(ctx context.Context) error {
+	if m.cfg.BootstrapURL != "" {
+		if err := m.bootstrap(ctx, m.cfg.BootstrapURL, m.internalTLS); err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to bootstrap state from peer", "url", m.cfg.BootstrapURL, "error", err)
+		}
+	} else if m.cfg.BootstrapPeers {
+		if err := m.bootstrapFromPeers(ctx); err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to bootstrap state from peers", "error", err)
+		}
+	}
+	collectCtx := m.collectors.ctx
+	if m.cfg.GossipReplayFile != "" {
+		go func() {
+			if err := m.replayGossip(ctx, m.cfg.GossipReplayFile, m.cfg.GossipReplaySpeed); err != nil {
+				level.Error(m.logger).Log("msg", "Unable to replay gossip", "file", m.cfg.GossipReplayFile, "error", err)
+			}
+		}()
+	} else if m.cfg.Passive {
+		// Passive members only receive the state of the cluster.
+		go m.runPassive(ctx)
+	} else if m.fake != nil {
+		// The synthetic nodes replace the local sources.
+		m.collectors.wg.Add(1)
+		go func() {
+			defer m.collectors.wg.Done()
+			m.runFakeDiscovery(collectCtx, m.fake)
+		}()
+	} else {
+		m.startCollectors(collectCtx)
+	}
+	go m.partition.Run(ctx, m.logger)
+	if m.edge != nil {
+		go m.edge.Run(ctx)
+	}
+	go m.runHistory(ctx)
+	go m.runConflicts(ctx)
+	if m.redis != nil {
+		go m.runRedisSync(ctx)
+	}
+	if m.sink != nil {
+		go m.sink.Run(ctx, m.events, m.isLocal)
+	}
+	go m.runMembershipTracker(ctx)
+	if m.memoryLimiter != nil {
+		go m.memoryLimiter.Run(ctx)
+	}
+	if m.updates != nil {
+		go m.updates.Run(ctx)
+	}
+	if m.cfg.ChecksumInterval > 0 {
+		go m.runChecksums(ctx)
+	}
+	if m.internalCerts != nil {
+		go m.internalCerts.Run(ctx)
+	}
+	if m.cfg.GossipKeysRefresh > 0 {
+		go m.signer.Run(ctx, m.cfg.GossipKeysRefresh, m.logger)
+		if m.keyring != nil {
+			go m.keyring.Run(ctx, m.cfg.GossipKeysRefresh, m.logger)
+		}
+	}
+	if m.statsd != nil {
+		go m.statsd.Run(ctx)
+	}
+	if m.discovery != nil {
+		go m.discovery.Run(ctx, m.peer)
+	}
+	if m.peersFile != nil {
+		go m.peersFile.Run(ctx, m.peer)
+	}
+	m.scheduler.Run(ctx)
+
+	<-ctx.Done()
+	return nil
+}
+
+// startCollectors starts collecting the local and SSH sources, tracking them
+// so that they can be stopped.
+func (m *Manager) startCollectors(ctx context.Context) {
+	for _, s := range m.sources {
+		m.collectors.wg.Add(1)
+		go func(s *dockerSource) {
+			defer m.collectors.wg.Done()
+			m.runCollector(ctx, s)
+		}(s)
+		if m.cfg.ContainerUsageInterval > 0 {
+			m.collectors.wg.Add(1)
+			go func(s *dockerSource) {
+				defer m.collectors.wg.Done()
+				m.runUsageCollector(ctx, s)
+			}(s)
+		}
+	}
+	for _, s := range m.sshSources {
+		m.collectors.wg.Add(1)
+		go func(s *sshSource) {
+			defer m.collectors.wg.Done()
+			m.runSSHCollector(ctx, s)
+		}(s)
+	}
+	if m.cfg.SampleUsage {
+		m.collectors.wg.Add(1)
+		go func() {
+			defer m.collectors.wg.Done()
+			m.runUsageSampler(ctx)
+		}()
+	}
+}
+
+// source returns the Docker source reported under the given node name.
+func (m *Manager) source(node string) (*dockerSource, bool) {
+	for _, s := range m.sources {
+		if s.node == node {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// stop makes the node shut down gracefully and exit.
+func (m *Manager) stop() {
+	m.quitOnce.Do(func() { close(m.quit) })
+}
+
+func (m *Manager) StopAndWait() {
+	m.settleCancel()
+	if err := m.peer.Leave(m.cfg.ShutdownLeaveTimeout); err != nil {
+		level.Error(m.logger).Log("Unable to leave cluster", "error", err)
+	}
+	level.Debug(m.logger).Log("Quitting...")
+}
+
+func (m *Manager) http(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nodes, failed, err := m.containers(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := containerRows(nodes, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := paginateContainers(rows, r.URL, m.cfg.UIPageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		history, _ := m.history.Get(tierRaw, nil)
+		data := struct {
+			*cluster.Peer
+			Nodes     []*NodeInventory
+			Partition PartitionStatus
+			History   string
+			Columns   uiColumns
+			Failed    []string
+			Alerts    []SelfAlert
+			Cost      *CostReport
+			PeerStats map[string]PeerStats
+			// PeerNames are the display names of the peers, by name.
+			PeerNames map[string]string
+			// Annotations are the ephemeral annotations of the containers.
+			Annotations ephemeralAnnotations
+			// Page is the page of the containers table.
+			Page containersPage
+			// Query holds the filters, sort and columns of the table, and
+			// AllColumns the extra columns which may be shown.
+			Query      url.Values
+			AllColumns uiColumns
+			States     []string
+			SortKeys   []string
+			// Views are the saved views of the user, when ViewsEnabled.
+			Views        []View
+			ViewsEnabled bool
+			// Update is set when the release feed is checked.
+			Update *UpdateStatus
+		}{m.peer, nodes, m.partition.Status(), sparkline(history.Cluster.Samples, 300, 40), m.cfg.UIColumns.Select(r.URL.Query()), failed, m.selfAlerts(), nil, m.peerStats(), m.peerDisplayNames(), m.activeEphemeralAnnotations(time.Now()), page, r.URL.Query(), m.cfg.UIColumns, []string{StateRunning, StateExited, "all"}, containerSortKeys, m.views(r), m.savedViews != nil, nil}
+		if m.costEnabled() {
+			cost := m.costReport()
+			data.Cost = &cost
+		}
+		if m.updates != nil {
+			update := m.updates.Status()
+			data.Update = &update
+		}
+		if err := localize(t, r).Execute(w, data); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
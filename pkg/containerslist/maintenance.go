@@ -0,0 +1,368 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultMaintenanceWindowMaxDuration is the longest maintenance window
+// accepted by the API.
+const defaultMaintenanceWindowMaxDuration = 7 * 24 * time.Hour
+
+// maintenanceWindowID are the IDs of the maintenance windows, chosen by the
+// operators, such as kernel-upgrade-zone-a.
+var maintenanceWindowID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// nodeLabelKeys are the facts of the nodes by which the maintenance windows
+// select them.
+var nodeLabelKeys = []string{"zone", "os", "architecture", "runtime"}
+
+const maintenanceTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - maintenance</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <table>
+      <tr><th>ID</th><th>{{ t "Nodes" }}</th><th>Labels</th><th>{{ t "Reason" }}</th><th>{{ t "Window" }}</th><th>{{ t "Silenced nodes" }}</th><th></th></tr>
+    {{ range . }}
+      <tr><td>{{ .ID }}</td><td>{{ range .Nodes }}<code>{{ . }}</code> {{ end }}</td><td>{{ range $k, $v := .Labels }}<code>{{ $k }}={{ $v }}</code> {{ end }}</td><td>{{ .Reason }}</td><td>{{ if .Active }}{{ t "ends in %s" (until .End) }}{{ else }}{{ t "starts in %s" (until .Start) }}, {{ t "for %s" (.Duration.String) }}{{ end }}</td><td>{{ range .Silenced }}{{ . }} {{ end }}</td><td><button data-id="{{ .ID }}" class="cancel">{{ t "Cancel" }}</button></td></tr>
+    {{ else }}
+      <tr><td colspan="7">{{ t "No maintenance window" }}</td></tr>
+    {{ end }}
+    </table>
+    <form id="maintenance">
+      <input name="id" placeholder="ID" required>
+      <input name="node" placeholder="{{ t "node patterns, comma-separated" }}">
+      <input name="label" placeholder="{{ t "labels, such as zone=a" }}">
+      <input name="duration" placeholder="{{ t "duration, such as 2h" }}" required>
+      <input name="reason" placeholder="{{ t "reason" }}">
+      <button type="submit">{{ t "Schedule" }}</button>
+    </form>
+    <script>
+      (function() {
+        function put(id, query) {
+          fetch("/api/v1/maintenance-windows/" + encodeURIComponent(id) + "?" + query, { method: "PUT" }).then(function(res) {
+            if (res.ok) {
+              location.reload();
+            } else {
+              res.json().then(function(p) { alert(p.detail); });
+            }
+          });
+        }
+        document.getElementById("maintenance").onsubmit = function(e) {
+          e.preventDefault();
+          var query = new URLSearchParams();
+          new FormData(e.target).forEach(function(value, name) {
+            if (name == "id") {
+              return;
+            }
+            value.split(",").forEach(function(v) {
+              if (v.trim()) {
+                query.append(name, v.trim());
+              }
+            });
+          });
+          put(e.target.elements.id.value, query);
+        };
+        document.querySelectorAll("button.cancel").forEach(function(b) {
+          b.onclick = function() { put(b.dataset.id, "cancel=true"); };
+        });
+      })();
+    </script>
+  </body>
+</html>
+`
+
+// MaintenanceWindow is a time range during which the self-alerts and the
+// policy violations of the nodes it selects are silenced. Nodes are selected
+// by name, with glob patterns, and by labels, derived from their facts: a
+// node must match one of the patterns, when there are any, and all the
+// labels.
+type MaintenanceWindow struct {
+	ID     string            `json:"id"`
+	Nodes  []string          `json:"nodes,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Reason string            `json:"reason,omitempty"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	// Cancelled is set on the windows ended early. They are kept until
+	// their end, to supersede their previous versions.
+	Cancelled bool      `json:"cancelled,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Active reports whether the window is in progress.
+func (w MaintenanceWindow) Active() bool {
+	now := time.Now()
+	return !w.Cancelled && !now.Before(w.Start) && now.Before(w.End)
+}
+
+// Duration returns the length of the window.
+func (w MaintenanceWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+func (w MaintenanceWindow) matches(node string, labels map[string]string) bool {
+	if len(w.Nodes) > 0 {
+		matched := false
+		for _, p := range w.Nodes {
+			if ok, _ := path.Match(p, node); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for k, v := range w.Labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeLabels returns the labels of a node, from its facts.
+func nodeLabels(f *NodeFacts) map[string]string {
+	labels := map[string]string{
+		"zone":         f.Zone,
+		"os":           f.OS,
+		"architecture": f.Architecture,
+		"runtime":      f.Runtime,
+	}
+	for k, v := range f.Labels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// maintenanceWindows returns the latest version of the windows which have
+// not ended nor been cancelled, sorted by start. Each member gossips the
+// windows scheduled through its API, so a window may have been updated by
+// several members: the most recent version wins.
+func (m *Manager) maintenanceWindows(now time.Time) []MaintenanceWindow {
+	latest := map[string]MaintenanceWindow{}
+	for _, n := range m.maintenance.Nodes() {
+		for _, w := range n.Items {
+			if prev, ok := latest[w.ID]; !ok || w.Updated.After(prev.Updated) {
+				latest[w.ID] = w
+			}
+		}
+	}
+	res := []MaintenanceWindow{}
+	for _, w := range latest {
+		if !w.Cancelled && w.End.After(now) {
+			res = append(res, w)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Start.Equal(res[j].Start) {
+			return res[i].Start.Before(res[j].Start)
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// maintenanceSchedule tells the nodes in maintenance.
+type maintenanceSchedule struct {
+	windows []MaintenanceWindow
+	labels  map[string]map[string]string
+}
+
+// activeMaintenance returns the schedule of the windows in progress.
+func (m *Manager) activeMaintenance(now time.Time) maintenanceSchedule {
+	s := maintenanceSchedule{labels: map[string]map[string]string{}}
+	for _, w := range m.maintenanceWindows(now) {
+		if !now.Before(w.Start) {
+			s.windows = append(s.windows, w)
+		}
+	}
+	if len(s.windows) > 0 {
+		for _, f := range m.facts.Nodes() {
+			s.labels[f.Node] = nodeLabels(f)
+		}
+	}
+	return s
+}
+
+// Window returns the window in progress silencing a node, if any.
+func (s maintenanceSchedule) Window(node string) *MaintenanceWindow {
+	for _, w := range s.windows {
+		if w.matches(node, s.labels[node]) {
+			return &w
+		}
+	}
+	return nil
+}
+
+// setMaintenanceWindow adds a version of a window to the entry of the local
+// node, dropping the ended windows and the previous versions of the same
+// window, and broadcasts it.
+func (m *Manager) setMaintenanceWindow(w MaintenanceWindow) error {
+	m.maintenanceMtx.Lock()
+	defer m.maintenanceMtx.Unlock()
+
+	items := []MaintenanceWindow{w}
+	if n, ok := m.maintenance.Get(m.nodeID); ok {
+		for _, prev := range n.Items {
+			if prev.End.After(w.Updated) && prev.ID != w.ID {
+				items = append(items, prev)
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Updated.Before(items[j].Updated) })
+	b, err := m.maintenance.Set(&NodeResources[MaintenanceWindow]{Node: m.nodeID, Timestamp: w.Updated, Items: items})
+	if err != nil {
+		return err
+	}
+	m.maintenanceChannel.Broadcast(b)
+	return nil
+}
+
+// apiSetMaintenanceWindow schedules the window of the path
+// /api/v1/maintenance-windows/{id}, replacing any previous one with the same
+// ID, or cancels it with ?cancel=true. Since the window silences the
+// self-alerts and the policy violations, it requires the admin role.
+func (m *Manager) apiSetMaintenanceWindow() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/maintenance-windows/")
+		if !maintenanceWindowID.MatchString(id) {
+			writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+			return
+		}
+		if !requireMethod(w, r, http.MethodPut) {
+			return
+		}
+		q := r.URL.Query()
+		now := time.Now().UTC()
+
+		if q.Get("cancel") == "true" {
+			for _, mw := range m.maintenanceWindows(now) {
+				if mw.ID == id {
+					mw.Cancelled, mw.Updated = true, now
+					if err := m.setMaintenanceWindow(mw); err != nil {
+						writeProblem(w, r, problemf(ErrInternal, "unable to cancel maintenance window: %v", err))
+						return
+					}
+					writeJSON(w, mw)
+					return
+				}
+			}
+			writeProblem(w, r, problemf(ErrNotFound, "no maintenance window %q", id))
+			return
+		}
+
+		mw := MaintenanceWindow{ID: id, Nodes: q["node"], Reason: q.Get("reason"), Start: now, Updated: now}
+		if err := parseMaintenanceWindow(&mw, q.Get("start"), q.Get("end"), q.Get("duration"), q["label"], m.cfg.MaintenanceWindowMaxDuration); err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		if err := m.setMaintenanceWindow(mw); err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to schedule maintenance window: %v", err))
+			return
+		}
+		writeJSON(w, mw)
+	})
+}
+
+// parseMaintenanceWindow sets the time range and the labels of a window from
+// the query parameters, and validates it.
+func parseMaintenanceWindow(mw *MaintenanceWindow, start, end, duration string, labels []string, maxDuration time.Duration) error {
+	for _, p := range mw.Nodes {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid node pattern %q: %v", p, err)
+		}
+	}
+	for _, l := range labels {
+		k, v, ok := strings.Cut(l, "=")
+		if !ok || !contains(nodeLabelKeys, k) {
+			return fmt.Errorf("invalid label %q: must be key=value, with key one of %s", l, strings.Join(nodeLabelKeys, ", "))
+		}
+		if mw.Labels == nil {
+			mw.Labels = map[string]string{}
+		}
+		mw.Labels[k] = v
+	}
+	if len(mw.Nodes) == 0 && len(mw.Labels) == 0 {
+		return fmt.Errorf("a node pattern or a label is required")
+	}
+
+	var err error
+	if start != "" {
+		if mw.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return fmt.Errorf("invalid start %q: must be an RFC 3339 time", start)
+		}
+	}
+	switch {
+	case end != "" && duration != "":
+		return fmt.Errorf("end and duration are mutually exclusive")
+	case end != "":
+		if mw.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return fmt.Errorf("invalid end %q: must be an RFC 3339 time", end)
+		}
+	case duration != "":
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q: must be a positive duration", duration)
+		}
+		mw.End = mw.Start.Add(d)
+	default:
+		return fmt.Errorf("an end or a duration is required")
+	}
+	if !mw.End.After(mw.Start) || !mw.End.After(mw.Updated) {
+		return fmt.Errorf("the window must end after its start and in the future")
+	}
+	if d := mw.Duration(); d > maxDuration {
+		return fmt.Errorf("duration %s exceeds the maximum of %s", d, maxDuration)
+	}
+	return nil
+}
+
+// apiMaintenanceWindows lists the maintenance windows in progress and
+// upcoming.
+func (m *Manager) apiMaintenanceWindows() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.maintenanceWindows(time.Now()))
+	})
+}
+
+func (m *Manager) maintenancePage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type row struct {
+			MaintenanceWindow
+			// Silenced are the nodes matched by the window.
+			Silenced []string
+		}
+		labels := map[string]map[string]string{}
+		for _, f := range m.facts.Nodes() {
+			labels[f.Node] = nodeLabels(f)
+		}
+		var rows []row
+		for _, mw := range m.maintenanceWindows(time.Now()) {
+			row := row{MaintenanceWindow: mw}
+			for _, n := range m.inventory.Nodes() {
+				if mw.matches(n.Node, labels[n.Node]) {
+					row.Silenced = append(row.Silenced, n.Node)
+				}
+			}
+			sort.Strings(row.Silenced)
+			rows = append(rows, row)
+		}
+		if err := localize(t, r).ExecuteTemplate(w, "maintenance", rows); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
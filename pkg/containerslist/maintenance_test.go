@@ -0,0 +1,164 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mw := MaintenanceWindow{ID: "kernel", Nodes: []string{"web-*"}, Start: now, Updated: now}
+	if err := parseMaintenanceWindow(&mw, "2024-03-01T14:00:00Z", "", "2h", []string{"zone=a", "os=linux"}, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !mw.Start.Equal(now.Add(2*time.Hour)) || mw.Duration() != 2*time.Hour || mw.Labels["zone"] != "a" || mw.Labels["os"] != "linux" {
+		t.Errorf("window = %+v, want 14:00-16:00 in zone a on linux", mw)
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		nodes                []string
+		start, end, duration string
+		labels               []string
+	}{
+		{name: "no selector", duration: "1h"},
+		{name: "invalid pattern", nodes: []string{"web-["}, duration: "1h"},
+		{name: "invalid label", nodes: []string{"*"}, labels: []string{"team=shop"}, duration: "1h"},
+		{name: "label without value", nodes: []string{"*"}, labels: []string{"zone"}, duration: "1h"},
+		{name: "no end", nodes: []string{"*"}},
+		{name: "end and duration", nodes: []string{"*"}, end: "2024-03-01T14:00:00Z", duration: "1h"},
+		{name: "invalid start", nodes: []string{"*"}, start: "tomorrow", duration: "1h"},
+		{name: "invalid end", nodes: []string{"*"}, end: "tonight"},
+		{name: "negative duration", nodes: []string{"*"}, duration: "-1h"},
+		{name: "end before start", nodes: []string{"*"}, start: "2024-03-01T14:00:00Z", end: "2024-03-01T13:00:00Z"},
+		{name: "ended", nodes: []string{"*"}, start: "2024-03-01T10:00:00Z", end: "2024-03-01T11:00:00Z"},
+		{name: "too long", nodes: []string{"*"}, duration: "25h"},
+	} {
+		mw := MaintenanceWindow{ID: "kernel", Nodes: tc.nodes, Start: now, Updated: now}
+		if err := parseMaintenanceWindow(&mw, tc.start, tc.end, tc.duration, tc.labels, 24*time.Hour); err == nil {
+			t.Errorf("%s: window %+v accepted, want an error", tc.name, mw)
+		}
+	}
+}
+
+func TestMaintenanceWindowMatches(t *testing.T) {
+	labels := nodeLabels(&NodeFacts{Zone: "a", OS: "linux", Labels: map[string]string{"team": "shop", "zone": "ignored"}})
+	if labels["zone"] != "a" || labels["team"] != "shop" {
+		t.Errorf("labels = %v, want the facts first", labels)
+	}
+	for _, tc := range []struct {
+		window MaintenanceWindow
+		node   string
+		want   bool
+	}{
+		{window: MaintenanceWindow{Nodes: []string{"web-*", "db-1"}}, node: "web-2", want: true},
+		{window: MaintenanceWindow{Nodes: []string{"web-*", "db-1"}}, node: "db-2"},
+		{window: MaintenanceWindow{Labels: map[string]string{"zone": "a"}}, node: "db-2", want: true},
+		{window: MaintenanceWindow{Nodes: []string{"web-*"}, Labels: map[string]string{"zone": "a", "os": "windows"}}, node: "web-1"},
+		{window: MaintenanceWindow{Labels: map[string]string{"zone": "b"}}, node: "web-1"},
+	} {
+		if got := tc.window.matches(tc.node, labels); got != tc.want {
+			t.Errorf("window %+v matches %s = %v, want %v", tc.window, tc.node, got, tc.want)
+		}
+	}
+}
+
+func TestAPIMaintenanceWindows(t *testing.T) {
+	m := newTestInventoryManager(t, &NodeInventory{Node: "web-1"}, &NodeInventory{Node: "web-2"}, &NodeInventory{Node: "db-1"})
+	m.nodeID = "n1"
+	m.facts = newNodeState[*NodeFacts]()
+	for _, f := range []*NodeFacts{{Node: "web-1", Zone: "a"}, {Node: "web-2", Zone: "b"}, {Node: "db-1", Zone: "a"}} {
+		f.Timestamp = time.Now()
+		if _, err := m.facts.Set(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.maintenance = newResourceState[MaintenanceWindow]()
+	ch := &testChannel{}
+	m.maintenanceChannel = ch
+	put := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.apiSetMaintenanceWindow().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, nil))
+		return rec
+	}
+
+	rec := put("/api/v1/maintenance-windows/kernel-a?node=web-*&label=zone=a&duration=1h&reason=kernel+upgrade")
+	var mw MaintenanceWindow
+	if err := json.NewDecoder(rec.Body).Decode(&mw); err != nil {
+		t.Fatal(err)
+	}
+	if mw.ID != "kernel-a" || mw.Reason != "kernel upgrade" || mw.Duration() != time.Hour || !mw.Active() {
+		t.Errorf("window = %+v, want kernel-a in progress for 1h", mw)
+	}
+	start := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if rec := put("/api/v1/maintenance-windows/later?node=db-1&duration=1h&start=" + start); rec.Code != http.StatusOK {
+		t.Errorf("status of an upcoming window = %d, want 200", rec.Code)
+	}
+	if len(ch.msgs) != 2 {
+		t.Errorf("%d broadcasts, want 2", len(ch.msgs))
+	}
+
+	// Only web-1 is in zone a and in a window in progress.
+	s := m.activeMaintenance(time.Now())
+	for node, want := range map[string]bool{"web-1": true, "web-2": false, "db-1": false} {
+		if got := s.Window(node) != nil; got != want {
+			t.Errorf("%s in maintenance = %v, want %v", node, got, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiMaintenanceWindows().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/maintenance-windows", nil))
+	var list []MaintenanceWindow
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "kernel-a" || list[1].ID != "later" {
+		t.Errorf("windows = %+v, want kernel-a then later", list)
+	}
+
+	if rec := put("/api/v1/maintenance-windows/kernel-a?cancel=true"); rec.Code != http.StatusOK {
+		t.Errorf("status of the cancel = %d, want 200", rec.Code)
+	}
+	if s := m.activeMaintenance(time.Now()); s.Window("web-1") != nil {
+		t.Error("web-1 still in maintenance once cancelled")
+	}
+	if got := m.maintenanceWindows(time.Now()); len(got) != 1 || got[0].ID != "later" {
+		t.Errorf("windows = %+v, want later", got)
+	}
+
+	for path, code := range map[string]int{
+		"/api/v1/maintenance-windows/kernel-a?cancel=true":  http.StatusNotFound,
+		"/api/v1/maintenance-windows/-x?node=*&duration=1h": http.StatusNotFound,
+		"/api/v1/maintenance-windows/x?duration=1h":         http.StatusBadRequest,
+	} {
+		if rec := put(path); rec.Code != code {
+			t.Errorf("status of %s = %d, want %d", path, rec.Code, code)
+		}
+	}
+}
+
+func TestMaintenanceWindowsLatest(t *testing.T) {
+	m := &Manager{maintenance: newResourceState[MaintenanceWindow]()}
+	now := time.Now().UTC()
+	window := func(id string, updated time.Time, cancelled bool) MaintenanceWindow {
+		return MaintenanceWindow{ID: id, Nodes: []string{"*"}, Start: now, End: now.Add(time.Hour), Updated: updated, Cancelled: cancelled}
+	}
+	for node, items := range map[string][]MaintenanceWindow{
+		"n1": {window("a", now, false), window("b", now, false)},
+		// n2 cancelled a after n1 scheduled it, and n1 rescheduled b since.
+		"n2": {window("a", now.Add(time.Second), true), window("b", now.Add(-time.Second), true)},
+	} {
+		if _, err := m.maintenance.Set(&NodeResources[MaintenanceWindow]{Node: node, Timestamp: now, Items: items}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := m.maintenanceWindows(now); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("windows = %+v, want b", got)
+	}
+	if got := m.maintenanceWindows(now.Add(2 * time.Hour)); len(got) != 0 {
+		t.Errorf("windows = %+v, want none once ended", got)
+	}
+}
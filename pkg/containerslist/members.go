@@ -0,0 +1,241 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+// SerfMember is a member of the cluster in the format of the
+// serf members -format json command, for the scripts built around it.
+type SerfMember struct {
+	Name string `json:"name"`
+	// Addr is the gossip address of the member, as host:port.
+	Addr string            `json:"addr"`
+	Port uint16            `json:"port"`
+	Tags map[string]string `json:"tags"`
+	// Status is always alive: the members which left or failed are not
+	// members anymore.
+	Status string `json:"status"`
+	// Protocol holds the min, max and version of the gossip protocol,
+	// only known for the receiving node.
+	Protocol map[string]uint8 `json:"protocol"`
+}
+
+// SerfMembers is the output of the serf members -format json command.
+type SerfMembers struct {
+	Members []SerfMember `json:"members"`
+}
+
+// serfFilter filters the members as the -status, -name and -tag flags of
+// the serf members command, with anchored regular expressions.
+type serfFilter struct {
+	status, name *regexp.Regexp
+	tags         map[string]*regexp.Regexp
+}
+
+func anchoredRegexp(param, expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", param, expr, err)
+	}
+	return re, nil
+}
+
+// parseSerfFilter parses the status, name and tag parameters of a query.
+func parseSerfFilter(status, name string, tags []string) (serfFilter, error) {
+	var f serfFilter
+	var err error
+	if status != "" {
+		if f.status, err = anchoredRegexp("status", status); err != nil {
+			return f, err
+		}
+	}
+	if name != "" {
+		if f.name, err = anchoredRegexp("name", name); err != nil {
+			return f, err
+		}
+	}
+	for _, tag := range tags {
+		key, expr, ok := strings.Cut(tag, "=")
+		if !ok || key == "" {
+			return f, fmt.Errorf("invalid tag %q: must be key=regexp", tag)
+		}
+		if f.tags == nil {
+			f.tags = map[string]*regexp.Regexp{}
+		}
+		if f.tags[key], err = anchoredRegexp("tag", expr); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (f serfFilter) matches(m SerfMember) bool {
+	if f.status != nil && !f.status.MatchString(m.Status) || f.name != nil && !f.name.MatchString(m.Name) {
+		return false
+	}
+	for key, re := range f.tags {
+		if v, ok := m.Tags[key]; !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// serfMembers returns the members of the cluster, tagged with the facts of
+// their nodes.
+func (m *Manager) serfMembers() []SerfMember {
+	facts := m.peerFacts()
+	passive := m.passivePeers()
+	names := m.peerDisplayNames()
+	self := m.peer.Self()
+
+	members := []SerfMember{}
+	for _, p := range m.peer.Peers() {
+		host, port, _ := net.SplitHostPort(p.Address())
+		portNum, _ := strconv.ParseUint(port, 10, 16)
+		member := SerfMember{
+			Name:     p.Name(),
+			Addr:     p.Address(),
+			Port:     uint16(portNum),
+			Tags:     map[string]string{"display_name": names[p.Name()]},
+			Status:   "alive",
+			Protocol: map[string]uint8{},
+		}
+		if site := m.cfg.PeerSites.site(host); site != "" {
+			member.Tags["site"] = site
+		}
+		if passive[p.Name()] {
+			member.Tags["passive"] = "true"
+		}
+		if f, ok := facts[p.Name()]; ok {
+			member.Tags["node"] = f.Node
+			for k, v := range nodeLabels(f) {
+				if v != "" {
+					member.Tags[k] = v
+				}
+			}
+			if f.InternalAddress != "" {
+				member.Tags["internal_address"] = f.InternalAddress
+			}
+		}
+		if p.Name() == self.Name {
+			member.Protocol = map[string]uint8{"min": self.PMin, "max": self.PMax, "version": self.PCur}
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members
+}
+
+// apiMembers lists the members of the cluster as serf members -format json.
+func (m *Manager) apiMembers() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f, err := parseSerfFilter(q.Get("status"), q.Get("name"), q["tag"])
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		res := SerfMembers{Members: []SerfMember{}}
+		for _, member := range m.serfMembers() {
+			if f.matches(member) {
+				res.Members = append(res.Members, member)
+			}
+		}
+		writeJSON(w, res)
+	})
+}
+
+// runMembers lists the members of the cluster as the serf members command,
+// for the scripts built around it.
+func runMembers(args []string) error {
+	fs := flag.NewFlagSet("members", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s members [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	var (
+		cf       cliClientFlags
+		tags     []string
+		format   = fs.String("format", "text", "Output format: text or json")
+		status   = fs.String("status", "", "Only list the members whose status matches this regular expression")
+		name     = fs.String("name", "", "Only list the members whose name matches this regular expression")
+		detailed = fs.Bool("detailed", false, "Also print the protocol versions of the members")
+	)
+	cf.register(fs)
+	fs.Func("tag", "Only list the members with a tag matching key=regexp; may be repeated", func(v string) error {
+		tags = append(tags, v)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", *format)
+	}
+	if _, err := parseSerfFilter(*status, *name, tags); err != nil {
+		return err
+	}
+
+	c, err := cf.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	res, err := c.Members(ctx, *status, *name, tags)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	if len(res.Members) == 0 {
+		return errors.New("no members matched")
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, member := range res.Members {
+		row := []string{member.Name, member.Addr, member.Status, serfTags(member)}
+		if *detailed {
+			row = append(row, serfProtocol(member))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// serfTags formats the tags of a member as serf: sorted key=value pairs,
+// separated by commas.
+func serfTags(m client.SerfMember) string {
+	pairs := make([]string, 0, len(m.Tags))
+	for _, k := range sortedKeys(m.Tags) {
+		pairs = append(pairs, k+"="+m.Tags[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func serfProtocol(m client.SerfMember) string {
+	if len(m.Protocol) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Protocol Version: %d, Available Protocol Range: [%d, %d]", m.Protocol["version"], m.Protocol["min"], m.Protocol["max"])
+}
@@ -0,0 +1,94 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+func TestSerfFilter(t *testing.T) {
+	member := SerfMember{Name: "01HQ", Status: "alive", Tags: map[string]string{"zone": "eu-1", "node": "web-1"}}
+	for _, tc := range []struct {
+		status, name string
+		tags         []string
+		want         bool
+	}{
+		{want: true},
+		{status: "alive|left", name: "01H.", tags: []string{"zone=eu-.*", "node=web-1"}, want: true},
+		{status: "failed"},
+		// The expressions are anchored.
+		{name: "01H"},
+		{tags: []string{"zone=eu"}},
+		{tags: []string{"os=.*"}},
+	} {
+		f, err := parseSerfFilter(tc.status, tc.name, tc.tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.matches(member); got != tc.want {
+			t.Errorf("filter %q %q %q matches = %v, want %v", tc.status, tc.name, tc.tags, got, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		status, name string
+		tags         []string
+	}{
+		{status: "("},
+		{name: "["},
+		{tags: []string{"zone"}},
+		{tags: []string{"=eu"}},
+		{tags: []string{"zone=("}},
+	} {
+		if _, err := parseSerfFilter(tc.status, tc.name, tc.tags); err == nil {
+			t.Errorf("filter %q %q %q accepted, want an error", tc.status, tc.name, tc.tags)
+		}
+	}
+}
+
+func TestAPIMembers(t *testing.T) {
+	m := newTestDecommissionManager(t)
+	m.facts = newNodeState[*NodeFacts]()
+	if _, err := m.facts.Set(&NodeFacts{Node: "web-1", Peer: m.peer.Name(), Zone: "eu-1", InternalAddress: "10.0.0.1:8443", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.cfg.PeerSites.Set("127.0.0.0/8=local"); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m.apiMembers())
+	defer srv.Close()
+	c := client.New(srv.URL)
+
+	res, err := c.Members(context.Background(), "alive", "", []string{"zone=eu-.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Members) != 1 {
+		t.Fatalf("members = %+v, want the node", res.Members)
+	}
+	member := res.Members[0]
+	_, port, _ := strings.Cut(member.Addr, "127.0.0.1:")
+	if member.Name != m.peer.Name() || port != strconv.Itoa(int(member.Port)) || member.Status != "alive" || member.Protocol["version"] == 0 {
+		t.Errorf("member = %+v, want the peer alive", member)
+	}
+	if got, want := serfTags(member), "display_name="+m.peer.Name()+",internal_address=10.0.0.1:8443,node=web-1,site=local,zone=eu-1"; got != want {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+	if got := serfProtocol(member); !strings.HasPrefix(got, "Protocol Version: ") {
+		t.Errorf("protocol = %q", got)
+	}
+
+	if res, err := c.Members(context.Background(), "", "", []string{"zone=us-.*"}); err != nil || len(res.Members) != 0 {
+		t.Errorf("members = %+v, %v, want none", res, err)
+	}
+	var apiErr *client.Error
+	if _, err := c.Members(context.Background(), "(", "", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("error = %v, want a 400", err)
+	}
+}
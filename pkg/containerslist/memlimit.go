@@ -0,0 +1,188 @@
+package containerslist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultGoMemLimitRatio    = 0.9
+	defaultGoMemLimitInterval = time.Minute
+
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroupUnlimited is beyond which the memory limits of cgroup v1, a
+	// multiple of the page size close to the largest int64, are unlimited.
+	cgroupUnlimited = math.MaxInt64 / 2
+)
+
+// errNoCgroupLimit is returned when the memory of the cgroup of the node is
+// not limited.
+var errNoCgroupLimit = errors.New("no cgroup memory limit")
+
+// cgroupMemoryLimit returns the memory limit in bytes of the cgroup of the
+// process, from cgroup v2 or else v1. The path of the cgroup is the one of
+// /proc/self/cgroup, or the root of the hierarchy when the cgroup namespace
+// of a container hides it.
+func cgroupMemoryLimit() (int64, error) {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+	var files []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		// Each line is hierarchy-ID:controllers:path, the controllers
+		// being empty for cgroup v2.
+		parts := strings.SplitN(sc.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			files = append(files, filepath.Join(cgroupRoot, parts[2], "memory.max"), filepath.Join(cgroupRoot, "memory.max"))
+		case contains(strings.Split(parts[1], ","), "memory"):
+			files = append(files, filepath.Join(cgroupRoot, "memory", parts[2], "memory.limit_in_bytes"), filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"))
+		}
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		s := strings.TrimSpace(string(b))
+		if s == "max" {
+			return 0, errNoCgroupLimit
+		}
+		limit, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid memory limit %q in %s: %w", s, f, err)
+		}
+		if limit >= cgroupUnlimited {
+			return 0, errNoCgroupLimit
+		}
+		return limit, nil
+	}
+	return 0, errNoCgroupLimit
+}
+
+// memoryLimiter tunes the garbage collector for the node to behave
+// predictably in containers with tight memory limits. Beyond -gogc and
+// -gomemlimit, which set the target percentage and the soft memory limit of
+// the runtime as GOGC and GOMEMLIMIT do, the soft memory limit is adaptive
+// when neither -gomemlimit nor GOMEMLIMIT are set: it is kept at a ratio of
+// the memory limit of the cgroup of the node, re-read at each interval for
+// the limits updated in place, such as by docker update or the in-place
+// resizing of pods. The runtime then collects more often as the heap nears
+// the limit, instead of the node being killed for running out of memory.
+type memoryLimiter struct {
+	ratio    float64
+	interval time.Duration
+	logger   log.Logger
+	// readLimit returns the memory limit of the cgroup.
+	readLimit func() (int64, error)
+
+	cgroupLimit prometheus.Gauge
+	// limit is the soft memory limit set, 0 until one is.
+	limit int64
+}
+
+// newMemoryLimiter applies -gogc and -gomemlimit. It returns a limiter
+// adapting the soft memory limit to the cgroup, or nil when the soft memory
+// limit is set otherwise or the adaptive limit is disabled.
+func newMemoryLimiter(cfg *Config, logger log.Logger, reg prometheus.Registerer) (*memoryLimiter, error) {
+	logger = log.With(logger, "component", "memory_limiter")
+	if cfg.GoGC != 0 {
+		percent := cfg.GoGC
+		if percent < 0 {
+			percent = -1
+		}
+		debug.SetGCPercent(percent)
+		level.Info(logger).Log("msg", "Set garbage collection target percentage", "gogc", percent)
+	}
+	if cfg.GoMemLimit < 0 {
+		return nil, fmt.Errorf("invalid soft memory limit %d: must not be negative", cfg.GoMemLimit)
+	}
+	if cfg.GoMemLimit > 0 {
+		debug.SetMemoryLimit(cfg.GoMemLimit)
+		level.Info(logger).Log("msg", "Set soft memory limit", "limit", cfg.GoMemLimit)
+		return nil, nil
+	}
+	if cfg.GoMemLimitRatio < 0 || cfg.GoMemLimitRatio > 1 {
+		return nil, fmt.Errorf("invalid ratio of the cgroup memory limit %v: must be between 0 and 1", cfg.GoMemLimitRatio)
+	}
+	if os.Getenv("GOMEMLIMIT") != "" || cfg.GoMemLimitRatio == 0 {
+		return nil, nil
+	}
+	l := &memoryLimiter{
+		ratio:     cfg.GoMemLimitRatio,
+		interval:  cfg.GoMemLimitInterval,
+		logger:    logger,
+		readLimit: cgroupMemoryLimit,
+		cgroupLimit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "containerslist_cgroup_memory_limit_bytes",
+			Help: "Memory limit in bytes of the cgroup of the node, from which its soft memory limit is derived; 0 when unlimited.",
+		}),
+	}
+	reg.MustRegister(l.cgroupLimit)
+	l.update()
+	return l, nil
+}
+
+// update sets the soft memory limit from the memory limit of the cgroup,
+// removing it when the cgroup is no longer limited.
+func (l *memoryLimiter) update() {
+	limit, err := l.readLimit()
+	switch {
+	case errors.Is(err, errNoCgroupLimit):
+		l.cgroupLimit.Set(0)
+		if l.limit != 0 {
+			debug.SetMemoryLimit(math.MaxInt64)
+			l.limit = 0
+			level.Info(l.logger).Log("msg", "Removed soft memory limit, the cgroup memory being unlimited")
+		}
+		return
+	case err != nil:
+		level.Warn(l.logger).Log("msg", "Unable to read the cgroup memory limit", "error", err)
+		return
+	}
+	l.cgroupLimit.Set(float64(limit))
+	soft := int64(float64(limit) * l.ratio)
+	if soft == l.limit {
+		return
+	}
+	debug.SetMemoryLimit(soft)
+	level.Info(l.logger).Log("msg", "Set soft memory limit from the cgroup memory limit", "cgroup_limit", limit, "limit", soft)
+	l.limit = soft
+}
+
+// Run re-reads the memory limit of the cgroup at each interval until ctx is
+// done.
+func (l *memoryLimiter) Run(ctx context.Context) {
+	if l.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.update()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package containerslist
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// restoreGC restores the settings of the garbage collector at the end of a
+// test.
+func restoreGC(t *testing.T) {
+	t.Helper()
+	percent, limit := debug.SetGCPercent(100), debug.SetMemoryLimit(-1)
+	debug.SetGCPercent(percent)
+	t.Cleanup(func() {
+		debug.SetGCPercent(percent)
+		debug.SetMemoryLimit(limit)
+	})
+}
+
+func TestNewMemoryLimiter(t *testing.T) {
+	restoreGC(t)
+	cfg := DefaultConfig()
+	cfg.GoGC, cfg.GoMemLimit = 50, 1<<30
+	l, err := newMemoryLimiter(cfg, log.NewNopLogger(), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != nil {
+		t.Error("limiter adapting a limit set by -gomemlimit")
+	}
+	if got := debug.SetGCPercent(100); got != 50 {
+		t.Errorf("GC percent = %d, want 50", got)
+	}
+	if got := debug.SetMemoryLimit(-1); got != 1<<30 {
+		t.Errorf("memory limit = %d, want %d", got, 1<<30)
+	}
+
+	cfg = DefaultConfig()
+	t.Setenv("GOMEMLIMIT", "1GiB")
+	if l, err := newMemoryLimiter(cfg, log.NewNopLogger(), prometheus.NewRegistry()); err != nil || l != nil {
+		t.Errorf("limiter = %v, %v, want none with GOMEMLIMIT", l, err)
+	}
+
+	for _, tc := range []struct {
+		limit int64
+		ratio float64
+	}{
+		{limit: -1, ratio: defaultGoMemLimitRatio},
+		{ratio: -0.1},
+		{ratio: 1.5},
+	} {
+		cfg := DefaultConfig()
+		cfg.GoMemLimit, cfg.GoMemLimitRatio = tc.limit, tc.ratio
+		if _, err := newMemoryLimiter(cfg, log.NewNopLogger(), prometheus.NewRegistry()); err == nil {
+			t.Errorf("limit %d with ratio %v accepted, want an error", tc.limit, tc.ratio)
+		}
+	}
+}
+
+func TestMemoryLimiterUpdate(t *testing.T) {
+	restoreGC(t)
+	var (
+		limit int64
+		err   error
+	)
+	l := &memoryLimiter{
+		ratio:       0.5,
+		logger:      log.NewNopLogger(),
+		readLimit:   func() (int64, error) { return limit, err },
+		cgroupLimit: prometheus.NewGauge(prometheus.GaugeOpts{Name: "containerslist_cgroup_memory_limit_bytes", Help: "Test."}),
+	}
+	for _, tc := range []struct {
+		name        string
+		limit       int64
+		err         error
+		cgroupLimit float64
+		want        int64
+	}{
+		{name: "limited", limit: 1 << 30, cgroupLimit: 1 << 30, want: 1 << 29},
+		{name: "resized", limit: 1 << 31, cgroupLimit: 1 << 31, want: 1 << 30},
+		// The limit is kept when the cgroup cannot be read.
+		{name: "unreadable", err: errors.New("permission denied"), cgroupLimit: 1 << 31, want: 1 << 30},
+		{name: "unlimited", err: errNoCgroupLimit, want: math.MaxInt64},
+	} {
+		limit, err = tc.limit, tc.err
+		l.update()
+		if got := testutil.ToFloat64(l.cgroupLimit); got != tc.cgroupLimit {
+			t.Errorf("%s: cgroup limit = %v, want %v", tc.name, got, tc.cgroupLimit)
+		}
+		if got := debug.SetMemoryLimit(-1); got != tc.want {
+			t.Errorf("%s: memory limit = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+	if l.limit != 0 {
+		t.Errorf("limit = %d, want none", l.limit)
+	}
+}
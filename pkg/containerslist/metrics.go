@@ -0,0 +1,64 @@
+package containerslist
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var restartLoopNodesDesc = prometheus.NewDesc(
+	"containerslist_restart_loop_nodes",
+	"Number of nodes where the containers of an image are flagged as restart loops.",
+	[]string{"image"}, nil,
+)
+
+// inventoryCollector exports metrics computed from the inventory at scrape
+// time.
+type inventoryCollector struct {
+	inventory *inventory
+	labels    *nodeMetricLabels
+
+	inventoryAgeDesc   *prometheus.Desc
+	nodeContainersDesc *prometheus.Desc
+}
+
+func newInventoryCollector(inventory *inventory, labels *nodeMetricLabels) inventoryCollector {
+	return inventoryCollector{
+		inventory: inventory,
+		labels:    labels,
+		inventoryAgeDesc: labels.desc(
+			"containerslist_node_inventory_age_seconds",
+			"Time elapsed since the last inventory update of a node.",
+		),
+		nodeContainersDesc: labels.desc(
+			"containerslist_node_containers",
+			"Number of containers in the inventory of a node, including the ones left out of truncated inventories.",
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inventoryAgeDesc
+	ch <- c.nodeContainersDesc
+	ch <- restartLoopNodesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	nodes := c.inventory.Nodes()
+	values := c.labels.nodeValues()
+	for _, n := range nodes {
+		labels := c.labels.labelValues(values, n.Node)
+		ch <- prometheus.MustNewConstMetric(c.inventoryAgeDesc, prometheus.GaugeValue, now.Sub(n.Timestamp).Seconds(), labels...)
+		count := len(n.Containers)
+		if n.Truncated && n.TotalContainers > count {
+			count = n.TotalContainers
+		}
+		ch <- prometheus.MustNewConstMetric(c.nodeContainersDesc, prometheus.GaugeValue, float64(count), labels...)
+	}
+	for _, g := range restartLoops(nodes, func(c Container) string { return c.Image }) {
+		ch <- prometheus.MustNewConstMetric(restartLoopNodesDesc, prometheus.GaugeValue, float64(g.Looping), g.Key)
+	}
+}
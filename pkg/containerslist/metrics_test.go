@@ -0,0 +1,52 @@
+package containerslist
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInventoryCollector(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Timestamp: time.Now().Add(-time.Minute), Containers: []Container{{ID: "a1"}, {ID: "a2"}}},
+		&NodeInventory{Node: "b", Containers: []Container{{ID: "b1"}}, Truncated: true, TotalContainers: 5000},
+	)
+	labels, err := newNodeMetricLabels("", 10, func() []*NodeFacts { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newInventoryCollector(m.inventory, labels)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP containerslist_node_containers Number of containers in the inventory of a node, including the ones left out of truncated inventories.
+# TYPE containerslist_node_containers gauge
+containerslist_node_containers{node="a"} 2
+containerslist_node_containers{node="b"} 5000
+`), "containerslist_node_containers"); err != nil {
+		t.Error(err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "containerslist_node_inventory_age_seconds" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			node, age := metric.GetLabel()[0].GetValue(), metric.GetGauge().GetValue()
+			if node == "a" && (age < 60 || age > 120) {
+				t.Errorf("age of a = %.0fs, want about 60s", age)
+			}
+			if node == "b" && age > 60 {
+				t.Errorf("age of b = %.0fs, want about 0s", age)
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package containerslist
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// Middlewares of the HTTP server, chained in the order of -http_middlewares.
+const (
+	middlewareRequestID   = "request_id"
+	middlewareLogging     = "logging"
+	middlewareMetrics     = "metrics"
+	middlewareAuth        = "auth"
+	middlewareRateLimit   = "rate_limit"
+	middlewareCompression = "compression"
+	middlewareRecovery    = "recovery"
+	middlewareLimits      = "limits"
+)
+
+const (
+	// defaultHTTPMiddlewares are the middlewares of the HTTP server, the
+	// outermost first. The recovery is inside the compression and the
+	// metrics, so that the 500s of the panics are compressed and observed,
+	// and the limits are right after the request IDs, rejecting the
+	// requests before any work.
+	defaultHTTPMiddlewares = middlewareRequestID + "," + middlewareLimits + "," + middlewareMetrics + "," + middlewareCompression + "," + middlewareRecovery
+
+	defaultHTTPRateLimit      = 20
+	defaultHTTPRateLimitBurst = 40
+	// rateLimitMaxClients bounds the clients whose requests are counted:
+	// the idle ones are forgotten beyond it.
+	rateLimitMaxClients = 10000
+)
+
+// middlewareOptOuts are the middlewares skipped by routes, by pattern of the
+// route, such as /metrics.
+type middlewareOptOuts map[string][]string
+
+// defaultMiddlewareOptOuts spare the scrapes of the metrics and the health
+// checks of the status from the access logs, authentication and rate
+// limiting.
+func defaultMiddlewareOptOuts() middlewareOptOuts {
+	return middlewareOptOuts{
+		"/metrics":       {middlewareLogging, middlewareAuth, middlewareRateLimit},
+		"/api/v1/status": {middlewareLogging, middlewareAuth, middlewareRateLimit},
+	}
+}
+
+// String implements flag.Value.
+func (o middlewareOptOuts) String() string {
+	var s []string
+	for _, pattern := range sortedKeys(o) {
+		s = append(s, pattern+"="+strings.Join(o[pattern], ","))
+	}
+	return strings.Join(s, " ")
+}
+
+// Set implements flag.Value, parsing pattern=middleware[,middleware...].
+func (o middlewareOptOuts) Set(v string) error {
+	pattern, names, ok := strings.Cut(v, "=")
+	if !ok || !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("invalid middleware opt-out %q: must be pattern=middleware[,middleware...]", v)
+	}
+	o[pattern] = nil
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			o[pattern] = append(o[pattern], name)
+		}
+	}
+	return nil
+}
+
+// chain wraps the handler of a mux in the middlewares, the first one being
+// the outermost. The routes of the mux opting out of a middleware skip it.
+func (m *Manager) chain(mux *http.ServeMux, names []string, optOuts middlewareOptOuts) (http.Handler, error) {
+	var h http.Handler = mux
+	for i := len(names) - 1; i >= 0; i-- {
+		wrap, err := m.middleware(names[i], mux)
+		if err != nil {
+			return nil, err
+		}
+		var skipped []string
+		for pattern, opted := range optOuts {
+			if contains(opted, names[i]) {
+				skipped = append(skipped, pattern)
+			}
+		}
+		h = optOut(mux, skipped, wrap(h), h)
+	}
+	return h, nil
+}
+
+// optOut serves the requests of the routes of skipped with next, and the
+// other ones with wrapped.
+func optOut(mux *http.ServeMux, skipped []string, wrapped, next http.Handler) http.Handler {
+	if len(skipped) == 0 {
+		return wrapped
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); contains(skipped, pattern) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		wrapped.ServeHTTP(w, r)
+	})
+}
+
+// middleware returns the middleware of a name.
+func (m *Manager) middleware(name string, mux *http.ServeMux) (func(http.Handler) http.Handler, error) {
+	switch name {
+	case middlewareRequestID:
+		return withRequestID, nil
+	case middlewareLogging:
+		return m.logRequests, nil
+	case middlewareMetrics:
+		return func(h http.Handler) http.Handler { return instrumentHandler(mux, h, m.httpDuration) }, nil
+	case middlewareAuth:
+		if m.cfg.HTTPAuthToken.Value() == "" && m.apiKeys == nil {
+			return nil, fmt.Errorf("the %s middleware requires -http_auth_token or -api_keys_file", name)
+		}
+		return m.requireToken, nil
+	case middlewareRateLimit:
+		if m.cfg.HTTPRateLimit <= 0 || m.cfg.HTTPRateLimitBurst < 1 {
+			return nil, fmt.Errorf("the %s middleware requires a positive -http_rate_limit and -http_rate_limit_burst", name)
+		}
+		l := newRateLimiter(m.cfg.HTTPRateLimit, m.cfg.HTTPRateLimitBurst)
+		return l.limit, nil
+	case middlewareCompression:
+		return m.compression.compress(m.cfg.CompressionMinSize), nil
+	case middlewareRecovery:
+		return m.recoverPanics(mux), nil
+	case middlewareLimits:
+		return m.limits.limit, nil
+	}
+	return nil, fmt.Errorf("unknown middleware %q: must be one of %s", name, strings.Join(middlewareNames(), ", "))
+}
+
+func middlewareNames() []string {
+	names := []string{middlewareRequestID, middlewareLogging, middlewareMetrics, middlewareAuth, middlewareRateLimit, middlewareCompression, middlewareRecovery, middlewareLimits}
+	sort.Strings(names)
+	return names
+}
+
+// parseMiddlewares parses a comma-separated list of middlewares.
+func parseMiddlewares(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// logRequests logs each request once served, with its status and duration.
+func (m *Manager) logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		level.Info(m.requestLogger(r.Context())).Log("msg", "HTTP request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start), "remote", r.RemoteAddr)
+	})
+}
+
+// requireToken only lets through the requests bearing the HTTP token, the
+// admin token or an API key, as a bearer token or as the password of the
+// basic authentication, for the browsers. The API keys with the viewer role
+// are only let through for reading.
+func (m *Manager) requireToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			_, token, ok = r.BasicAuth()
+		}
+		valid := func(expected string) bool {
+			return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+		}
+		if ok && (valid(m.cfg.HTTPAuthToken.Value()) || valid(m.cfg.AdminToken.Value())) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if k, kind, found := m.credential(r, token, ok); found {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && !k.grants(RoleEditor) {
+				writeProblem(w, r, problemf(ErrForbidden, "%s %q has the %s role: the editor role is required to write", kind, k.Name, k.Role))
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("WWW-Authenticate", "Bearer")
+		w.Header().Add("WWW-Authenticate", `Basic realm="containerslist"`)
+		writeProblem(w, r, problemf(ErrUnauthorized, "a valid bearer token is required"))
+	})
+}
+
+// rateLimiter limits the rate of the requests of each client IP, with a
+// token bucket.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx     sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes a token of the bucket of a client, reporting whether there was
+// one, or else the time until the next one.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= rateLimitMaxClients {
+			l.forgetIdle(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// forgetIdle drops the buckets which are full again.
+func (l *rateLimiter) forgetIdle(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+func (l *rateLimiter) limit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := clientIP(r.RemoteAddr)
+		if ok, retry := l.allow(client, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			writeProblem(w, r, problemf(ErrRateLimited, "too many requests from %s", client))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
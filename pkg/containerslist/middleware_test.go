@@ -0,0 +1,112 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestMiddlewareOptOuts(t *testing.T) {
+	o := middlewareOptOuts{}
+	for _, v := range []string{"/metrics=logging, auth,", "/api/v1/status=rate_limit", "/metrics=auth"} {
+		if err := o.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := o.String(), "/api/v1/status=rate_limit /metrics=auth"; got != want {
+		t.Errorf("opt-outs = %q, want %q", got, want)
+	}
+	for _, v := range []string{"/metrics", "metrics=auth"} {
+		if err := o.Set(v); err == nil {
+			t.Errorf("opt-out %q accepted, want an error", v)
+		}
+	}
+
+	if got, want := parseMiddlewares(" request_id,,auth "), []string{"request_id", "auth"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("middlewares = %q, want %q", got, want)
+	}
+}
+
+func TestChain(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig(), logger: log.NewNopLogger()}
+	m.cfg.HTTPRateLimit, m.cfg.HTTPRateLimitBurst = 1, 1
+	mux := http.NewServeMux()
+	var requestID string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requestID = w.Header().Get(requestIDHeader) })
+	mux.Handle("/metrics", ok)
+	mux.Handle("/api/v1/containers", ok)
+	names := []string{middlewareRequestID, middlewareLogging, middlewareAuth, middlewareRateLimit}
+
+	if _, err := m.chain(mux, names, defaultMiddlewareOptOuts()); err == nil {
+		t.Error("auth without a token accepted, want an error")
+	}
+	if _, err := m.chain(mux, []string{"gzip"}, nil); err == nil {
+		t.Error("unknown middleware accepted, want an error")
+	}
+
+	if err := m.cfg.HTTPAuthToken.Set("s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	h, err := m.chain(mux, names, defaultMiddlewareOptOuts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path, token string) int {
+		requestID = ""
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{path: "/api/v1/containers", want: http.StatusUnauthorized},
+		{path: "/api/v1/containers", token: "s3cr3t", want: http.StatusOK},
+		// The bucket of the client is empty.
+		{path: "/api/v1/containers", token: "s3cr3t", want: http.StatusTooManyRequests},
+		// The scrapes opt out of the authentication and rate limiting.
+		{path: "/metrics", want: http.StatusOK},
+		{path: "/metrics", want: http.StatusOK},
+	} {
+		if got := get(tc.path, tc.token); got != tc.want {
+			t.Errorf("status of %s with token %q = %d, want %d", tc.path, tc.token, got, tc.want)
+		}
+	}
+	if requestID == "" {
+		t.Error("no request ID set on /metrics")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 2)
+	now := time.Now()
+	for n, want := range []bool{true, true, false} {
+		if ok, _ := l.allow("10.0.0.1", now); ok != want {
+			t.Errorf("request %d allowed = %v, want %v", n, ok, want)
+		}
+	}
+	if ok, retry := l.allow("10.0.0.1", now); ok || retry != 500*time.Millisecond {
+		t.Errorf("allowed = %v, retry after %s, want 500ms", ok, retry)
+	}
+	if ok, _ := l.allow("10.0.0.2", now); !ok {
+		t.Error("other client limited")
+	}
+	if ok, _ := l.allow("10.0.0.1", now.Add(time.Second)); !ok {
+		t.Error("client still limited once refilled")
+	}
+
+	l.forgetIdle(now.Add(time.Minute))
+	if len(l.buckets) != 0 {
+		t.Errorf("%d buckets, want the idle ones forgotten", len(l.buckets))
+	}
+}
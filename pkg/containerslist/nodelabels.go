@@ -0,0 +1,125 @@
+package containerslist
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultMetricsNodeLabelMaxValues = 50
+	// nodeLabelOverflow replaces the values of a metric label beyond its
+	// maximum number of values.
+	nodeLabelOverflow = "other"
+)
+
+// nodeLabelName is the syntax of the names of the node labels, which may be
+// Prometheus label names.
+var nodeLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// nodeTags are the labels set by the operator on the local nodes, such as
+// role=web or tenant=acme, gossiped with their facts.
+type nodeTags map[string]string
+
+// String implements flag.Value.
+func (t nodeTags) String() string {
+	specs := make([]string, 0, len(t))
+	for _, k := range sortedKeys(t) {
+		specs = append(specs, k+"="+t[k])
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a key=value label.
+func (t nodeTags) Set(spec string) error {
+	key, value, ok := strings.Cut(spec, "=")
+	if !ok || !nodeLabelName.MatchString(key) {
+		return fmt.Errorf("invalid node label %q: must be key=value, the key being letters, digits and underscores", spec)
+	}
+	if _, ok := nodeLabels(&NodeFacts{})[key]; ok || key == "node" {
+		return fmt.Errorf("invalid node label %q: %s is a built-in label", spec, key)
+	}
+	t[key] = value
+	return nil
+}
+
+// nodeMetricLabels adds selected node labels to the labels of the per-node
+// metrics, for PromQL to group them by zone, role or tenant. As a guardrail
+// against the cardinality of the series, each label takes at most
+// maxValues values: the values seen afterwards are reported as other.
+type nodeMetricLabels struct {
+	names     []string
+	maxValues int
+	facts     func() []*NodeFacts
+
+	mtx sync.Mutex
+	// values are the values admitted for each label.
+	values map[string]map[string]bool
+}
+
+func newNodeMetricLabels(names string, maxValues int, facts func() []*NodeFacts) (*nodeMetricLabels, error) {
+	l := &nodeMetricLabels{maxValues: maxValues, facts: facts, values: map[string]map[string]bool{}}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !nodeLabelName.MatchString(name) || name == "node" {
+			return nil, fmt.Errorf("invalid metrics node label %q", name)
+		}
+		l.names = append(l.names, name)
+		l.values[name] = map[string]bool{}
+	}
+	if maxValues <= 0 {
+		return nil, fmt.Errorf("invalid maximum number of values of the metrics node labels %d: must be positive", maxValues)
+	}
+	return l, nil
+}
+
+// desc returns the descriptor of a per-node metric, with the node labels
+// after the node.
+func (l *nodeMetricLabels) desc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, append([]string{"node"}, l.names...), nil)
+}
+
+// nodeValues returns the values of the node labels of each node, empty for
+// the nodes without facts.
+func (l *nodeMetricLabels) nodeValues() map[string][]string {
+	res := map[string][]string{}
+	if len(l.names) == 0 {
+		return res
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	// The values are admitted in the order of the names of the nodes, for
+	// the same ones to be admitted after a restart.
+	for _, f := range l.facts() {
+		labels := nodeLabels(f)
+		values := make([]string, 0, len(l.names))
+		for _, name := range l.names {
+			v := labels[name]
+			if admitted := l.values[name]; v != "" && !admitted[v] {
+				if len(admitted) >= l.maxValues {
+					v = nodeLabelOverflow
+				} else {
+					admitted[v] = true
+				}
+			}
+			values = append(values, v)
+		}
+		res[f.Node] = values
+	}
+	return res
+}
+
+// labelValues returns the label values of a node: its name, then the
+// values of its node labels.
+func (l *nodeMetricLabels) labelValues(values map[string][]string, node string) []string {
+	v := values[node]
+	if v == nil {
+		v = make([]string, len(l.names))
+	}
+	return append([]string{node}, v...)
+}
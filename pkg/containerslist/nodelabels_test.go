@@ -0,0 +1,97 @@
+package containerslist
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNodeTags(t *testing.T) {
+	tags := nodeTags{}
+	for _, spec := range []string{"role=web", "tenant=acme", "role=db", "empty="} {
+		if err := tags.Set(spec); err != nil {
+			t.Errorf("label %q: %v", spec, err)
+		}
+	}
+	if got, want := tags.String(), "empty=,role=db,tenant=acme"; got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+	for _, spec := range []string{"role", "=web", "1role=web", "team-name=x", "zone=eu-1", "node=a"} {
+		if err := tags.Set(spec); err == nil {
+			t.Errorf("label %q accepted, want an error", spec)
+		}
+	}
+
+	// The built-in labels win over the ones of the operator.
+	labels := nodeLabels(&NodeFacts{Zone: "eu-1", Labels: map[string]string{"zone": "us-1", "role": "web"}})
+	if labels["zone"] != "eu-1" || labels["role"] != "web" {
+		t.Errorf("labels = %v, want the zone eu-1 and the role web", labels)
+	}
+}
+
+func TestNewNodeMetricLabels(t *testing.T) {
+	l, err := newNodeMetricLabels(" zone, role,,", 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(l.names, ","); got != "zone,role" {
+		t.Errorf("names = %q, want zone,role", got)
+	}
+	for _, tc := range []struct {
+		names     string
+		maxValues int
+	}{
+		{"node", 10},
+		{"team-name", 10},
+		{"zone", 0},
+	} {
+		if _, err := newNodeMetricLabels(tc.names, tc.maxValues, nil); err == nil {
+			t.Errorf("labels %q with %d values accepted, want an error", tc.names, tc.maxValues)
+		}
+	}
+}
+
+func TestInventoryCollectorNodeLabels(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Timestamp: time.Now(), Containers: []Container{{ID: "a1"}}},
+		&NodeInventory{Node: "b", Timestamp: time.Now(), Containers: []Container{{ID: "b1"}, {ID: "b2"}}},
+		&NodeInventory{Node: "c", Timestamp: time.Now()},
+		&NodeInventory{Node: "d", Timestamp: time.Now()},
+	)
+	facts := []*NodeFacts{
+		{Node: "a", Zone: "eu-1", Labels: map[string]string{"role": "web"}},
+		{Node: "b", Zone: "eu-2", Labels: map[string]string{"role": "db"}},
+		{Node: "c", Zone: "eu-3"},
+	}
+	// Each label takes two values at most.
+	labels, err := newNodeMetricLabels("zone,role", 2, func() []*NodeFacts { return facts })
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(newInventoryCollector(m.inventory, labels))
+	want := `
+# HELP containerslist_node_containers Number of containers in the inventory of a node, including the ones left out of truncated inventories.
+# TYPE containerslist_node_containers gauge
+containerslist_node_containers{node="a",role="web",zone="eu-1"} 1
+containerslist_node_containers{node="b",role="db",zone="eu-2"} 2
+containerslist_node_containers{node="c",role="",zone="other"} 0
+containerslist_node_containers{node="d",role="",zone=""} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "containerslist_node_containers"); err != nil {
+		t.Error(err)
+	}
+
+	// The admitted values stay admitted.
+	facts = []*NodeFacts{{Node: "c", Zone: "eu-3"}, {Node: "d", Zone: "eu-2"}}
+	values := labels.nodeValues()
+	if got := strings.Join(labels.labelValues(values, "c"), ","); got != "c,other," {
+		t.Errorf("labels of c = %q, want c,other,", got)
+	}
+	if got := strings.Join(labels.labelValues(values, "d"), ","); got != "d,eu-2," {
+		t.Errorf("labels of d = %q, want d,eu-2,", got)
+	}
+}
@@ -0,0 +1,394 @@
+package containerslist
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/web"
+)
+
+// apiParam is a query parameter of an API endpoint.
+type apiParam struct {
+	Name        string
+	Description string
+	Enum        []string
+	Multiple    bool
+	// InPath is set on the parameters in the path, such as {name}.
+	InPath bool
+}
+
+// apiEndpoint defines an endpoint of the API, from which it is registered and
+// documented in the OpenAPI document.
+type apiEndpoint struct {
+	Path        string
+	Method      string
+	Summary     string
+	Params      []apiParam
+	ContentType string
+	// Response is a value of the type of the response body.
+	Response interface{}
+	Admin    bool
+	Handler  http.Handler
+}
+
+// pattern returns the pattern under which an endpoint is registered: the
+// endpoints with path parameters are registered as the prefix before them.
+func (e apiEndpoint) pattern() string {
+	if i := strings.Index(e.Path, "{"); i >= 0 {
+		return e.Path[:i]
+	}
+	return e.Path
+}
+
+// handleEndpoints registers the endpoints on a mux, dispatching the requests
+// by method to the endpoints sharing a pattern.
+func (m *Manager) handleEndpoints(mux *http.ServeMux, endpoints []apiEndpoint) {
+	for _, r := range m.endpointRoutes(endpoints) {
+		mux.Handle(r.Pattern, r.Handler)
+	}
+}
+
+// endpointRoutes returns the routes of the endpoints, by pattern in the order
+// of the endpoints.
+func (m *Manager) endpointRoutes(endpoints []apiEndpoint) []web.Route {
+	var patterns []string
+	byPattern := map[string][]apiEndpoint{}
+	for _, e := range endpoints {
+		p := e.pattern()
+		if _, ok := byPattern[p]; !ok {
+			patterns = append(patterns, p)
+		}
+		byPattern[p] = append(byPattern[p], e)
+	}
+	routes := make([]web.Route, 0, len(patterns))
+	for _, p := range patterns {
+		h := byPattern[p][0].Handler
+		if len(byPattern[p]) > 1 {
+			h = byMethod(byPattern[p])
+		}
+		routes = append(routes, web.Route{Pattern: p, Handler: m.withRevision(h)})
+	}
+	return routes
+}
+
+// byMethod serves the requests with the handler of the endpoint of their
+// method, answering a 405 Method Not Allowed when there is none.
+func byMethod(endpoints []apiEndpoint) http.Handler {
+	handlers := map[string]http.Handler{}
+	var methods []string
+	for _, e := range endpoints {
+		method := e.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		handlers[method] = e.Handler
+		methods = append(methods, method)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := handlers[r.Method]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeProblem(w, r, problemf(ErrMethodNotAllowed, "%s must be used", strings.Join(methods, " or ")))
+	})
+}
+
+// apiEndpoints returns the endpoints of the API.
+func (m *Manager) apiEndpoints() []apiEndpoint {
+	return []apiEndpoint{
+		{Path: "/api/v1/status", Summary: "Status of the local node", Response: Status{}, Handler: m.apiStatus()},
+		{Path: "/api/v1/selfalerts", Summary: "Unhealthy conditions of the agent, with remediation hints", Response: []SelfAlert{}, Handler: m.apiSelfAlerts()},
+		{Path: "/api/v1/jobs", Summary: "Status of the scheduled jobs", Response: []JobStatus{}, Handler: m.apiJobs()},
+		{Path: "/api/v1/grafana/dashboards", Summary: "Grafana dashboards of the metrics of the nodes", Response: []GrafanaDashboard{}, Handler: apiGrafanaDashboards()},
+		{Path: "/api/v1/grafana/dashboards/{uid}", Summary: "JSON model of a Grafana dashboard, for provisioning", Params: []apiParam{
+			{Name: "uid", Description: "UID of the dashboard", InPath: true},
+			{Name: "import", Description: "Wrap the model in the body of the dashboard import API of Grafana", Enum: []string{"true"}},
+		}, Response: map[string]interface{}{}, Handler: apiGrafanaDashboards()},
+		{Path: "/api/v1/annotations", Summary: "Registered schemas of the container annotations", Response: []AnnotationSchema{}, Handler: m.apiAnnotations()},
+		{Path: "/api/v1/peers", Summary: "Members of the cluster", Params: []apiParam{
+			fieldsParam("members", PeerInfo{}),
+		}, Response: []PeerInfo{}, Handler: m.apiPeers()},
+		{Path: "/api/v1/members", Summary: "Members of the cluster in the format of serf members -format json, tagged with the facts of their nodes, for the scripts built around serf", Params: []apiParam{
+			{Name: "status", Description: "Anchored regular expression of the status of the members; all of them are alive"},
+			{Name: "name", Description: "Anchored regular expression of the names of the members"},
+			{Name: "tag", Description: "Tag of the members, as key=regexp with an anchored regular expression", Multiple: true},
+		}, Response: SerfMembers{}, Handler: m.apiMembers()},
+		{Path: "/api/v1/peers/{name}/stats", Summary: "Gossip statistics of a member: the inventory updates received for its nodes, and the size of their state", Params: []apiParam{
+			{Name: "name", Description: "Name of the member", InPath: true},
+		}, Response: PeerStats{}, Handler: m.apiPeerStats()},
+		{Path: "/api/v1/containers", Summary: "Containers of each node", Params: append([]apiParam{
+			{Name: "state", Description: "State of the listed containers", Enum: []string{StateRunning, StateExited, "all"}},
+			{Name: "scope", Description: "Only return the nodes collected by the receiving node, or the ones whose full inventory it keeps when the collection is sharded", Enum: []string{"local", "owned"}},
+			{Name: "flag", Description: "Only return the containers flagged with an anomaly", Enum: anomalyFlags},
+			{Name: "format", Description: "Deterministically ordered and normalized output, without volatile fields", Enum: []string{"stable"}},
+			fieldsParam("containers", Container{}),
+		}, longPollParams...), Response: []*NodeInventory{}, Handler: m.longPolled(m.apiContainers())},
+		{Path: "/api/v1/containers/{node}/{id}/annotations", Method: http.MethodPut, Summary: "Attach an ephemeral annotation to a container, gossiped and shown in the UI until it expires; an empty text clears it", Params: []apiParam{
+			{Name: "node", Description: "Node of the container", InPath: true},
+			{Name: "id", Description: "ID of the container, possibly truncated", InPath: true},
+			{Name: "text", Description: "Text of the annotation, such as \"under investigation\""},
+			{Name: "link", Description: "Link of the annotation, such as a ticket"},
+			{Name: "ttl", Description: "Lifetime of the annotation, such as 2h; 24h by default"},
+		}, Response: EphemeralAnnotation{}, Handler: m.apiSetEphemeralAnnotation()},
+		{Path: "/api/v1/ephemeral-annotations", Summary: "Active ephemeral annotations of the containers", Response: []EphemeralAnnotation{}, Handler: m.apiEphemeralAnnotations()},
+		{Path: "/api/v1/maintenance-windows", Summary: "Maintenance windows in progress and upcoming, during which the self-alerts and the policy violations of the selected nodes are silenced", Response: []MaintenanceWindow{}, Handler: m.apiMaintenanceWindows()},
+		{Path: "/api/v1/maintenance-windows/{id}", Method: http.MethodPut, Summary: "Schedule a maintenance window, gossiped to the cluster, replacing the previous one with the same ID", Params: []apiParam{
+			{Name: "id", Description: "ID of the window, such as kernel-upgrade", InPath: true},
+			{Name: "node", Description: "Glob pattern of the names of the selected nodes", Multiple: true},
+			{Name: "label", Description: "Label of the selected nodes, as key=value with key one of " + strings.Join(nodeLabelKeys, ", "), Multiple: true},
+			{Name: "start", Description: "Start of the window, as an RFC 3339 time; now by default"},
+			{Name: "end", Description: "End of the window, as an RFC 3339 time"},
+			{Name: "duration", Description: "Duration of the window, such as 2h, instead of its end"},
+			{Name: "reason", Description: "Reason of the maintenance"},
+			{Name: "cancel", Description: "Cancel the window instead", Enum: []string{"true"}},
+		}, Response: MaintenanceWindow{}, Admin: true, Handler: m.requireAdmin(m.apiSetMaintenanceWindow())},
+		{Path: "/api/v1/views", Summary: "Views of the containers table saved on the receiving node by the user of the request, along with the shared views", Response: []View{}, Handler: m.apiViews()},
+		{Path: "/api/v1/views/{name}", Method: http.MethodPut, Summary: "Save a view of the containers table on the receiving node, for the user of the request or shared, replacing the previous one with the same name, or delete it", Params: []apiParam{
+			{Name: "name", Description: "Name of the view, such as exited-by-image", InPath: true},
+			{Name: "query", Description: "Query string of the index page restoring the view, among the " + strings.Join(viewParams, ", ") + " parameters, such as state=all&sort=-created"},
+			{Name: "shared", Description: "Share the view with everyone instead of saving it for the user of the request", Enum: []string{"true"}},
+			{Name: "delete", Description: "Delete the view", Enum: []string{"true"}},
+		}, Response: View{}, Handler: m.apiSetView()},
+		{Path: "/api/v1/changes", Summary: "Inventories of the nodes changed since a sequence of the receiving node, including the tombstones of the decommissioned nodes, for external mirrors to sync incrementally", Params: []apiParam{
+			{Name: "since", Description: "Sequence from the previous sync; all the inventories when 0 or empty"},
+			{Name: "epoch", Description: "Epoch from the previous sync; all the inventories when it is not the one of the receiving node, such as after it restarted"},
+			{Name: "wait", Description: "Longest time to wait for the sequence to advance past since, such as 30s; at most " + maxLongPollWait.String()},
+		}, Response: Changes{}, Handler: m.apiChanges()},
+		{Path: "/api/v1/containers.parquet", Summary: "Containers of each node as a Parquet file, one row per container, to load into DuckDB or Spark", Params: []apiParam{
+			{Name: "state", Description: "State of the listed containers", Enum: []string{StateRunning, StateExited, "all"}},
+			{Name: "scope", Description: "Only return the nodes collected by the receiving node", Enum: []string{"local"}},
+			{Name: "flag", Description: "Only return the containers flagged with an anomaly", Enum: anomalyFlags},
+		}, ContentType: parquetContentType, Response: []ContainerRow{}, Handler: m.apiContainersParquet()},
+		{Path: "/api/v1/shards", Summary: "Shards of the nodes and the members aggregating them, when the collection is sharded", Response: []ShardInfo{}, Handler: m.apiShards()},
+		{Path: "/api/v1/events", Summary: "Stream of containers appearing, disappearing and reported by several nodes", Params: []apiParam{
+			{Name: "format", Description: "Format of the events: native, or cloudevents for CloudEvents 1.0 in structured JSON, whose SSE event names are their types; the default of the node when empty", Enum: []string{eventFormatNative, eventFormatCloudEvents}},
+		}, ContentType: "text/event-stream", Response: ContainerEvent{}, Handler: m.apiEvents()},
+		{Path: "/api/v1/conflicts", Summary: "Containers reported by several nodes, with the freshest claim kept and the others dropped from the listings", Response: []ContainerConflict{}, Handler: m.apiConflicts()},
+		{Path: "/api/v1/history", Summary: "History of the running containers", Params: []apiParam{
+			{Name: "tier", Description: "Resolution tier of the history", Enum: []string{tierRaw, tierDownsampled}},
+			{Name: "node", Description: "Nodes whose series are returned; all nodes by default", Multiple: true},
+		}, Response: History{}, Handler: m.apiHistory()},
+		{Path: "/api/v1/history.parquet", Summary: "Samples of all the tiers of the history of the running containers as a Parquet file", ContentType: parquetContentType, Response: []HistoryRow{}, Handler: m.apiHistoryParquet()},
+		{Path: "/api/v1/gpus", Summary: "GPUs attached to containers", Params: append([]apiParam{
+			fieldsParam("containers", GPUUsage{}),
+		}, longPollParams...), Response: []GPUUsage{}, Handler: m.longPolled(m.apiGPUs())},
+		{Path: "/api/v1/ports", Summary: "Host ports published by containers", Params: longPollParams, Response: []NodePorts{}, Handler: m.longPolled(m.apiPorts())},
+		{Path: "/api/v1/networks", Summary: "Networks of each node", Response: []*NodeResources[Network]{}, Handler: apiResources(m.networks)},
+		{Path: "/api/v1/volumes", Summary: "Volumes of each node", Response: []*NodeResources[Volume]{}, Handler: apiResources(m.volumes)},
+		{Path: "/api/v1/usage/containers", Summary: "Latest resource usage samples of the running containers of each node", Response: []*NodeResources[ContainerUsage]{}, Handler: apiResources(m.containerUsage)},
+		{Path: "/api/v1/usage/top", Summary: "Containers of the cluster using the most memory or CPU", Params: []apiParam{
+			{Name: "by", Description: "Resource by which the containers are sorted; memory by default", Enum: []string{"memory", "cpu"}},
+			{Name: "limit", Description: "Maximum number of containers; 20 by default"},
+		}, Response: []NodeContainerUsage{}, Handler: m.apiTop()},
+		{Path: "/api/v1/images", Summary: "Images of each node", Response: ImagesReport{}, Handler: m.apiImages()},
+		{Path: "/api/v1/sboms", Summary: "SBOMs of the running images, when fetched with -sbom", Response: []SBOMRef{}, Handler: m.apiSBOMs()},
+		{Path: "/api/v1/packages", Summary: "Packages of the running images, from their SBOMs", Params: []apiParam{
+			{Name: "name", Description: "Text contained in the package name, case-insensitively"},
+			{Name: "version", Description: "Prefix of the package version"},
+		}, Response: []PackageMatch{}, Handler: m.apiPackages()},
+		{Path: "/api/v1/policies", Summary: "Compliance policies checked against the running containers", Response: []Policy{}, Handler: m.apiPolicies()},
+		{Path: "/api/v1/violations", Summary: "Policy violations of the running containers, per node", Response: []NodeViolations{}, Handler: m.apiViolations()},
+		{Path: "/api/v1/reports/restart-loops", Summary: "Containers flagged as restart loops, grouped across nodes, such as an image crash-looping on most of the nodes running it", Params: []apiParam{
+			{Name: "by", Description: "Grouping of the containers; image by default", Enum: []string{"image", "name"}},
+		}, Response: []RestartLoopGroup{}, Handler: m.apiRestartLoops()},
+		{Path: "/api/v1/reports/allowlist", Summary: "Running containers whose image is outside the image allowlist, per node", Params: []apiParam{
+			{Name: "format", Description: "CSV export of the containers outside the allowlist, for compliance audits", Enum: []string{"csv"}},
+		}, Response: AllowlistReport{}, Handler: m.apiAllowlistReport()},
+		{Path: "/api/v1/costs", Summary: "Estimated hourly cost of the running containers, grouped by the cost label", Response: CostReport{}, Handler: m.apiCosts()},
+		{Path: "/api/v1/projects", Summary: "Compose projects and Swarm stacks", Params: longPollParams, Response: []Project{}, Handler: m.longPolled(m.apiProjects())},
+		{Path: "/api/v1/search", Summary: "Search containers and nodes", Params: []apiParam{
+			{Name: "q", Description: "Searched text"},
+		}, Response: SearchResults{}, Handler: m.apiSearch()},
+		{Path: "/api/v1/prune/candidates", Summary: "Containers and images recommended for pruning", Response: []*NodeResources[PruneCandidate]{}, Handler: apiResources(m.pruneCandidates)},
+		{Path: "/api/v1/update", Summary: "Whether a newer release than the one running on the receiving node is available in the release feed", Response: UpdateStatus{}, Handler: m.apiUpdate()},
+		{Path: "/api/v1/admin/prune", Method: http.MethodPost, Summary: "Prune stopped containers and unused images", Params: []apiParam{
+			{Name: "node", Description: "Node to prune; the receiving node by default"},
+		}, Response: PruneResult{}, Admin: true, Handler: m.requireAdmin(m.idempotency.Wrap(m.requireQuorum(m.apiPrune())))},
+		{Path: "/api/v1/admin/blocklist", Method: http.MethodPost, Summary: "Add or remove peer names or CIDRs of the blocklist, whose gossiped updates are dropped", Params: []apiParam{
+			{Name: "add", Description: "Peer name or CIDR to block", Multiple: true},
+			{Name: "remove", Description: "Peer name or CIDR to unblock", Multiple: true},
+		}, Response: []string{}, Admin: true, Handler: m.requireAdmin(m.apiBlocklist())},
+		{Path: "/api/v1/admin/cordon", Method: http.MethodPost, Summary: "Suspend or resume the polling of discovery sources of the receiving node", Params: []apiParam{
+			{Name: "cordon", Description: "Source to cordon, as named in the status", Multiple: true},
+			{Name: "uncordon", Description: "Source to uncordon", Multiple: true},
+			{Name: "reason", Description: "Reason of the cordons, shown in the status"},
+		}, Response: []SourceStatus{}, Admin: true, Handler: m.requireAdmin(m.apiCordon())},
+		{Path: "/api/v1/admin/decommission", Method: http.MethodPost, Summary: "Permanently remove the receiving node from the cluster, then exit", Response: DecommissionResult{}, Admin: true, Handler: m.requireAdmin(m.idempotency.Wrap(m.requireQuorum(m.apiDecommission())))},
+		{Path: "/api/v1/admin/debug/goroutines", Summary: "Stacks of the goroutines of a node", Params: []apiParam{
+			{Name: "node", Description: "Node to debug; the receiving node by default"},
+			{Name: "full", Description: "Dump each goroutine, as on a crash, instead of grouping the identical ones", Enum: []string{"true"}},
+		}, ContentType: "text/plain", Response: "", Admin: true, Handler: m.requireAdmin(m.apiGoroutines())},
+		{Path: "/api/v1/admin/debug/heap", Summary: "Heap profile of a node in the pprof format, for go tool pprof", Params: []apiParam{
+			{Name: "node", Description: "Node to debug; the receiving node by default"},
+			{Name: "gc", Description: "Run a garbage collection first, for the profile to be up to date", Enum: []string{"true"}},
+		}, ContentType: "application/octet-stream", Response: []byte{}, Admin: true, Handler: m.requireAdmin(m.apiHeapProfile())},
+		{Path: "/api/v1/admin/debug/gc", Summary: "Statistics of the garbage collector and of the heap of a node", Params: []apiParam{
+			{Name: "node", Description: "Node to debug; the receiving node by default"},
+		}, Response: GCStats{}, Admin: true, Handler: m.requireAdmin(m.apiGCStats())},
+		{Path: "/api/v1/admin/api-keys", Summary: "API keys of the receiving node, without the keys themselves", Response: []APIKey{}, Admin: true, Handler: m.requireAdmin(m.apiAPIKeys())},
+		{Path: "/api/v1/admin/api-keys/{name}", Method: http.MethodPut, Summary: "Create an API key of the receiving node, returning the key once", Params: []apiParam{
+			{Name: "name", Description: "Name of the key, such as ci-reports", InPath: true},
+			{Name: "role", Description: "Role of the key: viewer reads the API, editor also writes its non-admin endpoints and admin also calls the admin API", Enum: apiKeyRoles},
+			{Name: "ttl", Description: "Lifetime of the key, such as 720h; unlimited by default"},
+		}, Response: CreatedAPIKey{}, Admin: true, Handler: m.requireAdmin(m.apiSetAPIKey())},
+		{Path: "/api/v1/admin/api-keys/{name}", Method: http.MethodDelete, Summary: "Revoke an API key of the receiving node", Params: []apiParam{
+			{Name: "name", Description: "Name of the key", InPath: true},
+		}, Response: APIKey{}, Admin: true, Handler: m.requireAdmin(m.apiRevokeAPIKey())},
+	}
+}
+
+// openAPI returns the OpenAPI 3 document describing the API endpoints.
+func openAPI(endpoints []apiEndpoint) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, e := range endpoints {
+		method := e.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		contentType := e.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		params := []interface{}{}
+		for _, p := range e.Params {
+			schema := map[string]interface{}{"type": "string"}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			if p.Multiple {
+				schema = map[string]interface{}{"type": "array", "items": schema}
+			}
+			param := map[string]interface{}{
+				"name":        p.Name,
+				"in":          "query",
+				"description": p.Description,
+				"schema":      schema,
+			}
+			if p.InPath {
+				param["in"] = "path"
+				param["required"] = true
+			}
+			params = append(params, param)
+		}
+
+		op := map[string]interface{}{
+			"summary":    e.Summary,
+			"parameters": params,
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": e.Summary,
+					"content": map[string]interface{}{
+						contentType: map[string]interface{}{"schema": jsonSchema(reflect.TypeOf(e.Response))},
+					},
+				},
+				"default": map[string]interface{}{
+					"description": "Error, with a stable code",
+					"content": map[string]interface{}{
+						"application/problem+json": map[string]interface{}{"schema": jsonSchema(reflect.TypeOf(Problem{}))},
+					},
+				},
+			},
+		}
+		if e.Admin {
+			op["security"] = []interface{}{map[string]interface{}{"adminToken": []string{}}}
+		}
+		item, ok := paths[e.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[e.Path] = item
+		}
+		item[strings.ToLower(method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "containerslist",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	annotationType = reflect.TypeOf(AnnotationValue{})
+)
+
+// jsonSchema returns the JSON schema of the JSON encoding of a type.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == annotationType {
+		return map[string]interface{}{"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "number"},
+			map[string]interface{}{"type": "boolean"},
+		}}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := jsonSchema(t.Elem())
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = jsonSchema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]interface{}{}
+}
+
+func (m *Manager) apiOpenAPI() http.Handler {
+	doc := openAPI(m.apiEndpoints())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, doc)
+	})
+}
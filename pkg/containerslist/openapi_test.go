@@ -0,0 +1,286 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+var (
+	marshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// customJSON reports whether a type has a custom JSON encoding or decoding.
+func customJSON(t reflect.Type) bool {
+	for _, i := range []reflect.Type{marshalerType, unmarshalerType} {
+		if t.Implements(i) || reflect.PointerTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonShape returns the JSON types of the values of a Go type, by path, such
+// as ".containers[].labels{}" for the values of the labels of the containers.
+// The values of the types with a custom encoding are of any type.
+func jsonShape(t reflect.Type) map[string]string {
+	shape := map[string]string{}
+	var walk func(path string, t reflect.Type, depth int)
+	walk = func(path string, t reflect.Type, depth int) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case depth > 10:
+			// Recursive type.
+			return
+		case t == timeType:
+			shape[path] = "time"
+			return
+		case customJSON(t):
+			shape[path] = "any"
+			return
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+				switch {
+				case !f.IsExported() || name == "-":
+				case f.Anonymous && name == "":
+					walk(path, f.Type, depth)
+				default:
+					if name == "" {
+						name = f.Name
+					}
+					walk(path+"."+name, f.Type, depth+1)
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				shape[path] = "string"
+				return
+			}
+			walk(path+"[]", t.Elem(), depth+1)
+		case reflect.Map:
+			walk(path+"{}", t.Elem(), depth+1)
+		case reflect.Interface:
+			shape[path] = "any"
+		case reflect.String:
+			shape[path] = "string"
+		case reflect.Bool:
+			shape[path] = "bool"
+		default:
+			shape[path] = "number"
+		}
+	}
+	walk("", t, 0)
+	return shape
+}
+
+// shapeDiff returns the differences between the JSON shapes of the response
+// of an endpoint and of the type decoding it in the client. The values of any
+// type match all the values below them.
+func shapeDiff(server, client map[string]string) []string {
+	covered := func(path string, shape map[string]string) bool {
+		for p := path; p != ""; {
+			if shape[p] == "any" {
+				return true
+			}
+			i := strings.LastIndexAny(p, ".[{")
+			if i < 0 {
+				break
+			}
+			p = p[:i]
+		}
+		return shape[""] == "any"
+	}
+	var diff []string
+	for path, typ := range server {
+		switch c, ok := client[path]; {
+		case !ok && !covered(path, client):
+			diff = append(diff, "missing "+path)
+		case ok && c != typ && c != "any" && typ != "any":
+			diff = append(diff, "type of "+path+": "+c+", want "+typ)
+		}
+	}
+	for path := range client {
+		if _, ok := server[path]; !ok && !covered(path, server) {
+			diff = append(diff, "unknown "+path)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// testArg returns an argument of a client method.
+func testArg(t reflect.Type) reflect.Value {
+	switch {
+	case t == reflect.TypeOf((*context.Context)(nil)).Elem():
+		return reflect.ValueOf(context.Background())
+	case t == reflect.TypeOf(time.Duration(0)):
+		return reflect.ValueOf(time.Minute)
+	case t == timeType:
+		return reflect.ValueOf(time.Now())
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			v = reflect.ValueOf([]string{"x"}).Convert(t)
+		}
+	case reflect.Func:
+		v = reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+			out := make([]reflect.Value, t.NumOut())
+			for i := range out {
+				out[i] = reflect.Zero(t.Out(i))
+			}
+			return out
+		})
+	}
+	return v
+}
+
+// endpointPattern matches the paths of an endpoint.
+func endpointPattern(e apiEndpoint) *regexp.Regexp {
+	expr := regexp.QuoteMeta(e.Path)
+	expr = regexp.MustCompile(`\\\{[a-z]+\\\}`).ReplaceAllString(expr, `[^/]+`)
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// TestClientMatchesOpenAPI calls each method of the client, checking that
+// it calls an endpoint of the API and decodes its response into a type of
+// the same JSON shape, and that all the endpoints are covered.
+func TestClientMatchesOpenAPI(t *testing.T) {
+	m, _ := newTestAPIKeysManager(t)
+	endpoints := m.apiEndpoints()
+	patterns := make([]*regexp.Regexp, len(endpoints))
+	for i, e := range endpoints {
+		patterns[i] = endpointPattern(e)
+	}
+	endpointOf := func(method, path string) (int, bool) {
+		for i, e := range endpoints {
+			m := e.Method
+			if m == "" {
+				m = http.MethodGet
+			}
+			if m == method && patterns[i].MatchString(path) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	var (
+		mtx   sync.Mutex
+		calls []string
+		// format is the format requested by the last call, such as csv.
+		format string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		format = r.URL.Query().Get("format")
+		mtx.Unlock()
+		i, ok := endpointOf(r.Method, r.URL.Path)
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case endpoints[i].ContentType != "":
+			w.Header().Set("Content-Type", endpoints[i].ContentType)
+		default:
+			writeJSON(w, nil)
+		}
+	}))
+	defer srv.Close()
+	c := client.New(srv.URL)
+
+	covered := make([]bool, len(endpoints))
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumMethod(); i++ {
+		method := v.Type().Method(i)
+		t.Run(method.Name, func(t *testing.T) {
+			mt := method.Type
+			args := make([]reflect.Value, mt.NumIn()-1)
+			for j := range args {
+				args[j] = testArg(mt.In(j + 1))
+			}
+			mtx.Lock()
+			calls = nil
+			mtx.Unlock()
+			call := v.Method(i).Call
+			if mt.IsVariadic() {
+				call = v.Method(i).CallSlice
+			}
+			out := call(args)
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			if len(calls) != 1 {
+				t.Fatalf("%d requests sent, want 1: %q", len(calls), calls)
+			}
+			method, path, _ := strings.Cut(calls[0], " ")
+			j, ok := endpointOf(method, path)
+			if !ok {
+				t.Fatalf("%s is not an endpoint of the API", calls[0])
+			}
+			covered[j] = true
+
+			e := endpoints[j]
+			var got reflect.Type
+			switch {
+			case e.ContentType == "text/event-stream":
+				// The events are passed to a callback.
+				for k := 1; k < mt.NumIn(); k++ {
+					if mt.In(k).Kind() == reflect.Func {
+						got = mt.In(k).In(0)
+					}
+				}
+			case e.ContentType != "":
+				return
+			case format != "":
+				// Another format than the JSON default, such as CSV.
+				return
+			case len(out) > 1:
+				got = mt.Out(0)
+			}
+			if got == nil {
+				t.Fatalf("no decoded response of %s %s", method, e.Path)
+			}
+			for _, d := range shapeDiff(jsonShape(reflect.TypeOf(e.Response)), jsonShape(got)) {
+				t.Errorf("%s %s: %s", method, e.Path, d)
+			}
+		})
+	}
+	for i, e := range endpoints {
+		if !covered[i] {
+			m := e.Method
+			if m == "" {
+				m = http.MethodGet
+			}
+			t.Errorf("%s %s not covered by the client", m, e.Path)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package containerslist
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultUIPageSize = 200
+	// maxUIPageSize bounds the rows of a page of the UI.
+	maxUIPageSize = 5000
+	// uiChunkSize is the number of rows of the chunks of the tables, each
+	// rendered by the browser only once scrolled into view.
+	uiChunkSize = 50
+)
+
+// pagerTpl links to the other pages of a table.
+const pagerTpl = `<p class="pager">{{ if .Total }}{{ t "Containers %d to %d of %d" .From .To .Total }}{{ if gt .Pages 1 }} | {{ t "page %d of %d" .Page .Pages }}:
+  {{ with .First }}<a href="{{ . }}">{{ t "first" }}</a>{{ else }}{{ t "first" }}{{ end }}
+  {{ with .Prev }}<a href="{{ . }}" rel="prev">{{ t "previous" }}</a>{{ else }}{{ t "previous" }}{{ end }}
+  {{ with .Next }}<a href="{{ . }}" rel="next">{{ t "next" }}</a>{{ else }}{{ t "next" }}{{ end }}
+  {{ with .Last }}<a href="{{ . }}">{{ t "last" }}</a>{{ else }}{{ t "last" }}{{ end }}{{ end }}{{ end }}</p>`
+
+// uiContainerRow is a row of the containers table of the UI.
+type uiContainerRow struct {
+	Node string
+	Container
+}
+
+// containersPage is a page of the containers table of the UI, split into
+// chunks.
+type containersPage struct {
+	Chunks [][]uiContainerRow
+	// Page is the number of the page, from 1, out of Pages.
+	Page, Pages int
+	// From and To are the numbers of the first and last rows of the page,
+	// from 1, out of Total.
+	From, To, Total int
+	// PerPage is the number of rows of the pages.
+	PerPage int
+	// First, Prev, Next and Last are the URLs of the other pages, empty when
+	// they are the current one.
+	First, Prev, Next, Last string
+}
+
+// containerSortKeys are the keys by which the containers table is sorted,
+// descending with a - prefix.
+var containerSortKeys = []string{"node", "name", "image", "state", "created"}
+
+// containerRows returns the rows of the containers of the nodes matching the
+// q parameter of a query, case-insensitively by node, name or image, sorted
+// by its sort parameter: by node by default.
+func containerRows(nodes []*NodeInventory, q url.Values) ([]uiContainerRow, error) {
+	key := q.Get("sort")
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+	if key != "" && !contains(containerSortKeys, key) {
+		return nil, fmt.Errorf("invalid sort %q: must be one of %s, optionally prefixed by -", q.Get("sort"), strings.Join(containerSortKeys, ", "))
+	}
+
+	match := strings.ToLower(q.Get("q"))
+	rows := []uiContainerRow{}
+	for _, n := range nodes {
+		for _, c := range n.Containers {
+			if match != "" && !strings.Contains(strings.ToLower(n.Node+" "+c.Name+" "+c.Image), match) {
+				continue
+			}
+			rows = append(rows, uiContainerRow{Node: n.Node, Container: c})
+		}
+	}
+
+	less := func(a, b uiContainerRow) bool { return a.Node < b.Node }
+	switch key {
+	case "name":
+		less = func(a, b uiContainerRow) bool { return a.Name < b.Name }
+	case "image":
+		less = func(a, b uiContainerRow) bool { return a.Image < b.Image }
+	case "state":
+		less = func(a, b uiContainerRow) bool { return a.State < b.State }
+	case "created":
+		less = func(a, b uiContainerRow) bool { return a.Created.Before(b.Created) }
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+	return rows, nil
+}
+
+// paginateContainers returns the page of the rows requested by the page and
+// per_page parameters of a query.
+func paginateContainers(rows []uiContainerRow, u *url.URL, perPage int) (containersPage, error) {
+	q := u.Query()
+	p := containersPage{Page: 1, PerPage: perPage}
+	if s := q.Get("per_page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxUIPageSize {
+			return p, fmt.Errorf("invalid per_page %q: must be between 1 and %d", s, maxUIPageSize)
+		}
+		p.PerPage = n
+	}
+	if s := q.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("invalid page %q: must be a positive number", s)
+		}
+		p.Page = n
+	}
+
+	p.Total = len(rows)
+	p.Pages = (p.Total + p.PerPage - 1) / p.PerPage
+	if p.Pages == 0 {
+		p.Pages = 1
+	}
+	if p.Page > p.Pages {
+		p.Page = p.Pages
+	}
+	p.From = (p.Page-1)*p.PerPage + 1
+	p.To = p.From + p.PerPage - 1
+	if p.To > p.Total {
+		p.To = p.Total
+	}
+
+	for start := p.From - 1; start < p.To; start += uiChunkSize {
+		end := start + uiChunkSize
+		if end > p.To {
+			end = p.To
+		}
+		p.Chunks = append(p.Chunks, rows[start:end])
+	}
+
+	link := func(page int) string {
+		if page == p.Page {
+			return ""
+		}
+		q.Set("page", strconv.Itoa(page))
+		return "?" + q.Encode()
+	}
+	p.First, p.Last = link(1), link(p.Pages)
+	if p.Page > 1 {
+		p.Prev = link(p.Page - 1)
+	}
+	if p.Page < p.Pages {
+		p.Next = link(p.Page + 1)
+	}
+	return p, nil
+}
@@ -0,0 +1,49 @@
+package containerslist
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestPaginateContainers(t *testing.T) {
+	rows := make([]uiContainerRow, 120)
+	for i := range rows {
+		rows[i].Name = fmt.Sprint(i)
+	}
+	u, err := url.Parse("/?state=running&per_page=55&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := paginateContainers(rows, u, defaultUIPageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 2 || p.Pages != 3 || p.From != 56 || p.To != 110 || p.Total != 120 || p.PerPage != 55 {
+		t.Errorf("page = %+v, want the rows 56 to 110", p)
+	}
+	if len(p.Chunks) != 2 || len(p.Chunks[0]) != uiChunkSize || len(p.Chunks[1]) != 5 || p.Chunks[0][0].Name != "55" {
+		t.Errorf("chunks of %d rows, want 50 and 5 rows from 55", len(p.Chunks))
+	}
+	if p.Prev != "?page=1&per_page=55&state=running" || p.First != p.Prev || p.Next != "?page=3&per_page=55&state=running" || p.Last != p.Next {
+		t.Errorf("links = %q, %q, %q, %q, want the pages 1 and 3 keeping the filters", p.First, p.Prev, p.Next, p.Last)
+	}
+
+	// Pages past the last one show the last one.
+	p, err = paginateContainers(rows, &url.URL{RawQuery: "page=9"}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 2 || p.From != 101 || p.To != 120 || p.Next != "" || p.Last != "" {
+		t.Errorf("page = %+v, want the last one", p)
+	}
+	if p, err := paginateContainers(nil, &url.URL{}, 100); err != nil || p.Pages != 1 || p.Total != 0 || len(p.Chunks) != 0 {
+		t.Errorf("empty page = %+v, %v, want a single page", p, err)
+	}
+
+	for _, query := range []string{"page=0", "page=first", "per_page=0", "per_page=5001"} {
+		if _, err := paginateContainers(rows, &url.URL{RawQuery: query}, 100); err == nil {
+			t.Errorf("%s accepted, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package containerslist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// parquetContentType is the media type of Parquet files.
+const parquetContentType = "application/vnd.apache.parquet"
+
+// Parquet physical types, repetitions, converted types and encodings, as
+// defined by parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+
+	parquetUTF8            = 0
+	parquetMap             = 1
+	parquetList            = 3
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+// parquetElement is an element of the schema of a Parquet file, flattened in
+// depth-first order: a group has children, and a leaf a physical type.
+type parquetElement struct {
+	name       string
+	typ        int32 // -1 for groups
+	repetition int32
+	converted  int32 // -1 when none
+	children   int32
+}
+
+// parquetColumn is a leaf column of a Parquet file, holding its PLAIN-encoded
+// values along with their repetition and definition levels. The null values
+// and empty lists are only recorded in the levels.
+type parquetColumn struct {
+	path           []string
+	typ            int32
+	maxDef, maxRep int
+
+	values bytes.Buffer
+	bools  []bool
+	defs   []int
+	reps   []int
+}
+
+// parquetTable is a table written as a Parquet file with a single row group,
+// each column being a single uncompressed data page. It is meant for the
+// inventory and history exports, which fit in memory anyway.
+type parquetTable struct {
+	schema  []parquetElement
+	roots   int32
+	columns []*parquetColumn
+	rows    int64
+}
+
+// column adds a column of scalar values.
+func (t *parquetTable) column(name string, typ, converted int32, optional bool) *parquetColumn {
+	e := parquetElement{name: name, typ: typ, repetition: parquetRequired, converted: converted}
+	c := &parquetColumn{path: []string{name}, typ: typ}
+	if optional {
+		e.repetition = parquetOptional
+		c.maxDef = 1
+	}
+	t.roots++
+	t.schema = append(t.schema, e)
+	t.columns = append(t.columns, c)
+	return c
+}
+
+// list adds a column of lists of strings, with the three-level LIST
+// structure, whose values are appended with Strings.
+func (t *parquetTable) list(name string) *parquetColumn {
+	t.roots++
+	t.schema = append(t.schema,
+		parquetElement{name: name, typ: -1, repetition: parquetRequired, converted: parquetList, children: 1},
+		parquetElement{name: "list", typ: -1, repetition: parquetRepeated, converted: -1, children: 1},
+		parquetElement{name: "element", typ: parquetByteArray, repetition: parquetRequired, converted: parquetUTF8},
+	)
+	c := &parquetColumn{path: []string{name, "list", "element"}, typ: parquetByteArray, maxDef: 1, maxRep: 1}
+	t.columns = append(t.columns, c)
+	return c
+}
+
+// stringMap adds a column of maps of strings, with the MAP structure, whose
+// keys and values are appended with Strings.
+func (t *parquetTable) stringMap(name string) (keys, values *parquetColumn) {
+	t.roots++
+	t.schema = append(t.schema,
+		parquetElement{name: name, typ: -1, repetition: parquetRequired, converted: parquetMap, children: 1},
+		parquetElement{name: "key_value", typ: -1, repetition: parquetRepeated, converted: -1, children: 2},
+		parquetElement{name: "key", typ: parquetByteArray, repetition: parquetRequired, converted: parquetUTF8},
+		parquetElement{name: "value", typ: parquetByteArray, repetition: parquetRequired, converted: parquetUTF8},
+	)
+	keys = &parquetColumn{path: []string{name, "key_value", "key"}, typ: parquetByteArray, maxDef: 1, maxRep: 1}
+	values = &parquetColumn{path: []string{name, "key_value", "value"}, typ: parquetByteArray, maxDef: 1, maxRep: 1}
+	t.columns = append(t.columns, keys, values)
+	return keys, values
+}
+
+// level records the levels of a value, or of a null when def is below the
+// maximum definition level.
+func (c *parquetColumn) level(def, rep int) {
+	c.defs = append(c.defs, def)
+	c.reps = append(c.reps, rep)
+}
+
+func (c *parquetColumn) putString(s string) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+	c.values.WriteString(s)
+}
+
+func (c *parquetColumn) String(s string) {
+	c.putString(s)
+	c.level(c.maxDef, 0)
+}
+
+// Strings appends a list of strings to a repeated column, an empty list being
+// recorded as undefined.
+func (c *parquetColumn) Strings(values []string) {
+	if len(values) == 0 {
+		c.level(0, 0)
+		return
+	}
+	for i, v := range values {
+		c.putString(v)
+		c.level(c.maxDef, min(i, c.maxRep))
+	}
+}
+
+func (c *parquetColumn) Int64(v int64) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+	c.level(c.maxDef, 0)
+}
+
+func (c *parquetColumn) Double(v float64) {
+	binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+	c.level(c.maxDef, 0)
+}
+
+func (c *parquetColumn) Bool(v bool) {
+	c.bools = append(c.bools, v)
+	c.level(c.maxDef, 0)
+}
+
+// Time appends a timestamp in milliseconds, or a null for a nil time.
+func (c *parquetColumn) Time(t *time.Time) {
+	if t == nil {
+		c.Null()
+		return
+	}
+	c.Int64(t.UnixMilli())
+}
+
+func (c *parquetColumn) Null() {
+	c.level(0, 0)
+}
+
+// writeLevels writes levels as RLE runs, prefixed by their length.
+func writeLevels(b *bytes.Buffer, levels []int, max int) {
+	width := (bits.Len(uint(max)) + 7) / 8
+	var runs bytes.Buffer
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		runs.Write(binary.AppendUvarint(nil, uint64(j-i)<<1))
+		for k := 0; k < width; k++ {
+			runs.WriteByte(byte(levels[i] >> (8 * k)))
+		}
+		i = j
+	}
+	binary.Write(b, binary.LittleEndian, uint32(runs.Len()))
+	b.Write(runs.Bytes())
+}
+
+// page returns the data page of the column: its repetition and definition
+// levels, when it has any, followed by its values.
+func (c *parquetColumn) page() []byte {
+	var b bytes.Buffer
+	if c.maxRep > 0 {
+		writeLevels(&b, c.reps, c.maxRep)
+	}
+	if c.maxDef > 0 {
+		writeLevels(&b, c.defs, c.maxDef)
+	}
+	if c.typ == parquetBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		b.Write(packed)
+	}
+	b.Write(c.values.Bytes())
+	return b.Bytes()
+}
+
+// Bytes returns the Parquet file of the table.
+func (t *parquetTable) Bytes() []byte {
+	var b bytes.Buffer
+	b.WriteString("PAR1")
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(t.columns))
+	for i, c := range t.columns {
+		page := c.page()
+		var h thriftWriter
+		h.begin()
+		h.i32(1, 0) // DATA_PAGE
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(page)))
+		h.structField(5)
+		h.i32(1, int32(len(c.defs)))
+		h.i32(2, parquetPlain)
+		h.i32(3, parquetRLE)
+		h.i32(4, parquetRLE)
+		h.end()
+		h.end()
+
+		chunks[i].offset = int64(b.Len())
+		b.Write(h.buf.Bytes())
+		b.Write(page)
+		chunks[i].size = int64(b.Len()) - chunks[i].offset
+	}
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(t.schema)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, t.roots)
+	meta.end()
+	for _, e := range t.schema {
+		meta.begin()
+		if e.typ >= 0 {
+			meta.i32(1, e.typ)
+		}
+		meta.i32(3, e.repetition)
+		meta.binary(4, e.name)
+		if e.typ < 0 {
+			meta.i32(5, e.children)
+		}
+		if e.converted >= 0 {
+			meta.i32(6, e.converted)
+			meta.logicalType(e.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, t.rows)
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(t.columns))
+	var total int64
+	for i, c := range t.columns {
+		meta.begin()
+		meta.i64(2, chunks[i].offset)
+		meta.structField(3)
+		meta.i32(1, c.typ)
+		meta.list(2, thriftI32, 2)
+		meta.listI32(parquetPlain)
+		meta.listI32(parquetRLE)
+		meta.list(3, thriftBinary, len(c.path))
+		for _, p := range c.path {
+			meta.listBinary(p)
+		}
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(len(c.defs)))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.end()
+		meta.end()
+		total += chunks[i].size
+	}
+	meta.i64(2, total)
+	meta.i64(3, t.rows)
+	meta.end()
+	meta.binary(6, "containerslist")
+	meta.end()
+
+	b.Write(meta.buf.Bytes())
+	binary.Write(&b, binary.LittleEndian, uint32(meta.buf.Len()))
+	b.WriteString("PAR1")
+	return b.Bytes()
+}
+
+// logicalType writes the logical type matching a converted type, which the
+// recent readers expect along with it.
+func (w *thriftWriter) logicalType(converted int32) {
+	w.structField(10)
+	switch converted {
+	case parquetUTF8:
+		w.structField(1)
+	case parquetMap:
+		w.structField(2)
+	case parquetList:
+		w.structField(3)
+	case parquetTimestampMillis:
+		w.structField(8)
+		w.bool(1, true) // isAdjustedToUTC
+		w.structField(2)
+		w.structField(1) // MILLIS
+		w.end()
+		w.end()
+	}
+	w.end()
+	w.end()
+}
+
+// Thrift compact protocol types.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, as used by
+// the Parquet metadata.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	last   int16
+	parent []int16
+}
+
+// begin starts a struct, either at top level or as a list element.
+func (w *thriftWriter) begin() {
+	w.parent = append(w.parent, w.last)
+	w.last = 0
+}
+
+// end ends a struct.
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.parent[len(w.parent)-1]
+	w.parent = w.parent[:len(w.parent)-1]
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if d := id - w.last; d > 0 && d <= 15 {
+		w.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.last = id
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.buf.Write(binary.AppendVarint(nil, v))
+}
+
+func (w *thriftWriter) bool(id int16, v bool) {
+	if v {
+		w.field(id, thriftTrue)
+	} else {
+		w.field(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) binary(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.listBinary(s)
+}
+
+// structField starts a struct field, ended with end.
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+// list starts a list field of n elements, each written with begin and end
+// for structs, or with listI32 or listBinary.
+func (w *thriftWriter) list(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elem)
+	w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (w *thriftWriter) listI32(v int32) {
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) listBinary(s string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.buf.WriteString(s)
+}
+
+// ContainerRow is a row of the columnar export of the containers.
+type ContainerRow struct {
+	Node         string            `json:"node"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	ImageID      string            `json:"image_id"`
+	State        string            `json:"state"`
+	Status       string            `json:"status"`
+	Created      time.Time         `json:"created"`
+	StartedAt    *time.Time        `json:"started_at"`
+	FinishedAt   *time.Time        `json:"finished_at"`
+	RestartCount int64             `json:"restart_count"`
+	OOMKilled    bool              `json:"oom_killed"`
+	Labels       map[string]string `json:"labels"`
+	Flags        []string          `json:"flags"`
+}
+
+// containersParquet returns the containers of the nodes as a Parquet file,
+// one row per container.
+func containersParquet(nodes []*NodeInventory) []byte {
+	var t parquetTable
+	node := t.column("node", parquetByteArray, parquetUTF8, false)
+	id := t.column("id", parquetByteArray, parquetUTF8, false)
+	name := t.column("name", parquetByteArray, parquetUTF8, false)
+	image := t.column("image", parquetByteArray, parquetUTF8, false)
+	imageID := t.column("image_id", parquetByteArray, parquetUTF8, false)
+	state := t.column("state", parquetByteArray, parquetUTF8, false)
+	status := t.column("status", parquetByteArray, parquetUTF8, false)
+	created := t.column("created", parquetInt64, parquetTimestampMillis, false)
+	startedAt := t.column("started_at", parquetInt64, parquetTimestampMillis, true)
+	finishedAt := t.column("finished_at", parquetInt64, parquetTimestampMillis, true)
+	restartCount := t.column("restart_count", parquetInt64, -1, false)
+	oomKilled := t.column("oom_killed", parquetBoolean, -1, false)
+	labelKeys, labelValues := t.stringMap("labels")
+	flags := t.list("flags")
+	for _, n := range nodes {
+		for _, c := range n.Containers {
+			node.String(n.Node)
+			id.String(c.ID)
+			name.String(c.Name)
+			image.String(c.Image)
+			imageID.String(c.ImageID)
+			state.String(c.State)
+			status.String(c.Status)
+			created.Time(&c.Created)
+			startedAt.Time(c.StartedAt)
+			finishedAt.Time(c.FinishedAt)
+			restartCount.Int64(int64(c.RestartCount))
+			oomKilled.Bool(c.OOMKilled)
+			keys := make([]string, 0, len(c.Labels))
+			for k := range c.Labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			values := make([]string, len(keys))
+			for i, k := range keys {
+				values[i] = c.Labels[k]
+			}
+			labelKeys.Strings(keys)
+			labelValues.Strings(values)
+			flags.Strings(c.Flags)
+			t.rows++
+		}
+	}
+	return t.Bytes()
+}
+
+// HistoryRow is a row of the columnar export of the history. Node is null
+// for the cluster-wide series.
+type HistoryRow struct {
+	Tier  string    `json:"tier"`
+	Node  *string   `json:"node"`
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// historyParquet returns the samples of all the tiers of a history as a
+// Parquet file, one row per sample.
+func historyParquet(s historySnapshot) []byte {
+	var t parquetTable
+	tier := t.column("tier", parquetByteArray, parquetUTF8, false)
+	node := t.column("node", parquetByteArray, parquetUTF8, true)
+	ts := t.column("time", parquetInt64, parquetTimestampMillis, false)
+	value := t.column("value", parquetDouble, -1, false)
+
+	tiers := make([]string, 0, len(s))
+	for name := range s {
+		tiers = append(tiers, name)
+	}
+	sort.Strings(tiers)
+	add := func(name string, n *string, samples []Sample) {
+		for _, sample := range samples {
+			tier.String(name)
+			if n == nil {
+				node.Null()
+			} else {
+				node.String(*n)
+			}
+			ts.Time(&sample.Time)
+			value.Double(sample.Value)
+			t.rows++
+		}
+	}
+	for _, name := range tiers {
+		add(name, nil, s[name].Cluster)
+		nodes := make([]string, 0, len(s[name].Nodes))
+		for n := range s[name].Nodes {
+			nodes = append(nodes, n)
+		}
+		sort.Strings(nodes)
+		for _, n := range nodes {
+			add(name, &n, s[name].Nodes[n])
+		}
+	}
+	return t.Bytes()
+}
+
+func writeParquet(w http.ResponseWriter, b []byte) {
+	w.Header().Set("Content-Type", parquetContentType)
+	w.Write(b)
+}
+
+func (m *Manager) apiContainersParquet() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nodes, failed, err := m.containers(r)
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		setPartial(w, failed)
+		writeParquet(w, containersParquet(nodes))
+	})
+}
+
+func (m *Manager) apiHistoryParquet() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeParquet(w, historyParquet(m.history.Snapshot()))
+	})
+}
@@ -0,0 +1,168 @@
+package containerslist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// parquetContainer is a row of the containers export, as read back by
+// parquet-go.
+type parquetContainer struct {
+	Node         string            `parquet:"node"`
+	ID           string            `parquet:"id"`
+	Name         string            `parquet:"name"`
+	Image        string            `parquet:"image"`
+	ImageID      string            `parquet:"image_id"`
+	State        string            `parquet:"state"`
+	Status       string            `parquet:"status"`
+	Created      int64             `parquet:"created"`
+	StartedAt    *int64            `parquet:"started_at,optional"`
+	FinishedAt   *int64            `parquet:"finished_at,optional"`
+	RestartCount int64             `parquet:"restart_count"`
+	OOMKilled    bool              `parquet:"oom_killed"`
+	Labels       map[string]string `parquet:"labels"`
+	Flags        []string          `parquet:"flags,list"`
+}
+
+// parquetHistory is a row of the history export, as read back by
+// parquet-go.
+type parquetHistory struct {
+	Tier  string  `parquet:"tier"`
+	Node  *string `parquet:"node,optional"`
+	Time  int64   `parquet:"time"`
+	Value float64 `parquet:"value"`
+}
+
+func millis(t time.Time) *int64 {
+	ms := t.UnixMilli()
+	return &ms
+}
+
+func TestContainersParquet(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	started := created.Add(time.Minute)
+	finished := started.Add(time.Hour)
+	nodes := []*NodeInventory{
+		{Node: "node-1", Containers: []Container{
+			{
+				ID: "a", Name: "web", Image: "nginx", ImageID: "sha256:1", State: "running", Status: "Up",
+				Created: created, StartedAt: &started, RestartCount: 2,
+				Labels: map[string]string{"team": "web", "app": "nginx"}, Flags: []string{"privileged", "host-network"},
+			},
+			{
+				ID: "b", Name: "job", Image: "busybox", State: "exited", Status: "Exited (137)",
+				Created: created, StartedAt: &started, FinishedAt: &finished, OOMKilled: true,
+			},
+		}},
+		{Node: "node-2"},
+		{Node: "node-3", Containers: []Container{
+			{ID: "c", Name: "new", Image: "redis", State: "created", Created: created, Labels: map[string]string{"": ""}, Flags: []string{"root"}},
+		}},
+	}
+
+	b := containersParquet(nodes)
+	rows, err := parquet.Read[parquetContainer](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []parquetContainer{
+		{
+			Node: "node-1", ID: "a", Name: "web", Image: "nginx", ImageID: "sha256:1", State: "running", Status: "Up",
+			Created: created.UnixMilli(), StartedAt: millis(started), RestartCount: 2,
+			Labels: map[string]string{"app": "nginx", "team": "web"}, Flags: []string{"privileged", "host-network"},
+		},
+		{
+			Node: "node-1", ID: "b", Name: "job", Image: "busybox", State: "exited", Status: "Exited (137)",
+			Created: created.UnixMilli(), StartedAt: millis(started), FinishedAt: millis(finished), OOMKilled: true,
+		},
+		{
+			Node: "node-3", ID: "c", Name: "new", Image: "redis", State: "created",
+			Created: created.UnixMilli(), Labels: map[string]string{"": ""}, Flags: []string{"root"},
+		},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		// Empty lists and maps are read back as nil or empty.
+		if len(rows[i].Labels) == 0 && len(want[i].Labels) == 0 {
+			rows[i].Labels = want[i].Labels
+		}
+		if len(rows[i].Flags) == 0 && len(want[i].Flags) == 0 {
+			rows[i].Flags = want[i].Flags
+		}
+		if !reflect.DeepEqual(rows[i], want[i]) {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	elements := map[string]format.SchemaElement{}
+	for _, e := range f.Metadata().Schema {
+		elements[e.Name] = e
+	}
+	for _, tc := range []struct {
+		column     string
+		repetition format.FieldRepetitionType
+		logical    func(*format.LogicalType) bool
+	}{
+		{column: "created", repetition: format.Required, logical: func(l *format.LogicalType) bool { return l.Timestamp != nil }},
+		{column: "started_at", repetition: format.Optional, logical: func(l *format.LogicalType) bool { return l.Timestamp != nil }},
+		{column: "labels", repetition: format.Required, logical: func(l *format.LogicalType) bool { return l.Map != nil }},
+		{column: "flags", repetition: format.Required, logical: func(l *format.LogicalType) bool { return l.List != nil }},
+		{column: "element", repetition: format.Required, logical: func(l *format.LogicalType) bool { return l.UTF8 != nil }},
+	} {
+		e, ok := elements[tc.column]
+		if !ok {
+			t.Errorf("column %s missing", tc.column)
+			continue
+		}
+		if e.RepetitionType == nil || *e.RepetitionType != tc.repetition {
+			t.Errorf("column %s repetition = %v, want %v", tc.column, e.RepetitionType, tc.repetition)
+		}
+		if e.LogicalType == nil || !tc.logical(e.LogicalType) {
+			t.Errorf("column %s has logical type %v", tc.column, e.LogicalType)
+		}
+	}
+}
+
+func TestHistoryParquet(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := historySnapshot{
+		"raw": {
+			Cluster: []Sample{{Time: t0, Value: 3}, {Time: t0.Add(time.Minute), Value: 4}},
+			Nodes: map[string][]Sample{
+				"node-2": {{Time: t0, Value: 1}},
+				"node-1": {{Time: t0, Value: 2.5}},
+			},
+		},
+		"hourly": {
+			Cluster: []Sample{{Time: t0, Value: 3.5}},
+		},
+	}
+
+	b := historyParquet(s)
+	rows, err := parquet.Read[parquetHistory](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node1, node2 := "node-1", "node-2"
+	want := []parquetHistory{
+		{Tier: "hourly", Time: t0.UnixMilli(), Value: 3.5},
+		{Tier: "raw", Time: t0.UnixMilli(), Value: 3},
+		{Tier: "raw", Time: t0.Add(time.Minute).UnixMilli(), Value: 4},
+		{Tier: "raw", Node: &node1, Time: t0.UnixMilli(), Value: 2.5},
+		{Tier: "raw", Node: &node2, Time: t0.UnixMilli(), Value: 1},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+}
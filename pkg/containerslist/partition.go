@@ -0,0 +1,169 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PartitionStatus compares the size of the cluster as seen by the local node
+// against its expected size.
+type PartitionStatus struct {
+	Members  int `json:"members"`
+	Expected int `json:"expected"`
+	// Minority is set when the node does not see a strict majority of the
+	// expected members, and is likely cut from the rest of the cluster.
+	Minority bool `json:"minority"`
+}
+
+// partitionDetector detects whether the local node is in a minority
+// partition. The expected cluster size is configured, or else discovered as
+// the number of members seen since the node started which did not leave the
+// cluster gracefully: the failed members are still expected, while the ones
+// which were shut down or decommissioned are not.
+type partitionDetector struct {
+	members  func() []string
+	left     func() map[string]bool
+	expected int
+
+	mtx      sync.Mutex
+	seen     map[string]bool
+	minority bool
+}
+
+func newPartitionDetector(members func() []string, left func() map[string]bool, expected int, reg prometheus.Registerer) *partitionDetector {
+	d := &partitionDetector{
+		members:  members,
+		left:     left,
+		expected: expected,
+		seen:     map[string]bool{},
+	}
+	reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "containerslist_cluster_expected_members",
+			Help: "Expected number of members of the cluster.",
+		}, func() float64 { return float64(d.Status().Expected) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "containerslist_cluster_minority_partition",
+			Help: "Whether the node believes it is in a minority partition (1) or not (0).",
+		}, func() float64 {
+			if d.Status().Minority {
+				return 1
+			}
+			return 0
+		}),
+	)
+	return d
+}
+
+// Status returns the current partition status.
+func (d *partitionDetector) Status() PartitionStatus {
+	members := d.members()
+	left := d.left()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	alive := map[string]bool{}
+	for _, name := range members {
+		alive[name] = true
+		d.seen[name] = true
+	}
+	for name := range d.seen {
+		if left[name] && !alive[name] {
+			delete(d.seen, name)
+		}
+	}
+	s := PartitionStatus{Members: len(members), Expected: d.expected}
+	if s.Expected == 0 {
+		s.Expected = len(d.seen)
+	}
+	s.Minority = 2*s.Members <= s.Expected
+	return s
+}
+
+// Run periodically checks the partition status, logging transitions.
+func (d *partitionDetector) Run(ctx context.Context, logger log.Logger) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s := d.Status()
+		d.mtx.Lock()
+		changed := s.Minority != d.minority
+		d.minority = s.Minority
+		d.mtx.Unlock()
+		if !changed {
+			continue
+		}
+		if s.Minority {
+			level.Warn(logger).Log("msg", "Node is in a minority partition", "members", s.Members, "expected", s.Expected)
+		} else {
+			level.Info(logger).Log("msg", "Node is back in a majority partition", "members", s.Members, "expected", s.Expected)
+		}
+	}
+}
+
+// memberNames returns the names of the members of the cluster.
+func (m *Manager) memberNames() []string {
+	var names []string
+	for _, p := range m.peer.Peers() {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// leftPeers returns the peers which announced that they left the cluster
+// gracefully.
+func (m *Manager) leftPeers() map[string]bool {
+	peers := map[string]bool{}
+	for _, f := range m.facts.Nodes() {
+		if f.Left {
+			peers[f.Peer] = true
+		}
+	}
+	return peers
+}
+
+// announceLeave gossips that the local peer leaves the cluster gracefully,
+// marking the facts of its nodes as left, for the other members to stop
+// expecting it.
+func (m *Manager) announceLeave() {
+	now := time.Now().UTC()
+	for _, f := range m.facts.Nodes() {
+		if f.Peer != m.peer.Name() {
+			continue
+		}
+		left := *f
+		left.Left, left.Timestamp = true, now
+		b, err := m.facts.Set(&left)
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to announce leaving the cluster", "node", f.Node, "error", err)
+			continue
+		}
+		m.factsChannel.Broadcast(b)
+	}
+}
+
+// requireQuorum refuses destructive operations while the node is in a
+// minority partition, as its view of the cluster may be incomplete.
+func (m *Manager) requireQuorum(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s := m.partition.Status(); s.Minority {
+			level.Warn(m.logger).Log("msg", "Refusing admin operation without quorum", "path", r.URL.Path, "members", s.Members, "expected", s.Expected)
+			writeProblem(w, r, problemf(ErrDegraded, "no quorum: node only sees %d of %d expected members", s.Members, s.Expected))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
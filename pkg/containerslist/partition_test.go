@@ -0,0 +1,125 @@
+package containerslist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPartitionDetectorStatus(t *testing.T) {
+	type step struct {
+		members []string
+		left    []string
+		want    PartitionStatus
+	}
+	for _, tc := range []struct {
+		name     string
+		expected int
+		steps    []step
+	}{
+		{
+			name: "stable",
+			steps: []step{
+				{members: []string{"a", "b", "c"}, want: PartitionStatus{Members: 3, Expected: 3}},
+			},
+		},
+		{
+			name: "failed members",
+			steps: []step{
+				{members: []string{"a", "b", "c"}, want: PartitionStatus{Members: 3, Expected: 3}},
+				{members: []string{"a"}, want: PartitionStatus{Members: 1, Expected: 3, Minority: true}},
+				{members: []string{"a", "b", "c"}, want: PartitionStatus{Members: 3, Expected: 3}},
+			},
+		},
+		{
+			name: "scale-down",
+			steps: []step{
+				{members: []string{"a", "b"}, want: PartitionStatus{Members: 2, Expected: 2}},
+				{members: []string{"a"}, left: []string{"b"}, want: PartitionStatus{Members: 1, Expected: 1}},
+			},
+		},
+		{
+			name: "graceful leave then failure",
+			steps: []step{
+				{members: []string{"a", "b", "c", "d"}, want: PartitionStatus{Members: 4, Expected: 4}},
+				{members: []string{"a", "b", "c"}, left: []string{"d"}, want: PartitionStatus{Members: 3, Expected: 3}},
+				{members: []string{"a"}, left: []string{"d"}, want: PartitionStatus{Members: 1, Expected: 3, Minority: true}},
+			},
+		},
+		{
+			name: "rejoin after leave",
+			steps: []step{
+				{members: []string{"a", "b"}, want: PartitionStatus{Members: 2, Expected: 2}},
+				{members: []string{"a"}, left: []string{"b"}, want: PartitionStatus{Members: 1, Expected: 1}},
+				// The facts of b still say it left until it republishes them.
+				{members: []string{"a", "b"}, left: []string{"b"}, want: PartitionStatus{Members: 2, Expected: 2}},
+				{members: []string{"a"}, want: PartitionStatus{Members: 1, Expected: 2, Minority: true}},
+			},
+		},
+		{
+			name:     "configured size",
+			expected: 5,
+			steps: []step{
+				{members: []string{"a", "b", "c"}, want: PartitionStatus{Members: 3, Expected: 5}},
+				{members: []string{"a", "b"}, left: []string{"c"}, want: PartitionStatus{Members: 2, Expected: 5, Minority: true}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var cur step
+			d := newPartitionDetector(
+				func() []string { return cur.members },
+				func() map[string]bool {
+					left := map[string]bool{}
+					for _, name := range cur.left {
+						left[name] = true
+					}
+					return left
+				},
+				tc.expected,
+				prometheus.NewRegistry(),
+			)
+			for i, s := range tc.steps {
+				cur = s
+				if got := d.Status(); got != s.want {
+					t.Errorf("step %d: got %+v, want %+v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireQuorum(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	m := &Manager{cfg: DefaultConfig(), logger: log.NewNopLogger()}
+	m.partition = newPartitionDetector(func() []string { return members }, func() map[string]bool { return nil }, 0, prometheus.NewRegistry())
+	var called int
+	h := m.requireQuorum(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+	}))
+
+	for _, tc := range []struct {
+		name    string
+		members []string
+		want    int
+	}{
+		{name: "quorum", members: []string{"a", "b", "c"}, want: http.StatusOK},
+		{name: "majority", members: []string{"a", "b"}, want: http.StatusOK},
+		{name: "minority", members: []string{"a"}, want: http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			members, called = tc.members, 0
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune", nil))
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if wantCalled := tc.want == http.StatusOK; (called == 1) != wantCalled {
+				t.Errorf("handler called %d times, want called %v", called, wantCalled)
+			}
+		})
+	}
+}
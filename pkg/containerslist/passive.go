@@ -0,0 +1,49 @@
+package containerslist
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// runPassive periodically advertises that the local member is passive: it
+// receives the gossiped state to serve the API and the UI, but collects no
+// node, aggregates no shard and never writes the shared history.
+func (m *Manager) runPassive(ctx context.Context) {
+	for {
+		facts := NodeFacts{
+			Node:             m.nodeID,
+			Timestamp:        time.Now().UTC(),
+			Peer:             m.peer.Name(),
+			InternalAddress:  m.internalAddress,
+			InternalIdentity: m.internalIdentity(),
+			InventoryProto:   true,
+			Zone:             m.cfg.Zone,
+			Labels:           m.cfg.NodeLabels,
+			Passive:          true,
+		}
+		if b, err := m.facts.Set(&facts); err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to set passive member facts", "error", err)
+		} else {
+			m.factsChannel.Broadcast(b)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.cfg.CollectInterval):
+		}
+	}
+}
+
+// passivePeers returns the names of the passive members of the cluster.
+func (m *Manager) passivePeers() map[string]bool {
+	peers := map[string]bool{}
+	for _, f := range m.facts.Nodes() {
+		if f.Passive {
+			peers[f.Peer] = true
+		}
+	}
+	return peers
+}
@@ -0,0 +1,61 @@
+package containerslist
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestRunPassive(t *testing.T) {
+	peer := newTestPeer(t)
+	t.Cleanup(func() { peer.Leave(time.Second) })
+	ch := &testChannel{}
+	m := &Manager{
+		cfg:          DefaultConfig(),
+		logger:       log.NewNopLogger(),
+		nodeID:       "dashboard",
+		peer:         peer,
+		facts:        newNodeState[*NodeFacts](),
+		factsChannel: ch,
+	}
+	m.cfg.Passive = true
+	m.cfg.Zone = "dmz"
+
+	// The facts are advertised before waiting for the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.runPassive(ctx)
+
+	f, ok := m.facts.Get("dashboard")
+	if !ok || !f.Passive || f.Peer != peer.Name() || f.Zone != "dmz" || !f.InventoryProto {
+		t.Fatalf("facts = %+v, want the passive facts of the peer", f)
+	}
+	if len(ch.msgs) != 1 {
+		t.Errorf("%d facts broadcasts, want 1", len(ch.msgs))
+	}
+
+	if got, want := m.passivePeers(), map[string]bool{peer.Name(): true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("passive peers = %v, want %v", got, want)
+	}
+	// The passive member neither aggregates shards nor owns any node.
+	if got := m.clusterMembers(); len(got) != 0 {
+		t.Errorf("cluster members = %q, want none", got)
+	}
+	if m.ownsNode("a") {
+		t.Error("passive member owns node a")
+	}
+	if got := m.nodeFacts(); len(got) != 0 {
+		t.Errorf("node facts = %+v, want none of the passive member", got)
+	}
+
+	// Once the member is no longer passive, it aggregates shards again.
+	if _, err := m.facts.Set(&NodeFacts{Node: "dashboard", Peer: peer.Name(), Timestamp: time.Now().Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.clusterMembers(), []string{peer.Name()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cluster members = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package containerslist
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// peerNameTemplate is the template of the display names of the peers,
+// executed against their peerNameData.
+type peerNameTemplate struct {
+	text string
+	tpl  *texttemplate.Template
+}
+
+// String implements flag.Value.
+func (t *peerNameTemplate) String() string {
+	return t.text
+}
+
+// Set implements flag.Value, parsing the template.
+func (t *peerNameTemplate) Set(text string) error {
+	funcs := texttemplate.FuncMap{"stripDomain": stripDomain}
+	for name, f := range templateFuncs {
+		funcs[name] = f
+	}
+	tpl, err := texttemplate.New("peer").Funcs(funcs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid peer display name template: %w", err)
+	}
+	t.text, t.tpl = text, tpl
+	return nil
+}
+
+// stripDomain returns the first label of a host name, keeping IP addresses
+// whole.
+func stripDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	name, _, _ := strings.Cut(host, ".")
+	return name
+}
+
+// peerAliases are the display names of peers, by name of the peer, of its
+// node or of the host of its address.
+type peerAliases map[string]string
+
+// String implements flag.Value.
+func (a peerAliases) String() string {
+	specs := make([]string, 0, len(a))
+	for name, alias := range a {
+		specs = append(specs, name+"="+alias)
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a name=alias alias.
+func (a peerAliases) Set(spec string) error {
+	name, alias, ok := strings.Cut(spec, "=")
+	if !ok || name == "" || alias == "" {
+		return fmt.Errorf("invalid peer alias %q: must be name=alias", spec)
+	}
+	a[name] = alias
+	return nil
+}
+
+// peerSite is a site of the peers, by range of their addresses.
+type peerSite struct {
+	prefix netip.Prefix
+	name   string
+}
+
+// peerSites are the sites of the peers, the most specific range matching
+// the address of a peer giving its site.
+type peerSites []peerSite
+
+// String implements flag.Value.
+func (s *peerSites) String() string {
+	specs := make([]string, 0, len(*s))
+	for _, site := range *s {
+		specs = append(specs, site.prefix.String()+"="+site.name)
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a CIDR=site site.
+func (s *peerSites) Set(spec string) error {
+	cidr, name, ok := strings.Cut(spec, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid peer site %q: must be CIDR=site", spec)
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid range of peer site %q: %w", name, err)
+	}
+	*s = append(*s, peerSite{prefix: prefix.Masked(), name: name})
+	sort.SliceStable(*s, func(i, j int) bool { return (*s)[i].prefix.Bits() > (*s)[j].prefix.Bits() })
+	return nil
+}
+
+// site returns the site of an address, or an empty string when it is in
+// none of the ranges.
+func (s peerSites) site(host string) string {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	for _, site := range s {
+		if site.prefix.Contains(addr) {
+			return site.name
+		}
+	}
+	return ""
+}
+
+// peerNameData describes a peer to the display name template.
+type peerNameData struct {
+	// Name is the name of the peer in the gossip cluster.
+	Name string
+	// Address is its gossip address, as host:port, and Host its host.
+	Address string
+	Host    string
+	// Node is the name of its node, when its facts are known.
+	Node string
+	// Site is the site of its address, from -peer_site.
+	Site string
+}
+
+// peerDisplayName returns the name of a peer shown in the UI, the API and
+// the metrics labels: its alias, or else the display name template executed
+// against it, or else its name.
+func (m *Manager) peerDisplayName(d peerNameData) string {
+	for _, key := range []string{d.Name, d.Node, d.Host} {
+		if alias, ok := m.cfg.PeerAliases[key]; ok && key != "" {
+			return alias
+		}
+	}
+	if m.cfg.PeerDisplayName.tpl == nil {
+		return d.Name
+	}
+	var b bytes.Buffer
+	if err := m.cfg.PeerDisplayName.tpl.Execute(&b, d); err != nil || strings.TrimSpace(b.String()) == "" {
+		return d.Name
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// peerDisplayNames returns the display names of the members of the cluster,
+// by name.
+func (m *Manager) peerDisplayNames() map[string]string {
+	facts := m.peerFacts()
+	names := map[string]string{}
+	for _, p := range m.peer.Peers() {
+		d := peerNameData{Name: p.Name(), Address: p.Address()}
+		d.Host, _, _ = net.SplitHostPort(p.Address())
+		d.Site = m.cfg.PeerSites.site(d.Host)
+		if f, ok := facts[p.Name()]; ok {
+			d.Node = f.Node
+		}
+		names[p.Name()] = m.peerDisplayName(d)
+	}
+	return names
+}
+
+// peerDisplayNameOf returns the display name of a peer, or its name when it
+// is not a member anymore.
+func (m *Manager) peerDisplayNameOf(name string) string {
+	if display, ok := m.peerDisplayNames()[name]; ok {
+		return display
+	}
+	return name
+}
@@ -0,0 +1,125 @@
+package containerslist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStripDomain(t *testing.T) {
+	for host, want := range map[string]string{
+		"web-1.eu.example.com": "web-1",
+		"web-1":                "web-1",
+		"10.0.0.1":             "10.0.0.1",
+		"fd00::1":              "fd00::1",
+	} {
+		if got := stripDomain(host); got != want {
+			t.Errorf("stripDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestPeerSites(t *testing.T) {
+	var s peerSites
+	for _, spec := range []string{"10.0.0.0/8=dc", "10.1.2.3/16=paris"} {
+		if err := s.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := s.String(), "10.1.0.0/16=paris,10.0.0.0/8=dc"; got != want {
+		t.Errorf("sites = %q, want %q", got, want)
+	}
+	for host, want := range map[string]string{
+		"10.1.200.1":        "paris",
+		"10.2.0.1":          "dc",
+		"::ffff:10.1.0.1":   "paris",
+		"192.168.0.1":       "",
+		"web-1.example.com": "",
+	} {
+		if got := s.site(host); got != want {
+			t.Errorf("site of %s = %q, want %q", host, got, want)
+		}
+	}
+	for _, spec := range []string{"10.0.0.0/8", "10.0.0.0/8=", "10.0.0.0/33=dc"} {
+		if err := s.Set(spec); err == nil {
+			t.Errorf("site %q accepted, want an error", spec)
+		}
+	}
+
+	a := peerAliases{}
+	for _, spec := range []string{"=web", "web", "web="} {
+		if err := a.Set(spec); err == nil {
+			t.Errorf("alias %q accepted, want an error", spec)
+		}
+	}
+}
+
+func TestPeerDisplayName(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig()}
+	d := peerNameData{Name: "01HQ", Address: "10.1.0.1:9094", Host: "10.1.0.1", Node: "web-1.eu.example.com", Site: "paris"}
+	if got := m.peerDisplayName(d); got != "01HQ" {
+		t.Errorf("display name = %q, want the name by default", got)
+	}
+
+	if err := m.cfg.PeerDisplayName.Set("{{ .Site }}-{{ stripDomain .Node }}"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.peerDisplayName(d); got != "paris-web-1" {
+		t.Errorf("display name = %q, want paris-web-1", got)
+	}
+	// The name is kept when the template yields nothing.
+	if got := m.peerDisplayName(peerNameData{Name: "01HR"}); got != "-" {
+		t.Errorf("display name = %q, want -", got)
+	}
+	if err := m.cfg.PeerDisplayName.Set("{{ .Node }}"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.peerDisplayName(peerNameData{Name: "01HR"}); got != "01HR" {
+		t.Errorf("display name = %q, want the name", got)
+	}
+	if err := m.cfg.PeerDisplayName.Set("{{ .Node"); err == nil {
+		t.Error("invalid template accepted")
+	}
+
+	// The aliases win, by name, then node, then host.
+	for _, tc := range []struct {
+		aliases []string
+		want    string
+	}{
+		{aliases: []string{"10.1.0.1=by-host"}, want: "by-host"},
+		{aliases: []string{"10.1.0.1=by-host", "web-1.eu.example.com=by-node"}, want: "by-node"},
+		{aliases: []string{"web-1.eu.example.com=by-node", "01HQ=by-name"}, want: "by-name"},
+	} {
+		m.cfg.PeerAliases = peerAliases{}
+		for _, spec := range tc.aliases {
+			if err := m.cfg.PeerAliases.Set(spec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := m.peerDisplayName(d); got != tc.want {
+			t.Errorf("display name with aliases %s = %q, want %q", m.cfg.PeerAliases, got, tc.want)
+		}
+	}
+}
+
+func TestPeerDisplayNames(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig(), facts: newNodeState[*NodeFacts]()}
+	peer := newTestPeer(t)
+	t.Cleanup(func() { peer.Leave(time.Second) })
+	m.peer = peer
+	if _, err := m.facts.Set(&NodeFacts{Node: "web-1.example.com", Peer: peer.Name(), Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.cfg.PeerSites.Set("127.0.0.0/8=local"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.cfg.PeerDisplayName.Set("{{ .Site }}/{{ stripDomain .Node }}"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.peerDisplayNameOf(peer.Name()); got != "local/web-1" {
+		t.Errorf("display name = %q, want local/web-1", got)
+	}
+	if got := m.peerDisplayNameOf("gone"); got != "gone" {
+		t.Errorf("display name of a departed peer = %q, want its name", got)
+	}
+}
@@ -0,0 +1,149 @@
+package containerslist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/cluster"
+)
+
+const defaultPeersFileInterval = 5 * time.Second
+
+// peersFile is a file listing peers, one host or host:port per line with #
+// comments, maintained by configuration management. It is watched for
+// changes, the new peers being joined, and the removed ones left to the
+// failure detection of the mesh once stopped.
+type peersFile struct {
+	path     string
+	port     string
+	interval time.Duration
+	logger   log.Logger
+
+	// content is the content of the file when last read.
+	content []byte
+}
+
+func newPeersFile(path, port string, interval time.Duration, logger log.Logger) *peersFile {
+	return &peersFile{
+		path:     path,
+		port:     port,
+		interval: interval,
+		logger:   log.With(logger, "component", "peers_file", "path", path),
+	}
+}
+
+// read returns the peers of the file, and whether it changed since it was
+// last read.
+func (f *peersFile) read() ([]string, bool, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+	changed := f.content == nil || !bytes.Equal(b, f.content)
+	f.content = b
+
+	var peers []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(line); err != nil {
+			line = net.JoinHostPort(line, f.port)
+		}
+		peers = append(peers, line)
+	}
+	return peers, changed, sc.Err()
+}
+
+// Run watches the file until ctx is done, joining the peers which are not
+// members of the mesh when it changes. The peers whose names resolve to
+// several addresses are joined at all of them.
+func (f *peersFile) Run(ctx context.Context, peer *cluster.Peer) {
+	f.watch(ctx, func(peers []string) {
+		var addrs []string
+		for _, p := range peers {
+			host, port, _ := net.SplitHostPort(p)
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				level.Warn(f.logger).Log("msg", "Unable to resolve peer", "peer", p, "error", err)
+				continue
+			}
+			for _, ip := range ips {
+				addrs = append(addrs, net.JoinHostPort(ip, port))
+			}
+		}
+		joinNewPeers(peer, addrs, f.logger)
+	})
+}
+
+// watch calls changed with the peers of the file each time it changes, until
+// ctx is done. The directory of the file is watched with fsnotify, so that the
+// file can be replaced by a rename as configuration management does; the file
+// is polled at each interval instead when it cannot be watched, such as on
+// some network file systems.
+func (f *peersFile) watch(ctx context.Context, changed func(peers []string)) {
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+		poll   <-chan time.Time
+	)
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(filepath.Dir(f.path)); err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		level.Warn(f.logger).Log("msg", "Unable to watch the peers file, polling it instead", "interval", f.interval, "error", err)
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	} else {
+		defer watcher.Close()
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	// The file is checked once watched, for the changes made since it was
+	// last read.
+	f.check(changed)
+	name := filepath.Clean(f.path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			if filepath.Clean(ev.Name) != name || !ev.Has(fsnotify.Create|fsnotify.Write) {
+				continue
+			}
+		case err := <-errs:
+			level.Warn(f.logger).Log("msg", "Error watching the peers file", "error", err)
+			continue
+		case <-poll:
+		}
+		f.check(changed)
+	}
+}
+
+// check reads the file, calling changed with its peers when it changed.
+func (f *peersFile) check(changed func(peers []string)) {
+	peers, ok, err := f.read()
+	if err != nil {
+		level.Warn(f.logger).Log("msg", "Unable to read the peers file", "error", err)
+		return
+	}
+	if ok {
+		level.Info(f.logger).Log("msg", "Peers file changed", "peers", len(peers))
+		changed(peers)
+	}
+}
@@ -0,0 +1,151 @@
+package containerslist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestPeersFileRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.txt")
+	f := newPeersFile(path, "9094", time.Second, log.NewNopLogger())
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    []string
+		changed bool
+	}{
+		{
+			name:    "first read",
+			content: "# peers\nnode-1\n\n  node-2:7946  # other port\n10.0.0.1\n[fd00::1]:9094\n",
+			want:    []string{"node-1:9094", "node-2:7946", "10.0.0.1:9094", "[fd00::1]:9094"},
+			changed: true,
+		},
+		{
+			name:    "unchanged",
+			content: "# peers\nnode-1\n\n  node-2:7946  # other port\n10.0.0.1\n[fd00::1]:9094\n",
+			want:    []string{"node-1:9094", "node-2:7946", "10.0.0.1:9094", "[fd00::1]:9094"},
+		},
+		{
+			name:    "peer removed",
+			content: "node-1\n",
+			want:    []string{"node-1:9094"},
+			changed: true,
+		},
+		{
+			name:    "comments only",
+			content: "# none\n",
+			changed: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			peers, changed, err := f.read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(peers, tc.want) {
+				t.Errorf("peers = %q, want %q", peers, tc.want)
+			}
+			if changed != tc.changed {
+				t.Errorf("changed = %v, want %v", changed, tc.changed)
+			}
+		})
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.read(); err == nil {
+		t.Error("read of a missing file succeeded")
+	}
+}
+
+// watchPeersFile watches a peers file, returning the channel of the peers
+// read each time it changes.
+func watchPeersFile(t *testing.T, f *peersFile) <-chan []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan []string, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.watch(ctx, func(peers []string) { changes <- peers })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return changes
+}
+
+// waitPeers waits for the peers read from a file to be the wanted ones, past
+// the ones read while it was being written.
+func waitPeers(t *testing.T, changes <-chan []string, want ...string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	var peers []string
+	for !reflect.DeepEqual(peers, want) {
+		select {
+		case peers = <-changes:
+		case <-timeout:
+			t.Fatalf("peers = %q, want %q", peers, want)
+		}
+	}
+}
+
+func TestPeersFileWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "peers.txt")
+	if err := os.WriteFile(path, []byte("node-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The interval is long enough for the changes to be noticed through the
+	// events only.
+	f := newPeersFile(path, "9094", time.Hour, log.NewNopLogger())
+	if _, _, err := f.read(); err != nil {
+		t.Fatal(err)
+	}
+	changes := watchPeersFile(t, f)
+	// Let the watcher start.
+	time.Sleep(50 * time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("node-1\nnode-2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitPeers(t, changes, "node-1:9094", "node-2:9094")
+
+	// Configuration management replaces the file by a rename; the other
+	// files of the directory are ignored.
+	tmp := filepath.Join(dir, ".peers.txt.tmp")
+	if err := os.WriteFile(tmp, []byte("node-3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitPeers(t, changes, "node-3:9094")
+}
+
+func TestPeersFilePoll(t *testing.T) {
+	// The directory of the file does not exist yet, so it cannot be watched.
+	dir := filepath.Join(t.TempDir(), "peers")
+	path := filepath.Join(dir, "peers.txt")
+	f := newPeersFile(path, "9094", 10*time.Millisecond, log.NewNopLogger())
+	changes := watchPeersFile(t, f)
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("node-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitPeers(t, changes, "node-1:9094")
+}
@@ -0,0 +1,137 @@
+package containerslist
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of the gossiped inventory entries.
+const (
+	gossipMerged   = "merged"
+	gossipConflict = "conflict"
+	gossipRejected = "rejected"
+)
+
+// nodeGossipStats counts the gossiped inventory entries of a node.
+type nodeGossipStats struct {
+	updates   int
+	conflicts int
+	rejected  int
+	last      time.Time
+}
+
+// gossipStats counts the gossiped inventory entries received for each node,
+// to attribute them to the peer collecting the node.
+type gossipStats struct {
+	mtx   sync.Mutex
+	nodes map[string]*nodeGossipStats
+}
+
+func newGossipStats() *gossipStats {
+	return &gossipStats{nodes: map[string]*nodeGossipStats{}}
+}
+
+// observe counts an entry received for a node: merged, a conflict when it is
+// not more recent than the local one, or rejected by the blocklist or the
+// signature verification.
+func (s *gossipStats) observe(node, outcome string, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, ok := s.nodes[node]
+	if !ok {
+		st = &nodeGossipStats{}
+		s.nodes[node] = st
+	}
+	st.updates++
+	switch outcome {
+	case gossipConflict:
+		st.conflicts++
+	case gossipRejected:
+		st.rejected++
+	}
+	st.last = now
+}
+
+func (s *gossipStats) get(node string) (nodeGossipStats, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, ok := s.nodes[node]
+	if !ok {
+		return nodeGossipStats{}, false
+	}
+	return *st, true
+}
+
+// PeerStats are the gossip statistics of a peer: the inventory updates
+// received for the nodes it collects, and the size of their state.
+type PeerStats struct {
+	Peer             string     `json:"peer"`
+	DisplayName      string     `json:"displayName"`
+	Nodes            []string   `json:"nodes"`
+	UpdatesReceived  int        `json:"updatesReceived"`
+	MergeConflicts   int        `json:"mergeConflicts"`
+	RejectedPayloads int        `json:"rejectedPayloads"`
+	LastUpdate       *time.Time `json:"lastUpdate,omitempty"`
+	// StateBytes is the size of the gossiped encoding of the inventories of
+	// its nodes.
+	StateBytes int `json:"stateBytes"`
+}
+
+// peerStats returns the gossip statistics of the members of the cluster, by
+// name. The nodes of a peer are the ones whose facts it advertises.
+func (m *Manager) peerStats() map[string]PeerStats {
+	res := map[string]PeerStats{}
+	names := m.peerDisplayNames()
+	for _, p := range m.peer.Peers() {
+		res[p.Name()] = PeerStats{Peer: p.Name(), DisplayName: names[p.Name()], Nodes: []string{}}
+	}
+	for _, f := range m.nodeFacts() {
+		ps, ok := res[f.Peer]
+		if !ok {
+			continue
+		}
+		ps.Nodes = append(ps.Nodes, f.Node)
+		if st, ok := m.inventory.stats.get(f.Node); ok {
+			ps.UpdatesReceived += st.updates
+			ps.MergeConflicts += st.conflicts
+			ps.RejectedPayloads += st.rejected
+			if ps.LastUpdate == nil || st.last.After(*ps.LastUpdate) {
+				last := st.last
+				ps.LastUpdate = &last
+			}
+		}
+		if n, ok := m.inventory.Get(f.Node); ok && !n.Summary {
+			ps.StateBytes += len(marshalInventoryProto([]*NodeInventory{n}))
+		}
+		res[f.Peer] = ps
+	}
+	for name, ps := range res {
+		sort.Strings(ps.Nodes)
+		res[name] = ps
+	}
+	return res
+}
+
+// apiPeerStats serves the gossip statistics of the peer named in the path
+// /api/v1/peers/{name}/stats.
+func (m *Manager) apiPeerStats() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/peers/"), "/stats")
+		if !ok || name == "" || strings.Contains(name, "/") {
+			writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+			return
+		}
+		ps, ok := m.peerStats()[name]
+		if !ok {
+			writeProblem(w, r, problemf(ErrNotFound, "no peer %q", name))
+			return
+		}
+		writeJSON(w, ps)
+	})
+}
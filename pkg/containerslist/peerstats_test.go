@@ -0,0 +1,74 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGossipStats(t *testing.T) {
+	now := time.Now()
+	m := newTestInventoryManager(t, &NodeInventory{Node: "a", Timestamp: now})
+	m.inventory.blocklist = newBlocklist(prometheus.NewRegistry())
+	if err := m.inventory.blocklist.Add("b"); err != nil {
+		t.Fatal(err)
+	}
+	for _, nodes := range [][]*NodeInventory{
+		{{Node: "a", Timestamp: now.Add(time.Second), Containers: []Container{{ID: "a1", Name: "web"}}}, {Node: "b", Timestamp: now}},
+		{{Node: "a", Timestamp: now.Add(-time.Second)}},
+	} {
+		b, err := json.Marshal(nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.inventory.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st, ok := m.inventory.stats.get("a"); !ok || st.updates != 2 || st.conflicts != 1 || st.rejected != 0 || st.last.IsZero() {
+		t.Errorf("stats of a = %+v, want 2 updates with 1 conflict", st)
+	}
+	if st, ok := m.inventory.stats.get("b"); !ok || st.updates != 1 || st.rejected != 1 {
+		t.Errorf("stats of b = %+v, want 1 rejected update", st)
+	}
+	if _, ok := m.inventory.stats.get("c"); ok {
+		t.Error("stats of c, want none")
+	}
+
+	peer := newTestPeer(t)
+	t.Cleanup(func() { peer.Leave(time.Second) })
+	m.peer = peer
+	m.facts = newNodeState[*NodeFacts]()
+	for _, f := range []*NodeFacts{
+		{Node: "b", Peer: peer.Name(), Timestamp: now},
+		{Node: "a", Peer: peer.Name(), Timestamp: now},
+		{Node: "x", Peer: "gone", Timestamp: now},
+	} {
+		if _, err := m.facts.Set(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiPeerStats().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/peers/"+peer.Name()+"/stats", nil))
+	var ps PeerStats
+	if err := json.NewDecoder(rec.Body).Decode(&ps); err != nil {
+		t.Fatal(err)
+	}
+	if ps.Peer != peer.Name() || !reflect.DeepEqual(ps.Nodes, []string{"a", "b"}) || ps.UpdatesReceived != 3 || ps.MergeConflicts != 1 || ps.RejectedPayloads != 1 || ps.LastUpdate == nil || ps.StateBytes == 0 {
+		t.Errorf("stats = %+v, want the 3 updates of a and b", ps)
+	}
+
+	for _, path := range []string{"/api/v1/peers/gone/stats", "/api/v1/peers//stats", "/api/v1/peers/" + peer.Name()} {
+		rec := httptest.NewRecorder()
+		m.apiPeerStats().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status of %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+# The built-in compliance policies. Each rule is the set of the violations
+# of a running container, given as input with its parsed image reference
+# and the parameters of the policy.
+package containerslist.policies
+
+import rego.v1
+
+no_latest contains "image is tagged latest" if input.image.reference == "latest"
+
+required_label contains sprintf("missing label %s", [input.params.label]) if {
+	not input.container.labels[input.params.label]
+}
+
+approved_registries contains sprintf("unknown registry: %s", [input.imageError]) if input.imageError
+
+approved_registries contains sprintf("registry %s is not approved", [input.image.registry]) if {
+	input.image
+	not input.image.registry in input.params.registries
+}
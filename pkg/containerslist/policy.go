@@ -0,0 +1,302 @@
+package containerslist
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// Kinds of compliance policies. The built-in kinds are rules of the Rego
+// module policies/builtin.rego, and the rego kind a module of the operator.
+const (
+	PolicyNoLatest           = "no-latest"
+	PolicyRequiredLabel      = "required-label"
+	PolicyApprovedRegistries = "approved-registries"
+	PolicyRego               = "rego"
+)
+
+// builtinPolicies is the Rego module of the built-in kinds of policies.
+//
+//go:embed policies/builtin.rego
+var builtinPolicies string
+
+const violationsTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - violations</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    {{ range . }}
+    <h2>{{ .Node }}: {{ len .Violations }}</h2>
+    <table>
+      <tr><th>Policy</th><th>Container</th><th>Image</th><th>Violation</th></tr>
+    {{ range .Violations }}
+      <tr><td>{{ .Policy }}</td><td>{{ .Container }}</td><td>{{ .Image }}</td><td>{{ .Message }}</td></tr>
+    {{ end }}
+    </table>
+    {{ else }}
+    <p>{{ t "No violation" }}</p>
+    {{ end }}
+  </body>
+</html>
+`
+
+// Policy is a compliance policy checked against the running containers.
+type Policy struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Arg is the label of a required-label policy, the comma-separated
+	// registries of an approved-registries policy, or the Rego module file
+	// of a rego policy.
+	Arg string `json:"arg,omitempty"`
+}
+
+// policyInput is the input document of the Rego policies: a running
+// container, its parsed image reference and the parameters of the policy.
+type policyInput struct {
+	Container Container    `json:"container"`
+	Image     *policyImage `json:"image,omitempty"`
+	// ImageError is why the image reference could not be parsed.
+	ImageError string         `json:"imageError,omitempty"`
+	Params     map[string]any `json:"params,omitempty"`
+}
+
+type policyImage struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Reference  string `json:"reference"`
+}
+
+// compiledPolicy is a policy prepared for evaluation: its query returns the
+// set of the messages of the violations of the input container.
+type compiledPolicy struct {
+	Policy
+	query  rego.PreparedEvalQuery
+	params map[string]any
+}
+
+// compile prepares the query of the policy.
+func (p Policy) compile(ctx context.Context) (*compiledPolicy, error) {
+	c := &compiledPolicy{Policy: p}
+	file, module := "policies/builtin.rego", builtinPolicies
+	query := "data.containerslist.policies." + strings.ReplaceAll(p.Kind, "-", "_")
+	switch p.Kind {
+	case PolicyRequiredLabel:
+		c.params = map[string]any{"label": p.Arg}
+	case PolicyApprovedRegistries:
+		var registries []any
+		for _, r := range strings.Split(p.Arg, ",") {
+			registries = append(registries, normalizeRegistry(r))
+		}
+		c.params = map[string]any{"registries": registries}
+	case PolicyRego:
+		b, err := os.ReadFile(p.Arg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read policy %s: %w", p.Name, err)
+		}
+		file, module = p.Arg, string(b)
+		parsed, err := ast.ParseModule(file, module)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %w", p.Name, err)
+		}
+		query = parsed.Package.Path.String() + ".deny"
+	}
+	q, err := rego.New(rego.Query(query), rego.Module(file, module)).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", p.Name, err)
+	}
+	c.query = q
+	return c, nil
+}
+
+// check returns why a container violates the policy, sorted, none when it
+// complies.
+func (c *compiledPolicy) check(ctx context.Context, container Container) ([]string, error) {
+	input := policyInput{Container: container, Params: c.params}
+	if ref, err := parseImageRef(container.Image); err != nil {
+		input.ImageError = err.Error()
+	} else {
+		input.Image = &policyImage{Registry: ref.Registry, Repository: ref.Repository, Reference: ref.Reference}
+	}
+	rs, err := c.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []string
+	for _, r := range rs {
+		for _, e := range r.Expressions {
+			set, ok := e.Value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("the policy returned %T, not a set of messages", e.Value)
+			}
+			for _, v := range set {
+				if s, ok := v.(string); ok {
+					msgs = append(msgs, s)
+				} else {
+					msgs = append(msgs, fmt.Sprint(v))
+				}
+			}
+		}
+	}
+	sort.Strings(msgs)
+	return msgs, nil
+}
+
+// normalizeRegistry returns the host serving a registry, mapping the
+// aliases of the Docker Hub to it.
+func normalizeRegistry(r string) string {
+	switch r = strings.TrimSpace(r); r {
+	case "docker.io", "index.docker.io":
+		return defaultRegistry
+	}
+	return r
+}
+
+// policies are the configured compliance policies, by name.
+type policies map[string]Policy
+
+// String implements flag.Value.
+func (ps policies) String() string {
+	specs := make([]string, 0, len(ps))
+	for _, p := range ps {
+		specs = append(specs, p.Name+"="+p.Kind)
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a name=kind[:arg] policy.
+func (ps policies) Set(spec string) error {
+	name, rest, ok := strings.Cut(spec, "=")
+	kind, arg, _ := strings.Cut(rest, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid policy %q: must be name=kind[:arg]", spec)
+	}
+	switch kind {
+	case PolicyNoLatest:
+	case PolicyRequiredLabel, PolicyApprovedRegistries, PolicyRego:
+		if arg == "" {
+			return fmt.Errorf("invalid policy %s: %s requires an argument", name, kind)
+		}
+	default:
+		return fmt.Errorf("invalid kind %q of policy %s: must be one of %s, %s, %s, %s", kind, name, PolicyNoLatest, PolicyRequiredLabel, PolicyApprovedRegistries, PolicyRego)
+	}
+	if _, ok := ps[name]; ok {
+		return fmt.Errorf("policy %s already defined", name)
+	}
+	ps[name] = Policy{Name: name, Kind: kind, Arg: arg}
+	return nil
+}
+
+// List returns the policies, sorted by name.
+func (ps policies) List() []Policy {
+	list := make([]Policy, 0, len(ps))
+	for _, p := range ps {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// compile prepares the policies for evaluation, sorted by name.
+func (ps policies) compile(ctx context.Context) ([]*compiledPolicy, error) {
+	var compiled []*compiledPolicy
+	for _, p := range ps.List() {
+		c, err := p.compile(ctx)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Violation is a running container violating a policy.
+type Violation struct {
+	Policy      string `json:"policy"`
+	Container   string `json:"container"`
+	ContainerID string `json:"containerID"`
+	Image       string `json:"image"`
+	Message     string `json:"message"`
+}
+
+// NodeViolations are the policy violations of the containers of a node.
+type NodeViolations struct {
+	Node       string      `json:"node"`
+	Violations []Violation `json:"violations"`
+}
+
+// violations checks the running containers of the cluster against the
+// policies, returning the nodes with violations which are not in
+// maintenance. A policy failing to evaluate is a violation, so that it is
+// not silently ignored.
+func (m *Manager) violations(ctx context.Context) []NodeViolations {
+	res := []NodeViolations{}
+	if len(m.policies) == 0 {
+		return res
+	}
+	maintenance := m.activeMaintenance(time.Now())
+	for _, n := range m.inventory.Nodes() {
+		if maintenance.Window(n.Node) != nil {
+			continue
+		}
+		nv := NodeViolations{Node: n.Node}
+		for _, c := range n.Containers {
+			if c.State != StateRunning {
+				continue
+			}
+			for _, p := range m.policies {
+				msgs, err := p.check(ctx, c)
+				if err != nil {
+					level.Warn(m.logger).Log("msg", "Unable to evaluate policy", "policy", p.Name, "container", c.ID, "error", err)
+					msgs = []string{fmt.Sprintf("unable to evaluate the policy: %v", err)}
+				}
+				for _, msg := range msgs {
+					nv.Violations = append(nv.Violations, Violation{Policy: p.Name, Container: c.Name, ContainerID: c.ID, Image: c.Image, Message: msg})
+				}
+			}
+		}
+		if len(nv.Violations) > 0 {
+			res = append(res, nv)
+		}
+	}
+	return res
+}
+
+func (m *Manager) apiPolicies() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := make([]Policy, 0, len(m.policies))
+		for _, p := range m.policies {
+			list = append(list, p.Policy)
+		}
+		writeJSON(w, list)
+	})
+}
+
+func (m *Manager) apiViolations() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.violations(r.Context()))
+	})
+}
+
+func (m *Manager) violationsPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := localize(t, r).ExecuteTemplate(w, "violations", m.violations(r.Context())); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
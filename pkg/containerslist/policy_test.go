@@ -0,0 +1,238 @@
+package containerslist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestPoliciesSet(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		want    Policy
+		wantErr string
+	}{
+		{spec: "latest=no-latest", want: Policy{Name: "latest", Kind: PolicyNoLatest}},
+		{spec: "team=required-label:team", want: Policy{Name: "team", Kind: PolicyRequiredLabel, Arg: "team"}},
+		{spec: "registries=approved-registries:docker.io,ghcr.io", want: Policy{Name: "registries", Kind: PolicyApprovedRegistries, Arg: "docker.io,ghcr.io"}},
+		{spec: "custom=rego:/etc/policies/custom.rego", want: Policy{Name: "custom", Kind: PolicyRego, Arg: "/etc/policies/custom.rego"}},
+		{spec: "no-latest", wantErr: "must be name=kind[:arg]"},
+		{spec: "=no-latest", wantErr: "must be name=kind[:arg]"},
+		{spec: "team=required-label", wantErr: "requires an argument"},
+		{spec: "custom=rego", wantErr: "requires an argument"},
+		{spec: "x=unknown", wantErr: "invalid kind"},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			ps := policies{}
+			err := ps.Set(tc.spec)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Set(%q) = %v, want an error containing %q", tc.spec, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ps[tc.want.Name]; got != tc.want {
+				t.Errorf("policy = %+v, want %+v", got, tc.want)
+			}
+			if err := ps.Set(tc.spec); err == nil || !strings.Contains(err.Error(), "already defined") {
+				t.Errorf("second Set(%q) = %v, want already defined", tc.spec, err)
+			}
+		})
+	}
+}
+
+// writePolicy writes a Rego module in a temporary file, returning its path.
+func writePolicy(t *testing.T, module string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policy.rego")
+	if err := os.WriteFile(file, []byte(module), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestPolicyCheck(t *testing.T) {
+	custom := writePolicy(t, `package example.compliance
+
+import rego.v1
+
+deny contains sprintf("%s runs privileged ports", [input.container.name]) if {
+	some p in input.container.ports
+	p.containerPort < 1024
+}
+
+deny contains "owner is not a team" if not startswith(input.container.labels.owner, "team-")
+`)
+	for _, tc := range []struct {
+		name      string
+		policy    Policy
+		container Container
+		want      []string
+	}{
+		{
+			name:      "latest tag",
+			policy:    Policy{Kind: PolicyNoLatest},
+			container: Container{Image: "nginx"},
+			want:      []string{"image is tagged latest"},
+		},
+		{
+			name:      "pinned tag",
+			policy:    Policy{Kind: PolicyNoLatest},
+			container: Container{Image: "nginx:1.25"},
+		},
+		{
+			name:      "digest",
+			policy:    Policy{Kind: PolicyNoLatest},
+			container: Container{Image: "nginx@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		},
+		{
+			name:      "missing label",
+			policy:    Policy{Kind: PolicyRequiredLabel, Arg: "team"},
+			container: Container{Image: "nginx:1.25", Labels: map[string]string{"app": "web"}},
+			want:      []string{"missing label team"},
+		},
+		{
+			name:      "empty label",
+			policy:    Policy{Kind: PolicyRequiredLabel, Arg: "team"},
+			container: Container{Image: "nginx:1.25", Labels: map[string]string{"team": ""}},
+		},
+		{
+			name:      "no labels",
+			policy:    Policy{Kind: PolicyRequiredLabel, Arg: "team"},
+			container: Container{Image: "nginx:1.25"},
+			want:      []string{"missing label team"},
+		},
+		{
+			name:      "Docker Hub alias",
+			policy:    Policy{Kind: PolicyApprovedRegistries, Arg: "docker.io, ghcr.io"},
+			container: Container{Image: "library/nginx:1.25"},
+		},
+		{
+			name:      "approved registry",
+			policy:    Policy{Kind: PolicyApprovedRegistries, Arg: "docker.io,ghcr.io"},
+			container: Container{Image: "ghcr.io/example/app:1.0"},
+		},
+		{
+			name:      "registry not approved",
+			policy:    Policy{Kind: PolicyApprovedRegistries, Arg: "ghcr.io"},
+			container: Container{Image: "quay.io/example/app:1.0"},
+			want:      []string{"registry quay.io is not approved"},
+		},
+		{
+			name:      "unknown registry",
+			policy:    Policy{Kind: PolicyApprovedRegistries, Arg: "ghcr.io"},
+			container: Container{Image: ""},
+			want:      []string{"unknown registry: invalid image reference \"\""},
+		},
+		{
+			name:      "Rego module",
+			policy:    Policy{Kind: PolicyRego, Arg: custom},
+			container: Container{Name: "web", Image: "nginx:1.25", Ports: []Port{{ContainerPort: 80}}, Labels: map[string]string{"owner": "alice"}},
+			want:      []string{"owner is not a team", "web runs privileged ports"},
+		},
+		{
+			name:      "Rego module complying",
+			policy:    Policy{Kind: PolicyRego, Arg: custom},
+			container: Container{Name: "web", Image: "nginx:1.25", Ports: []Port{{ContainerPort: 8080}}, Labels: map[string]string{"owner": "team-web"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, err := tc.policy.compile(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.check(ctx, tc.container)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) || len(got) > 0 && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("check = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCompileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		module  string
+		wantErr string
+	}{
+		{name: "missing file", wantErr: "unable to read policy"},
+		{name: "syntax error", module: "package example\n\ndeny contains if {", wantErr: "invalid policy"},
+		{name: "unsafe variable", module: "package example\n\nimport rego.v1\n\ndeny contains x if input.container.name == \"web\"", wantErr: "invalid policy"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "missing.rego")
+			if tc.module != "" {
+				file = writePolicy(t, tc.module)
+			}
+			_, err := Policy{Name: "p", Kind: PolicyRego, Arg: file}.compile(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("compile = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagerViolations(t *testing.T) {
+	ps := policies{}
+	for _, spec := range []string{"latest=no-latest", "team=required-label:team"} {
+		if err := ps.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The conflicting values of the complete rule fail the evaluation.
+	if err := ps.Set("broken=rego:" + writePolicy(t, "package broken\n\ndeny := input.container.labels.deny\n")); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := ps.compile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		cfg:         DefaultConfig(),
+		logger:      log.NewNopLogger(),
+		inventory:   newInventory(),
+		facts:       newNodeState[*NodeFacts](),
+		maintenance: newResourceState[MaintenanceWindow](),
+		policies:    compiled,
+	}
+	for _, n := range []*NodeInventory{
+		{Node: "a", Timestamp: time.Now(), Containers: []Container{
+			{ID: "a1", Name: "web", Image: "nginx", State: StateRunning, Labels: map[string]string{"team": "web"}},
+			{ID: "a2", Name: "old", Image: "nginx", State: "exited"},
+		}},
+		{Node: "b", Timestamp: time.Now(), Containers: []Container{
+			{ID: "b1", Name: "db", Image: "postgres:16", State: StateRunning, Labels: map[string]string{"team": "db"}},
+		}},
+		{Node: "c", Timestamp: time.Now(), Containers: []Container{
+			{ID: "c1", Name: "job", Image: "busybox:1", State: StateRunning, Labels: map[string]string{"deny": "not a set"}},
+		}},
+	} {
+		if _, err := m.inventory.Set(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []NodeViolations{
+		{Node: "a", Violations: []Violation{{Policy: "latest", Container: "web", ContainerID: "a1", Image: "nginx", Message: "image is tagged latest"}}},
+		{Node: "c", Violations: []Violation{
+			{Policy: "broken", Container: "job", ContainerID: "c1", Image: "busybox:1", Message: "unable to evaluate the policy: the policy returned string, not a set of messages"},
+			{Policy: "team", Container: "job", ContainerID: "c1", Image: "busybox:1", Message: "missing label team"},
+		}},
+	}
+	if got := m.violations(context.Background()); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %+v, want %+v", got, want)
+	}
+}
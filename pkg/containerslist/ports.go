@@ -0,0 +1,189 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const portsTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - ports</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    {{ range . }}
+    <h2>{{ .Node }}</h2>
+    <p>{{ .Used }} published ports in range ({{ printf "%.0f" .UsagePercent }}%){{ if .NearExhaustion }} - <strong>near exhaustion</strong>{{ end }}</p>
+    <table>
+      <tr><th>Host port</th><th>Containers</th><th>Conflict</th></tr>
+    {{ range .Ports }}
+      <tr><td><code>{{ .HostIP }}:{{ .HostPort }}/{{ .Protocol }}</code></td><td>{{ range .Containers }}{{ . }} {{ end }}</td><td>{{ if .Conflict }}<strong>yes</strong>{{ end }}</td></tr>
+    {{ end }}
+    </table>
+    {{ end }}
+  </body>
+</html>
+`
+
+// PortUsage lists the containers publishing a given host port.
+type PortUsage struct {
+	HostIP     string   `json:"hostIP"`
+	HostPort   uint16   `json:"hostPort"`
+	Protocol   string   `json:"protocol"`
+	Containers []string `json:"containers"`
+	Conflict   bool     `json:"conflict"`
+}
+
+// NodePorts is the published ports report of a node.
+type NodePorts struct {
+	Node           string      `json:"node"`
+	Ports          []PortUsage `json:"ports"`
+	Conflicts      int         `json:"conflicts"`
+	Used           int         `json:"used"`
+	UsagePercent   float64     `json:"usagePercent"`
+	NearExhaustion bool        `json:"nearExhaustion"`
+}
+
+// parsePortRange parses a "min-max" port range.
+func parsePortRange(s string) (uint16, uint16, error) {
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	min, err := strconv.ParseUint(lo, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	max, err := strconv.ParseUint(hi, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	return uint16(min), uint16(max), nil
+}
+
+func isWildcardIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// nodePorts computes the published ports report of a node. Two containers
+// conflict when they publish the same port and protocol on overlapping host
+// addresses; exited containers are included since they would conflict on
+// restart.
+func nodePorts(n *NodeInventory, rangeMin, rangeMax uint16, threshold float64) NodePorts {
+	type key struct {
+		ip    string
+		port  uint16
+		proto string
+	}
+	usages := map[key]*PortUsage{}
+	for _, c := range n.Containers {
+		for _, p := range c.Ports {
+			k := key{p.HostIP, p.HostPort, p.Protocol}
+			u, ok := usages[k]
+			if !ok {
+				u = &PortUsage{HostIP: p.HostIP, HostPort: p.HostPort, Protocol: p.Protocol}
+				usages[k] = u
+			}
+			u.Containers = append(u.Containers, c.Name)
+		}
+	}
+
+	res := NodePorts{Node: n.Node, Ports: make([]PortUsage, 0, len(usages))}
+	inRange := map[uint16]struct{}{}
+	for k, u := range usages {
+		for k2, u2 := range usages {
+			if k2.port != k.port || k2.proto != k.proto {
+				continue
+			}
+			if k2 == k {
+				u.Conflict = u.Conflict || len(u.Containers) > 1
+				continue
+			}
+			if (isWildcardIP(k.ip) || isWildcardIP(k2.ip) || k.ip == k2.ip) && !sameContainers(u.Containers, u2.Containers) {
+				u.Conflict = true
+			}
+		}
+		if u.Conflict {
+			res.Conflicts++
+		}
+		if k.port >= rangeMin && k.port <= rangeMax {
+			inRange[k.port] = struct{}{}
+		}
+		res.Ports = append(res.Ports, *u)
+	}
+	sort.Slice(res.Ports, func(i, j int) bool {
+		if res.Ports[i].HostPort != res.Ports[j].HostPort {
+			return res.Ports[i].HostPort < res.Ports[j].HostPort
+		}
+		if res.Ports[i].Protocol != res.Ports[j].Protocol {
+			return res.Ports[i].Protocol < res.Ports[j].Protocol
+		}
+		return res.Ports[i].HostIP < res.Ports[j].HostIP
+	})
+
+	res.Used = len(inRange)
+	res.UsagePercent = 100 * float64(res.Used) / float64(int(rangeMax)-int(rangeMin)+1)
+	res.NearExhaustion = res.UsagePercent >= 100*threshold
+	return res
+}
+
+// sameContainers reports whether both lists hold the same containers, as it
+// happens for a container publishing a port both on IPv4 and IPv6.
+func sameContainers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) ports() ([]NodePorts, error) {
+	min, max, err := parsePortRange(m.cfg.PortsRange)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := m.inventory.Nodes()
+	reports := make([]NodePorts, 0, len(nodes))
+	for _, n := range nodes {
+		reports = append(reports, nodePorts(n, min, max, m.cfg.PortsExhaustionThreshold))
+	}
+	return reports, nil
+}
+
+func (m *Manager) apiPorts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reports, err := m.ports()
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		writeJSON(w, reports)
+	})
+}
+
+func (m *Manager) portsPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reports, err := m.ports()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := localize(t, r).ExecuteTemplate(w, "ports", reports); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
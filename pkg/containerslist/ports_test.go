@@ -0,0 +1,75 @@
+package containerslist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		min, max uint16
+		wantErr  bool
+	}{
+		{s: "32768-60999", min: 32768, max: 60999},
+		{s: "80-80", min: 80, max: 80},
+		{s: "8080", wantErr: true},
+		{s: "100-10", wantErr: true},
+		{s: "1-65536", wantErr: true},
+		{s: "a-b", wantErr: true},
+	} {
+		min, max, err := parsePortRange(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parsePortRange(%q) error = %v, want error %v", tc.s, err, tc.wantErr)
+			continue
+		}
+		if min != tc.min || max != tc.max {
+			t.Errorf("parsePortRange(%q) = %d, %d, want %d, %d", tc.s, min, max, tc.min, tc.max)
+		}
+	}
+}
+
+func TestNodePorts(t *testing.T) {
+	n := &NodeInventory{Node: "a", Containers: []Container{
+		// Published on IPv4 and IPv6: not a conflict with itself.
+		{Name: "web", Ports: []Port{{HostIP: "0.0.0.0", HostPort: 8080, Protocol: "tcp"}, {HostIP: "::", HostPort: 8080, Protocol: "tcp"}}},
+		// Same port on a specific address, overlapping the wildcard.
+		{Name: "admin", Ports: []Port{{HostIP: "127.0.0.1", HostPort: 8080, Protocol: "tcp"}}},
+		// Same port in another protocol.
+		{Name: "dns", Ports: []Port{{HostIP: "0.0.0.0", HostPort: 53, Protocol: "udp"}}},
+		{Name: "api", Ports: []Port{{HostIP: "10.0.0.1", HostPort: 9000, Protocol: "tcp"}}},
+		{Name: "api2", Ports: []Port{{HostIP: "10.0.0.2", HostPort: 9000, Protocol: "tcp"}}},
+		{Name: "db", Ports: []Port{{HostIP: "0.0.0.0", HostPort: 9001, Protocol: "tcp"}}},
+		{Name: "db2", Ports: []Port{{HostIP: "0.0.0.0", HostPort: 9001, Protocol: "tcp"}}},
+	}}
+
+	got := nodePorts(n, 9000, 9003, 0.5)
+	conflicts := map[string]bool{}
+	for _, p := range got.Ports {
+		conflicts[p.HostIP+":"+strings.Join(p.Containers, ",")] = p.Conflict
+	}
+	want := map[string]bool{
+		"0.0.0.0:dns":     false,
+		"0.0.0.0:web":     true,
+		"127.0.0.1:admin": true,
+		":::web":          true,
+		"10.0.0.1:api":    false,
+		"10.0.0.2:api2":   false,
+		"0.0.0.0:db,db2":  true,
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("conflicts = %v, want %v", conflicts, want)
+	}
+	if got.Conflicts != 4 {
+		t.Errorf("Conflicts = %d, want 4", got.Conflicts)
+	}
+	if got.Used != 2 || got.UsagePercent != 50 || !got.NearExhaustion {
+		t.Errorf("usage = %d, %.0f%%, near exhaustion %v, want 2, 50%%, true", got.Used, got.UsagePercent, got.NearExhaustion)
+	}
+	for i := 1; i < len(got.Ports); i++ {
+		if got.Ports[i-1].HostPort > got.Ports[i].HostPort {
+			t.Errorf("ports not sorted: %v", got.Ports)
+		}
+	}
+}
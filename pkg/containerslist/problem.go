@@ -0,0 +1,123 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors of the API. Each is answered with an RFC 7807 problem bearing a
+// stable error code.
+var (
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrConflict         = errors.New("conflict")
+	ErrKeyReused        = errors.New("idempotency key reused")
+	ErrMisdirected      = errors.New("misdirected request")
+	ErrPeerUnreachable  = errors.New("peer unreachable")
+	ErrRuntime          = errors.New("runtime error")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrTooManyConns     = errors.New("too many connections")
+	ErrTooLarge         = errors.New("request too large")
+	ErrHeadersTooLarge  = errors.New("request headers too large")
+	ErrDegraded         = errors.New("degraded")
+	ErrInternal         = errors.New("internal error")
+)
+
+// problemKinds maps the API errors to their status and code.
+var problemKinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrInvalidRequest, http.StatusBadRequest, "invalid_request"},
+	{ErrNotFound, http.StatusNotFound, "not_found"},
+	{ErrMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+	{ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{ErrForbidden, http.StatusForbidden, "forbidden"},
+	{ErrConflict, http.StatusConflict, "conflict"},
+	{ErrKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{ErrMisdirected, http.StatusMisdirectedRequest, "misdirected_request"},
+	{ErrPeerUnreachable, http.StatusBadGateway, "peer_unreachable"},
+	{ErrRuntime, http.StatusBadGateway, "runtime_error"},
+	{ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+	{ErrTooManyConns, http.StatusTooManyRequests, "too_many_connections"},
+	{ErrTooLarge, http.StatusRequestEntityTooLarge, "request_too_large"},
+	{ErrHeadersTooLarge, http.StatusRequestHeaderFieldsTooLarge, "headers_too_large"},
+	{ErrDegraded, http.StatusServiceUnavailable, "degraded"},
+	{ErrInternal, http.StatusInternalServerError, "internal_error"},
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the stable code of the error.
+	Code string `json:"code"`
+	// RequestID is the ID of the request, to correlate with the logs.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// apiError is an API error with a detailed message.
+type apiError struct {
+	kind   error
+	detail string
+}
+
+func (e *apiError) Error() string { return e.detail }
+func (e *apiError) Unwrap() error { return e.kind }
+
+// problemf returns an error of the given kind with a formatted detail.
+func problemf(kind error, format string, args ...interface{}) error {
+	return &apiError{kind: kind, detail: fmt.Sprintf(format, args...)}
+}
+
+// writeProblem answers a request with the problem describing err. Errors of
+// unknown kinds are internal errors.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := Problem{
+		Status:   http.StatusInternalServerError,
+		Code:     "internal_error",
+		Title:    ErrInternal.Error(),
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+	}
+	for _, k := range problemKinds {
+		if errors.Is(err, k.err) {
+			p.Status, p.Code, p.Title = k.status, k.code, k.err.Error()
+			break
+		}
+	}
+	p.Type = "urn:containerslist:problem:" + p.Code
+	p.RequestID = requestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// requireMethod answers with a problem to the requests not using the given
+// method, returning whether the request can be served.
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeProblem(w, r, problemf(ErrMethodNotAllowed, "%s must be used", method))
+	return false
+}
+
+// apiNotFound answers the requests to unknown API endpoints.
+func apiNotFound() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+	})
+}
@@ -0,0 +1,70 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{name: "kind", err: problemf(ErrNotFound, "no node %q", "a"), wantStatus: http.StatusNotFound, wantCode: "not_found", wantDetail: `no node "a"`},
+		{name: "wrapped", err: fmt.Errorf("querying peer: %w", problemf(ErrPeerUnreachable, "timeout")), wantStatus: http.StatusBadGateway, wantCode: "peer_unreachable", wantDetail: "querying peer: timeout"},
+		{name: "bare kind", err: ErrDegraded, wantStatus: http.StatusServiceUnavailable, wantCode: "degraded", wantDetail: "degraded"},
+		{name: "unknown", err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCode: "internal_error", wantDetail: "boom"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeProblem(rec, httptest.NewRequest(http.MethodGet, "/api/v1/nodes/a?x=1", nil), tc.err)
+			if rec.Code != tc.wantStatus || rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("response = %d %s, want %d application/problem+json", rec.Code, rec.Header().Get("Content-Type"), tc.wantStatus)
+			}
+			var p Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			want := Problem{
+				Type:     "urn:containerslist:problem:" + tc.wantCode,
+				Title:    p.Title,
+				Status:   tc.wantStatus,
+				Detail:   tc.wantDetail,
+				Instance: "/api/v1/nodes/a",
+				Code:     tc.wantCode,
+			}
+			if p != want || p.Title == "" {
+				t.Errorf("problem = %+v, want %+v", p, want)
+			}
+		})
+	}
+
+	// Every kind has its own code.
+	codes := map[string]bool{}
+	for _, k := range problemKinds {
+		if codes[k.code] {
+			t.Errorf("code %s used twice", k.code)
+		}
+		codes[k.code] = true
+	}
+}
+
+func TestRequireMethod(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if requireMethod(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/prune", nil), http.MethodPost) {
+		t.Error("GET accepted, want POST only")
+	}
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("response = %d, Allow %q, want 405 allowing POST", rec.Code, rec.Header().Get("Allow"))
+	}
+	if !requireMethod(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune", nil), http.MethodPost) {
+		t.Error("POST refused")
+	}
+}
@@ -0,0 +1,141 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// Labels set by Docker Compose and Swarm stacks.
+const (
+	labelComposeProject = "com.docker.compose.project"
+	labelComposeService = "com.docker.compose.service"
+	labelStackNamespace = "com.docker.stack.namespace"
+	labelSwarmService   = "com.docker.swarm.service.name"
+)
+
+const projectsTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - projects</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    {{ range . }}
+    <h2>{{ .Name }} ({{ .Kind }}){{ if not .Healthy }} - <strong>unhealthy</strong>{{ end }}</h2>
+    <table>
+      <tr><th>Service</th><th>Running</th><th>Expected</th><th>Nodes</th></tr>
+    {{ range .Services }}
+      <tr><td>{{ .Name }}</td><td>{{ .Running }}</td><td>{{ .Expected }}</td><td>{{ range .Nodes }}{{ . }} {{ end }}</td></tr>
+    {{ end }}
+    </table>
+    {{ end }}
+  </body>
+</html>
+`
+
+// Kinds of projects.
+const (
+	ProjectCompose = "compose"
+	ProjectStack   = "stack"
+)
+
+// Project is a Docker Compose project or Swarm stack, aggregated across
+// nodes.
+type Project struct {
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`
+	Services []ServiceHealth `json:"services"`
+	Healthy  bool            `json:"healthy"`
+	services map[string]*ServiceHealth
+}
+
+// ServiceHealth compares the containers of a project service which are
+// expected (all the known ones, whatever their state) and running.
+type ServiceHealth struct {
+	Name     string   `json:"name"`
+	Expected int      `json:"expected"`
+	Running  int      `json:"running"`
+	Nodes    []string `json:"nodes"`
+}
+
+// containerProject returns the project and service a container belongs to,
+// according to its labels.
+func containerProject(c Container) (kind, project, service string, ok bool) {
+	if project := c.Labels[labelComposeProject]; project != "" {
+		return ProjectCompose, project, c.Labels[labelComposeService], true
+	}
+	if project := c.Labels[labelStackNamespace]; project != "" {
+		return ProjectStack, project, c.Labels[labelSwarmService], true
+	}
+	return "", "", "", false
+}
+
+// projects groups the containers of all nodes by project.
+func (m *Manager) projects() []*Project {
+	type key struct{ kind, name string }
+	projects := map[key]*Project{}
+	for _, n := range m.inventory.Nodes() {
+		for _, c := range n.Containers {
+			kind, name, service, ok := containerProject(c)
+			if !ok {
+				continue
+			}
+			p, ok := projects[key{kind, name}]
+			if !ok {
+				p = &Project{Name: name, Kind: kind, services: map[string]*ServiceHealth{}}
+				projects[key{kind, name}] = p
+			}
+			s, ok := p.services[service]
+			if !ok {
+				s = &ServiceHealth{Name: service}
+				p.services[service] = s
+			}
+			s.Expected++
+			if c.State == StateRunning {
+				s.Running++
+				if len(s.Nodes) == 0 || s.Nodes[len(s.Nodes)-1] != n.Node {
+					s.Nodes = append(s.Nodes, n.Node)
+				}
+			}
+		}
+	}
+
+	res := make([]*Project, 0, len(projects))
+	for _, p := range projects {
+		p.Healthy = true
+		for _, s := range p.services {
+			p.Services = append(p.Services, *s)
+			if s.Running < s.Expected {
+				p.Healthy = false
+			}
+		}
+		sort.Slice(p.Services, func(i, j int) bool {
+			return p.Services[i].Name < p.Services[j].Name
+		})
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Kind < res[j].Kind
+	})
+	return res
+}
+
+func (m *Manager) apiProjects() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.projects())
+	})
+}
+
+func (m *Manager) projectsPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := localize(t, r).ExecuteTemplate(w, "projects", m.projects()); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestInventoryManager returns a manager whose inventory holds the
+// containers of the nodes.
+func newTestInventoryManager(t *testing.T, nodes ...*NodeInventory) *Manager {
+	t.Helper()
+	m := &Manager{cfg: DefaultConfig(), inventory: newInventory(), conflicts: newConflictTracker(prometheus.NewRegistry())}
+	for _, n := range nodes {
+		if n.Timestamp.IsZero() {
+			n.Timestamp = time.Now()
+		}
+		if _, err := m.inventory.Set(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestProjects(t *testing.T) {
+	compose := func(project, service string) map[string]string {
+		return map[string]string{labelComposeProject: project, labelComposeService: service}
+	}
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{
+			{ID: "a1", State: StateRunning, Labels: compose("shop", "web")},
+			{ID: "a2", State: StateRunning, Labels: compose("shop", "web")},
+			{ID: "a3", State: "exited", Labels: compose("shop", "db")},
+			{ID: "a4", State: StateRunning, Labels: map[string]string{labelStackNamespace: "shop", labelSwarmService: "shop_api"}},
+			{ID: "a5", State: StateRunning},
+		}},
+		&NodeInventory{Node: "b", Containers: []Container{
+			{ID: "b1", State: StateRunning, Labels: compose("shop", "web")},
+			{ID: "b2", State: StateRunning, Labels: compose("blog", "web")},
+		}},
+	)
+
+	want := []*Project{
+		{Name: "blog", Kind: ProjectCompose, Healthy: true, Services: []ServiceHealth{
+			{Name: "web", Expected: 1, Running: 1, Nodes: []string{"b"}},
+		}},
+		{Name: "shop", Kind: ProjectCompose, Services: []ServiceHealth{
+			{Name: "db", Expected: 1},
+			{Name: "web", Expected: 3, Running: 3, Nodes: []string{"a", "b"}},
+		}},
+		{Name: "shop", Kind: ProjectStack, Healthy: true, Services: []ServiceHealth{
+			{Name: "shop_api", Expected: 1, Running: 1, Nodes: []string{"a"}},
+		}},
+	}
+	got := m.projects()
+	for _, p := range got {
+		p.services = nil
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("projects = %+v, want %+v", got, want)
+	}
+}
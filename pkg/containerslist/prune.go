@@ -0,0 +1,163 @@
+package containerslist
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// Kinds of prune candidates.
+const (
+	PruneContainer = "container"
+	PruneImage     = "image"
+)
+
+// PruneCandidate is a resource which is recommended for removal.
+type PruneCandidate struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// PruneResult is the outcome of a prune operation on a node.
+type PruneResult struct {
+	Node              string   `json:"node"`
+	ContainersDeleted []string `json:"containersDeleted"`
+	ImagesDeleted     []string `json:"imagesDeleted"`
+	SpaceReclaimed    int64    `json:"spaceReclaimed"`
+}
+
+// recommendPrune computes the prune candidates of a source and broadcasts
+// them to the cluster. It works on the complete container list since old
+// stopped containers are not part of the gossiped inventory.
+func (m *Manager) recommendPrune(s *dockerSource, containers []Container, images []Image) error {
+	now := time.Now().UTC()
+	candidates := []PruneCandidate{}
+
+	referenced := map[string]struct{}{}
+	for _, c := range containers {
+		referenced[c.ImageID] = struct{}{}
+		if c.State == StateRunning || c.FinishedAt == nil {
+			continue
+		}
+		if age := now.Sub(*c.FinishedAt); age >= m.cfg.PruneStoppedAfter {
+			candidates = append(candidates, PruneCandidate{
+				Kind:   PruneContainer,
+				ID:     c.ID,
+				Name:   c.Name,
+				Reason: "stopped for " + age.Truncate(time.Hour).String(),
+			})
+		}
+	}
+
+	for _, i := range images {
+		if _, ok := referenced[i.ID]; ok || len(i.Tags) > 0 {
+			continue
+		}
+		candidates = append(candidates, PruneCandidate{
+			Kind:   PruneImage,
+			ID:     i.ID,
+			Reason: "dangling",
+			Size:   i.Size,
+		})
+	}
+
+	b, err := m.pruneCandidates.Set(&NodeResources[PruneCandidate]{Node: s.node, Timestamp: now, Items: candidates})
+	if err != nil {
+		return err
+	}
+	m.pruneChannel.Broadcast(b)
+	return nil
+}
+
+// requireAdmin only lets through requests bearing the admin token or an API
+// key with the admin role, or forwarded by the peer which authorized them.
+// Admin operations are disabled when neither the admin token nor the API keys
+// are configured.
+func (m *Manager) requireAdmin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.cfg.AdminToken.Value() == "" && m.apiKeys == nil && (!m.cfg.HTTPTLSSPIFFE || m.cfg.HTTPSPIFFERole != RoleAdmin) {
+			writeProblem(w, r, problemf(ErrForbidden, "admin API is disabled"))
+			return
+		}
+		if _, ok := forwardedCaller(r); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if ok && m.cfg.AdminToken.Value() != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.AdminToken.Value())) == 1 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if k, kind, found := m.credential(r, token, ok); found {
+			if !k.grants(RoleAdmin) {
+				writeProblem(w, r, problemf(ErrForbidden, "%s %q has the %s role: the admin role is required", kind, k.Name, k.Role))
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeProblem(w, r, problemf(ErrUnauthorized, "a valid admin bearer token is required"))
+	})
+}
+
+// adminCaller returns the identity of the caller of an admin request: the
+// admin token, or the name of its API key or SPIFFE ID, as authorized by the
+// peer which forwarded it, if any.
+func (m *Manager) adminCaller(r *http.Request) string {
+	if caller, ok := forwardedCaller(r); ok {
+		return caller
+	}
+	token, ok := bearerToken(r)
+	if ok && m.cfg.AdminToken.Value() != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.AdminToken.Value())) == 1 {
+		return "admin token"
+	}
+	if k, kind, found := m.credential(r, token, ok); found {
+		return kind + " " + k.Name
+	}
+	return ""
+}
+
+// apiPrune removes the stopped containers and dangling images of the local
+// node, or of one of its sub-nodes. Pruning another node is done by calling
+// that node's API.
+func (m *Manager) apiPrune() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		s := m.sources[0]
+		if node := r.URL.Query().Get("node"); node != "" {
+			var ok bool
+			if s, ok = m.source(node); !ok {
+				m.forward(w, r, node)
+				return
+			}
+		}
+
+		res := PruneResult{Node: s.node}
+		containers, space, err := s.client.PruneContainers(r.Context(), m.cfg.PruneStoppedAfter)
+		if err != nil {
+			writeProblem(w, r, problemf(ErrRuntime, "%v", err))
+			return
+		}
+		res.ContainersDeleted = containers
+		res.SpaceReclaimed += space
+
+		images, space, err := s.client.PruneImages(r.Context())
+		if err != nil {
+			writeProblem(w, r, problemf(ErrRuntime, "%v", err))
+			return
+		}
+		res.ImagesDeleted = images
+		res.SpaceReclaimed += space
+
+		level.Info(m.requestLogger(r.Context())).Log("msg", "Pruned node", "node", s.node, "containers", len(res.ContainersDeleted), "images", len(res.ImagesDeleted), "space_reclaimed", res.SpaceReclaimed)
+		writeJSON(w, res)
+	})
+}
@@ -0,0 +1,77 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestRecommendPrune(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PruneStoppedAfter = 24 * time.Hour
+	m := &Manager{cfg: cfg, pruneCandidates: newResourceState[PruneCandidate](), pruneChannel: &testChannel{}}
+
+	now := time.Now().UTC()
+	longAgo, recently := now.Add(-50*time.Hour), now.Add(-time.Hour)
+	containers := []Container{
+		{ID: "c1", Name: "web", ImageID: "sha256:web", State: StateRunning},
+		{ID: "c2", Name: "old", ImageID: "sha256:old", State: "exited", FinishedAt: &longAgo},
+		{ID: "c3", Name: "recent", ImageID: "sha256:web", State: "exited", FinishedAt: &recently},
+		{ID: "c4", Name: "created", ImageID: "sha256:web", State: "created"},
+	}
+	images := []Image{
+		{ID: "sha256:web", Tags: []string{"nginx:1.25"}},
+		// Dangling but still used by a stopped container.
+		{ID: "sha256:old", Size: 10},
+		{ID: "sha256:dangling", Size: 20},
+		{ID: "sha256:tagged", Tags: []string{"redis:7"}, Size: 30},
+	}
+	if err := m.recommendPrune(&dockerSource{node: "a"}, containers, images); err != nil {
+		t.Fatal(err)
+	}
+
+	n, ok := m.pruneCandidates.Get("a")
+	if !ok {
+		t.Fatal("no candidates for a")
+	}
+	want := []PruneCandidate{
+		{Kind: PruneContainer, ID: "c2", Name: "old", Reason: "stopped for 50h0m0s"},
+		{Kind: PruneImage, ID: "sha256:dangling", Reason: "dangling", Size: 20},
+	}
+	if !reflect.DeepEqual(n.Items, want) {
+		t.Errorf("candidates = %+v, want %+v", n.Items, want)
+	}
+}
+
+func TestAPIPrune(t *testing.T) {
+	s := &dockerSource{node: "a", client: newTestDockerClient(t, map[string]string{
+		"/containers/prune": `{"ContainersDeleted": ["c2"], "SpaceReclaimed": 100}`,
+		"/images/prune":     `{"ImagesDeleted": [{"Untagged": "old:1"}, {"Deleted": "sha256:old"}], "SpaceReclaimed": 1000}`,
+	})}
+	m := &Manager{cfg: DefaultConfig(), logger: log.NewNopLogger(), sources: []*dockerSource{s}}
+
+	rec := httptest.NewRecorder()
+	m.apiPrune().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/prune", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	rec = httptest.NewRecorder()
+	m.apiPrune().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/prune?node=a", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var got PruneResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := PruneResult{Node: "a", ContainersDeleted: []string{"c2"}, ImagesDeleted: []string{"sha256:old"}, SpaceReclaimed: 1100}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package containerslist
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-kit/log/level"
+)
+
+const errorTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - {{ t "error" }}</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <h1>{{ t "Something went wrong" }}</h1>
+    <p>{{ t "The page could not be displayed. Retry later, or report the request ID %s." .RequestID }}</p>
+  </body>
+</html>`
+
+var errorPage = func() *template.Template {
+	t := template.Must(template.New("error").Funcs(templateFuncs).Parse(errorTpl))
+	template.Must(t.New("nav").Parse(navTpl))
+	return t
+}()
+
+// recoverPanics answers the requests whose handler panicked with a 500: a
+// problem for the API and an error page for the browsers. The panic is
+// logged with its stack and counted by pattern of the handler of mux. When
+// the response was already started, the connection is aborted instead, so
+// that the client does not take a truncated response for a complete one.
+func (m *Manager) recoverPanics(mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoverWriter{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				_, pattern := mux.Handler(r)
+				m.panics.WithLabelValues(pattern).Inc()
+				level.Error(m.requestLogger(r.Context())).Log("msg", "Handler panicked", "method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				w.Header().Del("Content-Length")
+				if !strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/internal/") && strings.Contains(r.Header.Get("Accept"), "text/html") {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.WriteHeader(http.StatusInternalServerError)
+					localize(errorPage, r).ExecuteTemplate(w, "error", struct{ RequestID string }{requestID(r.Context())})
+					return
+				}
+				writeProblem(w, r, problemf(ErrInternal, "the request failed unexpectedly"))
+			}()
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// recoverWriter records whether a response was started.
+type recoverWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *recoverWriter) WriteHeader(status int) {
+	if status >= http.StatusOK {
+		w.started = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, for the event streams.
+func (w *recoverWriter) Flush() {
+	w.started = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for
+// http.ResponseController.
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
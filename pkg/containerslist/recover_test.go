@@ -0,0 +1,65 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	m := &Manager{
+		logger: log.NewNopLogger(),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_panics_total", Help: "Test."}, []string{"handler"}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/api/v1/events", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: {}\n\n")
+		panic("boom")
+	})
+	h := withRequestID(m.recoverPanics(mux)(mux))
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// The API answers with a problem, even to the browsers.
+	rec := serve("/api/v1/containers", "text/html")
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusInternalServerError || p.Code != "internal_error" || p.RequestID == "" {
+		t.Errorf("status %d with problem %+v, want an internal error", rec.Code, p)
+	}
+
+	rec = serve("/", "text/html,application/xhtml+xml")
+	if id := rec.Header().Get(requestIDHeader); rec.Code != http.StatusInternalServerError || id == "" || !strings.Contains(rec.Body.String(), id) {
+		t.Errorf("status %d with page %q, want an error page giving the request ID %q", rec.Code, rec.Body, id)
+	}
+	if got := testutil.ToFloat64(m.panics.WithLabelValues("/api/v1/containers")); got != 1 {
+		t.Errorf("panics of the containers = %v, want 1", got)
+	}
+
+	// A started response is aborted.
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("panic = %v, want the abort of the handler", v)
+		}
+		if got := testutil.ToFloat64(m.panics.WithLabelValues("/api/v1/events")); got != 1 {
+			t.Errorf("panics of the events = %v, want 1", got)
+		}
+	}()
+	serve("/api/v1/events", "text/event-stream")
+}
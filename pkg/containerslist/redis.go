@@ -0,0 +1,360 @@
+package containerslist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// redisError is an error reply of the Redis server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisClient is a minimal client of the Redis protocol (RESP), holding a
+// single connection which is re-established after errors.
+type redisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+	timeout  time.Duration
+
+	mtx  sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newRedisClient parses a redis://[[user]:password@]host[:port][/db] URL.
+func newRedisClient(rawURL string, timeout time.Duration) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q: must be redis://[[user]:password@]host[:port][/db]", rawURL)
+	}
+	c := &redisClient{addr: u.Host, timeout: timeout}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and returns its reply: a string, an int64, a []any of
+// replies, nil, or a redisError.
+func (c *redisClient) Do(ctx context.Context, args ...string) (any, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	res, err := c.do(ctx, args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return res, err
+}
+
+func (c *redisClient) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+
+	var cmds [][]string
+	if c.password != "" {
+		if c.username != "" {
+			cmds = append(cmds, []string{"AUTH", c.username, c.password})
+		} else {
+			cmds = append(cmds, []string{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, cmd := range cmds {
+		if _, err := c.do(ctx, cmd...); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("%s: %w", cmd[0], err)
+		}
+	}
+	return nil
+}
+
+func (c *redisClient) do(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads a reply. The error replies nested in arrays are returned as
+// redisError values.
+func (c *redisClient) read() (any, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		res := make([]any, n)
+		for i := range res {
+			if res[i], err = c.read(); err != nil {
+				var rerr redisError
+				if !errors.As(err, &rerr) {
+					return nil, err
+				}
+				res[i] = rerr
+			}
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
+
+// redisState shares the merged inventory and the history of the aggregators
+// through Redis, so that any of them answers the API queries consistently
+// and that the history survives the failure of an aggregator.
+//
+// The inventory is a hash of the JSON-encoded inventory of each node, merged
+// both ways on each sync: the most recent entry of each node wins, as in the
+// gossiped state. The history is written by a single aggregator holding a
+// lease, and mirrored by the other ones.
+type redisState struct {
+	client   *redisClient
+	prefix   string
+	interval time.Duration
+
+	// loaded is set once the shared history has been loaded, before which
+	// the local history must not overwrite it.
+	loaded bool
+
+	failures prometheus.Counter
+	writer   prometheus.Gauge
+}
+
+func newRedisState(client *redisClient, prefix string, interval time.Duration, reg prometheus.Registerer) *redisState {
+	s := &redisState{
+		client:   client,
+		prefix:   prefix,
+		interval: interval,
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "containerslist_redis_sync_failures_total",
+			Help: "Total number of failed syncs of the shared state with Redis.",
+		}),
+		writer: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "containerslist_redis_history_writer",
+			Help: "Whether the node holds the lease to write the shared history to Redis.",
+		}),
+	}
+	reg.MustRegister(s.failures, s.writer)
+	return s
+}
+
+// runRedisSync periodically syncs the inventory and the history with Redis.
+func (m *Manager) runRedisSync(ctx context.Context) {
+	for {
+		if err := m.syncRedis(ctx); err != nil && ctx.Err() == nil {
+			m.redis.failures.Inc()
+			level.Warn(m.logger).Log("msg", "Unable to sync shared state with Redis", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.redis.interval):
+		}
+	}
+}
+
+func (m *Manager) syncRedis(ctx context.Context) error {
+	if err := m.syncRedisInventory(ctx); err != nil {
+		return fmt.Errorf("inventory: %w", err)
+	}
+	if err := m.syncRedisHistory(ctx); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	return nil
+}
+
+// syncRedisInventory merges the shared inventory into the local one, then
+// writes the local entries more recent than the shared ones, except the
+// summaries of the nodes of the shards not aggregated locally.
+func (m *Manager) syncRedisInventory(ctx context.Context) error {
+	s := m.redis
+	key := s.prefix + "inventory"
+	res, err := s.client.Do(ctx, "HGETALL", key)
+	if err != nil {
+		return err
+	}
+	fields, _ := res.([]any)
+	shared := map[string]time.Time{}
+	entries := make([]json.RawMessage, 0, len(fields)/2)
+	for i := 1; i < len(fields); i += 2 {
+		v, _ := fields[i].(string)
+		var n struct {
+			Node      string    `json:"node"`
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if err := json.Unmarshal([]byte(v), &n); err != nil {
+			level.Debug(m.logger).Log("msg", "Ignoring invalid shared inventory", "node", fields[i-1], "error", err)
+			continue
+		}
+		shared[n.Node] = n.Timestamp
+		entries = append(entries, json.RawMessage(v))
+	}
+	if len(entries) > 0 {
+		b, err := json.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		if err := m.inventory.Merge(b); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"HSET", key}
+	for _, n := range append(m.inventory.Nodes(), m.inventory.Tombstones()...) {
+		if ts, ok := shared[n.Node]; n.Summary || ok && !n.Timestamp.After(ts) {
+			continue
+		}
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		args = append(args, n.Node, string(b))
+	}
+	if len(args) == 2 {
+		return nil
+	}
+	_, err = s.client.Do(ctx, args...)
+	return err
+}
+
+// syncRedisHistory writes the local history to Redis when holding the lease
+// of the history writer, and otherwise replaces it with the shared one. The
+// lease expires after a few sync intervals, for another aggregator to take
+// over the writes when its holder fails. Passive members never take the
+// lease.
+func (m *Manager) syncRedisHistory(ctx context.Context) error {
+	s := m.redis
+	key := s.prefix + "history"
+	if !s.loaded {
+		if err := m.loadRedisHistory(ctx, key); err != nil {
+			return err
+		}
+		s.loaded = true
+	}
+
+	if m.cfg.Passive {
+		s.writer.Set(0)
+		return m.loadRedisHistory(ctx, key)
+	}
+	lease := strconv.FormatInt((3 * s.interval).Milliseconds(), 10)
+	res, err := s.client.Do(ctx, "SET", key+":writer", m.nodeID, "NX", "PX", lease)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		holder, err := s.client.Do(ctx, "GET", key+":writer")
+		if err != nil {
+			return err
+		}
+		if holder != m.nodeID {
+			s.writer.Set(0)
+			return m.loadRedisHistory(ctx, key)
+		}
+		if _, err := s.client.Do(ctx, "PEXPIRE", key+":writer", lease); err != nil {
+			return err
+		}
+	}
+	s.writer.Set(1)
+
+	b, err := json.Marshal(m.history.Snapshot())
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do(ctx, "SET", key, string(b))
+	return err
+}
+
+func (m *Manager) loadRedisHistory(ctx context.Context, key string) error {
+	res, err := m.redis.client.Do(ctx, "GET", key)
+	if err != nil || res == nil {
+		return err
+	}
+	v, _ := res.(string)
+	var snapshot historySnapshot
+	if err := json.Unmarshal([]byte(v), &snapshot); err != nil {
+		return err
+	}
+	m.history.Restore(snapshot)
+	return nil
+}
@@ -0,0 +1,251 @@
+package containerslist
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a Redis server answering each command with a scripted raw
+// reply, by command name, and recording the commands it received.
+type fakeRedis struct {
+	ln      net.Listener
+	replies map[string]string
+
+	mtx      sync.Mutex
+	commands [][]string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, replies map[string]string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{ln: ln, replies: replies}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.conns++
+			s.mtx.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		cmd, err := readRESPCommand(rd)
+		if err != nil {
+			return
+		}
+		s.mtx.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mtx.Unlock()
+		reply, ok := s.replies[cmd[0]]
+		if !ok {
+			reply = "-ERR unknown command '" + cmd[0] + "'\r\n"
+		}
+		if reply == "" {
+			// Drop the connection without replying.
+			return
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readRESPCommand reads a command sent as an array of bulk strings.
+func readRESPCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if line[0] != '*' || err != nil {
+		return nil, fmt.Errorf("invalid command %q", line)
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		if line, err = rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if line[0] != '$' || err != nil {
+			return nil, fmt.Errorf("invalid argument %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
+
+func (s *fakeRedis) received() [][]string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+func TestRedisClientReplies(t *testing.T) {
+	srv := newFakeRedis(t, map[string]string{
+		"SET":     "+OK\r\n",
+		"INCR":    ":42\r\n",
+		"NEGATE":  ":-7\r\n",
+		"GET":     "$5\r\nhello\r\n",
+		"BINARY":  "$7\r\na\r\nb\x00cd\r\n",
+		"EMPTY":   "$0\r\n\r\n",
+		"MISSING": "$-1\r\n",
+		"MGET":    "*4\r\n$1\r\na\r\n$-1\r\n:3\r\n*1\r\n+nested\r\n",
+		"EXEC":    "*2\r\n+OK\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		"NOARRAY": "*-1\r\n",
+		"NOITEMS": "*0\r\n",
+		"FAIL":    "-ERR failure\r\n",
+		"INVALID": "?what\r\n",
+	})
+	c, err := newRedisClient("redis://"+srv.ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		cmd     string
+		want    any
+		wantErr error
+	}{
+		{cmd: "SET", want: "OK"},
+		{cmd: "INCR", want: int64(42)},
+		{cmd: "NEGATE", want: int64(-7)},
+		{cmd: "GET", want: "hello"},
+		{cmd: "BINARY", want: "a\r\nb\x00cd"},
+		{cmd: "EMPTY", want: ""},
+		{cmd: "MISSING", want: nil},
+		{cmd: "MGET", want: []any{"a", nil, int64(3), []any{"nested"}}},
+		{cmd: "EXEC", want: []any{"OK", redisError("WRONGTYPE Operation against a key holding the wrong kind of value")}},
+		{cmd: "NOARRAY", want: nil},
+		{cmd: "NOITEMS", want: []any{}},
+		{cmd: "FAIL", wantErr: redisError("ERR failure")},
+		{cmd: "INVALID", wantErr: errors.New(`redis: invalid reply "?what"`)},
+	} {
+		t.Run(tc.cmd, func(t *testing.T) {
+			got, err := c.Do(context.Background(), tc.cmd, "key")
+			if tc.wantErr != nil {
+				if err == nil || err.Error() != tc.wantErr.Error() {
+					t.Fatalf("error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("reply = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	// The error replies keep the connection; the invalid one drops it.
+	if _, err := c.Do(context.Background(), "SET", "key"); err != nil {
+		t.Fatal(err)
+	}
+	srv.mtx.Lock()
+	defer srv.mtx.Unlock()
+	if srv.conns != 2 {
+		t.Errorf("%d connections, want 2", srv.conns)
+	}
+}
+
+func TestRedisClientConnect(t *testing.T) {
+	srv := newFakeRedis(t, map[string]string{
+		"AUTH":   "+OK\r\n",
+		"SELECT": "+OK\r\n",
+		"PING":   "+PONG\r\n",
+		"DROP":   "",
+	})
+	c, err := newRedisClient("redis://user:secret@"+srv.ln.Addr().String()+"/3", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(context.Background(), "PING"); err != nil {
+		t.Fatal(err)
+	}
+	// A dropped connection fails the command, and is established again by
+	// the next one.
+	if _, err := c.Do(context.Background(), "DROP"); err == nil {
+		t.Fatal("dropped connection not reported")
+	}
+	if _, err := c.Do(context.Background(), "PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"AUTH", "user", "secret"}, {"SELECT", "3"}, {"PING"}, {"DROP"},
+		{"AUTH", "user", "secret"}, {"SELECT", "3"}, {"PING"},
+	}
+	if got := srv.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestRedisClientAuthFailure(t *testing.T) {
+	srv := newFakeRedis(t, map[string]string{
+		"AUTH": "-WRONGPASS invalid username-password pair\r\n",
+	})
+	c, err := newRedisClient("redis://:wrong@"+srv.ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Do(context.Background(), "PING")
+	var rerr redisError
+	if !errors.As(err, &rerr) || !strings.HasPrefix(err.Error(), "AUTH: ") {
+		t.Fatalf("error = %v, want an AUTH error reply", err)
+	}
+	if c.conn != nil {
+		t.Error("connection kept after the authentication failure")
+	}
+}
+
+func TestNewRedisClient(t *testing.T) {
+	for _, tc := range []struct {
+		url      string
+		wantAddr string
+		wantDB   int
+		wantErr  bool
+	}{
+		{url: "redis://localhost", wantAddr: "localhost:6379"},
+		{url: "redis://localhost:6380/2", wantAddr: "localhost:6380", wantDB: 2},
+		{url: "rediss://localhost", wantErr: true},
+		{url: "redis://localhost/db", wantErr: true},
+		{url: "redis:///0", wantErr: true},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			c, err := newRedisClient(tc.url, time.Second)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && (c.addr != tc.wantAddr || c.db != tc.wantDB) {
+				t.Errorf("addr, db = %s, %d, want %s, %d", c.addr, c.db, tc.wantAddr, tc.wantDB)
+			}
+		})
+	}
+}
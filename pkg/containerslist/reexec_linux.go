@@ -0,0 +1,12 @@
+package containerslist
+
+import (
+	"os"
+	"syscall"
+)
+
+// reexec replaces the process with a binary, with the same arguments and
+// environment.
+func reexec(path string) error {
+	return syscall.Exec(path, os.Args, os.Environ())
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package containerslist
+
+import "errors"
+
+// reexec replaces the process with a binary, with the same arguments and
+// environment.
+func reexec(path string) error {
+	return errors.New("restarting on an update is only supported on Linux")
+}
@@ -0,0 +1,270 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Media types of the OCI and Docker manifests.
+const (
+	mediaTypeOCIIndex        = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest     = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList      = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest  = "application/vnd.docker.distribution.manifest.v2+json"
+	defaultRegistry          = "registry-1.docker.io"
+	maxRegistryResponseBytes = 32 << 20
+)
+
+// imageRef is a reference to an image of a registry.
+type imageRef struct {
+	Registry   string
+	Repository string
+	// Reference is the tag or the digest of the image.
+	Reference string
+}
+
+// parseImageRef parses an image reference, with the defaults of the Docker
+// CLI: images without registry are on the Docker Hub, in the library
+// namespace when they have no namespace either, and are tagged latest.
+func parseImageRef(s string) (imageRef, error) {
+	var ref imageRef
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Reference = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		if ref.Reference == "" {
+			ref.Reference = name[i+1:]
+		}
+		name = name[:i]
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+
+	ref.Registry = defaultRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		if host := name[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry, name = host, name[i+1:]
+		}
+	}
+	if name == "" || strings.HasPrefix(name, "sha256:") {
+		return imageRef{}, fmt.Errorf("invalid image reference %q", s)
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// ociDescriptor describes a manifest or a blob of a registry.
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is an image manifest or an image index.
+type ociManifest struct {
+	MediaType    string          `json:"mediaType"`
+	ArtifactType string          `json:"artifactType,omitempty"`
+	Config       ociDescriptor   `json:"config"`
+	Layers       []ociDescriptor `json:"layers,omitempty"`
+	Manifests    []ociDescriptor `json:"manifests,omitempty"`
+}
+
+// registryClient is a minimal client for the OCI distribution API, only
+// pulling anonymously.
+type registryClient struct {
+	client   *http.Client
+	breakers *breakers
+
+	mtx    sync.Mutex
+	tokens map[string]string
+}
+
+func newRegistryClient(bs *breakers) *registryClient {
+	return &registryClient{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		breakers: bs,
+		tokens:   map[string]string{},
+	}
+}
+
+// Manifest returns a manifest or an index, along with its digest.
+func (c *registryClient) Manifest(ctx context.Context, ref imageRef, reference string) (ociManifest, string, error) {
+	var m ociManifest
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeOCIManifest, mediaTypeDockerList, mediaTypeDockerManifest}, ", ")
+	b, header, err := c.get(ctx, ref, "/manifests/"+reference, accept)
+	if err != nil {
+		return m, "", err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, "", fmt.Errorf("invalid manifest %s: %w", reference, err)
+	}
+	digest := header.Get("Docker-Content-Digest")
+	if digest == "" && strings.HasPrefix(reference, "sha256:") {
+		digest = reference
+	}
+	return m, digest, nil
+}
+
+// Referrers returns the artifacts referring to a manifest, or nothing when
+// the registry does not support the referrers API.
+func (c *registryClient) Referrers(ctx context.Context, ref imageRef, digest string) ([]ociDescriptor, error) {
+	b, _, err := c.get(ctx, ref, "/referrers/"+digest, mediaTypeOCIIndex)
+	var statusErr registryStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index ociManifest
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("invalid referrers of %s: %w", digest, err)
+	}
+	return index.Manifests, nil
+}
+
+// Blob returns the content of a blob.
+func (c *registryClient) Blob(ctx context.Context, ref imageRef, digest string) ([]byte, error) {
+	b, _, err := c.get(ctx, ref, "/blobs/"+digest, "*/*")
+	return b, err
+}
+
+// registryStatusError is returned when a registry replies with an
+// unexpected status.
+type registryStatusError struct {
+	path   string
+	status int
+}
+
+func (e registryStatusError) Error() string {
+	return fmt.Sprintf("registry %s: unexpected status %d %s", e.path, e.status, http.StatusText(e.status))
+}
+
+func (c *registryClient) get(ctx context.Context, ref imageRef, path, accept string) ([]byte, http.Header, error) {
+	var (
+		b       []byte
+		header  http.Header
+		callErr error
+	)
+	err := c.breakers.Get("registry:"+ref.Registry).Do(ctx, func() error {
+		b, header, callErr = c.call(ctx, ref, path, accept)
+		var statusErr registryStatusError
+		if errors.As(callErr, &statusErr) && statusErr.status == http.StatusNotFound {
+			// A missing manifest is not a failure of the registry.
+			return nil
+		}
+		return callErr
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, header, callErr
+}
+
+func (c *registryClient) call(ctx context.Context, ref imageRef, path, accept string) ([]byte, http.Header, error) {
+	u := "https://" + ref.Registry + "/v2/" + ref.Repository + path
+	resp, err := c.do(ctx, u, accept, c.token(ref))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.authenticate(ctx, ref, challenge)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp, err = c.do(ctx, u, accept, token); err != nil {
+			return nil, nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, registryStatusError{path: ref.Repository + path, status: resp.StatusCode}
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxRegistryResponseBytes))
+	return b, resp.Header, err
+}
+
+func (c *registryClient) do(ctx context.Context, u, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.client.Do(req)
+}
+
+func (c *registryClient) token(ref imageRef) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.tokens[ref.Registry+"/"+ref.Repository]
+}
+
+// authenticate gets an anonymous pull token for a repository, following the
+// bearer challenge of the registry.
+func (c *registryClient) authenticate(ctx context.Context, ref imageRef, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry %s: unsupported authentication %q", ref.Registry, scheme)
+	}
+	values := map[string]string{}
+	for _, p := range strings.Split(params, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok {
+			values[k] = strings.Trim(v, `"`)
+		}
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", fmt.Errorf("registry %s: invalid authentication realm %q", ref.Registry, values["realm"])
+	}
+	q := realm.Query()
+	if values["service"] != "" {
+		q.Set("service", values["service"])
+	}
+	q.Set("scope", "repository:"+ref.Repository+":pull")
+	realm.RawQuery = q.Encode()
+
+	resp, err := c.do(ctx, realm.String(), "application/json", "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s: unable to authenticate: unexpected status %s", ref.Registry, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	c.mtx.Lock()
+	c.tokens[ref.Registry+"/"+ref.Repository] = token.Token
+	c.mtx.Unlock()
+	return token.Token, nil
+}
@@ -0,0 +1,114 @@
+package containerslist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/cluster"
+)
+
+// gossipRecord is an incoming gossiped update, as recorded for debugging.
+type gossipRecord struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+	Data  []byte    `json:"data"`
+}
+
+// gossipRecorder appends the incoming gossiped updates to a file, one JSON
+// record per line.
+type gossipRecorder struct {
+	mtx sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func newGossipRecorder(file string) (*gossipRecorder, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open gossip record file: %w", err)
+	}
+	return &gossipRecorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *gossipRecorder) record(state string, b []byte) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.enc.Encode(gossipRecord{Time: time.Now().UTC(), State: state, Data: b})
+}
+
+// recordedState records the updates merged into a state.
+type recordedState struct {
+	cluster.State
+	name     string
+	recorder *gossipRecorder
+}
+
+// Merge implements cluster.State.
+func (s recordedState) Merge(b []byte) error {
+	s.recorder.record(s.name, b)
+	return s.State.Merge(b)
+}
+
+// recorded wraps a state to record its incoming updates, when recording is
+// enabled.
+func (m *Manager) recorded(name string, s cluster.State) cluster.State {
+	if m.recorder == nil {
+		return s
+	}
+	return recordedState{State: s, name: name, recorder: m.recorder}
+}
+
+// replayGossip merges the recorded updates of a file into the local states,
+// to reproduce offline the state of a node. With a positive speed, the
+// updates are replayed at their recorded pace, accelerated by that factor;
+// otherwise they are replayed at once.
+func (m *Manager) replayGossip(ctx context.Context, file string, speed float64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	states := m.gossipStates()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var first, start time.Time
+	var merged, failed int
+	for line := 1; sc.Scan(); line++ {
+		var rec gossipRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		if first.IsZero() {
+			first, start = rec.Time, time.Now()
+		}
+		if speed > 0 {
+			at := start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(at)):
+			}
+		}
+
+		s, ok := states[rec.State]
+		if !ok {
+			level.Debug(m.logger).Log("msg", "Skipping replayed update of unknown state", "line", line, "state", rec.State)
+			continue
+		}
+		if err := s.Merge(rec.Data); err != nil {
+			failed++
+			level.Warn(m.logger).Log("msg", "Unable to merge replayed update", "line", line, "state", rec.State, "recorded_at", rec.Time, "error", err)
+			continue
+		}
+		merged++
+	}
+	level.Info(m.logger).Log("msg", "Gossip replay done", "file", file, "merged", merged, "failed", failed)
+	return sc.Err()
+}
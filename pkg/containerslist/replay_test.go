@@ -0,0 +1,99 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplayGossip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gossip.jsonl")
+	recorder, err := newGossipRecorder(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := newTestStatesManager(t)
+	if s := src.recorded("inventory", src.inventory); s != src.inventory {
+		t.Error("state wrapped without recorder")
+	}
+	src.recorder = recorder
+
+	now := time.Now().UTC()
+	for _, u := range []struct {
+		state string
+		v     interface{}
+	}{
+		{state: "inventory", v: []*NodeInventory{{Node: "a", Timestamp: now, Containers: []Container{{ID: "a1"}}}}},
+		{state: "facts", v: []*NodeFacts{{Node: "a", Peer: "p1", Timestamp: now}}},
+		{state: "inventory", v: []*NodeInventory{{Node: "b", Timestamp: now}}},
+	} {
+		b, err := json.Marshal(u.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		states := src.gossipStates()
+		if err := src.recorded(u.state, states[u.state]).Merge(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Updates which cannot be merged, or of unknown states, are skipped.
+	recorder.record("inventory", []byte("{invalid"))
+	recorder.record("unknown", []byte("[]"))
+	if err := recorder.f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestStatesManager(t)
+	if err := dst.replayGossip(context.Background(), file, 0); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := dst.inventory.Nodes(); len(nodes) != 2 || nodes[0].Node != "a" || len(nodes[0].Containers) != 1 || nodes[1].Node != "b" {
+		t.Errorf("replayed inventory = %+v, want a and b", nodes)
+	}
+	if _, ok := dst.facts.Get("a"); !ok {
+		t.Error("facts of a not replayed")
+	}
+
+	if err := os.WriteFile(file, []byte(`{"time": "2024-01-01T00:00:00Z", "state": "facts", "data": "W10="}`+"\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.replayGossip(context.Background(), file, 0); err == nil || !strings.Contains(err.Error(), "gossip.jsonl:2: ") {
+		t.Errorf("replay of an invalid record = %v, want an error at line 2", err)
+	}
+}
+
+func TestReplayGossipSpeed(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gossip.jsonl")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var lines []string
+	for i := 0; i < 3; i++ {
+		b, err := json.Marshal(gossipRecord{Time: start.Add(time.Duration(i) * time.Second), State: "unknown", Data: []byte("[]")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(b))
+	}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestStatesManager(t)
+
+	// The 2 seconds of records are replayed in 100ms.
+	begin := time.Now()
+	if err := m.replayGossip(context.Background(), file, 20); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(begin); d < 100*time.Millisecond || d > time.Second {
+		t.Errorf("replay took %v, want about 100ms", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.replayGossip(ctx, file, 1); err != context.Canceled {
+		t.Errorf("replay = %v, want cancelled", err)
+	}
+}
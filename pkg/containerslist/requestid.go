@@ -0,0 +1,63 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-kit/log"
+)
+
+// requestIDHeader identifies a request across the nodes it goes through, for
+// correlation in the logs.
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// withRequestID propagates the request ID of the incoming requests, or
+// generates one, and returns it in the responses. IDs longer than 128
+// characters or with non-printable characters are replaced.
+func withRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(requestIDHeader, id)
+		}
+		w.Header().Set(requestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// requestID returns the ID of the request being served.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger returns a logger adding the ID of the request being served.
+func (m *Manager) requestLogger(ctx context.Context) log.Logger {
+	if id := requestID(ctx); id != "" {
+		return log.With(m.logger, "request_id", id)
+	}
+	return m.logger
+}
@@ -0,0 +1,81 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var seen string
+	h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestID(r.Context())
+		writeProblem(w, r, problemf(ErrNotFound, "not here"))
+	}))
+
+	for _, tc := range []struct {
+		name, header string
+		keep         bool
+	}{
+		{name: "propagated", header: "req-42", keep: true},
+		{name: "generated"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+		{name: "non-printable", header: "req 42"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil)
+			if tc.header != "" {
+				r.Header.Set(requestIDHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tc.keep && got != tc.header || !tc.keep && (len(got) != 32 || got == tc.header) {
+				t.Errorf("request ID = %q, want %q kept %v", got, tc.header, tc.keep)
+			}
+			if seen != got {
+				t.Errorf("request ID of the handler = %q, want %q", seen, got)
+			}
+			var p Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.RequestID != got {
+				t.Errorf("request ID of the problem = %q, want %q", p.RequestID, got)
+			}
+		})
+	}
+}
+
+func TestRequestIDForwarded(t *testing.T) {
+	var forwarded string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(requestIDHeader)
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	m := &Manager{logger: log.NewLogfmtLogger(&logs)}
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-42")
+	if _, err := m.queryPeer(ctx, srv.Client(), srv.Listener.Addr().String(), url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	if forwarded != "req-42" {
+		t.Errorf("forwarded request ID = %q, want req-42", forwarded)
+	}
+
+	m.requestLogger(ctx).Log("msg", "served")
+	m.requestLogger(context.Background()).Log("msg", "background")
+	if want := "request_id=req-42 msg=served\nmsg=background\n"; logs.String() != want {
+		t.Errorf("logs = %q, want %q", logs.String(), want)
+	}
+}
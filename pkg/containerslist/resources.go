@@ -0,0 +1,202 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	navTpl = `{{ define "nav" }}<p><a href="/">{{ t "containers" }}</a> | <a href="/ports">{{ t "ports" }}</a> | <a href="/networks">{{ t "networks" }}</a> | <a href="/volumes">{{ t "volumes" }}</a> | <a href="/images">{{ t "images" }}</a> | <a href="/projects">{{ t "projects" }}</a> | <a href="/nodes">{{ t "nodes" }}</a> | <a href="/search">{{ t "search" }}</a> | <a href="/violations">{{ t "violations" }}</a> | <a href="/maintenance">{{ t "maintenance" }}</a> | <a href="/top">{{ t "top" }}</a></p>{{ end }}`
+
+	networksTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - networks</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <table>
+      <tr><th>Node</th><th>Name</th><th>Driver</th><th>Scope</th></tr>
+    {{ range . }}{{ $node := .Node }}{{ range .Items }}
+      <tr><td>{{ $node }}</td><td>{{ .Name }}</td><td>{{ .Driver }}</td><td>{{ .Scope }}</td></tr>
+    {{ end }}{{ end }}
+    </table>
+  </body>
+</html>
+`
+
+	volumesTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - volumes</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <table>
+      <tr><th>Node</th><th>Name</th><th>Driver</th><th>Mountpoint</th></tr>
+    {{ range . }}{{ $node := .Node }}{{ range .Items }}
+      <tr><td>{{ $node }}</td><td>{{ .Name }}</td><td>{{ .Driver }}</td><td><code>{{ .Mountpoint }}</code></td></tr>
+    {{ end }}{{ end }}
+    </table>
+  </body>
+</html>
+`
+)
+
+// Network is a container network defined on a node.
+type Network struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Driver   string `json:"driver"`
+	Scope    string `json:"scope"`
+	Internal bool   `json:"internal"`
+}
+
+// Volume is a container volume defined on a node.
+type Volume struct {
+	Name       string `json:"name"`
+	Driver     string `json:"driver"`
+	Mountpoint string `json:"mountpoint"`
+	Scope      string `json:"scope"`
+}
+
+// NodeResources is a list of resources of a single node, as gossiped to the
+// other peers.
+type NodeResources[T any] struct {
+	Node      string    `json:"node"`
+	Timestamp time.Time `json:"timestamp"`
+	Items     []T       `json:"items"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+func (n *NodeResources[T]) node() string              { return n.Node }
+func (n *NodeResources[T]) timestamp() time.Time      { return n.Timestamp }
+func (n *NodeResources[T]) signature() *Signature     { return n.Signature }
+func (n *NodeResources[T]) setSignature(s *Signature) { n.Signature = s }
+
+// resourceState is the cluster-wide state of a kind of node resources.
+type resourceState[T any] struct {
+	*nodeState[*NodeResources[T]]
+}
+
+func newResourceState[T any]() *resourceState[T] {
+	return &resourceState[T]{newNodeState[*NodeResources[T]]()}
+}
+
+// nodeEntry is a piece of state owned by a single node.
+type nodeEntry interface {
+	signedEntry
+	timestamp() time.Time
+}
+
+// nodeState is a cluster-wide state made of one entry per node. It implements
+// cluster.State: like the inventory, each node owns its own entry and the
+// most recent version of an entry wins on merge.
+type nodeState[E nodeEntry] struct {
+	mtx       sync.RWMutex
+	nodes     map[string]E
+	signer    *gossipSigner
+	blocklist *blocklist
+}
+
+func newNodeState[E nodeEntry]() *nodeState[E] {
+	return &nodeState[E]{
+		nodes: map[string]E{},
+	}
+}
+
+// MarshalBinary implements cluster.State.
+func (s *nodeState[E]) MarshalBinary() ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	nodes := make([]E, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		nodes = append(nodes, n)
+	}
+	return json.Marshal(nodes)
+}
+
+// Merge implements cluster.State.
+func (s *nodeState[E]) Merge(b []byte) error {
+	var nodes []E
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, n := range nodes {
+		if !s.blocklist.Drop(n.node()) && s.signer.Verify(n) {
+			s.merge(n)
+		}
+	}
+	return nil
+}
+
+func (s *nodeState[E]) merge(n E) {
+	if prev, ok := s.nodes[n.node()]; ok && !n.timestamp().After(prev.timestamp()) {
+		return
+	}
+	s.nodes[n.node()] = n
+}
+
+// Set replaces the entry of a node, returning the serialized update to
+// broadcast.
+func (s *nodeState[E]) Set(n E) ([]byte, error) {
+	if err := s.signer.Sign(n); err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	s.merge(n)
+	s.mtx.Unlock()
+
+	return json.Marshal([]E{n})
+}
+
+// Get returns the entry of a node.
+func (s *nodeState[E]) Get(node string) (E, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	n, ok := s.nodes[node]
+	return n, ok
+}
+
+// Nodes returns the entries of all nodes, sorted by node name.
+func (s *nodeState[E]) Nodes() []E {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	nodes := make([]E, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(a, b int) bool {
+		return nodes[a].node() < nodes[b].node()
+	})
+	return nodes
+}
+
+func apiResources[T any](s *resourceState[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.Nodes())
+	})
+}
+
+func resourcesPage[T any](t *template.Template, name string, s *resourceState[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := localize(t, r).ExecuteTemplate(w, name, s.Nodes()); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package containerslist
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListNetworksAndVolumes(t *testing.T) {
+	c := newTestDockerClient(t, map[string]string{
+		"/networks": `[{"Id": "n1", "Name": "bridge", "Driver": "bridge", "Scope": "local"}, {"Id": "n2", "Name": "backend", "Driver": "overlay", "Scope": "swarm", "Internal": true}]`,
+		"/volumes":  `{"Volumes": [{"Name": "data", "Driver": "local", "Mountpoint": "/var/lib/docker/volumes/data/_data", "Scope": "local"}]}`,
+	})
+	networks, err := c.ListNetworks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNetworks := []Network{
+		{ID: "n1", Name: "bridge", Driver: "bridge", Scope: "local"},
+		{ID: "n2", Name: "backend", Driver: "overlay", Scope: "swarm", Internal: true},
+	}
+	if !reflect.DeepEqual(networks, wantNetworks) {
+		t.Errorf("networks = %+v, want %+v", networks, wantNetworks)
+	}
+	volumes, err := c.ListVolumes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVolumes := []Volume{{Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data/_data", Scope: "local"}}
+	if !reflect.DeepEqual(volumes, wantVolumes) {
+		t.Errorf("volumes = %+v, want %+v", volumes, wantVolumes)
+	}
+}
+
+func TestResourceStateMerge(t *testing.T) {
+	now := time.Now().UTC()
+	local := newResourceState[Volume]()
+	if _, err := local.Set(&NodeResources[Volume]{Node: "b", Timestamp: now, Items: []Volume{{Name: "new"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := newResourceState[Volume]()
+	for _, n := range []*NodeResources[Volume]{
+		{Node: "a", Timestamp: now, Items: []Volume{{Name: "data"}}},
+		{Node: "b", Timestamp: now.Add(-time.Minute), Items: []Volume{{Name: "old"}}},
+	} {
+		if _, err := remote.Set(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := remote.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// The older entry of b does not replace the local one.
+	var got []string
+	for _, n := range local.Nodes() {
+		got = append(got, n.Node+"="+n.Items[0].Name)
+	}
+	if want := []string{"a=data", "b=new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes = %v, want %v", got, want)
+	}
+}
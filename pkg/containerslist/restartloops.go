@@ -0,0 +1,97 @@
+package containerslist
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// RestartLoopGroup aggregates the containers flagged as restart_loop across
+// the cluster which run the same image, or have the same name.
+type RestartLoopGroup struct {
+	// Key is the image or the container name.
+	Key string `json:"key"`
+	// Nodes are the nodes where containers of the group are looping.
+	Nodes []string `json:"nodes"`
+	// Looping is the number of Nodes, out of the Running nodes running
+	// containers of the group, e.g. looping on 14 of 20 nodes.
+	Looping    int `json:"looping"`
+	Running    int `json:"running"`
+	Containers int `json:"containers"`
+}
+
+// restartLoopKey returns the function grouping the containers by image or by
+// name.
+func restartLoopKey(by string) (func(Container) string, error) {
+	switch by {
+	case "", "image":
+		return func(c Container) string { return c.Image }, nil
+	case "name":
+		return func(c Container) string { return c.Name }, nil
+	}
+	return nil, fmt.Errorf("invalid by %q: must be one of image, name", by)
+}
+
+// restartLoops groups the containers flagged as restart_loop, comparing the
+// number of nodes where they loop with the number of nodes running them.
+// The groups looping on the most nodes come first.
+func restartLoops(nodes []*NodeInventory, key func(Container) string) []RestartLoopGroup {
+	type group struct {
+		looping    map[string]struct{}
+		running    map[string]struct{}
+		containers int
+	}
+	groups := map[string]*group{}
+	get := func(k string) *group {
+		g, ok := groups[k]
+		if !ok {
+			g = &group{looping: map[string]struct{}{}, running: map[string]struct{}{}}
+			groups[k] = g
+		}
+		return g
+	}
+	for _, n := range nodes {
+		for _, c := range n.Containers {
+			k := key(c)
+			if c.State == StateRunning || c.hasFlag(FlagRestartLoop) {
+				get(k).running[n.Node] = struct{}{}
+			}
+			if c.hasFlag(FlagRestartLoop) {
+				g := get(k)
+				g.looping[n.Node] = struct{}{}
+				g.containers++
+			}
+		}
+	}
+
+	res := []RestartLoopGroup{}
+	for k, g := range groups {
+		if len(g.looping) == 0 {
+			continue
+		}
+		rg := RestartLoopGroup{Key: k, Looping: len(g.looping), Running: len(g.running), Containers: g.containers}
+		for node := range g.looping {
+			rg.Nodes = append(rg.Nodes, node)
+		}
+		sort.Strings(rg.Nodes)
+		res = append(res, rg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Looping != res[j].Looping {
+			return res[i].Looping > res[j].Looping
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+func (m *Manager) apiRestartLoops() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := restartLoopKey(r.URL.Query().Get("by"))
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		writeJSON(w, restartLoops(m.inventory.Nodes(), key))
+	})
+}
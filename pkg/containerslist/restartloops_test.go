@@ -0,0 +1,79 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRestartLoops(t *testing.T) {
+	looping := []string{FlagRestartLoop}
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{
+			{Name: "api-1", Image: "shop/api:1.0", State: "restarting", Flags: looping},
+			{Name: "api-2", Image: "shop/api:1.0", State: StateRunning, Flags: looping},
+			{Name: "web", Image: "nginx", State: StateRunning, Flags: looping},
+		}},
+		&NodeInventory{Node: "b", Containers: []Container{
+			{Name: "api-1", Image: "shop/api:1.0", State: StateRunning, Flags: looping},
+			{Name: "web", Image: "nginx", State: StateRunning},
+		}},
+		&NodeInventory{Node: "c", Containers: []Container{
+			{Name: "api-1", Image: "shop/api:1.0", State: StateRunning},
+			{Name: "api-old", Image: "shop/api:1.0", State: "exited"},
+			{Name: "db", Image: "postgres", State: StateRunning},
+		}},
+	)
+
+	for _, tc := range []struct {
+		by   string
+		want []RestartLoopGroup
+	}{
+		{by: "", want: []RestartLoopGroup{
+			{Key: "shop/api:1.0", Nodes: []string{"a", "b"}, Looping: 2, Running: 3, Containers: 3},
+			{Key: "nginx", Nodes: []string{"a"}, Looping: 1, Running: 2, Containers: 1},
+		}},
+		{by: "name", want: []RestartLoopGroup{
+			{Key: "api-1", Nodes: []string{"a", "b"}, Looping: 2, Running: 3, Containers: 2},
+			{Key: "api-2", Nodes: []string{"a"}, Looping: 1, Running: 1, Containers: 1},
+			{Key: "web", Nodes: []string{"a"}, Looping: 1, Running: 2, Containers: 1},
+		}},
+	} {
+		rec := httptest.NewRecorder()
+		m.apiRestartLoops().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/restart-loops?by="+tc.by, nil))
+		var got []RestartLoopGroup
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("restart loops by %q = %+v, want %+v", tc.by, got, tc.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiRestartLoops().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/restart-loops?by=node", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status of an invalid by = %d, want 400", rec.Code)
+	}
+
+	labels, err := newNodeMetricLabels("", 10, func() []*NodeFacts { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(newInventoryCollector(m.inventory, labels))
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP containerslist_restart_loop_nodes Number of nodes where the containers of an image are flagged as restart loops.
+# TYPE containerslist_restart_loop_nodes gauge
+containerslist_restart_loop_nodes{image="nginx"} 1
+containerslist_restart_loop_nodes{image="shop/api:1.0"} 2
+`), "containerslist_restart_loop_nodes"); err != nil {
+		t.Error(err)
+	}
+}
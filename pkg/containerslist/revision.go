@@ -0,0 +1,22 @@
+package containerslist
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// clusterRevisionHeader is the header of the API responses holding the
+// revision of the cluster. Along with the revisions of the nodes in their
+// inventories, it lets clients sync incrementally and detect the updates
+// they missed.
+const clusterRevisionHeader = "X-Cluster-Revision"
+
+// withRevision sets the revision of the cluster in the responses of h. It is
+// read before serving the request, so that a change in between is not missed
+// by the next request.
+func (m *Manager) withRevision(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(clusterRevisionHeader, strconv.FormatUint(m.inventory.Revision(), 10))
+		h.ServeHTTP(w, r)
+	})
+}
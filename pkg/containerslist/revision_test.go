@@ -0,0 +1,70 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInventoryRevision(t *testing.T) {
+	i := newInventory()
+	start := uint64(time.Now().UnixMilli())
+	now := time.Now()
+	set := func(n *NodeInventory) *NodeInventory {
+		t.Helper()
+		if _, err := i.Set(n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	a := set(&NodeInventory{Node: "a", Timestamp: now, Containers: []Container{{ID: "a1", State: "running"}}})
+	if a.Revision < start || i.Revision() != a.Revision {
+		t.Errorf("revision = %d, cluster revision = %d, want the time in milliseconds", a.Revision, i.Revision())
+	}
+	// An unchanged inventory keeps its revision.
+	if again := set(&NodeInventory{Node: "a", Timestamp: now.Add(time.Second), Containers: []Container{{ID: "a1", State: "running"}}}); again.Revision != a.Revision {
+		t.Errorf("revision of an unchanged inventory = %d, want %d", again.Revision, a.Revision)
+	}
+
+	// A later revision merged from another member is taken over, and the
+	// next change of a local node goes past it.
+	remote := a.Revision + uint64(time.Hour.Milliseconds())
+	b, err := json.Marshal([]*NodeInventory{{Node: "b", Timestamp: now, Revision: remote}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if i.Revision() != remote {
+		t.Errorf("cluster revision = %d, want %d", i.Revision(), remote)
+	}
+	if a := set(&NodeInventory{Node: "a", Timestamp: now.Add(2 * time.Second)}); a.Revision != remote+1 || i.Revision() != remote+1 {
+		t.Errorf("revision = %d, cluster revision = %d, want %d", a.Revision, i.Revision(), remote+1)
+	}
+
+	// The cluster revision never decreases.
+	b, err = json.Marshal([]*NodeInventory{{Node: "c", Timestamp: now, Revision: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if i.Revision() != remote+1 {
+		t.Errorf("cluster revision = %d, want %d", i.Revision(), remote+1)
+	}
+}
+
+func TestWithRevision(t *testing.T) {
+	m := newTestInventoryManager(t, &NodeInventory{Node: "a"})
+	rec := httptest.NewRecorder()
+	m.withRevision(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers", nil))
+	if got, want := rec.Header().Get(clusterRevisionHeader), strconv.FormatUint(m.inventory.Revision(), 10); got != want || got == "0" {
+		t.Errorf("revision = %q, want %s", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// forwardedHeader is set on the requests forwarded to a peer, holding the
+// node which forwarded them. Forwarded requests are never forwarded again,
+// preventing loops.
+const forwardedHeader = "X-Containerslist-Forwarded-By"
+
+// forwardedCallerHeader is set on the forwarded requests, holding their
+// caller as authorized by the forwarding node: the API keys are local to each
+// node, and unknown to the owner.
+const forwardedCallerHeader = "X-Containerslist-Forwarded-Caller"
+
+// peerRequestKey marks the context of the requests served by the internal
+// API, whose clients are the peers, authenticated by their certificates.
+type peerRequestKey struct{}
+
+// fromPeers marks the requests served by the internal API.
+func fromPeers(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerRequestKey{}, true)))
+	})
+}
+
+// forwardedCaller returns the caller of a request forwarded by a peer over the
+// internal API, which authorized it. It is ignored on the other servers.
+func forwardedCaller(r *http.Request) (string, bool) {
+	if peer, _ := r.Context().Value(peerRequestKey{}).(bool); !peer || r.Header.Get(forwardedHeader) == "" {
+		return "", false
+	}
+	caller := r.Header.Get(forwardedCallerHeader)
+	return caller, caller != ""
+}
+
+// router forwards the API requests about a node to the peer owning it, over
+// the mutual TLS internal API.
+type router struct {
+	transport http.RoundTripper
+	latency   *prometheus.HistogramVec
+	loops     prometheus.Counter
+
+	// config is the TLS configuration of transport, from which the
+	// transports verifying the identity of the owners are derived.
+	config     *tls.Config
+	mtx        sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+func newRouter(m *Manager) *router {
+	r := &router{
+		latency: prometheus.NewHistogramVec(latencyHistogramOpts(prometheus.HistogramOpts{
+			Name: "containerslist_forwarded_request_duration_seconds",
+			Help: "Duration of the API requests forwarded to the peer owning a node.",
+		}), []string{"code"}),
+		loops: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "containerslist_forwarded_request_loops_total",
+			Help: "Number of forwarded API requests received for a node not owned by the local node.",
+		}),
+	}
+	if m.internalTLS != nil {
+		r.config = m.internalTLS
+		r.transport = &http.Transport{TLSClientConfig: m.internalTLS}
+		r.transports = map[string]http.RoundTripper{}
+	}
+	m.registry.MustRegister(r.latency, r.loops)
+	return r
+}
+
+// internalAdvertiseAddress returns the address of the internal API advertised
+// to the peers: the configured one, or the internal listen port on the host
+// advertised to the cluster.
+func (m *Manager) internalAdvertiseAddress() string {
+	if m.cfg.InternalAdvertiseAddress != "" || m.cfg.InternalListenAddress == "" {
+		return m.cfg.InternalAdvertiseAddress
+	}
+	host, port, err := net.SplitHostPort(m.cfg.InternalListenAddress)
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host, _, _ = net.SplitHostPort(m.peer.Self().Address())
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// transportFor returns the transport to the internal API of the peer having
+// an identity, which refuses the peers presenting another certificate: the
+// forwarded requests bear the credentials of the callers, which must not be
+// sent to a peer merely advertising the address of another one.
+func (r *router) transportFor(identity string) http.RoundTripper {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if t, ok := r.transports[identity]; ok {
+		return t
+	}
+	config := r.config.Clone()
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no certificate presented by the internal API")
+		}
+		if got := certIdentity(cs.PeerCertificates[0]); got != identity {
+			return fmt.Errorf("internal API certificate of %q presented instead of %q", got, identity)
+		}
+		return nil
+	}
+	t := &http.Transport{TLSClientConfig: config}
+	r.transports[identity] = t
+	return t
+}
+
+// internalIdentity returns the identity of the certificate of the internal
+// API, advertised to the peers.
+func (m *Manager) internalIdentity() string {
+	if m.internalCerts == nil {
+		return ""
+	}
+	cert, _ := m.internalCerts.current()
+	if cert == nil {
+		return ""
+	}
+	return certIdentity(cert.Leaf)
+}
+
+// owner returns the internal API address and identity of the peer owning a
+// node, as gossiped in its facts.
+func (m *Manager) owner(node string) (addr, identity string, ok bool) {
+	f, ok := m.facts.Get(node)
+	if !ok || f.InternalAddress == "" {
+		return "", "", false
+	}
+	return f.InternalAddress, f.InternalIdentity, true
+}
+
+// forward forwards a request about a node to the peer owning it, once its
+// certificate is verified to be the one of the owner. It answers with a 421
+// Misdirected Request when the owner or its identity is unknown, when the
+// internal API is disabled, or when the request was already forwarded.
+func (m *Manager) forward(w http.ResponseWriter, r *http.Request, node string) {
+	if by := r.Header.Get(forwardedHeader); by != "" {
+		m.router.loops.Inc()
+		level.Warn(m.requestLogger(r.Context())).Log("msg", "Refusing to forward a forwarded request", "node", node, "forwarded_by", by)
+		writeProblem(w, r, problemf(ErrMisdirected, "request for node %s forwarded by %s to a node not owning it", node, by))
+		return
+	}
+	addr, identity, ok := m.owner(node)
+	if !ok || m.router.transport == nil {
+		writeProblem(w, r, problemf(ErrMisdirected, "request must be sent to node %s", node))
+		return
+	}
+	if identity == "" {
+		writeProblem(w, r, problemf(ErrMisdirected, "request must be sent to node %s, whose owner does not advertise its internal API identity", node))
+		return
+	}
+
+	target := &url.URL{Scheme: "https", Host: addr}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = m.router.transportFor(identity)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		level.Warn(m.requestLogger(r.Context())).Log("msg", "Unable to forward request", "node", node, "peer", addr, "error", err)
+		writeProblem(w, r, problemf(ErrPeerUnreachable, "unable to forward request to node %s: %v", node, err))
+	}
+	r.Header.Set(forwardedHeader, m.nodeID)
+	r.Header.Set(forwardedCallerHeader, m.adminCaller(r))
+
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	proxy.ServeHTTP(rec, r)
+	observeRequest(m.router.latency.WithLabelValues(strconv.Itoa(rec.status)), r, time.Since(start))
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher, for the event streams.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for
+// http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
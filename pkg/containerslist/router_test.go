@@ -0,0 +1,155 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testCA issues the certificates of the internal API of test peers.
+type testCA struct {
+	t    *testing.T
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{t: t, cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate of the loopback address, with a common name.
+func (ca *testCA) issue(commonName string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestRouterTransportFor(t *testing.T) {
+	ca := newTestCA(t)
+	source := func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		return ca.issue("self"), ca.pool, nil
+	}
+	certs := newRotatingCerts(source, 0, log.NewNopLogger(), prometheus.NewRegistry())
+	if err := certs.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := &router{config: certs.config(), transports: map[string]http.RoundTripper{}}
+
+	// peer-b advertises the address of peer-a: the credentials must not
+	// reach it.
+	var authorizations []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorizations = append(authorizations, req.Header.Get("Authorization"))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{*ca.issue("peer-b")},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	for _, tc := range []struct {
+		identity string
+		wantErr  bool
+	}{
+		{identity: "peer-a", wantErr: true},
+		{identity: "peer-b"},
+	} {
+		t.Run(tc.identity, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://"+u.Host+"/api/v1/admin/prune", nil)
+			req.RequestURI = ""
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err := r.transportFor(tc.identity).RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+	if len(authorizations) != 1 {
+		t.Errorf("server received %d requests, want 1", len(authorizations))
+	}
+	if r.transportFor("peer-b") != r.transportFor("peer-b") {
+		t.Error("transport of an identity not reused")
+	}
+}
+
+func TestCertIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/containerslist/node-1")
+	for _, tc := range []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{name: "SPIFFE ID", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "node-1"}, URIs: []*url.URL{spiffe}}, want: spiffe.String()},
+		{name: "common name", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "node-1"}, DNSNames: []string{"node-1.example.org"}}, want: "node-1"},
+		{name: "DNS name", cert: &x509.Certificate{DNSNames: []string{"node-1.example.org"}}, want: "node-1.example.org"},
+		{name: "none", cert: &x509.Certificate{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := certIdentity(tc.cert); got != tc.want {
+				t.Errorf("certIdentity = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// Formats of the SBOMs.
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+// sbomPredicateTypes are the in-toto predicate types of the SBOM
+// attestations, by format.
+var sbomPredicateTypes = map[string]string{
+	"https://spdx.dev/Document":  SBOMFormatSPDX,
+	"https://cyclonedx.org/bom":  SBOMFormatCycloneDX,
+	"https://cyclonedx.org/spec": SBOMFormatCycloneDX,
+}
+
+// sbomArtifactTypes are the artifact types of the SBOMs attached with the
+// OCI referrers API, by format.
+var sbomArtifactTypes = map[string]string{
+	"application/spdx+json":          SBOMFormatSPDX,
+	"application/vnd.cyclonedx+json": SBOMFormatCycloneDX,
+}
+
+// errNoSBOM is returned when no SBOM is attached to an image.
+var errNoSBOM = errors.New("no SBOM attached to the image")
+
+// SBOMRef is the reference of the SBOM of a running image, as attached to
+// the image in its registry.
+type SBOMRef struct {
+	Image   string `json:"image"`
+	ImageID string `json:"imageID"`
+	// Format is the format of the SBOM, spdx or cyclonedx, and Digest the
+	// digest of the blob holding it.
+	Format    string    `json:"format,omitempty"`
+	Digest    string    `json:"digest,omitempty"`
+	Packages  int       `json:"packages"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Package is a software package listed in an SBOM.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// sbom is an SBOM fetched from a registry.
+type sbom struct {
+	ref      SBOMRef
+	packages []Package
+}
+
+// sbomStore holds the SBOMs of the images running in the cluster, by image
+// ID.
+type sbomStore struct {
+	registry *registryClient
+
+	mtx   sync.RWMutex
+	sboms map[string]*sbom
+}
+
+func newSBOMStore(registry *registryClient) *sbomStore {
+	return &sbomStore{
+		registry: registry,
+		sboms:    map[string]*sbom{},
+	}
+}
+
+// runningImages returns the image of each image ID running in the cluster.
+func (m *Manager) runningImages() map[string]string {
+	images := map[string]string{}
+	for _, n := range m.inventory.Nodes() {
+		for _, c := range n.Containers {
+			if c.State == StateRunning && c.ImageID != "" {
+				images[c.ImageID] = c.Image
+			}
+		}
+	}
+	return images
+}
+
+// sbomJob fetches the SBOMs of the images newly running in the cluster, and
+// forgets the ones of the images which no longer run. Failed fetches are
+// retried after an hour.
+func (m *Manager) sbomJob(ctx context.Context) error {
+	images := m.runningImages()
+
+	s := m.sboms
+	s.mtx.Lock()
+	for id := range s.sboms {
+		if _, ok := images[id]; !ok {
+			delete(s.sboms, id)
+		}
+	}
+	todo := map[string]string{}
+	for id, image := range images {
+		if e, ok := s.sboms[id]; !ok || (e.ref.Error != "" && time.Since(e.ref.FetchedAt) > time.Hour) {
+			todo[id] = image
+		}
+	}
+	s.mtx.Unlock()
+
+	var fetched, failed int
+	for id, image := range todo {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		e := &sbom{ref: SBOMRef{Image: image, ImageID: id, FetchedAt: time.Now().UTC()}}
+		ref, packages, err := s.fetch(ctx, image, id)
+		if err != nil {
+			e.ref.Error = err.Error()
+			if !errors.Is(err, errNoSBOM) {
+				failed++
+				level.Debug(m.logger).Log("msg", "Unable to fetch SBOM", "image", image, "error", err)
+			}
+		} else {
+			e.ref.Format, e.ref.Digest = ref.Format, ref.Digest
+			e.ref.Packages = len(packages)
+			e.packages = packages
+			fetched++
+		}
+		s.mtx.Lock()
+		s.sboms[id] = e
+		s.mtx.Unlock()
+	}
+	level.Debug(m.logger).Log("msg", "SBOMs fetched", "images", len(todo), "fetched", fetched, "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("unable to fetch the SBOM of %d of %d images", failed, len(todo))
+	}
+	return nil
+}
+
+// fetch fetches the SBOM of an image, from the attestations built by
+// BuildKit or else from the artifacts attached with the referrers API.
+func (s *sbomStore) fetch(ctx context.Context, image, imageID string) (SBOMRef, []Package, error) {
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return SBOMRef{}, nil, err
+	}
+	root, rootDigest, err := s.registry.Manifest(ctx, ref, ref.Reference)
+	if err != nil {
+		return SBOMRef{}, nil, err
+	}
+
+	// Find the manifest of the running platform, whose config is the image.
+	digest := rootDigest
+	for _, d := range root.Manifests {
+		if d.Annotations["vnd.docker.reference.type"] != "" {
+			continue
+		}
+		m, _, err := s.registry.Manifest(ctx, ref, d.Digest)
+		if err != nil {
+			return SBOMRef{}, nil, err
+		}
+		if m.Config.Digest == imageID {
+			digest = d.Digest
+			break
+		}
+	}
+
+	for _, d := range root.Manifests {
+		if d.Annotations["vnd.docker.reference.type"] != "attestation-manifest" || d.Annotations["vnd.docker.reference.digest"] != digest {
+			continue
+		}
+		m, _, err := s.registry.Manifest(ctx, ref, d.Digest)
+		if err != nil {
+			return SBOMRef{}, nil, err
+		}
+		for _, l := range m.Layers {
+			format, ok := sbomPredicateTypes[l.Annotations["in-toto.io/predicate-type"]]
+			if !ok {
+				continue
+			}
+			b, err := s.registry.Blob(ctx, ref, l.Digest)
+			if err != nil {
+				return SBOMRef{}, nil, err
+			}
+			var statement struct {
+				Predicate json.RawMessage `json:"predicate"`
+			}
+			if err := json.Unmarshal(b, &statement); err != nil {
+				return SBOMRef{}, nil, fmt.Errorf("invalid attestation %s: %w", l.Digest, err)
+			}
+			packages, err := parseSBOM(format, statement.Predicate)
+			return SBOMRef{Format: format, Digest: l.Digest}, packages, err
+		}
+	}
+
+	referrers, err := s.registry.Referrers(ctx, ref, digest)
+	if err != nil {
+		return SBOMRef{}, nil, err
+	}
+	for _, d := range referrers {
+		format := sbomArtifactTypes[d.ArtifactType]
+		if format == "" {
+			continue
+		}
+		m, _, err := s.registry.Manifest(ctx, ref, d.Digest)
+		if err != nil {
+			return SBOMRef{}, nil, err
+		}
+		if len(m.Layers) == 0 {
+			continue
+		}
+		b, err := s.registry.Blob(ctx, ref, m.Layers[0].Digest)
+		if err != nil {
+			return SBOMRef{}, nil, err
+		}
+		packages, err := parseSBOM(format, b)
+		return SBOMRef{Format: format, Digest: m.Layers[0].Digest}, packages, err
+	}
+	return SBOMRef{}, nil, errNoSBOM
+}
+
+// parseSBOM returns the packages listed in an SPDX or CycloneDX JSON
+// document.
+func parseSBOM(format string, b []byte) ([]Package, error) {
+	var packages []Package
+	switch format {
+	case SBOMFormatSPDX:
+		var doc struct {
+			Packages []struct {
+				Name        string `json:"name"`
+				VersionInfo string `json:"versionInfo"`
+			} `json:"packages"`
+		}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("invalid SPDX document: %w", err)
+		}
+		for _, p := range doc.Packages {
+			packages = append(packages, Package{Name: p.Name, Version: p.VersionInfo})
+		}
+	case SBOMFormatCycloneDX:
+		var doc struct {
+			Components []struct {
+				Group   string `json:"group"`
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX document: %w", err)
+		}
+		for _, c := range doc.Components {
+			name := c.Name
+			if c.Group != "" {
+				name = c.Group + "/" + c.Name
+			}
+			packages = append(packages, Package{Name: name, Version: c.Version})
+		}
+	}
+	return packages, nil
+}
+
+// Refs returns the SBOM references of the running images, sorted by image.
+func (s *sbomStore) Refs() []SBOMRef {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	refs := make([]SBOMRef, 0, len(s.sboms))
+	for _, e := range s.sboms {
+		refs = append(refs, e.ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Image < refs[j].Image || refs[i].Image == refs[j].Image && refs[i].ImageID < refs[j].ImageID
+	})
+	return refs
+}
+
+// PackageMatch is a package found in the SBOM of a running image, along with
+// the containers running the image on each node.
+type PackageMatch struct {
+	Package
+	Image   string              `json:"image"`
+	ImageID string              `json:"imageID"`
+	Nodes   map[string][]string `json:"nodes"`
+}
+
+// searchPackages returns the packages of the running images whose name
+// contains name and whose version starts with version, case-insensitively.
+func (m *Manager) searchPackages(name, version string) []PackageMatch {
+	name, version = strings.ToLower(name), strings.ToLower(version)
+
+	matches := map[string][]PackageMatch{}
+	m.sboms.mtx.RLock()
+	for id, e := range m.sboms.sboms {
+		for _, p := range e.packages {
+			if strings.Contains(strings.ToLower(p.Name), name) && strings.HasPrefix(strings.ToLower(p.Version), version) {
+				matches[id] = append(matches[id], PackageMatch{Package: p, Image: e.ref.Image, ImageID: id, Nodes: map[string][]string{}})
+			}
+		}
+	}
+	m.sboms.mtx.RUnlock()
+
+	res := []PackageMatch{}
+	for _, n := range m.inventory.Nodes() {
+		for _, c := range n.Containers {
+			if c.State != StateRunning {
+				continue
+			}
+			for _, p := range matches[c.ImageID] {
+				p.Nodes[n.Node] = append(p.Nodes[n.Node], c.Name)
+			}
+		}
+	}
+	for _, ps := range matches {
+		res = append(res, ps...)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		if res[i].Version != res[j].Version {
+			return res[i].Version < res[j].Version
+		}
+		return res[i].Image < res[j].Image
+	})
+	return res
+}
+
+func (m *Manager) apiSBOMs() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.sboms.Refs())
+	})
+}
+
+func (m *Manager) apiPackages() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "missing package name"))
+			return
+		}
+		writeJSON(w, m.searchPackages(name, r.URL.Query().Get("version")))
+	})
+}
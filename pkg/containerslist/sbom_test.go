@@ -0,0 +1,151 @@
+package containerslist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestParseImageRef(t *testing.T) {
+	for _, tc := range []struct {
+		image string
+		want  imageRef
+	}{
+		{image: "nginx", want: imageRef{Registry: defaultRegistry, Repository: "library/nginx", Reference: "latest"}},
+		{image: "grafana/grafana:10.4.0", want: imageRef{Registry: defaultRegistry, Repository: "grafana/grafana", Reference: "10.4.0"}},
+		{image: "localhost:5000/api", want: imageRef{Registry: "localhost:5000", Repository: "api", Reference: "latest"}},
+		{image: "ghcr.io/org/app:1.0@sha256:abc", want: imageRef{Registry: "ghcr.io", Repository: "org/app", Reference: "sha256:abc"}},
+		{image: "localhost/app", want: imageRef{Registry: "localhost", Repository: "app", Reference: "latest"}},
+	} {
+		got, err := parseImageRef(tc.image)
+		if err != nil || got != tc.want {
+			t.Errorf("parseImageRef(%q) = %+v, %v, want %+v", tc.image, got, err, tc.want)
+		}
+	}
+	for _, image := range []string{"", "@sha256:abc", "ghcr.io/"} {
+		if _, err := parseImageRef(image); err == nil {
+			t.Errorf("parseImageRef(%q) succeeded, want an error", image)
+		}
+	}
+}
+
+// newTestRegistry returns a registry requiring an anonymous token, serving
+// the given paths, with the digests of the manifests referenced by tag.
+func newTestRegistry(t *testing.T, paths, digests map[string]string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("service") != "test" || !strings.HasSuffix(r.URL.Query().Get("scope"), ":pull") {
+				http.Error(w, "invalid scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token": "anonymous"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := paths[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if digest, ok := digests[r.URL.Path]; ok {
+			w.Header().Set("Docker-Content-Digest", digest)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSBOMJob(t *testing.T) {
+	srv := newTestRegistry(t, map[string]string{
+		// The api image has a BuildKit SPDX attestation.
+		"/v2/shop/api/manifests/1.0": `{"mediaType": "` + mediaTypeOCIIndex + `", "manifests": [
+			{"digest": "sha256:api-arm64"},
+			{"digest": "sha256:api-amd64"},
+			{"digest": "sha256:api-att", "annotations": {"vnd.docker.reference.type": "attestation-manifest", "vnd.docker.reference.digest": "sha256:api-amd64"}}
+		]}`,
+		"/v2/shop/api/manifests/sha256:api-arm64": `{"config": {"digest": "sha256:api-image-arm64"}}`,
+		"/v2/shop/api/manifests/sha256:api-amd64": `{"config": {"digest": "sha256:api-image"}}`,
+		"/v2/shop/api/manifests/sha256:api-att": `{"layers": [
+			{"digest": "sha256:api-provenance", "annotations": {"in-toto.io/predicate-type": "https://slsa.dev/provenance/v0.2"}},
+			{"digest": "sha256:api-spdx", "annotations": {"in-toto.io/predicate-type": "https://spdx.dev/Document"}}
+		]}`,
+		"/v2/shop/api/blobs/sha256:api-spdx": `{"predicate": {"packages": [{"name": "log4j-core", "versionInfo": "2.14.1"}, {"name": "openssl", "versionInfo": "3.0.2"}]}}`,
+
+		// The worker image has a CycloneDX SBOM attached as a referrer.
+		"/v2/shop/worker/manifests/1.0":               `{"config": {"digest": "sha256:worker-image"}}`,
+		"/v2/shop/worker/referrers/sha256:worker":     `{"manifests": [{"artifactType": "application/vnd.cyclonedx+json", "digest": "sha256:worker-bom"}]}`,
+		"/v2/shop/worker/manifests/sha256:worker-bom": `{"layers": [{"digest": "sha256:worker-cdx"}]}`,
+		"/v2/shop/worker/blobs/sha256:worker-cdx":     `{"components": [{"group": "org.apache.logging.log4j", "name": "log4j-api", "version": "2.14.1"}]}`,
+
+		// The bare image has no SBOM.
+		"/v2/shop/bare/manifests/1.0": `{"config": {"digest": "sha256:bare-image"}}`,
+	}, map[string]string{
+		"/v2/shop/worker/manifests/1.0": "sha256:worker",
+		"/v2/shop/bare/manifests/1.0":   "sha256:bare",
+	})
+	registry := strings.TrimPrefix(srv.URL, "https://")
+
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{
+			{Name: "api-1", Image: registry + "/shop/api:1.0", ImageID: "sha256:api-image", State: StateRunning},
+			{Name: "worker-1", Image: registry + "/shop/worker:1.0", ImageID: "sha256:worker-image", State: StateRunning},
+			{Name: "old", Image: registry + "/shop/old:1.0", ImageID: "sha256:old-image", State: "exited"},
+		}},
+		&NodeInventory{Node: "b", Containers: []Container{
+			{Name: "api-2", Image: registry + "/shop/api:1.0", ImageID: "sha256:api-image", State: StateRunning},
+			{Name: "bare", Image: registry + "/shop/bare:1.0", ImageID: "sha256:bare-image", State: StateRunning},
+		}},
+	)
+	m.logger = log.NewNopLogger()
+	m.sboms = newSBOMStore(newRegistryClient(newBreakers(5, time.Minute)))
+	m.sboms.registry.client = srv.Client()
+
+	if err := m.sbomJob(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, r := range m.sboms.Refs() {
+		refs = append(refs, strings.TrimPrefix(r.Image, registry+"/")+" "+r.Format+" "+r.Digest+" "+r.Error)
+	}
+	want := []string{
+		"shop/api:1.0 spdx sha256:api-spdx ",
+		"shop/bare:1.0   " + errNoSBOM.Error(),
+		"shop/worker:1.0 cyclonedx sha256:worker-cdx ",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("SBOMs = %q, want %q", refs, want)
+	}
+
+	got := m.searchPackages("LOG4J", "2.14")
+	if len(got) != 2 {
+		t.Fatalf("packages = %+v, want log4j-api and log4j-core", got)
+	}
+	if p := got[0]; p.Name != "log4j-core" || !reflect.DeepEqual(p.Nodes, map[string][]string{"a": {"api-1"}, "b": {"api-2"}}) {
+		t.Errorf("package = %+v, want log4j-core on api-1 and api-2", p)
+	}
+	if p := got[1]; p.Name != "org.apache.logging.log4j/log4j-api" || !reflect.DeepEqual(p.Nodes, map[string][]string{"a": {"worker-1"}}) {
+		t.Errorf("package = %+v, want log4j-api on worker-1", p)
+	}
+	if got := m.searchPackages("log4j", "2.17"); len(got) != 0 {
+		t.Errorf("packages = %+v, want none in 2.17", got)
+	}
+
+	rec := httptest.NewRecorder()
+	m.apiPackages().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/packages", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status without name = %d, want 400", rec.Code)
+	}
+}
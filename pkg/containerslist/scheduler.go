@@ -0,0 +1,218 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scheduleOff disables a scheduled job.
+const scheduleOff = "off"
+
+// jobSchedules are the schedules of the jobs overridden on the command line,
+// by job name.
+type jobSchedules map[string]string
+
+// String implements flag.Value.
+func (s jobSchedules) String() string {
+	specs := make([]string, 0, len(s))
+	for name, spec := range s {
+		specs = append(specs, name+"="+spec)
+	}
+	sort.Strings(specs)
+	return strings.Join(specs, ",")
+}
+
+// Set implements flag.Value, parsing a job=schedule override.
+func (s jobSchedules) Set(v string) error {
+	name, spec, ok := strings.Cut(v, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid schedule %q: must be job=schedule", v)
+	}
+	if _, ok := defaultSchedules[name]; !ok {
+		return fmt.Errorf("unknown job %q", name)
+	}
+	if spec != scheduleOff {
+		if _, err := parseSchedule(spec); err != nil {
+			return err
+		}
+	}
+	s[name] = spec
+	return nil
+}
+
+// JobStatus is the status of a scheduled job.
+type JobStatus struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Running  bool   `json:"running"`
+	// NextRun is unset when the job is disabled.
+	NextRun             *time.Time `json:"nextRun,omitempty"`
+	LastRun             *time.Time `json:"lastRun,omitempty"`
+	LastDurationSeconds float64    `json:"lastDurationSeconds"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	Runs                int        `json:"runs"`
+	Failures            int        `json:"failures"`
+	// Skipped counts the runs skipped because the previous one was still
+	// running.
+	Skipped int `json:"skipped"`
+}
+
+// job is a periodic task of the scheduler.
+type job struct {
+	run      func(context.Context) error
+	schedule schedule
+
+	mtx    sync.Mutex
+	status JobStatus
+}
+
+// scheduler runs periodic jobs on cron-like schedules, delaying each run by a
+// random jitter so that the peers of the cluster do not run them all at once.
+// A run is skipped while the previous one is still running.
+type scheduler struct {
+	logger log.Logger
+	jitter time.Duration
+	jobs   []*job
+
+	runs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	success  *prometheus.GaugeVec
+}
+
+func newScheduler(jitter time.Duration, logger log.Logger, reg prometheus.Registerer) *scheduler {
+	s := &scheduler{
+		logger: logger,
+		jitter: jitter,
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_job_runs_total",
+			Help: "Number of runs of the scheduled jobs, by result.",
+		}, []string{"job", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "containerslist_job_duration_seconds",
+			Help: "Duration of the runs of the scheduled jobs.",
+		}, []string{"job"}),
+		success: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "containerslist_job_last_success_timestamp_seconds",
+			Help: "Time of the last successful run of the scheduled jobs.",
+		}, []string{"job"}),
+	}
+	reg.MustRegister(s.runs, s.duration, s.success)
+	return s
+}
+
+// Add schedules a job, unless its schedule is "off".
+func (s *scheduler) Add(name, spec string, run func(context.Context) error) error {
+	j := &job{run: run, status: JobStatus{Name: name, Schedule: spec}}
+	if spec != scheduleOff {
+		var err error
+		if j.schedule, err = parseSchedule(spec); err != nil {
+			return fmt.Errorf("job %s: %w", name, err)
+		}
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Run runs the jobs on their schedule until the context is done.
+func (s *scheduler) Run(ctx context.Context) {
+	for _, j := range s.jobs {
+		if j.schedule != nil {
+			go s.loop(ctx, j)
+		}
+	}
+}
+
+func (s *scheduler) loop(ctx context.Context, j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		j.mtx.Lock()
+		j.status.NextRun = &next
+		j.mtx.Unlock()
+
+		delay := time.Until(next)
+		if s.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(s.jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		j.mtx.Lock()
+		running := j.status.Running
+		if running {
+			j.status.Skipped++
+		} else {
+			j.status.Running = true
+		}
+		j.mtx.Unlock()
+		if running {
+			s.runs.WithLabelValues(j.status.Name, "skipped").Inc()
+			level.Warn(s.logger).Log("msg", "Skipping job run, the previous one is still running", "job", j.status.Name)
+			continue
+		}
+		go s.execute(ctx, j)
+	}
+}
+
+func (s *scheduler) execute(ctx context.Context, j *job) {
+	start := time.Now()
+	err := j.run(ctx)
+	elapsed := time.Since(start)
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	name := j.status.Name
+	started := start.UTC()
+	j.status.Running = false
+	j.status.LastRun = &started
+	j.status.LastDurationSeconds = elapsed.Seconds()
+	j.status.Runs++
+	s.duration.WithLabelValues(name).Observe(elapsed.Seconds())
+	if err != nil {
+		j.status.LastError = err.Error()
+		j.status.Failures++
+		s.runs.WithLabelValues(name, "failure").Inc()
+		level.Warn(s.logger).Log("msg", "Job failed", "job", name, "error", err)
+		return
+	}
+	j.status.LastError = ""
+	j.status.LastSuccess = &started
+	s.runs.WithLabelValues(name, "success").Inc()
+	s.success.WithLabelValues(name).Set(float64(start.Unix()))
+}
+
+// Statuses returns the status of the jobs, sorted by name.
+func (s *scheduler) Statuses() []JobStatus {
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mtx.Lock()
+		statuses = append(statuses, j.status)
+		j.mtx.Unlock()
+	}
+	sort.Slice(statuses, func(a, b int) bool {
+		return statuses[a].Name < statuses[b].Name
+	})
+	return statuses
+}
+
+func (m *Manager) apiJobs() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.scheduler.Statuses())
+	})
+}
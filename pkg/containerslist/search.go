@@ -0,0 +1,161 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const searchTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - search</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <p><input id="q" placeholder="Search containers, images, IDs, labels and nodes" size="60" autofocus></p>
+    <div id="results"></div>
+    <script>
+      (function() {
+        var q = document.getElementById("q");
+        var results = document.getElementById("results");
+        var timer = null;
+        function text(tag, s) {
+          var e = document.createElement(tag);
+          e.textContent = s;
+          return e;
+        }
+        function search() {
+          fetch("/api/v1/search?q=" + encodeURIComponent(q.value))
+            .then(function(r) { return r.json(); })
+            .then(function(res) {
+              results.replaceChildren();
+              res.groups.forEach(function(g) {
+                results.appendChild(text("h3", g.name + " (" + g.results.length + ")"));
+                var ul = document.createElement("ul");
+                g.results.forEach(function(r) {
+                  var label = r.container ? r.container.name + " (" + r.container.image + ") on " + r.node : r.node;
+                  ul.appendChild(text("li", label + " - " + r.field + ": " + r.match));
+                });
+                results.appendChild(ul);
+              });
+            });
+        }
+        q.oninput = function() {
+          clearTimeout(timer);
+          timer = setTimeout(search, 200);
+        };
+      })();
+    </script>
+  </body>
+</html>
+`
+
+// Scores of search matches, from the best to the worst.
+const (
+	scoreExact     = 3
+	scorePrefix    = 2
+	scoreSubstring = 1
+)
+
+// SearchResult is a container or node matching a search.
+type SearchResult struct {
+	Node      string     `json:"node"`
+	Container *Container `json:"container,omitempty"`
+	// Field is the field matching the search, and Match its value.
+	Field string `json:"field"`
+	Match string `json:"match"`
+	Score int    `json:"score"`
+}
+
+// SearchGroup gathers search results of the same kind, best first.
+type SearchGroup struct {
+	Name    string         `json:"name"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResults are the results of a search, grouped by kind.
+type SearchResults struct {
+	Query  string        `json:"query"`
+	Groups []SearchGroup `json:"groups"`
+}
+
+// matchScore scores how well a value matches a lowercase query.
+func matchScore(value, q string) int {
+	v := strings.ToLower(value)
+	switch {
+	case v == q:
+		return scoreExact
+	case strings.HasPrefix(v, q):
+		return scorePrefix
+	case strings.Contains(v, q):
+		return scoreSubstring
+	}
+	return 0
+}
+
+// bestMatch returns the best matching field of a container.
+func bestMatch(c Container, q string) (field, match string, score int) {
+	try := func(f, v string) {
+		if s := matchScore(v, q); s > score {
+			field, match, score = f, v, s
+		}
+	}
+	try("name", c.Name)
+	try("id", c.ID)
+	try("image", c.Image)
+	for k, v := range c.Labels {
+		try("label", k+"="+v)
+	}
+	return field, match, score
+}
+
+// search searches the containers and nodes of the cluster.
+func (m *Manager) search(query string) SearchResults {
+	res := SearchResults{
+		Query: query,
+		Groups: []SearchGroup{
+			{Name: "nodes", Results: []SearchResult{}},
+			{Name: "containers", Results: []SearchResult{}},
+		},
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return res
+	}
+
+	for _, n := range m.inventory.Nodes() {
+		if s := matchScore(n.Node, q); s > 0 {
+			res.Groups[0].Results = append(res.Groups[0].Results, SearchResult{Node: n.Node, Field: "node", Match: n.Node, Score: s})
+		}
+		for _, c := range n.Containers {
+			c := c
+			if field, match, s := bestMatch(c, q); s > 0 {
+				res.Groups[1].Results = append(res.Groups[1].Results, SearchResult{Node: n.Node, Container: &c, Field: field, Match: match, Score: s})
+			}
+		}
+	}
+	for _, g := range res.Groups {
+		sort.SliceStable(g.Results, func(i, j int) bool {
+			return g.Results[i].Score > g.Results[j].Score
+		})
+	}
+	return res
+}
+
+func (m *Manager) apiSearch() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.search(r.URL.Query().Get("q")))
+	})
+}
+
+func (m *Manager) searchPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := localize(t, r).ExecuteTemplate(w, "search", nil); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
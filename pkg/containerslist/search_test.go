@@ -0,0 +1,67 @@
+package containerslist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "web-1", Containers: []Container{
+			{ID: "0123abcd", Name: "nginx-proxy", Image: "nginx:1.25"},
+			{ID: "4567ef01", Name: "api", Image: "registry.example.com/shop/api:2", Labels: map[string]string{"team": "web"}},
+		}},
+		&NodeInventory{Node: "db-1", Containers: []Container{
+			{ID: "89ab2345", Name: "web", Image: "postgres:16"},
+		}},
+	)
+
+	type result struct{ node, container, field, match string }
+	for _, tc := range []struct {
+		query          string
+		wantNodes      []result
+		wantContainers []result
+	}{
+		{
+			query:     "WEB",
+			wantNodes: []result{{node: "web-1", field: "node", match: "web-1"}},
+			wantContainers: []result{
+				{node: "db-1", container: "web", field: "name", match: "web"},
+				{node: "web-1", container: "api", field: "label", match: "team=web"},
+			},
+		},
+		{
+			query:          " 0123 ",
+			wantContainers: []result{{node: "web-1", container: "nginx-proxy", field: "id", match: "0123abcd"}},
+		},
+		{
+			query: "nginx",
+			wantContainers: []result{
+				{node: "web-1", container: "nginx-proxy", field: "name", match: "nginx-proxy"},
+			},
+		},
+		{query: "shop/api", wantContainers: []result{{node: "web-1", container: "api", field: "image", match: "registry.example.com/shop/api:2"}}},
+		{query: "  "},
+		{query: "redis"},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			res := m.search(tc.query)
+			if len(res.Groups) != 2 || res.Groups[0].Name != "nodes" || res.Groups[1].Name != "containers" {
+				t.Fatalf("groups = %+v, want nodes and containers", res.Groups)
+			}
+			var nodes, containers []result
+			for _, r := range res.Groups[0].Results {
+				nodes = append(nodes, result{node: r.Node, field: r.Field, match: r.Match})
+			}
+			for _, r := range res.Groups[1].Results {
+				containers = append(containers, result{node: r.Node, container: r.Container.Name, field: r.Field, match: r.Match})
+			}
+			if !reflect.DeepEqual(nodes, tc.wantNodes) {
+				t.Errorf("nodes = %+v, want %+v", nodes, tc.wantNodes)
+			}
+			if !reflect.DeepEqual(containers, tc.wantContainers) {
+				t.Errorf("containers = %+v, want %+v", containers, tc.wantContainers)
+			}
+		})
+	}
+}
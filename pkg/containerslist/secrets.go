@@ -0,0 +1,139 @@
+package containerslist
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// secretRefUsage documents the references accepted by the secret flags.
+const secretRefUsage = "; may be a file://<path>, env://<variable> or vault://<path>[#<field>] reference, resolved on startup and on SIGHUP"
+
+// secretResolveTimeout bounds the resolution of all the secret references.
+const secretResolveTimeout = 30 * time.Second
+
+// Secret is a flag holding a secret, either as is or as a reference to a
+// file, an environment variable or a Vault secret, which keeps it out of the
+// command line and of the configuration files. References are resolved by
+// Config.ResolveSecrets.
+type Secret struct {
+	ref   string
+	value atomic.Pointer[string]
+}
+
+// secretVar defines a secret flag.
+func secretVar(fs *flag.FlagSet, s *Secret, name, usage string) {
+	fs.Var(s, name, usage+secretRefUsage)
+}
+
+// String implements flag.Value, returning the reference, never the secret.
+func (s *Secret) String() string {
+	if s == nil || !isSecretRef(s.ref) {
+		return ""
+	}
+	return s.ref
+}
+
+// Set implements flag.Value. Plain values are used as is.
+func (s *Secret) Set(v string) error {
+	s.ref = v
+	if !isSecretRef(v) {
+		s.value.Store(&v)
+	}
+	return nil
+}
+
+// Value returns the secret, or an empty string until its reference is
+// resolved.
+func (s *Secret) Value() string {
+	if v := s.value.Load(); v != nil {
+		return *v
+	}
+	return ""
+}
+
+// isSecretRef reports whether a value is a reference to a secret.
+func isSecretRef(v string) bool {
+	for _, scheme := range []string{"file://", "env://", "vault://"} {
+		if strings.HasPrefix(v, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSecret returns the secret of a reference.
+func resolveSecret(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "file://"):
+		b, err := os.ReadFile(strings.TrimPrefix(ref, "file://"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(ref, "vault://"):
+		return readVaultSecret(ctx, strings.TrimPrefix(ref, "vault://"))
+	}
+	return ref, nil
+}
+
+// ResolveSecrets resolves the references of the secrets of the
+// configuration. On error, the previous values of the secrets are kept.
+func (c *Config) ResolveSecrets(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, secretResolveTimeout)
+	defer cancel()
+	secrets := c.secrets()
+	values := map[string]string{}
+	for name, s := range secrets {
+		if !isSecretRef(s.ref) {
+			continue
+		}
+		v, err := resolveSecret(ctx, s.ref)
+		if err != nil {
+			return fmt.Errorf("unable to resolve secret of -%s: %w", name, err)
+		}
+		values[name] = v
+	}
+	for name, v := range values {
+		v := v
+		secrets[name].value.Store(&v)
+	}
+	return nil
+}
+
+// reloadSecrets resolves the secret references again on SIGHUP, such as
+// after the rotation of a secret. The secrets only read on startup require a
+// restart, except for the gossip signing key, resolved again by its signer.
+func (c *Config) reloadSecrets(ctx context.Context, logger log.Logger) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			if err := c.ResolveSecrets(ctx); err != nil {
+				level.Error(logger).Log("msg", "Unable to reload secrets", "error", err)
+				continue
+			}
+			level.Info(logger).Log("msg", "Secrets reloaded")
+		}
+	}
+}
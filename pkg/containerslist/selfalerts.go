@@ -0,0 +1,168 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severities of self-alerts.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Thresholds of self-alerts.
+const (
+	flappingWindow    = 10 * time.Minute
+	flappingThreshold = 5
+	budgetWarnRatio   = 0.9
+)
+
+// SelfAlert is an unhealthy condition of the agent itself, with a hint to
+// remediate it.
+type SelfAlert struct {
+	Name        string `json:"name"`
+	Severity    string `json:"severity"`
+	Summary     string `json:"summary"`
+	Remediation string `json:"remediation"`
+	// Node is the node the alert is about: the local node for the
+	// conditions of the agent itself.
+	Node string `json:"node"`
+}
+
+// membershipTracker records the changes of the cluster size, to detect
+// flapping members.
+type membershipTracker struct {
+	mtx     sync.Mutex
+	size    int
+	changes []time.Time
+}
+
+func (t *membershipTracker) observe(now time.Time, size int) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.size != 0 && size != t.size {
+		t.changes = append(t.changes, now)
+	}
+	t.size = size
+}
+
+// recentChanges returns the number of changes of the cluster size in the
+// flapping window.
+func (t *membershipTracker) recentChanges(now time.Time) int {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	i := 0
+	for i < len(t.changes) && now.Sub(t.changes[i]) > flappingWindow {
+		i++
+	}
+	t.changes = t.changes[i:]
+	return len(t.changes)
+}
+
+// runMembershipTracker periodically samples the cluster size.
+func (m *Manager) runMembershipTracker(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.membership.observe(now, m.peer.ClusterSize())
+		}
+	}
+}
+
+// selfAlerts evaluates the health conditions of the agent, leaving out the
+// alerts of the nodes in maintenance.
+func (m *Manager) selfAlerts() []SelfAlert {
+	now := time.Now()
+	alerts := []SelfAlert{}
+
+	if n := m.membership.recentChanges(now); n >= flappingThreshold {
+		alerts = append(alerts, SelfAlert{
+			Name:        "GossipFlapping",
+			Node:        m.nodeID,
+			Severity:    SeverityWarning,
+			Summary:     fmt.Sprintf("The cluster size changed %d times in the last %s.", n, flappingWindow),
+			Remediation: "Check the network between peers and that the HA listen and advertise addresses are reachable by all of them; raise --ha_push_pull_interval on lossy networks.",
+		})
+	}
+	// The initial join error is moot once peers were reached.
+	if m.joinErr != nil && m.peer.ClusterSize() <= 1 {
+		alerts = append(alerts, SelfAlert{
+			Name:        "DiscoveryFailed",
+			Node:        m.nodeID,
+			Severity:    SeverityWarning,
+			Summary:     "Unable to join the initial peers: " + strings.Join(strings.Fields(m.joinErr.Error()), " "),
+			Remediation: "Check --ha_peers: the peers must resolve and be reachable on their HA port.",
+		})
+	}
+	if s := m.partition.Status(); s.Minority {
+		alerts = append(alerts, SelfAlert{
+			Name:        "MinorityPartition",
+			Node:        m.nodeID,
+			Severity:    SeverityCritical,
+			Summary:     fmt.Sprintf("The node only sees %d of %d expected members.", s.Members, s.Expected),
+			Remediation: "Check the connectivity to the missing peers; if they were removed on purpose, lower --ha_expected_size.",
+		})
+	}
+	for _, n := range m.inventory.Nodes() {
+		if n.Truncated {
+			alerts = append(alerts, SelfAlert{
+				Name:        "InventoryTruncated",
+				Node:        n.Node,
+				Severity:    SeverityWarning,
+				Summary:     fmt.Sprintf("The inventory of node %s only holds %d of its %d containers.", n.Node, len(n.Containers), n.TotalContainers),
+				Remediation: "Raise --inventory.max-containers-per-node or --inventory.memory-budget on that node.",
+			})
+		} else if m.cfg.MemoryBudget > 0 && m.isLocal(n.Node) {
+			if size := len(encodeNodeInventory(n)); float64(size) >= budgetWarnRatio*float64(m.cfg.MemoryBudget) {
+				alerts = append(alerts, SelfAlert{
+					Name:        "InventoryNearBudget",
+					Node:        n.Node,
+					Severity:    SeverityWarning,
+					Summary:     fmt.Sprintf("The inventory of node %s uses %d of its %d bytes budget.", n.Node, size, m.cfg.MemoryBudget),
+					Remediation: "Raise --inventory.memory-budget, or prune stopped containers.",
+				})
+			}
+		}
+	}
+	facts := m.peerFacts()
+	names := m.peerDisplayNames()
+	for peer, skew := range m.clock.Skews() {
+		if m.clock.exceeds(skew) {
+			node := m.nodeID
+			if f, ok := facts[peer]; ok {
+				node = f.Node
+			}
+			alerts = append(alerts, SelfAlert{
+				Name:        "ClockSkew",
+				Node:        node,
+				Severity:    SeverityWarning,
+				Summary:     fmt.Sprintf("The clock of peer %s is skewed by %s.", names[peer], skew.Round(time.Millisecond)),
+				Remediation: "Synchronize the clocks of the nodes with NTP: the most recent inventory of a node wins, so skewed clocks hide updates.",
+			})
+		}
+	}
+
+	maintenance := m.activeMaintenance(now)
+	res := alerts[:0]
+	for _, a := range alerts {
+		if maintenance.Window(a.Node) == nil {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+func (m *Manager) apiSelfAlerts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.selfAlerts())
+	})
+}
@@ -0,0 +1,90 @@
+package containerslist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMembershipTracker(t *testing.T) {
+	var tr membershipTracker
+	start := time.Now()
+	for i, size := range []int{3, 3, 2, 3, 2, 3} {
+		tr.observe(start.Add(time.Duration(i)*time.Minute), size)
+	}
+	if got := tr.recentChanges(start.Add(5 * time.Minute)); got != 4 {
+		t.Errorf("changes = %d, want 4", got)
+	}
+	// The changes at 2 and 3 minutes are out of the window.
+	if got := tr.recentChanges(start.Add(flappingWindow + 3*time.Minute + time.Second)); got != 2 {
+		t.Errorf("changes = %d, want 2", got)
+	}
+}
+
+func TestSelfAlerts(t *testing.T) {
+	peer, err := cluster.Create(log.NewNopLogger(), prometheus.NewRegistry(), "127.0.0.1:0", "", nil, false, time.Minute, cluster.DefaultGossipInterval, time.Second, time.Second, time.Second, nil, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Leave(time.Second) })
+
+	m := newTestInventoryManager(t,
+		&NodeInventory{Node: "a", Containers: []Container{{ID: "a1"}}, Truncated: true, TotalContainers: 10},
+		&NodeInventory{Node: "b"},
+	)
+	m.nodeID = "a"
+	m.peer = peer
+	m.facts = newNodeState[*NodeFacts]()
+	m.maintenance = newResourceState[MaintenanceWindow]()
+	m.clock = newClockState(peer.Name(), time.Second, log.NewNopLogger())
+	m.joinErr = errors.New("1 error occurred:\n\t* lookup peer-1: no such host")
+	m.partition = newPartitionDetector(func() []string { return []string{peer.Name()} }, func() map[string]bool { return nil }, 3, prometheus.NewRegistry())
+
+	start := time.Now()
+	for i := 0; i < flappingThreshold+1; i++ {
+		m.membership.observe(start, 1+i%2)
+	}
+
+	var names []string
+	for _, a := range m.selfAlerts() {
+		names = append(names, a.Name)
+		if a.Remediation == "" {
+			t.Errorf("alert %s without remediation", a.Name)
+		}
+		if a.Name == "DiscoveryFailed" && a.Summary != "Unable to join the initial peers: 1 error occurred: * lookup peer-1: no such host" {
+			t.Errorf("summary = %q, want the join error on one line", a.Summary)
+		}
+	}
+	if want := []string{"GossipFlapping", "DiscoveryFailed", "MinorityPartition", "InventoryTruncated"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("alerts = %v, want %v", names, want)
+	}
+
+	// The alerts of the nodes in maintenance are silenced.
+	now := time.Now()
+	if _, err := m.maintenance.Set(&NodeResources[MaintenanceWindow]{Node: "a", Timestamp: now, Items: []MaintenanceWindow{
+		{ID: "upgrade", Nodes: []string{"a"}, Start: now.Add(-time.Minute), End: now.Add(time.Hour), Updated: now},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := m.selfAlerts(); len(alerts) != 0 {
+		t.Errorf("alerts = %+v, want none in maintenance", alerts)
+	}
+	m.maintenance = newResourceState[MaintenanceWindow]()
+
+	rec := httptest.NewRecorder()
+	m.joinErr = nil
+	m.partition = newPartitionDetector(func() []string { return []string{peer.Name()} }, func() map[string]bool { return nil }, 1, prometheus.NewRegistry())
+	m.membership = membershipTracker{}
+	m.inventory = newInventory()
+	m.apiSelfAlerts().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/selfalerts", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Errorf("response = %d %q, want no alert", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,240 @@
+package containerslist
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// shardRingPoints is the number of points of each member on the ring, to
+// spread the shards evenly.
+const shardRingPoints = 64
+
+// hash64 hashes a string with FNV-1a, then mixes the bits with the finalizer
+// of MurmurHash3: the FNV hashes of strings only differing by their last
+// characters, such as shard-1 and shard-2, are close, and would otherwise
+// all fall between the same points of the ring.
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+type ringPoint struct {
+	hash   uint64
+	member string
+}
+
+// shardRing assigns the nodes to shards, and the shards to the members of
+// the cluster on a consistent hash ring: when a member joins or leaves, only
+// the shards next to its points change owners. Each shard is aggregated by
+// several members, its replicas.
+type shardRing struct {
+	shards   int
+	replicas int
+	members  func() []string
+
+	mtx       sync.Mutex
+	refreshed time.Time
+	key       string
+	points    []ringPoint
+}
+
+func newShardRing(shards, replicas int, members func() []string) *shardRing {
+	return &shardRing{shards: shards, replicas: replicas, members: members}
+}
+
+// Shard returns the shard of a node.
+func (r *shardRing) Shard(node string) int {
+	return int(hash64(node) % uint64(r.shards))
+}
+
+// Owners returns the members aggregating a shard, walking the ring from the
+// point of the shard. The ring is rebuilt when the members change, checked
+// at most every second.
+func (r *shardRing) Owners(shard int) []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if time.Since(r.refreshed) > time.Second {
+		r.refresh()
+	}
+	h := hash64("shard-" + strconv.Itoa(shard))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	var owners []string
+	for j := 0; j < len(r.points) && len(owners) < r.replicas; j++ {
+		p := r.points[(i+j)%len(r.points)]
+		if !contains(owners, p.member) {
+			owners = append(owners, p.member)
+		}
+	}
+	return owners
+}
+
+func (r *shardRing) refresh() {
+	r.refreshed = time.Now()
+	members := r.members()
+	sort.Strings(members)
+	key := strings.Join(members, ",")
+	if key == r.key {
+		return
+	}
+	r.key = key
+	r.points = r.points[:0]
+	for _, m := range members {
+		for i := 0; i < shardRingPoints; i++ {
+			r.points = append(r.points, ringPoint{hash: hash64(m + "#" + strconv.Itoa(i)), member: m})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i].hash < r.points[j].hash })
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsNode reports whether the full inventory of a node is kept locally:
+// either it is collected locally, or its shard is aggregated locally.
+func (m *Manager) ownsNode(node string) bool {
+	if m.cfg.Passive {
+		return false
+	}
+	return m.isLocal(node) || contains(m.shards.Owners(m.shards.Shard(node)), m.peer.Name())
+}
+
+// clusterMembers returns the names of the members of the cluster which
+// aggregate shards, leaving out the passive ones.
+func (m *Manager) clusterMembers() []string {
+	passive := m.passivePeers()
+	var names []string
+	for _, p := range m.peer.Peers() {
+		if !passive[p.Name()] {
+			names = append(names, p.Name())
+		}
+	}
+	return names
+}
+
+// ShardInfo is a shard of the nodes, with the members aggregating it.
+type ShardInfo struct {
+	Shard  int      `json:"shard"`
+	Owners []string `json:"owners"`
+	Nodes  []string `json:"nodes"`
+}
+
+func (m *Manager) shardInfos() []ShardInfo {
+	shards := make([]ShardInfo, m.shards.shards)
+	for i := range shards {
+		shards[i] = ShardInfo{Shard: i, Owners: m.shards.Owners(i), Nodes: []string{}}
+	}
+	for _, n := range m.inventory.Nodes() {
+		s := m.shards.Shard(n.Node)
+		shards[s].Nodes = append(shards[s].Nodes, n.Node)
+	}
+	return shards
+}
+
+func (m *Manager) apiShards() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.shards == nil {
+			writeProblem(w, r, problemf(ErrNotFound, "collection is not sharded"))
+			return
+		}
+		writeJSON(w, m.shardInfos())
+	})
+}
+
+// gatherShards replaces the summaries of the nodes of the shards which are
+// not aggregated locally with their full inventories, queried in parallel
+// from the internal API of an owner of each shard, in the local zone when
+// there is one. The summaries of the
+// owners which did not answer are kept, and their addresses returned.
+func (m *Manager) gatherShards(ctx context.Context, query url.Values, nodes []*NodeInventory) ([]*NodeInventory, []string) {
+	addrs, zones := map[string]string{}, map[string]string{}
+	for _, f := range m.facts.Nodes() {
+		if f.Peer != "" && f.InternalAddress != "" && f.InternalAddress != m.internalAddress {
+			addrs[f.Peer] = f.InternalAddress
+			zones[f.Peer] = f.Zone
+		}
+	}
+	// The summarized nodes to query, by owner address.
+	wanted := map[string]map[string]int{}
+	for i, n := range nodes {
+		if !n.Summary {
+			continue
+		}
+		for _, owner := range preferZone(m.shards.Owners(m.shards.Shard(n.Node)), zones, m.cfg.Zone) {
+			if addr, ok := addrs[owner]; ok {
+				if wanted[addr] == nil {
+					wanted[addr] = map[string]int{}
+				}
+				wanted[addr][n.Node] = i
+				break
+			}
+		}
+	}
+	if len(wanted) == 0 {
+		return nodes, nil
+	}
+
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("scope", "owned")
+	q.Del("format")
+
+	var (
+		mtx    sync.Mutex
+		wg     sync.WaitGroup
+		failed []string
+	)
+	client := &http.Client{Transport: m.router.transport, Timeout: m.cfg.GatewayTimeout}
+	for addr, indexes := range wanted {
+		wg.Add(1)
+		go func(addr string, indexes map[string]int) {
+			defer wg.Done()
+			var peerNodes []*NodeInventory
+			err := m.breakers.Get("peer:"+addr).Do(ctx, func() (err error) {
+				peerNodes, err = m.queryPeer(ctx, client, addr, q)
+				return err
+			})
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				m.gatewayFailures.Inc()
+				level.Warn(m.requestLogger(ctx)).Log("msg", "Unable to query shard owner", "peer", addr, "error", err)
+				failed = append(failed, addr)
+				return
+			}
+			for _, n := range peerNodes {
+				if i, ok := indexes[n.Node]; ok {
+					nodes[i] = n
+				}
+			}
+		}(addr, indexes)
+	}
+	wg.Wait()
+	sort.Strings(failed)
+	return nodes, failed
+}
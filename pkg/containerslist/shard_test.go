@@ -0,0 +1,166 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testRing returns a ring of members, refreshed at once.
+func testRing(replicas int, members *[]string) *shardRing {
+	return newShardRing(64, replicas, func() []string { return append([]string(nil), *members...) })
+}
+
+func owners(r *shardRing) [][]string {
+	r.mtx.Lock()
+	r.refreshed = time.Time{}
+	r.mtx.Unlock()
+	owners := make([][]string, r.shards)
+	for i := range owners {
+		owners[i] = r.Owners(i)
+	}
+	return owners
+}
+
+func TestShardRingOwners(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		members  int
+		replicas int
+		want     int
+	}{
+		{name: "empty ring", members: 0, replicas: 2, want: 0},
+		{name: "fewer members than replicas", members: 1, replicas: 2, want: 1},
+		{name: "replicas", members: 5, replicas: 2, want: 2},
+		{name: "as many members as replicas", members: 3, replicas: 3, want: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var members []string
+			for i := 0; i < tc.members; i++ {
+				members = append(members, fmt.Sprintf("member-%d", i))
+			}
+			for shard, o := range owners(testRing(tc.replicas, &members)) {
+				if len(o) != tc.want {
+					t.Fatalf("shard %d has owners %v, want %d", shard, o, tc.want)
+				}
+				seen := map[string]bool{}
+				for _, m := range o {
+					if seen[m] {
+						t.Fatalf("shard %d has owner %s twice", shard, m)
+					}
+					seen[m] = true
+				}
+			}
+		})
+	}
+}
+
+func TestShardRingStability(t *testing.T) {
+	var members []string
+	for i := 0; i < 10; i++ {
+		members = append(members, fmt.Sprintf("member-%d", i))
+	}
+	r := testRing(2, &members)
+	before := owners(r)
+
+	// On join, the shards only move to the new member.
+	members = append(members, "member-new")
+	joined := owners(r)
+	moved := 0
+	for shard := range before {
+		for _, m := range before[shard] {
+			if !contains(joined[shard], m) {
+				moved++
+				if !contains(joined[shard], "member-new") {
+					t.Errorf("shard %d moved from %s to %v without the new member", shard, m, joined[shard])
+				}
+			}
+		}
+	}
+	// About 2/11 of the replicas are expected to move.
+	if moved == 0 || moved > len(before) {
+		t.Errorf("%d of %d replicas moved on join", moved, len(before)*2)
+	}
+
+	// On leave, only the replicas of the leaving member move.
+	members = members[1:]
+	left := owners(r)
+	for shard := range joined {
+		for _, m := range joined[shard] {
+			if m != "member-0" && !contains(left[shard], m) {
+				t.Errorf("shard %d lost its owner %s when member-0 left", shard, m)
+			}
+		}
+		if contains(left[shard], "member-0") {
+			t.Errorf("shard %d still owned by member-0 after it left", shard)
+		}
+	}
+}
+
+func TestShardRingBalance(t *testing.T) {
+	var members []string
+	for i := 0; i < 8; i++ {
+		members = append(members, fmt.Sprintf("member-%d", i))
+	}
+	counts := map[string]int{}
+	for _, o := range owners(testRing(1, &members)) {
+		counts[o[0]]++
+	}
+	// 8 shards each on average.
+	for _, m := range members {
+		if counts[m] < 2 || counts[m] > 20 {
+			t.Errorf("member %s owns %d of 64 shards", m, counts[m])
+		}
+	}
+}
+
+func TestShardRingShard(t *testing.T) {
+	r := newShardRing(8, 1, func() []string { return nil })
+	for i := 0; i < 100; i++ {
+		node := fmt.Sprintf("node-%d", i)
+		if s := r.Shard(node); s < 0 || s >= 8 || s != r.Shard(node) {
+			t.Fatalf("Shard(%s) = %d", node, s)
+		}
+	}
+}
+
+func TestGatherShards(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		ownerDown  bool
+		wantFull   bool
+		wantFailed bool
+	}{
+		{name: "owner answers", wantFull: true},
+		{name: "owner down keeps the summaries", ownerDown: true, wantFailed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			owner := &testZonePeer{name: "owner", nodes: []string{"node-1", "node-2"}}
+			owner.start(t, map[string][]string{"owned": {"node-1", "node-2"}})
+			m := newTestZoneManager(t, "", 1, []string{"owner"}, owner)
+			if tc.ownerDown {
+				owner.srv.Close()
+			}
+
+			nodes := []*NodeInventory{
+				{Node: "node-1", Summary: true},
+				{Node: "node-2", Summary: true},
+				{Node: "node-local"},
+			}
+			nodes, failed := m.gatherShards(context.Background(), url.Values{}, nodes)
+			for _, n := range nodes[:2] {
+				if n.Summary == tc.wantFull {
+					t.Errorf("node %s summarized = %v, want %v", n.Node, n.Summary, !tc.wantFull)
+				}
+			}
+			if nodes[2].Node != "node-local" || nodes[2].Summary {
+				t.Errorf("local node replaced: %+v", nodes[2])
+			}
+			if gotFailed := len(failed) == 1 && failed[0] == owner.addr(); gotFailed != tc.wantFailed {
+				t.Errorf("failed = %v, want failure %v", failed, tc.wantFailed)
+			}
+		})
+	}
+}
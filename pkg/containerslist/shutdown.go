@@ -0,0 +1,88 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// handleSignals cancels the context on the first SIGTERM or SIGINT, starting
+// a graceful shutdown. A second SIGINT forces an immediate exit, and SIGQUIT
+// dumps the goroutines without stopping.
+func handleSignals(ctx context.Context, logger log.Logger) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+
+	go func() {
+		for sig := range sigs {
+			switch {
+			case sig == syscall.SIGQUIT:
+				level.Info(logger).Log("msg", "Dumping goroutines")
+				pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+			case ctx.Err() == nil:
+				level.Info(logger).Log("msg", "Shutting down gracefully", "signal", sig)
+				cancel()
+			case sig == syscall.SIGINT:
+				level.Warn(logger).Log("msg", "Forcing exit", "signal", sig)
+				os.Exit(1)
+			default:
+				level.Info(logger).Log("msg", "Already shutting down", "signal", sig)
+			}
+		}
+	}()
+	return ctx
+}
+
+// shutdown stops the node in phases, each with its own deadline: the HTTP
+// servers are drained first, then the collectors are stopped and the node
+// leaves the cluster.
+func (m *Manager) shutdown(servers ...*http.Server) {
+	level.Info(m.logger).Log("msg", "Draining HTTP servers", "timeout", m.cfg.ShutdownDrainTimeout)
+	m.events.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.ShutdownDrainTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(ctx); err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to drain HTTP server", "addr", s.Addr, "error", err)
+		}
+	}
+
+	level.Info(m.logger).Log("msg", "Stopping collectors", "timeout", m.cfg.ShutdownCollectorsTimeout)
+	m.collectors.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		m.collectors.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(m.cfg.ShutdownCollectorsTimeout):
+		level.Warn(m.logger).Log("msg", "Collectors did not stop in time")
+	}
+
+	level.Info(m.logger).Log("msg", "Leaving cluster", "timeout", m.cfg.ShutdownLeaveTimeout)
+	m.announceLeave()
+	m.StopAndWait()
+}
+
+// listenAndServe serves HTTP until the server is shut down.
+func listenAndServe(s *http.Server, tls bool, logger log.Logger) {
+	var err error
+	if tls {
+		err = s.ListenAndServeTLS("", "")
+	} else {
+		err = s.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		level.Error(logger).Log("msg", "HTTP server failed", "addr", s.Addr, "error", err)
+	}
+}
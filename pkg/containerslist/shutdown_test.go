@@ -0,0 +1,78 @@
+package containerslist
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestHandleSignals(t *testing.T) {
+	ctx := handleSignals(context.Background(), log.NewNopLogger())
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not cancelled on SIGTERM")
+	}
+	// Another SIGTERM while shutting down is ignored.
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	m := newTestDecommissionManager(t)
+	// The node leaves the cluster on shutdown.
+	m.peer = newTestPeer(t)
+	m.events = newEventBroker()
+	m.facts = newNodeState[*NodeFacts]()
+	m.factsChannel = &testChannel{}
+	m.cfg.ShutdownCollectorsTimeout = 50 * time.Millisecond
+	_, m.settleCancel = context.WithCancel(context.Background())
+	if _, err := m.facts.Set(&NodeFacts{Node: "a", Peer: m.peer.Name(), Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+
+	// A collector ignoring the cancellation does not block the shutdown.
+	m.collectors.wg.Add(1)
+	t.Cleanup(m.collectors.wg.Done)
+
+	done := make(chan struct{})
+	go func() {
+		m.shutdown(srv)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not complete")
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("serve = %v, want the server closed", err)
+	}
+	if m.collectors.ctx.Err() == nil {
+		t.Error("collectors not cancelled")
+	}
+	if f, ok := m.facts.Get("a"); !ok || !f.Left {
+		t.Errorf("facts = %+v, want left", f)
+	}
+	if len(m.factsChannel.(*testChannel).msgs) != 1 {
+		t.Error("leave not broadcast")
+	}
+}
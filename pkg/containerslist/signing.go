@@ -0,0 +1,267 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Signature is the Ed25519 signature of a gossiped node entry, along with
+// the public key of its signer.
+type Signature struct {
+	Key []byte `json:"key"`
+	Sig []byte `json:"sig"`
+}
+
+// signedEntry is a gossiped node entry which can be signed.
+type signedEntry interface {
+	node() string
+	signature() *Signature
+	setSignature(*Signature)
+}
+
+// Reasons for rejecting a gossiped update.
+const (
+	rejectUnsigned    = "unsigned"
+	rejectUntrusted   = "untrusted_key"
+	rejectBadSig      = "bad_signature"
+	rejectKeyMismatch = "key_mismatch"
+)
+
+// gossipSigner signs the entries of the local node and verifies the entries
+// received from peers. Verification is only enforced when trusted keys are
+// configured; a node is then pinned to the first trusted key it was seen
+// with, so that a trusted peer cannot forge another node's updates, until
+// that key is no longer trusted.
+//
+// The keys are read again by Run, so that they are rotated without restarts:
+// the new public key is first added to the trusted keys of the cluster, then
+// the signing key is replaced, and finally the previous public key is
+// removed, at which point the nodes are pinned to their new key.
+type gossipSigner struct {
+	// keyRef is the reference of the signing key, such as vault://<path>,
+	// resolved again by Run. The key is not reloaded when it was set as is.
+	keyRef          string
+	trustedKeysFile string
+
+	mtx     sync.Mutex
+	key     ed25519.PrivateKey
+	trusted map[string]struct{}
+	pinned  map[string]string
+
+	rejected *prometheus.CounterVec
+}
+
+func newGossipSigner(key []byte, trustedKeysFile string, reg prometheus.Registerer) (*gossipSigner, error) {
+	s := &gossipSigner{
+		trustedKeysFile: trustedKeysFile,
+		pinned:          map[string]string{},
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_gossip_rejected_updates_total",
+			Help: "Number of gossiped updates rejected because of a missing or invalid signature.",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(s.rejected)
+
+	if len(key) > 0 {
+		var err error
+		if s.key, err = parseSigningKey(key); err != nil {
+			return nil, fmt.Errorf("invalid gossip signing key: %w", err)
+		}
+	}
+
+	if trustedKeysFile != "" {
+		var err error
+		if s.trusted, err = readTrustedKeys(trustedKeysFile); err != nil {
+			return nil, fmt.Errorf("invalid gossip trusted keys: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// reload reads the signing key of its reference and the trusted keys again.
+// On error, the previous keys are kept.
+func (s *gossipSigner) reload(ctx context.Context) error {
+	var key ed25519.PrivateKey
+	if isSecretRef(s.keyRef) {
+		v, err := resolveSecret(ctx, s.keyRef)
+		if err != nil {
+			return fmt.Errorf("unable to resolve gossip signing key: %w", err)
+		}
+		if key, err = parseSigningKey([]byte(v)); err != nil {
+			return fmt.Errorf("invalid gossip signing key: %w", err)
+		}
+	}
+	var trusted map[string]struct{}
+	if s.trustedKeysFile != "" {
+		var err error
+		if trusted, err = readTrustedKeys(s.trustedKeysFile); err != nil {
+			return fmt.Errorf("invalid gossip trusted keys: %w", err)
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if key != nil {
+		s.key = key
+	}
+	if trusted != nil {
+		s.trusted = trusted
+	}
+	return nil
+}
+
+// Run reloads the keys every interval until ctx is done.
+func (s *gossipSigner) Run(ctx context.Context, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloadCtx, cancel := context.WithTimeout(ctx, secretResolveTimeout)
+		err := s.reload(reloadCtx)
+		cancel()
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to reload the gossip keys", "error", err)
+		}
+	}
+}
+
+// parseSigningKey parses a PEM-encoded PKCS #8 Ed25519 private key.
+func parseSigningKey(b []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found; use file://<path> to read the key from a file")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return key, nil
+}
+
+// readTrustedKeys reads a file of PEM-encoded Ed25519 public keys, returning
+// the set of the keys.
+func readTrustedKeys(path string) (map[string]struct{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trusted := map[string]struct{}{}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := k.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an Ed25519 key")
+		}
+		trusted[string(pub)] = struct{}{}
+	}
+	if len(trusted) == 0 {
+		return nil, errors.New("no key found")
+	}
+	return trusted, nil
+}
+
+// Sign signs an entry of the local node, if a signing key is configured.
+func (s *gossipSigner) Sign(e signedEntry) error {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	key := s.key
+	s.mtx.Unlock()
+	if key == nil {
+		return nil
+	}
+	e.setSignature(nil)
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	e.setSignature(&Signature{
+		Key: pub,
+		Sig: ed25519.Sign(key, payload),
+	})
+
+	// Pin the local nodes to the local key, so that peers cannot forge them.
+	s.mtx.Lock()
+	s.pinned[e.node()] = string(pub)
+	s.mtx.Unlock()
+	return nil
+}
+
+// Verify reports whether an entry received from a peer can be merged,
+// counting the rejected ones.
+func (s *gossipSigner) Verify(e signedEntry) bool {
+	if s == nil {
+		return true
+	}
+	if reason := s.verify(e); reason != "" {
+		s.rejected.WithLabelValues(reason).Inc()
+		return false
+	}
+	return true
+}
+
+func (s *gossipSigner) verify(e signedEntry) string {
+	s.mtx.Lock()
+	trusted := s.trusted
+	s.mtx.Unlock()
+	if trusted == nil {
+		return ""
+	}
+
+	sig := e.signature()
+	if sig == nil {
+		return rejectUnsigned
+	}
+	if _, ok := trusted[string(sig.Key)]; !ok || len(sig.Key) != ed25519.PublicKeySize {
+		return rejectUntrusted
+	}
+
+	e.setSignature(nil)
+	payload, err := json.Marshal(e)
+	e.setSignature(sig)
+	if err != nil || !ed25519.Verify(sig.Key, payload, sig.Sig) {
+		return rejectBadSig
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if pinned, ok := s.pinned[e.node()]; ok && pinned != string(sig.Key) {
+		if _, ok := s.trusted[pinned]; ok {
+			return rejectKeyMismatch
+		}
+	}
+	s.pinned[e.node()] = string(sig.Key)
+	return ""
+}
@@ -0,0 +1,252 @@
+package containerslist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewGossipSignerSecretRef(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	keyFile := filepath.Join(t.TempDir(), "gossip.pem")
+	if err := os.WriteFile(keyFile, []byte(keyPEM+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_GOSSIP_SIGNING_KEY", keyPEM)
+
+	for _, tc := range []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{name: "value", ref: keyPEM},
+		{name: "file reference", ref: "file://" + keyFile},
+		{name: "env reference", ref: "env://TEST_GOSSIP_SIGNING_KEY"},
+		{name: "path", ref: keyFile, wantErr: true},
+		{name: "not PEM", ref: "not a key", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := resolveSecret(context.Background(), tc.ref)
+			if err != nil {
+				t.Fatalf("resolveSecret: %v", err)
+			}
+			s, err := newGossipSigner([]byte(v), "", prometheus.NewRegistry())
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newGossipSigner: %v", err)
+			}
+			if !pub.Equal(s.key.Public()) {
+				t.Error("signing key does not match the generated key")
+			}
+		})
+	}
+}
+
+// testEntry is a gossiped entry of a node.
+type testEntry struct {
+	Node string     `json:"node"`
+	Sig  *Signature `json:"sig,omitempty"`
+}
+
+func (e *testEntry) node() string              { return e.Node }
+func (e *testEntry) signature() *Signature     { return e.Sig }
+func (e *testEntry) setSignature(s *Signature) { e.Sig = s }
+
+func TestGossipSignerReload(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "gossip.pem")
+	trustedFile := filepath.Join(dir, "trusted.pem")
+	keys := map[string]ed25519.PrivateKey{}
+	keyPEM := map[string][]byte{}
+	pubPEM := map[string][]byte{}
+	for _, name := range []string{"old", "new"} {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[name] = priv
+		keyPEM[name] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		pubPEM[name] = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	}
+
+	// The signer of node a rotates its key, checked by the verifier of a peer.
+	write := func(key string, trusted ...string) {
+		if err := os.WriteFile(keyFile, keyPEM[key], 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var b []byte
+		for _, name := range trusted {
+			b = append(b, pubPEM[name]...)
+		}
+		if err := os.WriteFile(trustedFile, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old", "old")
+	signer, err := newGossipSigner(keyPEM["old"], trustedFile, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.keyRef = "file://" + keyFile
+	verifier, err := newGossipSigner(nil, trustedFile, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		key        string
+		trusted    []string
+		wantKey    string
+		wantReason string
+	}{
+		{name: "initial key", key: "old", trusted: []string{"old"}, wantKey: "old"},
+		{name: "new key trusted", key: "old", trusted: []string{"old", "new"}, wantKey: "old"},
+		{name: "key replaced", key: "new", trusted: []string{"old", "new"}, wantKey: "new", wantReason: rejectKeyMismatch},
+		{name: "old key untrusted", key: "new", trusted: []string{"new"}, wantKey: "new"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			write(tc.key, tc.trusted...)
+			for _, s := range []*gossipSigner{signer, verifier} {
+				if err := s.reload(context.Background()); err != nil {
+					t.Fatalf("reload: %v", err)
+				}
+			}
+			e := &testEntry{Node: "a"}
+			if err := signer.Sign(e); err != nil {
+				t.Fatal(err)
+			}
+			if !keys[tc.wantKey].Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(e.Sig.Key)) {
+				t.Errorf("entry not signed by the %s key", tc.wantKey)
+			}
+			if got := verifier.verify(e); got != tc.wantReason {
+				t.Errorf("verify = %q, want %q", got, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestGossipSignerVerify(t *testing.T) {
+	signers := map[string]*gossipSigner{}
+	var trusted []byte
+	for _, name := range []string{"a", "b", "c"} {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signers[name], err = newGossipSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "", prometheus.NewRegistry()); err != nil {
+			t.Fatal(err)
+		}
+		// The key of c is not trusted.
+		if name != "c" {
+			pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			trusted = append(trusted, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})...)
+		}
+	}
+	trustedFile := filepath.Join(t.TempDir(), "trusted.pem")
+	if err := os.WriteFile(trustedFile, trusted, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := newGossipSigner(nil, trustedFile, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := func(signer, node string) *NodeInventory {
+		n := &NodeInventory{Node: node, Timestamp: time.Now().UTC(), Containers: []Container{{ID: "c1", Name: "web"}}}
+		if err := signers[signer].Sign(n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	for _, tc := range []struct {
+		name  string
+		entry *NodeInventory
+		want  string
+	}{
+		{name: "signed", entry: signed("a", "a")},
+		{name: "unsigned", entry: &NodeInventory{Node: "a"}, want: rejectUnsigned},
+		{name: "untrusted key", entry: signed("c", "c"), want: rejectUntrusted},
+		{name: "tampered", entry: func() *NodeInventory {
+			n := signed("a", "a")
+			n.Containers[0].Name = "forged"
+			return n
+		}(), want: rejectBadSig},
+		// a is pinned to its key: b cannot forge its updates.
+		{name: "other trusted key", entry: signed("b", "a"), want: rejectKeyMismatch},
+		{name: "own node of the other key", entry: signed("b", "b")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := verifier.verify(tc.entry); got != tc.want {
+				t.Errorf("verify = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// The state only merges the verified entries, counting the others.
+	s := newNodeState[*NodeFacts]()
+	s.signer = verifier
+	var facts []*NodeFacts
+	for _, f := range []struct{ signer, node string }{{"a", "a"}, {"c", "c"}, {"", "d"}} {
+		nf := &NodeFacts{Node: f.node, Timestamp: time.Now().UTC()}
+		if f.signer != "" {
+			if err := signers[f.signer].Sign(nf); err != nil {
+				t.Fatal(err)
+			}
+		}
+		facts = append(facts, nf)
+	}
+	b, err := json.Marshal(facts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := s.Nodes(); len(nodes) != 1 || nodes[0].Node != "a" {
+		t.Errorf("merged %d nodes, want a only", len(nodes))
+	}
+	for _, reason := range []string{rejectUnsigned, rejectUntrusted} {
+		if got := testutil.ToFloat64(verifier.rejected.WithLabelValues(reason)); got != 1 {
+			t.Errorf("%s rejections = %v, want 1", reason, got)
+		}
+	}
+}
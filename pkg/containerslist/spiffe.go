@@ -0,0 +1,120 @@
+package containerslist
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Files of an X.509 SVID, as written and rotated by the SPIFFE helper or the
+// SPIRE agent.
+const (
+	spiffeSVIDFile   = "svid.pem"
+	spiffeKeyFile    = "svid_key.pem"
+	spiffeBundleFile = "svid_bundle.pem"
+)
+
+// spiffeCertSource reads the X.509 SVID of the node, its key and the bundle
+// of its trust domain from a directory.
+func spiffeCertSource(dir string) certSource {
+	return fileCertSource(filepath.Join(dir, spiffeSVIDFile), filepath.Join(dir, spiffeKeyFile), filepath.Join(dir, spiffeBundleFile))
+}
+
+// spiffeID returns the SPIFFE ID of an X.509 SVID: its only URI SAN.
+func spiffeID(cert *x509.Certificate) (*url.URL, error) {
+	if len(cert.URIs) != 1 {
+		return nil, fmt.Errorf("an X.509 SVID must have exactly one URI SAN, found %d", len(cert.URIs))
+	}
+	id := cert.URIs[0]
+	if id.Scheme != "spiffe" || id.Host == "" || id.User != nil || id.RawQuery != "" || id.Fragment != "" {
+		return nil, fmt.Errorf("invalid SPIFFE ID %q", id)
+	}
+	return id, nil
+}
+
+// spiffePolicy are the SPIFFE IDs allowed to call the node: trust domains,
+// such as example.org, whose IDs are all allowed, or IDs, such as
+// spiffe://example.org/containerslist, ending with /* to allow the IDs under
+// them. When empty, the IDs of the trust domain of the node are allowed.
+type spiffePolicy []string
+
+// String implements flag.Value.
+func (p *spiffePolicy) String() string {
+	return strings.Join(*p, ",")
+}
+
+// Set implements flag.Value, parsing comma-separated trust domains or IDs.
+func (p *spiffePolicy) Set(v string) error {
+	for _, rule := range strings.Split(v, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if strings.HasPrefix(rule, "spiffe://") {
+			if u, err := url.Parse(strings.TrimSuffix(rule, "/*")); err != nil || u.Host == "" {
+				return fmt.Errorf("invalid SPIFFE ID %q", rule)
+			}
+		} else if strings.ContainsAny(rule, "/:") {
+			return fmt.Errorf("invalid trust domain %q", rule)
+		}
+		*p = append(*p, rule)
+	}
+	return nil
+}
+
+// allows reports whether the policy allows a SPIFFE ID, the node having the
+// SPIFFE ID self.
+func (p spiffePolicy) allows(id, self *url.URL) bool {
+	if len(p) == 0 {
+		return self != nil && strings.EqualFold(id.Host, self.Host)
+	}
+	for _, rule := range p {
+		switch {
+		case !strings.HasPrefix(rule, "spiffe://"):
+			if strings.EqualFold(id.Host, rule) {
+				return true
+			}
+		case strings.HasSuffix(rule, "/*"):
+			if strings.HasPrefix(id.String(), strings.TrimSuffix(rule, "*")) {
+				return true
+			}
+		case id.String() == rule:
+			return true
+		}
+	}
+	return false
+}
+
+// verifySPIFFEPeer verifies that the verified certificate of a peer is an
+// X.509 SVID whose SPIFFE ID is allowed by the policy, the certificate of the
+// node being self.
+func verifySPIFFEPeer(peer, self *x509.Certificate, policy spiffePolicy) error {
+	id, err := spiffeID(peer)
+	if err != nil {
+		return err
+	}
+	var selfID *url.URL
+	if self != nil {
+		selfID, _ = spiffeID(self)
+	}
+	if !policy.allows(id, selfID) {
+		return fmt.Errorf("SPIFFE ID %s is not allowed", id)
+	}
+	return nil
+}
+
+// spiffeClient returns the client of a request authenticated by an X.509
+// SVID on the HTTP server, as an API key with the -http_spiffe_role role.
+func (m *Manager) spiffeClient(r *http.Request) (APIKey, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || !m.cfg.HTTPTLSSPIFFE {
+		return APIKey{}, false
+	}
+	id, err := spiffeID(r.TLS.VerifiedChains[0][0])
+	if err != nil {
+		return APIKey{}, false
+	}
+	return APIKey{Name: id.String(), Role: m.cfg.HTTPSPIFFERole}, true
+}
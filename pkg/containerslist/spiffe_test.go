@@ -0,0 +1,127 @@
+package containerslist
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// svid returns a certificate with the given URI SANs.
+func svid(t *testing.T, uris ...string) *x509.Certificate {
+	t.Helper()
+	cert := &x509.Certificate{}
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.URIs = append(cert.URIs, u)
+	}
+	return cert
+}
+
+func TestSPIFFEID(t *testing.T) {
+	if id, err := spiffeID(svid(t, "spiffe://example.org/containerslist/n1")); err != nil || id.Host != "example.org" {
+		t.Errorf("spiffeID = %v, %v, want the ID in example.org", id, err)
+	}
+	for _, uris := range [][]string{
+		nil,
+		{"spiffe://example.org/a", "spiffe://example.org/b"},
+		{"https://example.org/a"},
+		{"spiffe:///a"},
+		{"spiffe://user@example.org/a"},
+		{"spiffe://example.org/a?x=1"},
+		{"spiffe://example.org/a#b"},
+	} {
+		if id, err := spiffeID(svid(t, uris...)); err == nil {
+			t.Errorf("spiffeID of %q = %s, want an error", uris, id)
+		}
+	}
+}
+
+func TestSPIFFEPolicy(t *testing.T) {
+	var p spiffePolicy
+	if err := p.Set("partner.org, spiffe://example.org/containerslist/*,spiffe://example.org/admin"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.String(), "partner.org,spiffe://example.org/containerslist/*,spiffe://example.org/admin"; got != want {
+		t.Errorf("policy = %q, want %q", got, want)
+	}
+	for _, v := range []string{"example.org/a", "spiffe:///a", "host:8443"} {
+		if err := (&spiffePolicy{}).Set(v); err == nil {
+			t.Errorf("policy %q accepted, want an error", v)
+		}
+	}
+
+	self := svid(t, "spiffe://example.org/containerslist/n1")
+	for _, tc := range []struct {
+		policy spiffePolicy
+		id     string
+		want   bool
+	}{
+		{policy: p, id: "spiffe://Partner.org/anything", want: true},
+		{policy: p, id: "spiffe://example.org/containerslist/n2", want: true},
+		{policy: p, id: "spiffe://example.org/admin", want: true},
+		{policy: p, id: "spiffe://example.org/admin/x"},
+		{policy: p, id: "spiffe://example.org/containerslist"},
+		{policy: p, id: "spiffe://other.org/containerslist/n2"},
+		// The trust domain of the node by default.
+		{id: "spiffe://example.org/web", want: true},
+		{id: "spiffe://other.org/containerslist/n2"},
+	} {
+		err := verifySPIFFEPeer(svid(t, tc.id), self, tc.policy)
+		if got := err == nil; got != tc.want {
+			t.Errorf("%s allowed by %q = %v (%v), want %v", tc.id, tc.policy, got, err, tc.want)
+		}
+	}
+	if err := verifySPIFFEPeer(svid(t, "spiffe://example.org/web"), nil, nil); err == nil {
+		t.Error("SVID allowed without a policy nor an SVID of the node")
+	}
+}
+
+func TestSPIFFEClient(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig()}
+	m.cfg.HTTPTLSSPIFFE = true
+	if err := m.cfg.HTTPAuthToken.Set("s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	h := m.requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(method string, cert *x509.Certificate) int {
+		r := httptest.NewRequest(method, "/api/v1/containers", nil)
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	client := svid(t, "spiffe://example.org/dashboard")
+	for _, tc := range []struct {
+		method string
+		cert   *x509.Certificate
+		want   int
+	}{
+		{method: http.MethodGet, want: http.StatusUnauthorized},
+		{method: http.MethodGet, cert: svid(t), want: http.StatusUnauthorized},
+		{method: http.MethodGet, cert: client, want: http.StatusOK},
+		// The viewer role does not write.
+		{method: http.MethodPut, cert: client, want: http.StatusForbidden},
+	} {
+		if got := serve(tc.method, tc.cert); got != tc.want {
+			t.Errorf("status of a %s with %v = %d, want %d", tc.method, tc.cert != nil, got, tc.want)
+		}
+	}
+
+	m.cfg.HTTPSPIFFERole = RoleEditor
+	if got := serve(http.MethodPut, client); got != http.StatusOK {
+		t.Errorf("status of a PUT with the editor role = %d, want 200", got)
+	}
+	m.cfg.HTTPTLSSPIFFE = false
+	if got := serve(http.MethodGet, client); got != http.StatusUnauthorized {
+		t.Errorf("status without -http_tls_spiffe = %d, want 401", got)
+	}
+}
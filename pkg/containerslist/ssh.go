@@ -0,0 +1,315 @@
+package containerslist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Runtimes which can be listed over SSH.
+const (
+	RuntimeDocker = "docker"
+	RuntimeCRI    = "crictl"
+
+	dockerPsCommand = `docker ps --all --no-trunc --format '{{json .}}'`
+	crictlPsCommand = `crictl ps --all --output json`
+)
+
+// sshSource is a remote host, unable to run the agent, whose containers are
+// listed over SSH by the local agent. It is reported as a node of its own.
+type sshSource struct {
+	name    string
+	addr    string
+	runtime string
+	config  *ssh.ClientConfig
+
+	exitedSince map[string]time.Time
+}
+
+// healthName is the name of the source in the health status.
+func (s *sshSource) healthName() string {
+	return "ssh:" + s.name
+}
+
+// newSSHSources parses a comma-separated list of name=user@host[:port]
+// targets.
+func newSSHSources(targets, runtime, keyFile, knownHostsFile string) ([]*sshSource, error) {
+	if targets == "" {
+		return nil, nil
+	}
+	if keyFile == "" || knownHostsFile == "" {
+		return nil, fmt.Errorf("an SSH key file and known hosts file are required for SSH targets")
+	}
+	if runtime != RuntimeDocker && runtime != RuntimeCRI {
+		return nil, fmt.Errorf("invalid SSH runtime %q: must be one of %s, %s", runtime, RuntimeDocker, RuntimeCRI)
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SSH key: %w", err)
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SSH known hosts: %w", err)
+	}
+
+	var sources []*sshSource
+	for _, t := range strings.Split(targets, ",") {
+		name, target, ok := strings.Cut(strings.TrimSpace(t), "=")
+		user, addr, ok2 := strings.Cut(target, "@")
+		if !ok || !ok2 || name == "" || user == "" || addr == "" {
+			return nil, fmt.Errorf("invalid SSH target %q: must be name=user@host[:port]", t)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "22")
+		}
+		sources = append(sources, &sshSource{
+			name:    name,
+			addr:    addr,
+			runtime: runtime,
+			config: &ssh.ClientConfig{
+				User:            user,
+				Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+				HostKeyCallback: hostKeyCallback,
+				Timeout:         10 * time.Second,
+			},
+			exitedSince: map[string]time.Time{},
+		})
+	}
+	return sources, nil
+}
+
+// run executes a command on the remote host and returns its standard output.
+func (s *sshSource) run(ctx context.Context, cmd string) ([]byte, error) {
+	client, err := ssh.Dial("tcp", s.addr, s.config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	// Closing the client interrupts the command when the context is done.
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(cmd); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// ListContainers lists the containers of the remote host.
+func (s *sshSource) ListContainers(ctx context.Context) ([]Container, error) {
+	if s.runtime == RuntimeCRI {
+		out, err := s.run(ctx, crictlPsCommand)
+		if err != nil {
+			return nil, err
+		}
+		return parseCrictlPs(out)
+	}
+
+	out, err := s.run(ctx, dockerPsCommand)
+	if err != nil {
+		return nil, err
+	}
+	return parseDockerPs(out)
+}
+
+// parseDockerPs parses the output of `docker ps --format '{{json .}}'`, made
+// of one JSON object per line.
+func parseDockerPs(out []byte) ([]Container, error) {
+	var containers []Container
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var dc struct {
+			ID        string `json:"ID"`
+			Names     string `json:"Names"`
+			Image     string `json:"Image"`
+			State     string `json:"State"`
+			Status    string `json:"Status"`
+			CreatedAt string `json:"CreatedAt"`
+			Labels    string `json:"Labels"`
+			Ports     string `json:"Ports"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &dc); err != nil {
+			return nil, fmt.Errorf("unable to parse docker ps output: %w", err)
+		}
+
+		c := Container{
+			ID:     dc.ID,
+			Name:   strings.Split(dc.Names, ",")[0],
+			Image:  dc.Image,
+			State:  dc.State,
+			Status: dc.Status,
+			Ports:  parseDockerPsPorts(dc.Ports),
+		}
+		if created, err := time.Parse("2006-01-02 15:04:05 -0700 MST", dc.CreatedAt); err == nil {
+			c.Created = created.UTC()
+		}
+		if dc.Labels != "" {
+			c.Labels = map[string]string{}
+			for _, l := range strings.Split(dc.Labels, ",") {
+				k, v, _ := strings.Cut(l, "=")
+				c.Labels[k] = v
+			}
+		}
+		containers = append(containers, c)
+	}
+	return containers, sc.Err()
+}
+
+// parseDockerPsPorts parses the ports column of `docker ps`, such as
+// "0.0.0.0:8080->80/tcp, :::8080->80/tcp".
+func parseDockerPsPorts(s string) []Port {
+	var ports []Port
+	for _, p := range strings.Split(s, ", ") {
+		host, container, ok := strings.Cut(p, "->")
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(host, ":")
+		if i < 0 {
+			continue
+		}
+		hostPort, err := strconv.ParseUint(host[i+1:], 10, 16)
+		if err != nil {
+			continue
+		}
+		containerPort, proto, _ := strings.Cut(container, "/")
+		cPort, err := strconv.ParseUint(containerPort, 10, 16)
+		if err != nil {
+			continue
+		}
+		ports = append(ports, Port{
+			HostIP:        strings.Trim(host[:i], "[]"),
+			HostPort:      uint16(hostPort),
+			ContainerPort: uint16(cPort),
+			Protocol:      proto,
+		})
+	}
+	return ports
+}
+
+// parseCrictlPs parses the output of `crictl ps --output json`.
+func parseCrictlPs(out []byte) ([]Container, error) {
+	var resp struct {
+		Containers []struct {
+			ID       string `json:"id"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Image struct {
+				Image string `json:"image"`
+			} `json:"image"`
+			ImageRef  string            `json:"imageRef"`
+			State     string            `json:"state"`
+			CreatedAt string            `json:"createdAt"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("unable to parse crictl ps output: %w", err)
+	}
+
+	containers := make([]Container, 0, len(resp.Containers))
+	for _, cc := range resp.Containers {
+		state := strings.ToLower(strings.TrimPrefix(cc.State, "CONTAINER_"))
+		c := Container{
+			ID:      cc.ID,
+			Name:    cc.Metadata.Name,
+			Image:   cc.Image.Image,
+			ImageID: cc.ImageRef,
+			State:   state,
+			Status:  state,
+			Labels:  cc.Labels,
+		}
+		if ns, err := strconv.ParseInt(cc.CreatedAt, 10, 64); err == nil {
+			c.Created = time.Unix(0, ns).UTC()
+		}
+		containers = append(containers, c)
+	}
+	attachPods(containers, nil)
+	return containers, nil
+}
+
+// collectSSH lists the containers of a remote host and broadcasts them to the
+// cluster.
+func (m *Manager) collectSSH(ctx context.Context, s *sshSource) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	var containers []Container
+	err := m.breakers.Get(s.healthName()).Do(ctx, func() (err error) {
+		containers, err = s.ListContainers(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	b, err := m.inventory.Set(&NodeInventory{
+		Node:       s.name,
+		Timestamp:  now,
+		Containers: retainExited(s.exitedSince, containers, now, m.cfg.ExitedRetention),
+		Edge:       m.cfg.EdgeMode,
+	})
+	if err != nil {
+		return err
+	}
+	m.channel.Broadcast(b)
+	return nil
+}
+
+// runSSHCollector periodically collects the inventory of a remote host, with
+// the same degraded mode and backoff as local sources.
+func (m *Manager) runSSHCollector(ctx context.Context, s *sshSource) {
+	for {
+		delay := m.cfg.CollectInterval
+		if m.health.Cordoned(s.healthName()) {
+			level.Debug(m.logger).Log("msg", "Skipping cordoned source", "source", s.healthName())
+		} else if err := m.collectSSH(ctx, s); err != nil {
+			failures := m.health.Failure(s.healthName(), err)
+			delay = retryBackoff(failures, m.cfg.CollectInterval)
+			level.Warn(m.logger).Log("msg", "Unable to list containers", "source", s.healthName(), "error", err, "failures", failures, "retry_in", delay)
+			if err := m.markNodeDegraded(s.name); err != nil {
+				level.Warn(m.logger).Log("msg", "Unable to flag inventory as degraded", "source", s.healthName(), "error", err)
+			}
+		} else {
+			m.health.Success(s.healthName())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package containerslist
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewSSHSources(t *testing.T) {
+	dir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile, knownHosts := filepath.Join(dir, "id_ed25519"), filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(knownHosts, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := newSSHSources("legacy=ops@10.0.0.7, edge=root@edge.example.com:2222", RuntimeDocker, keyFile, knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sources {
+		got = append(got, s.healthName()+" "+s.config.User+"@"+s.addr)
+	}
+	if want := []string{"ssh:legacy ops@10.0.0.7:22", "ssh:edge root@edge.example.com:2222"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sources = %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		name, targets, runtime, keyFile, wantErr string
+	}{
+		{name: "no key", targets: "a=ops@host", runtime: RuntimeDocker, wantErr: "are required"},
+		{name: "invalid runtime", targets: "a=ops@host", runtime: "podman", keyFile: keyFile, wantErr: "invalid SSH runtime"},
+		{name: "missing key", targets: "a=ops@host", runtime: RuntimeDocker, keyFile: filepath.Join(dir, "missing"), wantErr: "unable to read SSH key"},
+		{name: "no user", targets: "a=host", runtime: RuntimeDocker, keyFile: keyFile, wantErr: "invalid SSH target"},
+		{name: "no name", targets: "ops@host", runtime: RuntimeCRI, keyFile: keyFile, wantErr: "invalid SSH target"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newSSHSources(tc.targets, tc.runtime, tc.keyFile, knownHosts)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("newSSHSources = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+	if sources, err := newSSHSources("", "", "", ""); err != nil || sources != nil {
+		t.Errorf("newSSHSources without targets = %v, %v, want none", sources, err)
+	}
+}
+
+func TestParseDockerPs(t *testing.T) {
+	out := `{"ID":"c1","Names":"web","Image":"nginx:1.25","State":"running","Status":"Up 2 hours","CreatedAt":"2024-01-01 12:00:00 +0100 CET","Labels":"app=web,team=shop","Ports":"0.0.0.0:8080->80/tcp, :::8080->80/tcp, 443/tcp"}
+
+{"ID":"c2","Names":"job,alias","Image":"busybox","State":"exited","Status":"Exited (0) 1 hour ago","CreatedAt":"invalid","Labels":"","Ports":""}
+`
+	got, err := parseDockerPs([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Container{
+		{
+			ID: "c1", Name: "web", Image: "nginx:1.25", State: StateRunning, Status: "Up 2 hours",
+			Created: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+			Labels:  map[string]string{"app": "web", "team": "shop"},
+			Ports: []Port{
+				{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+				{HostIP: "::", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			},
+		},
+		{ID: "c2", Name: "job", Image: "busybox", State: "exited", Status: "Exited (0) 1 hour ago"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("containers = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseDockerPs([]byte("not json\n")); err == nil {
+		t.Error("parseDockerPs of invalid output succeeded, want an error")
+	}
+}
+
+func TestParseCrictlPs(t *testing.T) {
+	out := `{"containers": [
+		{"id": "c1", "metadata": {"name": "nginx"}, "image": {"image": "docker.io/library/nginx:1.25"}, "imageRef": "sha256:web", "state": "CONTAINER_RUNNING", "createdAt": "1704106800000000000",
+		 "labels": {"io.kubernetes.pod.uid": "u1", "io.kubernetes.pod.name": "web-0", "io.kubernetes.pod.namespace": "shop", "io.kubernetes.container.name": "nginx"}},
+		{"id": "c2", "metadata": {"name": "init"}, "image": {"image": "busybox"}, "state": "CONTAINER_EXITED"}
+	]}`
+	got, err := parseCrictlPs([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d containers, want 2", len(got))
+	}
+	if c := got[0]; c.State != StateRunning || c.ImageID != "sha256:web" || !c.Created.Equal(time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)) || c.Pod == nil || c.Pod.Name != "web-0" {
+		t.Errorf("container = %+v, want running in pod web-0", c)
+	}
+	if c := got[1]; c.State != "exited" || c.Pod != nil || !c.Created.IsZero() {
+		t.Errorf("container = %+v, want exited without pod", c)
+	}
+
+	if _, err := parseCrictlPs([]byte("[]")); err == nil {
+		t.Error("parseCrictlPs of invalid output succeeded, want an error")
+	}
+}
@@ -0,0 +1,109 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// stableContainer is a container without its volatile fields, such as its
+// human-readable status, for infrastructure-as-code tools to diff.
+type stableContainer struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Image      string            `json:"image"`
+	ImageID    string            `json:"imageID"`
+	State      string            `json:"state"`
+	Created    time.Time         `json:"created"`
+	FinishedAt *time.Time        `json:"finishedAt"`
+	Labels     map[string]string `json:"labels"`
+	Devices    []Device          `json:"devices"`
+	GPUs       []string          `json:"gpus"`
+	Ports      []Port            `json:"ports"`
+	Pod        *Pod              `json:"pod"`
+
+	Annotations map[string]AnnotationValue `json:"annotations"`
+}
+
+// stableNode is the inventory of a node without its volatile fields.
+type stableNode struct {
+	Node            string            `json:"node"`
+	Degraded        bool              `json:"degraded"`
+	Truncated       bool              `json:"truncated"`
+	TotalContainers int               `json:"totalContainers"`
+	ImageCounts     map[string]int    `json:"imageCounts"`
+	Containers      []stableContainer `json:"containers"`
+}
+
+// stableInventory normalizes the inventories of the nodes: containers and
+// their attributes are sorted and every field is always present. Its JSON
+// encoding has sorted keys.
+func stableInventory(nodes []*NodeInventory) (json.RawMessage, error) {
+	res := make([]stableNode, 0, len(nodes))
+	for _, n := range nodes {
+		sn := stableNode{
+			Node:            n.Node,
+			Degraded:        n.Degraded,
+			Truncated:       n.Truncated,
+			TotalContainers: n.TotalContainers,
+			ImageCounts:     map[string]int{},
+			Containers:      make([]stableContainer, 0, len(n.Containers)),
+		}
+		for image, count := range n.ImageCounts {
+			sn.ImageCounts[image] = count
+		}
+		for _, c := range n.Containers {
+			sc := stableContainer{
+				ID:         c.ID,
+				Name:       c.Name,
+				Image:      c.Image,
+				ImageID:    c.ImageID,
+				State:      c.State,
+				Created:    c.Created,
+				FinishedAt: c.FinishedAt,
+				Labels:     map[string]string{},
+				Devices:    append([]Device{}, c.Devices...),
+				GPUs:       append([]string{}, c.GPUs...),
+				Ports:      append([]Port{}, c.Ports...),
+				Pod:        c.Pod,
+			}
+			for k, v := range c.Labels {
+				sc.Labels[k] = v
+			}
+			sc.Annotations = map[string]AnnotationValue{}
+			for k, v := range c.Annotations {
+				sc.Annotations[k] = v
+			}
+			sort.Slice(sc.Devices, func(i, j int) bool {
+				return sc.Devices[i].HostPath < sc.Devices[j].HostPath
+			})
+			sort.Strings(sc.GPUs)
+			sort.Slice(sc.Ports, func(i, j int) bool {
+				a, b := sc.Ports[i], sc.Ports[j]
+				if a.HostPort != b.HostPort {
+					return a.HostPort < b.HostPort
+				}
+				if a.Protocol != b.Protocol {
+					return a.Protocol < b.Protocol
+				}
+				return a.HostIP < b.HostIP
+			})
+			sn.Containers = append(sn.Containers, sc)
+		}
+		sort.Slice(sn.Containers, func(i, j int) bool {
+			return sn.Containers[i].ID < sn.Containers[j].ID
+		})
+		res = append(res, sn)
+	}
+
+	// Encoding a generic value sorts the keys of the objects.
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,54 @@
+package containerslist
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStableInventory(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	nodes := func(reversed bool) []*NodeInventory {
+		containers := []Container{
+			{ID: "c2", Name: "job", State: "exited", Status: "Exited (0) 1 hour ago", Created: created},
+			{ID: "c1", Name: "web", State: StateRunning, Status: "Up 2 hours", Created: created,
+				Labels: map[string]string{"team": "shop", "app": "web"},
+				GPUs:   []string{"GPU-b", "GPU-a"},
+				Ports:  []Port{{HostPort: 8443, ContainerPort: 443, Protocol: "tcp"}, {HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+			},
+		}
+		if reversed {
+			containers[0], containers[1] = containers[1], containers[0]
+			containers[0].Status = "Up 3 hours"
+			containers[0].GPUs = []string{"GPU-a", "GPU-b"}
+		}
+		return []*NodeInventory{{Node: "a", Timestamp: time.Now(), TotalContainers: 2, Containers: containers}}
+	}
+
+	a, err := stableInventory(nodes(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := stableInventory(nodes(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("outputs differ:\n%s\n%s", a, b)
+	}
+
+	got := string(a)
+	for _, want := range []string{
+		`"containers":[{"annotations":{},"created":"2024-01-01T12:00:00Z","devices":[],"finishedAt":null,"gpus":["GPU-a","GPU-b"],"id":"c1"`,
+		`"labels":{"app":"web","team":"shop"}`,
+		`"ports":[{"containerPort":80`,
+		`{"annotations":{},"created":"2024-01-01T12:00:00Z","devices":[],"finishedAt":null,"gpus":[],"id":"c2","image":"","imageID":"","labels":{}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %s does not contain %s", got, want)
+		}
+	}
+	if strings.Contains(got, "Up 2 hours") || strings.Contains(got, "timestamp") {
+		t.Errorf("output %s contains volatile fields", got)
+	}
+}
@@ -0,0 +1,13 @@
+package containerslist
+
+import "syscall"
+
+// diskSpace returns the total and available space of the filesystem of a
+// path, in bytes.
+func diskSpace(path string) (total, free uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Blocks * uint64(st.Bsize), st.Bavail * uint64(st.Bsize), nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package containerslist
+
+import "errors"
+
+// diskSpace returns the total and available space of the filesystem of a
+// path, in bytes.
+func diskSpace(path string) (total, free uint64, err error) {
+	return 0, 0, errors.New("disk usage is only sampled on Linux")
+}
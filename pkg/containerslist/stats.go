@@ -0,0 +1,217 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+const topTpl = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>containerlist - top</title>
+  </head>
+  <body>
+    {{ template "nav" }}
+    <table>
+      <tr><th>Node</th><th>Container</th><th>Memory (MB)</th><th>Memory limit (MB)</th><th>CPU (%)</th></tr>
+    {{ range . }}
+      <tr><td>{{ .Node }}</td><td>{{ .Name }}</td><td>{{ printf "%.1f" (megabytes .MemoryBytes) }}</td><td>{{ if .MemoryLimitBytes }}{{ printf "%.1f" (megabytes .MemoryLimitBytes) }}{{ end }}</td><td>{{ printf "%.1f" .CPUPercent }}</td></tr>
+    {{ end }}
+    </table>
+  </body>
+</html>
+`
+
+// ContainerUsage is the latest resource usage sample of a running container.
+type ContainerUsage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// CPUPercent is the CPU usage since the previous sample, where 100% is
+	// one full CPU.
+	CPUPercent float64 `json:"cpuPercent"`
+	// MemoryBytes is the memory usage without the inactive page cache, as
+	// reported by docker stats.
+	MemoryBytes      int64 `json:"memoryBytes"`
+	MemoryLimitBytes int64 `json:"memoryLimitBytes,omitempty"`
+}
+
+// containerStats are the fields of the Docker stats of a container used to
+// compute its usage.
+type containerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemCPUUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs     uint64 `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage int64            `json:"usage"`
+		Limit int64            `json:"limit"`
+		Stats map[string]int64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+// ContainerStats returns a single stats sample of a container.
+func (c *dockerClient) ContainerStats(ctx context.Context, id string) (containerStats, error) {
+	var stats containerStats
+	err := c.get(ctx, "/containers/"+id+"/stats?stream=false&one-shot=true", &stats)
+	return stats, err
+}
+
+// cpuSample is the cumulated CPU usage of a container and of the host when
+// it was sampled, in nanoseconds.
+type cpuSample struct {
+	container, system uint64
+}
+
+// usage computes the usage of a container from its stats, and its CPU usage
+// since the previous sample.
+func (st containerStats) usage(prev cpuSample, hasPrev bool) ContainerUsage {
+	var u ContainerUsage
+	mem := st.MemoryStats
+	u.MemoryBytes = mem.Usage
+	// Same as docker stats: the inactive file cache can be reclaimed.
+	if v, ok := mem.Stats["inactive_file"]; ok && v < u.MemoryBytes {
+		u.MemoryBytes -= v
+	} else if v, ok := mem.Stats["total_inactive_file"]; ok && v < u.MemoryBytes {
+		u.MemoryBytes -= v
+	}
+	// The limit is the memory of the host when the container is unlimited.
+	u.MemoryLimitBytes = mem.Limit
+
+	cpu := st.CPUStats
+	if hasPrev && cpu.SystemCPUUsage > prev.system && cpu.CPUUsage.TotalUsage >= prev.container {
+		cpus := cpu.OnlineCPUs
+		if cpus == 0 {
+			cpus = 1
+		}
+		u.CPUPercent = 100 * float64(cpu.CPUUsage.TotalUsage-prev.container) / float64(cpu.SystemCPUUsage-prev.system) * float64(cpus)
+	}
+	return u
+}
+
+// runUsageCollector periodically samples the resource usage of the running
+// containers of a source and broadcasts the latest samples to the cluster.
+func (m *Manager) runUsageCollector(ctx context.Context, s *dockerSource) {
+	prev := map[string]cpuSample{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.cfg.ContainerUsageInterval):
+		}
+
+		n, ok := m.inventory.Get(s.node)
+		if !ok || m.health.Cordoned(s.healthName()) {
+			continue
+		}
+		samples := map[string]cpuSample{}
+		usages := []ContainerUsage{}
+		for _, c := range n.Containers {
+			if c.State != StateRunning {
+				continue
+			}
+			st, err := s.client.ContainerStats(ctx, c.ID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				level.Debug(m.logger).Log("msg", "Unable to sample container usage", "source", s.healthName(), "container", c.Name, "error", err)
+				continue
+			}
+			p, hasPrev := prev[c.ID]
+			u := st.usage(p, hasPrev)
+			u.ID, u.Name = c.ID, c.Name
+			usages = append(usages, u)
+			samples[c.ID] = cpuSample{container: st.CPUStats.CPUUsage.TotalUsage, system: st.CPUStats.SystemCPUUsage}
+		}
+		prev = samples
+
+		b, err := m.containerUsage.Set(&NodeResources[ContainerUsage]{Node: s.node, Timestamp: time.Now().UTC(), Items: usages})
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to set container usage", "source", s.healthName(), "error", err)
+			continue
+		}
+		m.containerUsageChannel.Broadcast(b)
+	}
+}
+
+// NodeContainerUsage is the usage of a container, along with its node.
+type NodeContainerUsage struct {
+	Node string `json:"node"`
+	ContainerUsage
+}
+
+// topContainers returns the containers of the cluster using the most memory,
+// or CPU.
+func (m *Manager) topContainers(by string, limit int) []NodeContainerUsage {
+	res := []NodeContainerUsage{}
+	for _, n := range m.containerUsage.Nodes() {
+		for _, u := range n.Items {
+			res = append(res, NodeContainerUsage{Node: n.Node, ContainerUsage: u})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if by == "cpu" {
+			return res[i].CPUPercent > res[j].CPUPercent
+		}
+		return res[i].MemoryBytes > res[j].MemoryBytes
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// topParams parses the by and limit parameters of the top containers.
+func topParams(r *http.Request) (string, int, error) {
+	by := r.URL.Query().Get("by")
+	switch by {
+	case "":
+		by = "memory"
+	case "memory", "cpu":
+	default:
+		return "", 0, fmt.Errorf("invalid by %q: must be one of memory, cpu", by)
+	}
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return "", 0, fmt.Errorf("invalid limit %q: must be a positive integer", s)
+		}
+	}
+	return by, limit, nil
+}
+
+func (m *Manager) apiTop() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		by, limit, err := topParams(r)
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "%v", err))
+			return
+		}
+		writeJSON(w, m.topContainers(by, limit))
+	})
+}
+
+func (m *Manager) topPage(t *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		by, limit, err := topParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := localize(t, r).ExecuteTemplate(w, "top", m.topContainers(by, limit)); err != nil {
+			fmt.Fprintf(w, "Error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContainerStatsUsage(t *testing.T) {
+	c := newTestDockerClient(t, map[string]string{
+		"/containers/a1/stats": `{
+			"cpu_stats": {"cpu_usage": {"total_usage": 3000}, "system_cpu_usage": 20000, "online_cpus": 4},
+			"memory_stats": {"usage": 1000, "limit": 4000, "stats": {"inactive_file": 200}}
+		}`,
+	})
+	st, err := c.ContainerStats(context.Background(), "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a previous sample, the CPU usage is unknown.
+	if got, want := st.usage(cpuSample{}, false), (ContainerUsage{MemoryBytes: 800, MemoryLimitBytes: 4000}); got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+	// 1000ns of the 10000ns of the 4 CPUs of the host since the previous
+	// sample is 40% of one CPU.
+	if got := st.usage(cpuSample{container: 2000, system: 10000}, true); got.CPUPercent != 40 {
+		t.Errorf("CPU = %v%%, want 40%%", got.CPUPercent)
+	}
+	// The counters went back, e.g. after a restart of the container.
+	if got := st.usage(cpuSample{container: 5000, system: 10000}, true); got.CPUPercent != 0 {
+		t.Errorf("CPU = %v%%, want 0%% after a reset", got.CPUPercent)
+	}
+
+	// cgroup v1 reports the total inactive file cache.
+	st.MemoryStats.Stats = map[string]int64{"total_inactive_file": 300}
+	if got := st.usage(cpuSample{}, false); got.MemoryBytes != 700 {
+		t.Errorf("memory = %d bytes, want 700", got.MemoryBytes)
+	}
+	// A cache larger than the usage is not subtracted.
+	st.MemoryStats.Stats = map[string]int64{"inactive_file": 2000}
+	if got := st.usage(cpuSample{}, false); got.MemoryBytes != 1000 {
+		t.Errorf("memory = %d bytes, want 1000", got.MemoryBytes)
+	}
+
+	if _, err := c.ContainerStats(context.Background(), "missing"); err == nil {
+		t.Error("stats of a missing container succeeded, want an error")
+	}
+}
+
+func TestTopContainers(t *testing.T) {
+	m := &Manager{containerUsage: newResourceState[ContainerUsage]()}
+	now := time.Now().UTC()
+	for _, n := range []*NodeResources[ContainerUsage]{
+		{Node: "a", Timestamp: now, Items: []ContainerUsage{
+			{ID: "a1", Name: "web", CPUPercent: 5, MemoryBytes: 300},
+			{ID: "a2", Name: "db", CPUPercent: 50, MemoryBytes: 900},
+		}},
+		{Node: "b", Timestamp: now, Items: []ContainerUsage{
+			{ID: "b1", Name: "worker", CPUPercent: 150, MemoryBytes: 500},
+		}},
+	} {
+		if _, err := m.containerUsage.Set(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(top []NodeContainerUsage) []string {
+		var res []string
+		for _, u := range top {
+			res = append(res, u.Node+"/"+u.Name)
+		}
+		return res
+	}
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"a/db", "b/worker", "a/web"}},
+		{query: "?by=cpu&limit=2", want: []string{"b/worker", "a/db"}},
+		{query: "?by=memory&limit=1", want: []string{"a/db"}},
+	} {
+		rec := httptest.NewRecorder()
+		m.apiTop().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/top"+tc.query, nil))
+		var top []NodeContainerUsage
+		if err := json.NewDecoder(rec.Body).Decode(&top); err != nil {
+			t.Fatal(err)
+		}
+		if got := names(top); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("top%s = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+
+	for _, query := range []string{"?by=disk", "?limit=0", "?limit=many"} {
+		rec := httptest.NewRecorder()
+		m.apiTop().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/top"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status of top%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Formats of the statsd lines.
+const (
+	statsdFormatDogStatsD = "dogstatsd"
+	statsdFormatStatsD    = "statsd"
+)
+
+const (
+	defaultStatsdPrefix        = "containerslist."
+	defaultStatsdFlushInterval = 10 * time.Second
+	// defaultStatsdMetrics are the metrics mirrored by default: the members
+	// of the cluster, the containers of the nodes and the gossip errors.
+	defaultStatsdMetrics = "alertmanager_cluster_members,alertmanager_cluster_failed_peers,containerslist_node_containers,containerslist_gossip_rejected_updates_total,containerslist_gossip_blocked_updates_total"
+	// statsdMaxPacketSize keeps the datagrams within the usual MTU.
+	statsdMaxPacketSize = 1432
+)
+
+// statsdEmitter mirrors metrics of the registry to a statsd or DogStatsD
+// server, for the shops without Prometheus. Gauges are sent as gauges, and
+// counters as counters of their increase since the previous flush; the
+// histograms and summaries send the increase of their count and sum.
+type statsdEmitter struct {
+	conn     net.Conn
+	gatherer prometheus.Gatherer
+	format   string
+	prefix   string
+	tags     []string
+	metrics  []string
+	interval time.Duration
+	logger   log.Logger
+
+	// previous are the values of the counters at the previous flush, by
+	// line without value.
+	previous map[string]float64
+}
+
+func newStatsdEmitter(addr, format, prefix, tags, metrics string, interval time.Duration, gatherer prometheus.Gatherer, logger log.Logger) (*statsdEmitter, error) {
+	if format != statsdFormatDogStatsD && format != statsdFormatStatsD {
+		return nil, fmt.Errorf("invalid statsd format %q: must be %s or %s", format, statsdFormatDogStatsD, statsdFormatStatsD)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid statsd flush interval %s: must be positive", interval)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach statsd at %s: %w", addr, err)
+	}
+	e := &statsdEmitter{
+		conn:     conn,
+		gatherer: gatherer,
+		format:   format,
+		prefix:   prefix,
+		metrics:  statsdList(metrics),
+		interval: interval,
+		logger:   logger,
+		previous: map[string]float64{},
+	}
+	for _, tag := range statsdList(tags) {
+		if format == statsdFormatStatsD {
+			return nil, fmt.Errorf("statsd tags require the %s format", statsdFormatDogStatsD)
+		}
+		e.tags = append(e.tags, tag)
+	}
+	return e, nil
+}
+
+// statsdList parses a comma-separated list.
+func statsdList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Run flushes the metrics at each interval until ctx is done.
+func (e *statsdEmitter) Run(ctx context.Context) {
+	defer e.conn.Close()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.flush(); err != nil {
+				level.Warn(e.logger).Log("msg", "Unable to send metrics to statsd", "error", err)
+			}
+		}
+	}
+}
+
+// statsdName returns the statsd name of a Prometheus metric: its name
+// without its namespace, after the prefix.
+func (e *statsdEmitter) statsdName(name string) string {
+	for _, ns := range []string{"containerslist_", "alertmanager_"} {
+		name = strings.TrimPrefix(name, ns)
+	}
+	return e.prefix + name
+}
+
+// series returns the name and tags of a series, the labels being tags in the
+// DogStatsD format and suffixes of the name otherwise.
+func (e *statsdEmitter) series(name string, labels []*dto.LabelPair) string {
+	tags := append([]string{}, e.tags...)
+	for _, l := range labels {
+		if e.format == statsdFormatDogStatsD {
+			tags = append(tags, l.GetName()+":"+statsdSanitize(l.GetValue()))
+		} else {
+			name += "." + statsdSanitize(l.GetValue())
+		}
+	}
+	if len(tags) == 0 {
+		return name
+	}
+	return name + "|#" + strings.Join(tags, ",")
+}
+
+// statsdSanitize replaces the characters of a value separating the fields of
+// the statsd lines.
+func statsdSanitize(v string) string {
+	return strings.NewReplacer(":", "_", "|", "_", ",", "_", "@", "_", "#", "_", " ", "_", "\n", "_").Replace(v)
+}
+
+// line returns a statsd line, its type inserted before the tags.
+func statsdLine(series string, value float64, typ string) string {
+	name, tags, _ := strings.Cut(series, "|")
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + typ
+	if tags != "" {
+		line += "|" + tags
+	}
+	return line
+}
+
+// counter returns the line of the increase of a counter since the previous
+// flush, none on the first one.
+func (e *statsdEmitter) counter(series string, value float64, seen map[string]bool) (string, bool) {
+	seen[series] = true
+	prev, ok := e.previous[series]
+	e.previous[series] = value
+	// The counters restart from zero with the series.
+	if !ok || value < prev {
+		return "", false
+	}
+	return statsdLine(series, value-prev, "c"), true
+}
+
+// lines returns the statsd lines of the mirrored metrics.
+func (e *statsdEmitter) lines() ([]string, error) {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	seen := map[string]bool{}
+	for _, f := range families {
+		if !contains(e.metrics, f.GetName()) {
+			continue
+		}
+		name := e.statsdName(f.GetName())
+		for _, metric := range f.GetMetric() {
+			series := e.series(name, metric.GetLabel())
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				lines = append(lines, statsdLine(series, metric.GetGauge().GetValue(), "g"))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, statsdLine(series, metric.GetUntyped().GetValue(), "g"))
+			case dto.MetricType_COUNTER:
+				if line, ok := e.counter(series, metric.GetCounter().GetValue(), seen); ok {
+					lines = append(lines, line)
+				}
+			case dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+				count, sum := float64(metric.GetHistogram().GetSampleCount()), metric.GetHistogram().GetSampleSum()
+				if f.GetType() == dto.MetricType_SUMMARY {
+					count, sum = float64(metric.GetSummary().GetSampleCount()), metric.GetSummary().GetSampleSum()
+				}
+				countName, sumName := e.series(name+".count", metric.GetLabel()), e.series(name+".sum", metric.GetLabel())
+				if line, ok := e.counter(countName, count, seen); ok {
+					lines = append(lines, line)
+				}
+				if line, ok := e.counter(sumName, sum, seen); ok && !math.IsNaN(sum) {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	// The counters of the series which disappeared are forgotten.
+	for series := range e.previous {
+		if !seen[series] {
+			delete(e.previous, series)
+		}
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+// flush sends the lines of the mirrored metrics, in datagrams of several
+// lines.
+func (e *statsdEmitter) flush() error {
+	lines, err := e.lines()
+	if err != nil {
+		return err
+	}
+	var packet []byte
+	send := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := e.conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdMaxPacketSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	return send()
+}
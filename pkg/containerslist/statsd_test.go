@@ -0,0 +1,90 @@
+package containerslist
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewStatsdEmitter(t *testing.T) {
+	for _, tc := range []struct {
+		format, tags string
+		interval     time.Duration
+	}{
+		{format: "graphite", interval: time.Second},
+		{format: statsdFormatDogStatsD},
+		{format: statsdFormatStatsD, tags: "env:prod", interval: time.Second},
+	} {
+		if _, err := newStatsdEmitter("127.0.0.1:8125", tc.format, "", tc.tags, "", tc.interval, prometheus.NewRegistry(), log.NewNopLogger()); err == nil {
+			t.Errorf("emitter %+v accepted, want an error", tc)
+		}
+	}
+}
+
+func TestStatsdEmitter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	containers := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "containerslist_node_containers", Help: "Test."}, []string{"node"})
+	rejected := prometheus.NewCounter(prometheus.CounterOpts{Name: "containerslist_gossip_rejected_updates_total", Help: "Test."})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "containerslist_test_seconds", Help: "Test."})
+	ignored := prometheus.NewGauge(prometheus.GaugeOpts{Name: "containerslist_ignored", Help: "Test."})
+	reg.MustRegister(containers, rejected, duration, ignored)
+	containers.WithLabelValues("web:1").Set(3)
+	rejected.Add(2)
+	duration.Observe(0.5)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	e, err := newStatsdEmitter(pc.LocalAddr().String(), statsdFormatDogStatsD, "cl.", "env:prod", "containerslist_node_containers, containerslist_gossip_rejected_updates_total,containerslist_test_seconds", time.Minute, reg, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.conn.Close()
+
+	// The counters are only sent from the second flush, as increases.
+	lines, err := e.lines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cl.node_containers:3|g|#env:prod,node:web_1"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	rejected.Inc()
+	duration.Observe(1.5)
+	if err := e.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, statsdMaxPacketSize)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"cl.gossip_rejected_updates_total:1|c|#env:prod",
+		"cl.node_containers:3|g|#env:prod,node:web_1",
+		"cl.test_seconds.count:1|c|#env:prod",
+		"cl.test_seconds.sum:1.5|c|#env:prod",
+	}
+	if got := strings.Split(string(b[:n]), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("packet = %q, want %q", got, want)
+	}
+
+	// The labels are suffixes of the names in the statsd format.
+	e.format, e.tags = statsdFormatStatsD, nil
+	if lines, err = e.lines(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0] != "cl.node_containers.web_1:3|g" {
+		t.Errorf("statsd lines = %q, want the gauge of web_1", lines)
+	}
+}
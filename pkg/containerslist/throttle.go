@@ -0,0 +1,153 @@
+package containerslist
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// byteLimiter is a token bucket limiting a rate in bytes per second, with a
+// burst of one second worth of bytes.
+type byteLimiter struct {
+	rate float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newByteLimiter(rate int) *byteLimiter {
+	return &byteLimiter{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// reserve takes n bytes from the bucket, returning how long to wait before
+// sending them. Messages larger than the burst are let through once the
+// bucket is full, leaving it in debt.
+func (l *byteLimiter) reserve(n int) time.Duration {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// throttle holds the bandwidth limits of the gossip and of the full-state
+// transfers.
+type throttle struct {
+	broadcast *byteLimiter
+	stateRate int
+
+	mtx   sync.Mutex
+	peers map[string]*byteLimiter
+
+	throttled *prometheus.CounterVec
+}
+
+func newThrottle(broadcastRate, stateRate int, reg prometheus.Registerer) *throttle {
+	t := &throttle{
+		stateRate: stateRate,
+		peers:     map[string]*byteLimiter{},
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_throttled_seconds_total",
+			Help: "Time spent waiting for the bandwidth limits, by kind of traffic.",
+		}, []string{"kind"}),
+	}
+	if broadcastRate > 0 {
+		t.broadcast = newByteLimiter(broadcastRate)
+	}
+	reg.MustRegister(t.throttled)
+	return t
+}
+
+func (t *throttle) wait(l *byteLimiter, n int, kind string) {
+	if d := l.reserve(n); d > 0 {
+		t.throttled.WithLabelValues(kind).Add(d.Seconds())
+		time.Sleep(d)
+	}
+}
+
+// throttledChannel limits the bytes broadcast to the cluster. All the
+// channels share the same limit; broadcasting blocks while it is exceeded.
+type throttledChannel struct {
+	cluster.ClusterChannel
+	throttle *throttle
+}
+
+// Broadcast implements cluster.ClusterChannel.
+func (c throttledChannel) Broadcast(b []byte) {
+	c.throttle.wait(c.throttle.broadcast, len(b), "broadcast")
+	c.ClusterChannel.Broadcast(b)
+}
+
+// channel wraps a channel with the broadcast limit, when configured.
+func (t *throttle) channel(c cluster.ClusterChannel) cluster.ClusterChannel {
+	if t.broadcast == nil {
+		return c
+	}
+	return throttledChannel{ClusterChannel: c, throttle: t}
+}
+
+// throttledWriter limits the bytes written to a peer.
+type throttledWriter struct {
+	http.ResponseWriter
+	throttle *throttle
+	limiter  *byteLimiter
+}
+
+// chunkSize is the size of the writes of a throttled response, so that it
+// is sent at a steady pace.
+const chunkSize = 16 * 1024
+
+// Write implements http.ResponseWriter.
+func (w throttledWriter) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		n := len(b)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		w.throttle.wait(w.limiter, n, "state_transfer")
+		n, err := w.ResponseWriter.Write(b[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// limitStateTransfer limits the bandwidth of the full-state transfers to
+// each peer, identified by its IP address.
+func (t *throttle) limitStateTransfer(h http.Handler) http.Handler {
+	if t.stateRate <= 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			peer = r.RemoteAddr
+		}
+		t.mtx.Lock()
+		l, ok := t.peers[peer]
+		if !ok {
+			l = newByteLimiter(t.stateRate)
+			t.peers[peer] = l
+		}
+		t.mtx.Unlock()
+		h.ServeHTTP(throttledWriter{ResponseWriter: w, throttle: t, limiter: l}, r)
+	})
+}
@@ -0,0 +1,90 @@
+package containerslist
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestByteLimiter(t *testing.T) {
+	l := newByteLimiter(1000)
+	if d := l.reserve(600); d != 0 {
+		t.Errorf("wait within the burst = %v, want none", d)
+	}
+	// 200 bytes over the bucket take about 200ms to refill.
+	if d := l.reserve(600); d < 150*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("wait = %v, want about 200ms", d)
+	}
+	// A message larger than the burst goes into debt.
+	l = newByteLimiter(1000)
+	if d := l.reserve(3000); d < 1900*time.Millisecond || d > 2*time.Second {
+		t.Errorf("wait = %v, want about 2s", d)
+	}
+}
+
+func TestThrottledChannel(t *testing.T) {
+	ch := &testChannel{}
+	if c := newThrottle(0, 0, prometheus.NewRegistry()).channel(ch); c != ch {
+		t.Error("channel throttled without limit")
+	}
+
+	th := newThrottle(10000, 0, prometheus.NewRegistry())
+	c := th.channel(ch)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Broadcast(make([]byte, 5000))
+	}
+	// The first 10000 bytes are the burst; the last 5000 wait 500ms.
+	if d := time.Since(start); d < 400*time.Millisecond || d > 2*time.Second {
+		t.Errorf("broadcasts took %v, want about 500ms", d)
+	}
+	if len(ch.msgs) != 3 {
+		t.Errorf("%d messages broadcast, want 3", len(ch.msgs))
+	}
+	if got := testutil.ToFloat64(th.throttled.WithLabelValues("broadcast")); got < 0.4 {
+		t.Errorf("throttled time = %vs, want about 0.5s", got)
+	}
+}
+
+func TestLimitStateTransfer(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3*chunkSize)
+	var throttled bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, throttled = w.(throttledWriter)
+		w.Write(body)
+	})
+	newThrottle(0, 0, prometheus.NewRegistry()).limitStateTransfer(h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/internal/state", nil))
+	if throttled {
+		t.Error("state transfer throttled without limit")
+	}
+
+	th := newThrottle(0, 2*chunkSize, prometheus.NewRegistry())
+	limited := th.limitStateTransfer(h)
+	transfer := func(remoteAddr string) time.Duration {
+		r := httptest.NewRequest(http.MethodGet, "/internal/state", nil)
+		r.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		limited.ServeHTTP(rec, r)
+		if !bytes.Equal(rec.Body.Bytes(), body) {
+			t.Errorf("%d bytes transferred, want %d", rec.Body.Len(), len(body))
+		}
+		return time.Since(start)
+	}
+	// The first 2 chunks are the burst, the third one waits 500ms.
+	if d := transfer("10.0.0.1:4000"); d < 400*time.Millisecond || d > 2*time.Second {
+		t.Errorf("transfer took %v, want about 500ms", d)
+	}
+	// Each peer has its own limit.
+	if d := transfer("10.0.0.2:4000"); d > 2*time.Second {
+		t.Errorf("transfer to another peer took %v", d)
+	}
+	if len(th.peers) != 2 {
+		t.Errorf("%d limited peers, want 2", len(th.peers))
+	}
+}
@@ -0,0 +1,353 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+	"golang.org/x/term"
+)
+
+// topSortKeys are the columns by which the containers of the top command
+// can be sorted, cycled through with the s key.
+var topSortKeys = []string{"node", "name", "image", "state", "age"}
+
+// topModel is the state displayed by the top command: the containers of the
+// nodes, kept up to date by the container events, and the peers, refreshed
+// periodically.
+type topModel struct {
+	server string
+	nodes  stringSet
+
+	mtx        sync.Mutex
+	containers map[string]map[string]client.Container
+	peers      []client.PeerInfo
+	err        error
+	updated    time.Time
+
+	sortKey   int
+	reverse   bool
+	filter    string
+	editing   bool
+	input     string
+	hidePeers bool
+}
+
+// load replaces the containers and the peers with the ones listed by the
+// server.
+func (m *topModel) load(ctx context.Context, c *client.Client, state string) {
+	inventories, err := c.Containers(ctx, state)
+	var peers []client.PeerInfo
+	if err == nil {
+		peers, err = c.Peers(ctx)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.err = err
+	if err != nil {
+		return
+	}
+	m.containers = map[string]map[string]client.Container{}
+	for _, n := range inventories {
+		if !m.selected(n.Node) {
+			continue
+		}
+		m.containers[n.Node] = map[string]client.Container{}
+		for _, ctr := range n.Containers {
+			m.containers[n.Node][ctr.ID] = ctr
+		}
+	}
+	m.peers = peers
+	m.updated = time.Now()
+}
+
+func (m *topModel) selected(node string) bool {
+	return len(m.nodes) == 0 || m.nodes[node]
+}
+
+// apply applies a container event.
+func (m *topModel) apply(e client.ContainerEvent) {
+	if !m.selected(e.Node) {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.containers == nil {
+		m.containers = map[string]map[string]client.Container{}
+	}
+	if m.containers[e.Node] == nil {
+		m.containers[e.Node] = map[string]client.Container{}
+	}
+	switch e.Type {
+	case EventAppeared:
+		m.containers[e.Node][e.Container.ID] = e.Container
+	case EventDisappeared:
+		delete(m.containers[e.Node], e.Container.ID)
+	}
+	m.updated = time.Now()
+}
+
+// key handles a key press, and reports whether to quit.
+func (m *topModel) key(k byte) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.editing {
+		switch {
+		case k == '\r' || k == '\n':
+			m.filter, m.editing = m.input, false
+		case k == 27: // Escape
+			m.editing = false
+		case k == 127 || k == 8: // Backspace
+			if r := []rune(m.input); len(r) > 0 {
+				m.input = string(r[:len(r)-1])
+			}
+		case k >= ' ' && k < 127:
+			m.input += string(k)
+		}
+		return false
+	}
+	switch k {
+	case 'q', 3: // Ctrl-C
+		return true
+	case 's':
+		m.sortKey = (m.sortKey + 1) % len(topSortKeys)
+	case 'r':
+		m.reverse = !m.reverse
+	case '/':
+		m.editing, m.input = true, m.filter
+	case 27:
+		m.filter = ""
+	case 'p':
+		m.hidePeers = !m.hidePeers
+	}
+	return false
+}
+
+type topRow struct {
+	node string
+	c    client.Container
+}
+
+// render draws the screen, truncated to its width and height.
+func (m *topModel) render(width, height int) string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var rows []topRow
+	total := 0
+	for node, containers := range m.containers {
+		for _, c := range containers {
+			total++
+			if m.filter == "" || strings.Contains(node, m.filter) || strings.Contains(c.Name, m.filter) || strings.Contains(c.Image, m.filter) {
+				rows = append(rows, topRow{node: node, c: c})
+			}
+		}
+	}
+	key := topSortKeys[m.sortKey]
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		var less, equal bool
+		switch key {
+		case "name":
+			less, equal = a.c.Name < b.c.Name, a.c.Name == b.c.Name
+		case "image":
+			less, equal = a.c.Image < b.c.Image, a.c.Image == b.c.Image
+		case "state":
+			less, equal = a.c.State < b.c.State, a.c.State == b.c.State
+		case "age":
+			// The youngest first.
+			less, equal = a.c.Created.After(b.c.Created), a.c.Created.Equal(b.c.Created)
+		default:
+			less, equal = a.node < b.node, a.node == b.node
+		}
+		if equal {
+			return a.node+"/"+a.c.Name < b.node+"/"+b.c.Name
+		}
+		return less != m.reverse
+	})
+
+	var lines []string
+	status := fmt.Sprintf("%s - %d peers, %d containers", m.server, len(m.peers), total)
+	if !m.updated.IsZero() {
+		status += " - updated " + m.updated.Format("15:04:05")
+	}
+	lines = append(lines, "\x1b[1m"+status+"\x1b[0m")
+	if m.err != nil {
+		lines = append(lines, "\x1b[31mError: "+m.err.Error()+"\x1b[0m")
+	}
+	order := "asc"
+	if m.reverse {
+		order = "desc"
+	}
+	help := fmt.Sprintf("sort: %s %s (s, r)  filter: %s (/, esc)  peers (p)  quit (q)", key, order, orNone(m.filter))
+	if m.editing {
+		help = "filter: " + m.input + "_"
+	}
+	lines = append(lines, help, "")
+
+	if !m.hidePeers {
+		table := &cliTable{headers: []string{"PEER", "NODE", "ADDRESS", "CPU", "MEMORY", "DISK"}}
+		for _, p := range m.peers {
+			cpu, mem, disk := "<none>", "<none>", "<none>"
+			if u := p.Usage; u != nil {
+				cpu = fmt.Sprintf("%.0f%%", u.CPUPercent)
+				mem = fmt.Sprintf("%.0f%%", u.MemoryPercent)
+				disk = fmt.Sprintf("%.0f%%", u.DiskPercent)
+			}
+			table.rows = append(table.rows, []string{p.Name, orNone(p.Node), p.Address, cpu, mem, disk})
+		}
+		lines = append(lines, tableLines(table)...)
+		lines = append(lines, "")
+	}
+	table := &cliTable{headers: []string{"NODE", "NAME", "IMAGE", "STATE", "STATUS", "AGE"}}
+	for _, r := range rows {
+		table.rows = append(table.rows, []string{r.node, r.c.Name, r.c.Image, r.c.State, r.c.Status, since(r.c.Created)})
+	}
+	lines = append(lines, tableLines(table)...)
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i, l := range lines {
+		// The escape sequences of the status lines do not take any width.
+		if r := []rune(l); !strings.Contains(l, "\x1b") && len(r) > width {
+			lines[i] = string(r[:width])
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+func tableLines(t *cliTable) []string {
+	var b bytes.Buffer
+	t.print(&b, true)
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+// runTop shows the peers and the containers of the cluster in the terminal,
+// updated live from the container events: containerslist top [--node
+// node...] [--sort key].
+func runTop(args []string) error {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s top [flags]\n\nKeys: s and r sort, / filters, esc clears the filter, p toggles the peers, q quits.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	var (
+		cf      cliClientFlags
+		model   topModel
+		state   = fs.String("state", "", "State of the containers: running, exited or all; running when empty")
+		sortKey = fs.String("sort", topSortKeys[0], "Initial sort key: "+strings.Join(topSortKeys, ", "))
+		refresh = fs.Duration("refresh", 10*time.Second, "Interval between two full refreshes of the containers and the peers, between which the container events are applied")
+	)
+	cf.register(fs)
+	fs.Var(&model.nodes, "node", "Only show the containers of this node; may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for i, k := range topSortKeys {
+		if k == *sortKey {
+			model.sortKey = i
+			break
+		}
+		if i == len(topSortKeys)-1 {
+			return fmt.Errorf("invalid sort key %q: must be one of %s", *sortKey, strings.Join(topSortKeys, ", "))
+		}
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("top must be run in a terminal")
+	}
+	c, err := cf.client()
+	if err != nil {
+		return err
+	}
+	model.server = c.BaseURL
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, oldState)
+	// Use the alternate screen, without the cursor, restoring both on exit.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				close(keys)
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	go func() {
+		for ctx.Err() == nil {
+			err := c.Events(ctx, func(e client.ContainerEvent) {
+				model.apply(e)
+				notify()
+			})
+			if err != nil && ctx.Err() == nil {
+				model.mtx.Lock()
+				model.err = fmt.Errorf("event stream: %w", err)
+				model.mtx.Unlock()
+				notify()
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	model.load(ctx, c, *state)
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	// Redraw every second, for the ages and the size of the terminal.
+	redraw := time.NewTicker(time.Second)
+	defer redraw.Stop()
+	for {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		fmt.Print("\x1b[H\x1b[2J" + model.render(width, height))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok || model.key(k) {
+				return nil
+			}
+		case <-changed:
+		case <-redraw.C:
+		case <-ticker.C:
+			model.load(ctx, c, *state)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package containerslist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+func TestTopModel(t *testing.T) {
+	m := &topModel{server: "http://localhost:3000", nodes: stringSet{"a": true, "b": true}}
+	now := time.Now()
+	for _, e := range []client.ContainerEvent{
+		{Type: EventAppeared, Node: "a", Container: client.Container{ID: "a1", Name: "web", Image: "nginx", State: "running", Created: now.Add(-time.Hour)}},
+		{Type: EventAppeared, Node: "b", Container: client.Container{ID: "b1", Name: "api", Image: "shop/api", State: "running", Created: now}},
+		{Type: EventAppeared, Node: "a", Container: client.Container{ID: "a2", Name: "job", Image: "busybox", State: "exited", Created: now.Add(-2 * time.Hour)}},
+		{Type: EventAppeared, Node: "c", Container: client.Container{ID: "c1", Name: "db", Image: "postgres"}},
+		{Type: EventDisappeared, Node: "a", Container: client.Container{ID: "a2"}},
+	} {
+		m.apply(e)
+	}
+	m.peers = []client.PeerInfo{{Name: "p1", Node: "a", Address: "10.0.0.1:9094", Usage: &client.NodeUsage{CPUPercent: 12, MemoryPercent: 50, DiskPercent: 80}}}
+
+	containerNames := func(screen string) []string {
+		var names []string
+		lines := strings.Split(screen, "\r\n")
+		for i, l := range lines {
+			if !strings.HasPrefix(l, "NODE ") {
+				continue
+			}
+			for _, row := range lines[i+1:] {
+				names = append(names, strings.Fields(row)[1])
+			}
+		}
+		return names
+	}
+
+	screen := m.render(200, 100)
+	if !strings.Contains(screen, "2 containers") || !strings.Contains(screen, "p1     a      10.0.0.1:9094   12%   50%      80%") {
+		t.Errorf("screen = %q, want the 2 containers of a and b, and the usage of p1", screen)
+	}
+	if got := containerNames(screen); strings.Join(got, ",") != "web,api" {
+		t.Errorf("containers sorted by node = %q", got)
+	}
+
+	for _, tc := range []struct {
+		keys string
+		want string
+	}{
+		{keys: "s", want: "api,web"},
+		{keys: "r", want: "web,api"},
+		// Sorted by age, the youngest first.
+		{keys: "rsss", want: "api,web"},
+		{keys: "/ngi\x7fin\r", want: "web"},
+		{keys: "\x1b", want: "api,web"},
+	} {
+		for _, k := range []byte(tc.keys) {
+			if m.key(k) {
+				t.Fatalf("key %q quit", k)
+			}
+		}
+		if got := containerNames(m.render(200, 100)); strings.Join(got, ",") != tc.want {
+			t.Errorf("containers after %q = %q, want %s", tc.keys, got, tc.want)
+		}
+	}
+
+	m.key('p')
+	if screen := m.render(200, 100); strings.Contains(screen, "PEER") {
+		t.Error("peers shown once hidden")
+	}
+	if screen := m.render(10, 3); len(strings.Split(screen, "\r\n")) != 3 || strings.Split(screen, "\r\n")[1] != "sort: age " {
+		t.Errorf("screen truncated to 10x3 = %q", screen)
+	}
+	if !m.key('q') {
+		t.Error("q did not quit")
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = out
+	err = runCompletion([]string{"zsh"})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(b)
+	// The bash script is embedded in the zsh one, with all the placeholders
+	// replaced.
+	if !strings.Contains(script, "bashcompinit\n_containerslist() {") || strings.Contains(script, "{{") {
+		t.Errorf("script = %s", script)
+	}
+	if !strings.Contains(script, `compgen -W "container containers node nodes peer peers"`) || !strings.Contains(script, `compgen -W "node name image state age"`) {
+		t.Errorf("script = %s, want the resources and the sort keys", script)
+	}
+
+	for _, args := range [][]string{nil, {"powershell"}, {"bash", "zsh"}} {
+		if err := runCompletion(args); err == nil {
+			t.Errorf("completion %q succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	defaultUpdateCheckInterval = 6 * time.Hour
+	// maxUpdateBinarySize bounds the size of the downloaded binaries.
+	maxUpdateBinarySize = 512 << 20
+)
+
+// version is the version of the binary, given to Main by the command, where
+// it is set when building it with -ldflags "-X main.version=v1.2.3".
+var version string
+
+// buildVersion returns the version of the binary: the one set when building
+// it, or else the version of the main module, or dev for the development
+// builds.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	bi, _ := debug.ReadBuildInfo()
+	return moduleVersion(bi)
+}
+
+// moduleVersion returns the version of the main module of a build, or dev.
+func moduleVersion(bi *debug.BuildInfo) string {
+	if bi != nil && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+	return "dev"
+}
+
+// binaryVersion returns the version of a binary, as buildVersion would in
+// it: the version set with -X main.version in its linker flags, or else the
+// version of its main module.
+func binaryVersion(b []byte) (string, error) {
+	bi, err := buildinfo.Read(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	for _, s := range bi.Settings {
+		if s.Key != "-ldflags" {
+			continue
+		}
+		for _, f := range strings.Fields(s.Value) {
+			if v, ok := strings.CutPrefix(strings.TrimPrefix(f, "-X="), "main.version="); ok {
+				return strings.Trim(v, `"'`), nil
+			}
+		}
+	}
+	return moduleVersion(bi), nil
+}
+
+// Release is the latest release published by the release feed.
+type Release struct {
+	Version string `json:"version"`
+	// Notes is the URL of the release notes.
+	Notes    string          `json:"notes,omitempty"`
+	Binaries []ReleaseBinary `json:"binaries"`
+}
+
+// ReleaseBinary is the binary of a release for a platform. Signature is the
+// base64-encoded Ed25519 signature, by one of the keys of
+// -update_trusted_keys, of the manifest of the binary returned by
+// releaseManifest, which binds the binary to its version and platform.
+type ReleaseBinary struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// URL is the URL of the binary, relative to the feed.
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// newerRelease reports whether the version of a release is newer than the
+// current one, with the precedence of semantic versioning: v1.3.0 is newer
+// than v1.3.0-rc1. The development builds, which have no semantic version,
+// are never updated.
+func newerRelease(latest, current string) bool {
+	latest, current = semanticVersion(latest), semanticVersion(current)
+	if !semver.IsValid(current) || !semver.IsValid(latest) {
+		return false
+	}
+	return semver.Compare(latest, current) > 0
+}
+
+// semanticVersion returns a version with the v prefix of semver.
+func semanticVersion(v string) string {
+	if v != "" && !strings.HasPrefix(v, "v") {
+		return "v" + v
+	}
+	return v
+}
+
+// releaseManifest returns the signed manifest of the binary of a release:
+// the lines containerslist-release, then its version, OS, architecture and
+// hex-encoded SHA-256, each ending with a newline.
+func releaseManifest(version string, bin *ReleaseBinary) []byte {
+	return []byte("containerslist-release\n" + version + "\n" + bin.OS + "\n" + bin.Arch + "\n" + bin.SHA256 + "\n")
+}
+
+// UpdateStatus reports whether a newer release than the running one is
+// available.
+type UpdateStatus struct {
+	Current   string    `json:"current"`
+	Latest    string    `json:"latest,omitempty"`
+	Available bool      `json:"available"`
+	Notes     string    `json:"notes,omitempty"`
+	Checked   time.Time `json:"checked"`
+	Error     string    `json:"error,omitempty"`
+	// AutoUpdate is set when the available releases are installed, and
+	// Installed is the release installed, which the node is restarting on.
+	AutoUpdate bool   `json:"autoUpdate"`
+	Installed  string `json:"installed,omitempty"`
+}
+
+// updateChecker checks the release feed for a newer release than the
+// running one at each interval. With -update_auto, the binary of the newer
+// release for the platform of the node is downloaded, verified against its
+// checksum and signature, and replaces the running binary, on which the
+// node then restarts once shut down gracefully. The releases signed by none
+// of the trusted keys are never installed.
+type updateChecker struct {
+	feed     string
+	interval time.Duration
+	auto     bool
+	trusted  map[string]struct{}
+	client   *http.Client
+	logger   log.Logger
+	// installed is called once a release is installed, with the path of
+	// the new binary.
+	installed func(path string)
+
+	mtx    sync.Mutex
+	status UpdateStatus
+
+	checks    *prometheus.CounterVec
+	available prometheus.Gauge
+}
+
+func newUpdateChecker(feed string, interval time.Duration, auto bool, trustedKeysFile string, logger log.Logger, reg prometheus.Registerer, installed func(string)) (*updateChecker, error) {
+	if _, err := url.Parse(feed); err != nil {
+		return nil, fmt.Errorf("invalid release feed %q: %w", feed, err)
+	}
+	c := &updateChecker{
+		feed:      feed,
+		interval:  interval,
+		auto:      auto,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		logger:    log.With(logger, "component", "update"),
+		installed: installed,
+		status:    UpdateStatus{Current: buildVersion(), AutoUpdate: auto},
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "containerslist_update_checks_total",
+			Help: "Total number of checks of the release feed, by result: current, available or error.",
+		}, []string{"result"}),
+		available: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "containerslist_update_available",
+			Help: "Whether a newer release than the running one is available.",
+		}),
+	}
+	if auto {
+		if trustedKeysFile == "" {
+			return nil, errors.New("-update_auto requires -update_trusted_keys")
+		}
+		var err error
+		if c.trusted, err = readTrustedKeys(trustedKeysFile); err != nil {
+			return nil, fmt.Errorf("invalid update trusted keys: %w", err)
+		}
+	}
+	reg.MustRegister(c.checks, c.available)
+	return c, nil
+}
+
+// Status returns the result of the last check.
+func (c *updateChecker) Status() UpdateStatus {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.status
+}
+
+// latest fetches the latest release of the feed.
+func (c *updateChecker) latest(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.feed, nil)
+	if err != nil {
+		return nil, err
+	}
+	var r Release
+	if err := doSinkRequest(c.client, req, &r); err != nil {
+		return nil, err
+	}
+	if r.Version == "" {
+		return nil, errors.New("no version in the release feed")
+	}
+	return &r, nil
+}
+
+// check checks the feed, installing the newer release with -update_auto.
+func (c *updateChecker) check(ctx context.Context) {
+	r, err := c.latest(ctx)
+	c.mtx.Lock()
+	c.status.Checked = time.Now().UTC()
+	if err != nil {
+		c.status.Error = err.Error()
+		c.mtx.Unlock()
+		c.checks.WithLabelValues("error").Inc()
+		level.Warn(c.logger).Log("msg", "Unable to check the release feed", "feed", c.feed, "error", err)
+		return
+	}
+	current := c.status.Current
+	c.status.Error = ""
+	c.status.Latest, c.status.Notes = r.Version, r.Notes
+	c.status.Available = newerRelease(r.Version, current)
+	available, installed := c.status.Available, c.status.Installed
+	c.mtx.Unlock()
+
+	if !available {
+		c.checks.WithLabelValues("current").Inc()
+		c.available.Set(0)
+		return
+	}
+	c.checks.WithLabelValues("available").Inc()
+	c.available.Set(1)
+	level.Info(c.logger).Log("msg", "Update available", "current", current, "latest", r.Version, "notes", r.Notes)
+	if !c.auto || installed != "" {
+		return
+	}
+	path, err := c.install(ctx, r)
+	if err != nil {
+		level.Error(c.logger).Log("msg", "Unable to install the update", "version", r.Version, "error", err)
+		c.mtx.Lock()
+		c.status.Error = err.Error()
+		c.mtx.Unlock()
+		return
+	}
+	level.Info(c.logger).Log("msg", "Update installed, restarting", "version", r.Version, "path", path)
+	c.mtx.Lock()
+	c.status.Installed = r.Version
+	c.mtx.Unlock()
+	c.installed(path)
+}
+
+// install downloads and verifies the binary of a release for the platform
+// of the node, then replaces the running binary with it, returning its path.
+func (c *updateChecker) install(ctx context.Context, r *Release) (string, error) {
+	var bin *ReleaseBinary
+	for i := range r.Binaries {
+		if r.Binaries[i].OS == runtime.GOOS && r.Binaries[i].Arch == runtime.GOARCH {
+			bin = &r.Binaries[i]
+		}
+	}
+	if bin == nil {
+		return "", fmt.Errorf("no binary for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	feed, _ := url.Parse(c.feed)
+	u, err := feed.Parse(bin.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid binary URL %q: %w", bin.URL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download binary: %s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxUpdateBinarySize+1))
+	if err != nil {
+		return "", fmt.Errorf("unable to download binary: %w", err)
+	}
+	if len(b) > maxUpdateBinarySize {
+		return "", fmt.Errorf("binary larger than %d bytes", maxUpdateBinarySize)
+	}
+	if err := c.verify(r.Version, bin, b); err != nil {
+		return "", err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+	// The binary is written next to the running one, for the rename
+	// replacing it to be atomic.
+	f, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".update-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, bytes.NewReader(b)); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Chmod(0o755); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), exe); err != nil {
+		return "", err
+	}
+	return exe, nil
+}
+
+// verify checks a downloaded binary of a release: its manifest must be
+// signed by one of the trusted keys, its checksum must match the manifest,
+// and its embedded version must be the version of the release, lest the
+// node restart on a binary which still finds an update to install.
+func (c *updateChecker) verify(version string, bin *ReleaseBinary, b []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(bin.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	manifest := releaseManifest(version, bin)
+	signed := false
+	for k := range c.trusted {
+		if ed25519.Verify(ed25519.PublicKey(k), manifest, sig) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return errors.New("release not signed by a trusted key")
+	}
+	sum := sha256.Sum256(b)
+	if hex.EncodeToString(sum[:]) != bin.SHA256 {
+		return fmt.Errorf("checksum mismatch: got %x, expected %s", sum, bin.SHA256)
+	}
+	v, err := binaryVersion(b)
+	if err != nil {
+		return fmt.Errorf("unable to read the version of the binary: %w", err)
+	}
+	if v != version {
+		return fmt.Errorf("binary of version %s, not %s", v, version)
+	}
+	return nil
+}
+
+// Run checks the feed on startup and at each interval until ctx is done, or
+// until an update is installed.
+func (c *updateChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.check(ctx)
+		if c.Status().Installed != "" {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// restartOn makes the node shut down gracefully, then restart on a binary.
+func (m *Manager) restartOn(path string) {
+	m.restartBinary = path
+	m.stop()
+}
+
+// apiUpdate returns whether a newer release than the running one is
+// available.
+func (m *Manager) apiUpdate() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.updates == nil {
+			writeJSON(w, UpdateStatus{Current: buildVersion()})
+			return
+		}
+		writeJSON(w, m.updates.Status())
+	})
+}
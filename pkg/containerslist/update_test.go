@@ -0,0 +1,94 @@
+package containerslist
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUpdateCheckerVerify(t *testing.T) {
+	// The test binary stands for the downloaded one: it has no version set
+	// with -X main.version, and is of version dev.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, untrusted, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &updateChecker{trusted: map[string]struct{}{string(pub): {}}}
+
+	for _, tc := range []struct {
+		name string
+		// signed is the version of the signed manifest, and version the
+		// one of the feed.
+		signed, version string
+		key             ed25519.PrivateKey
+		arch            string
+		wantErr         string
+	}{
+		{name: "valid", signed: "dev", version: "dev", key: priv},
+		{name: "untrusted key", signed: "dev", version: "dev", key: untrusted, wantErr: "not signed by a trusted key"},
+		{name: "relabeled version", signed: "dev", version: "v9.0.0", key: priv, wantErr: "not signed by a trusted key"},
+		{name: "other platform", signed: "dev", version: "dev", key: priv, arch: "other", wantErr: "not signed by a trusted key"},
+		{name: "version not embedded", signed: "v9.0.0", version: "v9.0.0", key: priv, wantErr: "binary of version dev"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bin := &ReleaseBinary{OS: "linux", Arch: "amd64", SHA256: hex.EncodeToString(sum[:])}
+			bin.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(tc.key, releaseManifest(tc.signed, bin)))
+			if tc.arch != "" {
+				bin.Arch = tc.arch
+			}
+			err := c.verify(tc.version, bin, b)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewerRelease(t *testing.T) {
+	for _, tc := range []struct {
+		latest, current string
+		want            bool
+	}{
+		{latest: "v1.3.0", current: "v1.2.9", want: true},
+		{latest: "v1.10.0", current: "v1.9.0", want: true},
+		{latest: "v1.3.0", current: "v1.3.0"},
+		{latest: "v1.2.0", current: "v1.3.0"},
+		{latest: "v1.3.0", current: "v1.3.0-rc1", want: true},
+		{latest: "v1.3.0-rc2", current: "v1.3.0-rc1", want: true},
+		{latest: "v1.3.0-rc.10", current: "v1.3.0-rc.9", want: true},
+		{latest: "v1.3.0-rc1", current: "v1.3.0"},
+		{latest: "v1.3.0-rc1", current: "v1.2.0", want: true},
+		{latest: "v1.3.0+build2", current: "v1.3.0+build1"},
+		{latest: "1.3.0", current: "v1.2.0", want: true},
+		{latest: "v1.3.0", current: "dev"},
+		{latest: "v1.3.0", current: ""},
+		{latest: "latest", current: "v1.2.0"},
+	} {
+		t.Run(tc.latest+" over "+tc.current, func(t *testing.T) {
+			if got := newerRelease(tc.latest, tc.current); got != tc.want {
+				t.Errorf("newerRelease(%q, %q) = %v, want %v", tc.latest, tc.current, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package containerslist
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// NodeUsage is a compact summary of the CPU, memory and disk usage of the
+// host of a node, sampled from /proc.
+type NodeUsage struct {
+	Node      string    `json:"node"`
+	Timestamp time.Time `json:"timestamp"`
+	// CPUPercent is the CPU usage since the previous sample, over all CPUs.
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryPercent    float64 `json:"memoryPercent"`
+	MemoryTotalBytes uint64  `json:"memoryTotalBytes"`
+	DiskPercent      float64 `json:"diskPercent"`
+	DiskTotalBytes   uint64  `json:"diskTotalBytes"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+func (u *NodeUsage) node() string              { return u.Node }
+func (u *NodeUsage) timestamp() time.Time      { return u.Timestamp }
+func (u *NodeUsage) signature() *Signature     { return u.Signature }
+func (u *NodeUsage) setSignature(s *Signature) { u.Signature = s }
+
+// usageSampler samples the usage of the host from the proc filesystem and
+// the filesystem of a disk path.
+type usageSampler struct {
+	procPath string
+	diskPath string
+
+	idle, total uint64
+}
+
+// Sample returns the current usage of the host. The CPU usage of the first
+// sample is the average since boot.
+func (s *usageSampler) Sample() (NodeUsage, error) {
+	var u NodeUsage
+
+	idle, total, err := readCPUTimes(filepath.Join(s.procPath, "stat"))
+	if err != nil {
+		return u, err
+	}
+	if d := total - s.total; total > s.total {
+		u.CPUPercent = 100 * float64(d-(idle-s.idle)) / float64(d)
+	}
+	s.idle, s.total = idle, total
+
+	memTotal, memAvailable, err := readMemInfo(filepath.Join(s.procPath, "meminfo"))
+	if err != nil {
+		return u, err
+	}
+	u.MemoryTotalBytes = memTotal
+	if memTotal > 0 {
+		u.MemoryPercent = 100 * float64(memTotal-memAvailable) / float64(memTotal)
+	}
+
+	diskTotal, diskFree, err := diskSpace(s.diskPath)
+	if err != nil {
+		return u, err
+	}
+	u.DiskTotalBytes = diskTotal
+	if diskTotal > 0 {
+		u.DiskPercent = 100 * float64(diskTotal-diskFree) / float64(diskTotal)
+	}
+	return u, nil
+}
+
+// readCPUTimes returns the idle and total CPU times of the aggregated cpu
+// line of /proc/stat, in jiffies.
+func readCPUTimes(path string) (idle, total uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid %s: %w", path, err)
+			}
+			// Guest times are already counted in user and nice times.
+			if i < 8 {
+				total += v
+			}
+			// Idle and iowait.
+			if i == 3 || i == 4 {
+				idle += v
+			}
+		}
+		return idle, total, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("invalid %s: no cpu line", path)
+}
+
+// readMemInfo returns the total and available memory of /proc/meminfo, in
+// bytes.
+func readMemInfo(path string) (total, available uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var found int
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var dst *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dst = &total
+		case "MemAvailable:":
+			dst = &available
+		default:
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %w", path, err)
+		}
+		*dst = kb * 1024
+		found++
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, err
+	}
+	if found < 2 {
+		return 0, 0, errors.New("invalid " + path + ": missing MemTotal or MemAvailable")
+	}
+	return total, available, nil
+}
+
+// runUsageSampler periodically samples the usage of the host of the local
+// node and broadcasts it to the cluster.
+func (m *Manager) runUsageSampler(ctx context.Context) {
+	s := &usageSampler{procPath: m.cfg.ProcPath, diskPath: m.cfg.UsageDiskPath}
+	for {
+		u, err := s.Sample()
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "Unable to sample node usage", "error", err)
+		} else {
+			u.Node = m.nodeID
+			u.Timestamp = time.Now().UTC()
+			if b, err := m.usage.Set(&u); err != nil {
+				level.Warn(m.logger).Log("msg", "Unable to set node usage", "error", err)
+			} else {
+				m.usageChannel.Broadcast(b)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.cfg.CollectInterval):
+		}
+	}
+}
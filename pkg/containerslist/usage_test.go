@@ -0,0 +1,118 @@
+package containerslist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+// writeProc writes fake stat and meminfo files to a proc directory.
+func writeProc(t *testing.T, dir, stat, meminfo string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meminfo"), []byte(meminfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testMemInfo = "MemTotal:       1000 kB\nMemFree:         100 kB\nMemAvailable:    250 kB\n"
+
+func TestUsageSampler(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("disk usage is only sampled on Linux")
+	}
+	dir := t.TempDir()
+	writeProc(t, dir, "cpu  100 0 100 700 100 0 0 0 50 0\ncpu0 100 0 100 700 100 0 0 0 50 0\n", testMemInfo)
+	s := &usageSampler{procPath: dir, diskPath: dir}
+
+	u, err := s.Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 200 busy jiffies out of 1000 since boot, the guest time is not counted.
+	if u.CPUPercent != 20 {
+		t.Errorf("CPU = %v%%, want 20%%", u.CPUPercent)
+	}
+	if u.MemoryTotalBytes != 1000*1024 || u.MemoryPercent != 75 {
+		t.Errorf("memory = %v%% of %d bytes, want 75%% of 1024000 bytes", u.MemoryPercent, u.MemoryTotalBytes)
+	}
+	if u.DiskTotalBytes == 0 || u.DiskPercent < 0 || u.DiskPercent > 100 {
+		t.Errorf("disk = %v%% of %d bytes", u.DiskPercent, u.DiskTotalBytes)
+	}
+
+	// 150 busy jiffies out of the 200 since the previous sample.
+	writeProc(t, dir, "cpu  200 0 150 720 130 0 0 0 50 0\n", testMemInfo)
+	if u, err = s.Sample(); err != nil {
+		t.Fatal(err)
+	}
+	if u.CPUPercent != 75 {
+		t.Errorf("CPU = %v%%, want 75%%", u.CPUPercent)
+	}
+
+	// Without any jiffies since the previous sample, the CPU is idle.
+	if u, err = s.Sample(); err != nil {
+		t.Fatal(err)
+	}
+	if u.CPUPercent != 0 {
+		t.Errorf("CPU = %v%%, want 0%%", u.CPUPercent)
+	}
+}
+
+func TestUsageSamplerErrors(t *testing.T) {
+	for name, tc := range map[string]struct{ stat, meminfo string }{
+		"no cpu line":          {stat: "cpu0 100 0 100 700 100\n", meminfo: testMemInfo},
+		"invalid cpu time":     {stat: "cpu  100 0 x 700 100\n", meminfo: testMemInfo},
+		"no available memory":  {stat: "cpu  100 0 100 700 100\n", meminfo: "MemTotal: 1000 kB\n"},
+		"invalid total memory": {stat: "cpu  100 0 100 700 100\n", meminfo: "MemTotal: x kB\nMemAvailable: 250 kB\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeProc(t, dir, tc.stat, tc.meminfo)
+			s := &usageSampler{procPath: dir, diskPath: dir}
+			if _, err := s.Sample(); err == nil {
+				t.Error("Sample succeeded, want an error")
+			}
+		})
+	}
+	s := &usageSampler{procPath: filepath.Join(t.TempDir(), "missing")}
+	if _, err := s.Sample(); err == nil {
+		t.Error("Sample of a missing proc filesystem succeeded, want an error")
+	}
+}
+
+func TestRunUsageSampler(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("disk usage is only sampled on Linux")
+	}
+	dir := t.TempDir()
+	writeProc(t, dir, "cpu  100 0 100 700 100 0 0 0 0 0\n", testMemInfo)
+	ch := &testChannel{}
+	m := &Manager{
+		cfg:          DefaultConfig(),
+		logger:       log.NewNopLogger(),
+		nodeID:       "a",
+		usage:        newNodeState[*NodeUsage](),
+		usageChannel: ch,
+	}
+	m.cfg.ProcPath = dir
+	m.cfg.UsageDiskPath = dir
+
+	// The first sample is taken before waiting for the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.runUsageSampler(ctx)
+
+	u, ok := m.usage.Get("a")
+	if !ok || u.CPUPercent != 20 || u.Timestamp.IsZero() {
+		t.Fatalf("usage = %+v, want the sample of a", u)
+	}
+	if len(ch.msgs) != 1 {
+		t.Errorf("%d usage broadcasts, want 1", len(ch.msgs))
+	}
+}
@@ -0,0 +1,138 @@
+package containerslist
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultInternalTLSVaultTTL is the default lifetime of the certificates of
+// the internal API issued by Vault.
+const defaultInternalTLSVaultTTL = 72 * time.Hour
+
+// defaultVaultField is the field of a Vault secret read when a reference
+// names none.
+const defaultVaultField = "value"
+
+var vaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// vaultRequest sends a request to the Vault server of VAULT_ADDR with the
+// token of VAULT_TOKEN, in the namespace of VAULT_NAMESPACE, returning the
+// data of the response.
+func vaultRequest(ctx context.Context, method, path string, body interface{}) (map[string]json.RawMessage, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set")
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := vaultHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("Vault request to %s failed: unexpected status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	var res struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("invalid Vault response of %s: %w", path, err)
+	}
+	return res.Data, nil
+}
+
+// readVaultSecret reads a field of a secret of Vault, referenced as
+// <path>[#<field>], such as secret/data/containerslist#admin_token. Both the
+// KV version 1 and 2 secrets engines are supported.
+func readVaultSecret(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = defaultVaultField
+	}
+	data, err := vaultRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	// The KV version 2 secrets engine nests the data along with metadata.
+	if nested, ok := data["data"]; ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", fmt.Errorf("invalid Vault secret %s: %w", path, err)
+			}
+		}
+	}
+	raw, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("no field %q in Vault secret %s", field, path)
+	}
+	var v string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", fmt.Errorf("field %q of Vault secret %s is not a string", field, path)
+	}
+	return v, nil
+}
+
+// vaultCertSource issues certificates from the issue endpoint of a PKI
+// secrets engine of Vault, such as pki/issue/containerslist, trusting its
+// issuing CA and chain along with the CAs of caFile, when set.
+func vaultCertSource(path, commonName string, altNames []string, ttl time.Duration, caFile string) certSource {
+	return func(ctx context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		data, err := vaultRequest(ctx, http.MethodPost, path, map[string]string{
+			"common_name": commonName,
+			"alt_names":   strings.Join(altNames, ","),
+			"ttl":         ttl.String(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		var issued struct {
+			Certificate string   `json:"certificate"`
+			PrivateKey  string   `json:"private_key"`
+			IssuingCA   string   `json:"issuing_ca"`
+			CAChain     []string `json:"ca_chain"`
+		}
+		b, _ := json.Marshal(data)
+		if err := json.Unmarshal(b, &issued); err != nil {
+			return nil, nil, fmt.Errorf("invalid certificate issued by Vault: %w", err)
+		}
+		cert, err := tls.X509KeyPair([]byte(issued.Certificate+"\n"+issued.IssuingCA), []byte(issued.PrivateKey))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid certificate issued by Vault: %w", err)
+		}
+		pool, err := loadCAPool(caFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		pool.AppendCertsFromPEM([]byte(issued.IssuingCA))
+		for _, ca := range issued.CAChain {
+			pool.AppendCertsFromPEM([]byte(ca))
+		}
+		return &cert, pool, nil
+	}
+}
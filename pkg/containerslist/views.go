@@ -0,0 +1,336 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// viewParams are the parameters of the index page restored by the views.
+var viewParams = []string{"state", "flag", "q", "sort", "columns", "per_page"}
+
+const viewsTpl = `<p>
+      {{ t "Views:" }}
+      <select id="views">
+        <option value="">-</option>
+      {{ range .Views }}
+        <option value="{{ .Query }}" data-name="{{ .Name }}" data-store="{{ if .Owner }}user{{ else }}shared{{ end }}">{{ .Name }}{{ if not .Owner }} ({{ t "shared" }}){{ end }}</option>
+      {{ end }}
+      </select>
+      <input id="view-name" placeholder="{{ t "name of the view" }}">
+      <select id="view-store">
+        <option value="local">{{ t "in this browser" }}</option>
+      {{ if .ViewsEnabled }}
+        <option value="user">{{ t "for me" }}</option>
+        <option value="shared">{{ t "shared" }}</option>
+      {{ end }}
+      </select>
+      <button id="view-save">{{ t "Save view" }}</button>
+      <button id="view-delete">{{ t "Delete view" }}</button>
+      <button id="view-copy">{{ t "Copy link" }}</button>
+    </p>
+    <script>
+      (function() {
+        var views = document.getElementById("views");
+        var local = JSON.parse(localStorage.getItem("views") || "{}");
+        Object.keys(local).sort().forEach(function(name) {
+          var o = new Option(name + " (" + {{ t "in this browser" }} + ")", local[name]);
+          o.dataset.name = name;
+          o.dataset.store = "local";
+          views.add(o);
+        });
+        Array.prototype.forEach.call(views.options, function(o) {
+          if (o.value && o.value == location.search.replace(/^\?/, "")) {
+            o.selected = true;
+          }
+        });
+        views.onchange = function() {
+          location.search = views.value;
+        };
+        function query() {
+          var q = new URLSearchParams(location.search);
+          q.delete("page");
+          return q.toString();
+        }
+        function put(name, params) {
+          fetch("/api/v1/views/" + encodeURIComponent(name) + "?" + params, { method: "PUT" }).then(function(res) {
+            if (res.ok) {
+              location.reload();
+            } else {
+              res.json().then(function(p) { alert(p.detail); });
+            }
+          });
+        }
+        document.getElementById("view-save").onclick = function() {
+          var name = document.getElementById("view-name").value.trim(), store = document.getElementById("view-store").value;
+          if (!name) {
+            return;
+          }
+          if (store == "local") {
+            local[name] = query();
+            localStorage.setItem("views", JSON.stringify(local));
+            location.reload();
+            return;
+          }
+          var params = new URLSearchParams({ query: query() });
+          if (store == "shared") {
+            params.set("shared", "true");
+          }
+          put(name, params);
+        };
+        document.getElementById("view-delete").onclick = function() {
+          var o = views.options[views.selectedIndex];
+          if (!o.value) {
+            return;
+          }
+          if (o.dataset.store == "local") {
+            delete local[o.dataset.name];
+            localStorage.setItem("views", JSON.stringify(local));
+            location.reload();
+            return;
+          }
+          var params = new URLSearchParams({ delete: "true" });
+          if (o.dataset.store == "shared") {
+            params.set("shared", "true");
+          }
+          put(o.dataset.name, params);
+        };
+        document.getElementById("view-copy").onclick = function() {
+          navigator.clipboard.writeText(location.href);
+        };
+      })();
+    </script>`
+
+// View is a saved view of the containers table: its filters, sort and
+// columns, restored by the query string of the index page. Views are owned
+// by a user, or shared with everyone.
+type View struct {
+	Name string `json:"name"`
+	// Query is the query string of the index page restoring the view, such
+	// as state=all&sort=-created.
+	Query string `json:"query"`
+	// Owner is the user of the view, empty for the shared views.
+	Owner   string    `json:"owner,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// viewQuery returns the query string of a view, keeping the parameters of
+// the index page restored by the views.
+func viewQuery(s string) (string, error) {
+	q, err := url.ParseQuery(strings.TrimPrefix(s, "?"))
+	if err != nil {
+		return "", err
+	}
+	for name := range q {
+		if !contains(viewParams, name) {
+			delete(q, name)
+		}
+	}
+	return q.Encode(), nil
+}
+
+// viewStore holds the saved views of the node, persisted in a file. The
+// views are local to the node: they are not gossiped.
+type viewStore struct {
+	file string
+
+	mtx   sync.Mutex
+	views map[viewKey]View
+}
+
+type viewKey struct {
+	owner, name string
+}
+
+// loadViews loads the views of a file, which is created on the first view.
+func loadViews(file string) (*viewStore, error) {
+	s := &viewStore{file: file, views: map[viewKey]View{}}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var views []View
+	if err := json.Unmarshal(b, &views); err != nil {
+		return nil, fmt.Errorf("invalid views file %s: %w", file, err)
+	}
+	for _, v := range views {
+		s.views[viewKey{v.Owner, v.Name}] = v
+	}
+	return s, nil
+}
+
+// List returns the views of a user and the shared views, sorted by name.
+func (s *viewStore) List(user string) []View {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	views := []View{}
+	for _, v := range s.views {
+		if v.Owner == "" || v.Owner == user {
+			views = append(views, v)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].Owner > views[j].Owner
+	})
+	return views
+}
+
+// Set saves a view, replacing the previous one of its owner with the same
+// name.
+func (s *viewStore) Set(v View) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	k := viewKey{v.Owner, v.Name}
+	prev, existed := s.views[k]
+	s.views[k] = v
+	if err := s.save(); err != nil {
+		if existed {
+			s.views[k] = prev
+		} else {
+			delete(s.views, k)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes a view, returning it when it existed.
+func (s *viewStore) Delete(owner, name string) (View, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	k := viewKey{owner, name}
+	v, ok := s.views[k]
+	if !ok {
+		return View{}, false, nil
+	}
+	delete(s.views, k)
+	if err := s.save(); err != nil {
+		s.views[k] = v
+		return View{}, false, err
+	}
+	return v, true, nil
+}
+
+func (s *viewStore) save() error {
+	views := make([]View, 0, len(s.views))
+	for _, v := range s.views {
+		views = append(views, v)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Owner != views[j].Owner {
+			return views[i].Owner < views[j].Owner
+		}
+		return views[i].Name < views[j].Name
+	})
+	b, err := json.MarshalIndent(views, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(s.file, b)
+}
+
+// viewUser returns the user of a request, owning its views: the name of its
+// API key, its SPIFFE ID or its basic authentication user name, or an empty
+// string when anonymous. Without the auth middleware, the user names of the
+// basic authentication are not verified: the views are conveniences, not
+// secrets.
+func (m *Manager) viewUser(r *http.Request) string {
+	token, ok := bearerToken(r)
+	user, password, basic := r.BasicAuth()
+	if !ok {
+		token, ok = password, basic
+	}
+	if k, _, found := m.credential(r, token, ok); found {
+		return k.Name
+	}
+	if basic {
+		return user
+	}
+	return ""
+}
+
+// views returns the views of the user of a request, none when the views
+// are disabled.
+func (m *Manager) views(r *http.Request) []View {
+	if m.savedViews == nil {
+		return nil
+	}
+	return m.savedViews.List(m.viewUser(r))
+}
+
+// apiViews lists the views of the user of the request and the shared views.
+func (m *Manager) apiViews() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.savedViews == nil {
+			writeProblem(w, r, problemf(ErrForbidden, "saved views are disabled: -ui_views_file is not set"))
+			return
+		}
+		writeJSON(w, m.views(r))
+	})
+}
+
+// apiSetView saves the view named in the path /api/v1/views/{name}, or
+// deletes it.
+func (m *Manager) apiSetView() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/views/")
+		if !maintenanceWindowID.MatchString(name) {
+			writeProblem(w, r, problemf(ErrNotFound, "no API endpoint at %s", r.URL.Path))
+			return
+		}
+		if !requireMethod(w, r, http.MethodPut) {
+			return
+		}
+		if m.savedViews == nil {
+			writeProblem(w, r, problemf(ErrForbidden, "saved views are disabled: -ui_views_file is not set"))
+			return
+		}
+		q := r.URL.Query()
+		owner := ""
+		if q.Get("shared") != "true" {
+			if owner = m.viewUser(r); owner == "" {
+				writeProblem(w, r, problemf(ErrInvalidRequest, "the views of anonymous users must be shared: set shared=true"))
+				return
+			}
+		}
+
+		if q.Get("delete") == "true" {
+			v, ok, err := m.savedViews.Delete(owner, name)
+			if err != nil {
+				writeProblem(w, r, problemf(ErrInternal, "unable to delete view: %v", err))
+				return
+			}
+			if !ok {
+				writeProblem(w, r, problemf(ErrNotFound, "no view %q", name))
+				return
+			}
+			writeJSON(w, v)
+			return
+		}
+
+		query, err := viewQuery(q.Get("query"))
+		if err != nil {
+			writeProblem(w, r, problemf(ErrInvalidRequest, "invalid query %q: %v", q.Get("query"), err))
+			return
+		}
+		v := View{Name: name, Query: query, Owner: owner, Updated: time.Now().UTC()}
+		if err := m.savedViews.Set(v); err != nil {
+			writeProblem(w, r, problemf(ErrInternal, "unable to save view: %v", err))
+			return
+		}
+		writeJSON(w, v)
+	})
+}
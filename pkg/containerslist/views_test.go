@@ -0,0 +1,184 @@
+package containerslist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContainerRows(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	nodes := []*NodeInventory{
+		{Node: "b", Containers: []Container{{Name: "web", Image: "nginx", Created: start.Add(time.Minute)}}},
+		{Node: "a", Containers: []Container{{Name: "db", Image: "postgres", Created: start}, {Name: "cache", Image: "Redis", Created: start.Add(time.Hour)}}},
+	}
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"a/db", "a/cache", "b/web"}},
+		{query: "sort=-node", want: []string{"b/web", "a/db", "a/cache"}},
+		{query: "sort=-name", want: []string{"b/web", "a/db", "a/cache"}},
+		{query: "sort=created", want: []string{"a/db", "b/web", "a/cache"}},
+		{query: "q=REDIS", want: []string{"a/cache"}},
+		{query: "q=a+d", want: []string{"a/db"}},
+	} {
+		q, err := url.ParseQuery(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := containerRows(nodes, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, r := range rows {
+			got = append(got, r.Node+"/"+r.Name)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("rows of %q = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+	if _, err := containerRows(nodes, url.Values{"sort": {"status"}}); err == nil {
+		t.Error("sort by status accepted, want an error")
+	}
+}
+
+func TestUIColumnsSelect(t *testing.T) {
+	cs := uiColumns{{Header: "Team"}, {Header: "Version"}, {Header: "Owner"}}
+	for query, want := range map[string][]string{
+		"":                              {"Team", "Version", "Owner"},
+		"columns=":                      nil,
+		"columns=Owner,Team":            {"Team", "Owner"},
+		"columns=Version&columns=Other": {"Version"},
+	} {
+		q, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range cs.Select(q) {
+			got = append(got, c.Header)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("columns of %q = %q, want %q", query, got, want)
+		}
+	}
+	if !cs.Has("Team") || cs.Has("team") {
+		t.Error("Has is not exact")
+	}
+}
+
+func TestViewQuery(t *testing.T) {
+	got, err := viewQuery("?state=all&page=3&sort=-created&token=s3cr3t&q=web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "q=web&sort=-created&state=all"; got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+	if _, err := viewQuery("q=%zz"); err == nil {
+		t.Error("invalid query accepted")
+	}
+}
+
+func TestViewStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "views.json")
+	s, err := loadViews(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []View{
+		{Name: "running", Query: "state=running"},
+		{Name: "running", Query: "state=running&sort=name", Owner: "alice"},
+		{Name: "web", Query: "q=web", Owner: "bob"},
+	} {
+		if err := s.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The views are kept across restarts.
+	s, err = loadViews(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := s.List("alice")
+	if len(list) != 2 || list[0].Owner != "alice" || list[1].Owner != "" {
+		t.Errorf("views of alice = %+v, want hers then the shared one", list)
+	}
+	if list := s.List(""); len(list) != 1 || list[0].Owner != "" {
+		t.Errorf("views of anonymous users = %+v, want the shared one", list)
+	}
+
+	if _, ok, err := s.Delete("alice", "web"); err != nil || ok {
+		t.Errorf("deleted the view of bob as alice: %v, %v", ok, err)
+	}
+	if v, ok, err := s.Delete("bob", "web"); err != nil || !ok || v.Query != "q=web" {
+		t.Errorf("Delete = %+v, %v, %v, want the view of bob", v, ok, err)
+	}
+	if list := s.List("bob"); len(list) != 1 {
+		t.Errorf("views of bob = %+v, want the shared one", list)
+	}
+}
+
+func TestAPIViews(t *testing.T) {
+	m := &Manager{cfg: DefaultConfig()}
+	serve := func(h http.Handler, method, path, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			r.SetBasicAuth(user, "")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	if rec := serve(m.apiViews(), http.MethodGet, "/api/v1/views", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("status with the views disabled = %d, want 403", rec.Code)
+	}
+
+	var err error
+	if m.savedViews, err = loadViews(filepath.Join(t.TempDir(), "views.json")); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(m.apiSetView(), http.MethodPut, "/api/v1/views/mine?query="+url.QueryEscape("state=all&page=2"), "alice")
+	var v View
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "mine" || v.Owner != "alice" || v.Query != "state=all" || time.Since(v.Updated) > time.Minute {
+		t.Errorf("view = %+v, want the view of alice without the page", v)
+	}
+
+	for _, tc := range []struct {
+		method, path, user string
+		want               int
+	}{
+		{method: http.MethodPut, path: "/api/v1/views/all?shared=true&query=state%3Dall", want: http.StatusOK},
+		{method: http.MethodPut, path: "/api/v1/views/anonymous?query=state%3Dall", want: http.StatusBadRequest},
+		{method: http.MethodPut, path: "/api/v1/views/mine?query=%25zz", user: "alice", want: http.StatusBadRequest},
+		{method: http.MethodPut, path: "/api/v1/views/-x?shared=true", want: http.StatusNotFound},
+		{method: http.MethodPut, path: "/api/v1/views/mine?delete=true", user: "bob", want: http.StatusNotFound},
+		{method: http.MethodGet, path: "/api/v1/views/mine", user: "alice", want: http.StatusMethodNotAllowed},
+	} {
+		if rec := serve(m.apiSetView(), tc.method, tc.path, tc.user); rec.Code != tc.want {
+			t.Errorf("status of %s %s as %q = %d, want %d", tc.method, tc.path, tc.user, rec.Code, tc.want)
+		}
+	}
+
+	var list []View
+	if err := json.NewDecoder(serve(m.apiViews(), http.MethodGet, "/api/v1/views", "bob").Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "all" {
+		t.Errorf("views of bob = %+v, want the shared one", list)
+	}
+	if rec := serve(m.apiSetView(), http.MethodPut, "/api/v1/views/mine?delete=true", "alice"); rec.Code != http.StatusOK || len(m.savedViews.List("alice")) != 1 {
+		t.Errorf("status of the deletion = %d, want 200 leaving the shared view", rec.Code)
+	}
+}
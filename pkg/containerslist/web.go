@@ -0,0 +1,91 @@
+package containerslist
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/olivierlemasle/containerslist/pkg/web"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// parseTemplates parses the templates of the UI pages.
+func parseTemplates() (*template.Template, error) {
+	t, err := template.New("webpage").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+	for name, text := range map[string]string{
+		"nav":         navTpl,
+		"ports":       portsTpl,
+		"networks":    networksTpl,
+		"volumes":     volumesTpl,
+		"images":      imagesTpl,
+		"projects":    projectsTpl,
+		"nodes":       nodesTpl,
+		"search":      searchTpl,
+		"violations":  violationsTpl,
+		"maintenance": maintenanceTpl,
+		"top":         topTpl,
+		"pager":       pagerTpl,
+		"views":       viewsTpl,
+		"live":        liveTpl,
+	} {
+		if _, err := t.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return t, nil
+}
+
+// Routes returns the pages of the UI and the endpoints of the API, for
+// web.NewHandler.
+func (m *Manager) Routes() ([]web.Route, error) {
+	t, err := parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+	routes := m.endpointRoutes(m.apiEndpoints())
+	return append(routes, []web.Route{
+		{Pattern: "/api/v1/openapi.json", Handler: m.apiOpenAPI()},
+		{Pattern: "/api/", Handler: apiNotFound()},
+		{Pattern: "/ports", Handler: m.portsPage(t)},
+		{Pattern: "/networks", Handler: resourcesPage(t, "networks", m.networks)},
+		{Pattern: "/volumes", Handler: resourcesPage(t, "volumes", m.volumes)},
+		{Pattern: "/images", Handler: m.imagesPage(t)},
+		{Pattern: "/projects", Handler: m.projectsPage(t)},
+		{Pattern: "/nodes", Handler: m.nodesPage(t)},
+		{Pattern: "/search", Handler: m.searchPage(t)},
+		{Pattern: "/violations", Handler: m.violationsPage(t)},
+		{Pattern: "/maintenance", Handler: m.maintenancePage(t)},
+		{Pattern: "/top", Handler: m.topPage(t)},
+		{Pattern: "/", Handler: m.http(t)},
+	}...), nil
+}
+
+// Gatherer returns the registry of the metrics of the manager.
+func (m *Manager) Gatherer() prometheus.Gatherer {
+	return m.registry
+}
+
+// Middleware wraps a mux in the middlewares of -http_middlewares, less the
+// opt-outs of -http_middleware_opt_out for the patterns of the mux.
+func (m *Manager) Middleware(mux *http.ServeMux) (http.Handler, error) {
+	return m.chain(mux, parseMiddlewares(m.cfg.HTTPMiddlewares), m.cfg.MiddlewareOptOuts)
+}
+
+// newInternalHandler returns the handler of the internal API, serving the
+// state transfers of the peers along with the API. The peers authenticate
+// with their certificates: it is only given request IDs, instrumented and
+// recovers from panics.
+func newInternalHandler(m *Manager) (http.Handler, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/internal/v1/state", m.rejectBlocked(m.throttle.limitStateTransfer(m.internalState())))
+	m.handleEndpoints(mux, m.apiEndpoints())
+	mux.Handle("/api/", apiNotFound())
+	h, err := m.chain(mux, []string{middlewareRequestID, middlewareLimits, middlewareMetrics, middlewareRecovery}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fromPeers(h), nil
+}
@@ -0,0 +1,82 @@
+package containerslist
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// nodeZone returns the zone of a node, as gossiped in its facts.
+func (m *Manager) nodeZone(node string) string {
+	if f, ok := m.facts.Get(node); ok {
+		return f.Zone
+	}
+	return ""
+}
+
+// preferZone returns the peers of a zone first, then the other ones, each in
+// their order, so that the peers of the local zone are queried rather than
+// the ones across zones. The peers are not reordered without a zone.
+func preferZone(peers []string, zones map[string]string, zone string) []string {
+	if zone == "" {
+		return peers
+	}
+	sorted := make([]string, 0, len(peers))
+	for _, p := range peers {
+		if zones[p] == zone {
+			sorted = append(sorted, p)
+		}
+	}
+	for _, p := range peers {
+		if zones[p] != zone {
+			sorted = append(sorted, p)
+		}
+	}
+	return sorted
+}
+
+// bootstrapFromPeers pulls the full state once from the internal API of a
+// peer, as known once joined, trying those of the local zone first.
+func (m *Manager) bootstrapFromPeers(ctx context.Context) error {
+	zones := map[string]string{}
+	var addrs []string
+	for _, f := range m.facts.Nodes() {
+		if f.InternalAddress == "" || f.InternalAddress == m.internalAddress || f.Peer == m.peer.Name() {
+			continue
+		}
+		if _, ok := zones[f.InternalAddress]; !ok {
+			addrs = append(addrs, f.InternalAddress)
+		}
+		zones[f.InternalAddress] = f.Zone
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no peer with an internal API")
+	}
+	var err error
+	for _, addr := range preferZone(addrs, zones, m.cfg.Zone) {
+		if err = m.bootstrap(ctx, "https://"+addr+"/internal/v1/state", m.internalTLS); err == nil {
+			level.Info(m.logger).Log("msg", "State bootstrapped from peer", "peer", addr, "zone", zones[addr])
+			return nil
+		}
+		level.Debug(m.logger).Log("msg", "Unable to bootstrap state from peer", "peer", addr, "error", err)
+	}
+	return err
+}
+
+// annotateZones flags the inventories of the nodes in other zones than the
+// local one: their updates cross zones, and may be staler. They are reported
+// stale after the cross-zone threshold.
+func (m *Manager) annotateZones(nodes []*NodeInventory, now time.Time) {
+	if m.cfg.Zone == "" {
+		return
+	}
+	for _, n := range nodes {
+		n.Zone = m.nodeZone(n.Node)
+		n.CrossZone = n.Zone != "" && n.Zone != m.cfg.Zone
+		if n.CrossZone && m.cfg.CrossZoneStaleAfter > 0 {
+			n.Stale = now.Sub(n.Timestamp) > m.cfg.CrossZoneStaleAfter
+		}
+	}
+}
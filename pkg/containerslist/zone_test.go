@@ -0,0 +1,218 @@
+package containerslist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPreferZone(t *testing.T) {
+	zones := map[string]string{"a1": "a", "a2": "a", "b1": "b"}
+	for _, tc := range []struct {
+		name  string
+		peers []string
+		zone  string
+		want  []string
+	}{
+		{name: "no zone", peers: []string{"b1", "a1", "c1"}, want: []string{"b1", "a1", "c1"}},
+		{name: "local zone first", peers: []string{"b1", "a1", "c1", "a2"}, zone: "a", want: []string{"a1", "a2", "b1", "c1"}},
+		{name: "no peer in the zone", peers: []string{"b1", "c1"}, zone: "d", want: []string{"b1", "c1"}},
+		{name: "empty", zone: "a", want: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := preferZone(tc.peers, zones, tc.zone)
+			if len(got) != len(tc.want) || len(got) > 0 && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("preferZone = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// testZonePeer is a peer of a zone serving the inventories of its nodes on
+// its internal API, recording the scopes of the queries.
+type testZonePeer struct {
+	name, zone string
+	nodes      []string
+	srv        *httptest.Server
+
+	mtx    sync.Mutex
+	scopes []string
+}
+
+func (p *testZonePeer) start(t *testing.T, answers map[string][]string) {
+	p.srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := r.URL.Query().Get("scope")
+		p.mtx.Lock()
+		p.scopes = append(p.scopes, scope)
+		p.mtx.Unlock()
+		var nodes []*NodeInventory
+		for _, n := range answers[scope] {
+			nodes = append(nodes, &NodeInventory{Node: n, Timestamp: time.Now()})
+		}
+		json.NewEncoder(w).Encode(nodes)
+	}))
+	t.Cleanup(p.srv.Close)
+}
+
+func (p *testZonePeer) addr() string {
+	return p.srv.Listener.Addr().String()
+}
+
+func newTestZoneManager(t *testing.T, localZone string, replicas int, members []string, peers ...*testZonePeer) *Manager {
+	cfg := DefaultConfig()
+	cfg.Zone = localZone
+	m := &Manager{
+		cfg:             cfg,
+		logger:          log.NewNopLogger(),
+		facts:           newNodeState[*NodeFacts](),
+		breakers:        newBreakers(5, time.Minute),
+		gatewayFailures: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_gateway_failures_total"}),
+		internalAddress: "127.0.0.1:1",
+	}
+	if members != nil {
+		m.shards = newShardRing(16, replicas, func() []string { return members })
+	}
+	for _, p := range peers {
+		for _, n := range p.nodes {
+			if _, err := m.facts.Set(&NodeFacts{Node: n, Peer: p.name, Zone: p.zone, InternalAddress: p.addr(), Timestamp: time.Now()}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m.router = &router{transport: p.srv.Client().Transport}
+	}
+	return m
+}
+
+func TestGatewayTargets(t *testing.T) {
+	a := &testZonePeer{name: "a", zone: "zone-a", nodes: []string{"node-a"}}
+	b := &testZonePeer{name: "b", zone: "zone-b", nodes: []string{"node-b1", "node-b2"}}
+	c := &testZonePeer{name: "c", zone: "zone-c", nodes: []string{"node-c"}}
+	for _, p := range []*testZonePeer{a, b, c} {
+		p.start(t, nil)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		localZone string
+		// members of the shard ring, not sharded when nil.
+		members     []string
+		want        map[string]string
+		wantCovered map[string][]string
+	}{
+		{
+			name:      "not sharded",
+			localZone: "zone-a",
+			want:      map[string]string{a.addr(): "local", b.addr(): "local", c.addr(): "local"},
+		},
+		{
+			name:    "no zone",
+			members: []string{"a", "b", "c"},
+			want:    map[string]string{a.addr(): "local", b.addr(): "local", c.addr(): "local"},
+		},
+		{
+			name:        "owner in the local zone",
+			localZone:   "zone-a",
+			members:     []string{"a", "b", "c"},
+			want:        map[string]string{a.addr(): "owned"},
+			wantCovered: map[string][]string{a.addr(): {b.addr(), c.addr()}},
+		},
+		{
+			name:      "no owner in the local zone",
+			localZone: "zone-a",
+			members:   []string{"b", "c"},
+			want:      map[string]string{a.addr(): "local", b.addr(): "local", c.addr(): "local"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Each shard is owned by every member.
+			m := newTestZoneManager(t, tc.localZone, 3, tc.members, a, b, c)
+			if tc.members == nil {
+				m.shards = nil
+			}
+			targets, covered := m.gatewayTargets()
+			for _, addrs := range covered {
+				sort.Strings(addrs)
+			}
+			if tc.wantCovered == nil {
+				tc.wantCovered = map[string][]string{}
+			}
+			for _, addrs := range tc.wantCovered {
+				sort.Strings(addrs)
+			}
+			if !reflect.DeepEqual(targets, tc.want) {
+				t.Errorf("targets = %v, want %v", targets, tc.want)
+			}
+			if !reflect.DeepEqual(covered, tc.wantCovered) {
+				t.Errorf("covered = %v, want %v", covered, tc.wantCovered)
+			}
+		})
+	}
+}
+
+func TestGatherContainersPreferZone(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// ownerDown stops the owner in the local zone.
+		ownerDown  bool
+		wantNodes  []string
+		wantFailed bool
+		wantScopes map[string][]string
+	}{
+		{
+			name:       "owner in the local zone",
+			wantNodes:  []string{"node-a", "node-b", "node-c", "node-local"},
+			wantScopes: map[string][]string{"a": {"owned"}},
+		},
+		{
+			name:       "owner down",
+			ownerDown:  true,
+			wantNodes:  []string{"node-b", "node-c", "node-local"},
+			wantFailed: true,
+			wantScopes: map[string][]string{"b": {"local"}, "c": {"local"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &testZonePeer{name: "a", zone: "zone-a", nodes: []string{"node-a"}}
+			b := &testZonePeer{name: "b", zone: "zone-b", nodes: []string{"node-b"}}
+			c := &testZonePeer{name: "c", zone: "zone-c", nodes: []string{"node-c"}}
+			a.start(t, map[string][]string{"owned": {"node-a", "node-b", "node-c"}})
+			b.start(t, map[string][]string{"local": {"node-b"}})
+			c.start(t, map[string][]string{"local": {"node-c"}})
+			m := newTestZoneManager(t, "zone-a", 3, []string{"a", "b", "c"}, a, b, c)
+			if tc.ownerDown {
+				a.srv.Close()
+			}
+
+			local := []*NodeInventory{{Node: "node-local", Timestamp: time.Now()}}
+			nodes, failed := m.gatherContainers(context.Background(), url.Values{}, local)
+			var names []string
+			for _, n := range nodes {
+				names = append(names, n.Node)
+			}
+			if !reflect.DeepEqual(names, tc.wantNodes) {
+				t.Errorf("nodes = %v, want %v", names, tc.wantNodes)
+			}
+			if gotFailed := len(failed) > 0; gotFailed != tc.wantFailed {
+				t.Errorf("failed = %v, want failures %v", failed, tc.wantFailed)
+			}
+			for _, p := range []*testZonePeer{a, b, c} {
+				if tc.ownerDown && p == a {
+					continue
+				}
+				if want := tc.wantScopes[p.name]; !reflect.DeepEqual(p.scopes, want) {
+					t.Errorf("peer %s queried with scopes %v, want %v", p.name, p.scopes, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Package informer keeps a local cache of the containers of a containerslist
+// cluster up to date from its changes API, and notifies event handlers of
+// the containers added, updated and deleted, in the style of the informers
+// of client-go.
+package informer
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+const (
+	// DefaultWait is the default time the server holds a request for
+	// changes.
+	DefaultWait = 30 * time.Second
+
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Container is a container of the cache, along with its node.
+type Container struct {
+	Node string
+	client.Container
+}
+
+// Key returns the key of a container in the cache: node/id.
+func (c Container) Key() string {
+	return c.Node + "/" + c.ID
+}
+
+// ResourceEventHandler is notified of the changes of the cache. The
+// notifications of an informer are sent sequentially, in the goroutine of
+// Run, once the cache is updated, so that handlers can read it.
+type ResourceEventHandler interface {
+	OnAdd(c Container)
+	// OnUpdate is called when a container changed, and on each resync with
+	// the same container as old and new.
+	OnUpdate(old, new Container)
+	OnDelete(c Container)
+}
+
+// ResourceEventHandlerFuncs is a ResourceEventHandler calling its non-nil
+// functions.
+type ResourceEventHandlerFuncs struct {
+	AddFunc    func(c Container)
+	UpdateFunc func(old, new Container)
+	DeleteFunc func(c Container)
+}
+
+// OnAdd implements ResourceEventHandler.
+func (f ResourceEventHandlerFuncs) OnAdd(c Container) {
+	if f.AddFunc != nil {
+		f.AddFunc(c)
+	}
+}
+
+// OnUpdate implements ResourceEventHandler.
+func (f ResourceEventHandlerFuncs) OnUpdate(old, new Container) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(old, new)
+	}
+}
+
+// OnDelete implements ResourceEventHandler.
+func (f ResourceEventHandlerFuncs) OnDelete(c Container) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(c)
+	}
+}
+
+// Informer caches the containers of the cluster. It first lists all the
+// inventories, then applies the inventories changed since the sequence of
+// the last sync. The server lists everything again when the sequence is not
+// its own, such as when it restarted or when another member answers behind a
+// load balancer.
+type Informer struct {
+	client *client.Client
+	resync time.Duration
+	// Wait is the time the server holds a request for changes; DefaultWait
+	// when 0. It must be set before Run.
+	Wait time.Duration
+
+	mtx        sync.RWMutex
+	containers map[string]map[string]Container
+	epoch      string
+	sequence   uint64
+	revision   uint64
+	synced     bool
+	handlers   []ResourceEventHandler
+}
+
+// New returns an informer of the cluster of the node called by c, which
+// notifies the handlers of all the cached containers every resync period,
+// or never when 0. The resyncs happen between two requests for changes, so
+// they may be delayed by up to Wait.
+func New(c *client.Client, resync time.Duration) *Informer {
+	return &Informer{
+		client:     c,
+		resync:     resync,
+		containers: map[string]map[string]Container{},
+	}
+}
+
+// AddEventHandler adds a handler of the changes of the cache. It is notified
+// of the containers already cached as added, in the calling goroutine.
+func (i *Informer) AddEventHandler(h ResourceEventHandler) {
+	i.mtx.Lock()
+	i.handlers = append(i.handlers, h)
+	var notifications []notification
+	for _, c := range i.list() {
+		c := c
+		notifications = append(notifications, notification{new: &c})
+	}
+	i.mtx.Unlock()
+	dispatch([]ResourceEventHandler{h}, notifications)
+}
+
+// HasSynced reports whether the first list completed.
+func (i *Informer) HasSynced() bool {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.synced
+}
+
+// Revision returns the revision of the cluster of the last sync.
+func (i *Informer) Revision() uint64 {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.revision
+}
+
+// Get returns a cached container.
+func (i *Informer) Get(node, id string) (Container, bool) {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	c, ok := i.containers[node][id]
+	return c, ok
+}
+
+// List returns the cached containers, sorted by key.
+func (i *Informer) List() []Container {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+	return i.list()
+}
+
+func (i *Informer) list() []Container {
+	var res []Container
+	for _, containers := range i.containers {
+		for _, c := range containers {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].Key() < res[b].Key() })
+	return res
+}
+
+// Run syncs the cache until ctx is done, retrying the failed requests with
+// an exponential backoff.
+func (i *Informer) Run(ctx context.Context) {
+	wait := i.Wait
+	if wait == 0 {
+		wait = DefaultWait
+	}
+	var resync <-chan time.Time
+	if i.resync > 0 {
+		ticker := time.NewTicker(i.resync)
+		defer ticker.Stop()
+		resync = ticker.C
+	}
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		select {
+		case <-resync:
+			i.notifyResync()
+		default:
+		}
+
+		i.mtx.RLock()
+		epoch, since := i.epoch, i.sequence
+		i.mtx.RUnlock()
+		changes, err := i.client.Changes(ctx, epoch, since, wait)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+		i.apply(changes)
+	}
+}
+
+// notification is a change of the cache: an addition when old is nil, a
+// deletion when new is nil, and else an update.
+type notification struct {
+	old, new *Container
+}
+
+// dispatch notifies handlers of changes, outside of the lock of the
+// informer, so that they can read its cache.
+func dispatch(handlers []ResourceEventHandler, notifications []notification) {
+	for _, n := range notifications {
+		for _, h := range handlers {
+			switch {
+			case n.old == nil:
+				h.OnAdd(*n.new)
+			case n.new == nil:
+				h.OnDelete(*n.old)
+			default:
+				h.OnUpdate(*n.old, *n.new)
+			}
+		}
+	}
+}
+
+// apply applies the changed inventories to the cache, then notifies the
+// handlers. On a full list, the nodes which are not listed are deleted.
+func (i *Informer) apply(changes *client.Changes) {
+	i.mtx.Lock()
+	var notifications []notification
+	listed := map[string]bool{}
+	for _, n := range changes.Nodes {
+		listed[n.Node] = true
+		// The containers of the nodes summarized by sharded clusters are
+		// not known: the previous ones are kept.
+		if n.Summary {
+			continue
+		}
+		prev := i.containers[n.Node]
+		next := map[string]Container{}
+		if !n.Decommissioned {
+			for _, c := range n.Containers {
+				next[c.ID] = Container{Node: n.Node, Container: c}
+			}
+		}
+		notifications = append(notifications, diff(prev, next)...)
+		if len(next) == 0 {
+			delete(i.containers, n.Node)
+		} else {
+			i.containers[n.Node] = next
+		}
+	}
+	if changes.Full {
+		for node, prev := range i.containers {
+			if !listed[node] {
+				notifications = append(notifications, diff(prev, nil)...)
+				delete(i.containers, node)
+			}
+		}
+	}
+	i.epoch, i.sequence, i.revision = changes.Epoch, changes.Sequence, changes.Revision
+	i.synced = true
+	handlers := i.handlers
+	i.mtx.Unlock()
+
+	dispatch(handlers, notifications)
+}
+
+// diff returns the differences between two versions of the containers of a
+// node.
+func diff(prev, next map[string]Container) []notification {
+	var res []notification
+	for id, c := range next {
+		c := c
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			res = append(res, notification{new: &c})
+		case !reflect.DeepEqual(old, c):
+			res = append(res, notification{old: &old, new: &c})
+		}
+	}
+	for id, c := range prev {
+		c := c
+		if _, ok := next[id]; !ok {
+			res = append(res, notification{old: &c})
+		}
+	}
+	return res
+}
+
+// notifyResync notifies the handlers of all the cached containers, for them
+// to reconcile periodically.
+func (i *Informer) notifyResync() {
+	i.mtx.RLock()
+	var notifications []notification
+	for _, c := range i.list() {
+		c := c
+		notifications = append(notifications, notification{old: &c, new: &c})
+	}
+	handlers := i.handlers
+	i.mtx.RUnlock()
+	dispatch(handlers, notifications)
+}
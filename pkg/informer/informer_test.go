@@ -0,0 +1,284 @@
+package informer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivierlemasle/containerslist/pkg/client"
+)
+
+// node returns the inventory of a node holding containers of the given IDs,
+// running.
+func node(name string, ids ...string) *client.NodeInventory {
+	n := &client.NodeInventory{Node: name}
+	for _, id := range ids {
+		n.Containers = append(n.Containers, client.Container{ID: id, State: "running"})
+	}
+	return n
+}
+
+// recorder records the notifications of an informer as op:key.
+type recorder struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (r *recorder) handler() ResourceEventHandler {
+	return ResourceEventHandlerFuncs{
+		AddFunc:    func(c Container) { r.record("add:" + c.Key()) },
+		UpdateFunc: func(_, c Container) { r.record("update:" + c.Key()) },
+		DeleteFunc: func(c Container) { r.record("delete:" + c.Key()) },
+	}
+}
+
+func (r *recorder) record(e string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, e)
+}
+
+// take returns the recorded notifications, sorted, and forgets them.
+func (r *recorder) take() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	events := r.events
+	r.events = nil
+	sort.Strings(events)
+	return events
+}
+
+func keys(containers []Container) []string {
+	var res []string
+	for _, c := range containers {
+		res = append(res, c.Key())
+	}
+	return res
+}
+
+func TestInformerApply(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		changes    []*client.Changes
+		wantKeys   []string
+		wantEvents []string
+	}{
+		{
+			name: "full list",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+			},
+			wantKeys:   []string{"a/1", "b/2"},
+			wantEvents: []string{"add:a/1", "add:b/2"},
+		},
+		{
+			name: "changes",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+				{Epoch: "e", Sequence: 3, Nodes: []*client.NodeInventory{node("a", "1", "3")}},
+			},
+			wantKeys:   []string{"a/1", "a/3", "b/2"},
+			wantEvents: []string{"add:a/1", "add:a/3", "add:b/2"},
+		},
+		{
+			name: "updated and removed containers",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 1, Full: true, Nodes: []*client.NodeInventory{node("a", "1", "2")}},
+				{Epoch: "e", Sequence: 2, Nodes: []*client.NodeInventory{{Node: "a", Containers: []client.Container{{ID: "1", State: "exited"}}}}},
+			},
+			wantKeys:   []string{"a/1"},
+			wantEvents: []string{"add:a/1", "add:a/2", "delete:a/2", "update:a/1"},
+		},
+		{
+			name: "tombstone",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+				{Epoch: "e", Sequence: 3, Nodes: []*client.NodeInventory{{Node: "a", Decommissioned: true}}},
+			},
+			wantKeys:   []string{"b/2"},
+			wantEvents: []string{"add:a/1", "add:b/2", "delete:a/1"},
+		},
+		{
+			name: "summary keeps the containers",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 1, Full: true, Nodes: []*client.NodeInventory{node("a", "1")}},
+				{Epoch: "e", Sequence: 2, Nodes: []*client.NodeInventory{{Node: "a", Summary: true}}},
+			},
+			wantKeys:   []string{"a/1"},
+			wantEvents: []string{"add:a/1"},
+		},
+		{
+			name: "relist deletes the nodes not listed",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+				{Epoch: "f", Sequence: 1, Full: true, Nodes: []*client.NodeInventory{node("b", "2")}},
+			},
+			wantKeys:   []string{"b/2"},
+			wantEvents: []string{"add:a/1", "add:b/2", "delete:a/1"},
+		},
+		{
+			name: "changes do not delete the nodes not listed",
+			changes: []*client.Changes{
+				{Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+				{Epoch: "e", Sequence: 3, Nodes: []*client.NodeInventory{node("b", "2", "3")}},
+			},
+			wantKeys:   []string{"a/1", "b/2", "b/3"},
+			wantEvents: []string{"add:a/1", "add:b/2", "add:b/3"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			i := New(client.New("http://unused"), 0)
+			var r recorder
+			i.AddEventHandler(r.handler())
+			for _, changes := range tc.changes {
+				i.apply(changes)
+			}
+			if got := keys(i.List()); !reflect.DeepEqual(got, tc.wantKeys) {
+				t.Errorf("cached %v, want %v", got, tc.wantKeys)
+			}
+			if got := r.take(); !reflect.DeepEqual(got, tc.wantEvents) {
+				t.Errorf("notified %v, want %v", got, tc.wantEvents)
+			}
+		})
+	}
+}
+
+// fakeServer answers the requests for changes with the responses of a
+// script, indexed by the since and epoch of the request, recording them.
+type fakeServer struct {
+	mtx       sync.Mutex
+	responses map[string]*client.Changes
+	requests  []string
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := q.Get("epoch") + "@" + q.Get("since")
+	s.mtx.Lock()
+	s.requests = append(s.requests, req)
+	changes, ok := s.responses[req]
+	s.mtx.Unlock()
+	if !ok {
+		// No more changes: hold the request as the server would.
+		wait, _ := time.ParseDuration(q.Get("wait"))
+		select {
+		case <-r.Context().Done():
+		case <-time.After(wait):
+		}
+		since, _ := strconv.ParseUint(q.Get("since"), 10, 64)
+		changes = &client.Changes{Epoch: q.Get("epoch"), Sequence: since, Nodes: []*client.NodeInventory{}}
+	}
+	json.NewEncoder(w).Encode(changes)
+}
+
+func TestInformerRun(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		responses map[string]*client.Changes
+		// until is the last request expected.
+		until        string
+		wantKeys     []string
+		wantRequests []string
+	}{
+		{
+			name: "incremental sync",
+			responses: map[string]*client.Changes{
+				"@0":  {Epoch: "e", Sequence: 2, Full: true, Nodes: []*client.NodeInventory{node("a", "1")}},
+				"e@2": {Epoch: "e", Sequence: 5, Nodes: []*client.NodeInventory{node("b", "2")}},
+			},
+			until:        "e@5",
+			wantKeys:     []string{"a/1", "b/2"},
+			wantRequests: []string{"@0", "e@2", "e@5"},
+		},
+		{
+			// The revisions of the cluster are merged out of order: the
+			// informer follows the sequence, not the revision.
+			name: "revisions out of order",
+			responses: map[string]*client.Changes{
+				"@0": {Epoch: "e", Sequence: 1, Revision: 1005, Full: true, Nodes: []*client.NodeInventory{
+					{Node: "c", Revision: 1005, Containers: []client.Container{{ID: "1"}}},
+				}},
+				"e@1": {Epoch: "e", Sequence: 2, Revision: 1005, Nodes: []*client.NodeInventory{
+					{Node: "a", Revision: 1000, Containers: []client.Container{{ID: "2"}}},
+				}},
+			},
+			until:        "e@2",
+			wantKeys:     []string{"a/2", "c/1"},
+			wantRequests: []string{"@0", "e@1", "e@2"},
+		},
+		{
+			name: "resync after a restart of the server",
+			responses: map[string]*client.Changes{
+				"@0":  {Epoch: "e", Sequence: 7, Full: true, Nodes: []*client.NodeInventory{node("a", "1"), node("b", "2")}},
+				"e@7": {Epoch: "f", Sequence: 3, Full: true, Nodes: []*client.NodeInventory{node("b", "2")}},
+			},
+			until:        "f@3",
+			wantKeys:     []string{"b/2"},
+			wantRequests: []string{"@0", "e@7", "f@3"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &fakeServer{responses: tc.responses}
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			i := New(client.New(srv.URL), 0)
+			i.Wait = 50 * time.Millisecond
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				i.Run(ctx)
+				close(done)
+			}()
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				s.mtx.Lock()
+				requests := append([]string(nil), s.requests...)
+				s.mtx.Unlock()
+				if len(requests) > 0 && requests[len(requests)-1] == tc.until {
+					if !reflect.DeepEqual(requests, tc.wantRequests) {
+						t.Errorf("requests %v, want %v", requests, tc.wantRequests)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("requests %v, never reached %s", requests, tc.until)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			cancel()
+			<-done
+			if got := keys(i.List()); !reflect.DeepEqual(got, tc.wantKeys) {
+				t.Errorf("cached %v, want %v", got, tc.wantKeys)
+			}
+			if !i.HasSynced() {
+				t.Error("not synced")
+			}
+		})
+	}
+}
+
+func TestInformerResync(t *testing.T) {
+	i := New(client.New("http://unused"), time.Minute)
+	i.apply(&client.Changes{Epoch: "e", Sequence: 1, Full: true, Nodes: []*client.NodeInventory{node("a", "1", "2")}})
+	var r recorder
+	i.AddEventHandler(r.handler())
+	r.take()
+
+	i.notifyResync()
+	want := []string{"update:a/1", "update:a/2"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+	if got := fmt.Sprint(keys(i.List())); got != "[a/1 a/2]" {
+		t.Errorf("resync changed the cache: %s", got)
+	}
+}
@@ -0,0 +1,82 @@
+// Package web serves the UI and the API of a containerslist manager. Its
+// handler is independent of http.DefaultServeMux and of the command line,
+// so that it can be mounted on any server, along with other handlers and
+// middlewares.
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Route is a page or an endpoint of the API, mounted at a pattern of
+// http.ServeMux.
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// Manager is the manager served by the handler, such as a
+// containerslist.Manager.
+type Manager interface {
+	// Routes returns the pages of the UI and the endpoints of the API.
+	Routes() ([]Route, error)
+	// Gatherer returns the registry of the metrics of the manager.
+	Gatherer() prometheus.Gatherer
+	// Middleware wraps the mux of the routes in the middlewares of the
+	// manager, which may depend on the pattern of the requests.
+	Middleware(mux *http.ServeMux) (http.Handler, error)
+}
+
+// Options configure the handler.
+type Options struct {
+	// Metrics serves the metrics of the manager at /metrics. Embedders
+	// gathering the registry of the manager themselves leave it unset.
+	Metrics bool
+	// Resolve serves the /resolve debugging page, resolving the host of its
+	// query with the resolver of the node.
+	Resolve bool
+}
+
+// NewHandler returns the handler of the UI and of the API of a manager,
+// wrapped in its middlewares. The UI links to absolute paths: it must be
+// mounted at the root of its host.
+func NewHandler(m Manager, opts Options) (http.Handler, error) {
+	routes, err := m.Routes()
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	if opts.Resolve {
+		mux.Handle("/resolve", resolvePage())
+	}
+	if opts.Metrics {
+		mux.Handle("/metrics", promhttp.HandlerFor(m.Gatherer(), promhttp.HandlerOpts{
+			// Exemplars are only exposed in the OpenMetrics format.
+			EnableOpenMetrics: true,
+		}))
+	}
+	for _, r := range routes {
+		mux.Handle(r.Pattern, r.Handler)
+	}
+	return m.Middleware(mux)
+}
+
+func resolvePage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host := r.URL.Query().Get("host"); host != "" {
+			ips, err := net.LookupIP(host)
+			if err != nil {
+				fmt.Fprintf(w, "Error: %v", err)
+			}
+			fmt.Fprintf(w, "%v: %v", host, ips)
+		} else {
+			fmt.Fprintln(w, "Use  /resolve?host=host")
+		}
+	})
+}
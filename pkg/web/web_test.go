@@ -0,0 +1,75 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type fakeManager struct {
+	registry *prometheus.Registry
+}
+
+func (fakeManager) Routes() ([]Route, error) {
+	return []Route{
+		{Pattern: "/api/v1/containers", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "containers")
+		})},
+		{Pattern: "/", Handler: http.NotFoundHandler()},
+	}, nil
+}
+
+func (m fakeManager) Gatherer() prometheus.Gatherer {
+	return m.registry
+}
+
+// Middleware tags the responses with the pattern of their request.
+func (fakeManager) Middleware(mux *http.ServeMux) (http.Handler, error) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		w.Header().Set("X-Pattern", pattern)
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+func TestNewHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test."}))
+
+	for _, tc := range []struct {
+		name        string
+		opts        Options
+		path        string
+		wantStatus  int
+		wantBody    string
+		wantPattern string
+	}{
+		{name: "route", path: "/api/v1/containers", wantStatus: http.StatusOK, wantBody: "containers", wantPattern: "/api/v1/containers"},
+		{name: "metrics", opts: Options{Metrics: true}, path: "/metrics", wantStatus: http.StatusOK, wantBody: "test_total 0", wantPattern: "/metrics"},
+		{name: "metrics disabled", path: "/metrics", wantStatus: http.StatusNotFound, wantPattern: "/"},
+		{name: "resolve", opts: Options{Resolve: true}, path: "/resolve", wantStatus: http.StatusOK, wantBody: "Use  /resolve?host=host", wantPattern: "/resolve"},
+		{name: "resolve disabled", path: "/resolve", wantStatus: http.StatusNotFound, wantPattern: "/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewHandler(fakeManager{registry: reg}, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tc.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tc.wantBody)
+			}
+			if got := rec.Header().Get("X-Pattern"); got != tc.wantPattern {
+				t.Errorf("pattern = %q, want %q", got, tc.wantPattern)
+			}
+		})
+	}
+}